@@ -0,0 +1,119 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains actions, which viewers perform when the user interacts
+// with the document, for example by clicking a button (p. 652).
+
+// Action is something for the viewer to do, like opening a web page.
+type Action interface {
+	object() interface{}
+}
+
+// actionDict returns the dictionary of an action of type s, with entries of
+// e added to it.
+func actionDict(s string, e map[string]interface{}) map[string]interface{} {
+	e["Type"] = name("Action")
+	e["S"] = name(s)
+	return e
+}
+
+// URI is an action that opens a URI, usually a web page, in the browser.
+type URI string
+
+func (u URI) object() interface{} {
+	return actionDict("URI", map[string]interface{}{"URI": string(u)})
+}
+
+// JavaScript is an action that runs a script in the viewer.
+type JavaScript string
+
+func (j JavaScript) object() interface{} {
+	return actionDict("JavaScript", map[string]interface{}{"JS": string(j)})
+}
+
+// Formats in which SubmitForm sends the values of the fields.
+const (
+	SubmitFDF = iota
+	SubmitHTML
+	SubmitXFDF
+	SubmitPDF
+)
+
+// SubmitForm is an action that sends the values of the fields of the form to
+// a URL (p. 703).
+type SubmitForm struct {
+	URL    string
+	Format int // one of SubmitFDF, SubmitHTML, SubmitXFDF and SubmitPDF
+	// Get makes HTML forms to be sent with a GET request, instead of POST.
+	Get bool
+	// Fields lists the names of the fields to be sent. All fields are sent
+	// if it's empty.
+	Fields []string
+	// Empty makes fields with no value to be sent too.
+	Empty bool
+}
+
+func (s *SubmitForm) object() interface{} {
+	flags := 0
+	if s.Empty {
+		flags |= 1 << 1
+	}
+	switch s.Format {
+	case SubmitHTML:
+		flags |= 1 << 2
+		if s.Get {
+			flags |= 1 << 3
+		}
+	case SubmitXFDF:
+		flags |= 1 << 5
+	case SubmitPDF:
+		flags |= 1 << 8
+	}
+	e := map[string]interface{}{
+		"F": map[string]interface{}{
+			"FS": name("URL"),
+			"F":  s.URL,
+		},
+		"Flags": flags,
+	}
+	if len(s.Fields) > 0 {
+		e["Fields"] = s.Fields
+	}
+	return actionDict("SubmitForm", e)
+}
+
+// ResetForm is an action that sets the fields of the form back to their
+// default values.
+type ResetForm struct {
+	// Fields lists the names of the fields to be reset. All fields are
+	// reset if it's empty.
+	Fields []string
+	// Exclude resets all the fields except those in Fields.
+	Exclude bool
+}
+
+func (r *ResetForm) object() interface{} {
+	e := map[string]interface{}{}
+	if len(r.Fields) > 0 {
+		e["Fields"] = r.Fields
+		if r.Exclude {
+			e["Flags"] = 1
+		}
+	}
+	return actionDict("ResetForm", e)
+}
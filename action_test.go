@@ -0,0 +1,48 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestActions(t *testing.T) {
+	tests := []outputTest{
+		{"uri", URI("http://example.com/"),
+			[]byte("<<\n/S /URI\n/Type /Action\n/URI (http://example.com/)\n>>")},
+		{"javascript", JavaScript("app.alert(1);"),
+			[]byte("<<\n/JS (app.alert\\(1\\);)\n/S /JavaScript\n/Type /Action\n>>")},
+		{"reset all", &ResetForm{},
+			[]byte("<<\n/S /ResetForm\n/Type /Action\n>>")},
+		{"reset except", &ResetForm{[]string{"a"}, true},
+			[]byte("<<\n/Fields [ (a) ]\n/Flags 1\n/S /ResetForm\n/Type /Action\n>>")},
+		{"submit html", &SubmitForm{URL: "http://x/", Format: SubmitHTML, Get: true},
+			[]byte("<<\n/F <<\n/F (http://x/)\n/FS /URL\n>>\n/Flags 12\n" +
+				"/S /SubmitForm\n/Type /Action\n>>")},
+		{"submit xfdf", &SubmitForm{URL: "u", Format: SubmitXFDF, Empty: true},
+			[]byte("<<\n/F <<\n/F (u)\n/FS /URL\n>>\n/Flags 34\n" +
+				"/S /SubmitForm\n/Type /Action\n>>")},
+	}
+
+	for _, test := range tests {
+		o := output(test.in)
+		if bytes.Compare(o, test.out) != 0 {
+			t.Errorf("%s: got\n\t%s\nexpected\n\t%s", test.name, o, test.out)
+		}
+	}
+}
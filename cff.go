@@ -0,0 +1,417 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file reads CFF font programs, which are the outlines of OpenType
+// fonts with a CFF table, for what PDF needs of them: the widths of their
+// glyphs, which are in their Type 2 charstrings, and the CIDs of the glyphs
+// of CID-keyed fonts.
+
+import (
+	"fmt"
+)
+
+// cff is a parsed CFF font program.
+type cff struct {
+	glyphs [][]byte     // charstrings, by glyph
+	gsubrs [][]byte     // global subroutines
+	privs  []cffPrivate // private dictionaries; one per Font DICT of CID-keyed fonts
+	fds    []byte       // index of the private dictionary of each glyph, if there are many
+	cids   []int        // CIDs of the glyphs, if the font is CID-keyed
+}
+
+// cffPrivate holds what's used of a private dictionary of a CFF font.
+type cffPrivate struct {
+	subrs                      [][]byte // local subroutines
+	defaultWidth, nominalWidth float64
+}
+
+// Operators of CFF dictionaries. Two-byte operators are 1200 plus their
+// second byte.
+const (
+	cffCharsetOp        = 15
+	cffCharStringsOp    = 17
+	cffPrivateOp        = 18
+	cffSubrsOp          = 19
+	cffDefaultWidthOp   = 20
+	cffNominalWidthOp   = 21
+	cffCharstringTypeOp = 1206
+	cffROSOp            = 1230
+	cffFDArrayOp        = 1236
+	cffFDSelectOp       = 1237
+)
+
+// parseCFF parses the CFF font program b, which has one font. It panics
+// with an error of kind ErrBadFile if b is not a CFF font it can read.
+func parseCFF(b []byte) *cff {
+	if len(b) < 4 || b[0] != 1 {
+		fail(ErrBadFile, "not a CFF font of version 1")
+	}
+	_, p := cffIndex(b, int(b[2]))
+	tops, p := cffIndex(b, p)
+	_, p = cffIndex(b, p)
+	c := &cff{}
+	c.gsubrs, _ = cffIndex(b, p)
+	if len(tops) != 1 {
+		fail(ErrBadFile, "CFF font programs with many fonts are not supported")
+	}
+	top := cffDict(tops[0])
+	if t, ok := cffOperand(top, cffCharstringTypeOp); ok && t != 2 {
+		fail(ErrBadFile, fmt.Sprint("CFF charstrings of type ", t, " are not supported"))
+	}
+	cs, ok := cffOperand(top, cffCharStringsOp)
+	if !ok {
+		fail(ErrBadFile, "CFF font with no charstrings")
+	}
+	c.glyphs, _ = cffIndex(b, int(cs))
+
+	if _, ok := top[cffROSOp]; !ok {
+		c.privs = []cffPrivate{cffPrivateDict(b, top)}
+		return c
+	}
+	fdArray, ok1 := cffOperand(top, cffFDArrayOp)
+	fdSelect, ok2 := cffOperand(top, cffFDSelectOp)
+	if !ok1 || !ok2 {
+		fail(ErrBadFile, "CID-keyed CFF font with no FDArray or FDSelect")
+	}
+	fonts, _ := cffIndex(b, int(fdArray))
+	for _, f := range fonts {
+		c.privs = append(c.privs, cffPrivateDict(b, cffDict(f)))
+	}
+	c.fds = cffFDSelect(b, int(fdSelect), len(c.glyphs))
+	for _, fd := range c.fds {
+		if int(fd) >= len(c.privs) {
+			fail(ErrBadFile, "CFF glyph with no Font DICT")
+		}
+	}
+	c.cids = cffCharsetCIDs(b, top, len(c.glyphs))
+	return c
+}
+
+// cffIndex returns the items of the INDEX at offset p of b, and the offset
+// of its end.
+func cffIndex(b []byte, p int) ([][]byte, int) {
+	n := int(u16(b, p))
+	if n == 0 {
+		return nil, p + 2
+	}
+	if p+3 > len(b) {
+		fail(ErrBadFile, "CFF INDEX out of the font")
+	}
+	size := int(b[p+2])
+	if size < 1 || size > 4 {
+		fail(ErrBadFile, fmt.Sprint("CFF INDEX with offsets of ", size, " bytes"))
+	}
+	offs := p + 3
+	data := offs + (n+1)*size - 1
+	off := func(i int) int {
+		o := offs + i*size
+		if o+size > len(b) {
+			fail(ErrBadFile, "CFF INDEX out of the font")
+		}
+		v := 0
+		for _, c := range b[o : o+size] {
+			v = v<<8 | int(c)
+		}
+		return data + v
+	}
+	items := make([][]byte, n)
+	from := off(0)
+	for i := range items {
+		to := off(i + 1)
+		if from > to || to > len(b) {
+			fail(ErrBadFile, "CFF INDEX item out of the font")
+		}
+		items[i] = b[from:to]
+		from = to
+	}
+	return items, from
+}
+
+// cffDict returns the operands of the operators of the CFF dictionary b.
+func cffDict(b []byte) map[int][]float64 {
+	d := make(map[int][]float64)
+	var args []float64
+	for p := 0; p < len(b); {
+		c := b[p]
+		switch {
+		case c <= 21:
+			op := int(c)
+			p++
+			if c == 12 {
+				if p >= len(b) {
+					fail(ErrBadFile, "CFF dictionary too short")
+				}
+				op = 1200 + int(b[p])
+				p++
+			}
+			d[op], args = args, nil
+		case c == 30:
+			// Reals are nibbles, ending with 0xf.
+			s := ""
+			for p++; p < len(b); p++ {
+				hi, lo := b[p]>>4, b[p]&0xf
+				if hi == 0xf {
+					break
+				}
+				s += cffNibbles[hi]
+				if lo == 0xf {
+					break
+				}
+				s += cffNibbles[lo]
+			}
+			p++
+			var f float64
+			fmt.Sscan(s, &f)
+			args = append(args, f)
+		default:
+			var v int
+			v, p = cffNumber(b, p)
+			args = append(args, float64(v))
+		}
+	}
+	return d
+}
+
+// cffOperand returns the first operand of operator op in the CFF dictionary
+// d, and whether it's there.
+func cffOperand(d map[int][]float64, op int) (float64, bool) {
+	v := d[op]
+	if len(v) == 0 {
+		return 0, false
+	}
+	return v[0], true
+}
+
+// cffNibbles are the strings of the nibbles of reals in CFF dictionaries.
+var cffNibbles = []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", ".", "E", "E-", "", "-", ""}
+
+// cffNumber returns the integer at offset p of b, which is an operand of a
+// dictionary or a charstring, and the offset after it.
+func cffNumber(b []byte, p int) (int, int) {
+	c := int(b[p])
+	switch {
+	case c == 28:
+		return int(int16(u16(b, p+1))), p + 3
+	case c == 29:
+		return int(int32(u32(b, p+1))), p + 5
+	case c >= 32 && c <= 246:
+		return c - 139, p + 1
+	case c >= 247 && c <= 254:
+		if p+1 >= len(b) {
+			fail(ErrBadFile, "CFF number out of the font")
+		}
+		if c <= 250 {
+			return (c-247)*256 + int(b[p+1]) + 108, p + 2
+		}
+		return -(c-251)*256 - int(b[p+1]) - 108, p + 2
+	}
+	fail(ErrBadFile, fmt.Sprint("bad CFF operand ", c))
+	return 0, 0
+}
+
+// cffPrivateDict reads the private dictionary of the Top DICT or Font DICT
+// dict of the CFF font b.
+func cffPrivateDict(b []byte, dict map[int][]float64) cffPrivate {
+	var pr cffPrivate
+	p, ok := dict[cffPrivateOp]
+	if !ok || len(p) < 2 {
+		return pr
+	}
+	size, off := int(p[0]), int(p[1])
+	if off < 0 || size < 0 || off+size > len(b) {
+		fail(ErrBadFile, "CFF private dictionary out of the font")
+	}
+	priv := cffDict(b[off : off+size])
+	pr.defaultWidth, _ = cffOperand(priv, cffDefaultWidthOp)
+	pr.nominalWidth, _ = cffOperand(priv, cffNominalWidthOp)
+	if v, ok := cffOperand(priv, cffSubrsOp); ok {
+		pr.subrs, _ = cffIndex(b, off+int(v))
+	}
+	return pr
+}
+
+// cffFDSelect returns the indices of the Font DICTs of n glyphs, from the
+// FDSelect at offset p of b.
+func cffFDSelect(b []byte, p, n int) []byte {
+	fds := make([]byte, n)
+	if p >= len(b) {
+		fail(ErrBadFile, "CFF FDSelect out of the font")
+	}
+	switch b[p] {
+	case 0:
+		if p+1+n > len(b) {
+			fail(ErrBadFile, "CFF FDSelect out of the font")
+		}
+		copy(fds, b[p+1:])
+	case 3:
+		ranges := int(u16(b, p+1))
+		for i := 0; i < ranges; i++ {
+			r := p + 3 + 3*i
+			first, last := int(u16(b, r)), int(u16(b, r+3))
+			for g := first; g < last && g < n; g++ {
+				fds[g] = b[r+2]
+			}
+		}
+	default:
+		fail(ErrBadFile, fmt.Sprint("CFF FDSelect of format ", b[p]))
+	}
+	return fds
+}
+
+// cffCharsetCIDs returns the CIDs of the n glyphs of the CID-keyed CFF font
+// b with Top DICT top, from its charset.
+func cffCharsetCIDs(b []byte, top map[int][]float64, n int) []int {
+	cids := make([]int, 1, n)
+	cs, ok := cffOperand(top, cffCharsetOp)
+	if !ok || cs <= 2 {
+		// Predefined charsets are not for CID-keyed fonts; glyphs are
+		// taken as their own CIDs.
+		for g := 1; g < n; g++ {
+			cids = append(cids, g)
+		}
+		return cids
+	}
+	p := int(cs)
+	if p >= len(b) {
+		fail(ErrBadFile, "CFF charset out of the font")
+	}
+	format := b[p]
+	p++
+	for len(cids) < n {
+		switch format {
+		case 0:
+			cids = append(cids, int(u16(b, p)))
+			p += 2
+		case 1, 2:
+			first := int(u16(b, p))
+			var left int
+			if format == 1 {
+				if p+2 >= len(b) {
+					fail(ErrBadFile, "CFF charset out of the font")
+				}
+				left, p = int(b[p+2]), p+3
+			} else {
+				left, p = int(u16(b, p+2)), p+4
+			}
+			for c := first; c <= first+left && len(cids) < n; c++ {
+				cids = append(cids, c)
+			}
+		default:
+			fail(ErrBadFile, fmt.Sprint("CFF charset of format ", format))
+		}
+	}
+	return cids
+}
+
+// width returns the advance width of glyph g of c, which is the first
+// operand of its charstring if it has one more operand than its first
+// operator takes, or the default width of its private dictionary.
+func (c *cff) width(g int) float64 {
+	if g < 0 || g >= len(c.glyphs) {
+		fail(ErrBadFile, fmt.Sprint("glyph ", g, " out of the font"))
+	}
+	pr := c.privs[0]
+	if c.fds != nil {
+		pr = c.privs[c.fds[g]]
+	}
+	var stack []float64
+	if w, ok := c.charstringWidth(c.glyphs[g], &pr, &stack, 0); ok {
+		return w
+	}
+	return pr.defaultWidth
+}
+
+// charstringWidth runs the Type 2 charstring b, with private dictionary pr
+// and operands stack, until its first operator that clears the stack. found
+// is false if b ends before it, like subroutines usually do.
+func (c *cff) charstringWidth(b []byte, pr *cffPrivate, stack *[]float64, depth int) (w float64, found bool) {
+	if depth > 10 {
+		fail(ErrBadFile, "CFF subroutines nested too deep")
+	}
+	for p := 0; p < len(b); {
+		op := int(b[p])
+		switch {
+		case op == 255:
+			if p+5 > len(b) {
+				fail(ErrBadFile, "CFF charstring too short")
+			}
+			*stack = append(*stack, float64(int32(u32(b, p+1)))/65536)
+			p += 5
+			continue
+		case op == 28 || op >= 32:
+			var v int
+			v, p = cffNumber(b, p)
+			*stack = append(*stack, float64(v))
+			continue
+		}
+		p++
+		n := len(*stack)
+		switch op {
+		case 10, 29: // callsubr and callgsubr
+			if n == 0 {
+				fail(ErrBadFile, "CFF subroutine call with no operand")
+			}
+			subrs := pr.subrs
+			if op == 29 {
+				subrs = c.gsubrs
+			}
+			i := int((*stack)[n-1]) + cffBias(len(subrs))
+			*stack = (*stack)[:n-1]
+			if i < 0 || i >= len(subrs) {
+				fail(ErrBadFile, fmt.Sprint("CFF subroutine ", i, " out of the font"))
+			}
+			if w, ok := c.charstringWidth(subrs[i], pr, stack, depth+1); ok {
+				return w, true
+			}
+		case 11: // return
+			return 0, false
+		case 1, 3, 18, 23, 19, 20, 21, 14: // stems, masks, rmoveto, endchar
+			if n%2 == 1 {
+				return (*stack)[0] + pr.nominalWidth, true
+			}
+			return pr.defaultWidth, true
+		case 4, 22: // vmoveto and hmoveto
+			if n%2 == 0 {
+				return (*stack)[0] + pr.nominalWidth, true
+			}
+			return pr.defaultWidth, true
+		default:
+			fail(ErrBadFile, fmt.Sprint("CFF charstring with operator ", op, " before its width"))
+		}
+	}
+	return 0, false
+}
+
+// cffBias returns the bias of the indices of n subroutines.
+func cffBias(n int) int {
+	switch {
+	case n < 1240:
+		return 107
+	case n < 33900:
+		return 1131
+	}
+	return 32768
+}
+
+// cid returns the CID of glyph g of c, which is g unless c is CID-keyed.
+func (c *cff) cid(g int) int {
+	if c.cids == nil || g >= len(c.cids) {
+		return g
+	}
+	return c.cids[g]
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// cffTestIndex returns a CFF INDEX of items, with offsets of one byte.
+func cffTestIndex(items ...[]byte) []byte {
+	if len(items) == 0 {
+		return be(uint16(0))
+	}
+	b := be(uint16(len(items)), uint8(1), uint8(1))
+	off, data := 1, []byte{}
+	for _, it := range items {
+		off += len(it)
+		b = append(b, byte(off))
+		data = append(data, it...)
+	}
+	return append(b, data...)
+}
+
+// cffTestInt returns v as an operand of CFF dictionaries, in five bytes so
+// that offsets can be set after the dictionaries are laid out.
+func cffTestInt(v int) []byte {
+	return be(uint8(29), int32(v))
+}
+
+// testCFF returns a CFF font program with three glyphs. Glyph 0 has the
+// default width, 500, glyph 1 is 600 wide and glyph 2 is 400 wide, with its
+// width in a local subroutine. If charset isn't nil, the font is CID-keyed
+// with that charset, and glyph 0 has a Font DICT of its own whose default
+// width is 450.
+func testCFF(charset []byte) []byte {
+	glyphs := cffTestIndex([]byte{14}, []byte{248, 136, 14}, []byte{32, 10, 14})
+	subrs := cffTestIndex([]byte{247, 192, 139, 22, 11})
+	priv := be(cffTestInt(500), uint8(20), cffTestInt(100), uint8(21), cffTestInt(18), uint8(19))
+	priv1 := be(cffTestInt(450), uint8(20))
+	fdSelect := be(uint8(3), uint16(2), uint16(0), uint8(1), uint16(1), uint8(0), uint16(3))
+
+	layout := func(start int) ([]byte, []byte) {
+		cs := start
+		p := cs + len(glyphs)
+		rest := be(glyphs, priv, subrs)
+		top := be(cffTestInt(cs), uint8(17))
+		if charset == nil {
+			top = be(top, cffTestInt(len(priv)), cffTestInt(p), uint8(18))
+			return top, rest
+		}
+		c := p + len(priv) + len(subrs)
+		fs := c + len(charset)
+		p1 := fs + len(fdSelect)
+		fa := p1 + len(priv1)
+		fds := cffTestIndex(be(cffTestInt(len(priv)), cffTestInt(p), uint8(18)),
+			be(cffTestInt(len(priv1)), cffTestInt(p1), uint8(18)))
+		top = be(cffTestInt(0), cffTestInt(0), cffTestInt(0), []byte{12, 30}, top,
+			cffTestInt(c), uint8(15), cffTestInt(fa), []byte{12, 36}, cffTestInt(fs), []byte{12, 37})
+		return top, be(rest, charset, fdSelect, priv1, fds)
+	}
+	head := be([]byte{1, 0, 4, 1}, cffTestIndex([]byte("Test1")))
+	top, _ := layout(0)
+	start := len(head) + len(cffTestIndex(top)) + 4
+	top, rest := layout(start)
+	return be(head, cffTestIndex(top), uint16(0), uint16(0), rest)
+}
+
+type cffTest struct {
+	charset []byte
+	widths  []float64
+	cids    []int
+}
+
+func TestCFF(t *testing.T) {
+	tests := []cffTest{
+		{nil, []float64{500, 600, 400}, []int{0, 1, 2}},
+		{be(uint8(0), uint16(10), uint16(20)), []float64{450, 600, 400}, []int{0, 10, 20}},
+		{be(uint8(1), uint16(10), uint8(1)), []float64{450, 600, 400}, []int{0, 10, 11}},
+		{be(uint8(2), uint16(7), uint16(5)), []float64{450, 600, 400}, []int{0, 7, 8}},
+	}
+
+	for _, test := range tests {
+		var err error
+		var c *cff
+		func() {
+			defer dontPanic(&err)
+			c = parseCFF(testCFF(test.charset))
+		}()
+		if err != nil {
+			t.Errorf("charset %v: %v", test.charset, err)
+			continue
+		}
+		var ws []float64
+		var cids []int
+		for g := range c.glyphs {
+			ws = append(ws, c.width(g))
+			cids = append(cids, c.cid(g))
+		}
+		if fmt.Sprint(ws) != fmt.Sprint(test.widths) || fmt.Sprint(cids) != fmt.Sprint(test.cids) {
+			t.Errorf("charset %v: got widths %v and CIDs %v, expected %v and %v",
+				test.charset, ws, cids, test.widths, test.cids)
+		}
+	}
+
+	for _, b := range [][]byte{
+		nil,
+		{2, 0, 4, 1},
+		testCFF(nil)[:40],
+	} {
+		var err error
+		func() {
+			defer dontPanic(&err)
+			parseCFF(b)
+		}()
+		if !errors.Is(err, ErrBadFile) {
+			t.Errorf("bad CFF %v: got %v", b, err)
+		}
+	}
+}
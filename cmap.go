@@ -0,0 +1,158 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file reads ToUnicode CMaps of fonts (p. 292), which map the character
+// codes shown by content streams to their text.
+
+import (
+	"unicode/utf16"
+)
+
+// cmap is a ToUnicode CMap.
+type cmap struct {
+	space [][2]string       // codespace ranges, which give the lengths of codes
+	chars map[string]string // text of codes
+}
+
+// maxRange is the largest number of codes read from a bfrange.
+const maxRange = 1 << 16
+
+// parseCMap parses the ToUnicode CMap b. Operators other than the ones for
+// codespace ranges and mappings of codes are ignored.
+func parseCMap(b []byte) *cmap {
+	m := &cmap{chars: make(map[string]string)}
+	p := &parser{b: b}
+	var args []interface{}
+	for !p.eof() {
+		o := p.object()
+		k, ok := o.(keyword)
+		if !ok {
+			args = append(args, o)
+			continue
+		}
+		switch k {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(args); i += 2 {
+				lo, ok1 := args[i].(string)
+				hi, ok2 := args[i+1].(string)
+				if ok1 && ok2 && len(lo) == len(hi) && lo != "" {
+					m.space = append(m.space, [2]string{lo, hi})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(args); i += 2 {
+				src, ok1 := args[i].(string)
+				dst, ok2 := args[i+1].(string)
+				if ok1 && ok2 {
+					m.chars[src] = utf16Text(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(args); i += 3 {
+				lo, ok1 := args[i].(string)
+				hi, ok2 := args[i+1].(string)
+				if ok1 && ok2 && len(lo) == len(hi) && lo != "" {
+					m.bfrange(lo, hi, args[i+2])
+				}
+			}
+		}
+		args = args[:0]
+	}
+	return m
+}
+
+// bfrange adds the codes from lo to hi to m. dst is either the text of lo,
+// whose last character is incremented for the next codes, or an array of the
+// text of each code.
+func (m *cmap) bfrange(lo, hi string, dst interface{}) {
+	start, end := codeValue(lo), codeValue(hi)
+	if end-start >= maxRange {
+		end = start + maxRange - 1
+	}
+	for c := start; c <= end; c++ {
+		i := c - start
+		code := codeString(c, len(lo))
+		switch t := dst.(type) {
+		case string:
+			if t == "" {
+				return
+			}
+			// Only the last byte is incremented (p. 474).
+			b := []byte(t)
+			b[len(b)-1] += byte(i)
+			m.chars[code] = utf16Text(string(b))
+		case []interface{}:
+			if i >= len(t) {
+				return
+			}
+			if s, ok := t[i].(string); ok {
+				m.chars[code] = utf16Text(s)
+			}
+		}
+	}
+}
+
+// codeLen returns the length of the code at the start of s, according to
+// the codespace ranges of m. It's def if no range matches.
+func (m *cmap) codeLen(s string, def int) int {
+	for _, r := range m.space {
+		n := len(r[0])
+		if n > len(s) {
+			continue
+		}
+		match := true
+		for i := 0; i < n; i++ {
+			if s[i] < r[0][i] || s[i] > r[1][i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return n
+		}
+	}
+	return def
+}
+
+// codeValue returns the number of code c, whose bytes are big-endian.
+func codeValue(c string) int {
+	v := 0
+	for i := 0; i < len(c); i++ {
+		v = v<<8 | int(c[i])
+	}
+	return v
+}
+
+// codeString returns the n bytes of code number v.
+func codeString(v, n int) string {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return string(b)
+}
+
+// utf16Text converts the big-endian UTF-16 string s to UTF-8.
+func utf16Text(s string) string {
+	u := make([]uint16, len(s)/2)
+	for i := range u {
+		u[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file makes the detached CMS (PKCS #7) signatures that are embedded in
+// signed documents (RFC 5652). Only what's needed for PDF signatures is done:
+// one signer, SHA-256 digests, and RSA or ECDSA keys.
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"math/big"
+	"sort"
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSA           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSASHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// asn1Null is the parameter of algorithms that have none.
+var asn1Null = asn1.RawValue{Tag: asn1.TagNull}
+
+type algorithm struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type signerInfo struct {
+	Version            int
+	Sid                issuerAndSerial
+	DigestAlgorithm    algorithm
+	SignedAttrs        asn1.RawValue // [0] IMPLICIT SET OF attribute
+	SignatureAlgorithm algorithm
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional"` // [1] IMPLICIT SET OF attribute
+}
+
+type encapContent struct {
+	Type asn1.ObjectIdentifier
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []algorithm `asn1:"set"`
+	Content          encapContent
+	Certificates     asn1.RawValue // [0] IMPLICIT SET OF Certificate
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type contentInfo struct {
+	Type    asn1.ObjectIdentifier
+	Content asn1.RawValue // [0] EXPLICIT
+}
+
+// marshal is asn1.Marshal that panics on errors.
+func marshal(v interface{}) []byte {
+	b, err := asn1.Marshal(v)
+	check(err)
+	return b
+}
+
+// asn1Set returns the DER encoding of a SET OF the given encoded values,
+// which are sorted as DER wants.
+func asn1Set(vals [][]byte) []byte {
+	s := make([]string, len(vals))
+	for i, v := range vals {
+		s[i] = string(v)
+	}
+	sort.Strings(s)
+	buf := bytes.NewBuffer(nil)
+	for _, v := range s {
+		buf.WriteString(v)
+	}
+	return marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: buf.Bytes()})
+}
+
+// implicit returns the encoded value v with its tag replaced by an implicit
+// context-specific tag.
+func implicit(v []byte, tag int) asn1.RawValue {
+	var r asn1.RawValue
+	_, err := asn1.Unmarshal(v, &r)
+	check(err)
+	r.Class = asn1.ClassContextSpecific
+	r.Tag = tag
+	r.FullBytes = nil
+	return r
+}
+
+// signatureAlgorithm returns the algorithm of signatures made by key with
+// SHA-256 digests.
+func signatureAlgorithm(key crypto.PublicKey) algorithm {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return algorithm{oidRSA, asn1Null}
+	case *ecdsa.PublicKey:
+		return algorithm{Algorithm: oidECDSASHA256}
+	}
+	panic("signing key should be RSA or ECDSA")
+}
+
+// signCMS returns a detached CMS signature of data with the given digest,
+// made by key. certs are put in the signature; the first one should be the
+// certificate of key. If ts is not nil, a timestamp of the signature from it
+// is added too.
+func signCMS(digest []byte, key crypto.Signer, certs []*x509.Certificate, ts TimestampClient) []byte {
+	if len(certs) == 0 {
+		panic("no certificate for the signature")
+	}
+	sa := signatureAlgorithm(key.Public())
+
+	attrs := asn1Set([][]byte{
+		marshal(attribute{oidContentType,
+			[]asn1.RawValue{{FullBytes: marshal(oidData)}}}),
+		marshal(attribute{oidMessageDigest,
+			[]asn1.RawValue{{FullBytes: marshal(digest)}}}),
+	})
+	h := sha256.New()
+	h.Write(attrs)
+	sig, err := key.Sign(rand.Reader, h.Sum(nil), crypto.SHA256)
+	check(err)
+
+	// The timestamp is of the signature value, and is not signed itself.
+	var uattrs asn1.RawValue
+	if ts != nil {
+		h = sha256.New()
+		h.Write(sig)
+		tok := timestamp(ts, h.Sum(nil))
+		uattrs = implicit(asn1Set([][]byte{marshal(attribute{oidTimestampToken,
+			[]asn1.RawValue{{FullBytes: tok}}})}), 1)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, c := range certs {
+		buf.Write(c.Raw)
+	}
+
+	sha := algorithm{oidSHA256, asn1Null}
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []algorithm{sha},
+		Content:          encapContent{oidData},
+		Certificates: asn1.RawValue{Class: asn1.ClassContextSpecific,
+			Tag: 0, IsCompound: true, Bytes: buf.Bytes()},
+		SignerInfos: []signerInfo{{
+			Version: 1,
+			Sid: issuerAndSerial{asn1.RawValue{FullBytes: certs[0].RawIssuer},
+				certs[0].SerialNumber},
+			DigestAlgorithm:    sha,
+			SignedAttrs:        implicit(attrs, 0), // signed as a SET
+			SignatureAlgorithm: sa,
+			Signature:          sig,
+			UnsignedAttrs:      uattrs,
+		}},
+	}
+	return marshal(contentInfo{oidSignedData, asn1.RawValue{
+		Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true,
+		Bytes: marshal(sd)}})
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains colors in the device color spaces (p. 284).
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Color is a color in DeviceGray, DeviceRGB, or DeviceCMYK color space. Values
+// of all components are between 0 and 1.
+type Color interface {
+	// components returns the values of the components of the color. Their
+	// number tells the color space.
+	components() []float64
+}
+
+// Gray is a shade of gray, from 0 for black to 1 for white.
+type Gray float64
+
+func (g Gray) components() []float64 {
+	return []float64{float64(g)}
+}
+
+// RGB is a color made of red, green and blue light.
+type RGB struct {
+	R, G, B float64
+}
+
+func (c RGB) components() []float64 {
+	return []float64{c.R, c.G, c.B}
+}
+
+// CMYK is a color made of cyan, magenta, yellow and black inks.
+type CMYK struct {
+	C, M, Y, K float64
+}
+
+func (c CMYK) components() []float64 {
+	return []float64{c.C, c.M, c.Y, c.K}
+}
+
+// ToGray returns c in DeviceGray, converted like viewers convert between the
+// device color spaces, with the NTSC weights of the components of RGB.
+func ToGray(c Color) Gray {
+	switch c := c.(type) {
+	case Gray:
+		return c
+	case RGB:
+		return Gray(0.3*c.R + 0.59*c.G + 0.11*c.B)
+	case CMYK:
+		return Gray(1 - math.Min(1, 0.3*c.C+0.59*c.M+0.11*c.Y+c.K))
+	}
+	return ToGray(colorOf(c.components()))
+}
+
+// ToRGB returns c in DeviceRGB, converted like viewers convert between the
+// device color spaces.
+func ToRGB(c Color) RGB {
+	switch c := c.(type) {
+	case Gray:
+		return RGB{float64(c), float64(c), float64(c)}
+	case RGB:
+		return c
+	case CMYK:
+		return RGB{1 - math.Min(1, c.C+c.K), 1 - math.Min(1, c.M+c.K),
+			1 - math.Min(1, c.Y+c.K)}
+	}
+	return ToRGB(colorOf(c.components()))
+}
+
+// ToCMYK returns c in DeviceCMYK, converted like viewers convert between the
+// device color spaces. The black of RGB colors is all put in K, and removed
+// from the other inks.
+func ToCMYK(c Color) CMYK {
+	switch c := c.(type) {
+	case Gray:
+		return CMYK{0, 0, 0, 1 - float64(c)}
+	case RGB:
+		cc, m, y := 1-c.R, 1-c.G, 1-c.B
+		k := math.Min(cc, math.Min(m, y))
+		return CMYK{cc - k, m - k, y - k, k}
+	case CMYK:
+		return c
+	}
+	return ToCMYK(colorOf(c.components()))
+}
+
+// colorOf returns the color with components v, telling its color space by
+// their number.
+func colorOf(v []float64) Color {
+	switch len(v) {
+	case 1:
+		return Gray(v[0])
+	case 3:
+		return RGB{v[0], v[1], v[2]}
+	case 4:
+		return CMYK{v[0], v[1], v[2], v[3]}
+	}
+	panic("color with wrong number of components")
+}
+
+// ParseColor returns the color written in hexadecimal like "#1a2b3c" or
+// "#abc", or named like "teal" (see NamedColors). Letters can be in either
+// case, and the number sign can be left out.
+func ParseColor(s string) (c RGB, err error) {
+	defer dontPanic(&err)
+
+	if c, ok := NamedColors[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+	h := strings.TrimPrefix(s, "#")
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+	v, e := strconv.ParseUint(h, 16, 32)
+	if len(h) != 6 || e != nil {
+		panic("bad color: " + strconv.Quote(s))
+	}
+	return hexRGB(uint32(v)), nil
+}
+
+// Hex returns c in hexadecimal like "#1a2b3c", with its components rounded
+// to 8 bits.
+func (c RGB) Hex() string {
+	b := []byte{'#'}
+	for _, f := range c.components() {
+		v := int(math.Floor(math.Max(0, math.Min(1, f))*255 + 0.5))
+		b = append(b, "0123456789abcdef"[v>>4], "0123456789abcdef"[v&15])
+	}
+	return string(b)
+}
+
+// hexRGB returns the color 0xRRGGBB.
+func hexRGB(v uint32) RGB {
+	return RGB{float64(v>>16) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255}
+}
+
+// colorOp returns the content stream operator that sets c as the fill color,
+// or as the stroke color if stroke is true.
+func colorOp(c Color, stroke bool) string {
+	return string(appendColor(nil, c, stroke, 3))
+}
+
+// colorOps are the operators that set colors by their number of components,
+// for fill colors and then for stroke colors.
+var colorOps = [2][5]string{
+	{1: "g", 3: "rg", 4: "k"},
+	{1: "G", 3: "RG", 4: "K"},
+}
+
+// appendColor appends the operator of colorOp to b, with prec digits after
+// the point of numbers at most.
+func appendColor(b []byte, c Color, stroke bool, prec int) []byte {
+	v := c.components()
+	if len(v) >= len(colorOps[0]) || colorOps[0][len(v)] == "" {
+		panic("color with wrong number of components")
+	}
+	for _, f := range v {
+		b = append(appendFloat(b, f, prec), ' ')
+	}
+	if stroke {
+		return append(b, colorOps[1][len(v)]...)
+	}
+	return append(b, colorOps[0][len(v)]...)
+}
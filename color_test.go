@@ -0,0 +1,141 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+type colorOpTest struct {
+	c      Color
+	stroke bool
+	out    string
+}
+
+func TestColorOp(t *testing.T) {
+	tests := []colorOpTest{
+		{Gray(0), false, "0 g"},
+		{Gray(0.5), true, "0.5 G"},
+		{RGB{1, 0, 0.25}, false, "1 0 0.25 rg"},
+		{RGB{0, 1, 0}, true, "0 1 0 RG"},
+		{CMYK{0, 0, 0, 1}, false, "0 0 0 1 k"},
+		{CMYK{1, 0.5, 0, 0}, true, "1 0.5 0 0 K"},
+	}
+
+	for _, test := range tests {
+		if o := colorOp(test.c, test.stroke); o != test.out {
+			t.Errorf("colorOp(%v, %v): got %q expected %q",
+				test.c, test.stroke, o, test.out)
+		}
+	}
+}
+
+type convertTest struct {
+	c    Color
+	gray Gray
+	rgb  RGB
+	cmyk CMYK
+}
+
+func TestConvert(t *testing.T) {
+	tests := []convertTest{
+		{Gray(0.25), 0.25, RGB{0.25, 0.25, 0.25}, CMYK{0, 0, 0, 0.75}},
+		{RGB{1, 0, 0}, 0.3, RGB{1, 0, 0}, CMYK{0, 1, 1, 0}},
+		{RGB{0.5, 0.75, 1}, 0.7025, RGB{0.5, 0.75, 1}, CMYK{0.5, 0.25, 0, 0}},
+		{RGB{0.2, 0.2, 0.2}, 0.2, RGB{0.2, 0.2, 0.2}, CMYK{0, 0, 0, 0.8}},
+		{CMYK{0, 0, 0, 1}, 0, RGB{0, 0, 0}, CMYK{0, 0, 0, 1}},
+		{CMYK{0.5, 0, 0.25, 0.25}, 0.5725, RGB{0.25, 0.75, 0.5}, CMYK{0.5, 0, 0.25, 0.25}},
+		{CMYK{1, 1, 1, 0.5}, 0, RGB{0, 0, 0}, CMYK{1, 1, 1, 0.5}},
+	}
+
+	near := func(a, b Color) bool {
+		x, y := a.components(), b.components()
+		for i := range x {
+			if math.Abs(x[i]-y[i]) > 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+	for _, test := range tests {
+		if g := ToGray(test.c); !near(g, test.gray) {
+			t.Errorf("ToGray(%v): got %v expected %v", test.c, g, test.gray)
+		}
+		if c := ToRGB(test.c); !near(c, test.rgb) {
+			t.Errorf("ToRGB(%v): got %v expected %v", test.c, c, test.rgb)
+		}
+		if c := ToCMYK(test.c); !near(c, test.cmyk) {
+			t.Errorf("ToCMYK(%v): got %v expected %v", test.c, c, test.cmyk)
+		}
+	}
+}
+
+type parseColorTest struct {
+	s   string
+	c   RGB
+	err bool
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []parseColorTest{
+		{"#ff0000", RGB{1, 0, 0}, false},
+		{"#1A2B3C", RGB{0x1a / 255.0, 0x2b / 255.0, 0x3c / 255.0}, false},
+		{"00ff00", RGB{0, 1, 0}, false},
+		{"#fff", RGB{1, 1, 1}, false},
+		{"#08f", RGB{0, 0x88 / 255.0, 1}, false},
+		{"teal", RGB{0, 128 / 255.0, 128 / 255.0}, false},
+		{"RebeccaPurple", RGB{0x66 / 255.0, 0x33 / 255.0, 0x99 / 255.0}, false},
+		{"#ff00", RGB{}, true},
+		{"#gg0000", RGB{}, true},
+		{"#+12345", RGB{}, true},
+		{"", RGB{}, true},
+		{"nocolor", RGB{}, true},
+	}
+
+	for _, test := range tests {
+		c, err := ParseColor(test.s)
+		if test.err {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("ParseColor(%q): got %v expected ErrInvalid", test.s, err)
+			}
+			continue
+		}
+		if err != nil || c != test.c {
+			t.Errorf("ParseColor(%q): got %v, %v expected %v", test.s, c, err, test.c)
+		}
+	}
+}
+
+func TestHex(t *testing.T) {
+	for _, s := range []string{"#000000", "#1a2b3c", "#ffffff", "#7f8081"} {
+		c, err := ParseColor(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h := c.Hex(); h != s {
+			t.Errorf("Hex of %v: got %q expected %q", c, h, s)
+		}
+	}
+	if h := (RGB{-1, 0.5, 2}).Hex(); h != "#0080ff" {
+		t.Errorf("Hex out of range: got %q", h)
+	}
+	if len(NamedColors) != 148 {
+		t.Errorf("got %d named colors", len(NamedColors))
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the named colors of CSS and SVG.
+
+// NamedColors holds the colors of CSS and SVG by their names, in lower case.
+// ParseColor knows them too. Gray and grey are the same in all the names
+// with them.
+var NamedColors = map[string]RGB{
+	"aliceblue":            hexRGB(0xf0f8ff),
+	"antiquewhite":         hexRGB(0xfaebd7),
+	"aqua":                 hexRGB(0x00ffff),
+	"aquamarine":           hexRGB(0x7fffd4),
+	"azure":                hexRGB(0xf0ffff),
+	"beige":                hexRGB(0xf5f5dc),
+	"bisque":               hexRGB(0xffe4c4),
+	"black":                hexRGB(0x000000),
+	"blanchedalmond":       hexRGB(0xffebcd),
+	"blue":                 hexRGB(0x0000ff),
+	"blueviolet":           hexRGB(0x8a2be2),
+	"brown":                hexRGB(0xa52a2a),
+	"burlywood":            hexRGB(0xdeb887),
+	"cadetblue":            hexRGB(0x5f9ea0),
+	"chartreuse":           hexRGB(0x7fff00),
+	"chocolate":            hexRGB(0xd2691e),
+	"coral":                hexRGB(0xff7f50),
+	"cornflowerblue":       hexRGB(0x6495ed),
+	"cornsilk":             hexRGB(0xfff8dc),
+	"crimson":              hexRGB(0xdc143c),
+	"cyan":                 hexRGB(0x00ffff),
+	"darkblue":             hexRGB(0x00008b),
+	"darkcyan":             hexRGB(0x008b8b),
+	"darkgoldenrod":        hexRGB(0xb8860b),
+	"darkgray":             hexRGB(0xa9a9a9),
+	"darkgreen":            hexRGB(0x006400),
+	"darkgrey":             hexRGB(0xa9a9a9),
+	"darkkhaki":            hexRGB(0xbdb76b),
+	"darkmagenta":          hexRGB(0x8b008b),
+	"darkolivegreen":       hexRGB(0x556b2f),
+	"darkorange":           hexRGB(0xff8c00),
+	"darkorchid":           hexRGB(0x9932cc),
+	"darkred":              hexRGB(0x8b0000),
+	"darksalmon":           hexRGB(0xe9967a),
+	"darkseagreen":         hexRGB(0x8fbc8f),
+	"darkslateblue":        hexRGB(0x483d8b),
+	"darkslategray":        hexRGB(0x2f4f4f),
+	"darkslategrey":        hexRGB(0x2f4f4f),
+	"darkturquoise":        hexRGB(0x00ced1),
+	"darkviolet":           hexRGB(0x9400d3),
+	"deeppink":             hexRGB(0xff1493),
+	"deepskyblue":          hexRGB(0x00bfff),
+	"dimgray":              hexRGB(0x696969),
+	"dimgrey":              hexRGB(0x696969),
+	"dodgerblue":           hexRGB(0x1e90ff),
+	"firebrick":            hexRGB(0xb22222),
+	"floralwhite":          hexRGB(0xfffaf0),
+	"forestgreen":          hexRGB(0x228b22),
+	"fuchsia":              hexRGB(0xff00ff),
+	"gainsboro":            hexRGB(0xdcdcdc),
+	"ghostwhite":           hexRGB(0xf8f8ff),
+	"gold":                 hexRGB(0xffd700),
+	"goldenrod":            hexRGB(0xdaa520),
+	"gray":                 hexRGB(0x808080),
+	"green":                hexRGB(0x008000),
+	"greenyellow":          hexRGB(0xadff2f),
+	"grey":                 hexRGB(0x808080),
+	"honeydew":             hexRGB(0xf0fff0),
+	"hotpink":              hexRGB(0xff69b4),
+	"indianred":            hexRGB(0xcd5c5c),
+	"indigo":               hexRGB(0x4b0082),
+	"ivory":                hexRGB(0xfffff0),
+	"khaki":                hexRGB(0xf0e68c),
+	"lavender":             hexRGB(0xe6e6fa),
+	"lavenderblush":        hexRGB(0xfff0f5),
+	"lawngreen":            hexRGB(0x7cfc00),
+	"lemonchiffon":         hexRGB(0xfffacd),
+	"lightblue":            hexRGB(0xadd8e6),
+	"lightcoral":           hexRGB(0xf08080),
+	"lightcyan":            hexRGB(0xe0ffff),
+	"lightgoldenrodyellow": hexRGB(0xfafad2),
+	"lightgray":            hexRGB(0xd3d3d3),
+	"lightgreen":           hexRGB(0x90ee90),
+	"lightgrey":            hexRGB(0xd3d3d3),
+	"lightpink":            hexRGB(0xffb6c1),
+	"lightsalmon":          hexRGB(0xffa07a),
+	"lightseagreen":        hexRGB(0x20b2aa),
+	"lightskyblue":         hexRGB(0x87cefa),
+	"lightslategray":       hexRGB(0x778899),
+	"lightslategrey":       hexRGB(0x778899),
+	"lightsteelblue":       hexRGB(0xb0c4de),
+	"lightyellow":          hexRGB(0xffffe0),
+	"lime":                 hexRGB(0x00ff00),
+	"limegreen":            hexRGB(0x32cd32),
+	"linen":                hexRGB(0xfaf0e6),
+	"magenta":              hexRGB(0xff00ff),
+	"maroon":               hexRGB(0x800000),
+	"mediumaquamarine":     hexRGB(0x66cdaa),
+	"mediumblue":           hexRGB(0x0000cd),
+	"mediumorchid":         hexRGB(0xba55d3),
+	"mediumpurple":         hexRGB(0x9370db),
+	"mediumseagreen":       hexRGB(0x3cb371),
+	"mediumslateblue":      hexRGB(0x7b68ee),
+	"mediumspringgreen":    hexRGB(0x00fa9a),
+	"mediumturquoise":      hexRGB(0x48d1cc),
+	"mediumvioletred":      hexRGB(0xc71585),
+	"midnightblue":         hexRGB(0x191970),
+	"mintcream":            hexRGB(0xf5fffa),
+	"mistyrose":            hexRGB(0xffe4e1),
+	"moccasin":             hexRGB(0xffe4b5),
+	"navajowhite":          hexRGB(0xffdead),
+	"navy":                 hexRGB(0x000080),
+	"oldlace":              hexRGB(0xfdf5e6),
+	"olive":                hexRGB(0x808000),
+	"olivedrab":            hexRGB(0x6b8e23),
+	"orange":               hexRGB(0xffa500),
+	"orangered":            hexRGB(0xff4500),
+	"orchid":               hexRGB(0xda70d6),
+	"palegoldenrod":        hexRGB(0xeee8aa),
+	"palegreen":            hexRGB(0x98fb98),
+	"paleturquoise":        hexRGB(0xafeeee),
+	"palevioletred":        hexRGB(0xdb7093),
+	"papayawhip":           hexRGB(0xffefd5),
+	"peachpuff":            hexRGB(0xffdab9),
+	"peru":                 hexRGB(0xcd853f),
+	"pink":                 hexRGB(0xffc0cb),
+	"plum":                 hexRGB(0xdda0dd),
+	"powderblue":           hexRGB(0xb0e0e6),
+	"purple":               hexRGB(0x800080),
+	"rebeccapurple":        hexRGB(0x663399),
+	"red":                  hexRGB(0xff0000),
+	"rosybrown":            hexRGB(0xbc8f8f),
+	"royalblue":            hexRGB(0x4169e1),
+	"saddlebrown":          hexRGB(0x8b4513),
+	"salmon":               hexRGB(0xfa8072),
+	"sandybrown":           hexRGB(0xf4a460),
+	"seagreen":             hexRGB(0x2e8b57),
+	"seashell":             hexRGB(0xfff5ee),
+	"sienna":               hexRGB(0xa0522d),
+	"silver":               hexRGB(0xc0c0c0),
+	"skyblue":              hexRGB(0x87ceeb),
+	"slateblue":            hexRGB(0x6a5acd),
+	"slategray":            hexRGB(0x708090),
+	"slategrey":            hexRGB(0x708090),
+	"snow":                 hexRGB(0xfffafa),
+	"springgreen":          hexRGB(0x00ff7f),
+	"steelblue":            hexRGB(0x4682b4),
+	"tan":                  hexRGB(0xd2b48c),
+	"teal":                 hexRGB(0x008080),
+	"thistle":              hexRGB(0xd8bfd8),
+	"tomato":               hexRGB(0xff6347),
+	"turquoise":            hexRGB(0x40e0d0),
+	"violet":               hexRGB(0xee82ee),
+	"wheat":                hexRGB(0xf5deb3),
+	"white":                hexRGB(0xffffff),
+	"whitesmoke":           hexRGB(0xf5f5f5),
+	"yellow":               hexRGB(0xffff00),
+	"yellowgreen":          hexRGB(0x9acd32),
+}
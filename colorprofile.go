@@ -0,0 +1,476 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file converts colors with ICC profiles (ICC.1:2001-04), through the
+// CIE XYZ colors of the profile connection space. Version 4 profiles are read
+// too, as long as they use the tag types of version 2.
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+)
+
+// d50 is the white of the profile connection space.
+var d50 = [3]float64{0.9642, 1, 0.8249}
+
+// ColorProfile is an ICC profile of a gray, RGB or CMYK color space, which
+// converts colors to and from other profiles.
+type ColorProfile struct {
+	space   string                       // "GRAY", "RGB" or "CMYK"
+	toXYZ   func(v []float64) [3]float64 // nil if it can't be made
+	fromXYZ func(c [3]float64) []float64 // nil if it can't be made
+}
+
+// NewColorProfile returns the ICC profile p. Its tables (A2B0 and B2A0 of
+// types lut8 and lut16) are used if it has them, and its tone curves and
+// colorants otherwise, which most gray and RGB display profiles have. Tables
+// of types lutAtoB and lutBtoA are not supported.
+func NewColorProfile(p []byte) (cp *ColorProfile, err error) {
+	defer dontPanic(&err)
+
+	return newColorProfile(p), nil
+}
+
+// SRGBProfile returns the profile of sRGB, the color space of most screens
+// and images.
+func SRGBProfile() *ColorProfile {
+	return newColorProfile(srgbProfile())
+}
+
+// Convert returns c, which is a color of profile p, as a color of profile to.
+// The perceptual rendering intent is used by profiles with tables, and the
+// colorimetric one by the others.
+func (p *ColorProfile) Convert(c Color, to *ColorProfile) (cc Color, err error) {
+	defer dontPanic(&err)
+
+	v := c.components()
+	if len(v) != spaceComponents(p.space) {
+		panic("color is not in the color space of the profile")
+	}
+	if p.toXYZ == nil || to.fromXYZ == nil {
+		panic("profile has no tables for the conversion")
+	}
+	w := to.fromXYZ(p.toXYZ(v))
+	for i := range w {
+		w[i] = math.Max(0, math.Min(1, w[i]))
+	}
+	return colorOf(w), nil
+}
+
+// spaceComponents returns the number of components of colors in color
+// space s of ICC profiles.
+func spaceComponents(s string) int {
+	switch s {
+	case "GRAY":
+		return 1
+	case "RGB":
+		return 3
+	}
+	return 4
+}
+
+// iccData returns n bytes of profile p from offset off, panicking if p is too
+// short.
+func iccData(p []byte, off, n int) []byte {
+	if off < 0 || n < 0 || off+n > len(p) || off+n < off {
+		fail(ErrBadFile, "ICC profile data too short")
+	}
+	return p[off : off+n]
+}
+
+func iccU16(p []byte, off int) int {
+	return int(binary.BigEndian.Uint16(iccData(p, off, 2)))
+}
+
+func iccU32(p []byte, off int) int {
+	return int(binary.BigEndian.Uint32(iccData(p, off, 4)))
+}
+
+// iccFixed reads the s15Fixed16Number at offset off of p.
+func iccFixed(p []byte, off int) float64 {
+	return float64(int32(iccU32(p, off))) / 65536
+}
+
+// newColorProfile returns the color profile p, panicking if it's not
+// supported.
+func newColorProfile(p []byte) *ColorProfile {
+	if len(p) < 132 || string(p[36:40]) != "acsp" {
+		fail(ErrBadFile, "bad ICC profile")
+	}
+	cp := &ColorProfile{space: strings.TrimSpace(string(p[16:20]))}
+	if cp.space != "GRAY" && cp.space != "RGB" && cp.space != "CMYK" {
+		fail(ErrBadFile, "color space of ICC profile is not RGB, CMYK or gray")
+	}
+	lab := string(p[20:24]) == "Lab "
+	tags := make(map[string][]byte)
+	for i, n := 0, iccU32(p, 128); i < n; i++ {
+		e := 132 + 12*i
+		tags[string(iccData(p, e, 4))] = iccData(p, iccU32(p, e+4), iccU32(p, e+8))
+	}
+
+	if t, ok := tags["A2B0"]; ok {
+		l := parseLut(t, spaceComponents(cp.space), 3)
+		cp.toXYZ = func(v []float64) [3]float64 {
+			return l.decodePCS(l.apply(v, false), lab)
+		}
+	}
+	if t, ok := tags["B2A0"]; ok {
+		l := parseLut(t, 3, spaceComponents(cp.space))
+		cp.fromXYZ = func(c [3]float64) []float64 {
+			return l.apply(l.encodePCS(c, lab), !lab)
+		}
+	}
+	if cp.toXYZ != nil || cp.fromXYZ != nil {
+		return cp
+	}
+
+	switch cp.space {
+	case "GRAY":
+		if t, ok := tags["kTRC"]; ok {
+			trc := parseCurve(t)
+			inv := inverseCurve(trc)
+			cp.toXYZ = func(v []float64) [3]float64 {
+				y := trc(v[0])
+				return [3]float64{y * d50[0], y * d50[1], y * d50[2]}
+			}
+			cp.fromXYZ = func(c [3]float64) []float64 {
+				return []float64{inv(c[1])}
+			}
+		}
+	case "RGB":
+		var trc, inv [3]func(float64) float64
+		var m [9]float64
+		for i, c := range "rgb" {
+			xyz, ok := tags[string(c)+"XYZ"]
+			t, ok2 := tags[string(c)+"TRC"]
+			if !ok || !ok2 {
+				break
+			}
+			trc[i] = parseCurve(t)
+			inv[i] = inverseCurve(trc[i])
+			for j := 0; j < 3; j++ {
+				m[3*j+i] = iccFixed(xyz, 8+4*j)
+			}
+		}
+		if trc[2] == nil {
+			break
+		}
+		mi, ok := invert3(m)
+		if !ok {
+			fail(ErrBadFile, "colorants of ICC profile can't be inverted")
+		}
+		cp.toXYZ = func(v []float64) [3]float64 {
+			return mul3(m, [3]float64{trc[0](v[0]), trc[1](v[1]), trc[2](v[2])})
+		}
+		cp.fromXYZ = func(c [3]float64) []float64 {
+			l := mul3(mi, c)
+			return []float64{inv[0](l[0]), inv[1](l[1]), inv[2](l[2])}
+		}
+	}
+	if cp.toXYZ == nil {
+		fail(ErrBadFile, "ICC profile has no tables or tone curves")
+	}
+	return cp
+}
+
+// parseCurve returns the function of curveType or parametricCurveType tag
+// data t.
+func parseCurve(t []byte) func(float64) float64 {
+	switch string(iccData(t, 0, 4)) {
+	case "curv":
+		n := iccU32(t, 8)
+		switch n {
+		case 0:
+			return func(x float64) float64 { return x }
+		case 1:
+			g := float64(iccU16(t, 12)) / 256
+			return func(x float64) float64 { return math.Pow(x, g) }
+		}
+		tab := make([]float64, n)
+		for i := range tab {
+			tab[i] = float64(iccU16(t, 12+2*i)) / 65535
+		}
+		return func(x float64) float64 { return sample(tab, x) }
+	case "para":
+		f := iccU16(t, 8)
+		counts := []int{1, 3, 4, 5, 7}
+		if f >= len(counts) {
+			fail(ErrBadFile, "unknown parametric curve in ICC profile")
+		}
+		var v [7]float64
+		for i := 0; i < counts[f]; i++ {
+			v[i] = iccFixed(t, 12+4*i)
+		}
+		g, a, b, c, d, e, ff := v[0], v[1], v[2], v[3], v[4], v[5], v[6]
+		pow := func(x float64) float64 { return math.Pow(math.Max(0, x), g) }
+		switch f {
+		case 0:
+			return pow
+		case 1:
+			return func(x float64) float64 {
+				if x >= -b/a {
+					return pow(a*x + b)
+				}
+				return 0
+			}
+		case 2:
+			return func(x float64) float64 {
+				if x >= -b/a {
+					return pow(a*x+b) + c
+				}
+				return c
+			}
+		case 3:
+			return func(x float64) float64 {
+				if x >= d {
+					return pow(a*x + b)
+				}
+				return c * x
+			}
+		}
+		return func(x float64) float64 {
+			if x >= d {
+				return pow(a*x+b) + e
+			}
+			return c*x + ff
+		}
+	}
+	fail(ErrBadFile, "unknown curve type in ICC profile")
+	return nil
+}
+
+// inverseCurve returns the inverse of the monotonic curve f on [0, 1],
+// found by bisection.
+func inverseCurve(f func(float64) float64) func(float64) float64 {
+	up := f(1) >= f(0)
+	return func(y float64) float64 {
+		lo, hi := 0.0, 1.0
+		for i := 0; i < 32; i++ {
+			mid := (lo + hi) / 2
+			if (f(mid) < y) == up {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		return (lo + hi) / 2
+	}
+}
+
+// sample returns the value of the table tab of evenly spaced samples of
+// a function on [0, 1] at x, interpolated linearly.
+func sample(tab []float64, x float64) float64 {
+	if len(tab) == 1 {
+		return tab[0]
+	}
+	x = math.Max(0, math.Min(1, x)) * float64(len(tab)-1)
+	i := int(x)
+	if i >= len(tab)-1 {
+		return tab[len(tab)-1]
+	}
+	return tab[i] + (x-float64(i))*(tab[i+1]-tab[i])
+}
+
+// mul3 returns the product of the 3×3 matrix m, by rows, and vector v.
+func mul3(m [9]float64, v [3]float64) [3]float64 {
+	return [3]float64{
+		m[0]*v[0] + m[1]*v[1] + m[2]*v[2],
+		m[3]*v[0] + m[4]*v[1] + m[5]*v[2],
+		m[6]*v[0] + m[7]*v[1] + m[8]*v[2],
+	}
+}
+
+// invert3 returns the inverse of the 3×3 matrix m, and whether it has one.
+func invert3(m [9]float64) ([9]float64, bool) {
+	c := [9]float64{
+		m[4]*m[8] - m[5]*m[7], m[2]*m[7] - m[1]*m[8], m[1]*m[5] - m[2]*m[4],
+		m[5]*m[6] - m[3]*m[8], m[0]*m[8] - m[2]*m[6], m[2]*m[3] - m[0]*m[5],
+		m[3]*m[7] - m[4]*m[6], m[1]*m[6] - m[0]*m[7], m[0]*m[4] - m[1]*m[3],
+	}
+	det := m[0]*c[0] + m[1]*c[3] + m[2]*c[6]
+	if det == 0 {
+		return c, false
+	}
+	for i := range c {
+		c[i] /= det
+	}
+	return c, true
+}
+
+// iccLut is a table of an ICC profile of type lut8 or lut16. All its values
+// are scaled to [0, 1].
+type iccLut struct {
+	wide      bool       // lut16
+	in, out   int        // number of input and output channels
+	grid      int        // number of points of the table in each dimension
+	mat       [9]float64 // applied to XYZ inputs
+	inCurves  [][]float64
+	clut      []float64
+	outCurves [][]float64
+}
+
+// parseLut returns the lut8 or lut16 tag data t, which should have in input
+// and out output channels.
+func parseLut(t []byte, in, out int) *iccLut {
+	l := &iccLut{}
+	switch string(iccData(t, 0, 4)) {
+	case "mft1":
+	case "mft2":
+		l.wide = true
+	default:
+		fail(ErrBadFile, "unsupported table type in ICC profile")
+	}
+	l.in, l.out, l.grid = int(iccData(t, 8, 1)[0]), int(t[9]), int(iccData(t, 10, 1)[0])
+	if l.in != in || l.out != out || l.grid < 2 {
+		fail(ErrBadFile, "bad table in ICC profile")
+	}
+	for i := range l.mat {
+		l.mat[i] = iccFixed(t, 12+4*i)
+	}
+
+	off, inN, outN, size := 48, 256, 256, 1
+	if l.wide {
+		inN, outN, off = iccU16(t, 48), iccU16(t, 50), 52
+	}
+	read := func(n int) []float64 {
+		v := make([]float64, n)
+		for i := range v {
+			if l.wide {
+				v[i] = float64(iccU16(t, off)) / 65535
+				off += 2
+			} else {
+				v[i] = float64(iccData(t, off, 1)[0]) / 255
+				off++
+			}
+		}
+		return v
+	}
+	for i := 0; i < in; i++ {
+		l.inCurves = append(l.inCurves, read(inN))
+	}
+	for i := 0; i < in; i++ {
+		size *= l.grid
+	}
+	l.clut = read(size * out)
+	for i := 0; i < out; i++ {
+		l.outCurves = append(l.outCurves, read(outN))
+	}
+	return l
+}
+
+// apply returns the result of l for input v, multiplied by the matrix of l
+// first if mat is true.
+func (l *iccLut) apply(v []float64, mat bool) []float64 {
+	if mat {
+		m := mul3(l.mat, [3]float64{v[0], v[1], v[2]})
+		v = m[:]
+	}
+	idx := make([]int, l.in)
+	frac := make([]float64, l.in)
+	for i := 0; i < l.in; i++ {
+		x := sample(l.inCurves[i], v[i]) * float64(l.grid-1)
+		idx[i] = int(math.Min(x, float64(l.grid-2)))
+		frac[i] = x - float64(idx[i])
+	}
+
+	// Values at the corners of the cell of the grid around the input are
+	// interpolated linearly in all the dimensions. The first input changes
+	// the least from one point of the table to the next.
+	w := make([]float64, l.out)
+	for corner := 0; corner < 1<<l.in; corner++ {
+		weight, p := 1.0, 0
+		for i := 0; i < l.in; i++ {
+			j := idx[i]
+			if corner>>(l.in-1-i)&1 == 1 {
+				j++
+				weight *= frac[i]
+			} else {
+				weight *= 1 - frac[i]
+			}
+			p = p*l.grid + j
+		}
+		for o := range w {
+			w[o] += weight * l.clut[p*l.out+o]
+		}
+	}
+	for o := range w {
+		w[o] = sample(l.outCurves[o], w[o])
+	}
+	return w
+}
+
+// decodePCS returns the XYZ color of the profile connection space values v
+// of l, which are Lab if lab is true.
+func (l *iccLut) decodePCS(v []float64, lab bool) [3]float64 {
+	if !lab {
+		s := 65535.0 / 32768
+		return [3]float64{v[0] * s, v[1] * s, v[2] * s}
+	}
+	s := 1.0
+	if l.wide {
+		s = 65535.0 / 65280
+	}
+	return labToXYZ(v[0]*s*100, v[1]*s*255-128, v[2]*s*255-128)
+}
+
+// encodePCS returns the profile connection space values of l for XYZ color
+// c, which are Lab if lab is true. It's the inverse of decodePCS.
+func (l *iccLut) encodePCS(c [3]float64, lab bool) []float64 {
+	if !lab {
+		s := 32768.0 / 65535
+		return []float64{c[0] * s, c[1] * s, c[2] * s}
+	}
+	s := 1.0
+	if l.wide {
+		s = 65280.0 / 65535
+	}
+	L, a, b := xyzToLab(c)
+	return []float64{L / 100 * s, (a + 128) / 255 * s, (b + 128) / 255 * s}
+}
+
+// labToXYZ returns the XYZ color of CIE L*a*b* color L, a, b, relative to the
+// D50 white.
+func labToXYZ(L, a, b float64) [3]float64 {
+	fy := (L + 16) / 116
+	f := [3]float64{fy + a/500, fy, fy - b/200}
+	for i, v := range f {
+		if v > 6.0/29 {
+			f[i] = v * v * v
+		} else {
+			f[i] = 3 * 6.0 / 29 * 6.0 / 29 * (v - 4.0/29)
+		}
+		f[i] *= d50[i]
+	}
+	return f
+}
+
+// xyzToLab returns the CIE L*a*b* color of XYZ color c, relative to the D50
+// white.
+func xyzToLab(c [3]float64) (L, a, b float64) {
+	var f [3]float64
+	for i := range f {
+		v := c[i] / d50[i]
+		if v > 216.0/24389 {
+			f[i] = math.Cbrt(v)
+		} else {
+			f[i] = v/(3*6.0/29*6.0/29) + 4.0/29
+		}
+	}
+	return 116*f[1] - 16, 500 * (f[0] - f[1]), 200 * (f[1] - f[2])
+}
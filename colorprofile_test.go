@@ -0,0 +1,176 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+// testICC returns a profile of color space space with the given profile
+// connection space and tags.
+func testICC(space, pcs string, tags []iccTag) []byte {
+	table := bytes.NewBuffer(nil)
+	data := bytes.NewBuffer(nil)
+	binary.Write(table, binary.BigEndian, uint32(len(tags)))
+	for _, t := range tags {
+		table.WriteString(t.sig)
+		binary.Write(table, binary.BigEndian, []uint32{
+			uint32(128 + 4 + 12*len(tags) + data.Len()), uint32(len(t.data))})
+		data.Write(t.data)
+	}
+	h := make([]byte, 128)
+	binary.BigEndian.PutUint32(h, uint32(128+table.Len()+data.Len()))
+	copy(h[12:], "prtr"+space+"    "[len(space):]+pcs)
+	copy(h[36:], "acsp")
+	return append(append(h, table.Bytes()...), data.Bytes()...)
+}
+
+// testLut16 returns a lut16 table with 2 points in each dimension and no
+// curves, whose values at the corners are f of the corners.
+func testLut16(in, out int, f func(v []float64) []float64) []byte {
+	b := bytes.NewBuffer([]byte("mft2\x00\x00\x00\x00"))
+	b.Write([]byte{byte(in), byte(out), 2, 0})
+	for i := 0; i < 9; i++ {
+		m := 0.0
+		if i%4 == 0 {
+			m = 1
+		}
+		binary.Write(b, binary.BigEndian, s15Fixed16(m))
+	}
+	binary.Write(b, binary.BigEndian, []uint16{2, 2})
+	for i := 0; i < in; i++ {
+		binary.Write(b, binary.BigEndian, []uint16{0, 0xffff})
+	}
+	for c := 0; c < 1<<in; c++ {
+		v := make([]float64, in)
+		for i := range v {
+			v[i] = float64(c >> (in - 1 - i) & 1)
+		}
+		for _, w := range f(v) {
+			w = math.Max(0, math.Min(1, w))
+			binary.Write(b, binary.BigEndian, uint16(math.Floor(w*0xffff+0.5)))
+		}
+	}
+	for i := 0; i < out; i++ {
+		binary.Write(b, binary.BigEndian, []uint16{0, 0xffff})
+	}
+	return b.Bytes()
+}
+
+// testCMYKProfile returns a CMYK profile where only K makes colors darker,
+// and colors are made with K only.
+func testCMYKProfile(both bool) []byte {
+	const lab = 65280.0 / 65535 // lut16 encoding of L* 100
+	tags := []iccTag{
+		{"A2B0", testLut16(4, 3, func(v []float64) []float64 {
+			return []float64{(1 - v[3]) * lab, 128 / 255.0 * lab, 128 / 255.0 * lab}
+		})},
+	}
+	if both {
+		tags = append(tags, iccTag{"B2A0", testLut16(3, 4, func(v []float64) []float64 {
+			return []float64{0, 0, 0, 1 - v[0]/lab}
+		})})
+	}
+	return testICC("CMYK", "Lab ", tags)
+}
+
+type profileTest struct {
+	from, to []byte
+	c        Color
+	out      Color
+}
+
+func TestColorProfile(t *testing.T) {
+	linear := testICC("GRAY", "XYZ ", []iccTag{{"kTRC", []byte("curv\x00\x00\x00\x00\x00\x00\x00\x00")}})
+	gamma2 := testICC("GRAY", "XYZ ", []iccTag{{"kTRC", []byte("para\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00")}})
+	srgb := srgbProfile()
+	cmyk := testCMYKProfile(true)
+	tests := []profileTest{
+		{srgb, srgb, RGB{0.2, 0.5, 0.8}, RGB{0.2, 0.5, 0.8}},
+		{srgb, linear, RGB{1, 0, 0}, Gray(0.2225)},
+		{srgb, linear, RGB{1, 1, 1}, Gray(1)},
+		{linear, srgb, Gray(0.2140), RGB{0.5, 0.5, 0.5}},
+		{gamma2, linear, Gray(0.5), Gray(0.25)},
+		{linear, gamma2, Gray(0.25), Gray(0.5)},
+		{cmyk, srgb, CMYK{0, 0, 0, 0}, RGB{1, 1, 1}},
+		{cmyk, srgb, CMYK{0.5, 0.5, 0, 1}, RGB{0, 0, 0}},
+		{cmyk, linear, CMYK{0, 0, 0, 0.5}, Gray(0.1842)},
+		{srgb, cmyk, RGB{1, 1, 1}, CMYK{0, 0, 0, 0}},
+		{srgb, cmyk, RGB{0.5, 0.5, 0.5}, CMYK{0, 0, 0, 0.466}},
+		{srgb, cmyk, RGB{0, 0, 0}, CMYK{0, 0, 0, 1}},
+	}
+
+	for i, test := range tests {
+		from, err := NewColorProfile(test.from)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		to, err := NewColorProfile(test.to)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		c, err := from.Convert(test.c, to)
+		if err != nil {
+			t.Errorf("%d: converting %v: %v", i, test.c, err)
+			continue
+		}
+		got, want := c.components(), test.out.components()
+		if len(got) != len(want) {
+			t.Errorf("%d: converting %v: got %v expected %v", i, test.c, c, test.out)
+			continue
+		}
+		for j := range got {
+			if math.Abs(got[j]-want[j]) > 0.005 {
+				t.Errorf("%d: converting %v: got %v expected %v", i, test.c, c, test.out)
+				break
+			}
+		}
+	}
+}
+
+func TestColorProfileErrors(t *testing.T) {
+	if _, err := NewColorProfile([]byte("not a profile")); !errors.Is(err, ErrBadFile) {
+		t.Errorf("NewColorProfile of bad data: got %v", err)
+	}
+	if _, err := NewColorProfile(testICC("GRAY", "XYZ ", nil)); !errors.Is(err, ErrBadFile) {
+		t.Errorf("NewColorProfile with no curves: got %v", err)
+	}
+	p := srgbProfile()
+	if _, err := NewColorProfile(p[:len(p)-100]); !errors.Is(err, ErrBadFile) {
+		t.Errorf("NewColorProfile of short profile: got %v", err)
+	}
+
+	srgb := SRGBProfile()
+	if _, err := srgb.Convert(CMYK{0, 0, 0, 1}, srgb); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Convert of CMYK with sRGB: got %v", err)
+	}
+	// With no B2A0, colors can be converted from the profile but not to it.
+	cmyk, err := NewColorProfile(testCMYKProfile(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmyk.Convert(CMYK{0, 0, 0, 1}, srgb); err != nil {
+		t.Errorf("Convert from CMYK: %v", err)
+	}
+	if _, err := srgb.Convert(RGB{0, 0, 0}, cmyk); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Convert to CMYK with no B2A0: got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file checks documents against the rules of the standards they are made
+// to conform to, like PDF/A. Objects are checked as they are written, and
+// documents that break any rule can't be closed.
+
+import (
+	"sort"
+	"strings"
+)
+
+// walk calls f for every dictionary in v, including the dictionaries inside
+// other dictionaries and arrays, and the dictionaries of streams. Indirect
+// objects are not followed; they are checked when they are written.
+func walk(v interface{}, f func(map[string]interface{})) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		f(t)
+		for _, e := range t {
+			walk(e, f)
+		}
+	case []interface{}:
+		for _, e := range t {
+			walk(e, f)
+		}
+	case *stream:
+		if t.dic != nil {
+			walk(t.dic, f)
+		}
+	case *indirect:
+	case objecter:
+		walk(t.object(), f)
+	}
+}
+
+// violate records that the document breaks a rule of standard std.
+func (d *Document) violate(std, rule string) {
+	if d.violations == nil {
+		d.violations = make(map[string]bool)
+	}
+	d.violations[std+": "+rule] = true
+}
+
+// checkObject checks o, which is about to be written, against the standards
+// of the document.
+func (d *Document) checkObject(o interface{}) {
+	if d.pdfa {
+		walk(o, d.checkPDFA)
+	}
+	if d.pdfx != "" {
+		walk(o, d.checkPDFX)
+	}
+	if d.pdfua {
+		walk(o, d.checkPDFUA)
+	}
+}
+
+// checkPage checks page p, which is about to be written, against the
+// standards of the document.
+func (d *Document) checkPage(p *page) {
+	if d.pdfx != "" {
+		d.checkPDFXPage(p)
+	}
+}
+
+// checkViolations panics if the document breaks any rule.
+func (d *Document) checkViolations() {
+	if d.pdfx != "" && (d.info == nil || d.info.Title == "") {
+		d.violate(d.pdfx, "documents should have a title")
+	}
+	if d.pdfua && (d.info == nil || d.info.Title == "") {
+		d.violate("PDF/UA-1", "documents should have a title")
+	}
+	if len(d.violations) == 0 {
+		return
+	}
+	v := make([]string, 0, len(d.violations))
+	for r := range d.violations {
+		v = append(v, r)
+	}
+	sort.Strings(v)
+	panic("document doesn't conform to its standards: " + strings.Join(v, "; "))
+}
+
+// deviceColors tells whether the dictionary m uses RGB or CMYK colors, in
+// the default appearance or the appearance characteristics of a field, or as
+// the color space of an image.
+func deviceColors(m map[string]interface{}) (rgb, cmyk bool) {
+	if cs, ok := m["ColorSpace"].(name); ok {
+		rgb = cs == "DeviceRGB"
+		cmyk = cs == "DeviceCMYK"
+	}
+	if da, ok := m["DA"].(string); ok {
+		rgb = strings.HasSuffix(da, " rg")
+		cmyk = strings.HasSuffix(da, " k")
+	}
+	if mk, ok := m["MK"].(map[string]interface{}); ok {
+		for _, k := range []string{"BC", "BG"} {
+			if c, ok := mk[k].([]float64); ok {
+				rgb = rgb || len(c) == 3
+				cmyk = cmyk || len(c) == 4
+			}
+		}
+	}
+	return
+}
+
+// contentColors tells whether the content stream b uses RGB or CMYK colors,
+// set by operators like rg and k, or by the device color spaces.
+func contentColors(b []byte) (rgb, cmyk bool) {
+	var ops []operation
+	// Content that can't be parsed is left to the viewers.
+	if recovered(func() { ops = operations(b) }) != nil {
+		return false, false
+	}
+	for _, op := range ops {
+		switch op.op {
+		case "rg", "RG":
+			rgb = true
+		case "k", "K":
+			cmyk = true
+		case "cs", "CS":
+			if len(op.args) == 1 {
+				rgb = rgb || op.args[0] == name("DeviceRGB")
+				cmyk = cmyk || op.args[0] == name("DeviceCMYK")
+			}
+		}
+	}
+	return
+}
+
+// checkContentColors checks the colors of the content stream b against the
+// output intent of the document, for the standards that restrict them.
+func (d *Document) checkContentColors(b []byte) {
+	rgb, cmyk := contentColors(b)
+	if d.pdfa {
+		d.checkSpaces("PDF/A-3", rgb, cmyk)
+	}
+	if d.pdfx != "" {
+		d.checkSpaces(d.pdfx, rgb, cmyk)
+	}
+}
+
+// checkColors checks the colors of the dictionary m against the output
+// intent of the document. Gray can be used with any output intent.
+func (d *Document) checkColors(std string, m map[string]interface{}) {
+	rgb, cmyk := deviceColors(m)
+	d.checkSpaces(std, rgb, cmyk)
+}
+
+// checkSpaces records that the document breaks a rule of standard std if it
+// uses RGB or CMYK colors, as rgb and cmyk tell, that its output intent is
+// not for.
+func (d *Document) checkSpaces(std string, rgb, cmyk bool) {
+	space := d.intentSpace()
+	if rgb && space != "RGB" {
+		d.violate(std, "RGB colors need an RGB output intent")
+	}
+	if cmyk && space != "CMYK" {
+		d.violate(std, "CMYK colors need a CMYK output intent")
+	}
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains ContentWriter, which writes the operators of content
+// streams (p. 151) straight into a buffer, with no formatting of strings.
+
+import (
+	"strconv"
+)
+
+// ContentWriter writes operators of content streams into a buffer. Its
+// methods return the writer, so that they can be chained:
+//
+//	w.MoveTo(0, 0).LineTo(100, 100).Stroke()
+//
+// The zero value is an empty writer ready to use. The buffer is kept when
+// the writer is reset, so that a writer can be reused with no more
+// allocations.
+type ContentWriter struct {
+	b    []byte
+	prec int     // digits after the point of numbers; 3 if zero
+	t    tracker // current point and what's painted
+}
+
+// NewContentWriter returns a writer that appends operators to b.
+func NewContentWriter(b []byte) *ContentWriter {
+	return &ContentWriter{b: b}
+}
+
+// Bytes returns the content written by w. It's valid until w is changed.
+func (w *ContentWriter) Bytes() []byte {
+	return w.b
+}
+
+// Len returns the length of the content written by w.
+func (w *ContentWriter) Len() int {
+	return len(w.b)
+}
+
+// Reset empties w, keeping its buffer. The current point and the bounds of
+// what's painted are kept too, since the content written before is usually
+// added to a page already.
+func (w *ContentWriter) Reset() *ContentWriter {
+	w.b = w.b[:0]
+	return w
+}
+
+// Write appends b, which should hold complete operators, to the content.
+func (w *ContentWriter) Write(b []byte) (int, error) {
+	w.b = append(w.b, b...)
+	return len(b), nil
+}
+
+// maxPrecision is the most digits after the point of numbers written by
+// ContentWriters, which is more than viewers can tell apart.
+const maxPrecision = 10
+
+// Precision sets the number of digits after the point of the numbers
+// written after it to n, or to 3 if n is zero or negative. More than 10 digits
+// are written as 10, like Options.Precision.
+func (w *ContentWriter) Precision(n int) *ContentWriter {
+	if n < 0 {
+		n = 0
+	}
+	if n > maxPrecision {
+		n = maxPrecision
+	}
+	w.prec = n
+	return w
+}
+
+// Int writes the operand i.
+func (w *ContentWriter) Int(i int) *ContentWriter {
+	w.b = append(strconv.AppendInt(w.b, int64(i), 10), ' ')
+	return w
+}
+
+// Float writes the operand f, with at most three digits after the point,
+// unless the precision of w is changed.
+func (w *ContentWriter) Float(f float64) *ContentWriter {
+	w.b = append(appendFloat(w.b, f, w.precision()), ' ')
+	return w
+}
+
+// precision returns the number of digits after the point of numbers written
+// by w.
+func (w *ContentWriter) precision() int {
+	if w.prec == 0 {
+		return 3
+	}
+	return w.prec
+}
+
+// Name writes the name operand n.
+func (w *ContentWriter) Name(n string) *ContentWriter {
+	w.b = append(append(w.b, '/'), escapeName(n)...)
+	w.b = append(w.b, ' ')
+	return w
+}
+
+// String writes the string operand s.
+func (w *ContentWriter) String(s string) *ContentWriter {
+	w.b = append(appendEscaped(append(w.b, '('), s), ") "...)
+	return w
+}
+
+// Op writes the operator op, which ends the operands written before it.
+func (w *ContentWriter) Op(op string) *ContentWriter {
+	w.b = append(append(w.b, op...), '\n')
+	return w
+}
+
+// appendFloat appends f to b like ftoa, with prec digits after the point
+// at most.
+func appendFloat(b []byte, f float64, prec int) []byte {
+	n := len(b)
+	b = strconv.AppendFloat(b, f, 'f', prec, 64)
+	if prec > 0 {
+		for b[len(b)-1] == '0' {
+			b = b[:len(b)-1]
+		}
+		if b[len(b)-1] == '.' {
+			b = b[:len(b)-1]
+		}
+	}
+	if string(b[n:]) == "-0" {
+		b = append(b[:n], '0')
+	}
+	return b
+}
+
+// points writes the coordinates of the given points as operands.
+func (w *ContentWriter) points(p ...float64) *ContentWriter {
+	for _, f := range p {
+		w.Float(f)
+	}
+	return w
+}
+
+// LineWidth changes the width of the lines to be drawn after it.
+func (w *ContentWriter) LineWidth(lw float64) *ContentWriter {
+	return w.Float(lw).Op("w")
+}
+
+// LineCapStyle changes line cap style to LineCapBut, LineCapRound, or
+// LineCapProjecting.
+func (w *ContentWriter) LineCapStyle(s int) *ContentWriter {
+	return w.Int(s).Op("J")
+}
+
+// LineJoinStyle changes line join style to LineJoinMiter, LineJoinRound, or
+// LineJoinBevel.
+func (w *ContentWriter) LineJoinStyle(s int) *ContentWriter {
+	return w.Int(s).Op("j")
+}
+
+// MoveTo starts a new path at the given point.
+func (w *ContentWriter) MoveTo(x, y float64) *ContentWriter {
+	w.t.op("m", x, y)
+	return w.points(x, y).Op("m")
+}
+
+// LineTo draws a single line from the current point to the given point.
+func (w *ContentWriter) LineTo(x, y float64) *ContentWriter {
+	w.t.op("l", x, y)
+	return w.points(x, y).Op("l")
+}
+
+// Curve draws a bézier curve from current point to point (x2, y2) using
+// (x0, y0) and (x1, y1) as control points.
+func (w *ContentWriter) Curve(x0, y0, x1, y1, x2, y2 float64) *ContentWriter {
+	w.t.op("c", x0, y0, x1, y1, x2, y2)
+	return w.points(x0, y0, x1, y1, x2, y2).Op("c")
+}
+
+// CurveV draws a bézier curve from current point to point (x1, y1) using
+// current point and (x0, y0) as control points.
+func (w *ContentWriter) CurveV(x0, y0, x1, y1 float64) *ContentWriter {
+	w.t.op("v", x0, y0, x1, y1)
+	return w.points(x0, y0, x1, y1).Op("v")
+}
+
+// CurveY draws a bézier curve from current point to point (x1, y1) using
+// (x0, y0) and current point as control points.
+func (w *ContentWriter) CurveY(x0, y0, x1, y1 float64) *ContentWriter {
+	w.t.op("y", x0, y0, x1, y1)
+	return w.points(x0, y0, x1, y1).Op("y")
+}
+
+// Rectangle draws a rectangle with lower-left corner at (x, y).
+func (w *ContentWriter) Rectangle(x, y, rw, rh float64) *ContentWriter {
+	w.t.op("re", x, y, rw, rh)
+	return w.points(x, y, rw, rh).Op("re")
+}
+
+// Circle draws a circle with center (x, y) and radius r, made of four
+// bézier curves.
+func (w *ContentWriter) Circle(x, y, r float64) *ContentWriter {
+	// Distance of the control points from the ends of quarter circles.
+	k := r * 0.5523
+	return w.MoveTo(x+r, y).Curve(x+r, y+k, x+k, y+r, x, y+r).
+		Curve(x-k, y+r, x-r, y+k, x-r, y).Curve(x-r, y-k, x-k, y-r, x, y-r).
+		Curve(x+k, y-r, x+r, y-k, x+r, y).ClosePath()
+}
+
+// ClosePath closes the current active path by drawing a straight line from
+// current point to the beginning of the path.
+func (w *ContentWriter) ClosePath() *ContentWriter {
+	w.t.op("h")
+	return w.Op("h")
+}
+
+// Stroke paints the current path with stroke.
+func (w *ContentWriter) Stroke() *ContentWriter {
+	w.t.op("S")
+	return w.Op("S")
+}
+
+// Fill paints inside of the current path.
+func (w *ContentWriter) Fill() *ContentWriter {
+	w.t.op("f")
+	return w.Op("f")
+}
+
+// SaveState saves the graphics state, to be restored by RestoreState.
+func (w *ContentWriter) SaveState() *ContentWriter {
+	w.t.op("q")
+	return w.Op("q")
+}
+
+// RestoreState restores the graphics state saved by the last SaveState.
+func (w *ContentWriter) RestoreState() *ContentWriter {
+	w.t.op("Q")
+	return w.Op("Q")
+}
+
+// Transform changes the coordinates of what's drawn after it by the matrix
+// [a b c d e f] (p. 204).
+func (w *ContentWriter) Transform(a, b, c, d, e, f float64) *ContentWriter {
+	w.t.op("cm", a, b, c, d, e, f)
+	return w.points(a, b, c, d, e, f).Op("cm")
+}
+
+// StrokeColor changes the color of the lines drawn after it.
+func (w *ContentWriter) StrokeColor(c Color) *ContentWriter {
+	return w.color(c, true)
+}
+
+// FillColor changes the color of the shapes filled after it.
+func (w *ContentWriter) FillColor(c Color) *ContentWriter {
+	return w.color(c, false)
+}
+
+// color writes the operator that sets c as the fill color, or as the stroke
+// color if stroke is true.
+func (w *ContentWriter) color(c Color, stroke bool) *ContentWriter {
+	w.b = append(appendColor(w.b, c, stroke, w.precision()), '\n')
+	return w
+}
+
+// Dash changes the lines drawn after it to dashed lines, with dashes and
+// gaps of the lengths in a, starting phase into the pattern (p. 217). Lines
+// are solid if a is empty.
+func (w *ContentWriter) Dash(a []float64, phase float64) *ContentWriter {
+	w.b = append(w.b, '[')
+	for i, f := range a {
+		if i > 0 {
+			w.b = append(w.b, ' ')
+		}
+		w.b = appendFloat(w.b, f, w.precision())
+	}
+	w.b = append(w.b, "] "...)
+	return w.Float(phase).Op("d")
+}
+
+// BeginText begins a text object, which is ended by EndText. Text is shown
+// only inside text objects.
+func (w *ContentWriter) BeginText() *ContentWriter {
+	return w.Op("BT")
+}
+
+// EndText ends the text object begun by BeginText.
+func (w *ContentWriter) EndText() *ContentWriter {
+	return w.Op("ET")
+}
+
+// Font changes the font of the text shown after it to the font with the
+// given name in the resources, in the given size.
+func (w *ContentWriter) Font(n string, size float64) *ContentWriter {
+	return w.Name(n).Float(size).Op("Tf")
+}
+
+// TextPosition moves the start of the next line of text by (x, y) from the
+// start of the current line, or from the origin at the beginning of the
+// text object.
+func (w *ContentWriter) TextPosition(x, y float64) *ContentWriter {
+	return w.points(x, y).Op("Td")
+}
+
+// ShowText shows the string s, which should be encoded like the font, at
+// the current position of the text.
+func (w *ContentWriter) ShowText(s string) *ContentWriter {
+	return w.String(s).Op("Tj")
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"testing"
+)
+
+type contentWriterTest struct {
+	write func(w *ContentWriter)
+	out   string
+}
+
+func TestContentWriter(t *testing.T) {
+	tests := []contentWriterTest{
+		{func(w *ContentWriter) { w.MoveTo(0, 0).LineTo(100, 50.5).Stroke() },
+			"0 0 m\n100 50.5 l\nS\n"},
+		{func(w *ContentWriter) { w.LineWidth(0.5).LineCapStyle(LineCapRound).LineJoinStyle(2) },
+			"0.5 w\n1 J\n2 j\n"},
+		{func(w *ContentWriter) { w.Curve(1, 2, 3, 4, 5, 6).CurveV(1, 2, 3, 4).CurveY(-1, -2, 1.0/3, 4) },
+			"1 2 3 4 5 6 c\n1 2 3 4 v\n-1 -2 0.333 4 y\n"},
+		{func(w *ContentWriter) { w.Rectangle(1, 1, 2, 2).ClosePath().Fill() },
+			"1 1 2 2 re\nh\nf\n"},
+		{func(w *ContentWriter) { w.Circle(0, 0, 1) },
+			"1 0 m\n1 0.552 0.552 1 0 1 c\n-0.552 1 -1 0.552 -1 0 c\n-1 -0.552 -0.552 -1 0 -1 c\n0.552 -1 1 -0.552 1 0 c\nh\n"},
+		{func(w *ContentWriter) { w.SaveState().Transform(2, 0, 0, 2, 10, -0.0001).RestoreState() },
+			"q\n2 0 0 2 10 0 cm\nQ\n"},
+		{func(w *ContentWriter) { w.Name("X 1").Op("Do").Int(-3).Op("Tz") },
+			"/X#201 Do\n-3 Tz\n"},
+		{func(w *ContentWriter) { w.String("a (b)\\").Op("Tj") },
+			"(a \\(b\\)\\\\) Tj\n"},
+		{func(w *ContentWriter) { w.FillColor(RGB{1, 0.5, 0}).StrokeColor(Gray(0.25)).Dash([]float64{3, 1.5}, 1) },
+			"1 0.5 0 rg\n0.25 G\n[3 1.5] 1 d\n"},
+		{func(w *ContentWriter) {
+			w.BeginText().Font("Helv", 10.5).TextPosition(72, -12).ShowText("(a)").EndText()
+		},
+			"BT\n/Helv 10.5 Tf\n72 -12 Td\n(\\(a\\)) Tj\nET\n"},
+		{func(w *ContentWriter) { w.Precision(-1).Float(1.23456).Precision(20).Float(1.0 / 3).Precision(0) },
+			"1.235 0.3333333333 "},
+	}
+
+	w := new(ContentWriter)
+	for _, test := range tests {
+		test.write(w.Reset())
+		if s := string(w.Bytes()); s != test.out {
+			t.Errorf("got %q expected %q", s, test.out)
+		}
+		if w.Len() != len(test.out) {
+			t.Errorf("length: got %d expected %d", w.Len(), len(test.out))
+		}
+	}
+
+	w = NewContentWriter([]byte("q\n"))
+	if s := string(w.RestoreState().Bytes()); s != "q\nQ\n" {
+		t.Errorf("appending: got %q", s)
+	}
+
+	// A reused writer doesn't allocate.
+	n := testing.AllocsPerRun(100, func() {
+		w.Reset().MoveTo(1.5, 2).LineTo(300, 400.25).Stroke()
+	})
+	if n > 0 {
+		t.Errorf("%v allocations per run", n)
+	}
+}
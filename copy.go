@@ -0,0 +1,110 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file copies objects of existing files to new documents.
+
+// copier copies objects of a Reader to a Document, giving them new numbers.
+// Objects are copied with all the objects they refer to, except for pages and
+// page tree nodes, which are copied only if they are added by addPage.
+// References to other pages become null.
+type copier struct {
+	r     *Reader
+	d     *Document
+	refs  map[int]*indirect   // new objects of the objects of r, by number
+	over  map[int]interface{} // values to be written in place of objects of r
+	queue []int               // objects reserved but not written yet
+}
+
+func newCopier(d *Document, r *Reader) *copier {
+	return &copier{r: r, d: d, refs: make(map[int]*indirect), over: make(map[int]interface{})}
+}
+
+// ref returns the new object of object num of r, reserving it the first time.
+func (c *copier) ref(num int) interface{} {
+	if i, ok := c.refs[num]; ok {
+		return i
+	}
+	if m, ok := c.r.object(num).(map[string]interface{}); ok &&
+		(m["Type"] == name("Page") || m["Type"] == name("Pages")) {
+		return nil
+	}
+	i := c.d.reserveIndirect()
+	c.refs[num] = i
+	c.queue = append(c.queue, num)
+	return i
+}
+
+// value returns a copy of v with the references of r replaced by the new
+// ones.
+func (c *copier) value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case ref:
+		return c.ref(t.num)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = c.value(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = c.value(e)
+		}
+		return a
+	case *stream:
+		return &stream{c.value(t.dic).(map[string]interface{}), t.buf}
+	}
+	return v
+}
+
+// override makes the value of object num of r be v in the new document.
+func (c *copier) override(num int, v interface{}) *indirect {
+	c.over[num] = v
+	if i, ok := c.refs[num]; ok {
+		return i
+	}
+	i := c.d.reserveIndirect()
+	c.refs[num] = i
+	c.queue = append(c.queue, num)
+	return i
+}
+
+// addPage adds page p of r to the end of the document.
+func (c *copier) addPage(p rpage) {
+	c.d.setPage(nil)
+	dic := make(map[string]interface{}, len(p.dic))
+	for k, v := range p.dic {
+		dic[k] = v
+	}
+	dic["Parent"] = c.d.ptree
+	c.d.pgs = append(c.d.pgs, c.override(p.num, dic))
+}
+
+// flush writes the objects reserved by ref to the document.
+func (c *copier) flush() {
+	for len(c.queue) > 0 {
+		num := c.queue[0]
+		c.queue = c.queue[1:]
+		o, ok := c.over[num]
+		if !ok {
+			o = c.r.object(num)
+		}
+		c.d.outputIndirect(c.refs[num], c.value(o))
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file decrypts files encrypted with the standard security handler
+// (p. 115), so that they can be read like the others.
+
+import (
+	"fmt"
+)
+
+// Decrypt unlocks the encrypted file read by r with password pw, which can
+// be either its user or its owner password. Files with an empty user
+// password are unlocked by NewReader, and need no call to Decrypt.
+func (r *Reader) Decrypt(pw string) (err error) {
+	defer dontPanic(&err)
+
+	if !r.Encrypted() {
+		fail(ErrBadFile, "file is not encrypted")
+	}
+	r.unlock(pw)
+	return nil
+}
+
+// unlock sets the security handler of r if pw is a password of the file,
+// and panics otherwise.
+func (r *Reader) unlock(pw string) {
+	s := r.security()
+	var id []byte
+	if a, ok := r.resolve(r.trailer["ID"]).([]interface{}); ok && len(a) > 0 {
+		if t, ok := a[0].(string); ok {
+			id = []byte(t)
+		}
+	}
+	if !s.authenticate(pw, id) {
+		fail(ErrPassword, "wrong password")
+	}
+	r.sec, r.locked = s, false
+	// Objects read before, like object streams read while rebuilding the
+	// cross-reference table, are read again to be decrypted.
+	r.objs = make(map[int]interface{})
+}
+
+// security returns the security handler of the file, as described by its
+// encryption dictionary. Its key is not set.
+func (r *Reader) security() *security {
+	d, ok := r.resolve(r.trailer["Encrypt"]).(map[string]interface{})
+	if !ok {
+		fail(ErrBadFile, "bad encryption dictionary")
+	}
+	if f := r.resolve(d["Filter"]); f != name("Standard") {
+		fail(ErrBadFile, fmt.Sprintf("unsupported security handler: %v", f))
+	}
+	s := new(security)
+	s.v, _ = r.resolve(d["V"]).(int)
+	s.r, _ = r.resolve(d["R"]).(int)
+	p, _ := r.resolve(d["P"]).(int)
+	s.p = int32(p)
+	for _, e := range []struct {
+		k string
+		b *[]byte
+	}{{"O", &s.o}, {"U", &s.u}, {"OE", &s.oe}, {"UE", &s.ue}, {"Perms", &s.perms}} {
+		t, _ := r.resolve(d[e.k]).(string)
+		*e.b = []byte(t)
+	}
+	if m, ok := r.resolve(d["EncryptMetadata"]).(bool); ok {
+		s.noMeta = !m
+	}
+
+	switch s.v {
+	case 1:
+		s.n = 5
+	case 2:
+		bits, ok := r.resolve(d["Length"]).(int)
+		if !ok {
+			bits = 40
+		}
+		if bits < 40 || bits > 128 || bits%8 != 0 {
+			fail(ErrBadFile, fmt.Sprintf("bad length of encryption key: %d", bits))
+		}
+		s.n = bits / 8
+	case 4, 5:
+		// Only files that use one crypt filter for both strings and
+		// streams are supported.
+		stmf, _ := r.resolve(d["StmF"]).(name)
+		strf, _ := r.resolve(d["StrF"]).(name)
+		if stmf != strf {
+			fail(ErrBadFile, "different crypt filters for strings and streams")
+		}
+		cfs, _ := r.resolve(d["CF"]).(map[string]interface{})
+		cf, _ := r.resolve(cfs[string(stmf)]).(map[string]interface{})
+		switch m := r.resolve(cf["CFM"]); m {
+		case name("V2"):
+			s.n = 16
+		case name("AESV2"):
+			s.n, s.aes = 16, true
+		case name("AESV3"):
+			s.n, s.aes = 32, true
+		default:
+			fail(ErrBadFile, fmt.Sprintf("unsupported crypt filter: %v", m))
+		}
+		if (s.v == 5) != (s.n == 32) {
+			fail(ErrBadFile, "bad crypt filter for the version of encryption")
+		}
+	default:
+		fail(ErrBadFile, fmt.Sprintf("unsupported version of encryption: %d", s.v))
+	}
+	return s
+}
+
+// decrypt returns object o with its strings and streams decrypted by f.
+// Cross-reference streams, the contents of signatures, streams with the
+// Identity Crypt filter and, if the file says so, the metadata are not
+// encrypted (p. 116).
+func (r *Reader) decrypt(o interface{}, f func([]byte) []byte) interface{} {
+	switch t := o.(type) {
+	case string:
+		return string(f([]byte(t)))
+	case []interface{}:
+		for i, v := range t {
+			t[i] = r.decrypt(v, f)
+		}
+	case map[string]interface{}:
+		sig := t["Type"] == name("Sig") || t["Type"] == name("DocTimeStamp")
+		for k, v := range t {
+			if !sig || k != "Contents" {
+				t[k] = r.decrypt(v, f)
+			}
+		}
+	case *stream:
+		typ := t.dic["Type"]
+		if typ == name("XRef") {
+			break
+		}
+		r.decrypt(t.dic, f)
+		if unencrypted(t.dic) {
+			break
+		}
+		if typ != name("Metadata") || !r.sec.noMeta {
+			t.buf = f(t.buf)
+		}
+	}
+	return o
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+type decryptTest struct {
+	aes         bool
+	bits        int
+	user, owner string
+}
+
+var decryptTests = []decryptTest{
+	{false, 40, "", ""},
+	{false, 128, "user", "owner"},
+	{true, 128, "", "owner"},
+	{true, 128, "user", "owner"},
+	{true, 256, "user", "owner"},
+	{true, 256, "", ""},
+}
+
+// testEncrypted returns an encrypted file with one page, whose title is
+// "Secret €".
+func testEncrypted(t *testing.T, tt decryptTest) []byte {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	var err error
+	if tt.aes {
+		err = d.EncryptAES(tt.user, tt.owner, tt.bits, PermPrint)
+	} else {
+		err = d.Encrypt(tt.user, tt.owner, tt.bits, PermPrint)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetInfo(&Info{Title: "Secret €"})
+	d.NewPage(100, 100)
+	d.Rectangle(10, 10, 20, 20)
+	d.Fill()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// checkDecrypted reports an error if the file read by r is not the one
+// made by testEncrypted.
+func checkDecrypted(t *testing.T, r *Reader, prefix string) {
+	if info, err := r.Info(); err != nil || info["Title"] != "Secret €" {
+		t.Errorf("%s: info: got %v, %v", prefix, info, err)
+	}
+	pgs := r.pages()
+	if c := string(r.contents(pgs[0].dic["Contents"])); c != "10 10 20 20 re\nf\n\n" {
+		t.Errorf("%s: contents: got %q", prefix, c)
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	for _, tt := range decryptTests {
+		b := testEncrypted(t, tt)
+		for _, pw := range []string{tt.user, tt.owner} {
+			prefix := fmt.Sprintf("%v %d with %q", tt.aes, tt.bits, pw)
+			r, err := NewReader(b)
+			if err != nil {
+				t.Fatalf("%s: %s", prefix, err)
+			}
+			if !r.Encrypted() {
+				t.Errorf("%s: file is not encrypted", prefix)
+			}
+			if tt.user != "" {
+				if _, err := r.NumPages(); fmt.Sprint(err) != "pdf.go: encrypted file needs a password" {
+					t.Errorf("%s: locked file: got error %v", prefix, err)
+				}
+				if err := r.Decrypt("wrong"); fmt.Sprint(err) != "pdf.go: wrong password" {
+					t.Errorf("%s: wrong password: got error %v", prefix, err)
+				}
+				if err := r.Decrypt(pw); err != nil {
+					t.Errorf("%s: %s", prefix, err)
+					continue
+				}
+			}
+			checkDecrypted(t, r, prefix)
+		}
+	}
+}
+
+func TestDecryptImport(t *testing.T) {
+	r, err := NewReader(testEncrypted(t, decryptTest{true, 128, "user", "owner"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Decrypt("owner")
+
+	// The page is copied and encrypted again, with another password.
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.Encrypt("new", "", 128, PermAll)
+	d.NewPage(100, 100)
+	x, err := d.ImportPage(r, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.DrawXObject(x, 0, 0, 100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r2, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r2.Decrypt("new"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r2.NumPages(); err != nil || n != 1 {
+		t.Errorf("number of pages: got %d, %v", n, err)
+	}
+	if err := Flatten(bytes.NewBuffer(nil), r); fmt.Sprint(err) != "pdf.go: encrypted files can't be flattened" {
+		t.Errorf("flattening encrypted file: got error %v", err)
+	}
+}
+
+func TestDecryptFillForm(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.EncryptAES("user", "owner", 256, PermAll)
+	d.NewPage(200, 200)
+	d.TextBox(10, 10, 100, 20, &TextField{Name: "name", Value: "Old"})
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewReader(buf.Bytes())
+	r.Decrypt("user")
+	out := bytes.NewBuffer(nil)
+	if err := FillForm(out, r, map[string]string{"name": "Ali"}); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out.Bytes()[buf.Len():], []byte("(Ali)")) {
+		t.Error("value of field written with no encryption")
+	}
+	r, err := NewReader(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Decrypt("owner"); err != nil {
+		t.Fatal(err)
+	}
+	if values, err := r.FieldValues(); err != nil || values["name"] != "Ali" {
+		t.Errorf("values: got %v, %v", values, err)
+	}
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file compares parsed files by what they hold, not by their bytes, so
+// that tests of applications making documents don't fail because of what
+// changes every time a document is made.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Difference is a difference between two files found by Diff.
+type Difference struct {
+	// Path is where the files differ, from the trailer, like
+	// "Root/Pages/Kids/0/MediaBox/2". Operators of content streams are
+	// numbered after the path of the contents, like
+	// "Root/Pages/Kids/0/Contents/3".
+	Path string
+	A, B string // the values in the files; empty if there's none
+}
+
+func (d Difference) String() string {
+	a, b := d.A, d.B
+	if a == "" {
+		a = "nothing"
+	}
+	if b == "" {
+		b = "nothing"
+	}
+	return d.Path + ": " + a + " vs " + b
+}
+
+// xmpDates and xmpIDs match the dates and identifiers of XMP metadata,
+// which are left out of comparisons.
+var (
+	xmpDates = regexp.MustCompile(`\d{4}-\d\d-\d\dT[0-9:.]+(Z|[+-]\d\d:\d\d)?`)
+	xmpIDs   = regexp.MustCompile(`uuid:[0-9A-Fa-f-]+`)
+)
+
+// differ holds the state of Diff.
+type differ struct {
+	a, b *Reader
+	seen map[[2]int]bool // pairs of objects compared, by their numbers
+	ds   []Difference
+}
+
+// Diff compares the objects of files a and b that can be reached from the
+// catalog and the document information dictionary, and returns where they
+// differ, so that tests can check that two documents are the same. Files
+// that hold the same objects with different numbers, in a different order,
+// in object streams or not, or with different filters are the same. Their
+// identifiers, dates, like CreationDate and ModDate, and the dates and
+// identifiers of XMP metadata are left out, and content streams are compared
+// by their operators, so differences of white space and of how numbers are
+// written are left out too.
+func Diff(a, b *Reader) (ds []Difference, err error) {
+	defer dontPanic(&err)
+
+	df := &differ{a: a, b: b, seen: make(map[[2]int]bool)}
+	for _, k := range []string{"Root", "Info"} {
+		df.value(k, a.trailer[k], b.trailer[k])
+	}
+	return df.ds, nil
+}
+
+// add adds a difference of x and y at path.
+func (df *differ) add(path string, x, y interface{}) {
+	df.ds = append(df.ds, Difference{path, parsedText(x), parsedText(y)})
+}
+
+// value compares object x of a with object y of b, at path.
+func (df *differ) value(path string, x, y interface{}) {
+	if rx, ok := x.(ref); ok {
+		if ry, ok := y.(ref); ok {
+			k := [2]int{rx.num, ry.num}
+			if df.seen[k] {
+				return
+			}
+			df.seen[k] = true
+		}
+	}
+	x, y = df.a.resolve(x), df.b.resolve(y)
+	switch tx := x.(type) {
+	case map[string]interface{}:
+		if ty, ok := y.(map[string]interface{}); ok {
+			df.dict(path, tx, ty, false)
+			return
+		}
+	case []interface{}:
+		if ty, ok := y.([]interface{}); ok {
+			for i := 0; i < len(tx) || i < len(ty); i++ {
+				p := path + "/" + strconv.Itoa(i)
+				switch {
+				case i >= len(ty):
+					df.add(p, tx[i], nil)
+				case i >= len(tx):
+					df.add(p, nil, ty[i])
+				default:
+					df.value(p, tx[i], ty[i])
+				}
+			}
+			return
+		}
+	case *stream:
+		if ty, ok := y.(*stream); ok {
+			df.stream(path, tx, ty)
+			return
+		}
+	}
+	if !df.same(x, y) {
+		df.add(path, x, y)
+	}
+}
+
+// same returns whether the values x of a and y of b, which aren't
+// dictionaries, arrays or streams, are the same. Dates are always the same.
+func (df *differ) same(x, y interface{}) bool {
+	sx, okx := x.(string)
+	sy, oky := y.(string)
+	if okx && oky {
+		return sx == sy || strings.HasPrefix(sx, "D:") && strings.HasPrefix(sy, "D:")
+	}
+	fx, okx := df.a.number(x)
+	fy, oky := df.b.number(y)
+	if okx && oky {
+		return ftoa(fx) == ftoa(fy)
+	}
+	switch x.(type) {
+	case map[string]interface{}, []interface{}, *stream:
+		return false
+	}
+	return x == y
+}
+
+// dict compares dictionary x of a with dictionary y of b, at path. Entries
+// about how the data of a stream is stored are left out if stm is true.
+func (df *differ) dict(path string, x, y map[string]interface{}, stm bool) {
+	keys := make([]string, 0, len(x)+len(y))
+	for k := range x {
+		keys = append(keys, k)
+	}
+	for k := range y {
+		if _, ok := x[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	page := df.a.resolve(x["Type"]) == name("Page") && df.b.resolve(y["Type"]) == name("Page")
+	for _, k := range keys {
+		switch {
+		case stm && (k == "Length" || k == "Filter" || k == "DecodeParms" || k == "DL"):
+		case page && k == "Contents":
+			df.content(path+"/"+k, df.a.contents(x[k]), df.b.contents(y[k]))
+		default:
+			vx, okx := x[k]
+			vy, oky := y[k]
+			if okx && oky {
+				df.value(path+"/"+k, vx, vy)
+			} else {
+				df.add(path+"/"+k, vx, vy)
+			}
+		}
+	}
+}
+
+// stream compares stream x of a with stream y of b, at path. The data of
+// forms are compared as content streams.
+func (df *differ) stream(path string, x, y *stream) {
+	df.dict(path, x.dic, y.dic, true)
+	bx, by := df.a.decode(x), df.b.decode(y)
+	if bx == nil || by == nil {
+		// Data with filters that aren't supported is compared as it
+		// is.
+		bx, by = x.buf, y.buf
+	}
+	switch {
+	case df.a.resolve(x.dic["Subtype"]) == name("Form"):
+		df.content(path, bx, by)
+		return
+	case df.a.resolve(x.dic["Type"]) == name("Metadata"):
+		bx = xmpIDs.ReplaceAll(xmpDates.ReplaceAll(bx, nil), nil)
+		by = xmpIDs.ReplaceAll(xmpDates.ReplaceAll(by, nil), nil)
+	}
+	if !bytes.Equal(bx, by) {
+		df.ds = append(df.ds, Difference{path, digest(bx), digest(by)})
+	}
+}
+
+// digest describes data b of a stream.
+func digest(b []byte) string {
+	return fmt.Sprintf("%d bytes with SHA-256 %x", len(b), sha256.Sum256(b))
+}
+
+// content compares content streams x and y, at path, and adds the first of
+// their operators that differ.
+func (df *differ) content(path string, x, y []byte) {
+	ox, oy := operations(x), operations(y)
+	for i := 0; i < len(ox) || i < len(oy); i++ {
+		var sx, sy string
+		if i < len(ox) {
+			sx = opText(ox[i])
+		}
+		if i < len(oy) {
+			sy = opText(oy[i])
+		}
+		if sx != sy {
+			df.ds = append(df.ds, Difference{path + "/" + strconv.Itoa(i), sx, sy})
+			return
+		}
+	}
+}
+
+// opText returns operation op as it's written in content streams.
+func opText(op operation) string {
+	s := make([]string, 0, len(op.args)+1)
+	for _, a := range op.args {
+		s = append(s, parsedText(a))
+	}
+	return strings.Join(append(s, op.op), " ")
+}
+
+// parsedText returns the parsed object o as it's written in files, with
+// streams left out. It returns "" for nil.
+func parsedText(o interface{}) string {
+	switch t := o.(type) {
+	case nil:
+		return ""
+	case ref:
+		return fmt.Sprintf("%d %d R", t.num, t.gen)
+	case *stream:
+		return "stream"
+	case []interface{}:
+		s := make([]string, len(t))
+		for i, v := range t {
+			if s[i] = parsedText(v); v == nil {
+				s[i] = "null"
+			}
+		}
+		return "[" + strings.Join(s, " ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		s := make([]string, len(keys))
+		for i, k := range keys {
+			s[i] = string(output(name(k))) + " " + parsedText(t[k])
+		}
+		return "<< " + strings.Join(s, " ") + " >>"
+	case name, string, bool, int, float64:
+		return string(output(t))
+	}
+	return fmt.Sprint(o)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	doc := func(title string, created time.Time, compress bool, w int) *Reader {
+		buf := bytes.NewBuffer(nil)
+		d, _ := New(buf, Options{Compress: compress})
+		d.SetInfo(&Info{Title: title, Created: created})
+		d.NewPage(300, 300)
+		d.Rectangle(10, 10, w, 100)
+		d.Stroke()
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := NewReader(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	t1, t2 := time.Date(2011, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2012, 2, 2, 0, 0, 0, 0, time.UTC)
+	a := doc("A", t1, false, 100)
+	tests := []struct {
+		b    *Reader
+		want string
+	}{
+		{doc("A", t2, true, 100), "[]"},
+		{doc("B", t1, false, 100), "[Info/Title: (A) vs (B)]"},
+		{doc("A", t1, false, 90), "[Root/Pages/Kids/0/Contents/0: 10 10 100 100 re vs 10 10 90 100 re]"},
+	}
+	for _, tt := range tests {
+		ds, err := Diff(a, tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(ds); got != tt.want {
+			t.Errorf("differences: got %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestDiffObjects(t *testing.T) {
+	img := "<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Length 1 >>\nstream\n%s\nendstream"
+	objs := append(testPage("q 1 0 0 1 0 0 cm /Im1 Do Q", "<< /XObject << /Im1 5 0 R >> >>"), fmt.Sprintf(img, "\x00"))
+	a, err := NewReader(testFile(objs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same page with the objects in another order and numbers, and
+	// written differently.
+	b, err := NewReader(testFile([]string{
+		"<< /Type /Catalog /Pages 3 0 R >>",
+		fmt.Sprintf(img, "\x00"),
+		"<< /Type /Pages /Kids [ 5 0 R ] /Count 1.0 /MediaBox [ 0 0 100 100.0 ] >>",
+		"<< /Length 27 >>\nstream\nq 1 0 0 1 0 0.0 cm\n/Im1 Do Q\nendstream",
+		"<< /Type /Page /Parent 3 0 R /Resources << /XObject << /Im1 2 0 R >> >> /Contents 4 0 R >>",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds, err := Diff(a, b); err != nil || len(ds) > 0 {
+		t.Errorf("differences of the same files: got %v, %v", ds, err)
+	}
+
+	objs[4] = fmt.Sprintf(img, "\xff")
+	objs[1] = "<< /Type /Pages /Kids [ 3 0 R ] /Count 1 /MediaBox [ 0 0 100 100 ] /Rotate 90 >>"
+	c, err := NewReader(testFile(objs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := Diff(a, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[Root/Pages/Kids/0/Resources/XObject/Im1: 1 bytes with SHA-256 6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d vs " +
+		"1 bytes with SHA-256 a8100ae6aa1940d0b663bb31cd466142ebbdbd5187131b92d93818987832eb89 Root/Pages/Rotate: nothing vs 90]"
+	if got := fmt.Sprint(ds); got != want {
+		t.Errorf("differences: got %s, want %s", got, want)
+	}
+}
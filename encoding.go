@@ -0,0 +1,190 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file holds the character encodings of simple fonts (p. 996) and the
+// names of their glyphs, which are used to find the text shown by content
+// streams of existing files.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// asciiNames are the names of the glyphs of codes 32 to 126 in
+// WinAnsiEncoding.
+var asciiNames = strings.Fields(`space exclam quotedbl numbersign dollar
+	percent ampersand quotesingle parenleft parenright asterisk plus comma
+	hyphen period slash zero one two three four five six seven eight nine
+	colon semicolon less equal greater question at A B C D E F G H I J K L M
+	N O P Q R S T U V W X Y Z bracketleft backslash bracketright asciicircum
+	underscore grave a b c d e f g h i j k l m n o p q r s t u v w x y z
+	braceleft bar braceright asciitilde`)
+
+// latinNames are the names of the glyphs of codes 160 to 255 in
+// WinAnsiEncoding, which are the same as Latin-1.
+var latinNames = strings.Fields(`space exclamdown cent sterling currency yen
+	brokenbar section dieresis copyright ordfeminine guillemotleft logicalnot
+	hyphen registered macron degree plusminus twosuperior threesuperior acute
+	mu paragraph periodcentered cedilla onesuperior ordmasculine
+	guillemotright onequarter onehalf threequarters questiondown Agrave
+	Aacute Acircumflex Atilde Adieresis Aring AE Ccedilla Egrave Eacute
+	Ecircumflex Edieresis Igrave Iacute Icircumflex Idieresis Eth Ntilde
+	Ograve Oacute Ocircumflex Otilde Odieresis multiply Oslash Ugrave Uacute
+	Ucircumflex Udieresis Yacute Thorn germandbls agrave aacute acircumflex
+	atilde adieresis aring ae ccedilla egrave eacute ecircumflex edieresis
+	igrave iacute icircumflex idieresis eth ntilde ograve oacute ocircumflex
+	otilde odieresis divide oslash ugrave uacute ucircumflex udieresis yacute
+	thorn ydieresis`)
+
+// otherGlyphs maps the names of other glyphs to their characters.
+var otherGlyphs = map[string]int{
+	"Euro": 0x20ac, "quotesinglbase": 0x201a, "florin": 0x0192,
+	"quotedblbase": 0x201e, "ellipsis": 0x2026, "dagger": 0x2020,
+	"daggerdbl": 0x2021, "circumflex": 0x02c6, "perthousand": 0x2030,
+	"Scaron": 0x0160, "guilsinglleft": 0x2039, "OE": 0x0152, "Zcaron": 0x017d,
+	"quoteleft": 0x2018, "quoteright": 0x2019, "quotedblleft": 0x201c,
+	"quotedblright": 0x201d, "bullet": 0x2022, "endash": 0x2013,
+	"emdash": 0x2014, "tilde": 0x02dc, "trademark": 0x2122, "scaron": 0x0161,
+	"guilsinglright": 0x203a, "oe": 0x0153, "zcaron": 0x017e,
+	"Ydieresis": 0x0178, "fraction": 0x2044, "breve": 0x02d8,
+	"dotaccent": 0x02d9, "ring": 0x02da, "hungarumlaut": 0x02dd,
+	"ogonek": 0x02db, "caron": 0x02c7, "Lslash": 0x0141, "lslash": 0x0142,
+	"dotlessi": 0x0131, "fi": 0xfb01, "fl": 0xfb02, "minus": 0x2212,
+	"nbspace": 0xa0, "sfthyphen": 0xad,
+}
+
+// glyphText returns the text of the glyph named n, or "" if it's not known.
+// Names like uni20AC and u1F600 are the code points of their characters.
+func glyphText(n string) string {
+	n = strings.SplitN(n, ".", 2)[0] // variants, like a.sc
+	if c, ok := otherGlyphs[n]; ok {
+		return char(c)
+	}
+	for i, a := range asciiNames {
+		if a == n {
+			return char(32 + i)
+		}
+	}
+	for i, a := range latinNames {
+		if a == n {
+			return char(160 + i)
+		}
+	}
+	hex := ""
+	switch {
+	case strings.HasPrefix(n, "uni") && len(n) == 7:
+		hex = n[3:]
+	case strings.HasPrefix(n, "u") && len(n) >= 5 && len(n) <= 7:
+		hex = n[1:]
+	}
+	if hex != "" {
+		if v, err := strconv.ParseUint(hex, 16, 64); err == nil {
+			return char(int(v))
+		}
+	}
+	return ""
+}
+
+// char returns the UTF-8 encoding of the character c.
+func char(c int) string {
+	return fmt.Sprintf("%c", c)
+}
+
+// standardHigh are the names of the glyphs of codes 161 to 251 in
+// StandardEncoding; codes with no glyph are empty.
+var standardHigh = map[int]string{
+	0xa1: "exclamdown", 0xa2: "cent", 0xa3: "sterling", 0xa4: "fraction",
+	0xa5: "yen", 0xa6: "florin", 0xa7: "section", 0xa8: "currency",
+	0xa9: "quotesingle", 0xaa: "quotedblleft", 0xab: "guillemotleft",
+	0xac: "guilsinglleft", 0xad: "guilsinglright", 0xae: "fi", 0xaf: "fl",
+	0xb1: "endash", 0xb2: "dagger", 0xb3: "daggerdbl", 0xb4: "periodcentered",
+	0xb6: "paragraph", 0xb7: "bullet", 0xb8: "quotesinglbase",
+	0xb9: "quotedblbase", 0xba: "quotedblright", 0xbb: "guillemotright",
+	0xbc: "ellipsis", 0xbd: "perthousand", 0xbf: "questiondown", 0xc1: "grave",
+	0xc2: "acute", 0xc3: "circumflex", 0xc4: "tilde", 0xc5: "macron",
+	0xc6: "breve", 0xc7: "dotaccent", 0xc8: "dieresis", 0xca: "ring",
+	0xcb: "cedilla", 0xcd: "hungarumlaut", 0xce: "ogonek", 0xcf: "caron",
+	0xd0: "emdash", 0xe1: "AE", 0xe3: "ordfeminine", 0xe8: "Lslash",
+	0xe9: "Oslash", 0xea: "OE", 0xeb: "ordmasculine", 0xf1: "ae",
+	0xf5: "dotlessi", 0xf8: "lslash", 0xf9: "oslash", 0xfa: "oe",
+	0xfb: "germandbls",
+}
+
+// macRomanHigh holds the characters of codes 128 to 255 in MacRomanEncoding.
+const macRomanHigh = "ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü" +
+	"†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø" +
+	"¿¡¬√ƒ≈∆«»… ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ" +
+	"‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ"
+
+// baseEncoding returns the text of the codes of the named encoding. Fonts
+// with no encoding use StandardEncoding.
+func baseEncoding(enc name) (t [256]string) {
+	for i := 32; i < 127; i++ {
+		t[i] = char(i)
+	}
+	switch enc {
+	case "WinAnsiEncoding":
+		t[127] = ""
+		for c, b := range winAnsiHigh {
+			t[b] = char(c)
+		}
+		for i := 160; i < 256; i++ {
+			t[i] = char(i)
+		}
+	case "MacRomanEncoding":
+		i := 128
+		for _, c := range macRomanHigh {
+			t[i] = string(c)
+			i++
+		}
+	default:
+		t['\''] = "’"
+		t['`'] = "‘"
+		for c, n := range standardHigh {
+			t[c] = glyphText(n)
+		}
+	}
+	return t
+}
+
+// pdfDocHigh are the names of the glyphs of codes 128 to 160 in
+// PDFDocEncoding (p. 1005), which are not the same as Latin-1.
+var pdfDocHigh = strings.Fields(`bullet dagger daggerdbl ellipsis emdash endash
+	florin fraction guilsinglleft guilsinglright minus perthousand
+	quotedblbase quotedblleft quotedblright quoteleft quoteright
+	quotesinglbase trademark fi fl Lslash OE Scaron Ydieresis Zcaron dotlessi
+	lslash oe scaron zcaron .notdef Euro`)
+
+// decodeText converts the PDF text string s (p. 158), which is either
+// UTF-16BE with a byte order mark or in PDFDocEncoding, to UTF-8.
+func decodeText(s string) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		return utf16Text(s[2:])
+	}
+	t := ""
+	for i := 0; i < len(s); i++ {
+		c := int(s[i])
+		if c >= 128 && c <= 160 {
+			t += glyphText(pdfDocHigh[c-128])
+		} else {
+			t += char(c)
+		}
+	}
+	return t
+}
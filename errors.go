@@ -0,0 +1,156 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the errors returned by the package. Functions of the
+// package panic with them, and the exported ones recover and return them.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Kinds of errors returned by the package. All the errors returned by the
+// functions and methods of the package are of type *Error, and their Kind is
+// one of these, which errors.Is tells.
+var (
+	// ErrInvalid is for invalid arguments, and for calls that can't be
+	// made at the time, like EndTag with no open tag.
+	ErrInvalid = errors.New("invalid argument or call")
+	// ErrBadName is for bad or duplicate names, like the names of fields
+	// and attached files.
+	ErrBadName = errors.New("bad name")
+	// ErrNoPage is for content added before any page was started.
+	ErrNoPage = errors.New("no current page")
+	// ErrClosed is for documents used after they're closed.
+	ErrClosed = errors.New("document is closed")
+	// ErrWriterFailed is for documents whose output returned an error.
+	ErrWriterFailed = errors.New("writing to the output failed")
+	// ErrBadFile is for files that are broken, or use what isn't
+	// supported by the package, when they're read.
+	ErrBadFile = errors.New("bad or unsupported file")
+	// ErrPassword is for encrypted files read with no password or a wrong
+	// one.
+	ErrPassword = errors.New("wrong password")
+	// ErrNoGlyph is for text with characters its font doesn't have, in
+	// documents made with FailOnMissingGlyphs.
+	ErrNoGlyph = errors.New("character with no glyph")
+	// ErrCanceled is for work stopped because its context is canceled or
+	// its deadline passed. Err of the error is the error of the context.
+	ErrCanceled = errors.New("canceled")
+)
+
+// Error is an error returned by the package.
+type Error struct {
+	Kind error  // one of the kinds of errors, like ErrBadName
+	Msg  string // what went wrong
+	Err  error  // the error that caused it, if any, like the error of the output
+}
+
+func (e *Error) Error() string {
+	return "pdf.go: " + e.Msg
+}
+
+// Is tells whether t is the kind of e, so that errors.Is(err, ErrBadName)
+// is true for errors of that kind.
+func (e *Error) Is(t error) bool {
+	return t == e.Kind
+}
+
+// Unwrap returns the error that caused e, like the error of a canceled
+// context, or nil.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// fail panics with an error of kind k, to be returned by an exported function
+// of the package.
+func fail(k error, msg string) {
+	panic(&Error{Kind: k, Msg: msg})
+}
+
+// check panics with an error of kind ErrInvalid caused by err, if err is not
+// nil. Errors of the package are kept as they are.
+func check(err error) {
+	if e, ok := err.(*Error); ok {
+		panic(e)
+	}
+	if err != nil {
+		panic(&Error{ErrInvalid, fmt.Sprint(err), err})
+	}
+}
+
+// checkWrite panics with an error of kind ErrWriterFailed caused by err, if
+// err is not nil.
+func checkWrite(err error) {
+	if err != nil {
+		panic(&Error{ErrWriterFailed, fmt.Sprint(err), err})
+	}
+}
+
+// checkFile panics with an error of kind ErrBadFile caused by err, if err is
+// not nil.
+func checkFile(err error) {
+	if err != nil {
+		panic(&Error{ErrBadFile, fmt.Sprint(err), err})
+	}
+}
+
+// checkContext panics with an error of kind ErrCanceled if ctx is done. ctx
+// can be nil.
+func checkContext(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	if err := ctx.Err(); err != nil {
+		panic(&Error{ErrCanceled, fmt.Sprint(err), err})
+	}
+}
+
+// canceled tells whether r, which a function of the package panicked with,
+// is an error of kind ErrCanceled. Such panics are not tolerated like the
+// other ones.
+func canceled(r interface{}) bool {
+	e, ok := r.(*Error)
+	return ok && e.Kind == ErrCanceled
+}
+
+// dontPanic recovers the panics of the package and turns them into errors of
+// type *Error. Panics with strings are errors of kind ErrInvalid. Other
+// panics, like runtime errors, are not recovered.
+func dontPanic(err *error) {
+	if r := recover(); r != nil {
+		switch e := r.(type) {
+		case *Error:
+			*err = e
+		case string:
+			*err = &Error{Kind: ErrInvalid, Msg: e}
+		default:
+			panic(r)
+		}
+	}
+}
+
+// panicText returns the message of r, which a function of the package
+// panicked with.
+func panicText(r interface{}) string {
+	if e, ok := r.(*Error); ok {
+		return e.Msg
+	}
+	return fmt.Sprint(r)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// failingWriter fails to write after n bytes.
+type failingWriter struct {
+	n int
+}
+
+var errFailingWriter = errors.New("disk is full")
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if len(b) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errFailingWriter
+	}
+	w.n -= len(b)
+	return len(b), nil
+}
+
+func TestErrors(t *testing.T) {
+	d, _ := New(new(bytes.Buffer))
+	if err := d.LineTo(1, 1); !errors.Is(err, ErrNoPage) {
+		t.Errorf("drawing before any page: got %v expected ErrNoPage", err)
+	}
+	d.NewPage(100, 100)
+	d.TextBox(10, 10, 50, 20, &TextField{Name: "name"})
+	if err := d.TextBox(10, 40, 50, 20, &TextField{Name: "name"}); !errors.Is(err, ErrBadName) {
+		t.Errorf("two fields named name: got %v expected ErrBadName", err)
+	}
+	if err := d.SetTabOrder(10); !errors.Is(err, ErrInvalid) {
+		t.Errorf("unknown tab order: got %v expected ErrInvalid", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.LineTo(1, 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("drawing after Close: got %v expected ErrClosed", err)
+	}
+	if err := d.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("second Close: got %v expected ErrClosed", err)
+	}
+
+	d, _ = New(&failingWriter{100})
+	d.NewPage(100, 100)
+	err := d.Close()
+	if !errors.Is(err, ErrWriterFailed) {
+		t.Errorf("failing writer: got %v expected ErrWriterFailed", err)
+	}
+	if !errors.Is(err, errFailingWriter) {
+		t.Errorf("failing writer: got %v expected the error of the writer", err)
+	}
+
+	_, err = NewReader([]byte("%PDF-1.7\n"))
+	if !errors.Is(err, ErrBadFile) {
+		t.Errorf("empty file: got %v expected ErrBadFile", err)
+	}
+}
+
+func TestCloseContext(t *testing.T) {
+	d, _ := New(new(bytes.Buffer))
+	d.NewPage(100, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := d.CloseContext(ctx)
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled Close: got %v expected ErrCanceled", err)
+	}
+}
+
+func TestDontPanic(t *testing.T) {
+	f := func(v interface{}) (err error) {
+		defer dontPanic(&err)
+		if v != nil {
+			panic(v)
+		}
+		return nil
+	}
+	if err := f("bad"); fmt.Sprint(err) != "pdf.go: bad" {
+		t.Errorf("panic with string: got %v", err)
+	}
+	defer func() {
+		if r := recover(); r != 1 {
+			t.Errorf("panic with 1: got %v", r)
+		}
+	}()
+	f(1)
+	t.Error("panic with 1 recovered")
+}
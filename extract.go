@@ -0,0 +1,495 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file extracts text from pages of existing files, by interpreting the
+// text operators of their content streams (p. 405). Character codes are
+// converted to text with ToUnicode CMaps of fonts, or else with their
+// encodings.
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TextRun is text shown by one operator of a content stream.
+type TextRun struct {
+	X, Y float64 // start of the baseline, in the default coordinates of the page
+	Size float64 // font size, as scaled by the transformations of the page
+	Text string
+}
+
+// spaceGap is the smallest adjustment in a TJ array, in thousandths of the
+// font size, which is taken as a space between words.
+const spaceGap = 250
+
+// maxFormDepth limits how deep forms drawn by other forms are followed.
+const maxFormDepth = 16
+
+// matrix is a transformation matrix [a b c d e f] (p. 208).
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m×n, which transforms by m first and then by n.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// textState is the part of the graphics state used for text (p. 397).
+type textState struct {
+	ctm  matrix
+	font *rfont
+	size float64 // font size
+	tc   float64 // character spacing
+	tw   float64 // word spacing
+	th   float64 // horizontal scaling, 1 for 100%
+	tl   float64 // leading
+	rise float64
+}
+
+// extractor interprets content streams for their text.
+type extractor struct {
+	r     *Reader
+	fonts map[int]*rfont // fonts read, by object number
+	gs    textState
+	stack []textState // states saved by q
+	tm    matrix      // text matrix
+	tlm   matrix      // text line matrix
+	depth int         // depth of the form being interpreted
+	runs  []TextRun
+}
+
+// PageText returns the text of page n of the file, in the order it's shown
+// by the content of the page. Pages are numbered from 1. Text of forms drawn
+// on the page is included, but not the text of annotations.
+func (r *Reader) PageText(n int) (runs []TextRun, err error) {
+	defer dontPanic(&err)
+
+	pgs := r.pages()
+	if n < 1 || n > len(pgs) {
+		panic(fmt.Sprintf("text of page %d of %d pages", n, len(pgs)))
+	}
+	p := pgs[n-1].dic
+	e := &extractor{r: r, fonts: make(map[int]*rfont)}
+	e.gs = textState{ctm: identity, th: 1}
+	e.run(r.contents(p["Contents"]), p["Resources"])
+	return e.runs, nil
+}
+
+// numbers returns the operands a as numbers, if there are n of them.
+func (e *extractor) numbers(a []interface{}, n int) ([]float64, bool) {
+	if len(a) != n {
+		return nil, false
+	}
+	v := make([]float64, n)
+	for i, o := range a {
+		var ok bool
+		if v[i], ok = e.r.number(o); !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// run interprets content stream b with resources res.
+func (e *extractor) run(b []byte, res interface{}) {
+	resd, _ := e.r.resolve(res).(map[string]interface{})
+	for _, op := range operations(b) {
+		if e.state(op, resd) {
+			continue
+		}
+		a := op.args
+		switch op.op {
+		case "Tj":
+			e.show(a)
+		case "'":
+			e.move(0, -e.gs.tl)
+			e.show(a)
+		case "\"":
+			if len(a) == 3 {
+				e.gs.tw, _ = e.r.number(a[0])
+				e.gs.tc, _ = e.r.number(a[1])
+				e.move(0, -e.gs.tl)
+				e.show(a[2:])
+			}
+		case "TJ":
+			if len(a) == 1 {
+				t, _ := a[0].([]interface{})
+				e.show(t)
+			}
+		case "Do":
+			if len(a) == 1 {
+				e.form(resd, a[0], res)
+			}
+		}
+	}
+}
+
+// state follows op if it changes the graphics state or the position of
+// text, and tells whether it does. res holds the resources of the content.
+func (e *extractor) state(op operation, res map[string]interface{}) bool {
+	a := op.args
+	switch op.op {
+	case "q":
+		e.stack = append(e.stack, e.gs)
+	case "Q":
+		if len(e.stack) > 0 {
+			e.gs = e.stack[len(e.stack)-1]
+			e.stack = e.stack[:len(e.stack)-1]
+		}
+	case "cm":
+		if v, ok := e.numbers(a, 6); ok {
+			e.gs.ctm = matrix{v[0], v[1], v[2], v[3], v[4], v[5]}.mul(e.gs.ctm)
+		}
+	case "BT":
+		e.tm, e.tlm = identity, identity
+	case "Tf":
+		if len(a) == 2 {
+			e.gs.font = e.font(res, a[0])
+			e.gs.size, _ = e.r.number(a[1])
+		}
+	case "Tc", "Tw", "Tz", "TL", "Ts":
+		v, ok := e.numbers(a, 1)
+		if !ok {
+			break
+		}
+		switch op.op {
+		case "Tc":
+			e.gs.tc = v[0]
+		case "Tw":
+			e.gs.tw = v[0]
+		case "Tz":
+			e.gs.th = v[0] / 100
+		case "TL":
+			e.gs.tl = v[0]
+		case "Ts":
+			e.gs.rise = v[0]
+		}
+	case "Td", "TD":
+		if v, ok := e.numbers(a, 2); ok {
+			if op.op == "TD" {
+				e.gs.tl = -v[1]
+			}
+			e.move(v[0], v[1])
+		}
+	case "Tm":
+		if v, ok := e.numbers(a, 6); ok {
+			e.tm = matrix{v[0], v[1], v[2], v[3], v[4], v[5]}
+			e.tlm = e.tm
+		}
+	case "T*":
+		e.move(0, -e.gs.tl)
+	default:
+		return false
+	}
+	return true
+}
+
+// move starts a new line at (x, y) from the start of the current line.
+func (e *extractor) move(x, y float64) {
+	e.tlm = matrix{1, 0, 0, 1, x, y}.mul(e.tlm)
+	e.tm = e.tlm
+}
+
+// show shows the strings in a, and moves by the numbers in it like TJ.
+func (e *extractor) show(a []interface{}) {
+	gs := &e.gs
+	if gs.font == nil {
+		return
+	}
+	trm := matrix{gs.size * gs.th, 0, 0, gs.size, 0, gs.rise}.mul(e.tm).mul(gs.ctm)
+	run := TextRun{X: trm[4], Y: trm[5], Size: math.Sqrt(trm[2]*trm[2] + trm[3]*trm[3])}
+	buf := new(bytes.Buffer)
+	for _, o := range a {
+		if s, ok := o.(string); ok {
+			for _, c := range gs.font.chars(s) {
+				buf.WriteString(c.text)
+				e.tm = matrix{1, 0, 0, 1, gs.advance(c) * gs.th, 0}.mul(e.tm)
+			}
+			continue
+		}
+		if v, ok := e.r.number(o); ok {
+			if v <= -spaceGap && buf.Len() > 0 && !strings.HasSuffix(buf.String(), " ") {
+				buf.WriteByte(' ')
+			}
+			e.tm = matrix{1, 0, 0, 1, -v / 1000 * gs.size * gs.th, 0}.mul(e.tm)
+		}
+	}
+	if buf.Len() > 0 {
+		run.Text = buf.String()
+		e.runs = append(e.runs, run)
+	}
+}
+
+// advance returns how far showing c moves the text, before horizontal
+// scaling.
+func (gs *textState) advance(c fchar) float64 {
+	w := gs.font.width(c.code)*gs.size + gs.tc
+	if c.code == ' ' && len(c.raw) == 1 {
+		w += gs.tw
+	}
+	return w
+}
+
+// font returns the font named n in the resources res.
+func (e *extractor) font(res map[string]interface{}, n interface{}) *rfont {
+	k, _ := n.(name)
+	fonts, _ := e.r.resolve(res["Font"]).(map[string]interface{})
+	o := fonts[string(k)]
+	if ref, ok := o.(ref); ok {
+		if f, ok := e.fonts[ref.num]; ok {
+			return f
+		}
+		d, _ := e.r.resolve(ref).(map[string]interface{})
+		if d == nil {
+			return nil
+		}
+		f := e.r.font(d)
+		e.fonts[ref.num] = f
+		return f
+	}
+	if d, ok := o.(map[string]interface{}); ok {
+		return e.r.font(d)
+	}
+	return nil
+}
+
+// form interprets the form XObject named n in the resources res. Forms with
+// no resources use the ones of the page, which are in pres.
+func (e *extractor) form(res map[string]interface{}, n, pres interface{}) {
+	k, _ := n.(name)
+	xobjs, _ := e.r.resolve(res["XObject"]).(map[string]interface{})
+	s, ok := e.r.resolve(xobjs[string(k)]).(*stream)
+	if !ok || e.r.resolve(s.dic["Subtype"]) != name("Form") || e.depth >= maxFormDepth {
+		return
+	}
+	b := e.r.decode(s)
+	if b == nil {
+		return
+	}
+	if r, ok := s.dic["Resources"]; ok {
+		pres = r
+	}
+	gs, stack, tm, tlm := e.gs, e.stack, e.tm, e.tlm
+	if m, ok := e.r.resolve(s.dic["Matrix"]).([]interface{}); ok {
+		if v, ok := e.numbers(m, 6); ok {
+			e.gs.ctm = matrix{v[0], v[1], v[2], v[3], v[4], v[5]}.mul(e.gs.ctm)
+		}
+	}
+	e.stack = nil
+	e.depth++
+	e.run(b, pres)
+	e.depth--
+	e.gs, e.stack, e.tm, e.tlm = gs, stack, tm, tlm
+}
+
+// rfont is a font of a parsed file, as much of it as is needed to find the
+// text of codes shown with it and their widths.
+type rfont struct {
+	cmap   *cmap           // ToUnicode CMap, if any
+	codes  *cmap           // CMap of the codes of Type0 fonts, if any
+	type0  bool            // codes are two bytes, unless codes says otherwise
+	ucs2   bool            // codes of the Type0 font are UTF-16
+	enc    [256]string     // text of codes of simple fonts
+	widths map[int]float64 // widths of codes, in glyph space
+	dw     float64         // width of codes not in widths
+	scale  float64         // glyph space units in text space
+}
+
+// fchar is a character code of a string shown with a font.
+type fchar struct {
+	raw  string // bytes of the code
+	code int
+	text string
+}
+
+// font reads the font dictionary d.
+func (r *Reader) font(d map[string]interface{}) *rfont {
+	f := &rfont{widths: make(map[int]float64), scale: 0.001}
+	if s, ok := r.resolve(d["ToUnicode"]).(*stream); ok {
+		if b := r.decode(s); b != nil {
+			f.cmap = parseCMap(b)
+		}
+	}
+	sub, _ := r.resolve(d["Subtype"]).(name)
+	if sub == "Type0" {
+		f.type0 = true
+		f.dw = 1000
+		switch t := r.resolve(d["Encoding"]).(type) {
+		case name:
+			f.ucs2 = strings.Contains(string(t), "UCS2") || strings.Contains(string(t), "UTF16")
+		case *stream:
+			if b := r.decode(t); b != nil {
+				f.codes = parseCMap(b)
+			}
+		}
+		a, _ := r.resolve(d["DescendantFonts"]).([]interface{})
+		if len(a) == 0 {
+			return f
+		}
+		cid, _ := r.resolve(a[0]).(map[string]interface{})
+		if dw, ok := r.number(cid["DW"]); ok {
+			f.dw = dw
+		}
+		f.cidWidths(r, cid["W"])
+		return f
+	}
+
+	switch t := r.resolve(d["Encoding"]).(type) {
+	case name:
+		f.enc = baseEncoding(t)
+	case map[string]interface{}:
+		base, _ := r.resolve(t["BaseEncoding"]).(name)
+		f.enc = baseEncoding(base)
+		diffs, _ := r.resolve(t["Differences"]).([]interface{})
+		code := 0
+		for _, o := range diffs {
+			switch o := r.resolve(o).(type) {
+			case int:
+				code = o
+			case name:
+				if code >= 0 && code < 256 {
+					f.enc[code] = glyphText(string(o))
+				}
+				code++
+			}
+		}
+	default:
+		f.enc = baseEncoding("")
+	}
+
+	if sub == "Type3" {
+		if m, ok := r.resolve(d["FontMatrix"]).([]interface{}); ok && len(m) == 6 {
+			f.scale, _ = r.number(m[0])
+		}
+	}
+	ws, _ := r.resolve(d["Widths"]).([]interface{})
+	first, _ := r.resolve(d["FirstChar"]).(int)
+	for i, w := range ws {
+		f.widths[first+i], _ = r.number(w)
+	}
+	if len(ws) == 0 {
+		// Standard fonts may have no widths; Helvetica is close enough for
+		// the ones that are not known.
+		base, _ := r.resolve(d["BaseFont"]).(name)
+		sf := stdFontNamed(string(base))
+		if sf == nil && strings.Contains(string(base), "Courier") {
+			sf = stdFonts[FontCourier]
+		}
+		if sf == nil {
+			sf = stdFonts[FontHelvetica]
+		}
+		if sf.widths == nil {
+			f.dw = courierWidth
+		} else {
+			for i, w := range sf.widths {
+				f.widths[i] = float64(w)
+			}
+		}
+	}
+	return f
+}
+
+// cidWidths reads the W array of a CIDFont (p. 440), which holds either a
+// first code followed by an array of widths, or a range of codes followed by
+// their width.
+func (f *rfont) cidWidths(r *Reader, w interface{}) {
+	a, _ := r.resolve(w).([]interface{})
+	for i := 0; i+1 < len(a); {
+		first, ok := r.resolve(a[i]).(int)
+		if !ok {
+			return
+		}
+		if ws, ok := r.resolve(a[i+1]).([]interface{}); ok {
+			for j, w := range ws {
+				f.widths[first+j], _ = r.number(w)
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(a) {
+			return
+		}
+		last, _ := r.resolve(a[i+1]).(int)
+		v, _ := r.number(a[i+2])
+		if last-first >= maxRange {
+			last = first + maxRange - 1
+		}
+		for c := first; c <= last; c++ {
+			f.widths[c] = v
+		}
+		i += 3
+	}
+}
+
+// chars splits s into the character codes of f.
+func (f *rfont) chars(s string) []fchar {
+	var cs []fchar
+	for len(s) > 0 {
+		n := 1
+		if f.codes != nil {
+			n = f.codes.codeLen(s, 2)
+		} else if f.type0 {
+			n = 2
+		}
+		if n > len(s) {
+			n = len(s)
+		}
+		c := fchar{raw: s[:n], code: codeValue(s[:n])}
+		c.text = f.text(c)
+		cs = append(cs, c)
+		s = s[n:]
+	}
+	return cs
+}
+
+// text returns the text of code c, or the replacement character if it's not
+// known.
+func (f *rfont) text(c fchar) string {
+	if f.cmap != nil {
+		if t, ok := f.cmap.chars[c.raw]; ok {
+			return t
+		}
+	}
+	t := ""
+	switch {
+	case f.ucs2:
+		t = utf16Text(c.raw)
+	case !f.type0:
+		t = f.enc[c.code]
+	}
+	if t == "" {
+		return "�"
+	}
+	return t
+}
+
+// width returns the width of code, in text space units.
+func (f *rfont) width(code int) float64 {
+	w, ok := f.widths[code]
+	if !ok {
+		w = f.dw
+	}
+	return w * f.scale
+}
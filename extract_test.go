@@ -0,0 +1,158 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testCMap is a ToUnicode CMap of a Type0 font with two-byte codes.
+const testCMap = `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def
+/CMapName /Adobe-Identity-UCS def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+1 beginbfchar
+<0001> <0048>
+endbfchar
+2 beginbfrange
+<0002> <0003> <0069>
+<0004> <0005> [<D83DDE00> <00660069>]
+endbfrange
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+
+type extractTest struct {
+	content string
+	runs    []string // x, y, size and text of each run
+}
+
+var extractTests = []extractTest{
+	{"BT /F1 10 Tf 20 30 Td (Hello) Tj ET", []string{"20 30 10 Hello"}},
+	{"BT /F1 10 Tf (AB) Tj (C) Tj ET", []string{"0 0 10 AB", "13.34 0 10 C"}},
+	{"BT /F1 10 Tf 2 Tc 1 0 0 1 5 5 Tm (A A) Tj (x) Tj ET",
+		[]string{"5 5 10 A A", "27.12 5 10 x"}},
+	{"BT /F1 10 Tf 10 Tw ( ) Tj (x) Tj ET", []string{"0 0 10  ", "12.78 0 10 x"}},
+	{"BT /F1 10 Tf [(Hel) -300 (lo) -100 (!)] TJ ET", []string{"0 0 10 Hel lo!"}},
+	{"2 0 0 2 10 10 cm BT /F1 5 Tf 1 0 0 1 3 4 Tm (x) Tj ET", []string{"16 18 10 x"}},
+	{"BT /F1 10 Tf 12 TL 0 100 Td (a) Tj T* (b) Tj (c) ' ET",
+		[]string{"0 100 10 a", "0 88 10 b", "0 76 10 c"}},
+	{"BT /F1 10 Tf 0 50 TD (a) Tj 0 -10 TD (b) Tj T* (c) Tj ET",
+		[]string{"0 50 10 a", "0 40 10 b", "0 30 10 c"}},
+	{"BT /F1 10 Tf 50 Tz 3 Ts (AB) Tj (C) Tj ET", []string{"0 3 10 AB", "6.67 3 10 C"}},
+	{"q BT /F1 20 Tf ET Q BT (x) Tj ET", nil},
+	{"BT /F1 20 Tf q /F1 5 Tf Q (x) Tj ET", []string{"0 0 20 x"}},
+	{"BT /F2 10 Tf (ABC) Tj ET", []string{"0 0 10 é€C"}},
+	{"BT /F1 10 Tf (\\200\\351) Tj /F4 10 Tf (\\267\\341) Tj ET",
+		[]string{"0 0 10 €é", "11.12 0 10 •Æ"}},
+	{"BT /F3 10 Tf <00010002000300040005> Tj ET", []string{"0 0 10 Hij😀fi"}},
+	{"BT /F3 10 Tf <0001> Tj <00FF> Tj ET", []string{"0 0 10 H", "6 0 10 �"}},
+	{"q 1 0 0 1 100 0 cm /X1 Do Q BT /F1 10 Tf (y) Tj ET",
+		[]string{"110 20 10 x", "0 0 10 y"}},
+	{"BT (x) Tj ET", nil},
+}
+
+func TestPageText(t *testing.T) {
+	res := "<< /Font << /F1 5 0 R /F2 6 0 R /F3 7 0 R /F4 10 0 R >> /XObject << /X1 9 0 R >> >>"
+	form := "BT /F1 10 Tf 10 10 Td (x) Tj ET"
+	for i, tt := range extractTests {
+		objs := append(testPage(tt.content, res),
+			"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+			"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding << /Differences [ 65 /eacute /Euro ] >> >>",
+			"<< /Type /Font /Subtype /Type0 /BaseFont /F /Encoding /Identity-H /DescendantFonts [ 11 0 R ] /ToUnicode 8 0 R >>",
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(testCMap), testCMap),
+			fmt.Sprintf("<< /Type /XObject /Subtype /Form /BBox [ 0 0 100 100 ] /Matrix [ 1 0 0 1 0 10 ] /Length %d >>\nstream\n%s\nendstream", len(form), form),
+			"<< /Type /Font /Subtype /Type1 /BaseFont /Times-Roman >>",
+			"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /F /DW 500 /W [ 1 [ 600 ] 2 3 400 ] >>")
+		r, err := NewReader(testFile(objs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs, err := r.PageText(1)
+		if err != nil {
+			t.Errorf("%d. %s", i, err)
+			continue
+		}
+		var got []string
+		for _, run := range runs {
+			got = append(got, fmt.Sprint(ftoa(run.X), " ", ftoa(run.Y), " ", ftoa(run.Size), " ", run.Text))
+		}
+		if strings.Join(got, "|") != strings.Join(tt.runs, "|") {
+			t.Errorf("%d. %s\ngot  %q\nwant %q", i, tt.content, got, tt.runs)
+		}
+	}
+}
+
+func TestPageTextErrors(t *testing.T) {
+	r, err := NewReader(testFile(testPage("BT /F1 10 Tf (x) Tj", "<< >>")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, 2} {
+		if _, err := r.PageText(n); err == nil {
+			t.Errorf("no error for text of page %d", n)
+		}
+	}
+	if runs, err := r.PageText(1); err != nil || len(runs) != 0 {
+		t.Errorf("text of undefined font: got %v, %v", runs, err)
+	}
+}
+
+func TestParseCMap(t *testing.T) {
+	m := parseCMap([]byte(testCMap))
+	for _, tt := range []struct{ code, text string }{
+		{"\x00\x01", "H"},
+		{"\x00\x02", "i"},
+		{"\x00\x03", "j"},
+		{"\x00\x04", "😀"},
+		{"\x00\x05", "fi"},
+		{"\x00\x06", ""},
+	} {
+		if got := m.chars[tt.code]; got != tt.text {
+			t.Errorf("text of %q: got %q, want %q", tt.code, got, tt.text)
+		}
+	}
+	if n := m.codeLen("\x01\x02\x03", 1); n != 2 {
+		t.Errorf("length of code: got %d, want 2", n)
+	}
+}
+
+func TestGlyphText(t *testing.T) {
+	for _, tt := range []struct{ name, text string }{
+		{"A", "A"},
+		{"space", " "},
+		{"eacute", "é"},
+		{"Euro", "€"},
+		{"fi", "ﬁ"},
+		{"uni0628", "ب"},
+		{"u1F600", "😀"},
+		{"a.sc", "a"},
+		{"unknown", ""},
+		{"uniXYZW", ""},
+	} {
+		if got := glyphText(tt.name); got != tt.text {
+			t.Errorf("text of glyph %s: got %q, want %q", tt.name, got, tt.text)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file decodes the data of streams of existing files with the standard
+// filters (p. 67), and encodes data with Flate.
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"io"
+)
+
+// decodeFilter returns b decoded with filter f, whose parameters are parms.
+// It returns nil if the filter is not supported, like the ones of images
+// which are decoded by image decoders.
+func decodeFilter(f name, b []byte, parms map[string]interface{}) []byte {
+	switch f {
+	case "FlateDecode", "Fl":
+		r, err := zlib.NewReader(bytes.NewBuffer(b))
+		checkFile(err)
+		d, err := io.ReadAll(r)
+		if err != nil && len(d) == 0 {
+			// Some files end their data early, which is
+			// harmless if anything was decoded.
+			checkFile(err)
+		}
+		return predict(d, parms)
+	case "LZWDecode", "LZW":
+		early := true
+		if e, ok := parms["EarlyChange"].(int); ok && e == 0 {
+			early = false
+		}
+		return predict(lzwDecode(b, early), parms)
+	case "ASCIIHexDecode", "AHx":
+		p := &parser{b: append(append([]byte{}, b...), '>')}
+		return []byte(p.hex())
+	case "ASCII85Decode", "A85":
+		if i := bytes.Index(b, []byte("~>")); i >= 0 {
+			b = b[:i]
+		}
+		b = bytes.TrimLeft(b, " \t\r\n")
+		if bytes.HasPrefix(b, []byte("<~")) {
+			b = b[2:]
+		}
+		d, err := io.ReadAll(ascii85.NewDecoder(bytes.NewBuffer(b)))
+		check(err)
+		return d
+	case "RunLengthDecode", "RL":
+		return runLengthDecode(b)
+	case "Crypt":
+		// Streams with other crypt filters are not supported.
+		if n, _ := parms["Name"].(name); n == "" || n == "Identity" {
+			return b
+		}
+	}
+	return nil
+}
+
+// decodeFilters returns b decoded with filters fs, whose parameters are
+// parms. It returns nil if any of the filters is not supported.
+func decodeFilters(b []byte, fs []name, parms []map[string]interface{}) []byte {
+	for i, f := range fs {
+		if b = decodeFilter(f, b, parms[i]); b == nil {
+			return nil
+		}
+	}
+	return b
+}
+
+// flateEncode returns b compressed with FlateDecode.
+func flateEncode(b []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	w, err := zlib.NewWriterLevel(buf, zlib.BestCompression)
+	check(err)
+	_, err = w.Write(b)
+	check(err)
+	check(w.Close())
+	return buf.Bytes()
+}
+
+// runLengthDecode decodes b with RunLengthDecode (p. 80).
+func runLengthDecode(b []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < len(b); {
+		n := int(b[i])
+		i++
+		switch {
+		case n == 128:
+			return buf.Bytes()
+		case n < 128:
+			if i+n+1 > len(b) {
+				fail(ErrBadFile, "bad RunLengthDecode data")
+			}
+			buf.Write(b[i : i+n+1])
+			i += n + 1
+		default:
+			if i >= len(b) {
+				fail(ErrBadFile, "bad RunLengthDecode data")
+			}
+			for j := 0; j < 257-n; j++ {
+				buf.WriteByte(b[i])
+			}
+			i++
+		}
+	}
+	return buf.Bytes()
+}
+
+// lzwDecode decodes b with LZWDecode (p. 71). Codes get one bit longer one
+// code early if early is true.
+func lzwDecode(b []byte, early bool) []byte {
+	const clear, eod = 256, 257
+	buf := bytes.NewBuffer(nil)
+	var table [][]byte
+	reset := func() {
+		table = make([][]byte, 258, 4096)
+		for i := 0; i < 256; i++ {
+			table[i] = []byte{byte(i)}
+		}
+	}
+	reset()
+	width, prev := 9, []byte(nil)
+	var acc uint32
+	bits := 0
+	for _, c := range b {
+		acc = acc<<8 | uint32(c)
+		bits += 8
+		for bits >= width {
+			code := int(acc >> uint(bits-width) & (1<<uint(width) - 1))
+			bits -= width
+			switch {
+			case code == clear:
+				reset()
+				width, prev = 9, nil
+				continue
+			case code == eod:
+				return buf.Bytes()
+			}
+			var e []byte
+			switch {
+			case code < len(table):
+				e = table[code]
+			case code == len(table) && prev != nil:
+				e = append(append([]byte{}, prev...), prev[0])
+			default:
+				fail(ErrBadFile, "bad LZWDecode data")
+			}
+			buf.Write(e)
+			if prev != nil && len(table) < 4096 {
+				table = append(table, append(append([]byte{}, prev...), e[0]))
+			}
+			prev = e
+			n := len(table)
+			if early {
+				n++
+			}
+			switch {
+			case n >= 2048:
+				width = 12
+			case n >= 1024:
+				width = 11
+			case n >= 512:
+				width = 10
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// predict reverses the predictor of Flate and LZW filters (p. 76), if parms
+// has one.
+func predict(b []byte, parms map[string]interface{}) []byte {
+	pred, _ := parms["Predictor"].(int)
+	if pred <= 1 {
+		return b
+	}
+	colors, bpc, cols := 1, 8, 1
+	if v, ok := parms["Colors"].(int); ok {
+		colors = v
+	}
+	if v, ok := parms["BitsPerComponent"].(int); ok {
+		bpc = v
+	}
+	if v, ok := parms["Columns"].(int); ok {
+		cols = v
+	}
+	bpp := (colors*bpc + 7) / 8 // bytes per pixel, at least 1
+	row := (colors*bpc*cols + 7) / 8
+
+	if pred == 2 {
+		// TIFF predictor 2, only for 8 bits per component
+		if bpc != 8 {
+			fail(ErrBadFile, "unsupported TIFF predictor")
+		}
+		for i := 0; i+row <= len(b); i += row {
+			for j := bpp; j < row; j++ {
+				b[i+j] += b[i+j-bpp]
+			}
+		}
+		return b
+	}
+
+	// PNG predictors have a byte before each row for its filter.
+	out := make([]byte, 0, len(b))
+	prev := make([]byte, row)
+	for i := 0; i+row+1 <= len(b); i += row + 1 {
+		f, cur := b[i], b[i+1:i+1+row]
+		for j := 0; j < row; j++ {
+			var a, c byte
+			if j >= bpp {
+				a, c = cur[j-bpp], prev[j-bpp]
+			}
+			up := prev[j]
+			switch f {
+			case 1:
+				cur[j] += a
+			case 2:
+				cur[j] += up
+			case 3:
+				cur[j] += byte((int(a) + int(up)) / 2)
+			case 4:
+				cur[j] += paeth(a, up, c)
+			}
+		}
+		out = append(out, cur...)
+		prev = cur
+	}
+	return out
+}
+
+// paeth returns the Paeth predictor of PNG.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"testing"
+)
+
+type filterTest struct {
+	f     name
+	in    string
+	parms map[string]interface{}
+	out   string
+}
+
+// flate returns b compressed with zlib.
+func flate(b []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	w, _ := zlib.NewWriterLevel(buf, zlib.DefaultCompression)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeFilter(t *testing.T) {
+	tests := []filterTest{
+		{"ASCIIHexDecode", "48 65 6C6c 6f 2>", nil, "Hello "},
+		{"AHx", "414>", nil, "A@"},
+		{"ASCII85Decode", "<~87cURD]i,\"Ebo80~>", nil, "Hello World!"},
+		{"ASCII85Decode", "z~>", nil, "\x00\x00\x00\x00"},
+		{"RunLengthDecode", "\x02abc\xfdx\x80", nil, "abcxxxx"},
+		// Example of LZWDecode in PDF Reference (p. 74)
+		{"LZWDecode", "\x80\x0b\x60\x50\x22\x0c\x0c\x85\x01", nil, "-----A---B"},
+		{"FlateDecode", string(flate([]byte("q 1 0 0 1 0 0 cm Q"))), nil, "q 1 0 0 1 0 0 cm Q"},
+		// PNG predictors: None, Sub, Up, Average, Paeth
+		{"FlateDecode", string(flate([]byte("\x00\x01\x02\x03\x01\x01\x01\x01\x02\x01\x01\x01" +
+			"\x03\x02\x02\x02\x04\x01\x01\x01"))),
+			map[string]interface{}{"Predictor": 12, "Columns": 3},
+			"\x01\x02\x03\x01\x02\x03\x02\x03\x04\x03\x05\x06\x04\x06\x07"},
+		{"FlateDecode", string(flate([]byte("\x01\x01\x01\x05\x05\x05"))),
+			map[string]interface{}{"Predictor": 2, "Columns": 3},
+			"\x01\x02\x03\x05\x0a\x0f"},
+		{"DCTDecode", "\xff\xd8", nil, ""},
+	}
+
+	for _, test := range tests {
+		out := decodeFilter(test.f, []byte(test.in), test.parms)
+		if string(out) != test.out {
+			t.Errorf("%s %q: got %q expected %q", test.f, test.in, out, test.out)
+		}
+	}
+}
@@ -0,0 +1,718 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file makes static instances of variable TrueType fonts, with the
+// outlines and advance widths their glyphs have at the given coordinates of
+// their axes of variation, so that the chosen weight or width is kept when
+// the fonts are used by documents.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// FontAxis is an axis of variation of a variable font, like its weight or
+// width.
+type FontAxis struct {
+	Tag               string // like "wght" or "wdth"
+	Min, Default, Max float64
+}
+
+// variationTables are the tables of variable fonts that static instances
+// don't have. DSIG is dropped too, since the font is changed.
+var variationTables = []string{"fvar", "gvar", "avar", "cvar", "HVAR", "VVAR", "MVAR", "STAT", "DSIG"}
+
+// FontAxes returns the axes of variation of the TrueType or OpenType font
+// file b, or none if it's not a variable font.
+func FontAxes(b []byte) (axes []FontAxis, err error) {
+	defer dontPanic(&err)
+
+	return parseSfnt(b).axes(), nil
+}
+
+// InstanceFont returns a static instance of the variable TrueType font in the
+// font file b at the given coordinates of its axes, by their tags, like
+// {"wght": 700}. Axes not in coords are at their defaults, and coordinates
+// out of the range of an axis are clamped to it. The instance can be used
+// like any other TrueType font, like with ColorFont.
+//
+// Outlines and advance widths of glyphs are changed; variations of hinting
+// and of metrics of the whole font, like its ascender, are dropped. Fonts
+// with CFF2 outlines are not supported.
+func InstanceFont(b []byte, coords map[string]float64) (inst []byte, err error) {
+	defer dontPanic(&err)
+
+	f := parseSfnt(b)
+	axes := f.axes()
+	if len(axes) == 0 {
+		panic("not a variable font")
+	}
+	if _, ok := f.tables["CFF2"]; ok {
+		fail(ErrBadFile, "variable fonts with CFF2 outlines are not supported")
+	}
+	for tag := range coords {
+		known := false
+		for _, a := range axes {
+			known = known || a.Tag == tag
+		}
+		if !known {
+			panic("font has no axis " + tag)
+		}
+	}
+	return f.instance(coords, f.normalize(axes, coords)), nil
+}
+
+// axes returns the axes of variation in the fvar table of f, if it has one.
+func (f *sfnt) axes() []FontAxis {
+	t, ok := f.tables["fvar"]
+	if !ok {
+		return nil
+	}
+	off, n, size := int(u16(t, 4)), int(u16(t, 8)), int(u16(t, 10))
+	axes := make([]FontAxis, n)
+	for i := range axes {
+		r := off + i*size
+		if size < 20 || r+20 > len(t) {
+			fail(ErrBadFile, "font axes out of the fvar table")
+		}
+		axes[i] = FontAxis{string(t[r : r+4]), fixed(t, r+4), fixed(t, r+8), fixed(t, r+12)}
+	}
+	return axes
+}
+
+// normalize returns coords normalized to -1 to 1 for the axes of f, with the
+// defaults at 0, as changed by the avar table of f.
+func (f *sfnt) normalize(axes []FontAxis, coords map[string]float64) []float64 {
+	v := make([]float64, len(axes))
+	for i, a := range axes {
+		c, ok := coords[a.Tag]
+		if !ok {
+			continue
+		}
+		c = math.Max(a.Min, math.Min(a.Max, c))
+		switch {
+		case c < a.Default:
+			v[i] = (c - a.Default) / (a.Default - a.Min)
+		case c > a.Default:
+			v[i] = (c - a.Default) / (a.Max - a.Default)
+		}
+	}
+	t, ok := f.tables["avar"]
+	if !ok {
+		return v
+	}
+	p := 8
+	for i := range axes {
+		n := int(u16(t, p))
+		p += 2
+		// Segments map ranges of coordinates linearly.
+		for k := 0; k+1 < n; k++ {
+			from0, to0 := f2dot14(t, p+4*k), f2dot14(t, p+4*k+2)
+			from1, to1 := f2dot14(t, p+4*k+4), f2dot14(t, p+4*k+6)
+			if v[i] >= from0 && v[i] <= from1 {
+				if from1 > from0 {
+					v[i] = to0 + (v[i]-from0)*(to1-to0)/(from1-from0)
+				} else {
+					v[i] = to0
+				}
+				break
+			}
+		}
+		p += 4 * n
+	}
+	return v
+}
+
+// fixed, f2dot14 and u8 read numbers from b at offset off, panicking if b is
+// too short.
+func fixed(b []byte, off int) float64 {
+	return float64(int32(u32(b, off))) / 65536
+}
+
+func f2dot14(b []byte, off int) float64 {
+	return float64(int16(u16(b, off))) / 16384
+}
+
+func u8(b []byte, off int) byte {
+	if off < 0 || off >= len(b) {
+		fail(ErrBadFile, "font data too short")
+	}
+	return b[off]
+}
+
+// instance returns the font file of the instance of f at the normalized
+// coordinates v, which are coords given to InstanceFont.
+func (f *sfnt) instance(coords map[string]float64, v []float64) []byte {
+	gv := f.gvar()
+	n := f.numGlyphs
+	glyphs := make([][]byte, n)
+	dadv := make([]float64, n)
+	for g := range glyphs {
+		glyphs[g], dadv[g] = f.instanceGlyph(gv, g, v)
+	}
+	boxes := make([]*[4]float64, n)
+	font := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for g := range glyphs {
+		if bb := glyphBox(glyphs, boxes, g, 0); bb != nil {
+			font = [4]float64{math.Min(font[0], bb[0]), math.Min(font[1], bb[1]),
+				math.Max(font[2], bb[2]), math.Max(font[3], bb[3])}
+			if int16(u16(glyphs[g], 0)) < 0 {
+				putBox(glyphs[g], bb)
+			}
+		}
+	}
+
+	tables := make(map[string][]byte, len(f.tables))
+	for tag, t := range f.tables {
+		tables[tag] = t
+	}
+	for _, tag := range variationTables {
+		delete(tables, tag)
+	}
+	var glyf, loca []byte
+	for _, g := range glyphs {
+		loca = binary.BigEndian.AppendUint32(loca, uint32(len(glyf)))
+		glyf = append(glyf, g...)
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0)
+		}
+	}
+	tables["glyf"] = glyf
+	tables["loca"] = binary.BigEndian.AppendUint32(loca, uint32(len(glyf)))
+
+	head := append([]byte(nil), f.table("head", 54)...)
+	binary.BigEndian.PutUint16(head[50:], 1) // long offsets in loca
+	if !math.IsInf(font[0], 0) {
+		putBox(head[34:], &font)
+	}
+	tables["head"] = head
+
+	// All glyphs get their own advance widths, as they may not be the
+	// same anymore.
+	hhea, hmtx := append([]byte(nil), f.table("hhea", 36)...), f.table("hmtx", 0)
+	nm := int(u16(hhea, 34))
+	if nm == 0 {
+		fail(ErrBadFile, "font with no horizontal metrics")
+	}
+	var mtx []byte
+	widest := 0
+	for g := 0; g < n; g++ {
+		m := g
+		if m >= nm {
+			m = nm - 1
+		}
+		adv := int(math.Max(0, math.Round(float64(u16(hmtx, 4*m))+dadv[g])))
+		if adv > widest {
+			widest = adv
+		}
+		lsb := 0.0
+		if boxes[g] != nil {
+			lsb = boxes[g][0]
+		}
+		mtx = binary.BigEndian.AppendUint16(mtx, uint16(adv))
+		mtx = binary.BigEndian.AppendUint16(mtx, uint16(int16(lsb)))
+	}
+	binary.BigEndian.PutUint16(hhea[10:], uint16(widest))
+	binary.BigEndian.PutUint16(hhea[34:], uint16(n))
+	tables["hhea"], tables["hmtx"] = hhea, mtx
+
+	if os2, ok := f.tables["OS/2"]; ok && len(os2) >= 8 {
+		os2 = append([]byte(nil), os2...)
+		if w, ok := coords["wght"]; ok {
+			binary.BigEndian.PutUint16(os2[4:], uint16(math.Max(1, math.Min(1000, math.Round(w)))))
+		}
+		if w, ok := coords["wdth"]; ok {
+			binary.BigEndian.PutUint16(os2[6:], uint16(widthClass(w)))
+		}
+		tables["OS/2"] = os2
+	}
+	return writeSfnt(tables)
+}
+
+// widthClass returns the width class of OS/2 for width w, in percents of the
+// normal width.
+func widthClass(w float64) int {
+	widths := []float64{50, 62.5, 75, 87.5, 100, 112.5, 125, 150, 200}
+	c := 1
+	for i, x := range widths {
+		if math.Abs(w-x) < math.Abs(w-widths[c-1]) {
+			c = i + 1
+		}
+	}
+	return c
+}
+
+// putBox writes the bounding box bb to b, like the header of a glyph from
+// offset 2, or the head table from offset 36.
+func putBox(b []byte, bb *[4]float64) {
+	for i, x := range bb {
+		binary.BigEndian.PutUint16(b[2+2*i:], uint16(int16(math.Round(x))))
+	}
+}
+
+// glyphBox returns the bounding box of glyph g of glyphs, or nil if it has no
+// outline. Boxes are kept in boxes.
+func glyphBox(glyphs [][]byte, boxes []*[4]float64, g, depth int) *[4]float64 {
+	if g >= len(glyphs) || len(glyphs[g]) < 10 || depth > 8 {
+		return nil
+	}
+	if boxes[g] != nil {
+		return boxes[g]
+	}
+	b := glyphs[g]
+	if int16(u16(b, 0)) >= 0 {
+		boxes[g] = &[4]float64{float64(int16(u16(b, 2))), float64(int16(u16(b, 4))),
+			float64(int16(u16(b, 6))), float64(int16(u16(b, 8)))}
+		return boxes[g]
+	}
+	// Boxes of components are transformed like the components.
+	var bb *[4]float64
+	p := 10
+	for {
+		flags, cg := u16(b, p), int(u16(b, p+2))
+		// Arguments of instances are always words.
+		dx, dy := float64(int16(u16(b, p+4))), float64(int16(u16(b, p+6)))
+		if flags&2 == 0 {
+			dx, dy = 0, 0
+		}
+		p += 8
+		m := matrix{1, 0, 0, 1, dx, dy}
+		switch {
+		case flags&8 != 0:
+			m[0], m[3] = f2dot14(b, p), f2dot14(b, p)
+			p += 2
+		case flags&0x40 != 0:
+			m[0], m[3] = f2dot14(b, p), f2dot14(b, p+2)
+			p += 4
+		case flags&0x80 != 0:
+			m[0], m[1], m[2], m[3] = f2dot14(b, p), f2dot14(b, p+2), f2dot14(b, p+4), f2dot14(b, p+6)
+			p += 8
+		}
+		if c := glyphBox(glyphs, boxes, cg, depth+1); c != nil {
+			for _, pt := range [][2]float64{{c[0], c[1]}, {c[0], c[3]}, {c[2], c[1]}, {c[2], c[3]}} {
+				x, y := m.apply(pt[0], pt[1])
+				if bb == nil {
+					bb = &[4]float64{x, y, x, y}
+				}
+				bb[0], bb[1] = math.Min(bb[0], x), math.Min(bb[1], y)
+				bb[2], bb[3] = math.Max(bb[2], x), math.Max(bb[3], y)
+			}
+		}
+		if flags&0x20 == 0 {
+			break
+		}
+	}
+	boxes[g] = bb
+	return bb
+}
+
+// gvar is the table of variations of the glyphs of a font.
+type gvar struct {
+	t       []byte
+	axes    int
+	shared  [][]float64 // shared peak tuples
+	offsets []int       // offsets of the variations of glyphs in t
+}
+
+// gvar returns the gvar table of f, or nil if its glyphs don't vary.
+func (f *sfnt) gvar() *gvar {
+	t, ok := f.tables["gvar"]
+	if !ok {
+		return nil
+	}
+	gv := &gvar{t: t, axes: int(u16(t, 4))}
+	for i, n, p := 0, int(u16(t, 6)), int(u32(t, 8)); i < n; i++ {
+		gv.shared = append(gv.shared, gv.tuple(p+2*gv.axes*i))
+	}
+	n, long, data := int(u16(t, 12)), u16(t, 14)&1 != 0, int(u32(t, 16))
+	for i := 0; i <= n; i++ {
+		if long {
+			gv.offsets = append(gv.offsets, data+int(u32(t, 20+4*i)))
+		} else {
+			gv.offsets = append(gv.offsets, data+2*int(u16(t, 20+2*i)))
+		}
+	}
+	return gv
+}
+
+// tuple returns the tuple of coordinates at offset p of gv.
+func (gv *gvar) tuple(p int) []float64 {
+	c := make([]float64, gv.axes)
+	for i := range c {
+		c[i] = f2dot14(gv.t, p+2*i)
+	}
+	return c
+}
+
+// deltas returns how much the np points of glyph g, followed by its four
+// phantom points, move at the normalized coordinates v. Deltas of points
+// of simple glyph sg that aren't given are inferred from their neighbors.
+func (gv *gvar) deltas(g, np int, sg *simpleGlyph, v []float64) (dx, dy []float64) {
+	dx, dy = make([]float64, np+4), make([]float64, np+4)
+	if gv == nil || g+1 >= len(gv.offsets) || gv.offsets[g] >= gv.offsets[g+1] {
+		return dx, dy
+	}
+	if gv.offsets[g+1] > len(gv.t) {
+		fail(ErrBadFile, fmt.Sprint("variations of glyph ", g, " out of the gvar table"))
+	}
+	d := gv.t[gv.offsets[g]:gv.offsets[g+1]]
+	tc, sp := u16(d, 0), int(u16(d, 2))
+	var shared []int
+	if tc&0x8000 != 0 {
+		shared, sp = packedPoints(d, sp)
+	}
+	p := 4
+	for i := 0; i < int(tc&0xfff); i++ {
+		size, idx := int(u16(d, p)), u16(d, p+2)
+		p += 4
+		var peak, start, end []float64
+		if idx&0x8000 != 0 {
+			peak = tupleAt(d, p, gv.axes)
+			p += 2 * gv.axes
+		} else if int(idx&0xfff) < len(gv.shared) {
+			peak = gv.shared[idx&0xfff]
+		} else {
+			fail(ErrBadFile, "shared tuple out of the gvar table")
+		}
+		if idx&0x4000 != 0 {
+			start, end = tupleAt(d, p, gv.axes), tupleAt(d, p+2*gv.axes, gv.axes)
+			p += 4 * gv.axes
+		}
+		q := sp
+		sp += size
+		s := tupleScalar(v, peak, start, end)
+		if s == 0 {
+			continue
+		}
+		pts := shared
+		if idx&0x2000 != 0 {
+			pts, q = packedPoints(d, q)
+		}
+		m := len(pts)
+		if pts == nil {
+			m = np + 4
+		}
+		xs, q := packedDeltas(d, q, m)
+		ys, _ := packedDeltas(d, q, m)
+		tx, ty := make([]float64, np+4), make([]float64, np+4)
+		touched := make([]bool, np+4)
+		for k := 0; k < m; k++ {
+			pt := k
+			if pts != nil {
+				pt = pts[k]
+			}
+			if pt < np+4 {
+				tx[pt], ty[pt], touched[pt] = xs[k], ys[k], true
+			}
+		}
+		if pts != nil && sg != nil {
+			sg.infer(tx, ty, touched)
+		}
+		for k := range dx {
+			dx[k] += s * tx[k]
+			dy[k] += s * ty[k]
+		}
+	}
+	return dx, dy
+}
+
+// tupleAt returns the tuple of n coordinates at offset p of b.
+func tupleAt(b []byte, p, n int) []float64 {
+	c := make([]float64, n)
+	for i := range c {
+		c[i] = f2dot14(b, p+2*i)
+	}
+	return c
+}
+
+// tupleScalar returns how much of the deltas of a variation with the given
+// peak, and start and end if it's intermediate, apply at coordinates v.
+func tupleScalar(v, peak, start, end []float64) float64 {
+	s := 1.0
+	for i, pk := range peak {
+		c := 0.0
+		if i < len(v) {
+			c = v[i]
+		}
+		if pk == 0 {
+			continue
+		}
+		if c == 0 {
+			return 0
+		}
+		if start != nil {
+			st, en := start[i], end[i]
+			if st > pk || pk > en || (st < 0 && en > 0) {
+				continue
+			}
+			if c < st || c > en {
+				return 0
+			}
+			if c < pk {
+				s *= (c - st) / (pk - st)
+			} else if c > pk {
+				s *= (en - c) / (en - pk)
+			}
+			continue
+		}
+		if c < math.Min(0, pk) || c > math.Max(0, pk) {
+			return 0
+		}
+		s *= c / pk
+	}
+	return s
+}
+
+// packedPoints returns the packed point numbers at offset p of b, or nil if
+// they are all the points, and the offset after them.
+func packedPoints(b []byte, p int) ([]int, int) {
+	n := int(u8(b, p))
+	p++
+	if n == 0 {
+		return nil, p
+	}
+	if n&0x80 != 0 {
+		n = (n&0x7f)<<8 | int(u8(b, p))
+		p++
+	}
+	pts := make([]int, 0, n)
+	last := 0
+	for len(pts) < n {
+		c := u8(b, p)
+		p++
+		for k := 0; k <= int(c&0x7f) && len(pts) < n; k++ {
+			if c&0x80 != 0 {
+				last += int(u16(b, p))
+				p += 2
+			} else {
+				last += int(u8(b, p))
+				p++
+			}
+			pts = append(pts, last)
+		}
+	}
+	return pts, p
+}
+
+// packedDeltas returns n packed deltas at offset p of b, and the offset after
+// them.
+func packedDeltas(b []byte, p, n int) ([]float64, int) {
+	ds := make([]float64, 0, n)
+	for len(ds) < n {
+		c := u8(b, p)
+		p++
+		for k := 0; k <= int(c&0x3f) && len(ds) < n; k++ {
+			switch {
+			case c&0x80 != 0:
+				ds = append(ds, 0)
+			case c&0x40 != 0:
+				ds = append(ds, float64(int16(u16(b, p))))
+				p += 2
+			default:
+				ds = append(ds, float64(int8(u8(b, p))))
+				p++
+			}
+		}
+	}
+	return ds, p
+}
+
+// infer sets the deltas of the points of sg that aren't touched, from the
+// nearest touched points before and after them on their contours.
+func (sg *simpleGlyph) infer(dx, dy []float64, touched []bool) {
+	start := 0
+	for _, end := range sg.ends {
+		var ref []int
+		for i := start; i <= end; i++ {
+			if touched[i] {
+				ref = append(ref, i)
+			}
+		}
+		if len(ref) > 0 {
+			for i := start; i <= end; i++ {
+				if touched[i] {
+					continue
+				}
+				prev, next := ref[len(ref)-1], ref[0]
+				for _, r := range ref {
+					if r > i {
+						next = r
+						break
+					}
+					prev = r
+				}
+				dx[i] = interpolate(sg.xs, dx, prev, next, i)
+				dy[i] = interpolate(sg.ys, dy, prev, next, i)
+			}
+		}
+		start = end + 1
+	}
+}
+
+// interpolate returns the delta of point i with coordinates c, between points
+// p and n with deltas d.
+func interpolate(c, d []float64, p, n, i int) float64 {
+	c1, c2, d1, d2 := c[p], c[n], d[p], d[n]
+	if c1 == c2 {
+		if d1 == d2 {
+			return d1
+		}
+		return 0
+	}
+	if c1 > c2 {
+		c1, c2, d1, d2 = c2, c1, d2, d1
+	}
+	switch x := c[i]; {
+	case x <= c1:
+		return d1
+	case x >= c2:
+		return d2
+	default:
+		return d1 + (x-c1)*(d2-d1)/(c2-c1)
+	}
+}
+
+// instanceGlyph returns the data of glyph g of f at the normalized
+// coordinates v, and how much its advance width changes.
+func (f *sfnt) instanceGlyph(gv *gvar, g int, v []float64) ([]byte, float64) {
+	b := f.glyphData(g)
+	if len(b) == 0 {
+		dx, _ := gv.deltas(g, 0, nil, v)
+		return nil, dx[1] - dx[0]
+	}
+	if int16(u16(b, 0)) < 0 {
+		n := 0
+		for p := 10; ; n++ {
+			flags := u16(b, p)
+			p += 6
+			if flags&1 != 0 {
+				p += 2
+			}
+			switch {
+			case flags&8 != 0:
+				p += 2
+			case flags&0x40 != 0:
+				p += 4
+			case flags&0x80 != 0:
+				p += 8
+			}
+			if flags&0x20 == 0 {
+				n++
+				break
+			}
+		}
+		dx, dy := gv.deltas(g, n, nil, v)
+		return instanceComposite(b, dx, dy), dx[n+1] - dx[n]
+	}
+	sg := parseGlyph(b, g)
+	n := len(sg.xs)
+	dx, dy := gv.deltas(g, n, sg, v)
+	for i := range sg.xs {
+		sg.xs[i] = math.Round(sg.xs[i] + dx[i])
+		sg.ys[i] = math.Round(sg.ys[i] + dy[i])
+	}
+	return sg.encode(), dx[n+1] - dx[n]
+}
+
+// instanceComposite returns the composite glyph b with the offsets of its
+// components moved by dx and dy. Arguments of components are all made words,
+// so that the offsets fit.
+func instanceComposite(b []byte, dx, dy []float64) []byte {
+	out := append([]byte(nil), b[:10]...)
+	for p, i := 10, 0; ; i++ {
+		flags, g := u16(b, p), u16(b, p+2)
+		p += 4
+		var a1, a2 int
+		switch {
+		case flags&1 != 0 && flags&2 != 0:
+			a1, a2 = int(int16(u16(b, p))), int(int16(u16(b, p+2)))
+			p += 4
+		case flags&1 != 0:
+			a1, a2 = int(u16(b, p)), int(u16(b, p+2))
+			p += 4
+		case flags&2 != 0:
+			a1, a2 = int(int8(u8(b, p))), int(int8(u8(b, p+1)))
+			p += 2
+		default:
+			a1, a2 = int(u8(b, p)), int(u8(b, p+1))
+			p += 2
+		}
+		// Only offsets vary; the other arguments are numbers of points.
+		if flags&2 != 0 {
+			a1 += int(math.Round(dx[i]))
+			a2 += int(math.Round(dy[i]))
+		}
+		out = binary.BigEndian.AppendUint16(out, flags|1)
+		out = binary.BigEndian.AppendUint16(out, g)
+		out = binary.BigEndian.AppendUint16(out, uint16(a1))
+		out = binary.BigEndian.AppendUint16(out, uint16(a2))
+		n := 0
+		switch {
+		case flags&8 != 0:
+			n = 2
+		case flags&0x40 != 0:
+			n = 4
+		case flags&0x80 != 0:
+			n = 8
+		}
+		if p+n > len(b) {
+			fail(ErrBadFile, "composite glyph too short")
+		}
+		out = append(out, b[p:p+n]...)
+		p += n
+		if flags&0x20 == 0 {
+			if flags&0x100 != 0 { // instructions
+				out = append(out, b[p:]...)
+			}
+			return out
+		}
+	}
+}
+
+// encode returns the data of simple glyph sg in the glyf table. All the
+// coordinates are written as words.
+func (sg *simpleGlyph) encode() []byte {
+	var bb [4]float64
+	for i := range sg.xs {
+		if i == 0 {
+			bb = [4]float64{sg.xs[0], sg.ys[0], sg.xs[0], sg.ys[0]}
+		}
+		bb[0], bb[1] = math.Min(bb[0], sg.xs[i]), math.Min(bb[1], sg.ys[i])
+		bb[2], bb[3] = math.Max(bb[2], sg.xs[i]), math.Max(bb[3], sg.ys[i])
+	}
+	b := make([]byte, 10, 12+2*len(sg.ends)+len(sg.instr)+5*len(sg.xs))
+	binary.BigEndian.PutUint16(b, uint16(len(sg.ends)))
+	putBox(b, &bb)
+	for _, e := range sg.ends {
+		b = binary.BigEndian.AppendUint16(b, uint16(e))
+	}
+	b = binary.BigEndian.AppendUint16(b, uint16(len(sg.instr)))
+	b = append(b, sg.instr...)
+	for _, fl := range sg.flags {
+		b = append(b, fl&0x41) // on the curve, and overlapping contours
+	}
+	for _, c := range [][]float64{sg.xs, sg.ys} {
+		last := 0
+		for _, x := range c {
+			b = binary.BigEndian.AppendUint16(b, uint16(int16(int(x)-last)))
+			last = int(x)
+		}
+	}
+	return b
+}
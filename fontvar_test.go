@@ -0,0 +1,129 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// be returns the big-endian encoding of vs.
+func be(vs ...interface{}) []byte {
+	b := new(bytes.Buffer)
+	for _, v := range vs {
+		binary.Write(b, binary.BigEndian, v)
+	}
+	return b.Bytes()
+}
+
+// testVariableFont returns a variable TrueType font with a weight axis from
+// 100 to 900. Glyph 1 is a square whose right side moves 40 units, and which
+// gets 40 units wider, at the heaviest weight. Glyph 2 is glyph 1 moved 10
+// units, and then 30 more at the heaviest weight.
+func testVariableFont() []byte {
+	square := be(int16(1), make([]byte, 8), uint16(3), uint16(0), []byte{1, 1, 1, 1},
+		int16(0), int16(100), int16(0), int16(-100), int16(0), int16(0), int16(100), int16(0))
+	composite := be(int16(-1), make([]byte, 8), uint16(2), uint16(1), int8(10), int8(0))
+	glyf := append(append([]byte{}, square...), composite...)
+	v1 := be(uint16(1), uint16(10), uint16(10), uint16(0xa000), uint16(0x4000),
+		[]byte{3, 2, 0, 1, 4}, []byte{2, 0, 40, 40}, []byte{0x82})
+	v2 := be(uint16(1), uint16(10), uint16(7), uint16(0x8000), uint16(0x4000),
+		[]byte{4, 30, 0, 0, 0, 0}, []byte{0x84})
+	gvar := be(uint16(1), uint16(0), uint16(1), uint16(0), uint32(0), uint16(3), uint16(1), uint32(36),
+		uint32(0), uint32(0), uint32(len(v1)), uint32(len(v1)+len(v2)), v1, v2)
+	return writeSfnt(map[string][]byte{
+		"head": be(make([]byte, 18), uint16(1000), make([]byte, 30), uint16(1), uint16(0)),
+		"maxp": be(uint32(0x5000), uint16(3)),
+		"hhea": be(make([]byte, 34), uint16(3)),
+		"hmtx": be(uint16(500), int16(0), uint16(500), int16(0), uint16(500), int16(0)),
+		"loca": be(uint32(0), uint32(0), uint32(len(square)), uint32(len(glyf))),
+		"glyf": glyf,
+		"fvar": be(uint16(1), uint16(0), uint16(16), uint16(2), uint16(1), uint16(20), uint16(0), uint16(8),
+			[]byte("wght"), int32(100<<16), int32(400<<16), int32(900<<16), uint16(0), uint16(256)),
+		"avar": be(uint16(1), uint16(0), uint16(0), uint16(1), uint16(4),
+			int16(-16384), int16(-16384), int16(0), int16(0), int16(8192), int16(4096), int16(16384), int16(16384)),
+		"gvar": gvar,
+	})
+}
+
+func TestInstanceFont(t *testing.T) {
+	b := testVariableFont()
+	axes, err := FontAxes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(axes) != "[{wght 100 400 900}]" {
+		t.Errorf("axes: got %v", axes)
+	}
+	for _, c := range []struct {
+		wght       float64
+		right, off int // x of the right side of glyph 1, and offset of glyph 2
+		width      int // advance width of glyph 1
+	}{
+		{400, 100, 10, 500},
+		{900, 140, 40, 540},
+		{2000, 140, 40, 540},
+		// 0.5 is mapped to 0.25 by avar.
+		{650, 110, 18, 510},
+	} {
+		inst, err := InstanceFont(b, map[string]float64{"wght": c.wght})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := checksum(inst); s != 0xb1b0afba {
+			t.Errorf("%v: checksum of the font: got %x", c.wght, s)
+		}
+		f := parseSfnt(inst)
+		for _, tag := range []string{"fvar", "gvar", "avar"} {
+			if _, ok := f.tables[tag]; ok {
+				t.Errorf("%v: instance has %s table", c.wght, tag)
+			}
+		}
+		sg := parseGlyph(f.glyphData(1), 1)
+		if got := fmt.Sprint(sg.xs, sg.ys); got != fmt.Sprintf("[0 %d %d 0] [0 0 100 100]", c.right, c.right) {
+			t.Errorf("%v: points of glyph 1: got %s", c.wght, got)
+		}
+		comp := f.glyphData(2)
+		if got := int(int16(u16(comp, 14))); got != c.off {
+			t.Errorf("%v: offset of glyph 2: got %d, want %d", c.wght, got, c.off)
+		}
+		if got := int(int16(u16(comp, 2))); got != c.off {
+			t.Errorf("%v: left of glyph 2: got %d, want %d", c.wght, got, c.off)
+		}
+		if got := int(u16(f.tables["hmtx"], 4)); got != c.width {
+			t.Errorf("%v: width of glyph 1: got %d, want %d", c.wght, got, c.width)
+		}
+	}
+
+	for _, c := range []struct {
+		b      []byte
+		coords map[string]float64
+		want   error
+	}{
+		{b, map[string]float64{"wdth": 100}, ErrInvalid},
+		{testColorFont('a'), nil, ErrInvalid},
+		{b[:40], nil, ErrBadFile},
+	} {
+		_, err := InstanceFont(c.b, c.coords)
+		if !errors.Is(err, c.want) {
+			t.Errorf("got error %v, want kind %v", err, c.want)
+		}
+	}
+}
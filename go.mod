@@ -0,0 +1,3 @@
+module github.com/mostafah/pdf.go
+
+go 1.21
@@ -0,0 +1,312 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file applies the substitutions of glyphs of OpenType features, from
+// the GSUB table of fonts, like ligatures and small capitals. Lookups of
+// single, multiple, alternate and ligature substitutions are supported, and
+// chained contexts of the format with coverages, which is the one fonts
+// usually use for fractions. Flags of lookups, like the one to ignore marks,
+// are not.
+
+import (
+	"sort"
+)
+
+// maxLookupDepth is how deep lookups of chained contexts can call other
+// lookups.
+const maxLookupDepth = 8
+
+// gsubFeatures returns the tags of the features of the GSUB table of f, in
+// the script that's used, or nil if it has none.
+func (f *sfnt) gsubFeatures() []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, i := range f.gsubFeatureIndices() {
+		t := f.gsubFeature(i)
+		if !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// gsubFeatureIndices returns the indices of the features of the default
+// language of the script used, which is the default script, or Latin, or the
+// first one.
+func (f *sfnt) gsubFeatureIndices() []int {
+	gsub, ok := f.tables["GSUB"]
+	if !ok {
+		return nil
+	}
+	sl := int(u16(gsub, 4))
+	n := int(u16(gsub, sl))
+	if n == 0 {
+		return nil
+	}
+	script := sl + int(u16(gsub, sl+2+4))
+	for i := 0; i < n; i++ {
+		rec := sl + 2 + 6*i
+		if t := string(gsub[rec : rec+4]); t == "DFLT" || t == "latn" {
+			script = sl + int(u16(gsub, rec+4))
+			if t == "DFLT" {
+				break
+			}
+		}
+	}
+	ls := int(u16(gsub, script))
+	if ls == 0 {
+		return nil
+	}
+	ls += script
+	var is []int
+	if req := u16(gsub, ls+2); req != 0xffff {
+		is = append(is, int(req))
+	}
+	for i := 0; i < int(u16(gsub, ls+4)); i++ {
+		is = append(is, int(u16(gsub, ls+6+2*i)))
+	}
+	return is
+}
+
+// gsubFeature returns the tag of feature i of the GSUB table of f.
+func (f *sfnt) gsubFeature(i int) string {
+	gsub := f.tables["GSUB"]
+	fl := int(u16(gsub, 6))
+	if i >= int(u16(gsub, fl)) {
+		fail(ErrBadFile, "GSUB feature out of the list")
+	}
+	rec := fl + 2 + 6*i
+	u16(gsub, rec+4) // checks that the record is in the table
+	return string(gsub[rec : rec+4])
+}
+
+// gsubLookups returns the indices of the lookups of the features of f with
+// the given tags, in the order they're applied.
+func (f *sfnt) gsubLookups(tags []string) []int {
+	on := make(map[string]bool)
+	for _, t := range tags {
+		on[t] = true
+	}
+	gsub := f.tables["GSUB"]
+	seen := make(map[int]bool)
+	var ls []int
+	for _, i := range f.gsubFeatureIndices() {
+		if !on[f.gsubFeature(i)] {
+			continue
+		}
+		fl := int(u16(gsub, 6))
+		ft := fl + int(u16(gsub, fl+2+6*i+4))
+		for j := 0; j < int(u16(gsub, ft+2)); j++ {
+			l := int(u16(gsub, ft+4+2*j))
+			if !seen[l] {
+				seen[l] = true
+				ls = append(ls, l)
+			}
+		}
+	}
+	sort.Ints(ls)
+	return ls
+}
+
+// substitute returns glyphs gs with the substitutions of lookups ls of the
+// GSUB table of f applied.
+func (f *sfnt) substitute(gs []int, ls []int) []int {
+	for _, l := range ls {
+		for i := 0; i < len(gs); {
+			var n int
+			gs, n = f.lookup(l, gs, i, 0)
+			if n == 0 {
+				n = 1
+			}
+			i += n
+		}
+	}
+	return gs
+}
+
+// lookup applies lookup l of the GSUB table of f to glyphs gs at position i,
+// and returns the glyphs and how many of them after i it went through, which
+// is 0 if nothing is substituted.
+func (f *sfnt) lookup(l int, gs []int, i, depth int) ([]int, int) {
+	gsub := f.tables["GSUB"]
+	ll := int(u16(gsub, 8))
+	if l >= int(u16(gsub, ll)) {
+		fail(ErrBadFile, "GSUB lookup out of the list")
+	}
+	lt := ll + int(u16(gsub, ll+2+2*l))
+	typ := int(u16(gsub, lt))
+	for j := 0; j < int(u16(gsub, lt+4)); j++ {
+		st, t := lt+int(u16(gsub, lt+6+2*j)), typ
+		if t == 7 {
+			// Extension subtables point to subtables of other types
+			// with 32-bit offsets.
+			t = int(u16(gsub, st+2))
+			st += int(u32(gsub, st+4))
+		}
+		if out, n := f.substitution(t, st, gs, i, depth); n > 0 {
+			return out, n
+		}
+	}
+	return gs, 0
+}
+
+// substitution applies the subtable of type t at offset st of the GSUB table
+// of f to glyphs gs at position i, like lookup.
+func (f *sfnt) substitution(t, st int, gs []int, i, depth int) ([]int, int) {
+	gsub := f.tables["GSUB"]
+	format := u16(gsub, st)
+	if t == 6 {
+		if format == 3 {
+			return f.chain(st, gs, i, depth)
+		}
+		return gs, 0
+	}
+	c := coverageIndex(gsub, st+int(u16(gsub, st+2)), gs[i])
+	if c < 0 {
+		return gs, 0
+	}
+	// replace returns gs with the n glyphs at i replaced by rs.
+	replace := func(n int, rs ...int) ([]int, int) {
+		out := make([]int, 0, len(gs)-n+len(rs))
+		out = append(append(append(out, gs[:i]...), rs...), gs[i+n:]...)
+		return out, max(len(rs), 1)
+	}
+	switch {
+	case t == 1 && format == 1:
+		return replace(1, int(uint16(int(gs[i])+int(int16(u16(gsub, st+4))))))
+	case t == 1 && format == 2:
+		if c < int(u16(gsub, st+4)) {
+			return replace(1, int(u16(gsub, st+6+2*c)))
+		}
+	case t == 2 || t == 3:
+		// Alternates are left out for the first one.
+		if c < int(u16(gsub, st+4)) {
+			seq := st + int(u16(gsub, st+6+2*c))
+			n := int(u16(gsub, seq))
+			if t == 3 {
+				n = min(n, 1)
+			}
+			rs := make([]int, n)
+			for k := range rs {
+				rs[k] = int(u16(gsub, seq+2+2*k))
+			}
+			if n > 0 || t == 2 {
+				out, _ := replace(1, rs...)
+				return out, max(n, 1)
+			}
+		}
+	case t == 4:
+		if c >= int(u16(gsub, st+4)) {
+			break
+		}
+		set := st + int(u16(gsub, st+6+2*c))
+	ligatures:
+		for k := 0; k < int(u16(gsub, set)); k++ {
+			lig := set + int(u16(gsub, set+2+2*k))
+			n := int(u16(gsub, lig+2))
+			if n == 0 || i+n > len(gs) {
+				continue
+			}
+			for m := 1; m < n; m++ {
+				if int(u16(gsub, lig+4+2*(m-1))) != gs[i+m] {
+					continue ligatures
+				}
+			}
+			return replace(n, int(u16(gsub, lig)))
+		}
+	}
+	return gs, 0
+}
+
+// chain applies the chained context subtable of format 3 at offset st of the
+// GSUB table of f to glyphs gs at position i, like lookup.
+func (f *sfnt) chain(st int, gs []int, i, depth int) ([]int, int) {
+	gsub := f.tables["GSUB"]
+	p := st + 2
+	// covered returns whether the n glyphs from j have the n coverages at
+	// p, going backwards if back is true.
+	covered := func(j, n int, back bool) bool {
+		for k := 0; k < n; k++ {
+			g := j + k
+			if back {
+				g = j - k
+			}
+			if g < 0 || g >= len(gs) || coverageIndex(gsub, st+int(u16(gsub, p+2*k)), gs[g]) < 0 {
+				return false
+			}
+		}
+		return true
+	}
+	nb := int(u16(gsub, p))
+	p += 2
+	if !covered(i-1, nb, true) {
+		return gs, 0
+	}
+	p += 2 * nb
+	ni := int(u16(gsub, p))
+	p += 2
+	if ni == 0 || !covered(i, ni, false) {
+		return gs, 0
+	}
+	p += 2 * ni
+	na := int(u16(gsub, p))
+	p += 2
+	if !covered(i+ni, na, false) {
+		return gs, 0
+	}
+	p += 2 * na
+	if depth >= maxLookupDepth {
+		fail(ErrBadFile, "GSUB lookups nested too deep")
+	}
+	n := ni
+	for k := 0; k < int(u16(gsub, p)); k++ {
+		seq, l := int(u16(gsub, p+2+4*k)), int(u16(gsub, p+4+4*k))
+		if seq >= n {
+			continue
+		}
+		before := len(gs)
+		gs, _ = f.lookup(l, gs, i+seq, depth+1)
+		n += len(gs) - before
+	}
+	return gs, max(n, 1)
+}
+
+// coverageIndex returns the index of glyph g in the coverage table at offset off
+// of b, or -1 if it's not covered.
+func coverageIndex(b []byte, off, g int) int {
+	switch u16(b, off) {
+	case 1:
+		n := int(u16(b, off+2))
+		i := sort.Search(n, func(i int) bool { return int(u16(b, off+4+2*i)) >= g })
+		if i < n && int(u16(b, off+4+2*i)) == g {
+			return i
+		}
+	case 2:
+		n := int(u16(b, off+2))
+		i := sort.Search(n, func(i int) bool { return int(u16(b, off+4+6*i+2)) >= g })
+		if i < n {
+			r := off + 4 + 6*i
+			if start := int(u16(b, r)); g >= start {
+				return int(u16(b, r+4)) + g - start
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testGSUBFont returns a TrueType font with empty glyphs, where "a" to "j"
+// are glyphs 1 to 10, with features in GSUB: "liga" makes "ab" glyph 20,
+// "smcp" adds 10 to the glyph of "c", "onum" makes "d" and "e" glyphs 30
+// and 31, in an extension subtable, and "frac" makes "f" glyphs 40 and 41
+// when it's before "g".
+func testGSUBFont() []byte {
+	lookups := [][]byte{
+		be(uint16(4), uint16(0), uint16(1), uint16(8),
+			uint16(1), uint16(18), uint16(1), uint16(8), uint16(1), uint16(4), uint16(20), uint16(2), uint16(2),
+			uint16(1), uint16(1), uint16(1)),
+		be(uint16(1), uint16(0), uint16(1), uint16(8),
+			uint16(1), uint16(6), int16(10), uint16(1), uint16(1), uint16(3)),
+		be(uint16(7), uint16(0), uint16(1), uint16(8),
+			uint16(1), uint16(1), uint32(8), uint16(2), uint16(10), uint16(2), uint16(30), uint16(31),
+			uint16(2), uint16(1), uint16(4), uint16(5), uint16(0)),
+		be(uint16(6), uint16(0), uint16(1), uint16(8),
+			uint16(3), uint16(0), uint16(1), uint16(18), uint16(1), uint16(24), uint16(1), uint16(0), uint16(4),
+			uint16(1), uint16(1), uint16(6), uint16(1), uint16(1), uint16(7)),
+		be(uint16(2), uint16(0), uint16(1), uint16(8),
+			uint16(1), uint16(14), uint16(1), uint16(8), uint16(2), uint16(40), uint16(41), uint16(1), uint16(1), uint16(6)),
+	}
+	ll := be(uint16(len(lookups)))
+	off := 2 + 2*len(lookups)
+	var body []byte
+	for _, l := range lookups {
+		ll = append(ll, be(uint16(off+len(body)))...)
+		body = append(body, l...)
+	}
+	ll = append(ll, body...)
+	scripts := be(uint16(1), []byte("DFLT"), uint16(8), uint16(4), uint16(0),
+		uint16(0), uint16(0xffff), uint16(4), uint16(0), uint16(1), uint16(2), uint16(3))
+	features := be(uint16(4), []byte("frac"), uint16(26), []byte("liga"), uint16(32),
+		[]byte("onum"), uint16(38), []byte("smcp"), uint16(44),
+		uint16(0), uint16(1), uint16(3), uint16(0), uint16(1), uint16(0),
+		uint16(0), uint16(1), uint16(2), uint16(0), uint16(1), uint16(1))
+	gsub := be(uint16(1), uint16(0), uint16(10), uint16(10+len(scripts)), uint16(10+len(scripts)+len(features)),
+		scripts, features, ll)
+	return writeSfnt(map[string][]byte{
+		"head": be(make([]byte, 18), uint16(1000), make([]byte, 30), uint16(1), uint16(0)),
+		"maxp": be(uint32(0x5000), uint16(42)),
+		"hhea": be(make([]byte, 34), uint16(1)),
+		"hmtx": be(uint16(500), int16(0)),
+		"cmap": be(uint16(0), uint16(1), uint16(3), uint16(10), uint32(12),
+			uint16(12), uint16(0), uint32(28), uint32(0), uint32(1), uint32('a'), uint32('j'), uint32(1)),
+		"loca": be(make([]byte, 43*4)),
+		"glyf": nil,
+		"GSUB": gsub,
+	})
+}
+
+func TestGSUB(t *testing.T) {
+	f, err := NewOutlineFont(testGSUBFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := f.Features(); fmt.Sprint(tags) != "[frac liga onum smcp]" || err != nil {
+		t.Errorf("features: got %v, %v", tags, err)
+	}
+	tests := []struct {
+		features []string
+		s        string
+		want     string
+	}{
+		{nil, "abcdefg", "[1 2 3 4 5 6 7]"},
+		{[]string{"liga"}, "abcdefg", "[20 3 4 5 6 7]"},
+		{[]string{"liga", "smcp", "onum", "frac", "tnum"}, "abcdefg", "[20 13 30 31 40 41 7]"},
+		{[]string{"frac"}, "fgfaf", "[40 41 7 6 1 6]"},
+		{[]string{"liga"}, "aab", "[1 20]"},
+	}
+	for _, tt := range tests {
+		g, err := f.WithFeatures(tt.features...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gs, _ := g.glyphs(tt.s); fmt.Sprint(gs) != tt.want {
+			t.Errorf("glyphs of %q with %v: got %v, want %s", tt.s, tt.features, gs, tt.want)
+		}
+	}
+	if w, _ := f.Width("ab", 10); w != 10 {
+		t.Errorf("width without ligatures: got %v", w)
+	}
+	g, _ := f.WithFeatures("liga")
+	if w, _ := g.Width("ab", 10); w != 5 {
+		t.Errorf("width with ligatures: got %v", w)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file makes the sRGB ICC profile (ICC.1:2001-04, version 2) used by the
+// output intent of PDF/A documents, so that no profile file is needed.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// iccTag is a tag of an ICC profile.
+type iccTag struct {
+	sig  string
+	data []byte
+}
+
+// s15Fixed16 returns the fixed point representation of f used in ICC
+// profiles.
+func s15Fixed16(f float64) uint32 {
+	return uint32(int32(math.Floor(f*65536 + 0.5)))
+}
+
+// iccXYZ returns an XYZType tag data.
+func iccXYZ(x, y, z float64) []byte {
+	b := make([]byte, 20)
+	copy(b, "XYZ ")
+	binary.BigEndian.PutUint32(b[8:], s15Fixed16(x))
+	binary.BigEndian.PutUint32(b[12:], s15Fixed16(y))
+	binary.BigEndian.PutUint32(b[16:], s15Fixed16(z))
+	return b
+}
+
+// iccText returns a textType tag data.
+func iccText(s string) []byte {
+	return append([]byte("text\x00\x00\x00\x00"+s), 0)
+}
+
+// iccDesc returns a textDescriptionType tag data with no Unicode or
+// ScriptCode descriptions.
+func iccDesc(s string) []byte {
+	b := make([]byte, 12+len(s)+1+8+3+67)
+	copy(b, "desc")
+	binary.BigEndian.PutUint32(b[8:], uint32(len(s)+1))
+	copy(b[12:], s)
+	return b
+}
+
+// srgbCurve returns a curveType tag data with the transfer function of sRGB.
+func srgbCurve() []byte {
+	const n = 256
+	b := make([]byte, 12+2*n)
+	copy(b, "curv")
+	binary.BigEndian.PutUint32(b[8:], n)
+	for i := 0; i < n; i++ {
+		v := float64(i) / (n - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.BigEndian.PutUint16(b[12+2*i:], uint16(math.Floor(v*65535+0.5)))
+	}
+	return b
+}
+
+// srgbProfile returns the sRGB display profile. The colorants are adapted to
+// the D50 illuminant of the profile connection space.
+func srgbProfile() []byte {
+	trc := srgbCurve()
+	tags := []iccTag{
+		{"desc", iccDesc("sRGB IEC61966-2.1")},
+		{"cprt", iccText("No copyright, use freely")},
+		{"wtpt", iccXYZ(0.9642, 1, 0.8249)},
+		{"rXYZ", iccXYZ(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", iccXYZ(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", iccXYZ(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	// The tag table follows the 128 bytes header, and the data of the tags
+	// follows it, each starting at a multiple of four. The curve is shared
+	// by the three TRC tags.
+	table := bytes.NewBuffer(nil)
+	data := bytes.NewBuffer(nil)
+	binary.Write(table, binary.BigEndian, uint32(len(tags)))
+	start := 128 + 4 + 12*len(tags)
+	trcOff := 0
+	for _, t := range tags {
+		off := start + data.Len()
+		if t.sig[1:] == "TRC" && trcOff != 0 {
+			off = trcOff
+		} else {
+			if t.sig[1:] == "TRC" {
+				trcOff = off
+			}
+			data.Write(t.data)
+			for data.Len()%4 != 0 {
+				data.WriteByte(0)
+			}
+		}
+		table.WriteString(t.sig)
+		binary.Write(table, binary.BigEndian, []uint32{uint32(off), uint32(len(t.data))})
+	}
+
+	h := make([]byte, 128)
+	binary.BigEndian.PutUint32(h, uint32(128+table.Len()+data.Len()))
+	binary.BigEndian.PutUint32(h[8:], 0x02100000) // version 2.1
+	copy(h[12:], "mntrRGB XYZ ")
+	// Date of the profile: 2011-01-01 00:00:00
+	for i, v := range []uint16{2011, 1, 1, 0, 0, 0} {
+		binary.BigEndian.PutUint16(h[24+2*i:], v)
+	}
+	copy(h[36:], "acsp")
+	binary.BigEndian.PutUint32(h[68:], s15Fixed16(0.9642))
+	binary.BigEndian.PutUint32(h[72:], s15Fixed16(1))
+	binary.BigEndian.PutUint32(h[76:], s15Fixed16(0.8249))
+
+	return append(append(h, table.Bytes()...), data.Bytes()...)
+}
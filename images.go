@@ -0,0 +1,290 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file extracts images from existing files, to recover the assets of
+// documents or to check the images of files made by programs.
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"sort"
+)
+
+// Image is an image XObject of a parsed file.
+type Image struct {
+	Page             int    // first page the image is drawn on, from 1
+	Name             string // name of the image in the resources of the page
+	Width, Height    int
+	BitsPerComponent int
+	ColorSpace       string // like "DeviceRGB" or "Indexed"; empty if unknown
+	// Filter is the first filter of the image that could not be applied to
+	// Data, like "DCTDecode" for JPEG images. It's empty if Data holds the
+	// samples of the image.
+	Filter string
+	Data   []byte
+
+	comps   int    // components of colors: 1 gray, 3 RGB or 4 CMYK; 0 if unknown
+	palette []byte // colors of indexed images, in their base color space
+	invert  bool   // whether samples are reversed by the Decode array
+}
+
+// filterNames holds the full names of abbreviated filters.
+var filterNames = map[name]string{
+	"AHx": "ASCIIHexDecode",
+	"A85": "ASCII85Decode",
+	"LZW": "LZWDecode",
+	"Fl":  "FlateDecode",
+	"RL":  "RunLengthDecode",
+	"CCF": "CCITTFaxDecode",
+	"DCT": "DCTDecode",
+}
+
+// Images returns the image XObjects drawn on the pages of the file, in the
+// order of the pages and by their names on each page. Images drawn by forms
+// are included. Images drawn on more than one page are returned once, and
+// inline images are not returned.
+func (r *Reader) Images() (ims []*Image, err error) {
+	defer dontPanic(&err)
+
+	seen := make(map[int]bool)
+	for i, p := range r.pages() {
+		ims = r.images(p.dic["Resources"], i+1, 0, seen, ims)
+	}
+	return ims, nil
+}
+
+// images appends the images in resources res of page pg to ims. depth is the
+// number of forms the resources are nested in, and seen holds the numbers of
+// the XObjects already visited.
+func (r *Reader) images(res interface{}, pg, depth int, seen map[int]bool, ims []*Image) []*Image {
+	d, _ := r.resolve(res).(map[string]interface{})
+	xobjs, _ := r.resolve(d["XObject"]).(map[string]interface{})
+	names := make([]string, 0, len(xobjs))
+	for k := range xobjs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if ref, ok := xobjs[k].(ref); ok {
+			if seen[ref.num] {
+				continue
+			}
+			seen[ref.num] = true
+		}
+		s, ok := r.resolve(xobjs[k]).(*stream)
+		if !ok {
+			continue
+		}
+		switch r.resolve(s.dic["Subtype"]) {
+		case name("Image"):
+			ims = append(ims, r.image(s, pg, k))
+		case name("Form"):
+			if depth < maxFormDepth {
+				ims = r.images(s.dic["Resources"], pg, depth+1, seen, ims)
+			}
+		}
+	}
+	return ims
+}
+
+// image returns the image of stream s, named n on page pg. The filters of s
+// are applied until one that is not supported, like DCTDecode.
+func (r *Reader) image(s *stream, pg int, n string) *Image {
+	im := &Image{Page: pg, Name: n, Data: s.buf}
+	w, _ := r.number(s.dic["Width"])
+	h, _ := r.number(s.dic["Height"])
+	bpc, _ := r.number(s.dic["BitsPerComponent"])
+	im.Width, im.Height, im.BitsPerComponent = int(w), int(h), int(bpc)
+
+	fs, parms := r.filters(s)
+	for i, f := range fs {
+		b := decodeFilter(f, im.Data, parms[i])
+		if b == nil {
+			im.Filter = string(f)
+			if full, ok := filterNames[f]; ok {
+				im.Filter = full
+			}
+			break
+		}
+		im.Data = b
+	}
+
+	if m, _ := r.resolve(s.dic["ImageMask"]).(bool); m {
+		// Samples of 0 are painted, which is shown as black (p. 340).
+		im.BitsPerComponent, im.ColorSpace, im.comps = 1, "DeviceGray", 1
+	} else {
+		r.colorSpace(im, s.dic["ColorSpace"])
+	}
+	if a, ok := r.resolve(s.dic["Decode"]).([]interface{}); ok && len(a) >= 2 {
+		lo, _ := r.number(a[0])
+		hi, _ := r.number(a[1])
+		im.invert = lo > hi
+	}
+	return im
+}
+
+// deviceComps holds the number of color components of device color spaces.
+var deviceComps = map[name]int{
+	"DeviceGray": 1,
+	"DeviceRGB":  3,
+	"DeviceCMYK": 4,
+}
+
+// colorSpace sets the color space of im to cs.
+func (r *Reader) colorSpace(im *Image, cs interface{}) {
+	switch c := r.resolve(cs).(type) {
+	case name:
+		im.ColorSpace = string(c)
+		im.comps = deviceComps[c]
+	case []interface{}:
+		if len(c) == 0 {
+			return
+		}
+		n, _ := r.resolve(c[0]).(name)
+		im.ColorSpace = string(n)
+		switch n {
+		case "CalGray":
+			im.comps = 1
+		case "CalRGB":
+			im.comps = 3
+		case "ICCBased":
+			if len(c) < 2 {
+				return
+			}
+			if s, ok := r.resolve(c[1]).(*stream); ok {
+				if n, _ := r.number(s.dic["N"]); n == 1 || n == 3 || n == 4 {
+					im.comps = int(n)
+				}
+			}
+		case "Indexed":
+			if len(c) < 4 {
+				return
+			}
+			base := new(Image)
+			r.colorSpace(base, c[1])
+			hival, _ := r.number(c[2])
+			var lut []byte
+			switch l := r.resolve(c[3]).(type) {
+			case string:
+				lut = []byte(l)
+			case *stream:
+				lut = r.decode(l)
+			}
+			if base.comps == 0 || base.palette != nil || len(lut) < (int(hival)+1)*base.comps {
+				return
+			}
+			im.comps, im.palette = base.comps, lut
+		}
+	}
+}
+
+// Decode returns the image of im. Images with samples in Data and JPEG
+// images can be decoded. Calibrated and ICC based colors are decoded as
+// device colors, and CMYK colors are converted to RGB.
+func (im *Image) Decode() (m image.Image, err error) {
+	defer dontPanic(&err)
+
+	switch im.Filter {
+	case "":
+	case "DCTDecode":
+		return jpeg.Decode(bytes.NewBuffer(im.Data))
+	default:
+		fail(ErrBadFile, "can't decode images with filter "+im.Filter)
+	}
+	if im.comps == 0 {
+		fail(ErrBadFile, "can't decode images in color space "+im.ColorSpace)
+	}
+	bpc := im.BitsPerComponent
+	switch bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		fail(ErrBadFile, fmt.Sprintf("bad bits per component of image: %d", bpc))
+	}
+	w, h := im.Width, im.Height
+	if w <= 0 || h <= 0 {
+		fail(ErrBadFile, fmt.Sprintf("bad size of image: %dx%d", w, h))
+	}
+	spp := im.comps // samples per pixel
+	if im.palette != nil {
+		spp = 1
+	}
+	row := (w*spp*bpc + 7) / 8
+	if len(im.Data) < row*h {
+		fail(ErrBadFile, "data of image is too short")
+	}
+	max := 1<<uint(bpc) - 1
+	// sample returns sample i of row y.
+	sample := func(y, i int) int {
+		b := im.Data[y*row:]
+		switch bpc {
+		case 8:
+			return int(b[i])
+		case 16:
+			return int(b[2*i])<<8 | int(b[2*i+1])
+		}
+		bit := i * bpc
+		return int(b[bit/8]>>uint(8-bpc-bit%8)) & max
+	}
+
+	gray := im.comps == 1
+	var pix []byte
+	if gray {
+		pix = make([]byte, 0, w*h)
+	} else {
+		pix = make([]byte, 0, w*h*4)
+	}
+	var c [4]int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if im.palette != nil {
+				k := sample(y, x)
+				if (k+1)*im.comps > len(im.palette) {
+					k = 0
+				}
+				for j := 0; j < im.comps; j++ {
+					c[j] = int(im.palette[k*im.comps+j])
+				}
+			} else {
+				for j := 0; j < im.comps; j++ {
+					c[j] = sample(y, x*im.comps+j) * 255 / max
+				}
+			}
+			if im.invert {
+				for j := 0; j < im.comps; j++ {
+					c[j] = 255 - c[j]
+				}
+			}
+			switch im.comps {
+			case 1:
+				pix = append(pix, byte(c[0]))
+			case 3:
+				pix = append(pix, byte(c[0]), byte(c[1]), byte(c[2]), 255)
+			case 4:
+				k := 255 - c[3]
+				pix = append(pix, byte((255-c[0])*k/255), byte((255-c[1])*k/255),
+					byte((255-c[2])*k/255), 255)
+			}
+		}
+	}
+	if gray {
+		return &image.Gray{Pix: pix, Stride: w, Rect: image.Rect(0, 0, w, h)}, nil
+	}
+	return &image.RGBA{Pix: pix, Stride: 4 * w, Rect: image.Rect(0, 0, w, h)}, nil
+}
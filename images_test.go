@@ -0,0 +1,186 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"strings"
+	"testing"
+)
+
+// imageStream returns an image XObject with the given entries and data.
+func imageStream(dict, data string) string {
+	return fmt.Sprintf("<< /Type /XObject /Subtype /Image %s /Length %d >>\nstream\n%s\nendstream",
+		dict, len(data), data)
+}
+
+func TestImages(t *testing.T) {
+	form := "/Im1 Do /Im2 Do"
+	objs := testPage("/Im2 Do /Fm1 Do", "<< /XObject << /Im2 5 0 R /Fm1 6 0 R >> >>")
+	objs[1] = "<< /Type /Pages /Kids [ 3 0 R 8 0 R ] /Count 2 /MediaBox [ 0 0 100 100 ] >>"
+	objs = append(objs,
+		imageStream("/Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8", "\x00"),
+		fmt.Sprintf("<< /Type /XObject /Subtype /Form /BBox [ 0 0 1 1 ] /Resources << /XObject << /Im1 7 0 R /Im2 5 0 R >> >> /Length %d >>\nstream\n%s\nendstream", len(form), form),
+		imageStream("/Width 2 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCT", "\xff\xd8"),
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /XObject << /A 7 0 R /B 5 0 R >> >> >>")
+	r, err := NewReader(testFile(objs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := r.Images()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, im := range ims {
+		got = append(got, fmt.Sprint(im.Page, " ", im.Name, " ", im.Width, "x", im.Height,
+			" ", im.ColorSpace, " ", im.Filter, " ", len(im.Data)))
+	}
+	want := []string{
+		"1 Im1 2x1 DeviceRGB DCTDecode 2",
+		"1 Im2 1x1 DeviceGray  1",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+type imageTest struct {
+	dict, data string
+	pixels     string // colors of the pixels of each row, separated by "|"
+}
+
+var imageTests = []imageTest{
+	{"/Width 2 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8",
+		"\xff\x00\x00\x00\x00\xff", "255 0 0, 0 0 255"},
+	{"/Width 3 /Height 2 /ColorSpace /DeviceGray /BitsPerComponent 1",
+		"\xa0\x40", "255 255 255, 0 0 0, 255 255 255|0 0 0, 255 255 255, 0 0 0"},
+	{"/Width 2 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 2 /Decode [ 1 0 ]",
+		"\x70", "170 170 170, 0 0 0"},
+	{"/Width 2 /Height 1 /ImageMask true /Decode [ 1 0 ]", "\x80", "0 0 0, 255 255 255"},
+	{"/Width 2 /Height 1 /ColorSpace [ /Indexed /DeviceRGB 1 <ff000000ff00> ] /BitsPerComponent 4",
+		"\x10", "0 255 0, 255 0 0"},
+	{"/Width 1 /Height 1 /ColorSpace /DeviceCMYK /BitsPerComponent 8", "\x00\xff\x00\x00", "255 0 255"},
+	{"/Width 1 /Height 1 /ColorSpace [ /ICCBased 6 0 R ] /BitsPerComponent 16 /Filter /FlateDecode",
+		string(flateEncode([]byte("\x80\x00\x00\x00\xff\xff"))), "127 0 255"},
+}
+
+// pixels returns the colors of the pixels of m, formatted like the pixels of
+// imageTest.
+func pixels(m image.Image) string {
+	b := m.Bounds()
+	var rows []string
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		var row []string
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, b, _ := m.At(x, y).RGBA()
+			row = append(row, fmt.Sprint(r>>8, " ", g>>8, " ", b>>8))
+		}
+		rows = append(rows, strings.Join(row, ", "))
+	}
+	return strings.Join(rows, "|")
+}
+
+// testImage returns the image of a file with one page whose only XObject is
+// an image with the given entries and data.
+func testImage(dict, data string) (*Image, error) {
+	objs := append(testPage("/Im1 Do", "<< /XObject << /Im1 5 0 R >> >>"),
+		imageStream(dict, data),
+		"<< /N 3 /Length 0 >>\nstream\n\nendstream")
+	r, err := NewReader(testFile(objs))
+	if err != nil {
+		return nil, err
+	}
+	ims, err := r.Images()
+	if err != nil {
+		return nil, err
+	}
+	if len(ims) != 1 {
+		return nil, errors.New(fmt.Sprint(len(ims), " images"))
+	}
+	return ims[0], nil
+}
+
+func TestImageDecode(t *testing.T) {
+	for i, tt := range imageTests {
+		im, err := testImage(tt.dict, tt.data)
+		if err != nil {
+			t.Errorf("%d. %s", i, err)
+			continue
+		}
+		m, err := im.Decode()
+		if err != nil {
+			t.Errorf("%d. %s", i, err)
+			continue
+		}
+		if got := pixels(m); got != tt.pixels {
+			t.Errorf("%d. %s\ngot  %s\nwant %s", i, tt.dict, got, tt.pixels)
+		}
+	}
+}
+
+func TestImageDecodeJPEG(t *testing.T) {
+	src := &image.Gray{Pix: make([]byte, 64), Stride: 8, Rect: image.Rect(0, 0, 8, 8)}
+	for i := range src.Pix {
+		src.Pix[i] = 200
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := jpeg.Encode(buf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+	im, err := testImage("/Width 8 /Height 8 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode",
+		buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if im.Filter != "DCTDecode" || !bytes.Equal(im.Data, buf.Bytes()) {
+		t.Errorf("got filter %q and %d bytes, want the JPEG file", im.Filter, len(im.Data))
+	}
+	m, err := im.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := m.At(3, 3).RGBA(); r>>8 != 200 {
+		t.Errorf("got gray %d, want 200", r>>8)
+	}
+}
+
+func TestImageDecodeErrors(t *testing.T) {
+	for _, tt := range []imageTest{
+		{"/Width 1 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /JPXDecode",
+			"\x00", "pdf.go: can't decode images with filter JPXDecode"},
+		{"/Width 1 /Height 1 /ColorSpace [ /Lab << >> ] /BitsPerComponent 8",
+			"\x00\x00\x00", "pdf.go: can't decode images in color space Lab"},
+		{"/Width 2 /Height 2 /ColorSpace /DeviceRGB /BitsPerComponent 8",
+			"\x00\x00\x00", "pdf.go: data of image is too short"},
+		{"/Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 3",
+			"\x00", "pdf.go: bad bits per component of image: 3"},
+	} {
+		im, err := testImage(tt.dict, tt.data)
+		if err != nil {
+			t.Errorf("%s: %s", tt.dict, err)
+			continue
+		}
+		if _, err := im.Decode(); fmt.Sprint(err) != tt.pixels {
+			t.Errorf("%s: got error %v, want %q", tt.dict, err, tt.pixels)
+		}
+	}
+}
@@ -21,8 +21,9 @@ import (
 )
 
 type indirect struct {
-	num int // object number, i.e. ID among objects of the document
-	off int // offset in bytes in the document
+	num  int // object number, i.e. ID among objects of the document
+	off  int // offset in bytes in the document
+	part int // part of the document counted by Sizes, if it's not found from the object
 }
 
 // output returns an indirect representation of i.
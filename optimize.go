@@ -0,0 +1,294 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file rewrites existing files to make them smaller. Objects that are
+// not used are dropped, identical ones are written once, streams are
+// compressed with Flate, and other objects are packed into object streams
+// (p. 100) with a cross-reference stream (p. 106).
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// objStmSize is the largest number of objects packed into one object stream.
+const objStmSize = 100
+
+// optimizer rewrites a parsed file.
+type optimizer struct {
+	r    *Reader
+	objs map[int]interface{} // objects used by the file, by number
+	rep  map[int]int         // object written in place of each object, for duplicates
+	nums map[int]int         // new numbers of the objects written
+}
+
+// Optimize writes a smaller copy of the file read by r to w. Objects not
+// used by the catalog or the document information are dropped, and
+// duplicates of streams and other objects, like fonts and images embedded
+// more than once, are written once. Streams are compressed with Flate unless
+// they can't be decoded, like images in JPEG, or they would get larger. The
+// copy is at least PDF 1.5, since object streams are new in it.
+func Optimize(w io.Writer, r *Reader) (err error) {
+	defer dontPanic(&err)
+
+	// The copy would be written with no encryption.
+	if r.Encrypted() {
+		panic("encrypted files can't be optimized")
+	}
+	o := &optimizer{r: r, objs: make(map[int]interface{}), rep: make(map[int]int)}
+	o.walk(r.trailer["Root"])
+	o.walk(r.trailer["Info"])
+	o.dedupe()
+	o.write(w)
+	return nil
+}
+
+// walk adds the objects that v refers to, directly or through other
+// objects, to o.objs.
+func (o *optimizer) walk(v interface{}) {
+	switch t := v.(type) {
+	case ref:
+		if _, ok := o.objs[t.num]; ok {
+			return
+		}
+		obj := o.r.object(t.num)
+		o.objs[t.num] = obj
+		o.rep[t.num] = t.num
+		o.walk(obj)
+	case map[string]interface{}:
+		for _, e := range t {
+			o.walk(e)
+		}
+	case []interface{}:
+		for _, e := range t {
+			o.walk(e)
+		}
+	case *stream:
+		o.walk(t.dic)
+	}
+}
+
+// mapRefs returns a copy of v with references replaced by f of their
+// object numbers.
+func mapRefs(v interface{}, f func(num int) interface{}) interface{} {
+	switch t := v.(type) {
+	case ref:
+		return f(t.num)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = mapRefs(e, f)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = mapRefs(e, f)
+		}
+		return a
+	case *stream:
+		return &stream{mapRefs(t.dic, f).(map[string]interface{}), t.buf}
+	}
+	return v
+}
+
+// sorted returns the numbers of the objects that are not duplicates, in
+// order.
+func (o *optimizer) sorted() []int {
+	var nums []int
+	for num, obj := range o.objs {
+		if o.rep[num] == num && obj != nil {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// shared tells whether obj can be replaced by an identical object. Pages,
+// annotations and other objects that belong to one place in the document
+// can't, even if they look the same.
+func shared(obj interface{}) bool {
+	d, ok := obj.(map[string]interface{})
+	if s, isStream := obj.(*stream); isStream {
+		d, ok = s.dic, true
+	}
+	if !ok {
+		return true
+	}
+	switch d["Type"] {
+	case name("Catalog"), name("Pages"), name("Page"), name("Annot"):
+		return false
+	}
+	_, par := d["Parent"]
+	_, rect := d["Rect"]
+	return !par && !rect
+}
+
+// dedupe makes duplicate objects refer to the first of them in o.rep.
+// Objects that are the same once their references are replaced are
+// duplicates too, so it's repeated until no more duplicates are found.
+func (o *optimizer) dedupe() {
+	for changed := true; changed; {
+		changed = false
+		seen := make(map[string]int)
+		for _, num := range o.sorted() {
+			obj := o.objs[num]
+			if !shared(obj) {
+				continue
+			}
+			k := string(output(mapRefs(obj, func(n int) interface{} { return ref{o.rep[n], 0} })))
+			if first, ok := seen[k]; ok {
+				o.rep[num] = first
+				changed = true
+			} else {
+				seen[k] = num
+			}
+		}
+	}
+}
+
+// value returns v with the references replaced by the new ones. References
+// to objects that don't exist become null.
+func (o *optimizer) value(v interface{}) interface{} {
+	return mapRefs(v, func(num int) interface{} {
+		if n, ok := o.nums[o.rep[num]]; ok {
+			return ref{n, 0}
+		}
+		return nil
+	})
+}
+
+// compress returns the stream s, whose original is orig, compressed with
+// Flate if that makes it smaller. Metadata streams are left uncompressed for
+// the tools that search files for it.
+func (o *optimizer) compress(orig, s *stream) (c *stream) {
+	defer func() {
+		// Data that can't be decoded is kept as it is.
+		if e := recover(); e != nil {
+			if canceled(e) {
+				panic(e)
+			}
+			c = s
+		}
+	}()
+
+	if s.dic["Type"] == name("Metadata") {
+		return s
+	}
+	b := o.r.decode(orig)
+	if b == nil {
+		return s
+	}
+	z := flateEncode(b)
+	if len(z) >= len(orig.buf) {
+		return s
+	}
+	dic := make(map[string]interface{}, len(s.dic))
+	for k, v := range s.dic {
+		if k != "DecodeParms" {
+			dic[k] = v
+		}
+	}
+	dic["Filter"] = name("FlateDecode")
+	return &stream{dic, z}
+}
+
+// write writes the optimized file to w.
+func (o *optimizer) write(w io.Writer) {
+	nums := o.sorted()
+	o.nums = make(map[int]int)
+	for i, num := range nums {
+		o.nums[num] = i + 1
+	}
+
+	version := o.r.version
+	if version < "1.5" {
+		version = "1.5"
+	}
+	buf := bytes.NewBufferString("%PDF-" + version + "\n%سلام\n")
+	// Entries of the cross-reference stream have three fields (p. 109).
+	entries := make([][3]int, len(nums)+1)
+	entries[0] = [3]int{0, 0, 65535}
+	obj := func(num int, v interface{}) {
+		entries[num] = [3]int{1, buf.Len(), 0}
+		fmt.Fprintf(buf, "%d 0 obj\n", num)
+		buf.Write(output(v))
+		buf.WriteString("\nendobj\n")
+	}
+
+	// Streams are written on their own, and the other objects are packed.
+	var packed []int
+	for i, num := range nums {
+		if s, ok := o.objs[num].(*stream); ok {
+			obj(i+1, o.compress(s, o.value(s).(*stream)))
+		} else {
+			packed = append(packed, i+1)
+		}
+	}
+	for i := 0; i < len(packed); i += objStmSize {
+		group := packed[i:]
+		if len(group) > objStmSize {
+			group = group[:objStmSize]
+		}
+		stm := len(entries)
+		entries = append(entries, [3]int{})
+		head := bytes.NewBuffer(nil)
+		body := bytes.NewBuffer(nil)
+		for j, num := range group {
+			entries[num] = [3]int{2, stm, j}
+			fmt.Fprintf(head, "%d %d ", num, body.Len())
+			body.Write(output(o.value(o.objs[nums[num-1]])))
+			body.WriteString("\n")
+		}
+		obj(stm, &stream{map[string]interface{}{
+			"Type":   name("ObjStm"),
+			"N":      len(group),
+			"First":  head.Len(),
+			"Filter": name("FlateDecode"),
+		}, flateEncode(append(head.Bytes(), body.Bytes()...))})
+	}
+
+	// Offsets are four bytes, which is enough for files up to 4 GB.
+	x := len(entries)
+	entries = append(entries, [3]int{1, buf.Len(), 0})
+	data := make([]byte, 0, 7*len(entries))
+	for _, e := range entries {
+		data = append(data, byte(e[0]), byte(e[1]>>24), byte(e[1]>>16),
+			byte(e[1]>>8), byte(e[1]), byte(e[2]>>8), byte(e[2]))
+	}
+	dic := map[string]interface{}{
+		"Type":   name("XRef"),
+		"Size":   len(entries),
+		"W":      []int{1, 4, 2},
+		"Root":   o.value(o.r.trailer["Root"]),
+		"Filter": name("FlateDecode"),
+	}
+	for _, k := range []string{"Info", "ID"} {
+		if v, ok := o.r.trailer[k]; ok {
+			dic[k] = o.value(v)
+		}
+	}
+	obj(x, &stream{dic, flateEncode(data)})
+	fmt.Fprintf(buf, "startxref\n%d\n%%%%EOF\n", entries[x][1])
+
+	_, err := w.Write(buf.Bytes())
+	check(err)
+}
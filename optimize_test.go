@@ -0,0 +1,120 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	c := strings.Repeat("BT /F1 10 Tf 10 10 Td (Hello) Tj ET /F2 10 Tf (World) Tj /Im1 Do /Im2 Do\n", 20)
+	img := "<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray " +
+		"/BitsPerComponent 8 /Filter /DCTDecode /Length 4 >>\nstream\nJPEG\nendstream"
+	b := testFile([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [ 3 0 R ] /Count 1 /MediaBox [ 0 0 100 100 ] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R /F2 6 0 R >> " +
+			"/XObject << /Im1 7 0 R /Im2 8 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(c), c),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding 10 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding 11 0 R >>",
+		img,
+		img,
+		"<< /Unused true >>",
+		"<< /Differences [ 65 /B ] >>",
+		"<< /Differences [ 65 /B ] >>",
+	})
+	b = bytes.Replace(b, []byte("%PDF-1.7"), []byte("%PDF-1.4"), 1)
+	r, err := NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := Optimize(buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(b) {
+		t.Errorf("optimized file is %d bytes, original is %d", buf.Len(), len(b))
+	}
+	if ps := Validate(buf.Bytes()); len(ps) > 0 {
+		t.Errorf("problems of optimized file: %v", ps)
+	}
+	o, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Version() != "1.5" {
+		t.Errorf("version: got %s, want 1.5", o.Version())
+	}
+	// Catalog, pages, page, content, font, image, encoding, object stream
+	// and cross-reference stream
+	if size := o.trailer["Size"]; size != 10 {
+		t.Errorf("Size of optimized file: got %v, want 10", size)
+	}
+	p := o.pages()[0]
+	res := o.resolve(p.dic["Resources"]).(map[string]interface{})
+	fonts := o.resolve(res["Font"]).(map[string]interface{})
+	xobjs := o.resolve(res["XObject"]).(map[string]interface{})
+	if fonts["F1"] != fonts["F2"] || xobjs["Im1"] != xobjs["Im2"] {
+		t.Errorf("duplicates are not merged: %v %v", fonts, xobjs)
+	}
+	im := o.resolve(xobjs["Im1"]).(*stream)
+	if string(im.buf) != "JPEG" || im.dic["Filter"] != name("DCTDecode") {
+		t.Errorf("image is changed: %v %q", im.dic, im.buf)
+	}
+	con := o.resolve(p.dic["Contents"]).(*stream)
+	if con.dic["Filter"] != name("FlateDecode") {
+		t.Errorf("content is not compressed: %v", con.dic)
+	}
+	if string(o.decode(con)) != c {
+		t.Error("content is changed")
+	}
+	runs, err := o.PageText(1)
+	if err != nil || len(runs) != 40 || runs[0].Text != "Hello" || runs[1].Text != "World" {
+		t.Errorf("text of optimized page: got %v, %v", runs, err)
+	}
+}
+
+func TestOptimizeDocument(t *testing.T) {
+	b := testForm(t)
+	r, err := NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := Optimize(buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(b) {
+		t.Errorf("optimized file is %d bytes, original is %d", buf.Len(), len(b))
+	}
+	if ps := Validate(buf.Bytes()); len(ps) > 0 {
+		t.Errorf("problems of optimized file: %v", ps)
+	}
+	o, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := r.FieldValues()
+	got, err := o.FieldValues()
+	if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("fields of optimized file: got %v, %v; want %v", got, err, want)
+	}
+}
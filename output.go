@@ -22,20 +22,53 @@ package pdf
 
 import (
 	"bytes"
+	"encoding/hex"
+	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Names in PDF have a different representation than normal strings. Casting strings
 // to this type makes them to appear as names in the output.
 type name string
 
+// outputter is implemented by types which make their own PDF representation,
+// like indirect references.
 type outputter interface {
 	output() []byte
 }
 
-// output gives out the PDF representation of v.
+// objecter is implemented by types which are represented in PDF by another
+// value, usually a dictionary. The value is output in their place.
+type objecter interface {
+	object() interface{}
+}
+
+// hexString is a string that is written in hexadecimal form (p. 56). It's
+// used for binary data, like keys and IDs.
+type hexString []byte
+
+func (h hexString) output() []byte {
+	return []byte("<" + hex.EncodeToString(h) + ">")
+}
+
+// encoder makes PDF representation of values. The strings and streams of
+// encrypted documents are passed to crypt before they are written.
+type encoder struct {
+	crypt func(b []byte) []byte // nil if there's no encryption
+}
+
+// output gives out the PDF representation of v with no encryption.
 func output(v interface{}) []byte {
+	e := encoder{}
+	return e.output(v)
+}
+
+// output gives out the PDF representation of v.
+func (e *encoder) output(v interface{}) []byte {
 	// Check for nil
 	if v == nil {
 		return []byte("null")
@@ -45,6 +78,10 @@ func output(v interface{}) []byte {
 	switch t := v.(type) {
 	case outputter:
 		return t.output()
+	case objecter:
+		return e.output(t.object())
+	case *stream:
+		return e.outputStream(t.dic, t.buf)
 	case bool:
 		if t {
 			return []byte("true")
@@ -54,26 +91,38 @@ func output(v interface{}) []byte {
 	case int:
 		return []byte(strconv.Itoa(t))
 	case float32:
-		return []byte(strconv.Ftoa32(t, 'f', -1))
+		return []byte(strconv.FormatFloat(float64(t), 'f', -1, 32))
 	case float64:
+		if t == 0 {
+			return []byte("0") // not -0
+		}
 		// TODO 2.3 prints 2.299999952316284. Is it OK with PDF?
-		return []byte(strconv.Ftoa64(t, 'f', -1))
+		return []byte(strconv.FormatFloat(t, 'f', -1, 64))
 	case string:
 		// TODO non-ASCII characters?
-		// TODO escapes, \n, \t, etc. (p. 54)
 		// TODO break long lines (p. 54)
-		// TODO what about hexadecimal strings? (p. 56)
-		return []byte("(" + t + ")")
+		if e.crypt != nil {
+			return hexString(e.crypt([]byte(t))).output()
+		}
+		return []byte("(" + escapeString(t) + ")")
 	case name:
-		// TODO escape non-regular characters using # (p. 57)
 		// TODO check length limit (p. 57)
-		return []byte("/" + string(t))
+		return []byte("/" + escapeName(string(t)))
+	case Name:
+		return e.output(name(t))
+	case Str:
+		return e.output(textString(string(t)))
+	case *Stream:
+		if len(t.Filters) > 0 {
+			return e.outputStream(encodeStream(t.Dict, t.Data, t.Filters))
+		}
+		return e.outputStream(t.Dict, t.Data)
 	case []byte:
-		return outputStream(t)
+		return e.outputStream(nil, t)
 	case *bytes.Buffer:
-		return outputStream(t.Bytes())
+		return e.outputStream(nil, t.Bytes())
 	case reflect.Value:
-		return output(t.Interface())
+		return e.output(t.Interface())
 	}
 
 	switch r := reflect.ValueOf(v); r.Kind() {
@@ -83,7 +132,7 @@ func output(v interface{}) []byte {
 		buf := bytes.NewBufferString("[ ")
 
 		for i := 0; i < r.Len(); i++ {
-			buf.Write(output(r.Index(i)))
+			buf.Write(e.output(r.Index(i)))
 			buf.WriteString(" ")
 		}
 
@@ -93,13 +142,22 @@ func output(v interface{}) []byte {
 	case reflect.Map:
 		buf := bytes.NewBufferString("<<\n")
 
+		// Keys are sorted so the same dictionary always gives the same
+		// output.
+		keys := make([]string, 0, r.Len())
+		vals := make(map[string]reflect.Value, r.Len())
 		for _, k := range r.MapKeys() {
 			if k.Kind() != reflect.String {
 				panic("key of map passed to output is not string")
 			}
-			buf.Write(output(name(k.String())))
+			keys = append(keys, k.String())
+			vals[k.String()] = r.MapIndex(k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			buf.Write(e.output(name(k)))
 			buf.WriteString(" ")
-			buf.Write(output(r.MapIndex(k)))
+			buf.Write(e.output(vals[k]))
 			buf.WriteString("\n")
 		}
 
@@ -111,19 +169,117 @@ func output(v interface{}) []byte {
 	return []byte("null")
 }
 
-// outputStream returns the given buffer as PDF stream.
-func outputStream(b []byte) []byte {
-	// TODO add filters
+// stream holds a PDF stream along with the entries of its dictionary other
+// than Length. Use it instead of a plain []byte when the dictionary needs more
+// entries, like the type of an XObject.
+type stream struct {
+	dic map[string]interface{}
+	buf []byte
+}
+
+// outputStream returns the given buffer as PDF stream. dic holds the entries of
+// the stream dictionary other than Length; it can be nil.
+func (e *encoder) outputStream(dic map[string]interface{}, b []byte) []byte {
+	if e.crypt != nil && !unencrypted(dic) {
+		b = e.crypt(b)
+	}
 
 	// PDF streams start with a dictionary, then the word "stream", then
 	// the stream itself, and finally the world "endstream". The slice all
 	// holds []byte version of each of these four parts.
 	all := make([][]byte, 4)
 
-	all[0] = output(map[string]int{"Length": len(b)})
+	d := map[string]interface{}{"Length": len(b)}
+	for k, v := range dic {
+		d[k] = v
+	}
+	all[0] = e.output(d)
 	all[1] = []byte("stream")
 	all[2] = b
 	all[3] = []byte("endstream")
 
 	return bytes.Join(all, []byte{'\n'})
 }
+
+// ftoa returns f as a number ready to be used in content streams. Three
+// digits after the point are more than enough for positions and colors.
+func ftoa(f float64) string {
+	return string(appendFloat(nil, f, 3))
+}
+
+// escapeName writes the characters of s that can't be in PDF names as # and
+// two hexadecimal digits (p. 57).
+func escapeName(s string) string {
+	buf := bytes.NewBuffer(make([]byte, 0, len(s)))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '~' || strings.Contains("#()<>[]{}/%", string(c)) {
+			fmt.Fprintf(buf, "#%02X", c)
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// escapeString puts a backslash before characters that have a special meaning
+// inside PDF literal strings (p. 54).
+func escapeString(s string) string {
+	return string(appendEscaped(make([]byte, 0, len(s)), s))
+}
+
+// appendEscaped appends s to b, escaped like escapeString.
+func appendEscaped(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '(', ')':
+			b = append(b, '\\', c)
+		case '\r':
+			b = append(b, "\\r"...)
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// date returns t as a PDF date string (p. 160), like "D:20111231235959+03'30'".
+func date(t time.Time) string {
+	s := t.Format("D:20060102150405")
+	_, off := t.Zone()
+	if off == 0 {
+		return s + "Z"
+	}
+	sign := "+"
+	if off < 0 {
+		sign = "-"
+		off = -off
+	}
+	return fmt.Sprintf("%s%s%02d'%02d'", s, sign, off/3600, off/60%60)
+}
+
+// textString returns s as a PDF text string (p. 158). Strings with non-ASCII
+// characters are written in UTF-16BE with a byte order mark.
+func textString(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+	buf := bytes.NewBuffer([]byte{0xfe, 0xff})
+	for _, r := range s {
+		if r >= 0x10000 {
+			// Surrogate pair
+			r -= 0x10000
+			buf.Write([]byte{byte(0xd8 | r>>18), byte(r >> 10), byte(0xdc | r>>8&3), byte(r)})
+		} else {
+			buf.Write([]byte{byte(r >> 8), byte(r)})
+		}
+	}
+	return buf.String()
+}
@@ -19,6 +19,7 @@ package pdf
 import (
 	"bytes"
 	"testing"
+	"time"
 )
 
 type outputTest struct {
@@ -29,8 +30,6 @@ type outputTest struct {
 
 func TestOutput(t *testing.T) {
 	// TODO add test with Persian text for string
-	// TODO test escape characters in string
-	// TODO test space and other special characters for names in dictionaries
 	// TODO test empty stream
 	tests := []outputTest{
 		// simple types: null, boolean, numbers, strings
@@ -46,6 +45,13 @@ func TestOutput(t *testing.T) {
 		{"ten", 10, []byte("10")},
 		{"empty string", "", []byte("()")},
 		{"simple string", "hello", []byte("(hello)")},
+		{"string with escapes", "a(b)\\", []byte("(a\\(b\\)\\\\)")},
+		// names
+		{"simple name", name("Type"), []byte("/Type")},
+		{"name with special characters", name("text/xml #1"),
+			[]byte("/text#2Fxml#20#231")},
+		{"dictionary key with space", map[string]int{"A B": 1},
+			[]byte("<<\n/A#20B 1\n>>")},
 		// arrays
 		{"empty array", []int{}, []byte("[ ]")},
 		{"array of one", []float64{1.1}, []byte("[ 1.1 ]")},
@@ -56,6 +62,9 @@ func TestOutput(t *testing.T) {
 			map[string]string{"k": "v"}, []byte("<<\n/k (v)\n>>")},
 		{"dictionary of number",
 			map[string]int{"A": 1}, []byte("<<\n/A 1\n>>")},
+		{"dictionary with sorted keys",
+			map[string]int{"b": 2, "c": 3, "a": 1},
+			[]byte("<<\n/a 1\n/b 2\n/c 3\n>>")},
 		// arrays of arras and dictionaries
 		{"array including array and dictionary", []interface{}{
 			[]int{1, 2},
@@ -78,6 +87,9 @@ func TestOutput(t *testing.T) {
 			[]byte("<<\n/Length 4\n>>\nstream\nssss\nendstream")},
 		{"buffer", bytes.NewBufferString("a"),
 			[]byte("<<\n/Length 1\n>>\nstream\na\nendstream")},
+		{"stream with dictionary",
+			&stream{map[string]interface{}{"Type": name("XObject")}, []byte("q Q")},
+			[]byte("<<\n/Length 3\n/Type /XObject\n>>\nstream\nq Q\nendstream")},
 	}
 
 	for _, test := range tests {
@@ -88,3 +100,66 @@ func TestOutput(t *testing.T) {
 		}
 	}
 }
+
+type ftoaTest struct {
+	in  float64
+	out string
+}
+
+func TestFtoa(t *testing.T) {
+	tests := []ftoaTest{
+		{0, "0"},
+		{-0.0001, "0"},
+		{1, "1"},
+		{1.5, "1.5"},
+		{-2.25, "-2.25"},
+		{1.0 / 3, "0.333"},
+		{1e7, "10000000"},
+	}
+
+	for _, test := range tests {
+		if o := ftoa(test.in); o != test.out {
+			t.Errorf("ftoa(%v): got %q expected %q", test.in, o, test.out)
+		}
+	}
+}
+
+type dateTest struct {
+	in  time.Time
+	out string
+}
+
+func TestDate(t *testing.T) {
+	tests := []dateTest{
+		{time.Date(2011, 12, 31, 23, 59, 59, 0, time.UTC), "D:20111231235959Z"},
+		{time.Date(2011, 1, 2, 3, 4, 5, 0, time.FixedZone("IRST", 12600)),
+			"D:20110102030405+03'30'"},
+		{time.Date(2011, 1, 2, 3, 4, 5, 0, time.FixedZone("EST", -18000)),
+			"D:20110102030405-05'00'"},
+	}
+
+	for _, test := range tests {
+		if o := date(test.in); o != test.out {
+			t.Errorf("date(%v): got %q expected %q", test.in, o, test.out)
+		}
+	}
+}
+
+type textStringTest struct {
+	in, out string
+}
+
+func TestTextString(t *testing.T) {
+	tests := []textStringTest{
+		{"", ""},
+		{"Hello", "Hello"},
+		{"سلام", "\xfe\xff\x06\x33\x06\x44\x06\x27\x06\x45"},
+		{"a😀", "\xfe\xff\x00a\xd8\x3d\xde\x00"},
+	}
+
+	for _, test := range tests {
+		if o := textString(test.in); o != test.out {
+			t.Errorf("textString(%q): got %q expected %q", test.in, o, test.out)
+		}
+	}
+}
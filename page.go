@@ -18,11 +18,41 @@ package pdf
 
 // This file deals with pages in PDF.
 
+import (
+	"sort"
+)
+
 // type page holds a PDF page, its attributes and its content.
 type page struct {
-	box *rect       // size of the page
-	par *indirect   // page tree for this page
-	con []*indirect // page contents
+	ref      *indirect              // the page
+	index    int                    // index of the page in the pages of the document
+	buf      *spill                 // content stream of the page, while it's not the current page
+	flushed  bool                   // whether it's written to the output
+	box      *rect                  // size of the page
+	trim     *rect                  // intended size of the page after trimming, if set
+	bleed    *rect                  // region of the page to be printed, if set
+	crop     *rect                  // region of the page shown by viewers, if set
+	track    tracker                // current point and what's painted, while it's not the current page
+	text     textObject             // text object and font, while it's not the current page
+	autocrop bool                   // whether the crop box is set to the content
+	margin   float64                // margin around the content in the crop box
+	par      *indirect              // page tree for this page
+	con      []*indirect            // page contents
+	annots   []*indirect            // annotations on this page
+	arects   []*rect                // rectangles of annotations, in the same order
+	vps      []*viewport            // viewports of this page
+	res      resources              // resources used by the content of this page
+	tabs     int                    // tab order of annotations
+	mcids    []*structElem          // structure elements of marked content, by MCID
+	sp       int                    // key of the page in the parent tree, if it has marked content
+	counts   []int                  // offsets of page counts in the content of the page
+	compat   int                    // number of open compatibility sections
+	pieces   map[string]interface{} // private data of applications, by their names
+	modified string                 // date of the last change of pieces
+	af       []*indirect            // associated files of the page
+	dur      float64                // seconds the page is shown in presentations, if set
+	unit     float64                // size of the unit of the page, if it's not 1
+	extra    map[string]interface{} // custom entries of the page
 }
 
 func newPage(w, h int, par *indirect) *page {
@@ -37,14 +67,90 @@ func (p *page) addContent(con *indirect) {
 	p.con = append(p.con, con)
 }
 
-func (p *page) output() []byte {
+func (p *page) addAnnot(a *indirect, r *rect) {
+	p.annots = append(p.annots, a)
+	p.arects = append(p.arects, r)
+}
+
+// Len, Less and Swap sort the annotations of p by the tab order of p. They
+// are meant to be used only by sortAnnots.
+func (p *page) Len() int {
+	return len(p.annots)
+}
+
+func (p *page) Less(i, j int) bool {
+	a, b := p.arects[i], p.arects[j]
+	if p.tabs == TabColumn {
+		if a.llx != b.llx {
+			return a.llx < b.llx
+		}
+		return a.ury > b.ury
+	}
+	if a.ury != b.ury {
+		return a.ury > b.ury
+	}
+	return a.llx < b.llx
+}
+
+func (p *page) Swap(i, j int) {
+	p.annots[i], p.annots[j] = p.annots[j], p.annots[i]
+	p.arects[i], p.arects[j] = p.arects[j], p.arects[i]
+}
+
+// sortAnnots sorts the annotations of p by its tab order, so that viewers
+// which don't know about tab orders move between the fields in the same order.
+func (p *page) sortAnnots() {
+	if p.tabs == TabRow || p.tabs == TabColumn {
+		sort.Sort(p)
+	}
+}
+
+func (p *page) object() interface{} {
 	d := map[string]interface{}{
-		"Type":     name("Page"),
-		"Parent":   p.par,
-		"MediaBox": p.box,
-		// TODO Resources is only empty now
-		"Resource": map[string]interface{}{},
-		"Contents": p.con,
-	}
-	return output(d)
+		"Type":      name("Page"),
+		"Parent":    p.par,
+		"MediaBox":  p.box,
+		"Resources": p.res.dict(),
+		"Contents":  p.con,
+	}
+	if p.unit != 0 && p.unit != 1 {
+		d["UserUnit"] = p.unit
+	}
+	if p.trim != nil {
+		d["TrimBox"] = p.trim
+	}
+	if p.crop != nil {
+		d["CropBox"] = p.crop
+	}
+	if p.bleed != nil {
+		d["BleedBox"] = p.bleed
+	}
+	if len(p.annots) > 0 {
+		d["Annots"] = p.annots
+	}
+	switch p.tabs {
+	case TabRow:
+		d["Tabs"] = name("R")
+	case TabColumn:
+		d["Tabs"] = name("C")
+	case TabStructure:
+		d["Tabs"] = name("S")
+	}
+	if len(p.mcids) > 0 {
+		d["StructParents"] = p.sp
+	}
+	if len(p.vps) > 0 {
+		d["VP"] = p.vps
+	}
+	if len(p.af) > 0 {
+		d["AF"] = p.af
+	}
+	if p.dur > 0 {
+		d["Dur"] = p.dur
+	}
+	if len(p.pieces) > 0 {
+		d["PieceInfo"] = p.pieces
+		d["LastModified"] = p.modified
+	}
+	return d
 }
@@ -0,0 +1,366 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file parses the objects of existing PDF files (p. 51) and the
+// operators of content streams into the same values that are used for making
+// documents: map[string]interface{} for dictionaries, []interface{} for
+// arrays, name for names, string for strings, and int, float64, bool and nil.
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// ref is a reference to an indirect object of a parsed file.
+type ref struct {
+	num, gen int
+}
+
+func (r ref) output() []byte {
+	return []byte(fmt.Sprintf("%d %d R", r.num, r.gen))
+}
+
+// keyword is a bare word in a file, like obj, stream, or an operator of a
+// content stream.
+type keyword string
+
+// delim is a closing delimiter of arrays and dictionaries.
+type delim string
+
+// parser reads objects from b, starting at pos.
+type parser struct {
+	b   []byte
+	pos int
+}
+
+// isSpace tells whether c is a white-space character (p. 50).
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// isDelim tells whether c is a delimiter character (p. 50).
+func isDelim(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// skipSpace skips white-space and comments.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.b) {
+		switch c := p.b[p.pos]; {
+		case isSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.b) && p.b[p.pos] != '\n' && p.b[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// eof tells whether there's nothing but white-space left.
+func (p *parser) eof() bool {
+	p.skipSpace()
+	return p.pos >= len(p.b)
+}
+
+// word reads a run of regular characters.
+func (p *parser) word() string {
+	start := p.pos
+	for p.pos < len(p.b) && !isSpace(p.b[p.pos]) && !isDelim(p.b[p.pos]) {
+		p.pos++
+	}
+	return string(p.b[start:p.pos])
+}
+
+// token reads the next token, which is an object other than an array, a
+// dictionary or a reference, a keyword, or one of the delimiters "[", "]",
+// "<<" and ">>".
+func (p *parser) token() interface{} {
+	if p.eof() {
+		fail(ErrBadFile, "unexpected end of data")
+	}
+	switch c := p.b[p.pos]; c {
+	case '/':
+		p.pos++
+		return p.name()
+	case '(':
+		p.pos++
+		return p.literal()
+	case '<':
+		if p.pos+1 < len(p.b) && p.b[p.pos+1] == '<' {
+			p.pos += 2
+			return delim("<<")
+		}
+		p.pos++
+		return p.hex()
+	case '>':
+		if p.pos+1 < len(p.b) && p.b[p.pos+1] == '>' {
+			p.pos += 2
+			return delim(">>")
+		}
+	case '[', ']', '{', '}':
+		p.pos++
+		return delim(string(c))
+	}
+	w := p.word()
+	if w == "" {
+		fail(ErrBadFile, fmt.Sprintf("unexpected character %q at %d", p.b[p.pos], p.pos))
+	}
+	switch w {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if c := w[0]; c == '+' || c == '-' || c == '.' || c >= '0' && c <= '9' {
+		if i, err := strconv.Atoi(w); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(w, 64); err == nil {
+			return f
+		}
+	}
+	return keyword(w)
+}
+
+// name reads a name after its slash, decoding #XX escapes (p. 57).
+func (p *parser) name() name {
+	w := p.word()
+	if bytes.IndexByte([]byte(w), '#') < 0 {
+		return name(w)
+	}
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < len(w); i++ {
+		if w[i] == '#' && i+2 < len(w) {
+			if v, err := strconv.ParseUint(w[i+1:i+3], 16, 64); err == nil {
+				buf.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+		}
+		buf.WriteByte(w[i])
+	}
+	return name(buf.String())
+}
+
+// literal reads a literal string after its opening parenthesis (p. 53).
+func (p *parser) literal() string {
+	buf := bytes.NewBuffer(nil)
+	depth := 0
+	for p.pos < len(p.b) {
+		c := p.b[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return buf.String()
+			}
+			depth--
+		case '\r':
+			// End of lines are read as \n.
+			if p.pos < len(p.b) && p.b[p.pos] == '\n' {
+				p.pos++
+			}
+			c = '\n'
+		case '\\':
+			if p.pos >= len(p.b) {
+				break
+			}
+			c = p.b[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.b) && p.b[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && p.pos < len(p.b) &&
+						p.b[p.pos] >= '0' && p.b[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.b[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		buf.WriteByte(c)
+	}
+	panic(&Error{Kind: ErrBadFile, Msg: "unterminated string"})
+}
+
+// hex reads a hexadecimal string after its opening angle bracket (p. 56).
+func (p *parser) hex() string {
+	buf := bytes.NewBuffer(nil)
+	var v byte
+	odd := false
+	for p.pos < len(p.b) {
+		c := p.b[p.pos]
+		p.pos++
+		switch {
+		case c == '>':
+			if odd {
+				buf.WriteByte(v << 4)
+			}
+			return buf.String()
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c -= 'a' - 10
+		case c >= 'A' && c <= 'F':
+			c -= 'A' - 10
+		case isSpace(c):
+			continue
+		default:
+			fail(ErrBadFile, "bad character in hexadecimal string")
+		}
+		if odd {
+			buf.WriteByte(v<<4 | c)
+		} else {
+			v = c
+		}
+		odd = !odd
+	}
+	panic(&Error{Kind: ErrBadFile, Msg: "unterminated hexadecimal string"})
+}
+
+// object reads the next object. Indirect references are read as ref, and
+// keywords as keyword.
+func (p *parser) object() interface{} {
+	t := p.token()
+	switch t := t.(type) {
+	case delim:
+		switch t {
+		case "[":
+			a := make([]interface{}, 0)
+			for {
+				o := p.object()
+				if o == delim("]") {
+					return a
+				}
+				a = append(a, o)
+			}
+		case "<<":
+			d := make(map[string]interface{})
+			for {
+				k := p.object()
+				if k == delim(">>") {
+					return d
+				}
+				n, ok := k.(name)
+				if !ok {
+					fail(ErrBadFile, fmt.Sprintf("key of dictionary is not a name at %d", p.pos))
+				}
+				v := p.object()
+				if _, ok := v.(delim); ok {
+					fail(ErrBadFile, fmt.Sprintf("dictionary with no value for key %s", n))
+				}
+				d[string(n)] = v
+			}
+		}
+		return t
+	case int:
+		// Two numbers followed by R are a reference.
+		pos := p.pos
+		if !p.eof() && p.b[p.pos] >= '0' && p.b[p.pos] <= '9' {
+			if g, ok := p.token().(int); ok && !p.eof() && p.b[p.pos] == 'R' {
+				if r := p.word(); r == "R" {
+					return ref{t, g}
+				}
+			}
+		}
+		p.pos = pos
+	}
+	return t
+}
+
+// expect reads the keyword k, and panics if there's anything else.
+func (p *parser) expect(k string) {
+	if t := p.token(); t != keyword(k) {
+		fail(ErrBadFile, fmt.Sprintf("expected %s at %d, got %v", k, p.pos, t))
+	}
+}
+
+// operation is an operator of a content stream with its operands (p. 152).
+type operation struct {
+	op   string
+	args []interface{}
+}
+
+// operations parses the content stream b into operations. The entries of the
+// dictionary of inline images (p. 352) are the operands of their ID operators,
+// followed by their data.
+func operations(b []byte) []operation {
+	p := &parser{b: b}
+	var ops []operation
+	var args []interface{}
+	for !p.eof() {
+		o := p.object()
+		k, ok := o.(keyword)
+		if !ok {
+			if _, ok := o.(delim); ok {
+				fail(ErrBadFile, fmt.Sprintf("unexpected %v in content stream", o))
+			}
+			args = append(args, o)
+			continue
+		}
+		if k == "ID" {
+			// The data starts after a single white-space, and ends with
+			// white-space and EI.
+			p.pos++
+			e := p.pos
+			for {
+				n := bytes.Index(p.b[e:], []byte("EI"))
+				if n < 0 {
+					fail(ErrBadFile, "inline image with no end")
+				}
+				e += n
+				if isSpace(p.b[e-1]) && (e+2 == len(p.b) || isSpace(p.b[e+2])) {
+					break
+				}
+				e += 2
+			}
+			args = append(args, string(p.b[p.pos:e-1]))
+			p.pos = e
+		}
+		ops = append(ops, operation{string(k), args})
+		args = nil
+	}
+	return ops
+}
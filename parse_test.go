@@ -0,0 +1,75 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"reflect"
+	"testing"
+)
+
+type parseTest struct {
+	in  string
+	out interface{}
+}
+
+func TestParseObject(t *testing.T) {
+	tests := []parseTest{
+		{"12", 12},
+		{"-3.5", -3.5},
+		{".5", 0.5},
+		{"true", true},
+		{"null", nil},
+		{"/Name", name("Name")},
+		{"/A#20B", name("A B")},
+		{"(a (b) \\) \\101\\n)", "a (b) ) A\n"},
+		{"(line\\\nbreak)", "linebreak"},
+		{"<48 656c6c6f>", "Hello"},
+		{"<4>", "@"},
+		{"[ 1 2 0 R /X ]", []interface{}{1, ref{2, 0}, name("X")}},
+		{"[1 2]", []interface{}{1, 2}},
+		{"<< /A 1 % comment\n /B [ ] /C << >> >>", map[string]interface{}{
+			"A": 1, "B": []interface{}{}, "C": map[string]interface{}{}}},
+		{"obj", keyword("obj")},
+	}
+
+	for _, test := range tests {
+		p := &parser{b: []byte(test.in)}
+		if o := p.object(); !reflect.DeepEqual(o, test.out) {
+			t.Errorf("%q: got %#v expected %#v", test.in, o, test.out)
+		}
+	}
+}
+
+func TestOperations(t *testing.T) {
+	ops := operations([]byte("q 1 0 0 1 0 0 cm BT /F1 12 Tf [(A) -5 (B)] TJ ET\n" +
+		"BI /W 1 /H 1 ID \x00EI\x01 EI Q"))
+	expected := []operation{
+		{"q", nil},
+		{"cm", []interface{}{1, 0, 0, 1, 0, 0}},
+		{"BT", nil},
+		{"Tf", []interface{}{name("F1"), 12}},
+		{"TJ", []interface{}{[]interface{}{"A", -5, "B"}}},
+		{"ET", nil},
+		{"BI", nil},
+		{"ID", []interface{}{name("W"), 1, name("H"), 1, "\x00EI\x01"}},
+		{"EI", nil},
+		{"Q", nil},
+	}
+	if !reflect.DeepEqual(ops, expected) {
+		t.Errorf("got %v expected %v", ops, expected)
+	}
+}
@@ -18,12 +18,13 @@ limitations under the License.
 package pdf
 
 import (
-	"bytes"
-	"io"
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"runtime"
-	"os"
+	"sort"
+	"sync"
 )
 
 // Document holds all the objects of a PDF document.
@@ -33,22 +34,132 @@ type Document struct {
 	off  int // Number of bytes already written to w
 	xOff int // Offset of corss reference table
 
+	parts [numParts]int // Bytes written for the parts of the document counted by Sizes
+
+	missing map[rune]bool // Characters of text with no glyphs, guarded by resMu
+
+	closed bool            // Whether Close is called
+	ctx    context.Context // Work stops when it's done, if it's set
+
+	ws      io.WriteSeeker // w, if the document is made by NewSeekable
+	base    int64          // Position of w where the document starts
+	patches []patch        // Blanks to be filled in when the document is closed
+	out     io.Writer      // Output of documents assembled in memory
+	limit   int            // Memory limit of each stream, if it's set
+	temps   []TempFile     // Temporary files of streams over the limit, guarded by tempMu
+
+	// Objects and the output are guarded by mu, and the shared resources
+	// of pages, like fonts, by resMu, so that images and the content of
+	// pages can be added by many goroutines at once.
+	mu    sync.Mutex
+	resMu sync.Mutex
+	// Temporary files are made while objects are written, even under
+	// resMu, so they have a lock of their own.
+	tempMu sync.Mutex
+
+	opts    Options // Options given to New, with the defaults filled in
+	version string  // PDF version in the header
+
 	// The following *indirect variables are pointers to elements of objs.
 	cat   *indirect     // PDF catalog
 	ptree *indirect     // Page tree
 	pg    *page         // Current page
 	pgs   []*indirect   // List of pages
-	con   *bytes.Buffer // Current content stream.
+	open  []*page       // Pages not written to the output yet
+	con   *spill        // Current content stream.
+	cw    ContentWriter // Operators of graphics methods, reused by them
+	text  textObject    // Text object and font of the current page
+
+	fields []*indirect          // Fields of the interactive form
+	ffonts map[string]*indirect // Fonts used in appearances of fields
+	calcs  []*indirect          // Calculation order of fields
+
+	gstates  map[string]*indirect     // Graphics state parameter dictionaries, by their output
+	funcs    map[Function]interface{} // Functions of PDF, written once, by their values
+	regSpace *indirect                // Color space of printer's marks, once it's written
+
+	fnames    map[string]bool       // Fully qualified names of the fields
+	fnodes    map[string]*fieldNode // Non-terminal fields by name
+	fnodeList []*fieldNode          // Non-terminal fields, in order
+
+	sec *security   // Security handler, if the document is encrypted
+	id  []byte      // First part of the file identifier, if there's one
+	sig *signer     // Signer of the document, if it's signed
+	dss *Validation // Validation data of the signature, if any
+
+	catd map[string]interface{} // Catalog dictionary, once it's written
+
+	info       *Info                  // Metadata of the document, if it's set
+	infoRef    *indirect              // Information dictionary, once it's written
+	xmpDescs   []string               // rdf:Description elements of XMP metadata
+	files      []attachment           // Attached files
+	objAF      []*indirect            // Associated files of the annotations and images added next
+	outlines   *indirect              // Outline dictionary, if there's one
+	dests      map[string]interface{} // Named destinations
+	openAction interface{}            // Action or destination of opening the document, if set
+
+	pdfa       bool                   // Whether it's made to conform to PDF/A-3
+	pdfx       string                 // Version of PDF/X it's made to conform to, if any
+	intents    []*outputIntent        // Output intents
+	pdfua      bool                   // Whether it's made to conform to PDF/UA-1
+	lang       string                 // Natural language of the document, if set
+	pieces     map[string]interface{} // Private data of applications in the document, by their names
+	reqs       []string               // Types of the requirements of the document
+	extensions map[string]*Extension  // Developer extensions used by the document, by their prefixes
+	violations map[string]bool        // Rules of the standards broken by the document
+
+	stree   *indirect       // Structure tree root, if the document is tagged
+	tags    []*structElem   // Top-level structure elements
+	tag     *structElem     // Innermost open structure element, if any
+	parents [][]*structElem // Structure elements of the marked content of pages
+	actual  int             // Number of open spans of replacement text
+	marked  int             // Number of open sequences of custom marked content
+
+	copiers map[*Reader]*copier // Copiers of the files pages are imported from
+	flows   []*Flow             // Flows of content added to the document
+
+	headings []*heading       // Headings of the document, in order
+	toc      *TableOfContents // Table of contents, if it's set
+	tocPlan  *tocPlan         // Layout of the table of contents, when it's closed
+	tocStart int              // Index of the first page of the table of contents in pgs
+	terms    []*indexTerm     // Terms of the index, in order
+	index    *Index           // Index, if it's set
+
+	startHooks []PageHook                // Hooks called after pages are started
+	endHooks   []PageHook                // Hooks called before pages are written
+	closeHooks []func(d *Document) error // Hooks called when the document is closed
+
+	present *Presentation    // How the document is shown as slides, if it's set
+	notes   map[*page]string // Speaker notes of pages
+	print   *PrintPreset     // How the print dialog of viewers is filled in, if it's set
+
+	emoji  []Emoji              // Sources of the glyphs of emoji
+	emojis *emojiSet            // Font of the emoji used, once there's one
+	sfnts  map[string]*sfntFont // Embedded TrueType and OpenType fonts, by their names in resources
+
+	styles map[string]Style // Named styles
+	gstate *Style           // What the styles used on the current content have set, if it's known
+
+	exts     map[string][]func(dic map[string]interface{}) error // Functions adding custom entries, by kind of object
+	reserved map[*indirect]bool                                  // Objects reserved by Reserve and not defined yet
+
+	xbox   *rect       // Bounding box of the XObject being made, if any
+	xres   resources   // Resources of the XObject being made
+	xaf    []*indirect // Associated files of the XObject being made
+	pcon   *spill      // Content of the page while an XObject is being made
+	ptrack tracker     // Current point and what's painted on the page, while an XObject is being made
 }
 
 // New initializes a new PDF document, ready to be filled by new pages, graphics,
-// text, etc.
-func New(w io.Writer) (d *Document, err os.Error) {
+// text, etc. It's made according to opts, which can have one Options at most;
+// the defaults are used if it's empty.
+func New(w io.Writer, opts ...Options) (d *Document, err error) {
 	defer dontPanic(&err)
 
 	if w == nil {
 		panic("pdf.New function was called with a nil parameter.")
 	}
+	o := options(opts)
 
 	// Initiate the docuemnt.
 	d = new(Document)
@@ -58,59 +169,246 @@ func New(w io.Writer) (d *Document, err os.Error) {
 	d.cat = d.reserveIndirect()   // to be later updated by saveCatalog
 	d.ptree = d.reserveIndirect() // to be later updated by updatePageTree
 	d.off = 0
+	d.opts = o
+	d.version = o.Version
+	d.cw.Precision(o.Precision)
 
-	// Write header of the file.
-	d.writeHeader()
+	// The header of the file is written with the first object, so that
+	// its version can still be changed.
 
 	return d, nil
 }
 
-// Close finalizes the document by writing the rest of the PDF file to the output.
-func (d *Document) Close() (err os.Error) {
+// Close finalizes the document by writing the rest of the PDF file to the
+// output. The document can't be changed after it's closed, even if Close
+// fails.
+func (d *Document) Close() (err error) {
+	return d.CloseContext(context.Background())
+}
+
+// CloseContext is like Close, but stops writing the document when ctx is
+// done, with an error of kind ErrCanceled. The output is left unfinished
+// then.
+func (d *Document) CloseContext(ctx context.Context) (err error) {
 	defer dontPanic(&err)
 
+	d.checkClosed()
+	d.ctx = ctx
+	defer func() {
+		d.closed = true
+		d.removeTemps()
+	}()
+
+	if d.xbox != nil {
+		panic("document closed before EndXObject was called")
+	}
+	for _, f := range d.closeHooks {
+		check(f(d))
+	}
+	for _, f := range d.flows {
+		f.finish()
+	}
+	if len(d.reserved) > 0 {
+		panic(fmt.Sprintf("%d objects reserved but not defined", len(d.reserved)))
+	}
+	if d.toc != nil {
+		d.planTOC()
+	}
+	if d.index != nil {
+		d.makeIndex()
+	}
+	d.makeTOC()
+	if d.emojis != nil && d.emojis.ref != nil {
+		d.saveEmoji()
+	}
+	if len(d.sfnts) > 0 {
+		d.saveFonts()
+	}
+	if d.present != nil && d.present.Notes != nil {
+		d.writeNotes()
+	}
+
 	// Save the pages and catalog.
 	d.updatePageTree()
+	if d.sig != nil {
+		d.beginSignature()
+	}
+	if len(d.tags) > 0 {
+		d.saveStructTree()
+	}
+	d.saveInfo()
+	if d.outlines == nil && len(d.headings) > 0 {
+		d.headingOutlines()
+	}
 	d.saveCatalog()
+	if d.sec != nil {
+		d.outputIndirect(d.sec.ref, d.sec)
+	}
+	d.checkViolations()
+	d.checkWritten()
 
 	// Write the document to d.w.
-	d.writeRefs()
-	d.writeTrailer()
+	d.writeRefs(d.objs)
+	d.writeTrailer(0)
+	d.fillBlanks()
+	if d.sig != nil {
+		d.endSignature()
+	}
+	if d.dss != nil {
+		d.writeDSS()
+	}
+	d.emit()
 	return nil
 }
 
-// NewPage appends a new empty page to the document with the given size.
-func (d *Document) NewPage(w, h int) (err os.Error) {
+// NewPage appends a new empty page to the document with the given size, and
+// makes it the current page, which content is drawn on. The page is kept
+// open, and can be drawn on later with p, until it's flushed or the document
+// is closed. Pages with zero width and height have the default size of the
+// document's options.
+func (d *Document) NewPage(w, h int) (p *Page, err error) {
+	defer dontPanic(&err)
+
+	if d.xbox != nil {
+		panic("NewPage called before EndXObject")
+	}
+	if w == 0 && h == 0 {
+		w, h = d.opts.PageWidth, d.opts.PageHeight
+	}
+	return &Page{d, d.newPage(w, h)}, nil
+}
+
+// newPage appends a new page of width w and height h to d, and makes it the
+// current page.
+func (d *Document) newPage(w, h int) *page {
+	pg := newPage(w, h, d.ptree)
+	pg.unit = d.opts.UserUnit
+	pg.index = len(d.pgs)
+	pg.ref = d.reserveIndirect()
+	d.pgs = append(d.pgs, pg.ref)
+	d.open = append(d.open, pg)
+	d.setPage(pg)
+	d.runPageHooks(d.startHooks, pg)
+	return pg
+}
+
+// setPage makes pg the current page of d. There's no current page if it's
+// nil.
+func (d *Document) setPage(pg *page) {
+	d.stateChanged()
+	if d.pg != nil {
+		d.pg.buf, d.pg.track, d.pg.text = d.con, d.cw.t, d.text
+	}
+	d.pg, d.con, d.cw.t, d.text = pg, nil, tracker{}, textObject{}
+	if pg != nil {
+		d.con, d.cw.t, d.text = pg.buf, pg.track, pg.text
+	}
+}
+
+// SetTrimBox sets the trim box of the current page (p. 962), which is the
+// size of the page after it's printed and trimmed. It's the rectangle with
+// lower-left corner at (x, y), width w, and height h.
+func (d *Document) SetTrimBox(x, y, w, h float64) (err error) {
 	defer dontPanic(&err)
 
-	d.savePage() // Save the current one before starting anew.
-	d.pg = newPage(w, h, d.ptree)
+	if d.pg == nil {
+		fail(ErrNoPage, "SetTrimBox called before any page was started")
+	}
+	d.pg.trim = newRect(x, y, x+w, y+h)
 	return nil
 }
 
-// savePage writes the current page (d.pg) to the output.
-func (d *Document) savePage() {
+// SetBleedBox sets the bleed box of the current page (p. 962), which is the
+// region of the page that is printed before it's trimmed. It's the rectangle
+// with lower-left corner at (x, y), width w, and height h, and it should
+// contain the trim box.
+func (d *Document) SetBleedBox(x, y, w, h float64) (err error) {
+	defer dontPanic(&err)
+
 	if d.pg == nil {
-		return
+		fail(ErrNoPage, "SetBleedBox called before any page was started")
+	}
+	d.pg.bleed = newRect(x, y, x+w, y+h)
+	return nil
+}
+
+// flushPage writes the open page pg to the output. The current page stays
+// the same, unless it's pg: then there's no current page.
+func (d *Document) flushPage(pg *page) {
+	cur := d.pg
+	d.setPage(pg)
+
+	// Footnotes of flows go at the bottom of their pages.
+	for _, f := range d.flows {
+		if f.pg == d.pg {
+			f.endPage()
+		}
 	}
+	d.runPageHooks(d.endHooks, pg)
+	d.checkTags()
+	d.cropToContent(pg)
+	if pg.dur == 0 && d.present != nil {
+		pg.dur = d.present.Duration
+	}
+
 	// Save the current content stream and add it to the page.
-	d.pg.addContent(d.indirect(d.con))
+	if d.con != nil {
+		i := d.reserveIndirect()
+		if len(d.pg.counts) > 0 {
+			// Page counts are filled in where they're written.
+			d.checkContent(i, d.con)
+			d.outputSpill(i, nil, d.con)
+		} else {
+			d.outputContent(i, nil, d.con)
+		}
+		d.pg.addContent(i)
+		d.pageCountBlanks(d.con.Len())
+	}
 	// Current content stream was written to the output, so we don't need it
 	// anymore.
 	d.con = nil
 
+	// The widget of the signature field goes on the first page.
+	if d.sig != nil && d.pg.index == 0 {
+		d.pg.addAnnot(d.sig.field, newRect(0, 0, 0, 0))
+	}
+
+	// Marked content of the page goes to the parent tree.
+	if len(d.pg.mcids) > 0 {
+		d.pg.sp = len(d.parents)
+		d.parents = append(d.parents, d.pg.mcids)
+	}
+
 	// Add the page to the list of pages.
-	d.pgs = append(d.pgs, d.indirect(d.pg))
+	d.checkPage(d.pg)
+	d.pg.sortAnnots()
+	dic := d.pg.object().(map[string]interface{})
+	d.outputIndirect(d.pg.ref, d.extend(ExtendPage, dic, d.pg.extra))
+
+	pg.flushed = true
+	for i, o := range d.open {
+		if o == pg {
+			d.open = append(d.open[:i], d.open[i+1:]...)
+			break
+		}
+	}
+	d.pg = nil
+	if cur != pg {
+		d.setPage(cur)
+	}
 }
 
 // savePageTree makes page tree dictionary.
 func (d *Document) updatePageTree() {
-	d.savePage() // Save the current page first.
+	// Pages that are still open are written first.
+	for len(d.open) > 0 {
+		d.flushPage(d.open[0])
+	}
 
 	tree := map[string]interface{}{
 		"Type":  name("Pages"),
 		"Count": len(d.pgs),
-		"Kids":  d.pgs,
+		"Kids":  d.pageOrder(),
 	}
 	d.outputIndirect(d.ptree, tree)
 }
@@ -121,19 +419,126 @@ func (d *Document) saveCatalog() {
 		return
 	}
 	cat := map[string]interface{}{
-		"Type":  ("Catalog"),
+		"Type":  name("Catalog"),
 		"Pages": d.ptree,
 	}
+	if len(d.fields) > 0 {
+		f := d.acroForm()
+		if d.sig != nil {
+			f["SigFlags"] = 3 // SignaturesExist and AppendOnly
+		}
+		cat["AcroForm"] = f
+	}
+	names := make(map[string]interface{})
+	if len(d.files) > 0 {
+		d.filesCatalog(cat, names)
+	}
+	if len(d.dests) > 0 {
+		names["Dests"] = nameTree(d.dests)
+	}
+	if len(names) > 0 {
+		cat["Names"] = names
+	}
+	if d.outlines != nil {
+		cat["Outlines"] = d.outlines
+	}
+	if d.openAction != nil {
+		cat["OpenAction"] = d.openAction
+	}
+	if d.pdfa || len(d.intents) > 0 {
+		cat["OutputIntents"] = d.outputIntents()
+	}
+	if d.stree != nil {
+		cat["StructTreeRoot"] = d.stree
+		cat["MarkInfo"] = map[string]interface{}{"Marked": true}
+	}
+	if d.lang != "" {
+		cat["Lang"] = d.lang
+	}
+	if len(d.pieces) > 0 {
+		cat["PieceInfo"] = d.pieces
+	}
+	vp := d.printPreferences()
+	if d.pdfua {
+		vp["DisplayDocTitle"] = true
+	}
+	if len(vp) > 0 {
+		cat["ViewerPreferences"] = vp
+	}
+	if d.present != nil && d.present.FullScreen {
+		cat["PageMode"] = name("FullScreen")
+	}
+	if len(d.xmpDescs) > 0 {
+		cat["Metadata"] = d.indirect(&stream{map[string]interface{}{
+			"Type":    name("Metadata"),
+			"Subtype": name("XML"),
+		}, d.xmp(d.xmpDescs)})
+	}
+	if d.sec != nil && d.sec.r == 6 {
+		// AES-256 is an extension of Adobe to PDF 1.7.
+		d.addExtension(&Extension{Prefix: "ADBE", BaseVersion: "1.7", Level: 8})
+	}
+	if len(d.extensions) > 0 {
+		cat["Extensions"] = d.extensionsDict()
+	}
+	if len(d.reqs) > 0 {
+		cat["Requirements"] = d.requirements()
+	}
+	d.catd = d.extend(ExtendCatalog, cat, nil)
 	d.outputIndirect(d.cat, cat)
 }
 
+// nameTree returns a name tree (p. 161) with the entries of m. All of the
+// entries are in its root.
+func nameTree(m map[string]interface{}) map[string]interface{} {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// Keys of name trees are sorted.
+	sort.Strings(keys)
+	a := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		a = append(a, k, m[k])
+	}
+	return map[string]interface{}{"Names": a}
+}
+
 // addc writes string to the current content stream. Functions that work
-// with content, like Line and Stroke, use this to add content.
+// with content, like Line and Stroke, use this to add content. It panics
+// inside text objects, where graphics can't be drawn.
 func (d *Document) addc(s string) {
+	d.outsideText("graphics")
+	d.addm(s)
+}
+
+// addm writes s, which are operators of marked content or compatibility
+// sections, to the current content stream. Unlike addc, it can be called
+// inside text objects.
+func (d *Document) addm(s string) {
+	d.stateChanged()
+	w := d.ops()
+	w.b = append(append(w.b, s...), '\n')
+	d.addw(w)
+}
+
+// addw writes the operators written by w to the current content stream.
+func (d *Document) addw(w *ContentWriter) {
+	d.content()
+	d.traceContent(d.con)
+	_, err := d.con.Write(w.Bytes())
+	checkWrite(err)
+}
+
+// content makes sure that d has a current content stream.
+func (d *Document) content() {
+	d.checkClosed()
+	if d.pg == nil && d.xbox == nil {
+		fail(ErrNoPage, "content drawn before any page was started")
+	}
 	if d.con == nil {
-		d.con = bytes.NewBuffer([]byte{})
+		d.con = d.newSpill()
 	}
-	d.con.Write([]byte(s + "\n"))
 }
 
 // writeHeader writes the PDF header to the output.
@@ -142,57 +547,98 @@ func (d *Document) writeHeader() {
 	// recommended by PDF Reference for PDF files containing binary data.
 	// This helps other applications treat the file as binary. "سلام" means
 	// "hello" in Persian.
-	b := []byte("%PDF-1.7\n%سلام\n")
-	n, err := d.w.Write(b)
-	d.off += n
-	check(err)
+	d.write([]byte("%PDF-" + d.version + "\n%سلام\n"))
 }
 
-// writeRefs prints the cross-reference table for the objects.
-func (d *Document) writeRefs() {
+// writeRefs prints the cross-reference table for objs, which are sorted by
+// number. All the objects of the document are in the table of the file, but an
+// incremental update has only the ones it changes (p. 73).
+func (d *Document) writeRefs(objs []*indirect) {
 	d.xOff = d.off
 
-	// Print the beginning 'xref' and number of objects.
-	n, err := fmt.Fprintf(d.w, "xref\n%d %d\n", 0, len(d.objs)+1)
-	d.off += n
-	check(err)
-
-	// Print the first line in xref.
-	n, err = d.w.Write([]byte("0000000000 65535 f\r\n"))
-	d.off += n
-	check(err)
+	d.write([]byte("xref\n"))
 
-	// Write references of the objects.
-	for _, o := range d.objs {
-		n, err := d.w.Write(o.ref())
-		d.off += n
-		check(err)
+	// Each run of objects with consecutive numbers is a subsection. The
+	// free object 0 starts the subsection of object 1.
+	for i := 0; i < len(objs); {
+		j := i + 1
+		for j < len(objs) && objs[j].num == objs[j-1].num+1 {
+			j++
+		}
+		start, count := objs[i].num, j-i
+		if start == 1 {
+			start, count = 0, count+1
+		}
+		d.write([]byte(fmt.Sprintf("%d %d\n", start, count)))
+		if start == 0 {
+			d.write([]byte("0000000000 65535 f\r\n"))
+		}
+		for _, o := range objs[i:j] {
+			d.write(o.ref())
+		}
+		i = j
 	}
 }
 
-// writeTrailer finishes of the PDF document.
-func (d *Document) writeTrailer() {
+// writeTrailer finishes of the PDF document. prev is the offset of the
+// previous cross-reference table for incremental updates, or 0.
+func (d *Document) writeTrailer(prev int) {
 	// 'trailer' title
-	n, err := d.w.Write([]byte("trailer\n"))
-	d.off += n
-	check(err)
+	d.write([]byte("trailer\n"))
 
 	// Dictionary referring to the catalog as root
 	dic := map[string]interface{}{
 		"Size": len(d.objs) + 1,
 		"Root": d.cat,
 	}
-	n, err = d.w.Write(output(dic))
-	d.off += n
-	check(err)
+	if d.sec != nil {
+		dic["Encrypt"] = d.sec.ref
+	}
+	if d.infoRef != nil {
+		dic["Info"] = d.infoRef
+	}
+	if d.id != nil {
+		dic["ID"] = []hexString{d.id, d.id}
+	}
+	if prev != 0 {
+		dic["Prev"] = prev
+	}
+	d.write(output(dic))
 
 	// Offset of 'xref' table
-	n, err = d.w.Write([]byte(fmt.Sprintf("\nstartxref\n%d\n", d.xOff)))
+	d.write([]byte(fmt.Sprintf("\nstartxref\n%d\n", d.xOff)))
 
 	// Ending the document
-	n, err = d.w.Write([]byte("%%EOF\n"))
+	d.write([]byte("%%EOF\n"))
+}
+
+// checkClosed panics if d is closed.
+func (d *Document) checkClosed() {
+	if d.closed {
+		fail(ErrClosed, "document is already closed")
+	}
+}
+
+// write writes b to the output.
+func (d *Document) write(b []byte) {
+	d.checkClosed()
+	n, err := d.w.Write(b)
 	d.off += n
-	check(err)
+	checkWrite(err)
+}
+
+// started tells whether any object is already written to the output, or any
+// page is started.
+func (d *Document) started() bool {
+	if len(d.pgs) > 0 {
+		return true
+	}
+	for _, o := range d.objs {
+		if o.off != 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // indirect turns o into a PDF object, writes it to the output, and returns a PDF
@@ -206,6 +652,10 @@ func (d *Document) indirect(o interface{}) (i *indirect) {
 // reverseIndirect makes and returns a new indirect object, but doesn't save it. The
 // object itself can be outputted later by calling outputIndirect.
 func (d *Document) reserveIndirect() (i *indirect) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.checkClosed()
 	i = &indirect{num: len(d.objs) + 1}
 	d.objs = append(d.objs, i)
 	return i
@@ -213,37 +663,28 @@ func (d *Document) reserveIndirect() (i *indirect) {
 
 // outputIndirect writes o as a PDF indirect object to the output.
 func (d *Document) outputIndirect(i *indirect, o interface{}) {
-	i.off = d.off
-	n, err := d.w.Write([]byte(fmt.Sprintf("%d 0 obj\n", i.num)))
-	d.off += n
-	check(err)
-	n, err = d.w.Write(output(o))
-	d.off += n
-	check(err)
-	n, err = d.w.Write([]byte("\nendobj\n"))
-	d.off += n
-	check(err)
-}
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-// check panics if err is not nil.
-func check(err os.Error) {
-	if err != nil {
-		panic(err)
+	checkContext(d.ctx)
+	if d.off == 0 {
+		d.writeHeader()
 	}
-}
-
-// dontPanic handles expectws panics and turns them into errors of type os.Error.
-func dontPanic(err *os.Error) {
-	if r := recover(); r != nil {
-		switch e := r.(type) {
-		case string:
-			*err = os.NewError("pdf.go: " + e)
-		case os.Error:
-			*err = e
-		default:
-			panic(r)
+	i.off = d.off
+	defer d.countPart(i, o)
+	d.traceObject(i, o)
+	d.write([]byte(fmt.Sprintf("%d 0 obj\n", i.num)))
+	e := encoder{}
+	if d.sec != nil && i != d.sec.ref {
+		e.crypt = d.sec.crypt(i.num)
+		if !d.sec.aes && unencryptedStream(o) {
+			panic("Crypt filters need AES encryption")
 		}
 	}
+	d.checkObject(o)
+	d.checkStrict(i, o)
+	d.write(e.output(o))
+	d.write([]byte("\nendobj\n"))
 }
 
 // here prints the current line number and file name every time it's called.
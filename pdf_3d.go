@@ -0,0 +1,179 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains 3D annotations, which show 3D models in U3D or PRC
+// format that can be turned and zoomed in viewers, like the parts of a
+// machine exported from CAD programs.
+
+// Formats of 3D models.
+const (
+	Format3DU3D = "U3D" // Universal 3D (ECMA-363); needs PDF 1.6
+	Format3DPRC = "PRC" // Product Representation Compact (ISO 14739-1); needs PDF 1.7
+)
+
+// When 3D annotations are activated, so that their models can be moved.
+const (
+	Activate3DClick   = "XA" // when they're clicked
+	Activate3DOpen    = "PO" // when their page is opened
+	Activate3DVisible = "PV" // when their page is visible
+)
+
+// lightings are the lighting schemes of views of 3D models.
+var lightings = map[string]bool{
+	"Artwork": true, "None": true, "White": true, "Day": true, "Night": true,
+	"Hard": true, "Primary": true, "Blue": true, "Red": true, "Cube": true,
+	"CAD": true, "Headlamp": true,
+}
+
+// View3D is a view of a 3D model, which viewers list by its name.
+type View3D struct {
+	Name string // like "Front"
+
+	// Camera is the matrix from the coordinates of the camera to the ones
+	// of the model, [a b c d e f g h i tx ty tz], where the camera looks
+	// along its z axis. The view of the model itself is used if it's all
+	// zeros.
+	Camera [12]float64
+	Orbit  float64 // distance from the camera to the center of orbit
+
+	FieldOfView float64 // in degrees, for perspective projection; orthographic if zero
+	Background  *RGB    // white if nil
+	Lighting    string  // lighting scheme, like "CAD" or "Headlamp"; the one of the model if empty
+}
+
+// check panics if v is not a valid view.
+func (v *View3D) check() {
+	if v.Name == "" {
+		panic("3D view with no name")
+	}
+	if v.Lighting != "" && !lightings[v.Lighting] {
+		panic("unknown lighting scheme: " + v.Lighting)
+	}
+}
+
+func (v *View3D) object() interface{} {
+	d := map[string]interface{}{
+		"Type": name("3DView"),
+		"XN":   v.Name,
+		"IN":   v.Name,
+	}
+	if v.Camera != [12]float64{} {
+		d["MS"] = name("M")
+		d["C2W"] = v.Camera[:]
+		d["CO"] = v.Orbit
+	}
+	if v.FieldOfView > 0 {
+		d["P"] = map[string]interface{}{"Subtype": name("P"), "FOV": v.FieldOfView}
+	} else {
+		d["P"] = map[string]interface{}{"Subtype": name("O")}
+	}
+	if v.Background != nil {
+		d["BG"] = map[string]interface{}{
+			"Type": name("3DBG"),
+			"C":    v.Background.components(),
+		}
+	}
+	if v.Lighting != "" {
+		d["LS"] = map[string]interface{}{
+			"Type":    name("3DLightingScheme"),
+			"Subtype": name(v.Lighting),
+		}
+	}
+	return d
+}
+
+// Model3D is a 3D model to be shown by Add3D.
+type Model3D struct {
+	Data   []byte // the model
+	Format string // Format3DU3D or Format3DPRC
+
+	Views []View3D // the first one is shown first
+
+	Activation     string   // Activate3DClick, Activate3DOpen or Activate3DVisible; Activate3DClick if empty
+	Toolbar        bool     // whether viewers show their 3D toolbar
+	NavigationPane bool     // whether viewers show the tree of the parts of the model
+	Poster         *XObject // shown while the annotation isn't activated, scaled to its size; blank if nil
+}
+
+// Add3D adds a 3D annotation showing model m to the current page, in the
+// rectangle with lower-left corner at (x, y), width w and height h.
+func (d *Document) Add3D(x, y, w, h float64, m *Model3D) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "3D annotation added before any page was started")
+	}
+	if len(m.Data) == 0 {
+		panic("3D model with no data")
+	}
+	switch {
+	case m.Format != Format3DU3D && m.Format != Format3DPRC:
+		panic("unknown format of 3D model: " + m.Format)
+	case d.version < "1.6":
+		panic("3D annotations need PDF 1.6")
+	case m.Format == Format3DPRC && d.version < "1.7":
+		panic("PRC models need PDF 1.7")
+	}
+	act := m.Activation
+	switch act {
+	case "":
+		act = Activate3DClick
+	case Activate3DClick, Activate3DOpen, Activate3DVisible:
+	default:
+		panic("unknown activation of 3D annotation: " + act)
+	}
+
+	dic := map[string]interface{}{
+		"Type":    name("3D"),
+		"Subtype": name(m.Format),
+	}
+	if len(m.Views) > 0 {
+		views := make([]interface{}, len(m.Views))
+		for i := range m.Views {
+			m.Views[i].check()
+			views[i] = &m.Views[i]
+		}
+		dic["VA"] = views
+		dic["DV"] = 0
+	}
+	model := d.indirect(&stream{dic, m.Data})
+
+	c := ""
+	var xo map[string]interface{}
+	if m.Poster != nil {
+		c = string(m.Poster.draw(new(ContentWriter), 0, 0, w, h).Bytes())
+		xo = map[string]interface{}{m.Poster.name(): m.Poster.ref}
+	}
+	a := map[string]interface{}{
+		"Subtype": name("3D"),
+		"Rect":    newRect(x, y, x+w, y+h),
+		"3DD":     model,
+		"3DA": map[string]interface{}{
+			"A":  name(act),
+			"TB": m.Toolbar,
+			"NP": m.NavigationPane,
+		},
+		"AP": map[string]interface{}{"N": d.appearance(w, h, c, "", xo)},
+		"F":  4, // Print flag of annotations
+	}
+	if len(m.Views) > 0 {
+		a["3DV"] = name("F")
+	}
+	d.addAnnot(a)
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func Test3D(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.BeginXObject(10, 10)
+	d.Rectangle(0, 0, 10, 10)
+	d.Fill()
+	poster, _ := d.EndXObject()
+	d.NewPage(300, 300)
+	m := &Model3D{
+		Data:   []byte("U3D\x00model"),
+		Format: Format3DU3D,
+		Views: []View3D{
+			{Name: "Front", Camera: [12]float64{1, 0, 0, 0, 0, -1, 0, 1, 0, 0, -100, 0}, Orbit: 100,
+				FieldOfView: 30, Background: &RGB{0.9, 0.9, 1}, Lighting: "CAD"},
+			{Name: "Top"},
+		},
+		Activation: Activate3DOpen,
+		Toolbar:    true,
+		Poster:     poster,
+	}
+	if err := d.Add3D(10, 10, 200, 150, m); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		m   Model3D
+		err string
+	}{
+		{Model3D{Format: Format3DU3D}, "pdf.go: 3D model with no data"},
+		{Model3D{Data: []byte("x"), Format: "OBJ"}, "pdf.go: unknown format of 3D model: OBJ"},
+		{Model3D{Data: []byte("x"), Format: Format3DPRC, Activation: "X"},
+			"pdf.go: unknown activation of 3D annotation: X"},
+		{Model3D{Data: []byte("x"), Format: Format3DPRC, Views: []View3D{{}}}, "pdf.go: 3D view with no name"},
+		{Model3D{Data: []byte("x"), Format: Format3DPRC, Views: []View3D{{Name: "a", Lighting: "Sun"}}},
+			"pdf.go: unknown lighting scheme: Sun"},
+	}
+	for _, tt := range tests {
+		if err := d.Add3D(0, 0, 10, 10, &tt.m); fmt.Sprint(err) != tt.err {
+			t.Errorf("got %v expected %q", err, tt.err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	annots := r.resolve(r.pages()[0].dic["Annots"]).([]interface{})
+	if len(annots) != 1 {
+		t.Fatalf("%d annotations", len(annots))
+	}
+	a := r.resolve(annots[0]).(map[string]interface{})
+	if a["Subtype"] != name("3D") || a["3DV"] != name("F") {
+		t.Errorf("annotation: got %v", a)
+	}
+	if act := r.resolve(a["3DA"]).(map[string]interface{}); act["A"] != name("PO") || act["TB"] != true {
+		t.Errorf("activation: got %v", act)
+	}
+	s := r.resolve(a["3DD"]).(*stream)
+	if string(r.decode(s)) != string(m.Data) || s.dic["Subtype"] != name("U3D") {
+		t.Errorf("model: got %v", s.dic)
+	}
+	views := r.resolve(s.dic["VA"]).([]interface{})
+	if len(views) != 2 {
+		t.Fatalf("%d views", len(views))
+	}
+	front := r.resolve(views[0]).(map[string]interface{})
+	if front["XN"] != "Front" || front["MS"] != name("M") || front["CO"] != 100 {
+		t.Errorf("front view: got %v", front)
+	}
+	if top := r.resolve(views[1]).(map[string]interface{}); top["MS"] != nil {
+		t.Errorf("top view has a camera: %v", top)
+	}
+	for _, want := range []string{"/Type /3DLightingScheme", "/FOV 30", "/Type /3DBG"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("no %q in the document", want)
+		}
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+
+	d, _ = New(new(bytes.Buffer), Options{Version: "1.5"})
+	d.NewPage(100, 100)
+	if err := d.Add3D(0, 0, 10, 10, &Model3D{Data: []byte("x"), Format: Format3DU3D}); fmt.Sprint(err) != "pdf.go: 3D annotations need PDF 1.6" {
+		t.Errorf("PDF 1.5: got %v", err)
+	}
+	d, _ = New(new(bytes.Buffer), Options{Version: "1.6"})
+	d.NewPage(100, 100)
+	if err := d.Add3D(0, 0, 10, 10, &Model3D{Data: []byte("x"), Format: Format3DPRC}); fmt.Sprint(err) != "pdf.go: PRC models need PDF 1.7" {
+		t.Errorf("PRC in PDF 1.6: got %v", err)
+	}
+}
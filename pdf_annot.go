@@ -0,0 +1,104 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the common parts of annotations for type Document.
+
+// Tab orders of annotations in a page, which is the order that the user moves
+// between fields with the tab key.
+const (
+	// TabDefault is the order that annotations are added to the page.
+	TabDefault = iota
+	// TabRow moves from top to bottom, and from left to right in each row.
+	TabRow
+	// TabColumn moves from left to right, and from top to bottom in each
+	// column.
+	TabColumn
+	// TabStructure is the order of the structure tree of the document.
+	TabStructure
+)
+
+// addAnnot writes the annotation dictionary a to the output and adds it to the
+// current page. The type and rectangle of the annotation are set by the
+// caller.
+func (d *Document) addAnnot(a map[string]interface{}) *indirect {
+	if d.pg == nil {
+		fail(ErrNoPage, "annotation added before any page was started")
+	}
+	i := d.reserveIndirect()
+	d.addAnnotAt(i, a)
+	return i
+}
+
+// addAnnotAt is like addAnnot, but writes the annotation as i, which is
+// reserved already, for annotations that refer to themselves.
+func (d *Document) addAnnotAt(i *indirect, a map[string]interface{}) {
+	if d.pg == nil {
+		fail(ErrNoPage, "annotation added before any page was started")
+	}
+	a["Type"] = name("Annot")
+	if len(d.objAF) > 0 {
+		a["AF"] = d.objAF
+	}
+	d.outputIndirect(i, d.extend(ExtendAnnot, a, nil))
+	d.pg.addAnnot(i, a["Rect"].(*rect))
+}
+
+// SetTabOrder sets the tab order of the current page to one of TabDefault,
+// TabRow, TabColumn and TabStructure.
+func (d *Document) SetTabOrder(o int) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "SetTabOrder called before any page was started")
+	}
+	if o < TabDefault || o > TabStructure {
+		panic("unknown tab order")
+	}
+	d.pg.tabs = o
+	return nil
+}
+
+// pointsRect returns the smallest rectangle that contains all the given
+// points, grown by pad on each side. pts holds x and y of the points one
+// after another.
+func pointsRect(pts []float64, pad float64) *rect {
+	if len(pts) < 2 {
+		panic("no points given")
+	}
+	r := newRect(pts[0], pts[1], pts[0], pts[1])
+	for i := 2; i+1 < len(pts); i += 2 {
+		x, y := pts[i], pts[i+1]
+		if x < r.llx {
+			r.llx = x
+		}
+		if x > r.urx {
+			r.urx = x
+		}
+		if y < r.lly {
+			r.lly = y
+		}
+		if y > r.ury {
+			r.ury = y
+		}
+	}
+	r.llx -= pad
+	r.lly -= pad
+	r.urx += pad
+	r.ury += pad
+	return r
+}
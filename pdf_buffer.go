@@ -0,0 +1,107 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file assembles documents in memory, so that they can be filled in
+// later like the ones written to outputs that can seek, even when their
+// output is a plain io.Writer.
+
+import (
+	"io"
+)
+
+// memFile is an io.WriteSeeker in memory. It's moved to a temporary file
+// when it grows larger than the memory limit of its document, if d is set.
+type memFile struct {
+	b   []byte
+	pos int
+	d   *Document
+	f   TempFile // temporary file, once it's moved to it
+}
+
+func (f *memFile) Write(b []byte) (int, error) {
+	if f.f != nil {
+		return f.f.Write(b)
+	}
+	if end := f.pos + len(b); end > len(f.b) {
+		f.b = append(f.b, make([]byte, end-len(f.b))...)
+	}
+	copy(f.b[f.pos:], b)
+	f.pos += len(b)
+	if f.d != nil && f.d.limit > 0 && len(f.b) > f.d.limit {
+		t, err := f.d.tempFile()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := t.Write(f.b); err != nil {
+			return 0, err
+		}
+		if _, err := t.Seek(int64(f.pos), io.SeekStart); err != nil {
+			return 0, err
+		}
+		f.f, f.b = t, nil
+	}
+	return len(b), nil
+}
+
+func (f *memFile) Seek(off int64, whence int) (int64, error) {
+	if f.f != nil {
+		return f.f.Seek(off, whence)
+	}
+	switch whence {
+	case io.SeekCurrent:
+		off += int64(f.pos)
+	case io.SeekEnd:
+		off += int64(len(f.b))
+	}
+	if off < 0 {
+		return 0, &Error{Kind: ErrInvalid, Msg: "seeking before the start of the document"}
+	}
+	f.pos = int(off)
+	return off, nil
+}
+
+// NewBuffered is like NewSeekable, but the document is assembled in memory
+// and written to w when it's closed. Nothing is written to w before that.
+func NewBuffered(w io.Writer, opts ...Options) (d *Document, err error) {
+	defer dontPanic(&err)
+
+	if w == nil {
+		panic("pdf.NewBuffered function was called with a nil parameter.")
+	}
+	d, err = NewSeekable(new(memFile), opts...)
+	check(err)
+	d.out = w
+	return d, nil
+}
+
+// emit writes the document assembled in memory to its output.
+func (d *Document) emit() {
+	if d.out == nil {
+		return
+	}
+	f := d.ws.(*memFile)
+	if f.f == nil {
+		_, err := d.out.Write(f.b)
+		checkWrite(err)
+		return
+	}
+	_, err := f.f.Seek(0, io.SeekStart)
+	checkWrite(err)
+	_, err = io.Copy(d.out, f.f)
+	checkWrite(err)
+}
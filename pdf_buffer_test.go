@@ -0,0 +1,103 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuffered(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, err := NewBuffered(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.AttachFile(&File{Name: "a.txt", Reader: strings.NewReader("data")})
+	for i := 0; i < 2; i++ {
+		d.NewPage(100, 100)
+		if err := d.DrawPageCount(10, 10, courier); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, _ := d.NewPage(100, 100)
+	p.Flush()
+	if buf.Len() > 0 {
+		t.Errorf("%d bytes written before Close", buf.Len())
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+	r, _ := NewReader(buf.Bytes())
+	for i := 1; i <= 2; i++ {
+		runs, _ := r.PageText(i)
+		if len(runs) != 1 || strings.TrimSpace(runs[0].Text) != "3" {
+			t.Errorf("page count on page %d: got %v", i, runs)
+		}
+	}
+}
+
+func TestBufferedMemoryLimit(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := NewBuffered(buf)
+	if err := d.SetMemoryLimit(16); err != nil {
+		t.Fatal(err)
+	}
+	// Fonts are written while the resources are locked, which moves the
+	// document to a temporary file.
+	done := make(chan error)
+	go func() {
+		d.NewPage(100, 100)
+		d.BeginText()
+		d.SetFont(FontHelvetica, 10)
+		d.ShowText("limited")
+		d.EndText()
+		done <- d.Close()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("document with a memory limit hangs")
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+}
+
+func TestMemFile(t *testing.T) {
+	f := new(memFile)
+	f.Write([]byte("hello world"))
+	f.Seek(6, 0)
+	f.Write([]byte("there!"))
+	f.Seek(-1, 2)
+	f.Write([]byte("?"))
+	if s := string(f.b); s != "hello there?" {
+		t.Errorf("got %q", s)
+	}
+	if _, err := f.Seek(-1, 0); err == nil {
+		t.Error("seeking before the start")
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains push buttons, which perform an action when clicked
+// (p. 681).
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Flag of button fields
+const buttonPush = 1 << 16
+
+// Button describes a push button. It shows a caption, an icon, or both, with
+// the caption below the icon. The background of buttons is light gray unless
+// it's set in the style.
+type Button struct {
+	Name    string   // name of the field, which should be unique
+	Caption string   // text of the button
+	Icon    *XObject // image of the button
+	Action  Action   // what happens when the button is clicked
+
+	FieldStyle
+}
+
+// buttonBackground is the default background color of buttons.
+const buttonBackground = Gray(0.75)
+
+// PushButton adds a push button with lower-left corner at (x, y), width w, and
+// height h to the current page.
+func (d *Document) PushButton(x, y, w, h float64, b *Button) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "field added before any page was started")
+	}
+	if b.Name == "" {
+		panic("button with no name")
+	}
+	size := b.size()
+	dict := map[string]interface{}{
+		"FT":   name("Btn"),
+		"T":    b.Name,
+		"Ff":   buttonPush,
+		"Rect": newRect(x, y, x+w, y+h),
+		"H":    name("P"),
+	}
+	b.set(dict, buttonBackground)
+	mk := dict["MK"].(map[string]interface{})
+	if b.Action != nil {
+		dict["A"] = b.Action
+	}
+
+	buf := bytes.NewBufferString(b.box(w, h, buttonBackground))
+	var xo map[string]interface{}
+
+	// The icon takes all the button except the space of the caption.
+	ih := h - 4
+	if b.Caption != "" {
+		mk["CA"] = textString(b.Caption)
+		ih -= size + 2
+	}
+	if b.Icon != nil {
+		mk["I"] = b.Icon.ref
+		// Scale the icon to fit its space while keeping its aspect
+		// ratio, and put it in the center.
+		iw, ihh := b.Icon.Size()
+		s := (w - 4) / iw
+		if ih/ihh < s {
+			s = ih / ihh
+		}
+		tx := (w - iw*s) / 2
+		ty := h - 2 - (ih+ihh*s)/2
+		buf.WriteString(fmt.Sprint("q ", ftoa(s), " 0 0 ", ftoa(s), " ",
+			ftoa(tx), " ", ftoa(ty), " cm /Icon Do Q\n"))
+		xo = map[string]interface{}{"Icon": b.Icon.ref}
+	}
+	switch {
+	case b.Icon != nil && b.Caption != "":
+		mk["TP"] = 2 // caption below the icon
+	case b.Icon != nil:
+		mk["TP"] = 1 // icon only
+	}
+	if b.Caption != "" {
+		s := d.encodeText(b.Caption)
+		ty := baseline(0, h, size)
+		if b.Icon != nil {
+			ty = baseline(2, size+2, size)
+		}
+		buf.WriteString(b.text(s, (w-b.width(s, size))/2, ty, size))
+	}
+
+	dict["AP"] = map[string]interface{}{
+		"N": d.appearance(w, h, buf.String(), b.font(), xo),
+	}
+	d.addField(dict)
+	return nil
+}
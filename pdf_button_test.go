@@ -0,0 +1,83 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPushButton(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, err := New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(300, 300)
+	if err = d.BeginXObject(10, 20); err != nil {
+		t.Fatal(err)
+	}
+	d.Rectangle(0, 0, 10, 20)
+	d.Fill()
+	if err = d.BeginXObject(10, 20); err == nil {
+		t.Errorf("nested BeginXObject: expected error")
+	}
+	icon, err := d.EndXObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.PushButton(10, 10, 80, 40, &Button{
+		Name:    "send",
+		Caption: "Send",
+		Icon:    icon,
+		Action:  &SubmitForm{URL: "http://example.com/"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"/Ff 65536",
+		"/CA (Send)",
+		"/TP 2",
+		"/Icon Do",
+		"/S /SubmitForm",
+		"0 0 10 20 re",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("document: %q not found", s)
+		}
+	}
+}
+
+func TestPushButtonUnicode(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, _ := New(buf)
+	d.NewPage(300, 300)
+	if err := d.PushButton(10, 10, 80, 40, &Button{Name: "zurück", Caption: "Zurück"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"zurück", "Zurück"} {
+		if b := output(textString(s)); !bytes.Contains(buf.Bytes(), b) {
+			t.Errorf("document: %q not found", b)
+		}
+	}
+}
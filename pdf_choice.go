@@ -0,0 +1,218 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains combo boxes and list boxes, which are called choice
+// fields in PDF (p. 687).
+
+import (
+	"bytes"
+)
+
+// Flags of choice fields
+const (
+	choiceCombo       = 1 << 17
+	choiceEdit        = 1 << 18
+	choiceSort        = 1 << 19
+	choiceMultiSelect = 1 << 21
+)
+
+// Option is an item of a combo box or list box. Value is what is exported when
+// the form is submitted and Text is what the user sees. Value is shown if
+// Text is empty.
+type Option struct {
+	Value string
+	Text  string
+}
+
+// text returns what is shown to the user for o.
+func (o Option) text() string {
+	if o.Text == "" {
+		return o.Value
+	}
+	return o.Text
+}
+
+func (o Option) object() interface{} {
+	if o.Text == "" || o.Text == o.Value {
+		return textString(o.Value)
+	}
+	return []string{textString(o.Value), textString(o.Text)}
+}
+
+// ChoiceField describes a combo box or a list box.
+type ChoiceField struct {
+	Name     string   // name of the field, which should be unique
+	Options  []Option // items of the list
+	Selected []string // values of the selected items
+
+	// Editable lets the user type a value which isn't in the list. It's
+	// only for combo boxes.
+	Editable bool
+	// MultiSelect lets the user select more than one item. It's only for
+	// list boxes.
+	MultiSelect bool
+	// Sort asks viewers to sort the items alphabetically for the user.
+	Sort bool
+
+	FieldStyle
+	FieldScripts
+}
+
+// index returns the index of the option with value v, or -1.
+func (f *ChoiceField) index(v string) int {
+	for i, o := range f.Options {
+		if o.Value == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// dict returns the field dictionary of f, without the widget annotation
+// entries.
+func (f *ChoiceField) dict(flags int) map[string]interface{} {
+	if f.Name == "" {
+		panic("choice field with no name")
+	}
+	if f.Sort {
+		flags |= choiceSort
+	}
+	d := map[string]interface{}{
+		"FT":  name("Ch"),
+		"T":   f.Name,
+		"Ff":  flags,
+		"Opt": f.Options,
+	}
+	switch len(f.Selected) {
+	case 0:
+	case 1:
+		d["V"] = textString(f.Selected[0])
+	default:
+		v := make([]string, len(f.Selected))
+		for i, s := range f.Selected {
+			v[i] = textString(s)
+		}
+		d["V"] = v
+	}
+	return d
+}
+
+// ComboBox adds a combo box with lower-left corner at (x, y), width w, and
+// height h to the current page.
+func (d *Document) ComboBox(x, y, w, h float64, f *ChoiceField) (err error) {
+	defer dontPanic(&err)
+
+	if f.MultiSelect {
+		panic("combo box can't have multiple selections")
+	}
+	if len(f.Selected) > 1 {
+		panic("combo box with more than one selected item")
+	}
+	flags := choiceCombo
+	if f.Editable {
+		flags |= choiceEdit
+	}
+	dict := f.dict(flags)
+
+	// The appearance shows the selected item.
+	lines := []string{}
+	if len(f.Selected) == 1 {
+		s := f.Selected[0]
+		if i := f.index(s); i >= 0 {
+			s = f.Options[i].text()
+		} else if !f.Editable {
+			panic("selected value of combo box is not in the list: " + s)
+		}
+		lines = append(lines, d.encodeText(s))
+	}
+	c := f.variableText(w, h, lines, false, Gray(1))
+
+	d.addChoice(x, y, w, h, f, dict, c)
+	return nil
+}
+
+// ListBox adds a list box with lower-left corner at (x, y), width w, and height
+// h to the current page.
+func (d *Document) ListBox(x, y, w, h float64, f *ChoiceField) (err error) {
+	defer dontPanic(&err)
+
+	if f.Editable {
+		panic("list box can't be editable")
+	}
+	if len(f.Selected) > 1 && !f.MultiSelect {
+		panic("list box with more than one selected item")
+	}
+	flags := 0
+	if f.MultiSelect {
+		flags |= choiceMultiSelect
+	}
+	dict := f.dict(flags)
+
+	// Indices of selected items are needed by viewers when more than one
+	// is selected, but are harmless otherwise.
+	sel := make(map[int]bool)
+	ind := make([]int, 0, len(f.Selected))
+	for i, o := range f.Options {
+		for _, s := range f.Selected {
+			if o.Value == s && !sel[i] {
+				sel[i] = true
+				ind = append(ind, i)
+			}
+		}
+	}
+	if len(ind) != len(f.Selected) {
+		panic("selected value of list box is not in the list")
+	}
+	if len(ind) > 0 {
+		dict["I"] = ind
+	}
+
+	// The appearance shows as many items as fit, with the selected ones
+	// highlighted.
+	size := f.size()
+	lh := size * 1.15
+	buf := bytes.NewBufferString(f.box(w, h, Gray(1)))
+	buf.WriteString(fieldClip(w, h))
+	for i, o := range f.Options {
+		top := h - 2 - float64(i)*lh
+		if top-lh < 0 {
+			break
+		}
+		if sel[i] {
+			buf.WriteString("0.6 0.75 0.85 rg 1 " + ftoa(top-lh) + " " +
+				ftoa(w-2) + " " + ftoa(lh) + " re f\n")
+		}
+		buf.WriteString(f.line(d.encodeText(o.text()), 2, baseline(top-lh, lh, size), w-4, size))
+	}
+	buf.WriteString("Q EMC\n")
+
+	d.addChoice(x, y, w, h, f, dict, buf.String())
+	return nil
+}
+
+// addChoice adds the dictionary dict of choice field f to the current page at
+// (x, y) with size w×h, and with appearance content c.
+func (d *Document) addChoice(x, y, w, h float64, f *ChoiceField, dict map[string]interface{}, c string) {
+	if d.pg == nil {
+		fail(ErrNoPage, "field added before any page was started")
+	}
+	dict["Rect"] = newRect(x, y, x+w, y+h)
+	f.set(dict, Gray(1))
+	dict["AP"] = map[string]interface{}{"N": d.appearance(w, h, c, f.font(), nil)}
+	d.addScripted(dict, &f.FieldScripts)
+}
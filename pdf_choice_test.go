@@ -0,0 +1,125 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChoiceFields(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, err := New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(300, 300)
+	opts := []Option{{"de", "Germany"}, {"fr", "France"}, {"ir", ""}}
+	if err = d.ComboBox(10, 10, 100, 20, &ChoiceField{
+		Name:     "country",
+		Options:  opts,
+		Selected: []string{"fr"},
+		Editable: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.ListBox(10, 50, 100, 60, &ChoiceField{
+		Name:        "visited",
+		Options:     opts,
+		Selected:    []string{"de", "ir"},
+		MultiSelect: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"/FT /Ch",
+		"/Ff 393216", // combo and edit
+		"/Ff 2097152",
+		"/Opt [ [ (de) (Germany) ] [ (fr) (France) ] (ir) ]",
+		"/V (fr)",
+		"/V [ (de) (ir) ]",
+		"/I [ 0 2 ]",
+		"(France) Tj",
+		"/AcroForm <<",
+		"/BaseFont /Helvetica",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("document: %q not found", s)
+		}
+	}
+}
+
+func TestChoiceFieldUnicode(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, _ := New(buf)
+	d.NewPage(300, 300)
+	if err := d.ComboBox(10, 10, 100, 20, &ChoiceField{
+		Name:     "länder",
+		Options:  []Option{{"at", "Österreich"}, {"dä", ""}},
+		Selected: []string{"dä"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if opt := output([]interface{}{"at", textString("Österreich")}); !bytes.Contains(buf.Bytes(), opt) {
+		t.Errorf("document: %q not found", opt)
+	}
+	r, _ := NewReader(buf.Bytes())
+	if values, err := r.FieldValues(); err != nil || values["länder"] != "dä" {
+		t.Errorf("values: got %v, %v", values, err)
+	}
+}
+
+type choiceErrorTest struct {
+	name  string
+	combo bool
+	f     *ChoiceField
+}
+
+func TestChoiceFieldErrors(t *testing.T) {
+	opts := []Option{{"a", ""}, {"b", ""}}
+	tests := []choiceErrorTest{
+		{"no name", true, &ChoiceField{Options: opts}},
+		{"multi-select combo", true,
+			&ChoiceField{Name: "c", Options: opts, MultiSelect: true}},
+		{"unknown value in combo", true,
+			&ChoiceField{Name: "c", Options: opts, Selected: []string{"x"}}},
+		{"editable list", false,
+			&ChoiceField{Name: "l", Options: opts, Editable: true}},
+		{"two selected in list", false,
+			&ChoiceField{Name: "l", Options: opts, Selected: []string{"a", "b"}}},
+	}
+
+	d, _ := New(new(bytes.Buffer))
+	d.NewPage(100, 100)
+	for _, test := range tests {
+		var err error
+		if test.combo {
+			err = d.ComboBox(0, 0, 10, 10, test.f)
+		} else {
+			err = d.ListBox(0, 0, 10, 10, test.f)
+		}
+		if err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file has components of documents like invoices and letters: the
+// letterhead, the address shown in the window of the envelope, and the
+// totals. They can be drawn in grids and added to flows, and their look is
+// set by themes.
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// mm is the number of points in a millimeter.
+const mm = 72 / 25.4
+
+// Theme is the look of components. Components with no theme use
+// DefaultTheme.
+type Theme struct {
+	Text   TextStyle // normal text
+	Small  TextStyle // less important text, like return addresses
+	Strong TextStyle // more important text, like names in letterheads and grand totals
+	Rule   Color     // color of lines; gray if nil
+}
+
+// DefaultTheme is the theme of components that have none.
+var DefaultTheme = &Theme{
+	Text:   TextStyle{FontSize: 10},
+	Small:  TextStyle{FontSize: 7, Color: Gray(0.3)},
+	Strong: TextStyle{FontSize: 14},
+}
+
+// theme returns t, or DefaultTheme if it's nil.
+func theme(t *Theme) *Theme {
+	if t == nil {
+		return DefaultTheme
+	}
+	return t
+}
+
+// rule returns content stream operators drawing a line from (x, y) to
+// (x+w, y) with the rule color of t.
+func (t *Theme) rule(x, y, w float64) string {
+	c := t.Rule
+	if c == nil {
+		c = Gray(0.5)
+	}
+	return fmt.Sprint("q ", colorOp(c, true), " 0.5 w ", ftoa(x), " ", ftoa(y), " m ",
+		ftoa(x+w), " ", ftoa(y), " l S Q")
+}
+
+// textLine returns content stream operators showing the UTF-8 string s with
+// style st in a line that is w wide, with its top left corner at (x, top).
+func textLine(d *Document, st *TextStyle, s string, x, top, w float64) string {
+	size, lh := st.size(), st.leading()
+	d.pageFont(st.font())
+	return strings.TrimRight(st.line(d.encodeText(s), x, baseline(top-lh, lh, size), w, size), "\n")
+}
+
+// canDraw panics if d has no page to draw component c on.
+func (d *Document) canDraw(c string) {
+	if d.pg == nil {
+		fail(ErrNoPage, c+" drawn before any page was started")
+	}
+	if d.xbox != nil {
+		panic(c + " drawn inside an XObject")
+	}
+	d.outsideText(c)
+}
+
+// drawablePieces returns a piece of a flow that draws c in the width of the
+// flow, below floating pictures.
+func drawablePieces(c Drawable, fr *frame) []piece {
+	w, h := c.Size(fr.w, fr.h)
+	return []piece{{h: h, clear: true, draw: func(d *Document, x, y float64) {
+		check(c.Draw(d, x, y-h, w, h))
+	}}}
+}
+
+// Letterhead is the top of the first page of letters and invoices: a logo at
+// the left, and the name and the contact details of the sender at the right,
+// above a line.
+type Letterhead struct {
+	Logo       *XObject // no logo if nil
+	LogoHeight float64  // as high as the text if zero
+	Name       string
+	Lines      []string // like the address, the phone number and the web site
+	Theme      *Theme
+}
+
+// textHeight returns the height of the text of l.
+func (l *Letterhead) textHeight() float64 {
+	t := theme(l.Theme)
+	return t.Strong.leading() + float64(len(l.Lines))*t.Text.leading()
+}
+
+// logoSize returns the size of the logo of l.
+func (l *Letterhead) logoSize() (float64, float64) {
+	if l.Logo == nil {
+		return 0, 0
+	}
+	xw, xh := l.Logo.Size()
+	h := l.LogoHeight
+	if h <= 0 {
+		h = l.textHeight()
+	}
+	return xw * h / xh, h
+}
+
+// Size returns the width w, and the height of the logo or the text of l,
+// whichever is higher, and the line below them.
+func (l *Letterhead) Size(w, h float64) (float64, float64) {
+	_, lh := l.logoSize()
+	return w, math.Max(lh, l.textHeight()) + 6
+}
+
+// Draw draws l in the box of size w×h with the lower left corner at (x, y).
+func (l *Letterhead) Draw(d *Document, x, y, w, h float64) (err error) {
+	defer dontPanic(&err)
+
+	d.canDraw("letterhead")
+	t := theme(l.Theme)
+	top := y + h
+	if l.Logo != nil {
+		lw, lh := l.logoSize()
+		check(d.DrawXObject(l.Logo, x, top-lh, lw, lh))
+	}
+	right := t.Text
+	right.Align = AlignRight
+	strong := t.Strong
+	strong.Align = AlignRight
+	d.addc(textLine(d, &strong, l.Name, x, top, w))
+	top -= strong.leading()
+	for _, s := range l.Lines {
+		d.addc(textLine(d, &right, s, x, top, w))
+		top -= right.leading()
+	}
+	d.addc(t.rule(x, y+1, w))
+	return nil
+}
+
+func (l *Letterhead) pieces(fr *frame) []piece {
+	return drawablePieces(l, fr)
+}
+
+// Window is where the address shows through the window of an envelope, on a
+// letter folded into it. Left and Top are the distances from the left and
+// the top edges of the page to the window.
+type Window struct {
+	Left, Top, Width, Height float64
+}
+
+// Windows of envelopes
+var (
+	// A4 letters by DIN 5008, with the address field 45 mm from the top
+	// (form B) or 27 mm (form A).
+	WindowDIN5008B = Window{20 * mm, 45 * mm, 85 * mm, 45 * mm}
+	WindowDIN5008A = Window{20 * mm, 27 * mm, 85 * mm, 45 * mm}
+	// US letters folded in three, in #10 window envelopes.
+	WindowUS10 = Window{0.875 * 72, 2 * 72, 4.5 * 72, 1.125 * 72}
+)
+
+// Address is the address of the recipient of a letter, with the return
+// address of the sender above it in small text.
+type Address struct {
+	Return string   // return address in one line; none if empty
+	Lines  []string // lines of the address of the recipient
+	Theme  *Theme
+}
+
+// Size returns the width w and the height of the lines of a.
+func (a *Address) Size(w, h float64) (float64, float64) {
+	t := theme(a.Theme)
+	ah := float64(len(a.Lines)) * t.Text.leading()
+	if a.Return != "" {
+		ah += t.Small.leading() + 2
+	}
+	return w, ah
+}
+
+// Draw draws a in the box of size w×h with the lower left corner at (x, y),
+// starting from the top.
+func (a *Address) Draw(d *Document, x, y, w, h float64) (err error) {
+	defer dontPanic(&err)
+
+	d.canDraw("address")
+	t := theme(a.Theme)
+	top := y + h
+	if a.Return != "" {
+		d.addc(textLine(d, &t.Small, a.Return, x, top, w))
+		top -= t.Small.leading()
+		d.addc(t.rule(x, top+0.5, t.Small.width(d.encodeText(a.Return), t.Small.size())))
+		top -= 2
+	}
+	for _, s := range a.Lines {
+		d.addc(textLine(d, &t.Text, s, x, top, w))
+		top -= t.Text.leading()
+	}
+	return nil
+}
+
+// DrawAddress draws a in window w of the current page, which is measured
+// from its top left corner.
+func (d *Document) DrawAddress(a *Address, w Window) (err error) {
+	defer dontPanic(&err)
+
+	d.canDraw("address")
+	_, h := a.Size(w.Width, w.Height)
+	if h > w.Height {
+		panic("address doesn't fit in the window")
+	}
+	top := d.pg.box.ury - w.Top
+	check(a.Draw(d, w.Left, top-h, w.Width, h))
+	return nil
+}
+
+// Totals is a list of amounts with their labels at the bottom of invoices,
+// like the subtotal, the tax and the total. It's at the right of the room
+// it's drawn in, with the labels aligned to the right of each other, and the
+// amounts too.
+type Totals struct {
+	Rows  []TotalRow
+	Theme *Theme
+}
+
+// TotalRow is a row of totals.
+type TotalRow struct {
+	Label, Amount string
+	Strong        bool // strong rows, like the grand total, have a line above them
+}
+
+// style returns the style of the text of r in theme t.
+func (r *TotalRow) style(t *Theme) TextStyle {
+	if r.Strong {
+		return t.Strong
+	}
+	return t.Text
+}
+
+// Size returns the width w and the height of the rows of s.
+func (s *Totals) Size(w, h float64) (float64, float64) {
+	t := theme(s.Theme)
+	th := 0.0
+	for _, r := range s.Rows {
+		st := r.style(t)
+		th += st.leading()
+		if r.Strong {
+			th += 3
+		}
+	}
+	return w, th
+}
+
+// Draw draws s in the box of size w×h with the lower left corner at (x, y),
+// starting from the top.
+func (s *Totals) Draw(d *Document, x, y, w, h float64) (err error) {
+	defer dontPanic(&err)
+
+	d.canDraw("totals")
+	t := theme(s.Theme)
+	// Amounts are in a column as wide as the widest of them, and labels
+	// are in another column as wide, at its left.
+	aw, lw := 0.0, 0.0
+	for _, r := range s.Rows {
+		st := r.style(t)
+		aw = math.Max(aw, st.width(d.encodeText(r.Amount), st.size()))
+		lw = math.Max(lw, st.width(d.encodeText(r.Label), st.size()))
+	}
+	gap := 2 * t.Text.size()
+	if lw+gap+aw > w {
+		panic("totals are wider than their room")
+	}
+	top := y + h
+	for _, r := range s.Rows {
+		st := r.style(t)
+		st.Align = AlignRight
+		if r.Strong {
+			d.addc(t.rule(x+w-aw-gap-lw, top-1, lw+gap+aw))
+			top -= 3
+		}
+		d.addc(textLine(d, &st, r.Label, x+w-aw-gap-lw, top, lw))
+		d.addc(textLine(d, &st, r.Amount, x+w-aw, top, aw))
+		top -= st.leading()
+	}
+	return nil
+}
+
+func (s *Totals) pieces(fr *frame) []piece {
+	return drawablePieces(s, fr)
+}
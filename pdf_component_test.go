@@ -0,0 +1,84 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestComponents(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.BeginXObject(100, 50)
+	x, _ := d.EndXObject()
+	f, _ := d.NewFlow(595, 842, Margins{40, 40, 40, 40})
+	for i, b := range []Block{
+		&Letterhead{Logo: x, Name: "ACME", Lines: []string{"1 Main St", "acme.com"}},
+		Spacer(300),
+		&Totals{Rows: []TotalRow{{"Subtotal", "90.00", false}, {"Tax", "10.00", false}, {"Total", "100.00", true}}},
+	} {
+		if err := f.Add(b); err != nil {
+			t.Fatalf("%d. %s", i, err)
+		}
+	}
+	a := &Address{Return: "ACME, 1 Main St", Lines: []string{"Jo Doe", "2 Side St"}}
+	if err := d.DrawAddress(a, WindowDIN5008B); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, _ := r.PageText(1)
+	var got []string
+	for _, run := range runs {
+		got = append(got, fmt.Sprint(ftoa(run.X), " ", ftoa(run.Y), " ", run.Text))
+	}
+	want := "514.554 790.03 ACME|512.76 777.35 1 Main St|508.88 765.85 acme.com|" +
+		"455.498 448.35 Subtotal|529.98 448.35 90.00|475.518 436.85 Tax|529.98 436.85 10.00|" +
+		"461.066 418.93 Total|512.188 418.93 100.00|" +
+		"56.693 708.456 ACME, 1 Main St|56.693 695.841 Jo Doe|56.693 684.341 2 Side St"
+	if g := strings.Join(got, "|"); g != want {
+		t.Errorf("text: got %q, want %q", g, want)
+	}
+	// The logo is as high as the text.
+	if s := "q 0.782 0 0 0.782 40 762.9 cm /Fm"; !bytes.Contains(buf.Bytes(), []byte(s)) {
+		t.Errorf("%q not found in the output", s)
+	}
+}
+
+func TestComponentErrors(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	a := &Address{Lines: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"}}
+	if err := d.DrawAddress(a, WindowDIN5008A); fmt.Sprint(err) != "pdf.go: address drawn before any page was started" {
+		t.Errorf("no page: got error %v", err)
+	}
+	d.NewPage(595, 842)
+	if err := d.DrawAddress(a, WindowDIN5008A); fmt.Sprint(err) != "pdf.go: address doesn't fit in the window" {
+		t.Errorf("long address: got error %v", err)
+	}
+	s := &Totals{Rows: []TotalRow{{Label: "A long label", Amount: "1"}}}
+	if err := s.Draw(d, 0, 0, 50, 20); fmt.Sprint(err) != "pdf.go: totals are wider than their room" {
+		t.Errorf("wide totals: got error %v", err)
+	}
+}
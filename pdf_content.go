@@ -0,0 +1,172 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains contents, which hold the content of pages while it's
+// made apart from the document, so that many pages can be made at once.
+
+// Content holds drawings and text to be added to a page with AddContent. It
+// doesn't need the document, so the contents of different pages can be made
+// by different goroutines at the same time. The zero value is an empty
+// content ready to use.
+type Content struct {
+	w     ContentWriter
+	fonts map[string]bool // fonts used by the text, by name
+	res   resources       // other resources used, like XObjects
+
+	missing []rune // characters of the text that its fonts don't have
+}
+
+// add adds s to the content stream of c.
+func (c *Content) add(s string) {
+	c.w.Write([]byte(s + "\n"))
+}
+
+// Writer returns the writer of the operators of c, for graphics that have no
+// method of Content.
+func (c *Content) Writer() *ContentWriter {
+	return &c.w
+}
+
+// LineWidth changes the width of the lines to be drawn after it.
+func (c *Content) LineWidth(w int) {
+	c.w.LineWidth(float64(w))
+}
+
+// LineCapStyle changes line cap style, like the LineCapStyle method of
+// Document.
+func (c *Content) LineCapStyle(s int) {
+	c.w.LineCapStyle(s)
+}
+
+// LineJoinStyle changes line join style, like the LineJoinStyle method of
+// Document.
+func (c *Content) LineJoinStyle(s int) {
+	c.w.LineJoinStyle(s)
+}
+
+// MoveTo starts a new path at the given point.
+func (c *Content) MoveTo(x, y int) {
+	c.w.MoveTo(float64(x), float64(y))
+}
+
+// LineTo draws a single line from current the given point.
+func (c *Content) LineTo(x, y int) {
+	c.w.LineTo(float64(x), float64(y))
+}
+
+// Curve draws a bézier curve from current point to point (x2, y2) using
+// (x0, y0) and (x1, y1) as control points.
+func (c *Content) Curve(x0, y0, x1, y1, x2, y2 int) {
+	c.w.Curve(float64(x0), float64(y0), float64(x1), float64(y1),
+		float64(x2), float64(y2))
+}
+
+// CurveV draws a bézier curve from current point to point (x1, y1) using
+// current point and (x0, y0) as control points.
+func (c *Content) CurveV(x0, y0, x1, y1 int) {
+	c.w.CurveV(float64(x0), float64(y0), float64(x1), float64(y1))
+}
+
+// CurveY draws a bézier curve from current point to point (x1, y1) using
+// (x0, y0) and current point as control points.
+func (c *Content) CurveY(x0, y0, x1, y1 int) {
+	c.w.CurveY(float64(x0), float64(y0), float64(x1), float64(y1))
+}
+
+// Rectangle draws a rectangle with lower-left corner at (x, y).
+func (c *Content) Rectangle(x, y, w, h int) {
+	c.w.Rectangle(float64(x), float64(y), float64(w), float64(h))
+}
+
+// ClosePath closes the current active path by drawing a straight line from
+// current point to the beginning of the path.
+func (c *Content) ClosePath() {
+	c.w.ClosePath()
+}
+
+// Stroke paints the current path with stroke.
+func (c *Content) Stroke() {
+	c.w.Stroke()
+}
+
+// Fill paints inside of the current path.
+func (c *Content) Fill() {
+	c.w.Fill()
+}
+
+// Text shows the UTF-8 string s with style st, with the baseline starting at
+// (x, y).
+func (c *Content) Text(s string, x, y float64, st TextStyle) (err error) {
+	defer dontPanic(&err)
+
+	n := st.font()
+	t := winAnsi(s)
+	c.missing = append(c.missing, unencodable(s)...)
+	c.w.b = append(st.appendText(c.w.b, t, x, y, st.size(), c.w.precision()), '\n')
+	c.w.t.text(t, x, y, st.size(), &st)
+	if c.fonts == nil {
+		c.fonts = make(map[string]bool)
+	}
+	c.fonts[n] = true
+	return nil
+}
+
+// DrawXObject draws x scaled to width w and height h, with its lower-left
+// corner at (px, py). Images can be added with AddImage by many goroutines at
+// the same time, and drawn on the contents of all of them.
+func (c *Content) DrawXObject(x *XObject, px, py, w, h float64) {
+	c.res.add("XObject", x.name(), x.ref)
+	x.draw(&c.w, px, py, w, h)
+	c.w.t.paint(px, py, w, h)
+}
+
+// AddContent adds c to the end of the content of p. Different goroutines can
+// add contents to different pages at the same time, as long as the document
+// isn't used otherwise by them; a page can be added to by only one goroutine
+// at a time, and the document should be closed after all of them are done.
+func (p *Page) AddContent(c *Content) (err error) {
+	defer dontPanic(&err)
+
+	if p.pg.flushed {
+		panic("page is already flushed")
+	}
+	p.d.noGlyphs(c.missing)
+	for n := range c.fonts {
+		p.pg.res.add("Font", n, p.d.fieldFont(n))
+	}
+	p.pg.res.merge(c.res)
+	p.write(c.w.Bytes())
+	p.tracker().append(&c.w.t)
+	return nil
+}
+
+// write adds b to the end of the content of p.
+func (p *Page) write(b []byte) {
+	// The content of the current page is kept by the document.
+	buf := &p.pg.buf
+	if p.d.pg == p.pg {
+		buf = &p.d.con
+		p.d.stateChanged()
+	}
+	if *buf == nil {
+		*buf = p.d.newSpill()
+	}
+	p.d.traceContent(*buf)
+	_, err := (*buf).Write(b)
+	checkWrite(err)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestContent(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	pgs := make([]*Page, 4)
+	for i := range pgs {
+		pgs[i], _ = d.NewPage(100, 100)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(pgs))
+	for i, p := range pgs {
+		wg.Add(1)
+		go func(i int, p *Page) {
+			defer wg.Done()
+			x, err := d.AddImage(image.NewGray(image.Rect(0, 0, 2, 2)))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			c := new(Content)
+			c.Rectangle(i, i, 1, 1)
+			c.Fill()
+			c.Text(fmt.Sprint("page ", i+1), 10, 10, TextStyle{Font: FontCourier})
+			c.DrawXObject(x, 0, 0, 2, 2)
+			errs[i] = p.AddContent(c)
+		}(i, p)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("page %d: %v", i+1, err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpgs := r.pages()
+	if len(rpgs) != len(pgs) {
+		t.Fatalf("pages: got %d expected %d", len(rpgs), len(pgs))
+	}
+	for i, pg := range rpgs {
+		con := string(r.contents(pg.dic["Contents"]))
+		for _, s := range []string{fmt.Sprint(i, i, " 1 1 re"), fmt.Sprint("(page ", i+1, ") Tj"), " Do Q"} {
+			if !strings.Contains(con, s) {
+				t.Errorf("content of page %d: %q doesn't have %q", i+1, con, s)
+			}
+		}
+		res := r.resolve(pg.dic["Resources"]).(map[string]interface{})
+		if fonts := r.resolve(res["Font"]).(map[string]interface{}); fonts["Cour"] == nil {
+			t.Errorf("fonts of page %d: got %v", i+1, fonts)
+		}
+		if xobjs := r.resolve(res["XObject"]).(map[string]interface{}); len(xobjs) != 1 {
+			t.Errorf("XObjects of page %d: got %v", i+1, xobjs)
+		}
+	}
+}
+
+func TestAddContentFlushed(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	p, _ := d.NewPage(100, 100)
+	p.Flush()
+	c := new(Content)
+	if err := c.Text("x", 0, 0, TextStyle{Font: "Times"}); fmt.Sprint(err) != "pdf.go: unknown font: Times" {
+		t.Errorf("Text with unknown font: got %v", err)
+	}
+	if err := p.AddContent(c); fmt.Sprint(err) != "pdf.go: page is already flushed" {
+		t.Errorf("AddContent after Flush: got %v", err)
+	}
+}
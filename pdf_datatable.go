@@ -0,0 +1,141 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file makes tables from data, like records of CSV files and slices of
+// structs, to show them with little work.
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// dataTable returns an empty table with the style of tables of data, which
+// have borders and a header row.
+func dataTable() *Table {
+	return &Table{
+		Padding:    3,
+		Border:     Gray(0.5),
+		HeaderRows: 1,
+		Style:      TextStyle{FontSize: 10},
+	}
+}
+
+// headerBackground is the background of the header rows of tables of data.
+var headerBackground = Gray(0.9)
+
+// TableOfStrings returns a table with a row for each of rows. The first
+// row is the header row, which has a gray background and is repeated on
+// every page. The table has borders, and its text is Helvetica of size 10.
+func TableOfStrings(rows [][]string) *Table {
+	t := dataTable()
+	for i, row := range rows {
+		cells := make([]*Cell, len(row))
+		for j, s := range row {
+			cells[j] = &Cell{Text: s}
+			if i == 0 {
+				cells[j].Background = headerBackground
+			}
+		}
+		t.AddRow(cells...)
+	}
+	return t
+}
+
+// TableOfCSV returns a table with the records of the CSV file read from r,
+// like TableOfStrings. The first record is the header.
+func TableOfCSV(r io.Reader) (t *Table, err error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return TableOfStrings(rows), nil
+}
+
+// TableOfStructs returns a table with a row for each element of the slice
+// of structs, or pointers to structs, v. It has a column for each exported
+// field, which is shown with package fmt, with the name of the field in the
+// header row. A field with tag pdf:"Title" has Title in the header row
+// instead, and fields tagged pdf:"-" are left out. Numbers are aligned to
+// the right. The table looks like the ones made by TableOfStrings.
+func TableOfStructs(v interface{}) (t *Table, err error) {
+	defer dontPanic(&err)
+
+	s := reflect.ValueOf(v)
+	if s.Kind() == reflect.Ptr {
+		s = s.Elem()
+	}
+	if s.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("table of %T which is not a slice", v))
+	}
+	et := s.Type().Elem()
+	if et.Kind() == reflect.Ptr {
+		et = et.Elem()
+	}
+	if et.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("table of %T which is not a slice of structs", v))
+	}
+
+	t = dataTable()
+	right := t.Style
+	right.Align = AlignRight
+	var fields []int
+	var styles []*TextStyle
+	var head []*Cell
+	for i := 0; i < et.NumField(); i++ {
+		f := et.Field(i)
+		title := f.Tag.Get("pdf")
+		if f.PkgPath != "" || title == "-" {
+			continue
+		}
+		if title == "" {
+			title = f.Name
+		}
+		var style *TextStyle
+		switch f.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			style = &right
+		}
+		fields = append(fields, i)
+		styles = append(styles, style)
+		head = append(head, &Cell{Text: title, Background: headerBackground, Style: style})
+	}
+	if len(fields) == 0 {
+		panic(fmt.Sprintf("table of %T with no exported fields", v))
+	}
+	t.AddRow(head...)
+	for i := 0; i < s.Len(); i++ {
+		// Nil pointers have a row of empty cells.
+		e := s.Index(i)
+		if e.Kind() == reflect.Ptr {
+			e = e.Elem()
+		}
+		row := make([]*Cell, len(fields))
+		for j, f := range fields {
+			row[j] = &Cell{Style: styles[j]}
+			if e.IsValid() {
+				row[j].Text = fmt.Sprint(e.Field(f).Interface())
+			}
+		}
+		t.AddRow(row...)
+	}
+	return t, nil
+}
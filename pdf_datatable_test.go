@@ -0,0 +1,85 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// tableText returns the texts of the cells of t, with cells separated by
+// commas and rows by bars. Cells aligned to the right end in ">".
+func tableText(t *Table) string {
+	var rows []string
+	for _, row := range t.rows {
+		var cells []string
+		for _, c := range row {
+			s := c.Text
+			if c.Style != nil && c.Style.Align == AlignRight {
+				s += ">"
+			}
+			cells = append(cells, s)
+		}
+		rows = append(rows, strings.Join(cells, ","))
+	}
+	return strings.Join(rows, "|")
+}
+
+type item struct {
+	Name   string
+	Price  float64 `pdf:"Price (€)"`
+	Count  int
+	Secret string `pdf:"-"`
+	note   string
+}
+
+func TestTableOfStructs(t *testing.T) {
+	items := []*item{{"pen", 1.5, 10, "x", "y"}, nil, {Name: "ink", Count: 2}}
+	tb, err := TableOfStructs(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Name,Price (€)>,Count>|pen,1.5>,10>|,>,>|ink,0>,2>"
+	if g := tableText(tb); g != want {
+		t.Errorf("got %q, want %q", g, want)
+	}
+	if tb.HeaderRows != 1 || tb.rows[0][0].Background == nil {
+		t.Error("no header row")
+	}
+	for _, v := range []interface{}{5, []int{1}, []struct{ a int }{}} {
+		if _, err := TableOfStructs(v); err == nil {
+			t.Errorf("no error for table of %T", v)
+		}
+	}
+}
+
+func TestTableOfCSV(t *testing.T) {
+	tb, err := TableOfCSV(strings.NewReader("a,b\n1,\"2,3\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := tableText(tb); g != "a,b|1,2,3" {
+		t.Errorf("got %q", g)
+	}
+	if _, err := TableOfCSV(strings.NewReader("a,\"b\n")); err == nil {
+		t.Error("no error for bad CSV")
+	}
+	if s := fmt.Sprint(tb.rows[1][0].Background); s != "<nil>" {
+		t.Errorf("background of body: got %s", s)
+	}
+}
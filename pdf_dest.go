@@ -0,0 +1,202 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file has destinations, which are places in the document shown by
+// viewers when links, bookmarks or actions go to them, with how the page is
+// fitted in the window.
+
+import (
+	"fmt"
+	"math"
+)
+
+// How pages are fitted in the window by destinations
+const (
+	DestXYZ  = "XYZ"  // at Left and Top, with Zoom
+	DestFit  = "Fit"  // the whole page
+	DestFitH = "FitH" // the width of the page, with Top at the top of the window
+	DestFitV = "FitV" // the height of the page, with Left at the left of the window
+	DestFitR = "FitR" // the rectangle from Left, Bottom to Right, Top
+	// The same as DestFit, DestFitH and DestFitV, but fitting the bounding
+	// box of the content of the page instead of the whole page.
+	DestFitB  = "FitB"
+	DestFitBH = "FitBH"
+	DestFitBV = "FitBV"
+)
+
+// Keep, as a coordinate or the zoom of a destination, keeps the one the
+// viewer has.
+var Keep = math.NaN()
+
+// Destination is a place in the document to go to. It's used by links,
+// bookmarks, named destinations and the action of opening the document, and
+// is an Action itself.
+type Destination struct {
+	Page *Page
+	Fit  string // one of DestXYZ, DestFit, ...; DestXYZ if empty
+
+	// Coordinates used by Fit; Left and Top for DestXYZ, Top for DestFitH
+	// and DestFitBH, Left for DestFitV and DestFitBV, and all of them for
+	// DestFitR.
+	Left, Bottom, Right, Top float64
+	// Zoom of DestXYZ, like 1 for 100%; the zoom of the viewer is kept if
+	// it's zero.
+	Zoom float64
+}
+
+// array returns the destination array of dst.
+func (dst *Destination) array() []interface{} {
+	if dst.Page == nil {
+		panic("destination with no page")
+	}
+	// Keep is written as null.
+	n := func(f float64) interface{} {
+		if math.IsNaN(f) {
+			return nil
+		}
+		return f
+	}
+	a := []interface{}{dst.Page.pg.ref}
+	switch dst.Fit {
+	case DestXYZ, "":
+		z := n(dst.Zoom)
+		if dst.Zoom == 0 {
+			z = nil
+		}
+		a = append(a, name(DestXYZ), n(dst.Left), n(dst.Top), z)
+	case DestFit, DestFitB:
+		a = append(a, name(dst.Fit))
+	case DestFitH, DestFitBH:
+		a = append(a, name(dst.Fit), n(dst.Top))
+	case DestFitV, DestFitBV:
+		a = append(a, name(dst.Fit), n(dst.Left))
+	case DestFitR:
+		if dst.Left >= dst.Right || dst.Bottom >= dst.Top {
+			panic(fmt.Sprint("empty rectangle of destination: ", dst.Left, " ", dst.Bottom, " ", dst.Right, " ", dst.Top))
+		}
+		a = append(a, name(DestFitR), dst.Left, dst.Bottom, dst.Right, dst.Top)
+	default:
+		panic("unknown fit of destination: " + dst.Fit)
+	}
+	return a
+}
+
+func (dst *Destination) object() interface{} {
+	return actionDict("GoTo", map[string]interface{}{"D": dst.array()})
+}
+
+// dest returns the destination array of dst, checking that it's a place
+// in d.
+func (d *Document) dest(dst *Destination) []interface{} {
+	if dst == nil {
+		panic("nil destination")
+	}
+	a := dst.array()
+	if dst.Page.d != d {
+		panic("destination in another document")
+	}
+	return a
+}
+
+// NamedDestination is an action that goes to the destination with its name,
+// added by AddDestination.
+type NamedDestination string
+
+func (n NamedDestination) object() interface{} {
+	return actionDict("GoTo", map[string]interface{}{"D": string(n)})
+}
+
+// AddDestination adds dst to the document with the given name, so that it
+// can be gone to by NamedDestination, or by other documents and URLs like
+// "doc.pdf#nameddest=results".
+func (d *Document) AddDestination(n string, dst *Destination) (err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	if n == "" {
+		fail(ErrBadName, "destination with no name")
+	}
+	if _, ok := d.dests[n]; ok {
+		fail(ErrBadName, "two destinations with the same name: "+n)
+	}
+	a := d.dest(dst)
+	if d.dests == nil {
+		d.dests = make(map[string]interface{})
+	}
+	d.dests[n] = a
+	return nil
+}
+
+// AddLink adds a link to the rectangle of size w×h with the lower left
+// corner at (x, y) on the current page, which does a when it's clicked. a is
+// usually a *Destination, a NamedDestination or a URI.
+func (d *Document) AddLink(x, y, w, h float64, a Action) (err error) {
+	defer dontPanic(&err)
+
+	if a == nil {
+		panic("link with no action")
+	}
+	l := map[string]interface{}{
+		"Subtype": name("Link"),
+		"Rect":    newRect(x, y, x+w, y+h),
+		"Border":  []int{0, 0, 0},
+		"F":       4, // Print, which PDF/A needs
+	}
+	switch a := a.(type) {
+	case *Destination:
+		l["Dest"] = d.dest(a)
+	case NamedDestination:
+		l["Dest"] = string(a)
+	default:
+		l["A"] = a.object()
+	}
+	d.addAnnot(l)
+	return nil
+}
+
+// AddBookmark adds a bookmark with the given title and level, from 1 for the
+// top level, that goes to dst. Bookmarks are mixed with the headings of the
+// document in the order they're added, but they aren't in the table of
+// contents.
+func (d *Document) AddBookmark(title string, level int, dst *Destination) (err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	if level < 1 {
+		panic(fmt.Sprintf("bad level of bookmark: %d", level))
+	}
+	d.headings = append(d.headings, &heading{text: title, level: level, to: d.dest(dst)})
+	return nil
+}
+
+// SetOpenAction sets what the viewer does when the document is opened,
+// usually going to a *Destination.
+func (d *Document) SetOpenAction(a Action) (err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	switch a := a.(type) {
+	case nil:
+		d.openAction = nil
+	case *Destination:
+		d.openAction = d.dest(a)
+	default:
+		d.openAction = a.object()
+	}
+	return nil
+}
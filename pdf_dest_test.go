@@ -0,0 +1,124 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDestination(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf, Options{Deterministic: true})
+	p1, _ := d.NewPage(400, 300)
+	p2, _ := d.NewPage(400, 300)
+
+	tests := []struct {
+		dst  *Destination
+		want string
+	}{
+		{&Destination{Page: p1}, "[XYZ 0 0 <nil>]"},
+		{&Destination{Page: p1, Left: 10, Top: Keep, Zoom: 2}, "[XYZ 10 <nil> 2]"},
+		{&Destination{Page: p1, Fit: DestFit}, "[Fit]"},
+		{&Destination{Page: p1, Fit: DestFitBH, Top: 200}, "[FitBH 200]"},
+		{&Destination{Page: p1, Fit: DestFitV, Left: Keep}, "[FitV <nil>]"},
+		{&Destination{Page: p1, Fit: DestFitR, Left: 10, Bottom: 20, Right: 30, Top: 40}, "[FitR 10 20 30 40]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(d.dest(tt.dst)[1:]); got != tt.want {
+			t.Errorf("destination %+v: got %v, want %v", *tt.dst, got, tt.want)
+		}
+	}
+	bad := []*Destination{
+		nil,
+		{},
+		{Page: p1, Fit: "Zoom"},
+		{Page: p1, Fit: DestFitR, Left: 10, Right: 10, Top: 40},
+	}
+	for _, dst := range bad {
+		if err := d.AddDestination("bad", dst); !errors.Is(err, ErrInvalid) {
+			t.Errorf("bad destination %v: got %v expected ErrInvalid", dst, err)
+		}
+	}
+	other, _ := New(bytes.NewBuffer(nil))
+	op, _ := other.NewPage(100, 100)
+	if err := d.AddLink(0, 0, 10, 10, &Destination{Page: op}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("destination in another document: got %v expected ErrInvalid", err)
+	}
+
+	if err := d.AddDestination("results", &Destination{Page: p2, Fit: DestFitH, Top: 250}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddDestination("results", &Destination{Page: p1}); !errors.Is(err, ErrBadName) {
+		t.Errorf("two destinations with the same name: got %v expected ErrBadName", err)
+	}
+	if err := d.AddLink(10, 10, 100, 20, NamedDestination("results")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddLink(10, 40, 100, 20, &Destination{Page: p1, Fit: DestFit}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddBookmark("Results", 1, &Destination{Page: p2, Fit: DestFitB}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetOpenAction(&Destination{Page: p1, Zoom: 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(buf.Bytes()); err != nil {
+		t.Error(err)
+	}
+	pages := r.pages()
+	dest := func(o interface{}) string {
+		a := r.resolve(o).([]interface{})
+		pg := -1
+		for i, p := range pages {
+			if a[0] == (ref{p.num, 0}) {
+				pg = i + 1
+			}
+		}
+		return fmt.Sprint(pg, a[1:])
+	}
+	cat := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	if got := dest(cat["OpenAction"]); got != "1 [XYZ 0 0 1.5]" {
+		t.Errorf("open action: got %v", got)
+	}
+	outlines := r.resolve(cat["Outlines"]).(map[string]interface{})
+	first := r.resolve(outlines["First"]).(map[string]interface{})
+	if got := dest(first["Dest"]); got != "2 [FitB]" {
+		t.Errorf("bookmark: got %v", got)
+	}
+	annots := r.resolve(pages[1].dic["Annots"]).([]interface{})
+	if len(annots) != 2 {
+		t.Fatalf("got %d links, want 2", len(annots))
+	}
+	if got := r.resolve(annots[0]).(map[string]interface{})["Dest"]; got != "results" {
+		t.Errorf("link to named destination: got %v", got)
+	}
+	if got := dest(r.resolve(annots[1]).(map[string]interface{})["Dest"]); got != "1 [Fit]" {
+		t.Errorf("link to destination: got %v", got)
+	}
+}
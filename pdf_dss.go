@@ -0,0 +1,117 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file deals with the document security store (DSS), which keeps what is
+// needed to verify the signature of the document long after it's signed, even
+// when its certificates are expired or revoked (ISO 32000-2, section 12.8.4.3).
+// The store is added to the signed file with an incremental update, so the
+// signature stays valid.
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+)
+
+// Validation holds the data for long-term validation (LTV) of a signature.
+type Validation struct {
+	// Certificates of the chains of the signer, and of the responders.
+	// Certificates of the signature are added anyway.
+	Certificates []*x509.Certificate
+	CRLs         [][]byte // DER encoded certificate revocation lists
+	OCSPs        [][]byte // DER encoded OCSP responses
+}
+
+// AddValidation adds the certificates, CRLs and OCSP responses of v to the
+// security store of the document, which is written after it's signed. It can
+// be called more than once, but only after Sign or Timestamp.
+func (d *Document) AddValidation(v *Validation) (err error) {
+	defer dontPanic(&err)
+
+	if d.sig == nil {
+		panic("validation data added to a document that is not signed")
+	}
+	if d.dss == nil {
+		d.dss = new(Validation)
+	}
+	d.dss.Certificates = append(d.dss.Certificates, v.Certificates...)
+	d.dss.CRLs = append(d.dss.CRLs, v.CRLs...)
+	d.dss.OCSPs = append(d.dss.OCSPs, v.OCSPs...)
+	return nil
+}
+
+// streams writes each of bs as a stream and returns references to them.
+// Duplicates are written once.
+func (d *Document) streams(bs [][]byte) []*indirect {
+	seen := make(map[string]bool)
+	refs := make([]*indirect, 0, len(bs))
+	for _, b := range bs {
+		if seen[string(b)] {
+			continue
+		}
+		seen[string(b)] = true
+		refs = append(refs, d.indirect(b))
+	}
+	return refs
+}
+
+// writeDSS appends an incremental update that adds the security store to the
+// catalog of the signed document.
+func (d *Document) writeDSS() {
+	prev := d.xOff
+	first := len(d.objs) // objects after this are made by the update
+
+	certs := make([][]byte, 0, len(d.sig.s.Certificates)+len(d.dss.Certificates))
+	for _, c := range d.sig.s.Certificates {
+		certs = append(certs, c.Raw)
+	}
+	for _, c := range d.dss.Certificates {
+		certs = append(certs, c.Raw)
+	}
+	dss := map[string]interface{}{}
+	vri := map[string]interface{}{}
+	for _, e := range []struct {
+		dss, vri string
+		bs       [][]byte
+	}{
+		{"Certs", "Cert", certs},
+		{"CRLs", "CRL", d.dss.CRLs},
+		{"OCSPs", "OCSP", d.dss.OCSPs},
+	} {
+		if refs := d.streams(e.bs); len(refs) > 0 {
+			dss[e.dss] = refs
+			vri[e.vri] = refs
+		}
+	}
+
+	// The validation data of each signature is under the SHA-1 digest of
+	// its Contents.
+	h := sha1.New()
+	h.Write(d.sig.sig)
+	key := strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+	dss["VRI"] = map[string]interface{}{key: vri}
+
+	d.catd["DSS"] = d.indirect(dss)
+	d.outputIndirect(d.cat, d.catd)
+
+	objs := []*indirect{d.cat}
+	objs = append(objs, d.objs[first:]...)
+	d.writeRefs(objs)
+	d.writeTrailer(prev)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAddValidation(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	if err := d.AddValidation(&Validation{}); err == nil {
+		t.Error("validation data added to a document that is not signed")
+	}
+
+	s := testSignature(t)
+	buf := bytes.NewBuffer(nil)
+	d, _ = New(buf)
+	d.Sign(s)
+	d.NewPage(100, 100)
+	v := &Validation{
+		Certificates: s.Certificates, // written once
+		CRLs:         [][]byte{[]byte("crl")},
+	}
+	if err := d.AddValidation(v); err != nil {
+		t.Fatal(err)
+	}
+	d.AddValidation(&Validation{OCSPs: [][]byte{[]byte("ocsp1"), []byte("ocsp2")}})
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature covers the file before the update.
+	b := buf.Bytes()
+	end := bytes.Index(b, []byte("%%EOF\n")) + len("%%EOF\n")
+	_, sig := signedContent(t, b[:end])
+	upd := string(b[end:])
+
+	var xref int
+	fmt.Sscan(regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(string(b[:end]))[1], &xref)
+	h := sha1.New()
+	h.Write(sig)
+	key := strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+	for _, s := range []string{
+		"1 0 obj\n", "/DSS 10 0 R", "/Certs [ 6 0 R ]", "/CRLs [ 7 0 R ]",
+		"/OCSPs [ 8 0 R 9 0 R ]", "/" + key + " <<\n/CRL [ 7 0 R ]",
+		"xref\n0 2\n", "\n6 5\n", fmt.Sprintf("/Prev %d\n", xref), "/Size 11\n",
+	} {
+		if !strings.Contains(upd, s) {
+			t.Errorf("update doesn't have %q", s)
+		}
+	}
+
+	// Offsets in the new table should point to the objects.
+	for _, m := range regexp.MustCompile(`(\d{10}) 00000 n`).FindAllStringSubmatch(upd, -1) {
+		off, _ := strconv.Atoi(m[1])
+		if !regexp.MustCompile(`^\d+ 0 obj`).Match(b[off:]) {
+			t.Errorf("offset %d doesn't point to an object", off)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file shows emoji and other color characters in text, like the text of
+// user-generated content, which the standard fonts don't have. They are the
+// glyphs of a Type 3 font (p. 394) made for the document, drawn from layers
+// of color fonts or from images.
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Emoji is a source of the glyphs of emoji. It's either a color font, made by
+// ColorFont, or EmojiImages.
+type Emoji interface {
+	// glyph returns content stream operators drawing r in a square of
+	// 1000 units, with the baseline at 0, and adds the resources they use
+	// to res. ok is false if the source doesn't have r.
+	glyph(d *Document, r rune, res *resources) (c string, ok bool)
+}
+
+// emojiFont is the name of the Type 3 font of emoji in resources.
+const emojiFont = "Emoji"
+
+// emojiMark is put before the code of an emoji in WinAnsi encoded text. The
+// codes are from firstEmoji to 255, so that they are never spaces or line
+// breaks.
+const (
+	emojiMark  = "\x03"
+	firstEmoji = 0x21
+)
+
+// emojiDescent is how far below the baseline emoji go, in thousandths of the
+// font size; it's about the descender of Helvetica.
+const emojiDescent = 200
+
+// emojiSet is the Type 3 font of the emoji used in a document.
+type emojiSet struct {
+	ref     *indirect
+	codes   map[rune]byte        // codes of the characters in the font
+	chars   []rune               // characters of the font, by their codes from firstEmoji
+	procs   map[string]*indirect // glyph procedures, by the names of the glyphs
+	res     resources            // resources of the glyph procedures
+	missing map[rune]bool        // characters none of the sources have
+}
+
+// SetEmoji sets the sources of the glyphs of emoji and other characters that
+// WinAnsiEncoding doesn't have, in the text of paragraphs of flows. Sources
+// are tried in the order given, so a color font can be followed by images as
+// a fallback. Characters no source has are shown as question marks.
+//
+// Each emoji is one character; variation selectors are dropped, and sequences
+// of emoji joined by zero width joiners, flags and modifiers of skin tones are
+// shown as the separate characters they are made of. A document can have at
+// most 223 different emoji; the ones after that are question marks too.
+func (d *Document) SetEmoji(sources ...Emoji) (err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	for _, s := range sources {
+		if s == nil {
+			panic("nil source of emoji")
+		}
+	}
+	d.emoji = sources
+	return nil
+}
+
+// emojiText converts the UTF-8 string s to WinAnsiEncoding like winAnsi, but
+// characters that don't exist in the encoding are emoji if the sources of d
+// have them.
+func (d *Document) emojiText(s string) string {
+	if len(d.emoji) == 0 {
+		return d.encodeText(s)
+	}
+	var b strings.Builder
+	var missing []rune
+	for _, r := range s {
+		if r == 0xfe0e || r == 0xfe0f {
+			continue
+		}
+		t := winAnsi(string(r))
+		if t == "?" && r != '?' {
+			if c, ok := d.emojiCode(r); ok {
+				b.WriteString(emojiMark)
+				b.WriteByte(c)
+				continue
+			}
+			missing = append(missing, r)
+		}
+		b.WriteString(t)
+	}
+	d.noGlyphs(missing)
+	return b.String()
+}
+
+// emojiCode returns the code of r in the font of emoji, adding its glyph to
+// the font from the sources of d the first time it's used. ok is false if no
+// source has r, or the font is full.
+func (d *Document) emojiCode(r rune) (c byte, ok bool) {
+	d.resMu.Lock()
+	e := d.emojis
+	if e == nil {
+		e = &emojiSet{
+			codes:   make(map[rune]byte),
+			procs:   make(map[string]*indirect),
+			missing: make(map[rune]bool),
+		}
+		d.emojis = e
+	}
+	c, ok = e.codes[r]
+	skip := e.missing[r] || len(e.chars) >= 256-firstEmoji
+	d.resMu.Unlock()
+	if ok || skip {
+		return c, ok
+	}
+
+	// Glyphs are made without the lock, as sources use it for images
+	// and graphics states.
+	var res resources
+	var g string
+	for _, s := range d.emoji {
+		if g, ok = s.glyph(d, r, &res); ok {
+			break
+		}
+	}
+
+	d.resMu.Lock()
+	defer d.resMu.Unlock()
+	if !ok {
+		e.missing[r] = true
+		return 0, false
+	}
+	if c, ok := e.codes[r]; ok {
+		return c, true
+	}
+	if len(e.chars) >= 256-firstEmoji {
+		return 0, false
+	}
+	if e.ref == nil {
+		e.ref = d.reserveIndirect()
+	}
+	c = byte(firstEmoji + len(e.chars))
+	e.codes[r] = c
+	e.chars = append(e.chars, r)
+	e.res.merge(res)
+	e.procs[emojiGlyph(r)] = d.partIndirect(partFonts, &stream{nil, []byte(fmt.Sprint("1000 0 d0\n", g))})
+	return c, true
+}
+
+// emojiGlyph returns the name of the glyph of r in the font of emoji.
+func emojiGlyph(r rune) string {
+	return fmt.Sprintf("u%04X", r)
+}
+
+// pageEmoji adds the font of emoji to the resources of the current page,
+// or of the XObject being made, and returns its name.
+func (d *Document) pageEmoji() string {
+	return d.useResource("Font", emojiFont, d.emojis.ref)
+}
+
+// saveEmoji writes the font of emoji to the output.
+func (d *Document) saveEmoji() {
+	e := d.emojis
+	diffs := []interface{}{firstEmoji}
+	widths := make([]int, len(e.chars))
+	codes := make([]int, len(e.chars))
+	for i, r := range e.chars {
+		diffs = append(diffs, name(emojiGlyph(r)))
+		widths[i] = 1000
+		codes[i] = firstEmoji + i
+	}
+	d.outputIndirect(e.ref, map[string]interface{}{
+		"Type":       name("Font"),
+		"Subtype":    name("Type3"),
+		"FontBBox":   newRect(0, 0, 0, 0),
+		"FontMatrix": []float64{0.001, 0, 0, 0.001, 0, 0},
+		"CharProcs":  e.procs,
+		"Encoding": map[string]interface{}{
+			"Type":        name("Encoding"),
+			"Differences": diffs,
+		},
+		"FirstChar": firstEmoji,
+		"LastChar":  firstEmoji + len(e.chars) - 1,
+		"Widths":    widths,
+		"Resources": e.res.dict(),
+		"ToUnicode": d.partIndirect(partFonts, &stream{nil, []byte(toUnicodeCMap(1, codes, e.chars))}),
+	})
+}
+
+// toUnicodeCMap returns the ToUnicode CMap (p. 472) of a font with codes of
+// n bytes, where codes[i] is the code of chars[i], so that the characters can
+// be extracted from the text of pages.
+func toUnicodeCMap(n int, codes []int, chars []rune) string {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	fmt.Fprintf(&b, "1 begincodespacerange\n<%s> <%s>\nendcodespacerange\n",
+		strings.Repeat("00", n), strings.Repeat("FF", n))
+	// There can be at most 100 mappings in a section.
+	for i := 0; i < len(chars); i += 100 {
+		m := len(chars) - i
+		if m > 100 {
+			m = 100
+		}
+		fmt.Fprintf(&b, "%d beginbfchar\n", m)
+		for j, r := range chars[i : i+m] {
+			fmt.Fprintf(&b, "<%0*X> <", 2*n, codes[i+j])
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, "%04X", u)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.String()
+}
+
+// EmojiImages is a source of emoji which returns their images, like the PNG
+// files of an emoji set. It returns nil for the characters it doesn't have.
+// Images are drawn in a square as high as the font size, keeping their
+// aspect ratio.
+type EmojiImages func(r rune) image.Image
+
+func (f EmojiImages) glyph(d *Document, r rune, res *resources) (string, bool) {
+	m := f(r)
+	if m == nil {
+		return "", false
+	}
+	// Images of glyphs are not the ones associated files are meant for.
+	af := d.objAF
+	d.objAF = nil
+	x, err := d.AddImage(m)
+	d.objAF = af
+	check(err)
+	res.add("XObject", x.name(), x.ref)
+	w, h := 1000.0, 1000.0
+	if x.w > x.h {
+		h = 1000 * x.h / x.w
+	} else {
+		w = 1000 * x.w / x.h
+	}
+	cw := NewContentWriter(nil).Precision(1)
+	x.draw(cw, (1000-w)/2, (1000-h)/2-emojiDescent, w, h)
+	return string(cw.Bytes()), true
+}
+
+// colorFont is a TrueType or OpenType font whose glyphs are layers of
+// colors, in its COLR and CPAL tables.
+type colorFont struct {
+	f      *sfnt
+	base   []colorGlyph  // glyphs made of layers, sorted by glyph
+	layers []byte        // layer records of COLR
+	colors []color.NRGBA // colors of the first palette
+}
+
+// colorGlyph is a base glyph record of COLR.
+type colorGlyph struct {
+	glyph, first, n int // glyph, and the index and number of its layers
+}
+
+// ColorFont returns a source of emoji that draws the glyphs of the color font
+// in the TrueType or OpenType font file b, which should have version 0 of the
+// COLR and CPAL tables, like Twemoji Mozilla. Glyphs are layers of outlines in
+// colors of the first palette of the font. Fonts with outlines in CFF instead
+// of TrueType are not supported.
+func ColorFont(b []byte) (e Emoji, err error) {
+	defer dontPanic(&err)
+
+	f := parseSfnt(b)
+	colr, cpal := f.table("COLR", 14), f.table("CPAL", 12)
+	cf := &colorFont{f: f}
+	n, off := int(u16(colr, 2)), int(u32(colr, 4))
+	for i := 0; i < n; i++ {
+		r := off + 6*i
+		cf.base = append(cf.base, colorGlyph{int(u16(colr, r)), int(u16(colr, r+2)), int(u16(colr, r+4))})
+	}
+	sort.Slice(cf.base, func(i, j int) bool { return cf.base[i].glyph < cf.base[j].glyph })
+	loff, ln := int(u32(colr, 8)), int(u16(colr, 12))
+	if loff+4*ln > len(colr) {
+		fail(ErrBadFile, "COLR layers out of the table")
+	}
+	cf.layers = colr[loff : loff+4*ln]
+
+	entries, coff := int(u16(cpal, 2)), int(u32(cpal, 8))
+	first := coff + 4*int(u16(cpal, 12))
+	if first+4*entries > len(cpal) {
+		fail(ErrBadFile, "CPAL colors out of the table")
+	}
+	for i := 0; i < entries; i++ {
+		c := cpal[first+4*i:]
+		cf.colors = append(cf.colors, color.NRGBA{c[2], c[1], c[0], c[3]})
+	}
+	return cf, nil
+}
+
+func (cf *colorFont) glyph(d *Document, r rune, res *resources) (string, bool) {
+	g := cf.f.glyphIndex(r)
+	i := sort.Search(len(cf.base), func(i int) bool { return cf.base[i].glyph >= g })
+	if g == 0 || i == len(cf.base) || cf.base[i].glyph != g {
+		return "", false
+	}
+	s := 1000 / float64(cf.f.unitsPerEm)
+	m := matrix{s, 0, 0, s, 0, 0}
+	cw := NewContentWriter(nil).Precision(1)
+	for l := cf.base[i].first; l < cf.base[i].first+cf.base[i].n; l++ {
+		lg, p := int(u16(cf.layers, 4*l)), int(u16(cf.layers, 4*l+2))
+		// Layers with palette index 0xFFFF are in the color of the
+		// text.
+		if p == 0xffff {
+			cf.f.glyphPath(cw, lg, m, 0)
+			cw.Fill()
+			continue
+		}
+		if p >= len(cf.colors) {
+			fail(ErrBadFile, fmt.Sprint("color ", p, " out of the palette"))
+		}
+		c := cf.colors[p]
+		cw.SaveState()
+		if c.A < 255 {
+			gs := d.extGState(map[string]interface{}{
+				"Type": name("ExtGState"),
+				"ca":   float64(c.A) / 255,
+			})
+			cw.Name(resName("GS", gs)).Op("gs")
+			res.add("ExtGState", resName("GS", gs), gs)
+		}
+		cw.FillColor(RGB{float64(c.R) / 255, float64(c.G) / 255, float64(c.B) / 255})
+		cf.f.glyphPath(cw, lg, m, 0)
+		cw.Fill().RestoreState()
+	}
+	return string(cw.Bytes()), true
+}
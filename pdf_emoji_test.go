@@ -0,0 +1,143 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// testColorFont returns a TrueType font with COLR and CPAL tables. Character
+// r is glyph 1, made of a square in half transparent red and a triangle with
+// a curve in the color of the text.
+func testColorFont(r rune) []byte {
+	// Glyph 2 is a square and glyph 3 has points off the curve.
+	square := be(int16(1), int16(0), int16(0), int16(100), int16(100), uint16(3), uint16(0),
+		[]byte{1, 1, 1, 1}, int16(0), int16(100), int16(0), int16(-100),
+		int16(0), int16(0), int16(100), int16(0))
+	curve := be(int16(1), int16(0), int16(0), int16(100), int16(100), uint16(2), uint16(0),
+		[]byte{1, 0, 1}, int16(0), int16(50), int16(50), int16(0), int16(0), int16(100))
+	glyf := append(append([]byte{}, square...), curve...)
+	loca := be(uint32(0), uint32(0), uint32(0), uint32(len(square)), uint32(len(glyf)))
+	tables := map[string][]byte{
+		"head": be(make([]byte, 18), uint16(200), make([]byte, 30), uint16(1), uint16(0)),
+		"maxp": be(uint32(0x5000), uint16(4)),
+		"cmap": be(uint16(0), uint16(1), uint16(3), uint16(10), uint32(12),
+			uint16(12), uint16(0), uint32(28), uint32(0), uint32(1), uint32(r), uint32(r), uint32(1)),
+		"loca": loca,
+		"glyf": glyf,
+		"COLR": be(uint16(0), uint16(1), uint32(14), uint32(20), uint16(2),
+			uint16(1), uint16(0), uint16(2), uint16(2), uint16(0), uint16(3), uint16(0xffff)),
+		"CPAL": be(uint16(0), uint16(1), uint16(1), uint16(1), uint32(14), uint16(0),
+			[]byte{0, 0, 255, 128}),
+	}
+	return writeSfnt(tables)
+}
+
+func TestColorFont(t *testing.T) {
+	e, err := ColorFont(testColorFont(0x1f600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := New(bytes.NewBuffer(nil))
+	var res resources
+	c, ok := e.glyph(d, 0x1f600, &res)
+	if !ok {
+		t.Fatal("glyph of the character of the font not found")
+	}
+	var gs string
+	for n := range res["ExtGState"] {
+		gs = n
+	}
+	for _, s := range []string{
+		"q\n/" + gs + " gs\n1 0 0 rg\n0 0 m\n500 0 l\n500 500 l\n0 500 l\nh\nf\nQ\n",
+		"Q\n0 0 m\n166.7 0 333.3 166.7 500 500 c\nh\nf\n",
+	} {
+		if !strings.Contains(c, s) {
+			t.Errorf("glyph: %q not found in %q", s, c)
+		}
+	}
+	if _, ok := e.glyph(d, 'a', &res); ok {
+		t.Error("glyph of a character not in the font found")
+	}
+
+	for _, b := range [][]byte{
+		[]byte("not a font"),
+		testColorFont(0x1f600)[:100],
+	} {
+		_, err := ColorFont(b)
+		if !errors.Is(err, ErrBadFile) {
+			t.Errorf("bad font: got error %v", err)
+		}
+	}
+}
+
+func TestEmoji(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	m := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	m.Set(0, 0, color.RGBA{255, 200, 0, 255})
+	font, _ := ColorFont(testColorFont(0x1f600))
+	err := d.SetEmoji(font, EmojiImages(func(r rune) image.Image {
+		if r == 0x1f44d {
+			return m
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := d.NewFlow(300, 200, Margins{20, 20, 20, 20})
+	err = f.Add(&Paragraph{Text: "Hi \U0001f600\U0001f44d️ ☃ \x03", Style: courier})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("BT /Cour 10 Tf 0 g 20 171.45 Td (Hi ) Tj /Emoji 10 Tf (!\") Tj /Cour 10 Tf ( ? ?) Tj ET")) {
+		t.Error("emoji not shown with the font of emoji")
+	}
+	if ps := Validate(buf.Bytes()); len(ps) > 0 {
+		t.Errorf("problems: %v", ps)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err := r.PageText(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text string
+	for _, run := range runs {
+		text += run.Text
+	}
+	if want := "Hi \U0001f600\U0001f44d ? ?"; text != want {
+		t.Errorf("text: got %q, want %q", text, want)
+	}
+	fonts := r.resolve(r.pages()[0].dic["Resources"]).(map[string]interface{})["Font"].(map[string]interface{})
+	font3 := r.resolve(fonts["Emoji"]).(map[string]interface{})
+	if font3["Subtype"] != name("Type3") || len(r.resolve(font3["CharProcs"]).(map[string]interface{})) != 2 {
+		t.Errorf("font of emoji: got %v", font3)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file lets users add custom entries to the dictionaries of standard
+// objects, like for the extensions of a viewer. Custom objects are added by
+// the functions in pdf_object.go.
+
+// Kinds of standard objects that custom entries can be added to.
+const (
+	ExtendCatalog = "Catalog"
+	ExtendPage    = "Page"
+	ExtendInfo    = "Info"
+	ExtendAnnot   = "Annot"
+)
+
+// Extend adds f to the functions that add custom entries to the standard
+// objects of kind k, which is one of ExtendCatalog, ExtendPage, ExtendInfo
+// and ExtendAnnot. f is called before each of the objects is written, and the
+// entries it adds to dic are added to the object. Entries that pdf.go sets
+// itself can't be added, and the values must be objects AddObject accepts.
+func (d *Document) Extend(k string, f func(dic map[string]interface{}) error) (err error) {
+	defer dontPanic(&err)
+
+	switch k {
+	case ExtendCatalog, ExtendPage, ExtendInfo, ExtendAnnot:
+	default:
+		panic("unknown kind of object: " + k)
+	}
+	if d.exts == nil {
+		d.exts = make(map[string][]func(dic map[string]interface{}) error)
+	}
+	d.exts[k] = append(d.exts[k], f)
+	return nil
+}
+
+// SetEntry adds the custom entry k with value v to the dictionary of p. v can
+// be any object AddObject accepts.
+func (p *Page) SetEntry(k string, v interface{}) (err error) {
+	defer dontPanic(&err)
+
+	if p.pg.flushed {
+		panic("page is already flushed")
+	}
+	checkObject(v, 0)
+	if p.pg.extra == nil {
+		p.pg.extra = make(map[string]interface{})
+	}
+	p.pg.extra[k] = v
+	return nil
+}
+
+// extend adds the entries of extra, and the ones added by the functions of
+// d for objects of kind k, to dic, and returns dic.
+func (d *Document) extend(k string, dic, extra map[string]interface{}) map[string]interface{} {
+	add := func(e map[string]interface{}) {
+		for n, v := range e {
+			if _, ok := dic[n]; ok {
+				fail(ErrBadName, "entry "+n+" of "+k+" is set by pdf.go")
+			}
+			checkObject(v, 0)
+			dic[n] = v
+		}
+	}
+	add(extra)
+	for _, f := range d.exts[k] {
+		e := make(map[string]interface{})
+		check(f(e))
+		add(e)
+	}
+	return dic
+}
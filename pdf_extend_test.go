@@ -0,0 +1,132 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExtend(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	data, err := d.AddObject(&Stream{Dict: map[string]interface{}{"Subtype": Name("Custom")}, Data: []byte("abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := d.AddObject(map[string]interface{}{
+		"Type": Name("ViewerData"),
+		"Data": data,
+		"Kids": []interface{}{1, 2.5, "three"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Extend(ExtendCatalog, func(dic map[string]interface{}) error {
+		dic["ViewerData"] = r
+		return nil
+	})
+	d.Extend(ExtendInfo, func(dic map[string]interface{}) error {
+		dic["Build"] = "42"
+		return nil
+	})
+	d.Extend(ExtendAnnot, func(dic map[string]interface{}) error {
+		dic["Custom"] = true
+		return nil
+	})
+	p, _ := d.NewPage(100, 100)
+	p.SetEntry("PageKind", Name("Cover"))
+	d.TextBox(10, 10, 50, 20, &TextField{Name: "name"})
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rd, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := rd.resolve(rd.trailer["Root"]).(map[string]interface{})
+	vd := rd.resolve(cat["ViewerData"]).(map[string]interface{})
+	if vd["Type"] != name("ViewerData") || fmt.Sprint(vd["Kids"]) != "[1 2.5 three]" {
+		t.Errorf("custom object: got %v", vd)
+	}
+	if s := rd.resolve(vd["Data"]).(*stream); string(s.buf) != "abc" || s.dic["Subtype"] != name("Custom") {
+		t.Errorf("custom stream: got %v %q", s.dic, s.buf)
+	}
+	if info, _ := rd.Info(); info["Build"] != "42" {
+		t.Errorf("info: got %v", info)
+	}
+	pg := rd.pages()[0]
+	if pg.dic["PageKind"] != name("Cover") {
+		t.Errorf("page: got %v", pg.dic)
+	}
+	annot := rd.resolve(rd.resolve(pg.dic["Annots"]).([]interface{})[0]).(map[string]interface{})
+	if annot["Custom"] != true {
+		t.Errorf("annotation: got %v", annot)
+	}
+
+	tests := []struct {
+		k   string
+		f   func(dic map[string]interface{}) error
+		err string
+	}{
+		{ExtendCatalog, func(dic map[string]interface{}) error {
+			dic["Pages"] = 1
+			return nil
+		}, "pdf.go: entry Pages of Catalog is set by pdf.go"},
+		{ExtendPage, func(dic map[string]interface{}) error {
+			return errors.New("no entries")
+		}, "pdf.go: no entries"},
+		{"Font", nil, "pdf.go: unknown kind of object: Font"},
+		{ExtendInfo, func(dic map[string]interface{}) error {
+			dic["Done"] = make(chan int)
+			return nil
+		}, "pdf.go: unsupported type in object: chan int"},
+	}
+	for _, tt := range tests {
+		d, _ := New(bytes.NewBuffer(nil))
+		err := d.Extend(tt.k, tt.f)
+		if err == nil {
+			d.NewPage(100, 100)
+			err = d.Close()
+		}
+		if fmt.Sprint(err) != tt.err {
+			t.Errorf("%s: got %v expected %s", tt.k, err, tt.err)
+		}
+	}
+}
+
+func TestSetEntryInvalid(t *testing.T) {
+	tests := []struct {
+		v   interface{}
+		err string
+	}{
+		{make(chan int), "pdf.go: unsupported type in object: chan int"},
+		{struct{ A int }{1}, "pdf.go: unsupported type in object: struct { A int }"},
+		{NewDict("A"), "pdf.go: odd number of keys and values for NewDict"},
+	}
+	for _, tt := range tests {
+		d, _ := New(bytes.NewBuffer(nil))
+		p, _ := d.NewPage(100, 100)
+		err := p.SetEntry("Custom", tt.v)
+		if !errors.Is(err, ErrInvalid) || err.Error() != tt.err {
+			t.Errorf("SetEntry(%T): got %v", tt.v, err)
+		}
+	}
+}
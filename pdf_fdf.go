@@ -0,0 +1,255 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file exports the values of the fields of forms to FDF and XFDF files,
+// and imports them from the files, so that they can be filled in by
+// FillForm. FDF is a PDF-like file with only the data of the form;
+// XFDF is its XML version.
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// formField is the value of a field of a form, as written to FDF and XFDF.
+type formField struct {
+	name   string   // partial name
+	values []string // value, or the selected items of list boxes
+	state  bool     // whether the value is the name of a state of a button
+	kids   []*formField
+}
+
+// formFields returns the fields of the form of the file, with the values
+// returned by FieldValues, as a tree by their partial names.
+func (r *Reader) formFields() []*formField {
+	var top []*formField
+	nodes := make(map[string]*formField)
+	var node func(fq string) *formField
+	node = func(fq string) *formField {
+		if f, ok := nodes[fq]; ok {
+			return f
+		}
+		f := &formField{name: fq}
+		if dot := strings.LastIndex(fq, "."); dot >= 0 {
+			par := node(fq[:dot])
+			f.name = fq[dot+1:]
+			par.kids = append(par.kids, f)
+		} else {
+			top = append(top, f)
+		}
+		nodes[fq] = f
+		return f
+	}
+	for _, rf := range r.fields() {
+		ft, _ := r.resolve(rf.attrs["FT"]).(name)
+		flags, _ := r.resolve(rf.attrs["Ff"]).(int)
+		v := r.fieldValue(rf.attrs["V"])
+		switch {
+		case ft == "Tx":
+			node(rf.name).values = []string{v}
+		case ft == "Ch":
+			if v != "" {
+				node(rf.name).values = strings.Split(v, "\n")
+			} else {
+				node(rf.name).values = []string{""}
+			}
+		case ft == "Btn" && flags&buttonPush == 0:
+			if v == "" {
+				v = "Off"
+			}
+			f := node(rf.name)
+			f.values, f.state = []string{v}, true
+		}
+	}
+	return top
+}
+
+// fdf returns the FDF field dictionary of f.
+func (f *formField) fdf() map[string]interface{} {
+	d := map[string]interface{}{"T": textString(f.name)}
+	switch {
+	case f.state:
+		d["V"] = name(f.values[0])
+	case len(f.values) == 1:
+		d["V"] = textString(f.values[0])
+	case len(f.values) > 1:
+		vs := make([]string, len(f.values))
+		for i, v := range f.values {
+			vs[i] = textString(v)
+		}
+		d["V"] = vs
+	}
+	if len(f.kids) > 0 {
+		kids := make([]map[string]interface{}, len(f.kids))
+		for i, k := range f.kids {
+			kids[i] = k.fdf()
+		}
+		d["Kids"] = kids
+	}
+	return d
+}
+
+// WriteFDF writes the values of the fields of the form of the file to w as
+// an FDF file, with the values returned by FieldValues. file is the name of
+// the PDF file that the values belong to; it's left out if it's empty.
+func (r *Reader) WriteFDF(w io.Writer, file string) (err error) {
+	defer dontPanic(&err)
+
+	var fields []map[string]interface{}
+	for _, f := range r.formFields() {
+		fields = append(fields, f.fdf())
+	}
+	fdf := map[string]interface{}{"Fields": fields}
+	if file != "" {
+		fdf["F"] = file
+	}
+
+	buf := bytes.NewBufferString("%FDF-1.2\n%\xe2\xe3\xcf\xd3\n")
+	off := buf.Len()
+	buf.WriteString("1 0 obj\n")
+	buf.Write(output(map[string]interface{}{"FDF": fdf}))
+	buf.WriteString("\nendobj\n")
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 2\n0000000000 65535 f\r\n%010d 00000 n\r\n", off)
+	buf.WriteString("trailer\n")
+	buf.Write(output(map[string]interface{}{"Root": ref{1, 0}}))
+	fmt.Fprintf(buf, "\nstartxref\n%d\n%%%%EOF\n", xref)
+	_, err = w.Write(buf.Bytes())
+	check(err)
+	return nil
+}
+
+// FDFValues returns the values of the fields in FDF file b, by their fully
+// qualified names, to be passed to FillForm. Values are like the ones
+// returned by FieldValues.
+func FDFValues(b []byte) (values map[string]string, err error) {
+	defer dontPanic(&err)
+
+	r, err := NewReader(b)
+	check(err)
+	cat, _ := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	fdf, ok := r.resolve(cat["FDF"]).(map[string]interface{})
+	if !ok {
+		fail(ErrBadFile, "no FDF dictionary")
+	}
+	values = make(map[string]string)
+	r.fdfFields(fdf["Fields"], "", make(map[int]bool), values)
+	return values, nil
+}
+
+// fdfFields adds the values of the FDF fields in a, whose parent has fully
+// qualified name par, to values.
+func (r *Reader) fdfFields(a interface{}, par string, seen map[int]bool, values map[string]string) {
+	fs, _ := r.resolve(a).([]interface{})
+	for _, f := range fs {
+		if fr, ok := f.(ref); ok {
+			if seen[fr.num] {
+				continue
+			}
+			seen[fr.num] = true
+		}
+		d, ok := r.resolve(f).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		t, _ := r.resolve(d["T"]).(string)
+		fq := decodeText(t)
+		if par != "" {
+			fq = par + "." + fq
+		}
+		if v, ok := d["V"]; ok {
+			values[fq] = r.fieldValue(v)
+		}
+		r.fdfFields(d["Kids"], fq, seen, values)
+	}
+}
+
+// xfdf is the root element of XFDF files.
+type xfdf struct {
+	XMLName xml.Name    `xml:"xfdf"`
+	NS      string      `xml:"xmlns,attr,omitempty"`
+	Space   string      `xml:"http://www.w3.org/XML/1998/namespace space,attr,omitempty"`
+	F       *xfdfFile   `xml:"f"`
+	Fields  []xfdfField `xml:"fields>field"`
+}
+
+type xfdfFile struct {
+	Href string `xml:"href,attr"`
+}
+
+type xfdfField struct {
+	Name   string      `xml:"name,attr"`
+	Values []string    `xml:"value"`
+	Kids   []xfdfField `xml:"field"`
+}
+
+// xfdf returns the XFDF field element of f.
+func (f *formField) xfdf() xfdfField {
+	x := xfdfField{Name: f.name, Values: f.values}
+	for _, k := range f.kids {
+		x.Kids = append(x.Kids, k.xfdf())
+	}
+	return x
+}
+
+// WriteXFDF is like WriteFDF, but writes an XFDF file.
+func (r *Reader) WriteXFDF(w io.Writer, file string) (err error) {
+	defer dontPanic(&err)
+
+	x := &xfdf{NS: "http://ns.adobe.com/xfdf/", Space: "preserve"}
+	if file != "" {
+		x.F = &xfdfFile{file}
+	}
+	for _, f := range r.formFields() {
+		x.Fields = append(x.Fields, f.xfdf())
+	}
+	b, err := xml.MarshalIndent(x, "", "\t")
+	check(err)
+	_, err = io.WriteString(w, xml.Header+string(b)+"\n")
+	check(err)
+	return nil
+}
+
+// XFDFValues is like FDFValues, but reads XFDF file b.
+func XFDFValues(b []byte) (values map[string]string, err error) {
+	defer dontPanic(&err)
+
+	x := new(xfdf)
+	if err := xml.Unmarshal(b, x); err != nil {
+		fail(ErrBadFile, "bad XFDF: "+err.Error())
+	}
+	values = make(map[string]string)
+	var add func(fs []xfdfField, par string)
+	add = func(fs []xfdfField, par string) {
+		for _, f := range fs {
+			fq := f.Name
+			if par != "" {
+				fq = par + "." + fq
+			}
+			if len(f.Values) > 0 {
+				values[fq] = strings.Join(f.Values, "\n")
+			}
+			add(f.Kids, fq)
+		}
+	}
+	add(x.Fields, "")
+	return values, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFDF(t *testing.T) {
+	r, _ := NewReader(testForm(t))
+	buf := bytes.NewBuffer(nil)
+	fill := map[string]string{
+		"address.street": "Ferdowsi Street ☕",
+		"notes":          "First line\nSecond line",
+		"agree":          "Agreed",
+		"color":          "g",
+		"sizes":          "M\nL",
+	}
+	if err := FillForm(buf, r, fill); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = NewReader(buf.Bytes())
+	want, _ := r.FieldValues()
+
+	fdf, xfdf := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if err := r.WriteFDF(fdf, "form.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.WriteXFDF(xfdf, "form.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"%FDF-1.2", "/V /Agreed", "/F (form.pdf)", "/T (address)"} {
+		if !bytes.Contains(fdf.Bytes(), []byte(s)) {
+			t.Errorf("no %q in FDF", s)
+		}
+	}
+	for _, s := range []string{`<xfdf xmlns="http://ns.adobe.com/xfdf/" xml:space="preserve">`,
+		`<f href="form.pdf"></f>`, "<field name=\"sizes\">\n\t\t\t<value>M</value>\n\t\t\t<value>L</value>"} {
+		if !bytes.Contains(xfdf.Bytes(), []byte(s)) {
+			t.Errorf("no %q in XFDF", s)
+		}
+	}
+	if fr, _ := NewReader(fdf.Bytes()); fr.Repaired() {
+		t.Error("cross-reference table of FDF is rebuilt")
+	}
+
+	for _, tt := range []struct {
+		what string
+		f    func([]byte) (map[string]string, error)
+		b    []byte
+	}{
+		{"FDF", FDFValues, fdf.Bytes()},
+		{"XFDF", XFDFValues, xfdf.Bytes()},
+	} {
+		values, err := tt.f(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(values, want) {
+			t.Errorf("values of %s: got %q, want %q", tt.what, values, want)
+		}
+
+		// Values imported from the file fill a new form the same way.
+		buf := bytes.NewBuffer(nil)
+		empty, _ := NewReader(testForm(t))
+		if err := FillForm(buf, empty, values); err != nil {
+			t.Fatal(err)
+		}
+		filled, _ := NewReader(buf.Bytes())
+		if got, _ := filled.FieldValues(); !reflect.DeepEqual(got, want) {
+			t.Errorf("form filled by %s: got %q, want %q", tt.what, got, want)
+		}
+	}
+
+	values, err := XFDFValues([]byte(`<?xml version="1.0"?>
+<xfdf><fields><field name="a"><field name="b"><value>1</value></field></field>
+<field name="c"/></fields></xfdf>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, map[string]string{"a.b": "1"}) {
+		t.Errorf("values of XFDF: got %q", values)
+	}
+	if _, err := XFDFValues([]byte("<xfdf>")); err == nil {
+		t.Error("no error for bad XFDF")
+	}
+	if _, err := FDFValues(testForm(t)); err == nil {
+		t.Error("no error for PDF passed as FDF")
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains JavaScript actions of form fields, which viewers run when
+// the user types in a field, when the value of a field is shown, checked, or
+// calculated from other fields (p. 648).
+//
+// The helpers use the functions of the AForm script that comes with Adobe
+// viewers, which most other viewers support too.
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// FieldScripts holds the scripts of a field. Each of them is run by the
+// viewer at a certain time.
+type FieldScripts struct {
+	// Keystroke runs when the user types in the field, and can reject or
+	// change what is typed.
+	Keystroke JavaScript
+	// Format runs before the value of the field is shown, and can change
+	// how it looks.
+	Format JavaScript
+	// Validate runs when the value of the field changes, and can reject
+	// the new value.
+	Validate JavaScript
+	// Calculate runs when the value of another field changes, to
+	// recalculate the value of this field. Fields are calculated in the
+	// order they are added to the document.
+	Calculate JavaScript
+}
+
+// setAA adds the additional-actions dictionary of s to field f, if s has any
+// scripts.
+func (s *FieldScripts) setAA(f map[string]interface{}) {
+	aa := map[string]interface{}{}
+	for k, j := range map[string]JavaScript{
+		"K": s.Keystroke,
+		"F": s.Format,
+		"V": s.Validate,
+		"C": s.Calculate,
+	} {
+		if j != "" {
+			aa[k] = j
+		}
+	}
+	if len(aa) > 0 {
+		f["AA"] = aa
+	}
+}
+
+// addScripted adds field f, which has the scripts s, to the current page and
+// to the form, and returns it. Fields with a calculation script are kept in
+// the calculation order of the form.
+func (d *Document) addScripted(f map[string]interface{}, s *FieldScripts) *indirect {
+	s.setAA(f)
+	i := d.addField(f)
+	if s.Calculate != "" {
+		d.calcs = append(d.calcs, i)
+	}
+	return i
+}
+
+// jsString returns s as a JavaScript string literal.
+func jsString(s string) string {
+	buf := bytes.NewBufferString(`"`)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteString(`"`)
+	return buf.String()
+}
+
+// NumberFormat returns the scripts of a field that accepts only numbers and
+// shows them with the given number of decimals and with commas between
+// thousands, like 1,234.50. If currency isn't empty, it's put before the
+// numbers.
+func NumberFormat(decimals int, currency string) FieldScripts {
+	args := fmt.Sprintf("%d, 0, 0, 0, %s, true", decimals, jsString(currency))
+	return FieldScripts{
+		Keystroke: JavaScript("AFNumber_Keystroke(" + args + ");"),
+		Format:    JavaScript("AFNumber_Format(" + args + ");"),
+	}
+}
+
+// PercentFormat returns the scripts of a field that accepts only numbers and
+// shows them as percents with the given number of decimals. 0.25 is shown as
+// 25%.
+func PercentFormat(decimals int) FieldScripts {
+	args := fmt.Sprintf("%d, 0", decimals)
+	return FieldScripts{
+		Keystroke: JavaScript("AFPercent_Keystroke(" + args + ");"),
+		Format:    JavaScript("AFPercent_Format(" + args + ");"),
+	}
+}
+
+// DateFormat returns the scripts of a field that accepts only dates in the
+// given format, like "yyyy-mm-dd" or "dd/mm/yy HH:MM".
+func DateFormat(format string) FieldScripts {
+	f := jsString(format)
+	return FieldScripts{
+		Keystroke: JavaScript("AFDate_KeystrokeEx(" + f + ");"),
+		Format:    JavaScript("AFDate_FormatEx(" + f + ");"),
+	}
+}
+
+// RangeValidate returns a validation script that accepts only numbers between
+// min and max.
+func RangeValidate(min, max float64) JavaScript {
+	return JavaScript("AFRange_Validate(true, " + strconv.FormatFloat(min, 'f', -1, 64) +
+		", true, " + strconv.FormatFloat(max, 'f', -1, 64) + ");")
+}
+
+// Sum returns a calculation script that sets the value of the field to the
+// sum of the values of the given fields.
+func Sum(fields ...string) JavaScript {
+	buf := bytes.NewBufferString(`AFSimple_Calculate("SUM", new Array(`)
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(jsString(f))
+	}
+	buf.WriteString("));")
+	return JavaScript(buf.String())
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+type scriptTest struct {
+	name string
+	in   JavaScript
+	out  string
+}
+
+func TestScriptHelpers(t *testing.T) {
+	n := NumberFormat(2, "$")
+	d := DateFormat(`dd "mm" yyyy`)
+	p := PercentFormat(1)
+	tests := []scriptTest{
+		{"number keystroke", n.Keystroke, `AFNumber_Keystroke(2, 0, 0, 0, "$", true);`},
+		{"number format", n.Format, `AFNumber_Format(2, 0, 0, 0, "$", true);`},
+		{"date format", d.Format, `AFDate_FormatEx("dd \"mm\" yyyy");`},
+		{"percent keystroke", p.Keystroke, `AFPercent_Keystroke(1, 0);`},
+		{"range", RangeValidate(-1, 2.5), `AFRange_Validate(true, -1, true, 2.5);`},
+		{"sum", Sum("a", "b.c"), `AFSimple_Calculate("SUM", new Array("a", "b.c"));`},
+	}
+
+	for _, test := range tests {
+		if string(test.in) != test.out {
+			t.Errorf("%s: got %s expected %s", test.name, test.in, test.out)
+		}
+	}
+}
+
+func TestFieldScripts(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, err := New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(300, 300)
+	d.TextBox(10, 10, 100, 20, &TextField{Name: "a", FieldScripts: NumberFormat(0, "")})
+	total := &TextField{Name: "total", FieldScripts: NumberFormat(0, "")}
+	total.Calculate = Sum("a")
+	total.Validate = RangeValidate(0, 100)
+	d.TextBox(10, 40, 100, 20, total)
+	if err = d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"/AA <<\n/C <<\n/JS (AFSimple_Calculate",
+		"/F <<\n/JS (AFNumber_Format",
+		"/V <<\n/JS (AFRange_Validate",
+		"/CO [ ",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("document: %q not found", s)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file deals with files attached to documents (p. 184). Attached files
+// are also associated files of the document (PDF/A-3), so that electronic
+// invoices can carry their machine-readable XML along. Files can be associated
+// with pages, XObjects, images and annotations too, like the data of a chart.
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Relationships of attached and associated files to the document, or to
+// what they are associated with.
+const (
+	AFSource      = "Source"      // the original the document was made from
+	AFData        = "Data"        // data shown in the document, like a table
+	AFAlternative = "Alternative" // another representation of the document
+	AFSupplement  = "Supplement"  // more of what the document shows
+	AFUnspecified = "Unspecified"
+)
+
+// File is a file to be attached to a document.
+type File struct {
+	Name         string // name of the file, like "invoice.xml"
+	Description  string // optional
+	MIMEType     string // like "text/xml"; needed by PDF/A
+	Data         []byte
+	Reader       io.Reader // read for the data if Data is nil; streamed when the document can seek
+	Relationship string    // relationship to what it's associated with; AFUnspecified if empty
+	Modified     time.Time // modification time; now if zero
+}
+
+// attachment is a file attached to the document.
+type attachment struct {
+	name string
+	spec *indirect // file specification
+}
+
+// AttachFile attaches f to the document. Viewers list attached files by
+// their names, which should be unique.
+func (d *Document) AttachFile(f *File) (err error) {
+	defer dontPanic(&err)
+
+	d.attach(f)
+	return nil
+}
+
+// attach writes f and its file specification to the output.
+func (d *Document) attach(f *File) {
+	if f.Name == "" {
+		panic("attached file with no name")
+	}
+	for _, a := range d.files {
+		if a.name == f.Name {
+			fail(ErrBadName, "two attached files with the same name: "+f.Name)
+		}
+	}
+	d.files = append(d.files, attachment{f.Name, d.fileSpec(f)})
+}
+
+// fileSpec writes f and its file specification to the output, and returns
+// the specification.
+func (d *Document) fileSpec(f *File) *indirect {
+	if f.Name == "" {
+		panic("file with no name")
+	}
+	rel := f.Relationship
+	if rel == "" {
+		rel = AFUnspecified
+	}
+	mod := f.Modified
+	if mod.IsZero() {
+		mod = d.now()
+	}
+
+	ef := &stream{map[string]interface{}{
+		"Type": name("EmbeddedFile"),
+	}, f.Data}
+	if f.MIMEType != "" {
+		ef.dic["Subtype"] = name(f.MIMEType)
+	}
+	var efr *indirect
+	switch {
+	case f.Data == nil && f.Reader != nil && d.seekable():
+		// The size and the checksum are known after the data is
+		// written, so the parameters are written after it too.
+		efr = d.reserveIndirect()
+		params := d.reserveIndirect()
+		ef.dic["Params"] = params
+		h := md5.New()
+		n := d.outputStreamFrom(efr, ef.dic, io.TeeReader(f.Reader, h))
+		d.outputIndirect(params, map[string]interface{}{
+			"Size":     n,
+			"ModDate":  date(mod),
+			"CheckSum": string(h.Sum(nil)),
+		})
+	default:
+		if f.Data == nil && f.Reader != nil {
+			b, err := io.ReadAll(f.Reader)
+			check(err)
+			ef.buf = b
+		}
+		ef.dic["Params"] = map[string]interface{}{
+			"Size":     len(ef.buf),
+			"ModDate":  date(mod),
+			"CheckSum": string(md5Sum(ef.buf)),
+		}
+		efr = d.indirect(ef)
+	}
+
+	spec := map[string]interface{}{
+		"Type":           name("Filespec"),
+		"F":              f.Name,
+		"UF":             textString(f.Name),
+		"EF":             map[string]interface{}{"F": efr, "UF": efr},
+		"AFRelationship": name(rel),
+	}
+	if f.Description != "" {
+		spec["Desc"] = textString(f.Description)
+	}
+	return d.indirect(spec)
+}
+
+// AssociateFile associates f with the XObject being made, if BeginXObject was
+// called, or else with the current page, like the source data of a chart drawn
+// on it. f is not listed among the attached files of the document.
+func (d *Document) AssociateFile(f *File) (err error) {
+	defer dontPanic(&err)
+
+	if d.xbox != nil {
+		d.xaf = append(d.xaf, d.fileSpec(f))
+		return nil
+	}
+	if d.pg == nil {
+		fail(ErrNoPage, "file associated before any page was started")
+	}
+	d.pg.af = append(d.pg.af, d.fileSpec(f))
+	return nil
+}
+
+// AssociateFile associates f with p, like the AssociateFile method of the
+// document.
+func (p *Page) AssociateFile(f *File) (err error) {
+	defer dontPanic(&err)
+
+	if p.pg.flushed {
+		panic("page is already flushed")
+	}
+	p.pg.af = append(p.pg.af, p.d.fileSpec(f))
+	return nil
+}
+
+// SetAssociatedFiles associates files with the annotations and images added
+// after it, until it's called again. Calling it with no files stops
+// associating them.
+func (d *Document) SetAssociatedFiles(files ...*File) (err error) {
+	defer dontPanic(&err)
+
+	af := make([]*indirect, len(files))
+	for i, f := range files {
+		af[i] = d.fileSpec(f)
+	}
+	if len(af) == 0 {
+		af = nil
+	}
+	d.objAF = af
+	return nil
+}
+
+// filesCatalog adds the attached files to the catalog cat, both as the
+// embedded files of its name dictionary and as its associated files.
+func (d *Document) filesCatalog(cat, names map[string]interface{}) {
+	af := make([]*indirect, len(d.files))
+	specs := make(map[string]interface{})
+	for i, a := range d.files {
+		af[i] = a.spec
+		specs[a.name] = a.spec
+	}
+	names["EmbeddedFiles"] = nameTree(specs)
+	cat["AF"] = af
+}
+
+// Profiles of Factur-X invoices, which is the same as ZUGFeRD 2.
+const (
+	FacturXMinimum  = "MINIMUM"
+	FacturXBasicWL  = "BASIC WL"
+	FacturXBasic    = "BASIC"
+	FacturXEN16931  = "EN 16931"
+	FacturXExtended = "EXTENDED"
+)
+
+// facturXNS is the XMP namespace of Factur-X.
+const facturXNS = "urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#"
+
+// AttachFacturX attaches the XML of a Factur-X (ZUGFeRD 2) invoice with the
+// given profile to the document, and adds the metadata that Factur-X needs.
+// The document should be PDF/A-3; see SetPDFA3.
+func (d *Document) AttachFacturX(invoice []byte, profile string) (err error) {
+	defer dontPanic(&err)
+
+	if !d.pdfa {
+		panic("Factur-X invoices should be PDF/A-3 documents")
+	}
+	// Profiles that are not complete invoices only add data to the
+	// document.
+	rel := AFAlternative
+	switch profile {
+	case FacturXMinimum, FacturXBasicWL:
+		rel = AFData
+	case FacturXBasic, FacturXEN16931, FacturXExtended:
+	default:
+		panic("unknown Factur-X profile: " + profile)
+	}
+	const file = "factur-x.xml"
+	d.attach(&File{
+		Name:         file,
+		Description:  "Factur-X invoice",
+		MIMEType:     "text/xml",
+		Data:         invoice,
+		Relationship: rel,
+	})
+
+	// PDF/A needs schemas of metadata not defined by XMP itself.
+	props := ""
+	for _, p := range [][2]string{
+		{"DocumentFileName", "name of the embedded XML invoice file"},
+		{"DocumentType", "INVOICE"},
+		{"Version", "version of the Factur-X XML schema"},
+		{"ConformanceLevel", "profile of the Factur-X XML invoice"},
+	} {
+		props += "<rdf:li rdf:parseType=\"Resource\">\n" +
+			"<pdfaProperty:name>" + p[0] + "</pdfaProperty:name>\n" +
+			"<pdfaProperty:valueType>Text</pdfaProperty:valueType>\n" +
+			"<pdfaProperty:category>external</pdfaProperty:category>\n" +
+			"<pdfaProperty:description>" + p[1] + "</pdfaProperty:description>\n" +
+			"</rdf:li>\n"
+	}
+	d.xmpDescs = append(d.xmpDescs,
+		"<rdf:Description rdf:about=\"\"\n"+
+			" xmlns:pdfaExtension=\"http://www.aiim.org/pdfa/ns/extension/\"\n"+
+			" xmlns:pdfaSchema=\"http://www.aiim.org/pdfa/ns/schema#\"\n"+
+			" xmlns:pdfaProperty=\"http://www.aiim.org/pdfa/ns/property#\">\n"+
+			"<pdfaExtension:schemas><rdf:Bag><rdf:li rdf:parseType=\"Resource\">\n"+
+			"<pdfaSchema:schema>Factur-X PDFA Extension Schema</pdfaSchema:schema>\n"+
+			"<pdfaSchema:namespaceURI>"+facturXNS+"</pdfaSchema:namespaceURI>\n"+
+			"<pdfaSchema:prefix>fx</pdfaSchema:prefix>\n"+
+			"<pdfaSchema:property><rdf:Seq>\n"+props+"</rdf:Seq></pdfaSchema:property>\n"+
+			"</rdf:li></rdf:Bag></pdfaExtension:schemas>\n"+
+			"</rdf:Description>\n",
+		fmt.Sprintf("<rdf:Description rdf:about=\"\" xmlns:fx=\"%s\">\n"+
+			"<fx:DocumentType>INVOICE</fx:DocumentType>\n"+
+			"<fx:DocumentFileName>%s</fx:DocumentFileName>\n"+
+			"<fx:Version>1.0</fx:Version>\n"+
+			"<fx:ConformanceLevel>%s</fx:ConformanceLevel>\n"+
+			"</rdf:Description>\n", facturXNS, file, profile))
+	return nil
+}
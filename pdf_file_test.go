@@ -0,0 +1,156 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAttachFile(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	mod := time.Date(2011, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := d.AttachFile(&File{Name: "b.txt", MIMEType: "text/plain",
+		Data: []byte("b"), Modified: mod}); err != nil {
+		t.Fatal(err)
+	}
+	d.AttachFile(&File{Name: "a.csv", Description: "Data", Data: []byte("1,2"),
+		Relationship: AFData})
+	if err := d.AttachFile(&File{Name: "a.csv"}); err == nil {
+		t.Error("two files attached with the same name")
+	}
+	if err := d.AttachFile(&File{}); err == nil {
+		t.Error("file attached with no name")
+	}
+	d.NewPage(100, 100)
+	d.Close()
+
+	b := buf.String()
+	for _, s := range []string{
+		"/Subtype /text#2Fplain", "/ModDate (D:20110102030405Z)", "/Size 1\n",
+		"/AFRelationship /Unspecified", "/AFRelationship /Data", "/Desc (Data)",
+		"/AF [ 4 0 R 6 0 R ]", "/Names [ (a.csv) 6 0 R (b.txt) 4 0 R ]",
+	} {
+		if !strings.Contains(b, s) {
+			t.Errorf("document doesn't have %q", s)
+		}
+	}
+}
+
+func TestAttachFacturX(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	if err := d.AttachFacturX([]byte("<x/>"), FacturXBasic); err == nil {
+		t.Error("Factur-X invoice attached to a document that is not PDF/A")
+	}
+
+	buf := bytes.NewBuffer(nil)
+	d, _ = New(buf)
+	d.SetPDFA3()
+	if err := d.AttachFacturX([]byte("<x/>"), "PREMIUM"); err == nil {
+		t.Error("Factur-X invoice attached with an unknown profile")
+	}
+	if err := d.AttachFacturX([]byte("<x/>"), FacturXBasicWL); err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.String()
+	for _, s := range []string{
+		"/F (factur-x.xml)", "/Subtype /text#2Fxml", "/AFRelationship /Data",
+		"<pdfaSchema:prefix>fx</pdfaSchema:prefix>",
+		"<fx:ConformanceLevel>BASIC WL</fx:ConformanceLevel>",
+	} {
+		if !strings.Contains(b, s) {
+			t.Errorf("document doesn't have %q", s)
+		}
+	}
+}
+
+func TestAssociateFile(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.AssociateFile(&File{Name: "a.csv"}); !errors.Is(err, ErrNoPage) {
+		t.Errorf("file with no page: got %v expected ErrNoPage", err)
+	}
+	p, _ := d.NewPage(100, 100)
+	csv := &File{Name: "sales.csv", MIMEType: "text/csv", Data: []byte("q,n\n1,5\n"), Relationship: AFData}
+	if err := d.AssociateFile(csv); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AssociateFile(&File{Name: "page.svg", MIMEType: "image/svg+xml", Data: []byte("<svg/>"),
+		Relationship: AFSource}); err != nil {
+		t.Fatal(err)
+	}
+	d.BeginXObject(10, 10)
+	d.AssociateFile(&File{Name: "chart.json", Data: []byte("{}")})
+	x, _ := d.EndXObject()
+	d.DrawXObject(x, 0, 0, 10, 10)
+	if err := d.SetAssociatedFiles(&File{Name: "note.txt", Data: []byte("hi"), Relationship: AFAlternative}); err != nil {
+		t.Fatal(err)
+	}
+	im, _ := d.AddImage(image.NewGray(image.Rect(0, 0, 1, 1)))
+	d.AddSound(0, 0, &Sound{Data: []byte{1}, Rate: 8000})
+	d.SetAssociatedFiles()
+	d.AddSound(30, 0, &Sound{Data: []byte{1}, Rate: 8000})
+	if err := d.SetAssociatedFiles(&File{}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("file with no name: got %v expected ErrInvalid", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := NewReader(buf.Bytes())
+	names := func(af interface{}) string {
+		var s []string
+		a, _ := r.resolve(af).([]interface{})
+		for _, f := range a {
+			spec := r.resolve(f).(map[string]interface{})
+			s = append(s, spec["F"].(string)+"/"+string(spec["AFRelationship"].(name)))
+		}
+		return strings.Join(s, " ")
+	}
+	pg := r.pages()[0].dic
+	if af := names(pg["AF"]); af != "sales.csv/Data page.svg/Source" {
+		t.Errorf("page: got %q", af)
+	}
+	xo := r.object(x.ref.num).(*stream)
+	if af := names(xo.dic["AF"]); af != "chart.json/Unspecified" {
+		t.Errorf("XObject: got %q", af)
+	}
+	if af := names(r.object(im.ref.num).(*stream).dic["AF"]); af != "note.txt/Alternative" {
+		t.Errorf("image: got %q", af)
+	}
+	annots := r.resolve(pg["Annots"]).([]interface{})
+	for i, want := range []string{"note.txt/Alternative", ""} {
+		a := r.resolve(annots[i]).(map[string]interface{})
+		if af := names(a["AF"]); af != want {
+			t.Errorf("annotation %d: got %q expected %q", i, af, want)
+		}
+	}
+	cat := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	if cat["AF"] != nil || cat["Names"] != nil {
+		t.Errorf("associated files are attached to the document")
+	}
+}
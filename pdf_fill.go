@@ -0,0 +1,521 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file fills the interactive forms of existing files, which is how
+// documents are often made from templates. Values of fields are written as an
+// incremental update, with new appearances for them.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// rfield is a terminal field of the interactive form of a parsed file.
+type rfield struct {
+	num     int                    // object number of the field
+	name    string                 // fully qualified name
+	attrs   map[string]interface{} // inheritable attributes, including inherited ones
+	widgets []int                  // object numbers of the widgets of the field
+}
+
+// inheritableField are the attributes of fields that can be inherited from
+// their parents (p. 674), with the ones of variable text (p. 677).
+var inheritableField = []string{"FT", "Ff", "V", "DA", "Q", "Opt", "MaxLen"}
+
+// fields returns the terminal fields of the interactive form of the file.
+func (r *Reader) fields() []*rfield {
+	cat, _ := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	form, _ := r.resolve(cat["AcroForm"]).(map[string]interface{})
+	if form == nil {
+		return nil
+	}
+	inh := make(map[string]interface{})
+	for _, k := range []string{"DA", "Q"} {
+		if v, ok := form[k]; ok {
+			inh[k] = v
+		}
+	}
+	var fs []*rfield
+	r.fieldTree(form["Fields"], "", inh, make(map[int]bool), &fs)
+	return fs
+}
+
+// fieldTree appends the terminal fields in kids to fs. par is the fully
+// qualified name of their parent, and inh holds the attributes they inherit.
+func (r *Reader) fieldTree(kids interface{}, par string, inh map[string]interface{}, seen map[int]bool, fs *[]*rfield) {
+	a, _ := r.resolve(kids).([]interface{})
+	for _, k := range a {
+		kr, ok := k.(ref)
+		if !ok || seen[kr.num] {
+			continue
+		}
+		seen[kr.num] = true
+		d, ok := r.object(kr.num).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		attrs := make(map[string]interface{})
+		for k, v := range inh {
+			attrs[k] = v
+		}
+		for _, k := range inheritableField {
+			if v, ok := d[k]; ok {
+				attrs[k] = v
+			}
+		}
+		fq := par
+		if t, ok := r.resolve(d["T"]).(string); ok {
+			if fq != "" {
+				fq += "."
+			}
+			fq += decodeText(t)
+		}
+
+		// Kids with no names are the widgets of the field.
+		var fkids []interface{}
+		var widgets []int
+		dkids, _ := r.resolve(d["Kids"]).([]interface{})
+		for _, kid := range dkids {
+			kd, _ := r.resolve(kid).(map[string]interface{})
+			if _, ok := kd["T"]; ok {
+				fkids = append(fkids, kid)
+			} else if wr, ok := kid.(ref); ok && kd != nil {
+				widgets = append(widgets, wr.num)
+			}
+		}
+		if len(fkids) > 0 {
+			r.fieldTree(fkids, fq, attrs, seen, fs)
+			continue
+		}
+		if len(dkids) == 0 {
+			widgets = []int{kr.num}
+		}
+		*fs = append(*fs, &rfield{kr.num, fq, attrs, widgets})
+	}
+}
+
+// FieldValues returns the values of the fields of the interactive form of the
+// file, by their fully qualified names. Values of check boxes and radio
+// buttons are the names of their states, and selected items of list boxes
+// are separated by newlines. Push buttons and signature fields are not
+// included.
+func (r *Reader) FieldValues() (values map[string]string, err error) {
+	defer dontPanic(&err)
+
+	values = make(map[string]string)
+	for _, f := range r.fields() {
+		ft, _ := r.resolve(f.attrs["FT"]).(name)
+		flags, _ := r.resolve(f.attrs["Ff"]).(int)
+		if ft == "Tx" || ft == "Ch" || ft == "Btn" && flags&buttonPush == 0 {
+			values[f.name] = r.fieldValue(f.attrs["V"])
+		}
+	}
+	return values, nil
+}
+
+// fieldValue returns the value v of a field as a string.
+func (r *Reader) fieldValue(v interface{}) string {
+	switch t := r.resolve(v).(type) {
+	case string:
+		return decodeText(t)
+	case name:
+		return string(t)
+	case []interface{}:
+		vs := make([]string, len(t))
+		for i, e := range t {
+			vs[i] = r.fieldValue(e)
+		}
+		return strings.Join(vs, "\n")
+	}
+	return ""
+}
+
+// FillForm writes the file read by r to w, with the fields of its
+// interactive form set to values, which are by the fully qualified names of
+// the fields. Values are like the ones returned by FieldValues; check boxes
+// and radio buttons are turned off by "Off". Appearances of the fields are
+// made again with the fonts of the form, and their text is in
+// WinAnsiEncoding. The changes are written as an incremental update, so
+// signatures of the file stay valid.
+func FillForm(w io.Writer, r *Reader, values map[string]string) (err error) {
+	defer dontPanic(&err)
+
+	fs := r.fields()
+	found := make(map[string]bool)
+	for _, f := range fs {
+		found[f.name] = true
+	}
+	for n := range values {
+		if !found[n] {
+			fail(ErrBadName, "no field named "+n)
+		}
+	}
+
+	// Nothing is written to w if a value can't be set.
+	buf := bytes.NewBuffer(nil)
+	fl := &filler{r: r, d: newUpdate(buf, r), changed: make(map[int]map[string]interface{})}
+	cat, _ := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	form, _ := r.resolve(cat["AcroForm"]).(map[string]interface{})
+	dr, _ := r.resolve(form["DR"]).(map[string]interface{})
+	fl.fonts, _ = r.resolve(dr["Font"]).(map[string]interface{})
+	for _, f := range fs {
+		if v, ok := values[f.name]; ok {
+			fl.fill(f, v)
+		}
+	}
+
+	nums := make([]int, 0, len(fl.changed))
+	for num := range fl.changed {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		fl.d.outputIndirect(fl.d.old(num), fl.changed[num])
+	}
+	fl.d.finishUpdate()
+	_, err = w.Write(buf.Bytes())
+	check(err)
+	return nil
+}
+
+// filler sets the values of fields of a parsed file.
+type filler struct {
+	r       *Reader
+	d       *Document                      // update of the file
+	fonts   map[string]interface{}         // fonts of the form, by name
+	changed map[int]map[string]interface{} // objects to be written again
+}
+
+// dict returns object num, which is a dictionary, to be changed and written
+// again by the update.
+func (fl *filler) dict(num int) map[string]interface{} {
+	if c, ok := fl.changed[num]; ok {
+		return c
+	}
+	if fl.d.old(num) == nil {
+		panic(fmt.Sprintf("object %d is not in the cross-reference table", num))
+	}
+	o, _ := fl.r.object(num).(map[string]interface{})
+	c := make(map[string]interface{})
+	for k, v := range o {
+		c[k] = v
+	}
+	fl.changed[num] = c
+	return c
+}
+
+// fill sets the value of field f to v.
+func (fl *filler) fill(f *rfield, v string) {
+	ft, _ := fl.r.resolve(f.attrs["FT"]).(name)
+	flags, _ := fl.r.resolve(f.attrs["Ff"]).(int)
+	switch {
+	case ft == "Tx":
+		max, _ := fl.r.resolve(f.attrs["MaxLen"]).(int)
+		n := utf8.RuneCountInString(v)
+		if max > 0 && n > max {
+			panic("value of field " + f.name + " is longer than its MaxLen")
+		}
+		fl.dict(f.num)["V"] = textString(v)
+		switch {
+		case flags&textPassword != 0:
+			fl.appear(f, []string{strings.Repeat("*", n)}, false, false, nil)
+		case flags&textMultiline != 0:
+			fl.appear(f, []string{winAnsi(v)}, true, true, nil)
+		default:
+			fl.appear(f, []string{winAnsi(v)}, false, false, nil)
+		}
+	case ft == "Btn" && flags&buttonPush == 0:
+		fl.button(f, v)
+	case ft == "Ch":
+		fl.choice(f, v, flags)
+	default:
+		panic("value of field " + f.name + " can't be set")
+	}
+}
+
+// states returns the names of the states of widget num other than Off.
+func (fl *filler) states(num int) map[string]bool {
+	w, _ := fl.r.object(num).(map[string]interface{})
+	ap, _ := fl.r.resolve(w["AP"]).(map[string]interface{})
+	n, _ := fl.r.resolve(ap["N"]).(map[string]interface{})
+	s := make(map[string]bool)
+	for k := range n {
+		if k != "Off" {
+			s[k] = true
+		}
+	}
+	return s
+}
+
+// button sets check box or radio button f to state v. The widgets that have
+// the state are turned on, and the others off.
+func (fl *filler) button(f *rfield, v string) {
+	found := v == "Off"
+	for _, wn := range f.widgets {
+		if fl.states(wn)[v] {
+			found = true
+		}
+	}
+	if !found {
+		panic(v + " is not a state of field " + f.name)
+	}
+	fl.dict(f.num)["V"] = name(v)
+	for _, wn := range f.widgets {
+		as := name("Off")
+		if fl.states(wn)[v] {
+			as = name(v)
+		}
+		fl.dict(wn)["AS"] = as
+	}
+}
+
+// options returns the export values and the text of the items of choice
+// field f.
+func (fl *filler) options(f *rfield) (values, texts []string) {
+	opt, _ := fl.r.resolve(f.attrs["Opt"]).([]interface{})
+	for _, o := range opt {
+		switch t := fl.r.resolve(o).(type) {
+		case string:
+			values = append(values, decodeText(t))
+			texts = append(texts, decodeText(t))
+		case []interface{}:
+			if len(t) == 2 {
+				v, _ := fl.r.resolve(t[0]).(string)
+				s, _ := fl.r.resolve(t[1]).(string)
+				values = append(values, decodeText(v))
+				texts = append(texts, decodeText(s))
+			}
+		}
+	}
+	return values, texts
+}
+
+// choice sets the selected items of choice field f to the ones in v, which
+// are separated by newlines.
+func (fl *filler) choice(f *rfield, v string, flags int) {
+	var sel []string
+	if v != "" {
+		sel = strings.Split(v, "\n")
+	}
+	if len(sel) > 1 && (flags&choiceMultiSelect == 0 || flags&choiceCombo != 0) {
+		panic("field " + f.name + " can't have more than one selected item")
+	}
+	values, texts := fl.options(f)
+	var ind []int
+	isSel := make(map[int]bool)
+	shown := ""
+	for _, s := range sel {
+		i := 0
+		for i < len(values) && values[i] != s {
+			i++
+		}
+		if i == len(values) {
+			if flags&choiceCombo == 0 || flags&choiceEdit == 0 {
+				panic(s + " is not an item of field " + f.name)
+			}
+			shown = s
+			continue
+		}
+		if !isSel[i] {
+			isSel[i] = true
+			ind = append(ind, i)
+		}
+		shown = texts[i]
+	}
+	sort.Ints(ind)
+
+	// Entries with null values are the same as missing ones.
+	dic := fl.dict(f.num)
+	switch len(sel) {
+	case 0:
+		dic["V"] = nil
+	case 1:
+		dic["V"] = textString(sel[0])
+	default:
+		vs := make([]string, len(sel))
+		for i, s := range sel {
+			vs[i] = textString(s)
+		}
+		dic["V"] = vs
+	}
+	dic["I"] = nil
+	if len(ind) > 0 {
+		dic["I"] = ind
+	}
+
+	if flags&choiceCombo != 0 {
+		var lines []string
+		if shown != "" {
+			lines = append(lines, winAnsi(shown))
+		}
+		fl.appear(f, lines, false, false, nil)
+		return
+	}
+	lines := make([]string, len(texts))
+	for i, t := range texts {
+		lines[i] = winAnsi(t)
+	}
+	fl.appear(f, lines, true, false, isSel)
+}
+
+// appear makes new normal appearances for the widgets of text or choice
+// field f, which show the WinAnsi encoded lines with the font of the field.
+// Lines start from the top if top is true and are centered vertically
+// otherwise, and they are broken to fit the width if wrap is true. Lines with
+// their index in sel are highlighted.
+func (fl *filler) appear(f *rfield, lines []string, top, wrap bool, sel map[int]bool) {
+	da, _ := fl.r.resolve(f.attrs["DA"]).(string)
+	fn, size := parseDA(da)
+	if fn == "" {
+		panic("field " + f.name + " has no font")
+	}
+	fo, ok := fl.fonts[string(fn)]
+	if !ok {
+		panic("font /" + string(fn) + " of field " + f.name + " is not in the resources of the form")
+	}
+	fd, _ := fl.r.resolve(fo).(map[string]interface{})
+	font := fl.r.font(fd)
+	if font.type0 {
+		panic("font of field " + f.name + " is not a simple font")
+	}
+	width := func(s string, size float64) float64 {
+		w := 0.0
+		for i := 0; i < len(s); i++ {
+			w += font.width(int(s[i]))
+		}
+		return w * size
+	}
+	align, _ := fl.r.resolve(f.attrs["Q"]).(int)
+
+	for _, wn := range f.widgets {
+		wd := fl.dict(wn)
+		box := fl.r.box(wd["Rect"])
+		if box == nil {
+			continue
+		}
+		w, h := box.urx-box.llx, box.ury-box.lly
+		sz := size
+		if sz <= 0 {
+			sz = defaultFontSize // auto size
+		}
+		if !top && sz > h-4 {
+			sz = h - 4
+		}
+		ls := lines
+		if wrap {
+			ls = nil
+			for _, l := range lines {
+				ls = append(ls, wrapText(l, w-4, func(s string) float64 { return width(s, sz) })...)
+			}
+		}
+
+		buf := bytes.NewBufferString(fl.box(wd, w, h))
+		buf.WriteString(fieldClip(w, h))
+		lh := sz * 1.15
+		for i, l := range ls {
+			y := baseline(0, h, sz)
+			if top {
+				t := h - 2 - float64(i)*lh
+				if t-lh < 0 {
+					break
+				}
+				if sel[i] {
+					buf.WriteString("0.6 0.75 0.85 rg 1 " + ftoa(t-lh) + " " +
+						ftoa(w-2) + " " + ftoa(lh) + " re f\n")
+				}
+				y = baseline(t-lh, lh, sz)
+			}
+			x := 2.0
+			switch align {
+			case AlignCenter:
+				x += (w - 4 - width(l, sz)) / 2
+			case AlignRight:
+				x += w - 4 - width(l, sz)
+			}
+			buf.WriteString(fmt.Sprint("BT ", da, " /", fn, " ", ftoa(sz), " Tf ",
+				ftoa(x), " ", ftoa(y), " Td (", escapeString(l), ") Tj ET\n"))
+		}
+		buf.WriteString("Q EMC\n")
+
+		wd["AP"] = map[string]interface{}{"N": fl.d.indirect(&stream{map[string]interface{}{
+			"Type":      name("XObject"),
+			"Subtype":   name("Form"),
+			"BBox":      newRect(0, 0, w, h),
+			"Resources": map[string]interface{}{"Font": map[string]interface{}{string(fn): fo}},
+		}, buf.Bytes()})}
+	}
+}
+
+// box returns content stream operators painting the background and the
+// border of widget wd of size w×h, with the colors of its appearance
+// characteristics.
+func (fl *filler) box(wd map[string]interface{}, w, h float64) string {
+	mk, _ := fl.r.resolve(wd["MK"]).(map[string]interface{})
+	s := ""
+	if c := fl.r.color(mk["BG"]); c != nil {
+		s += fmt.Sprint(colorOp(c, false), " 0 0 ", ftoa(w), " ", ftoa(h), " re f\n")
+	}
+	if c := fl.r.color(mk["BC"]); c != nil {
+		s += fmt.Sprint(colorOp(c, true), " 1 w 0.5 0.5 ", ftoa(w-1), " ",
+			ftoa(h-1), " re S\n")
+	}
+	return s
+}
+
+// color returns the color whose components are in array o, or nil if it's
+// not a color.
+func (r *Reader) color(o interface{}) Color {
+	a, _ := r.resolve(o).([]interface{})
+	v := make([]float64, len(a))
+	for i, e := range a {
+		var ok bool
+		if v[i], ok = r.number(e); !ok {
+			return nil
+		}
+	}
+	switch len(v) {
+	case 1:
+		return Gray(v[0])
+	case 3:
+		return RGB{v[0], v[1], v[2]}
+	case 4:
+		return CMYK{v[0], v[1], v[2], v[3]}
+	}
+	return nil
+}
+
+// parseDA returns the name and the size of the font of the default
+// appearance string da (p. 677).
+func parseDA(da string) (font name, size float64) {
+	for _, op := range operations([]byte(da)) {
+		if op.op == "Tf" && len(op.args) == 2 {
+			font, _ = op.args[0].(name)
+			switch t := op.args[1].(type) {
+			case int:
+				size = float64(t)
+			case float64:
+				size = t
+			}
+		}
+	}
+	return font, size
+}
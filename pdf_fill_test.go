@@ -0,0 +1,151 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// testForm returns a file with fields of all kinds.
+func testForm(t *testing.T) []byte {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(400, 400)
+	d.TextBox(10, 10, 100, 20, &TextField{Name: "address.street", Value: "Old"})
+	d.TextBox(10, 40, 100, 60, &TextField{Name: "notes", Multiline: true})
+	d.TextBox(10, 110, 100, 20, &TextField{Name: "code", MaxLen: 4, FieldStyle: FieldStyle{Align: AlignRight}})
+	d.CheckBox(10, 140, 20, 20, &CheckField{Name: "agree", Value: "Agreed"})
+	d.ComboBox(10, 170, 100, 20, &ChoiceField{Name: "color",
+		Options: []Option{{Value: "r", Text: "Red"}, {Value: "g", Text: "Green"}}})
+	d.ListBox(10, 200, 100, 60, &ChoiceField{Name: "sizes", MultiSelect: true,
+		Options: []Option{{Value: "S"}, {Value: "M"}, {Value: "L"}}, Selected: []string{"S"}})
+	d.PushButton(10, 270, 100, 20, &Button{Name: "ok", Caption: "OK"})
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFillForm(t *testing.T) {
+	b := testForm(t)
+	r, err := NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := r.FieldValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"address.street": "Old", "notes": "", "code": "", "agree": "Off",
+		"color": "", "sizes": "S",
+	}
+	if len(values) != len(want) {
+		t.Errorf("values before filling: got %q", values)
+	}
+	for k, v := range want {
+		if values[k] != v {
+			t.Errorf("value of %s before filling: got %q, want %q", k, values[k], v)
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	fill := map[string]string{
+		"address.street": "Ferdowsi Street",
+		"notes":          "First line\nSecond line that is long enough to be broken",
+		"code":           "ab",
+		"agree":          "Agreed",
+		"color":          "g",
+		"sizes":          "M\nL",
+	}
+	if err := FillForm(buf, r, fill); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, b) {
+		t.Error("original file is changed by the update")
+	}
+	if ps := Validate(out); len(ps) > 0 {
+		t.Errorf("problems of filled file: %v", ps)
+	}
+	r2, err := NewReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err = r2.FieldValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range fill {
+		if values[k] != v {
+			t.Errorf("value of %s after filling: got %q, want %q", k, values[k], v)
+		}
+	}
+
+	// New appearances show the values; the combo box shows the text of its
+	// selected item.
+	for _, s := range []string{"(Ferdowsi Street) Tj", "(Green) Tj", "(Second line that) Tj",
+		"/AS /Agreed", "/I [ 1 2 ]"} {
+		if !bytes.Contains(out[len(b):], []byte(s)) {
+			t.Errorf("no %q in the update", s)
+		}
+	}
+
+	// Filling the filled file adds another update.
+	buf2 := bytes.NewBuffer(nil)
+	if err := FillForm(buf2, r2, map[string]string{"agree": "Off", "sizes": ""}); err != nil {
+		t.Fatal(err)
+	}
+	r3, err := NewReader(buf2.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, _ = r3.FieldValues()
+	if values["agree"] != "Off" || values["sizes"] != "" || values["color"] != "g" {
+		t.Errorf("values after second update: got %q", values)
+	}
+	if n := strings.Count(buf2.String(), "%%EOF"); n != 3 {
+		t.Errorf("got %d ends of file, want 3", n)
+	}
+}
+
+func TestFillFormErrors(t *testing.T) {
+	r, err := NewReader(testForm(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, values := range []map[string]string{
+		{"nothing": "x"},
+		{"address": "x"},
+		{"code": "abcde"},
+		{"agree": "Yes"},
+		{"color": "b"},
+		{"color": "r\ng"},
+		{"sizes": "S\nXL"},
+		{"ok": "x"},
+	} {
+		buf := bytes.NewBuffer(nil)
+		if err := FillForm(buf, r, values); err == nil {
+			t.Errorf("no error for %q", values)
+		}
+		if buf.Len() > 0 {
+			t.Errorf("output written for %q", values)
+		}
+	}
+}
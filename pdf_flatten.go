@@ -0,0 +1,177 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file flattens existing files: appearances of their annotations and
+// form fields are drawn as the content of their pages, and the interactive
+// objects are removed, which leaves a static copy for archiving.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Flags of annotations (p. 608)
+const (
+	annotHidden = 1 << 1
+	annotNoView = 1 << 5
+)
+
+// Flatten writes a copy of the file read by r to w, with the appearances of
+// its annotations and form fields drawn on its pages, and with no
+// annotations or interactive form. Hidden annotations and the ones with no
+// appearance are dropped. Outlines and named destinations are kept, like in
+// Merge.
+func Flatten(w io.Writer, r *Reader) (err error) {
+	defer dontPanic(&err)
+
+	// The copy would be written with no encryption.
+	if r.Encrypted() {
+		panic("encrypted files can't be flattened")
+	}
+	d, err := New(w)
+	check(err)
+	c := newCopier(d, r)
+	for _, p := range r.pages() {
+		c.addPage(d.flattenPage(c, p))
+	}
+	d.copyDests(c)
+	d.mergeOutlines([]*copier{c})
+	c.flush()
+	check(d.Close())
+	return nil
+}
+
+// flattenPage returns page p of the file of c with the appearances of its
+// annotations drawn after its content, and with no annotations.
+func (d *Document) flattenPage(c *copier, p rpage) rpage {
+	r := c.r
+	dic := make(map[string]interface{})
+	for k, v := range p.dic {
+		if k != "Annots" {
+			dic[k] = v
+		}
+	}
+	res := make(map[string]interface{})
+	if old, ok := r.resolve(p.dic["Resources"]).(map[string]interface{}); ok {
+		for k, v := range old {
+			res[k] = v
+		}
+	}
+	xobjs := make(map[string]interface{})
+	if old, ok := r.resolve(res["XObject"]).(map[string]interface{}); ok {
+		for k, v := range old {
+			xobjs[k] = v
+		}
+	}
+
+	con := bytes.NewBufferString("Q\n")
+	drawn := 0
+	annots, _ := r.resolve(p.dic["Annots"]).([]interface{})
+	for i, a := range annots {
+		ap, m := r.flatAppearance(a)
+		if ap == nil {
+			continue
+		}
+		drawn++
+		n := fmt.Sprint("Flat", i)
+		for xobjs[n] != nil {
+			n += "_"
+		}
+		xobjs[n] = ap
+		fmt.Fprintf(con, "q %s %s %s %s %s %s cm /%s Do Q\n", ftoa(m[0]), ftoa(m[1]),
+			ftoa(m[2]), ftoa(m[3]), ftoa(m[4]), ftoa(m[5]), n)
+	}
+	if drawn == 0 {
+		return rpage{p.num, dic}
+	}
+	res["XObject"] = xobjs
+	dic["Resources"] = res
+
+	// The content of the page is put in q and Q, so that the appearances
+	// start from the default graphics state.
+	contents := []interface{}{d.indirect([]byte("q\n"))}
+	switch t := r.resolve(p.dic["Contents"]).(type) {
+	case *stream:
+		contents = append(contents, p.dic["Contents"])
+	case []interface{}:
+		contents = append(contents, t...)
+	}
+	dic["Contents"] = append(contents, d.indirect(con.Bytes()))
+	return rpage{p.num, dic}
+}
+
+// flatAppearance returns the reference to the appearance of annotation a
+// that is shown on the page, and the matrix that puts it in the rectangle of
+// the annotation (p. 612). The reference is nil if a is hidden or has no
+// appearance.
+func (r *Reader) flatAppearance(a interface{}) (ap interface{}, m matrix) {
+	ad, _ := r.resolve(a).(map[string]interface{})
+	flags, _ := r.resolve(ad["F"]).(int)
+	if ad == nil || flags&(annotHidden|annotNoView) != 0 {
+		return nil, m
+	}
+	aps, _ := r.resolve(ad["AP"]).(map[string]interface{})
+	n := aps["N"]
+	// Appearances with states are chosen by the appearance state.
+	if states, ok := r.resolve(n).(map[string]interface{}); ok {
+		as, _ := r.resolve(ad["AS"]).(name)
+		n = states[string(as)]
+	}
+	if _, ok := n.(ref); !ok {
+		return nil, m
+	}
+	s, ok := r.resolve(n).(*stream)
+	box := r.box(ad["Rect"])
+	if !ok || box == nil {
+		return nil, m
+	}
+	bbox := r.box(s.dic["BBox"])
+	if bbox == nil {
+		return nil, m
+	}
+
+	// The bounding box transformed by the matrix of the form is fit to the
+	// rectangle.
+	fm := identity
+	if a, ok := r.resolve(s.dic["Matrix"]).([]interface{}); ok && len(a) == 6 {
+		for i, e := range a {
+			fm[i], _ = r.number(e)
+		}
+	}
+	var t *rect
+	for _, p := range [][2]float64{{bbox.llx, bbox.lly}, {bbox.urx, bbox.lly},
+		{bbox.llx, bbox.ury}, {bbox.urx, bbox.ury}} {
+		x := fm[0]*p[0] + fm[2]*p[1] + fm[4]
+		y := fm[1]*p[0] + fm[3]*p[1] + fm[5]
+		if t == nil {
+			t = newRect(x, y, x, y)
+			continue
+		}
+		t = newRect(math.Min(t.llx, x), math.Min(t.lly, y), math.Max(t.urx, x), math.Max(t.ury, y))
+	}
+	sx, sy := 1.0, 1.0
+	if t.urx > t.llx {
+		sx = (box.urx - box.llx) / (t.urx - t.llx)
+	}
+	if t.ury > t.lly {
+		sy = (box.ury - box.lly) / (t.ury - t.lly)
+	}
+	return n, matrix{sx, 0, 0, sy, box.llx - t.llx*sx, box.lly - t.lly*sy}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	r, err := NewReader(testForm(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filled := bytes.NewBuffer(nil)
+	if err := FillForm(filled, r, map[string]string{"address.street": "Ferdowsi Street", "color": "g"}); err != nil {
+		t.Fatal(err)
+	}
+	r, err = NewReader(filled.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := Flatten(buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if ps := Validate(buf.Bytes()); len(ps) > 0 {
+		t.Errorf("problems of flattened file: %v", ps)
+	}
+	f, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, _ := f.resolve(f.trailer["Root"]).(map[string]interface{})
+	if cat["AcroForm"] != nil {
+		t.Error("flattened file has a form")
+	}
+	pgs := f.pages()
+	if len(pgs) != 1 || pgs[0].dic["Annots"] != nil {
+		t.Fatalf("annotations of flattened pages: got %v", pgs)
+	}
+	runs, err := f.PageText(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text []string
+	for _, run := range runs {
+		text = append(text, run.Text)
+	}
+	got := strings.Join(text, "|")
+	if got != "Ferdowsi Street|Green|S|M|L|OK" {
+		t.Errorf("text of flattened page: got %q", got)
+	}
+}
+
+func TestFlattenAppearances(t *testing.T) {
+	form := func(dic, c string) string {
+		return fmt.Sprintf("<< /Type /XObject /Subtype /Form %s /Length %d >>\nstream\n%s\nendstream", dic, len(c), c)
+	}
+	r, err := NewReader(testFile([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [ 3 0 R ] /Count 1 /MediaBox [ 0 0 100 100 ] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [ 4 0 R ] /Resources << /XObject << /Flat0 5 0 R >> >> " +
+			"/Annots [ 6 0 R 7 0 R 8 0 R 9 0 R ] >>",
+		"<< /Length 12 >>\nstream\n1 0 0 RG 2 w\nendstream",
+		form("/BBox [ 0 0 1 1 ]", "0 0 1 1 re f"),
+		"<< /Type /Annot /Subtype /Square /Rect [ 10 10 30 20 ] /AP << /N 5 0 R >> >>",
+		"<< /Type /Annot /Subtype /Square /Rect [ 10 10 30 20 ] /F 2 /AP << /N 5 0 R >> >>",
+		"<< /Type /Annot /Subtype /Widget /Rect [ 50 50 60 70 ] /AS /On /AP << /N << /On 10 0 R /Off 5 0 R >> >> >>",
+		"<< /Type /Annot /Subtype /Link /Rect [ 0 0 10 10 ] >>",
+		form("/BBox [ 0 0 20 10 ] /Matrix [ 0 1 -1 0 0 0 ]", "0 0 20 10 re f"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := Flatten(buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if ps := Validate(buf.Bytes()); len(ps) > 0 {
+		t.Errorf("problems of flattened file: %v", ps)
+	}
+	f, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := f.pages()[0]
+	con := string(f.contents(p.dic["Contents"]))
+	want := "q\n\n1 0 0 RG 2 w\nQ\n" +
+		"q 20 0 0 10 10 10 cm /Flat0_ Do Q\n" +
+		"q 1 0 0 1 60 50 cm /Flat2 Do Q\n\n"
+	if con != want {
+		t.Errorf("content of flattened page:\ngot  %q\nwant %q", con, want)
+	}
+	res, _ := f.resolve(p.dic["Resources"]).(map[string]interface{})
+	xobjs, _ := f.resolve(res["XObject"]).(map[string]interface{})
+	if len(xobjs) != 3 {
+		t.Errorf("XObjects of flattened page: got %v", xobjs)
+	}
+}
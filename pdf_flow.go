@@ -0,0 +1,508 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file lays out content one block after another on pages, like the
+// text of a word processor. A new page is started when one is full. Pictures
+// can float at a side of the flow, with the text of paragraphs beside them.
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Margins are the space left empty at the edges of pages.
+type Margins struct {
+	Top, Right, Bottom, Left float64
+}
+
+// Block is content that can be added to a flow: a *Paragraph, a *Picture, a
+// *Table, a Spacer, a *Formula, or a component like *Letterhead and *Totals.
+type Block interface {
+	// pieces returns the parts of the block laid out in fr.
+	pieces(fr *frame) []piece
+}
+
+// frame is the room blocks are laid out in.
+type frame struct {
+	d      *Document // document of the flow
+	w, h   float64   // width of the flow and height of the room for content on pages
+	pg     *page     // page the block starts on, if it's already started
+	y      float64   // top of the room left on pg
+	floats []floater // floating pictures on pg
+	notes  int       // number of footnotes of the flow before the block
+}
+
+// floater is the room a floating picture takes beside the text of a flow.
+type floater struct {
+	right  bool    // whether it's at the right side of the flow
+	w      float64 // width of the picture and the gap beside it
+	bottom float64 // y of the bottom of the gap below the picture
+}
+
+// line returns the offset from the left of the flow and the width of the
+// room for a line of text whose top is off below the top of the room left.
+func (fr *frame) line(off float64) (x, w float64) {
+	w = fr.w
+	for _, fl := range fr.floats {
+		if fr.y-off > fl.bottom {
+			w -= fl.w
+			if !fl.right {
+				x += fl.w
+			}
+		}
+	}
+	return x, w
+}
+
+// piece is a part of a block that is not broken across pages.
+type piece struct {
+	h     float64                         // height
+	space bool                            // it's only space, which is dropped at the top of pages
+	clear bool                            // it's drawn below floating pictures
+	glue  bool                            // it's kept on the same page as the next piece that isn't space
+	head  *piece                          // drawn above the piece when it starts a page, if any
+	notes []string                        // footnotes referred to by the piece
+	draw  func(d *Document, x, y float64) // draws the piece with its top left corner at (x, y)
+}
+
+// glue keeps pieces from to to of ps, not including to, on the same page.
+func glue(ps []piece, from, to int) {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(ps) {
+		to = len(ps)
+	}
+	for i := from; i < to-1; i++ {
+		ps[i].glue = true
+	}
+}
+
+// unit returns the number of pieces at the start of ps that are kept on the
+// same page, or -1 if the last of them is kept with a piece not in ps yet.
+func unit(ps []piece) int {
+	keep := false
+	for i, p := range ps {
+		if !p.space {
+			keep = p.glue
+		}
+		if !keep {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// Flow adds blocks of content to pages of a document one below the other,
+// and starts new pages when they are full.
+type Flow struct {
+	// NoteStyle is the style of the text of footnotes. Their font size
+	// is 8 if it's not set.
+	NoteStyle TextStyle
+
+	d       *Document
+	w, h    int // size of pages
+	m       Margins
+	pg      *page     // current page of the flow, if any
+	y       float64   // top of the room left on the page
+	empty   bool      // whether nothing is drawn on the page yet
+	pending []piece   // pieces kept with pieces of blocks not added yet
+	floats  []floater // floating pictures on the page
+	nnotes  int       // number of footnotes added to f
+	notes   []string  // lines of footnotes at the bottom of the page
+	notesH  float64   // height taken by notes
+	carry   []string  // lines of footnotes waiting for room on the next page
+}
+
+// NewFlow returns a flow that adds pages of width w and height h to d, with
+// content inside margins m. Pages are added when blocks are added to the
+// flow, and the current page of d is left as it is.
+func (d *Document) NewFlow(w, h int, m Margins) (f *Flow, err error) {
+	defer dontPanic(&err)
+
+	if float64(w)-m.Left-m.Right <= 0 || float64(h)-m.Top-m.Bottom <= 0 {
+		panic("margins of flow are larger than its pages")
+	}
+	f = &Flow{d: d, w: w, h: h, m: m}
+	d.flows = append(d.flows, f)
+	return f, nil
+}
+
+// Add adds b below the content already added to f. Pages started by other
+// than f, like by NewPage of the document, are not used by f: b goes to a
+// new page then. Blocks kept with the next block are drawn when the next
+// block is added, or when the document is closed. Floating pictures are
+// drawn at once, at the top of the room left on the page.
+func (f *Flow) Add(b Block) (err error) {
+	defer dontPanic(&err)
+
+	if f.d.xbox != nil {
+		panic("Add called inside an XObject")
+	}
+	if p, ok := b.(*Picture); ok && p.Float {
+		f.flush()
+		f.float(p)
+		return nil
+	}
+	fr := f.frame()
+	f.pending = append(f.pending, b.pieces(fr)...)
+	f.nnotes = fr.notes
+	for len(f.pending) > 0 {
+		n := unit(f.pending)
+		if n < 0 {
+			break
+		}
+		f.placeUnit(f.pending[:n])
+		f.pending = f.pending[n:]
+	}
+	return nil
+}
+
+// NewPage makes the content added to f after it start on a new page.
+func (f *Flow) NewPage() (err error) {
+	defer dontPanic(&err)
+
+	f.flush()
+	f.newPage()
+	return nil
+}
+
+// flush draws the pieces of f that are waiting for the next block.
+func (f *Flow) flush() {
+	if len(f.pending) > 0 {
+		f.placeUnit(f.pending)
+		f.pending = nil
+	}
+}
+
+// width returns the width of the content of f.
+func (f *Flow) width() float64 {
+	return float64(f.w) - f.m.Left - f.m.Right
+}
+
+// height returns the height of the room for content on the pages of f.
+func (f *Flow) height() float64 {
+	return float64(f.h) - f.m.Top - f.m.Bottom
+}
+
+// frame returns the room the next block added to f is laid out in.
+func (f *Flow) frame() *frame {
+	fr := &frame{d: f.d, w: f.width(), h: f.height(), notes: f.nnotes}
+	if f.pg != nil && f.pg == f.d.pg {
+		fr.pg, fr.y, fr.floats = f.pg, f.y, f.floats
+		for _, p := range f.pending {
+			fr.y -= p.h
+		}
+	}
+	return fr
+}
+
+// newPage starts a new page for f.
+func (f *Flow) newPage() {
+	// Pages of flows are written to the output when the flow leaves
+	// them.
+	if f.pg != nil && !f.pg.flushed {
+		f.d.flushPage(f.pg)
+	}
+	f.pg = f.d.newPage(f.w, f.h)
+	f.y = float64(f.h) - f.m.Top
+	f.empty = true
+	f.floats = nil
+	f.takeNotes(f.y - f.height()/2)
+}
+
+// clear moves the top of the room left on the page of f below the floating
+// pictures on it.
+func (f *Flow) clear() {
+	for _, fl := range f.floats {
+		if fl.bottom < f.y {
+			f.y = fl.bottom
+		}
+	}
+	f.floats = nil
+}
+
+// float draws the floating picture p at the top of the room left on the page
+// of f, beside the floating pictures already there if there's room for it.
+func (f *Flow) float(p *Picture) {
+	if p.Align != AlignLeft && p.Align != AlignRight {
+		panic("floating pictures should be aligned to the left or right")
+	}
+	pw, ph := p.size(f.width(), f.height())
+	if _, w := f.frame().line(0); pw > w {
+		f.clear()
+	}
+	f.room(ph, piece{})
+	x, w := f.frame().line(0)
+	if p.Align == AlignRight {
+		x += w - pw
+	}
+	check(f.d.DrawXObject(p.XObject, f.m.Left+x, f.y-ph, pw, ph))
+	f.floats = append(f.floats, floater{p.Align == AlignRight, pw + p.Gap, f.y - ph - p.Gap})
+	f.empty = false
+}
+
+// room starts a new page for f if there's no room for content of height h
+// on the current one. p is the first piece of the content.
+func (f *Flow) room(h float64, p piece) {
+	if f.pg == nil || f.pg != f.d.pg {
+		f.newPage()
+		return
+	}
+	if f.y-h < f.m.Bottom+f.notesH && !f.empty {
+		f.newPage()
+		if p.head != nil && !p.space {
+			f.draw(*p.head)
+		}
+	}
+}
+
+// placeUnit draws pieces ps, which are kept on the same page unless they
+// are higher than pages.
+func (f *Flow) placeUnit(ps []piece) {
+	if len(ps) > 1 {
+		h := 0.0
+		for _, p := range ps {
+			h += p.h + f.noteRoom(p)
+		}
+		if h <= f.height() {
+			f.room(h, ps[0])
+		}
+	}
+	for _, p := range ps {
+		f.place(p)
+	}
+}
+
+// place draws p below the content of f, on a new page if there's no room
+// for it. Pieces higher than pages are drawn on pages of their own.
+func (f *Flow) place(p piece) {
+	if p.clear {
+		f.clear()
+	}
+	f.room(p.h+f.noteRoom(p), p)
+	if p.space && f.empty {
+		return
+	}
+	f.draw(p)
+	f.addNotes(p.notes)
+}
+
+// draw draws p at the top of the room left on the page of f.
+func (f *Flow) draw(p piece) {
+	if p.draw != nil {
+		p.draw(f.d, f.m.Left, f.y)
+	}
+	f.y -= p.h
+	if !p.space {
+		f.empty = false
+	}
+}
+
+// Paragraph is text broken into lines as wide as the flow it's added to, or
+// narrower beside floating pictures.
+type Paragraph struct {
+	// Text can have markers of footnotes, like "[^1]" for the first of
+	// Notes, which are replaced by the numbers of the notes in the flow.
+	Text        string
+	Notes       []string // texts of footnotes
+	Heading     int      // level of the paragraph as a heading; not a heading if zero
+	IndexTerms  []string // terms of the index on the paragraph
+	Style       TextStyle
+	SpaceBefore float64 // space above the paragraph
+	SpaceAfter  float64 // space below the paragraph
+
+	// KeepTogether keeps the lines of the paragraph on the same page, and
+	// KeepWithNext keeps its last line on the same page as the next block,
+	// like for headings.
+	KeepTogether, KeepWithNext bool
+	// Orphans is the least number of lines left at the bottom of a page
+	// when the paragraph is broken across pages, and Widows is the least
+	// number of lines moved to the next page. There's no least number if
+	// they are zero.
+	Orphans, Widows int
+}
+
+func (p *Paragraph) pieces(fr *frame) []piece {
+	style := p.Style
+	size, lh := style.size(), style.leading()
+	before := math.Max(p.SpaceBefore, 0)
+	top := func(i int) float64 {
+		return before + float64(i)*lh
+	}
+	text, notes := p.footnotes(fr)
+	var lines []piece
+	// Text is encoded before it's wrapped, as emoji are wider than the
+	// characters they replace.
+	for i, l := range wrapLines(fr.d.emojiText(text), func(i int) float64 {
+		_, w := fr.line(top(i))
+		return w
+	}, func(l string) float64 {
+		return style.width(l, size)
+	}) {
+		l, first := l, i == 0
+		dx, w := fr.line(top(i))
+		lines = append(lines, piece{h: lh, notes: lineNotes(l, notes), draw: func(d *Document, x, y float64) {
+			// Lines moved to the next page are not beside the
+			// pictures anymore.
+			if d.pg == fr.pg {
+				x += dx
+			}
+			if first {
+				if p.Heading > 0 {
+					check(d.AddHeading(p.Text, p.Heading, y))
+				}
+				for _, t := range p.IndexTerms {
+					check(d.AddIndexTerm(t, y))
+				}
+			}
+			d.pageFont(style.font())
+			if strings.Contains(l, emojiMark) {
+				d.pageEmoji()
+			}
+			d.addc(strings.TrimRight(style.line(l, x, baseline(y-lh, lh, size), w, size), "\n"))
+		}})
+	}
+	n := len(lines)
+	glue(lines, 0, p.Orphans)
+	glue(lines, n-p.Widows, n)
+	if p.KeepTogether {
+		glue(lines, 0, n)
+	}
+	lines[n-1].glue = p.KeepWithNext
+
+	var ps []piece
+	if p.SpaceBefore > 0 {
+		ps = append(ps, piece{h: p.SpaceBefore, space: true})
+	}
+	ps = append(ps, lines...)
+	if p.SpaceAfter > 0 {
+		ps = append(ps, piece{h: p.SpaceAfter, space: true})
+	}
+	return ps
+}
+
+// Picture is an XObject, like an image added by AddImage, drawn in a flow.
+// Pictures are drawn in their own size if Width and Height are both zero,
+// and keep their aspect ratio if one of them is zero. They are made smaller
+// if they don't fit in the flow.
+//
+// Floating pictures are drawn at the side of the flow given by Align, and
+// paragraphs added after them wrap around them. Other blocks are drawn below
+// them.
+type Picture struct {
+	XObject       *XObject
+	Width, Height float64
+	Align         int     // AlignLeft, AlignCenter or AlignRight
+	KeepWithNext  bool    // keep the picture on the same page as the next block
+	Float         bool    // let paragraphs wrap around the picture
+	Gap           float64 // space between a floating picture and the text beside and below it
+}
+
+// size returns the size of p drawn in a flow of width w with pages that have
+// room for content of height h.
+func (p *Picture) size(w, h float64) (float64, float64) {
+	if p.XObject == nil {
+		panic("picture with no XObject")
+	}
+	xw, xh := p.XObject.Size()
+	pw, ph := p.Width, p.Height
+	switch {
+	case pw <= 0 && ph <= 0:
+		pw, ph = xw, xh
+	case pw <= 0:
+		pw = ph * xw / xh
+	case ph <= 0:
+		ph = pw * xh / xw
+	}
+	if pw > w {
+		pw, ph = w, ph*w/pw
+	}
+	if ph > h {
+		pw, ph = pw*h/ph, h
+	}
+	return pw, ph
+}
+
+func (p *Picture) pieces(fr *frame) []piece {
+	x, w := p.XObject, fr.w
+	pw, ph := p.size(w, fr.h)
+	off := 0.0
+	switch p.Align {
+	case AlignCenter:
+		off = (w - pw) / 2
+	case AlignRight:
+		off = w - pw
+	}
+	return []piece{{h: ph, clear: true, glue: p.KeepWithNext, draw: func(d *Document, px, py float64) {
+		check(d.DrawXObject(x, px+off, py-ph, pw, ph))
+	}}}
+}
+
+// Spacer is empty space of the given height in a flow. Like the space
+// around paragraphs, it's dropped at the top of pages.
+type Spacer float64
+
+func (s Spacer) pieces(fr *frame) []piece {
+	return []piece{{h: float64(s), space: true}}
+}
+
+// pieces returns the rows of t as pieces. The header rows are drawn with the
+// first rows after them, and again above the rows that start pages.
+func (t *Table) pieces(fr *frame) []piece {
+	l, h := t.layout(fr.w), fr.h
+	var ps []piece
+	var head *piece
+	if t.HeaderRows > 0 {
+		head = &piece{h: l.rowsHeight(0, t.HeaderRows), draw: func(d *Document, x, y float64) {
+			d.addc(l.draw(0, t.HeaderRows, x, y, d.pageFont))
+		}}
+	}
+	for k := 0; k+1 < len(l.blocks); k++ {
+		from, to := l.blocks[k], l.blocks[k+1]
+		if from < t.HeaderRows {
+			continue
+		}
+		p := piece{h: l.rowsHeight(from, to), clear: true, head: head, draw: func(d *Document, x, y float64) {
+			d.addc(l.draw(from, to, x, y, d.pageFont))
+		}}
+		if head != nil && p.h+head.h > h || p.h > h {
+			panic(fmt.Sprintf("row %d of table is too high for the page", from+1))
+		}
+		if from == t.HeaderRows && head != nil {
+			// Header rows are not left alone at the bottom of pages.
+			p = piece{h: head.h + p.h, clear: true, head: head, draw: func(d *Document, x, y float64) {
+				head.draw(d, x, y)
+				d.addc(l.draw(from, to, x, y-head.h, d.pageFont))
+			}}
+		}
+		ps = append(ps, p)
+	}
+	if len(ps) == 0 && head != nil {
+		ps = append(ps, *head)
+		ps[0].clear = true
+	}
+	if t.KeepTogether {
+		glue(ps, 0, len(ps))
+	}
+	if len(ps) > 0 {
+		ps[len(ps)-1].glue = t.KeepWithNext
+	}
+	return ps
+}
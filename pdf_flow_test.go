@@ -0,0 +1,216 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFlow(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	f, err := d.NewFlow(200, 200, Margins{20, 20, 20, 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf("l%02d", i))
+	}
+	d.BeginXObject(100, 50)
+	x, _ := d.EndXObject()
+	tb := &Table{HeaderRows: 1, Style: courier}
+	tb.AddRow(&Cell{Text: "head"})
+	tb.AddRow(&Cell{Text: "body"})
+	for i, b := range []Block{
+		// 13 lines fit on a page.
+		&Paragraph{Text: strings.Join(lines, "\n"), Style: courier, SpaceBefore: 50},
+		Spacer(1000),
+		&Picture{XObject: x, Align: AlignCenter},
+		&Picture{XObject: x, Width: 500},
+		&Paragraph{Text: "right", Style: TextStyle{Font: FontCourier, FontSize: 10, Align: AlignRight}},
+		Spacer(40),
+		tb,
+	} {
+		if err := f.Add(b); err != nil {
+			t.Fatalf("%d. %s", i, err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"q 1 0 0 1 50 130 cm /Fm",
+		"q 1.6 0 0 1.6 20 50 cm /Fm",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("%q not found in the output", s)
+		}
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := r.NumPages(); n != 4 {
+		t.Fatalf("number of pages: got %d, want 4", n)
+	}
+	for i, want := range []string{
+		"20 171.45 l00|20 33.45 l12",
+		"20 171.45 l13|20 102.45 l19",
+		// The spacer doesn't fit on the page, and is dropped.
+		"150 41.45 right",
+		"20 171.45 head|20 159.95 body",
+	} {
+		runs, err := r.PageText(i + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for j, run := range runs {
+			if j == 0 || j == len(runs)-1 || len(runs) == 3 {
+				got = append(got, fmt.Sprint(ftoa(run.X), " ", ftoa(run.Y), " ", run.Text))
+			}
+		}
+		if g := strings.Join(got, "|"); g != want {
+			t.Errorf("text of page %d: got %q, want %q", i+1, g, want)
+		}
+	}
+}
+
+func TestFlowErrors(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	if _, err := d.NewFlow(100, 100, Margins{Left: 60, Right: 40}); err == nil {
+		t.Error("no error for margins larger than pages")
+	}
+	f, _ := d.NewFlow(100, 100, Margins{})
+	if err := f.Add(&Picture{}); fmt.Sprint(err) != "pdf.go: picture with no XObject" {
+		t.Errorf("picture with no XObject: got error %v", err)
+	}
+	tb := &Table{Style: courier}
+	tb.AddRow(&Cell{Text: strings.Repeat("x\n", 10)})
+	if err := f.Add(tb); fmt.Sprint(err) != "pdf.go: row 1 of table is too high for the page" {
+		t.Errorf("high row: got error %v", err)
+	}
+}
+
+func TestFlowFloat(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	f, _ := d.NewFlow(200, 200, Margins{20, 20, 20, 20})
+	d.BeginXObject(50, 50)
+	x, _ := d.EndXObject()
+	tb := &Table{Style: courier}
+	tb.AddRow(&Cell{Text: "table"})
+	for i, b := range []Block{
+		// Six lines are beside the picture, which is 60 wide with the
+		// gap.
+		&Picture{XObject: x, Float: true, Gap: 10},
+		&Paragraph{Text: strings.TrimSpace(strings.Repeat("xxx ", 40)), Style: courier},
+		&Picture{XObject: x, Width: 30, Float: true, Align: AlignRight},
+		tb,
+	} {
+		if err := f.Add(b); err != nil {
+			t.Fatalf("%d. %s", i, err)
+		}
+	}
+	if err := f.Add(&Picture{XObject: x, Float: true, Align: AlignCenter}); err == nil {
+		t.Error("no error for a floating picture in the center")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"q 1 0 0 1 20 130 cm /Fm",
+		"q 0.6 0 0 0.6 150 46.5 cm /Fm",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("%q not found in the output", s)
+		}
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, _ := r.PageText(1)
+	var got []string
+	for _, run := range runs {
+		got = append(got, fmt.Sprint(ftoa(run.X), " ", ftoa(run.Y), " ", len(run.Text)))
+	}
+	want := "80 171.45 15|80 159.95 15|80 148.45 15|80 136.95 15|80 125.45 15|" +
+		"80 113.95 15|20 102.45 23|20 90.95 23|20 79.45 15|20 37.95 5"
+	if g := strings.Join(got, "|"); g != want {
+		t.Errorf("text: got %q, want %q", g, want)
+	}
+}
+
+// lines returns a paragraph of n lines in Courier of size 10, which are 11.5
+// high.
+func lines(n int) *Paragraph {
+	return &Paragraph{Text: strings.TrimSpace(strings.Repeat("x\n", n)), Style: courier}
+}
+
+type keepTest struct {
+	blocks []Block
+	pages  string // number of lines on each page
+}
+
+var keepTests = []keepTest{
+	{[]Block{lines(11), lines(4)}, "13 2"},
+	{[]Block{lines(11), &Paragraph{Text: "x\nx\nx\nx", Style: courier, Orphans: 3}}, "11 4"},
+	{[]Block{lines(11), &Paragraph{Text: "x\nx\nx\nx", Style: courier, Widows: 3}}, "12 3"},
+	{[]Block{lines(11), &Paragraph{Text: "x\nx\nx\nx", Style: courier, Orphans: 2, Widows: 2}}, "13 2"},
+	{[]Block{lines(11), &Paragraph{Text: "x\nx\nx\nx", Style: courier, KeepTogether: true}}, "11 4"},
+	{[]Block{lines(12), &Paragraph{Text: "x", Style: courier, KeepWithNext: true, SpaceAfter: 5}, lines(3)}, "12 4"},
+	{[]Block{lines(12), &Paragraph{Text: "x", Style: courier, KeepWithNext: true}}, "13"},
+	{[]Block{lines(30), &Paragraph{Text: "x", Style: courier, KeepTogether: true}}, "13 13 5"},
+	{[]Block{lines(11), &Table{Style: courier, KeepTogether: true,
+		rows: [][]*Cell{{{Text: "x"}}, {{Text: "x"}}, {{Text: "x"}}}}}, "11 3"},
+}
+
+func TestFlowKeep(t *testing.T) {
+	for i, tt := range keepTests {
+		buf := bytes.NewBuffer(nil)
+		d, _ := New(buf)
+		f, _ := d.NewFlow(200, 200, Margins{20, 20, 20, 20})
+		for _, b := range tt.blocks {
+			if err := f.Add(b); err != nil {
+				t.Fatalf("%d. %s", i, err)
+			}
+		}
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := NewReader(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := r.NumPages()
+		var pages []string
+		for p := 1; p <= n; p++ {
+			runs, _ := r.PageText(p)
+			pages = append(pages, fmt.Sprint(len(runs)))
+		}
+		if got := strings.Join(pages, " "); got != tt.pages {
+			t.Errorf("%d. lines on pages: got %s, want %s", i, got, tt.pages)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file lays out footnotes of paragraphs of flows at the bottom of
+// pages. Notes that don't fit on the page of their markers continue on the
+// next pages.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// noteSep is the space above the footnotes of a page, with a short line in
+// the middle of it.
+const noteSep = 8
+
+// footnotes returns the text of p with the markers of its footnotes replaced
+// by the numbers of the notes in the flow, as superscripts, and the texts of
+// the notes by their numbers. The numbers of notes start after fr.notes,
+// which is updated.
+func (p *Paragraph) footnotes(fr *frame) (string, map[string]string) {
+	notes := make(map[string]string)
+	text := p.Text
+	for i := 0; ; {
+		j := strings.Index(text[i:], "[^")
+		if j < 0 {
+			break
+		}
+		j += i
+		k := strings.Index(text[j:], "]")
+		n, err := 0, error(nil)
+		if k >= 0 {
+			n, err = strconv.Atoi(text[j+2 : j+k])
+		}
+		if k < 0 || err != nil {
+			// Not a marker
+			i = j + 2
+			continue
+		}
+		if n < 1 || n > len(p.Notes) {
+			panic(fmt.Sprintf("paragraph has no footnote %d", n))
+		}
+		fr.notes++
+		num := fmt.Sprint(fr.notes)
+		notes[num] = supStart + num + supEnd + " " + p.Notes[n-1]
+		mark := supStart + num + supEnd
+		text = text[:j] + mark + text[j+k+1:]
+		i = j + len(mark)
+	}
+	return text, notes
+}
+
+// lineNotes returns the texts of the footnotes whose markers are in the
+// WinAnsi encoded line l, with notes from their numbers to their texts.
+func lineNotes(l string, notes map[string]string) []string {
+	var ns []string
+	for _, sup, after, ok := superscript(l); ok; _, sup, after, ok = superscript(after) {
+		if n, ok := notes[sup]; ok {
+			ns = append(ns, n)
+		}
+	}
+	return ns
+}
+
+// noteStyle returns the style of the footnotes of f.
+func (f *Flow) noteStyle() *TextStyle {
+	s := f.NoteStyle
+	if s.FontSize <= 0 {
+		s.FontSize = 8
+	}
+	return &s
+}
+
+// noteRoom returns the room needed at the bottom of the page for the
+// footnotes of p to start on the page of its markers.
+func (f *Flow) noteRoom(p piece) float64 {
+	if len(p.notes) == 0 || len(f.carry) > 0 {
+		return 0
+	}
+	h := f.noteStyle().leading()
+	if len(f.notes) == 0 {
+		h += noteSep
+	}
+	return h
+}
+
+// addNotes adds the texts of footnotes notes to the bottom of the page of
+// f, or to the next pages if they don't fit.
+func (f *Flow) addNotes(notes []string) {
+	for _, n := range notes {
+		f.carry = append(f.carry, f.noteStyle().wrap(n, f.width())...)
+	}
+	f.takeNotes(f.y)
+}
+
+// takeNotes moves lines of footnotes waiting for room to the bottom of the
+// page of f, as long as they stay below top. The first line on a page is
+// moved even if it doesn't.
+func (f *Flow) takeNotes(top float64) {
+	lh := f.noteStyle().leading()
+	for len(f.carry) > 0 {
+		h := lh
+		if len(f.notes) == 0 {
+			h += noteSep
+		}
+		if len(f.notes) > 0 && f.m.Bottom+f.notesH+h > top {
+			break
+		}
+		f.notes = append(f.notes, f.carry[0])
+		f.carry = f.carry[1:]
+		f.notesH += h
+	}
+}
+
+// endPage draws the footnotes of the page of f at its bottom, before the
+// page is written to the output.
+func (f *Flow) endPage() {
+	if len(f.notes) == 0 {
+		return
+	}
+	st := f.noteStyle()
+	size, lh := st.size(), st.leading()
+	x, y := f.m.Left, f.m.Bottom+f.notesH
+	f.d.addc(fmt.Sprint("q ", colorOp(st.color(), true), " 0.5 w ", ftoa(x), " ",
+		ftoa(y-noteSep/2), " m ", ftoa(x+f.width()/3), " ", ftoa(y-noteSep/2), " l S Q"))
+	f.d.pageFont(st.font())
+	y -= noteSep
+	for _, l := range f.notes {
+		f.d.addc(strings.TrimRight(st.line(l, x, baseline(y-lh, lh, size), f.width(), size), "\n"))
+		y -= lh
+	}
+	f.notes, f.notesH = nil, 0
+}
+
+// finish draws the pieces of f that are waiting for the next block, and the
+// footnotes waiting for room on new pages.
+func (f *Flow) finish() {
+	f.flush()
+	for len(f.carry) > 0 {
+		f.newPage()
+	}
+}
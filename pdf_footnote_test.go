@@ -0,0 +1,71 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFootnotes(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	f, _ := d.NewFlow(200, 200, Margins{20, 20, 20, 20})
+	f.NoteStyle = TextStyle{Font: FontCourier}
+	// Only two lines of the first note fit below the paragraph.
+	for i, b := range []Block{
+		lines(10),
+		&Paragraph{Text: "x[^1] y[^2] [^z]", Notes: []string{"a\nb\nc\nd\ne", "z"}, Style: courier},
+	} {
+		if err := f.Add(b); err != nil {
+			t.Fatalf("%d. %s", i, err)
+		}
+	}
+	err := f.Add(&Paragraph{Text: "[^2]", Notes: []string{"a"}})
+	if fmt.Sprint(err) != "pdf.go: paragraph has no footnote 2" {
+		t.Errorf("missing footnote: got error %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s := "(x) Tj /Cour 6 Tf 3.5 Ts (1) Tj /Cour 10 Tf 0 Ts ( y) Tj /Cour 6 Tf 3.5 Ts (2) Tj /Cour 10 Tf 0 Ts ( [^z]) Tj"
+	if !bytes.Contains(buf.Bytes(), []byte(s)) {
+		t.Errorf("%q not found in the output", s)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{
+		"20 34.36 1|22.88 31.56  a|20 22.36 b",
+		"20 49.96 c|20 40.76 d|20 31.56 e|20 25.16 2|22.88 22.36  z",
+	} {
+		runs, _ := r.PageText(i + 1)
+		var got []string
+		for _, run := range runs {
+			if run.Y < 50 {
+				got = append(got, fmt.Sprint(ftoa(run.X), " ", ftoa(run.Y), " ", run.Text))
+			}
+		}
+		if g := strings.Join(got, "|"); g != want {
+			t.Errorf("notes of page %d: got %q, want %q", i+1, g, want)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the parts of interactive forms (AcroForm) shared by all
+// types of fields (p. 670). Every field of the document has exactly one widget
+// annotation, and the two are merged into one dictionary.
+//
+// Appearances of the fields are made here too, so that they look the same in
+// all viewers, including the ones that don't make appearances themselves.
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// defaultFontSize is used for fields with no font size set.
+const defaultFontSize = 12
+
+// Fonts that can be used for the text of fields. They are the names of the
+// fonts in the resources of the form.
+const (
+	FieldHelvetica = "Helv"
+	FieldCourier   = "Cour"
+)
+
+// fieldZapfDingbats is used for the check mark of check boxes.
+const fieldZapfDingbats = "ZaDb"
+
+// Alignments of text
+const (
+	AlignLeft = iota
+	AlignCenter
+	AlignRight
+)
+
+// fieldFonts holds the standard fonts that can be used for the text of
+// fields.
+var fieldFonts = map[string]bool{
+	FieldHelvetica: true,
+	FieldCourier:   true,
+}
+
+// FieldStyle holds how a field and its text look. The zero value is black
+// Helvetica of size 12, aligned to the left, on white with a gray border.
+type FieldStyle struct {
+	Font       string  // FieldHelvetica or FieldCourier
+	FontSize   float64 // 12 if zero
+	Color      Color   // color of the text; black if nil
+	Align      int     // AlignLeft, AlignCenter or AlignRight
+	Border     Color   // gray if nil
+	Background Color   // white if nil, except for buttons
+}
+
+// font returns the name of the font of s.
+func (s *FieldStyle) font() string {
+	if s.Font == "" {
+		return FieldHelvetica
+	}
+	if !fieldFonts[s.Font] {
+		panic("unknown font for field: " + s.Font)
+	}
+	return s.Font
+}
+
+// size returns the font size of s.
+func (s *FieldStyle) size() float64 {
+	if s.FontSize <= 0 {
+		return defaultFontSize
+	}
+	return s.FontSize
+}
+
+// color returns the color of the text of s.
+func (s *FieldStyle) color() Color {
+	if s.Color == nil {
+		return Gray(0)
+	}
+	return s.Color
+}
+
+// da returns the default appearance string of fields with style s.
+func (s *FieldStyle) da() string {
+	return "/" + s.font() + " " + ftoa(s.size()) + " Tf " + colorOp(s.color(), false)
+}
+
+// textStyle returns the style of the text of fields with style s.
+func (s *FieldStyle) textStyle() *TextStyle {
+	return &TextStyle{s.font(), s.size(), s.color(), s.Align}
+}
+
+// width returns the width of the WinAnsi encoded string t in the font of s
+// with the given size.
+func (s *FieldStyle) width(t string, size float64) float64 {
+	return s.textStyle().width(t, size)
+}
+
+// text returns content stream operators showing the WinAnsi encoded string t
+// with the font and color of s in the given size, starting at (x, y).
+func (s *FieldStyle) text(t string, x, y, size float64) string {
+	return s.textStyle().text(t, x, y, size)
+}
+
+// line returns content stream operators showing the WinAnsi encoded string t
+// aligned according to s in a box that starts at x and is w wide, with the
+// baseline at y.
+func (s *FieldStyle) line(t string, x, y, w, size float64) string {
+	return s.textStyle().line(t, x, y, w, size)
+}
+
+// background returns the background color of s, or def if it's not set.
+func (s *FieldStyle) background(def Color) Color {
+	if s.Background == nil {
+		return def
+	}
+	return s.Background
+}
+
+// border returns the border color of s.
+func (s *FieldStyle) border() Color {
+	if s.Border == nil {
+		return Gray(0.5)
+	}
+	return s.Border
+}
+
+// set sets the default appearance, alignment, appearance characteristics
+// and border style of field f according to s. bg is the background color used
+// when s has none.
+func (s *FieldStyle) set(f map[string]interface{}, bg Color) {
+	f["DA"] = s.da()
+	if s.Align != AlignLeft {
+		f["Q"] = s.Align
+	}
+	f["MK"] = map[string]interface{}{
+		"BC": s.border().components(),
+		"BG": s.background(bg).components(),
+	}
+	f["BS"] = map[string]interface{}{
+		"W": 1,
+		"S": name("S"),
+	}
+}
+
+// box returns content stream operators painting the background and the border
+// of a field of size w×h. bg is the background color used when s has none.
+func (s *FieldStyle) box(w, h float64, bg Color) string {
+	return fmt.Sprint(colorOp(s.background(bg), false), " 0 0 ", ftoa(w), " ",
+		ftoa(h), " re f\n", colorOp(s.border(), true), " 1 w 0.5 0.5 ",
+		ftoa(w-1), " ", ftoa(h-1), " re S\n")
+}
+
+// fieldFont returns the font dictionary of the standard font with the given
+// name, which is in the resources of the form too. It's written to the output
+// the first time it's needed.
+func (d *Document) fieldFont(n string) *indirect {
+	// Pages made by many goroutines at once share the fonts.
+	d.resMu.Lock()
+	defer d.resMu.Unlock()
+
+	if d.ffonts == nil {
+		d.ffonts = make(map[string]*indirect)
+	}
+	if i, ok := d.ffonts[n]; ok {
+		return i
+	}
+	i := d.indirect(stdFonts[n].dict())
+	d.ffonts[n] = i
+	return i
+}
+
+// fieldNode is a non-terminal field, which has no widget and only groups its
+// kids. They are made for fully qualified names like "address.street", where
+// "address" is the non-terminal field (p. 673).
+type fieldNode struct {
+	ref  *indirect
+	name string     // partial name
+	par  *fieldNode // nil for the fields at the top
+	kids []*indirect
+}
+
+func (n *fieldNode) object() interface{} {
+	d := map[string]interface{}{
+		"T":    textString(n.name),
+		"Kids": n.kids,
+	}
+	if n.par != nil {
+		d["Parent"] = n.par.ref
+	}
+	return d
+}
+
+// fieldParent returns the non-terminal field with fully qualified name fq,
+// making it and its parents if they don't exist.
+func (d *Document) fieldParent(fq string) *fieldNode {
+	if n, ok := d.fnodes[fq]; ok {
+		return n
+	}
+	if d.fnames[fq] {
+		fail(ErrBadName, "field "+fq+" can't have kids")
+	}
+	n := &fieldNode{ref: d.reserveIndirect(), name: fq}
+	if dot := strings.LastIndex(fq, "."); dot >= 0 {
+		n.par = d.fieldParent(fq[:dot])
+		n.name = fq[dot+1:]
+		n.par.kids = append(n.par.kids, n.ref)
+	} else {
+		d.fields = append(d.fields, n.ref)
+	}
+	if d.fnodes == nil {
+		d.fnodes = make(map[string]*fieldNode)
+	}
+	d.fnodes[fq] = n
+	d.fnodeList = append(d.fnodeList, n)
+	return n
+}
+
+// addField adds the widget annotation f, merged with the dictionary of its
+// field, to the current page and to the form of the document. Names with dots
+// put the field under non-terminal fields; "address.street" is named "street"
+// and is a kid of "address".
+func (d *Document) addField(f map[string]interface{}) *indirect {
+	fq := f["T"].(string)
+	if fq == "" || strings.HasPrefix(fq, ".") || strings.HasSuffix(fq, ".") ||
+		strings.Contains(fq, "..") {
+		fail(ErrBadName, "bad field name: "+fq)
+	}
+	if _, ok := d.fnodes[fq]; ok || d.fnames[fq] {
+		fail(ErrBadName, "two fields with the same name: "+fq)
+	}
+	if d.fnames == nil {
+		d.fnames = make(map[string]bool)
+	}
+	d.fnames[fq] = true
+
+	var par *fieldNode
+	f["T"] = textString(fq)
+	if dot := strings.LastIndex(fq, "."); dot >= 0 {
+		par = d.fieldParent(fq[:dot])
+		f["T"] = textString(fq[dot+1:])
+		f["Parent"] = par.ref
+	}
+
+	f["Subtype"] = name("Widget")
+	if _, ok := f["F"]; !ok {
+		f["F"] = 4 // Print flag of annotations
+	}
+	i := d.addAnnot(f)
+	if par != nil {
+		par.kids = append(par.kids, i)
+	} else {
+		d.fields = append(d.fields, i)
+	}
+	return i
+}
+
+// acroForm writes the non-terminal fields to the output and returns the
+// interactive form dictionary of the document, which is referred to by the
+// catalog.
+func (d *Document) acroForm() map[string]interface{} {
+	for _, n := range d.fnodeList {
+		d.outputIndirect(n.ref, n)
+	}
+	f := map[string]interface{}{
+		"Fields": d.fields,
+	}
+	// Forms with no text, like the ones with only a signature, need no
+	// fonts.
+	if len(d.ffonts) > 0 {
+		d.fieldFont(FieldHelvetica) // used by the default appearance
+		f["DA"] = "/Helv 0 Tf 0 g"
+		f["DR"] = map[string]interface{}{"Font": d.ffonts}
+	}
+	if len(d.calcs) > 0 {
+		f["CO"] = d.calcs
+	}
+	return f
+}
+
+// appearance writes a form XObject with bounding box of size w×h and content
+// c to the output, to be used as the appearance of a widget. font is the name
+// of the font used by c and xo holds the XObjects used by it; both can be
+// empty.
+func (d *Document) appearance(w, h float64, c string, font string, xo map[string]interface{}) *indirect {
+	res := map[string]interface{}{}
+	if font != "" {
+		res["Font"] = map[string]interface{}{font: d.fieldFont(font)}
+	}
+	if xo != nil {
+		res["XObject"] = xo
+	}
+	return d.indirect(&stream{map[string]interface{}{
+		"Type":      name("XObject"),
+		"Subtype":   name("Form"),
+		"BBox":      newRect(0, 0, w, h),
+		"Resources": res,
+	}, []byte(c)})
+}
+
+// variableText returns the appearance of a field of size w×h that shows the
+// WinAnsi encoded lines with style s, starting from the top if multiline is
+// true and centered vertically otherwise. bg is the background color used
+// when s has none.
+func (s *FieldStyle) variableText(w, h float64, lines []string, multiline bool, bg Color) string {
+	size := s.size()
+	if !multiline && size > h-4 {
+		size = h - 4
+	}
+	buf := bytes.NewBufferString(s.box(w, h, bg))
+	buf.WriteString(fieldClip(w, h))
+	lh := size * 1.15
+	for i, l := range lines {
+		y := baseline(0, h, size)
+		if multiline {
+			y = baseline(h-2-float64(i+1)*lh, lh, size)
+		}
+		buf.WriteString(s.line(l, 2, y, w-4, size))
+	}
+	buf.WriteString("Q EMC\n")
+	return buf.String()
+}
+
+// fieldClip returns content stream operators that begin the variable text of a
+// field of size w×h, clipped inside its border (p. 678). They should be
+// followed by the text and "Q EMC\n".
+func fieldClip(w, h float64) string {
+	return fmt.Sprint("/Tx BMC q 1 1 ", ftoa(w-2), " ", ftoa(h-2), " re W n\n")
+}
+
+// baseline returns the y position of the baseline of a line of text with the
+// given font size, centered vertically in a box that starts at y and is h
+// high.
+func baseline(y, h, size float64) float64 {
+	// Descender of Helvetica is about 0.21 of the font size.
+	return y + (h-size)/2 + 0.22*size
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFieldHierarchy(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, err := New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(300, 300)
+	for _, n := range []string{"address.street", "address.city", "address.zip.code", "name"} {
+		if err = d.TextBox(10, 10, 100, 20, &TextField{Name: n}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, n := range []string{"name", "address", "address.street", ".x", "a..b"} {
+		if err = d.TextBox(10, 10, 100, 20, &TextField{Name: n}); err == nil {
+			t.Errorf("field named %q: expected error", n)
+		}
+	}
+	if err = d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"/T (street)",
+		"/T (code)",
+		"/Kids [ ",
+		"/T (address)\n",
+		"/T (zip)\n",
+		"/T (name)\n",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("document: %q not found", s)
+		}
+	}
+	if bytes.Contains(buf.Bytes(), []byte("address.")) {
+		t.Errorf("document: fully qualified name found")
+	}
+}
+
+func TestTabOrder(t *testing.T) {
+	p := newPage(100, 100, &indirect{num: 9})
+	p.tabs = TabRow
+	rects := []*rect{
+		newRect(50, 0, 60, 10),
+		newRect(0, 50, 10, 60),
+		newRect(0, 0, 10, 10),
+		newRect(50, 50, 60, 60),
+	}
+	for i, r := range rects {
+		p.addAnnot(&indirect{num: i + 1}, r)
+	}
+	p.sortAnnots()
+	order := []int{2, 4, 3, 1}
+	for i, a := range p.annots {
+		if a.num != order[i] {
+			t.Errorf("row order: got %d at %d, expected %d", a.num, i, order[i])
+		}
+	}
+	p.tabs = TabColumn
+	p.sortAnnots()
+	order = []int{2, 3, 4, 1}
+	for i, a := range p.annots {
+		if a.num != order[i] {
+			t.Errorf("column order: got %d at %d, expected %d", a.num, i, order[i])
+		}
+	}
+	if !bytes.Contains(output(p), []byte("/Tabs /C")) {
+		t.Errorf("page: /Tabs not found")
+	}
+}
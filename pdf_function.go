@@ -0,0 +1,205 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains functions of PDF (p. 166), which map numbers to
+// numbers. They are used by the graphics state, like for the transfer of inks,
+// and by color spaces and shadings.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Function is a function of PDF, which maps some numbers, its inputs, to
+// others, its outputs.
+type Function interface {
+	// function returns the function object, which is written to the output
+	// of d first if it's a stream.
+	function(d *Document) interface{}
+	// arity returns the number of inputs and outputs of the function.
+	arity() (in, out int)
+}
+
+// ExponentialFunction maps x, from 0 to 1, to C0 + x^N × (C1 - C0) for each
+// of its outputs (p. 172).
+type ExponentialFunction struct {
+	C0 []float64 // outputs for 0; {0} if nil
+	C1 []float64 // outputs for 1; {1} if nil
+	N  float64
+}
+
+func (f *ExponentialFunction) arity() (in, out int) {
+	c0, c1 := len(f.C0), len(f.C1)
+	switch {
+	case c0 == 0 && c1 == 0:
+		return 1, 1
+	case c0 == 0:
+		c0 = 1
+	case c1 == 0:
+		c1 = 1
+	}
+	if c0 != c1 {
+		panic(fmt.Sprint("exponential function with ", c0, " and ", c1, " outputs"))
+	}
+	return 1, c0
+}
+
+func (f *ExponentialFunction) function(d *Document) interface{} {
+	f.arity()
+	m := map[string]interface{}{
+		"FunctionType": 2,
+		"Domain":       []int{0, 1},
+		"N":            f.N,
+	}
+	if f.C0 != nil {
+		m["C0"] = f.C0
+	}
+	if f.C1 != nil {
+		m["C1"] = f.C1
+	}
+	return m
+}
+
+// SampledFunction is a function given by a table of samples (p. 169). Inputs
+// between the samples are found by linear interpolation.
+type SampledFunction struct {
+	Domain        []float64 // minimum and maximum of each input; 0 and 1 for each if nil
+	Range         []float64 // minimum and maximum of each output
+	Size          []int     // number of samples for each input
+	BitsPerSample int       // 1, 2, 4, 8, 12, 16, 24 or 32; 8 if zero
+
+	// Samples holds the outputs for the samples, with the first input
+	// changing fastest, packed with BitsPerSample bits for each output.
+	// The smallest and the largest numbers are mapped to the range of
+	// their output.
+	Samples []byte
+}
+
+func (f *SampledFunction) bits() int {
+	switch f.BitsPerSample {
+	case 0:
+		return 8
+	case 1, 2, 4, 8, 12, 16, 24, 32:
+		return f.BitsPerSample
+	}
+	panic(fmt.Sprint("bad bits per sample of function: ", f.BitsPerSample))
+}
+
+func (f *SampledFunction) arity() (in, out int) {
+	in, out = len(f.Size), len(f.Range)/2
+	if in == 0 || out == 0 || len(f.Range)%2 != 0 {
+		panic("sampled function with no size or range")
+	}
+	if f.Domain != nil && len(f.Domain) != 2*in {
+		panic(fmt.Sprint("sampled function with ", in, " inputs and ", len(f.Domain), " numbers in domain"))
+	}
+	n := out
+	for _, s := range f.Size {
+		if s < 1 {
+			panic(fmt.Sprint("bad size of sampled function: ", f.Size))
+		}
+		n *= s
+	}
+	if need := (n*f.bits() + 7) / 8; len(f.Samples) != need {
+		panic(fmt.Sprint("sampled function with ", len(f.Samples), " bytes of samples, expected ", need))
+	}
+	return in, out
+}
+
+func (f *SampledFunction) function(d *Document) interface{} {
+	in, _ := f.arity()
+	dom := f.Domain
+	if dom == nil {
+		dom = make([]float64, 0, 2*in)
+		for i := 0; i < in; i++ {
+			dom = append(dom, 0, 1)
+		}
+	}
+	return d.indirect(&stream{map[string]interface{}{
+		"FunctionType":  0,
+		"Domain":        dom,
+		"Range":         f.Range,
+		"Size":          f.Size,
+		"BitsPerSample": f.bits(),
+	}, f.Samples})
+}
+
+// PostScriptFunction is a function given by a program in a small part of the
+// PostScript language (p. 176). The program finds the inputs on the operand
+// stack and leaves the outputs there.
+type PostScriptFunction struct {
+	Domain []float64 // minimum and maximum of each input
+	Range  []float64 // minimum and maximum of each output
+	Code   string    // the program, like "dup mul", with or without braces around it
+}
+
+func (f *PostScriptFunction) arity() (in, out int) {
+	in, out = len(f.Domain)/2, len(f.Range)/2
+	if in == 0 || out == 0 || len(f.Domain)%2 != 0 || len(f.Range)%2 != 0 {
+		panic("PostScript function with no domain or range")
+	}
+	return in, out
+}
+
+func (f *PostScriptFunction) function(d *Document) interface{} {
+	f.arity()
+	c := strings.TrimSpace(f.Code)
+	if !strings.HasPrefix(c, "{") {
+		c = "{ " + c + " }"
+	}
+	return d.indirect(&stream{map[string]interface{}{
+		"FunctionType": 4,
+		"Domain":       f.Domain,
+		"Range":        f.Range,
+	}, []byte(c)})
+}
+
+// IdentityFunction is the function whose output is its input. As a transfer
+// function, it leaves the components of colors as they are.
+var IdentityFunction Function = identityFunction{}
+
+type identityFunction struct{}
+
+func (identityFunction) arity() (in, out int) {
+	return 1, 1
+}
+
+func (identityFunction) function(d *Document) interface{} {
+	return name("Identity")
+}
+
+// function returns the object of f, after checking that it has in inputs and
+// out outputs. what tells what f is for in errors. Functions are written to
+// the output the first time they're needed.
+func (d *Document) function(f Function, in, out int, what string) interface{} {
+	if i, o := f.arity(); i != in || o != out {
+		panic(fmt.Sprint(what, " with ", i, " inputs and ", o, " outputs, expected ", in, " and ", out))
+	}
+	d.resMu.Lock()
+	defer d.resMu.Unlock()
+
+	if o, ok := d.funcs[f]; ok {
+		return o
+	}
+	if d.funcs == nil {
+		d.funcs = make(map[Function]interface{})
+	}
+	o := f.function(d)
+	d.funcs[f] = o
+	return o
+}
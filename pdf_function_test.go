@@ -0,0 +1,100 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestFunctionArity(t *testing.T) {
+	for _, tt := range []struct {
+		f       Function
+		in, out int
+		err     string
+	}{
+		{&ExponentialFunction{N: 1}, 1, 1, ""},
+		{&ExponentialFunction{C1: []float64{1, 0, 0}, C0: []float64{0, 0, 1}, N: 2}, 1, 3, ""},
+		{&ExponentialFunction{C1: []float64{1, 0, 0}, N: 2}, 0, 0,
+			"pdf.go: exponential function with 1 and 3 outputs"},
+		{&SampledFunction{Range: []float64{0, 1}, Size: []int{4}, Samples: []byte{0, 1, 2, 3}}, 1, 1, ""},
+		{&SampledFunction{Range: []float64{0, 1}, Size: []int{2, 3}, BitsPerSample: 4,
+			Samples: []byte{0, 1, 2}}, 2, 1, ""},
+		{&SampledFunction{Range: []float64{0, 1}, Size: []int{4}, Samples: []byte{0}}, 0, 0,
+			"pdf.go: sampled function with 1 bytes of samples, expected 4"},
+		{&SampledFunction{Range: []float64{0, 1}, Size: []int{1}, BitsPerSample: 3, Samples: []byte{0}}, 0, 0,
+			"pdf.go: bad bits per sample of function: 3"},
+		{&SampledFunction{Size: []int{1}}, 0, 0, "pdf.go: sampled function with no size or range"},
+		{&PostScriptFunction{Domain: []float64{0, 1}, Range: []float64{0, 1}, Code: "dup mul"}, 1, 1, ""},
+		{&PostScriptFunction{Domain: []float64{0, 1}, Code: "dup"}, 0, 0,
+			"pdf.go: PostScript function with no domain or range"},
+		{IdentityFunction, 1, 1, ""},
+	} {
+		var in, out int
+		err := func() (err error) {
+			defer dontPanic(&err)
+			in, out = tt.f.arity()
+			return nil
+		}()
+		if fmt.Sprint(err) != tt.err && !(err == nil && tt.err == "") {
+			t.Errorf("%#v: got error %v expected %q", tt.f, err, tt.err)
+		}
+		if in != tt.in || out != tt.out {
+			t.Errorf("%#v: got %d, %d expected %d, %d", tt.f, in, out, tt.in, tt.out)
+		}
+	}
+}
+
+func TestFunctionObjects(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(100, 100)
+	ps := &PostScriptFunction{Domain: []float64{0, 1}, Range: []float64{0, 1}, Code: "dup mul"}
+	if d.function(ps, 1, 1, "test") != d.function(ps, 1, 1, "test") {
+		t.Errorf("function written twice")
+	}
+	sampled := d.function(&SampledFunction{Range: []float64{0, 1}, Size: []int{2}, Samples: []byte{0, 255}}, 1, 1, "test")
+	exp := d.function(&ExponentialFunction{N: 2}, 1, 1, "test").(map[string]interface{})
+	if exp["FunctionType"] != 2 || exp["N"] != 2.0 || exp["C0"] != nil {
+		t.Errorf("exponential function: got %v", exp)
+	}
+	err := func() (err error) {
+		defer dontPanic(&err)
+		d.function(ps, 2, 1, "spot function")
+		return nil
+	}()
+	if e := "pdf.go: spot function with 1 inputs and 1 outputs, expected 2 and 1"; fmt.Sprint(err) != e {
+		t.Errorf("got error %v expected %q", err, e)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := r.object(d.funcs[ps].(*indirect).num).(*stream)
+	if s.dic["FunctionType"] != 4 || string(r.decode(s)) != "{ dup mul }" {
+		t.Errorf("PostScript function: got %v %q", s.dic, r.decode(s))
+	}
+	s = r.object(sampled.(*indirect).num).(*stream)
+	if s.dic["FunctionType"] != 0 || s.dic["BitsPerSample"] != 8 || fmt.Sprint(s.dic["Domain"]) != "[0 1]" {
+		t.Errorf("sampled function: got %v", s.dic)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file keeps the characters of the text of documents that their fonts
+// don't have, so that applications can tell when text isn't shown right.
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MissingGlyphs returns the characters of the text drawn in d so far that
+// their fonts don't have, in order, whether they're emoji that no source of
+// emoji has or are drawn with outline fonts. Text in standard fonts can only
+// have the characters of WinAnsiEncoding.
+func (d *Document) MissingGlyphs() []rune {
+	d.resMu.Lock()
+	defer d.resMu.Unlock()
+
+	rs := make([]rune, 0, len(d.missing))
+	for r := range d.missing {
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i] < rs[j] })
+	return rs
+}
+
+// noGlyphs notes that rs are characters of text of d with no glyphs. It
+// panics with an error of kind ErrNoGlyph if d fails on missing glyphs.
+func (d *Document) noGlyphs(rs []rune) {
+	if len(rs) == 0 {
+		return
+	}
+	d.resMu.Lock()
+	if d.missing == nil {
+		d.missing = make(map[rune]bool)
+	}
+	for _, r := range rs {
+		d.missing[r] = true
+	}
+	d.resMu.Unlock()
+	if d.opts.FailOnMissingGlyphs {
+		fail(ErrNoGlyph, fmt.Sprintf("no glyph for %U %q", rs[0], rs[0]))
+	}
+}
+
+// encodeText converts the UTF-8 string s, to be shown in a standard font, to
+// WinAnsiEncoding like winAnsi, noting the characters it doesn't have.
+func (d *Document) encodeText(s string) string {
+	d.noGlyphs(unencodable(s))
+	return winAnsi(s)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestMissingGlyphs(t *testing.T) {
+	f, err := NewOutlineFont(testOutlineFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := New(bytes.NewBuffer(nil))
+	p, _ := d.NewPage(300, 300)
+	if err := d.DrawTextOutlines(f, "AZB", 10, 10, 12, nil); err != nil {
+		t.Fatal(err)
+	}
+	c := new(Content)
+	c.Text("Café 中", 10, 50, TextStyle{})
+	if err := p.AddContent(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.TextBox(10, 100, 100, 20, &TextField{Name: "name", Value: "日本?"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(d.MissingGlyphs()); got != "Z中日本" {
+		t.Errorf("missing glyphs: got %q", got)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil), Options{FailOnMissingGlyphs: true})
+	p, _ = d.NewPage(300, 300)
+	if err := d.DrawTextOutlines(f, "AB", 10, 10, 12, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DrawTextOutlines(f, "AZ", 10, 10, 12, nil); !errors.Is(err, ErrNoGlyph) {
+		t.Errorf("outlines with a missing glyph: got %v", err)
+	}
+	c = new(Content)
+	c.Text("中", 10, 50, TextStyle{})
+	if err := p.AddContent(c); !errors.Is(err, ErrNoGlyph) {
+		t.Errorf("content with a missing glyph: got %v", err)
+	}
+	err = d.TextBox(10, 100, 100, 20, &TextField{Name: "name", Value: "日"})
+	if !errors.Is(err, ErrNoGlyph) || err.Error() != "pdf.go: no glyph for U+65E5 '日'" {
+		t.Errorf("field with a missing glyph: got %v", err)
+	}
+}
@@ -35,67 +35,159 @@ const (
 )
 
 // LineWidth changes the width of the lines to be drawn after it.
-func (d *Document) LineWidth(w int) {
-	d.addc(fmt.Sprint(w, " w"))
+func (d *Document) LineWidth(w int) (err error) {
+	defer dontPanic(&err)
+
+	d.addw(d.ops().LineWidth(float64(w)))
+	if d.gstate != nil {
+		d.gstate.LineWidth = float64(w)
+	}
+	return nil
 }
 
 // LineCapStyle changes line cap style to one of the three options. Use
 // LineCapBut, LineCapRound, and LineCapProjecting constants as the argument.
-func (d *Document) LineCapStyle(s int) {
-	d.addc(fmt.Sprint(s, " J"))
+func (d *Document) LineCapStyle(s int) (err error) {
+	defer dontPanic(&err)
+
+	d.addw(d.ops().LineCapStyle(s))
+	return nil
 }
 
 // LineJoinStyle changes line join style to one of the three options. Use
 // LineJoinMiter, LineJoinRound, LineJoinBevel constants as the argument.
-func (d *Document) LineJoinStyle(s int) {
-	d.addc(fmt.Sprint(s, " j"))
+func (d *Document) LineJoinStyle(s int) (err error) {
+	defer dontPanic(&err)
+
+	d.addw(d.ops().LineJoinStyle(s))
+	return nil
 }
 
 // MoveTo starts a new path at the given point.
-func (d *Document) MoveTo(x, y int) {
-	d.addc(fmt.Sprint(x, y, " m"))
+func (d *Document) MoveTo(x, y int) (err error) {
+	defer dontPanic(&err)
+
+	d.outsideText("path")
+	d.addw(d.ops().MoveTo(float64(x), float64(y)))
+	return nil
 }
 
 // LineTo draws a single line from current the given point.
-func (d *Document) LineTo(x, y int) {
-	d.addc(fmt.Sprint(x, y, " l"))
+func (d *Document) LineTo(x, y int) (err error) {
+	defer dontPanic(&err)
+
+	d.outsideText("path")
+	d.addw(d.ops().LineTo(float64(x), float64(y)))
+	return nil
 }
 
 // Curve draws a bézier curve from current point to point (x2, y2) using
 // (x0, y0) and (x1, y1) as control points.
-func (d *Document) Curve(x0, y0, x1, y1, x2, y2 int) {
-	d.addc(fmt.Sprint(x0, y0, x1, y1, x2, y2, " c"))
+func (d *Document) Curve(x0, y0, x1, y1, x2, y2 int) (err error) {
+	defer dontPanic(&err)
+
+	d.outsideText("path")
+	d.addw(d.ops().Curve(float64(x0), float64(y0), float64(x1), float64(y1),
+		float64(x2), float64(y2)))
+	return nil
 }
 
 // CurveV draws a bézier curve from current point to point (x1, y1) using
 // current point and (x0, y0) as control points.
-func (d *Document) CurveV(x0, y0, x1, y1 int) {
-	d.addc(fmt.Sprint(x0, y0, x1, y1, " v"))
+func (d *Document) CurveV(x0, y0, x1, y1 int) (err error) {
+	defer dontPanic(&err)
+
+	d.outsideText("path")
+	d.addw(d.ops().CurveV(float64(x0), float64(y0), float64(x1), float64(y1)))
+	return nil
 }
 
 // CurveY draws a bézier curve from current point to point (x1, y1) using
 // (x0, y0) and current point as control points.
-func (d *Document) CurveY(x0, y0, x1, y1 int) {
-	d.addc(fmt.Sprint(x0, y0, x1, y1, " y"))
+func (d *Document) CurveY(x0, y0, x1, y1 int) (err error) {
+	defer dontPanic(&err)
+
+	d.outsideText("path")
+	d.addw(d.ops().CurveY(float64(x0), float64(y0), float64(x1), float64(y1)))
+	return nil
 }
 
 // Rectangle draws a renctangle using PDF's 're' command.
-func (d *Document) Rectangle(x, y, w, h int) {
-	d.addc(fmt.Sprint(x, y, w, h, " re"))
+func (d *Document) Rectangle(x, y, w, h int) (err error) {
+	defer dontPanic(&err)
+
+	d.outsideText("path")
+	d.addw(d.ops().Rectangle(float64(x), float64(y), float64(w), float64(h)))
+	return nil
 }
 
 // ClosePath closes the current active path by drawing a straight line from
 // current point to the beginning of the path.
-func (d *Document) ClosePath() {
-	d.addc("h")
+func (d *Document) ClosePath() (err error) {
+	defer dontPanic(&err)
+
+	d.outsideText("path")
+	d.addw(d.ops().ClosePath())
+	return nil
 }
 
 // Stroke paints the current path with stroke.
-func (d *Document) Stroke() {
-	d.addc("S")
+func (d *Document) Stroke() (err error) {
+	defer dontPanic(&err)
+
+	d.outsideText("path")
+	d.addw(d.ops().Stroke())
+	return nil
+}
+
+// ops returns the content writer of d, emptied, for the operators of a
+// graphics method to be added to the current content stream by addw.
+func (d *Document) ops() *ContentWriter {
+	return d.cw.Reset()
 }
 
 // Fill paints inside of the current path.
-func (d *Document) Fill() {
-	d.addc("f")
+func (d *Document) Fill() (err error) {
+	defer dontPanic(&err)
+
+	d.outsideText("path")
+	d.addw(d.ops().Fill())
+	return nil
+}
+
+// SetOpacity changes the opacity of what's painted after it, from 0 for
+// transparent to 1 for opaque. fill is the opacity of filled shapes and text,
+// and stroke the opacity of lines.
+func (d *Document) SetOpacity(fill, stroke float64) (err error) {
+	defer dontPanic(&err)
+
+	if fill < 0 || fill > 1 || stroke < 0 || stroke > 1 {
+		panic(fmt.Sprint("opacity out of range: ", fill, ", ", stroke))
+	}
+	d.content()
+	g := d.extGState(map[string]interface{}{
+		"Type": name("ExtGState"),
+		"ca":   fill,
+		"CA":   stroke,
+	})
+	d.addw(d.ops().Name(d.useResource("ExtGState", resName("GS", g), g)).Op("gs"))
+	return nil
+}
+
+// extGState returns the graphics state parameter dictionary g (p. 219). It's
+// written to the output the first time it's needed.
+func (d *Document) extGState(g map[string]interface{}) *indirect {
+	d.resMu.Lock()
+	defer d.resMu.Unlock()
+
+	k := string(output(g))
+	if i, ok := d.gstates[k]; ok {
+		return i
+	}
+	if d.gstates == nil {
+		d.gstates = make(map[string]*indirect)
+	}
+	i := d.indirect(g)
+	d.gstates[k] = i
+	return i
 }
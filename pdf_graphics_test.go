@@ -0,0 +1,68 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"io"
+	"testing"
+)
+
+// benchOps is the number of operators of the pages of benchmarks.
+const benchOps = 10000
+
+// BenchmarkPage draws pages of benchOps operators with the graphics methods
+// of Document.
+func BenchmarkPage(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d, _ := New(io.Discard)
+		d.NewPage(600, 800)
+		for j := 0; j < benchOps/4; j++ {
+			x := j % 600
+			d.MoveTo(x, 0)
+			d.LineTo(x, 800)
+			d.Rectangle(x, 10, 5, 5)
+			d.Stroke()
+		}
+		d.Close()
+	}
+	b.ReportMetric(float64(b.N*benchOps)/b.Elapsed().Seconds(), "ops/s")
+}
+
+// BenchmarkContent makes contents of benchOps operators with text, XObjects
+// and paths, and adds them to pages.
+func BenchmarkContent(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d, _ := New(io.Discard)
+		d.BeginXObject(10, 10)
+		x, _ := d.EndXObject()
+		p, _ := d.NewPage(600, 800)
+		c := new(Content)
+		// Texts and XObjects are 5 operators each.
+		for j := 0; j < benchOps/12; j++ {
+			y := float64(j % 800)
+			c.Text("Hello, world", 10, y, TextStyle{FontSize: 10})
+			c.DrawXObject(x, 300, y, 10, 10)
+			c.Rectangle(400, int(y), 5, 5)
+			c.Fill()
+		}
+		p.AddContent(c)
+		d.Close()
+	}
+	b.ReportMetric(float64(b.N*benchOps)/b.Elapsed().Seconds(), "ops/s")
+}
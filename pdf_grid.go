@@ -0,0 +1,272 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file lays out content in a grid of rows and columns in a region of a
+// page, like the boxes of a dashboard.
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Vertical alignments of content in cells of grids
+const (
+	AlignTop = iota
+	AlignMiddle
+	AlignBottom
+)
+
+// Drawable is content that can be drawn in a box, like a cell of a grid.
+// Pictures, paragraphs, tables, formulas and components like *Address are
+// drawables, and so is any function converted to DrawFunc.
+type Drawable interface {
+	// Size returns the size of the content drawn in a box of size w×h. It
+	// can be larger than the box if the content doesn't fit in it.
+	Size(w, h float64) (float64, float64)
+	// Draw draws the content on the current page, with size w×h and the
+	// lower left corner at (x, y).
+	Draw(d *Document, x, y, w, h float64) error
+}
+
+// DrawFunc is a function that draws content in a box of size w×h with the
+// lower left corner at (x, y). The content fills the box it's drawn in.
+type DrawFunc func(d *Document, x, y, w, h float64) error
+
+// Size returns w and h.
+func (f DrawFunc) Size(w, h float64) (float64, float64) {
+	return w, h
+}
+
+// Draw calls f.
+func (f DrawFunc) Draw(d *Document, x, y, w, h float64) error {
+	return f(d, x, y, w, h)
+}
+
+// Size returns the size of p drawn in a box of size w×h. It's made smaller
+// to fit in the box.
+func (p *Picture) Size(w, h float64) (float64, float64) {
+	if p.XObject == nil {
+		return 0, 0
+	}
+	return p.size(w, h)
+}
+
+// Draw draws the XObject of p with size w×h and the lower left corner at
+// (x, y).
+func (p *Picture) Draw(d *Document, x, y, w, h float64) error {
+	if p.XObject == nil {
+		return &Error{Kind: ErrInvalid, Msg: "picture with no XObject"}
+	}
+	return d.DrawXObject(p.XObject, x, y, w, h)
+}
+
+// Size returns the width w and the height of the lines of p broken to be
+// as wide as w. The space around p is not used.
+func (p *Paragraph) Size(w, h float64) (float64, float64) {
+	return w, float64(len(p.Style.wrap(p.Text, w))) * p.Style.leading()
+}
+
+// Draw draws the lines of p broken to be as wide as w, starting from the top
+// of the box of size w×h with the lower left corner at (x, y).
+func (p *Paragraph) Draw(d *Document, x, y, w, h float64) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "paragraph drawn before any page was started")
+	}
+	if d.xbox != nil {
+		panic("Paragraph.Draw called inside an XObject")
+	}
+	style := p.Style
+	size, lh := style.size(), style.leading()
+	d.pageFont(style.font())
+	for i, l := range style.wrap(p.Text, w) {
+		by := baseline(y+h-float64(i+1)*lh, lh, size)
+		d.addc(strings.TrimRight(style.line(l, x, by, w, size), "\n"))
+	}
+	return nil
+}
+
+// Size returns the width w and the height of the rows of t laid out with
+// width w.
+func (t *Table) Size(w, h float64) (float64, float64) {
+	l := t.layout(w)
+	return w, l.rowsHeight(0, len(t.rows))
+}
+
+// Draw draws all the rows of t with width w, starting from the top of the
+// box of size w×h with the lower left corner at (x, y). Unlike DrawTable, it
+// doesn't start new pages.
+func (t *Table) Draw(d *Document, x, y, w, h float64) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "table drawn before any page was started")
+	}
+	if d.xbox != nil {
+		panic("Table.Draw called inside an XObject")
+	}
+	l := t.layout(w)
+	d.addc(l.draw(0, len(t.rows), x, y+h, d.pageFont))
+	return nil
+}
+
+// Grid is a region of a page divided into rows and columns, with content in
+// cells of it. Content can span more than one row or column.
+type Grid struct {
+	// Widths of the columns and heights of the rows. The ones that are
+	// zero share the room left by the others equally. A grid with no
+	// columns or rows has one that takes all the room.
+	Columns, Rows []float64
+
+	ColumnGap float64 // space between columns
+	RowGap    float64 // space between rows
+
+	items []*GridItem
+}
+
+// GridItem is content in a cell of a grid, or in cells joined by spanning
+// more than one row or column.
+type GridItem struct {
+	Row, Column      int // top left cell, counted from 0
+	RowSpan, ColSpan int // number of rows and columns spanned; 1 if zero
+
+	Content    Drawable // nothing is drawn if nil
+	Align      int      // AlignLeft, AlignCenter or AlignRight
+	VAlign     int      // AlignTop, AlignMiddle or AlignBottom
+	Padding    float64  // space between the edges of the cells and the content
+	Background Color    // no background if nil
+}
+
+// rowSpan returns the number of rows it spans.
+func (it *GridItem) rowSpan() int {
+	if it.RowSpan < 1 {
+		return 1
+	}
+	return it.RowSpan
+}
+
+// colSpan returns the number of columns it spans.
+func (it *GridItem) colSpan() int {
+	if it.ColSpan < 1 {
+		return 1
+	}
+	return it.ColSpan
+}
+
+// Add adds it to g.
+func (g *Grid) Add(it *GridItem) {
+	g.items = append(g.items, it)
+}
+
+// tracks returns the starts and the sizes of the columns or rows with the
+// given sizes, with gap between them, in room of length l. The ones with no
+// size share the room left equally.
+func tracks(sizes []float64, l, gap float64, what string) ([]float64, []float64) {
+	if len(sizes) == 0 {
+		sizes = []float64{0}
+	}
+	n := len(sizes)
+	left := l - gap*float64(n-1)
+	auto := 0
+	for _, s := range sizes {
+		if s <= 0 {
+			auto++
+		}
+		left -= math.Max(s, 0)
+	}
+	if left < -0.001 {
+		panic(what + " of grid are larger than the grid")
+	}
+	start, size := make([]float64, n), make([]float64, n)
+	pos := 0.0
+	for i, s := range sizes {
+		if s <= 0 {
+			s = math.Max(left, 0) / float64(auto)
+		}
+		start[i], size[i] = pos, s
+		pos += s + gap
+	}
+	return start, size
+}
+
+// DrawGrid draws the content of g on the current page, in the region of
+// size w×h with the lower left corner at (x, y).
+func (d *Document) DrawGrid(g *Grid, x, y, w, h float64) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "grid drawn before any page was started")
+	}
+	if d.xbox != nil {
+		panic("DrawGrid called inside an XObject")
+	}
+	cx, cw := tracks(g.Columns, w, g.ColumnGap, "columns")
+	ry, rh := tracks(g.Rows, h, g.RowGap, "rows")
+
+	taken := make(map[string]bool)
+	for _, it := range g.items {
+		r, c, rs, cs := it.Row, it.Column, it.rowSpan(), it.colSpan()
+		if r < 0 || c < 0 || r+rs > len(rh) || c+cs > len(cw) {
+			panic(fmt.Sprintf("grid item at row %d, column %d is outside the grid", r+1, c+1))
+		}
+		for i := r; i < r+rs; i++ {
+			for j := c; j < c+cs; j++ {
+				k := fmt.Sprint(i, " ", j)
+				if taken[k] {
+					panic(fmt.Sprintf("items of grid overlap at row %d, column %d", i+1, j+1))
+				}
+				taken[k] = true
+			}
+		}
+
+		// Box of the cells of the item
+		bx, bw := x+cx[c], cx[c+cs-1]+cw[c+cs-1]-cx[c]
+		by := y + h - ry[r+rs-1] - rh[r+rs-1]
+		bh := ry[r+rs-1] + rh[r+rs-1] - ry[r]
+		if it.Background != nil {
+			d.addc(fmt.Sprint("q ", colorOp(it.Background, false), " ", ftoa(bx), " ",
+				ftoa(by), " ", ftoa(bw), " ", ftoa(bh), " re f Q"))
+		}
+		if it.Content == nil {
+			continue
+		}
+		p := it.Padding
+		iw, ih := bw-2*p, bh-2*p
+		ow, oh := it.Content.Size(iw, ih)
+		if ow > iw+0.001 || oh > ih+0.001 {
+			panic(fmt.Sprintf("content of grid item at row %d, column %d doesn't fit", r+1, c+1))
+		}
+		ox, oy := bx+p, by+p
+		switch it.Align {
+		case AlignCenter:
+			ox += (iw - ow) / 2
+		case AlignRight:
+			ox += iw - ow
+		}
+		switch it.VAlign {
+		case AlignTop:
+			oy += ih - oh
+		case AlignMiddle:
+			oy += (ih - oh) / 2
+		}
+		check(it.Content.Draw(d, ox, oy, ow, oh))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGrid(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.BeginXObject(50, 50)
+	x, _ := d.EndXObject()
+	d.NewPage(200, 200)
+
+	// Columns are 60 and 110 wide, and rows are 85 high.
+	g := &Grid{Columns: []float64{60, 0}, Rows: []float64{0, 0}, ColumnGap: 10, RowGap: 10}
+	g.Add(&GridItem{RowSpan: 2, Background: Gray(0.5)})
+	g.Add(&GridItem{Column: 1, Content: &Picture{XObject: x}, Align: AlignCenter, VAlign: AlignMiddle})
+	g.Add(&GridItem{Row: 1, Column: 1, VAlign: AlignBottom, Content: &Paragraph{Text: "hi",
+		Style: TextStyle{Font: FontCourier, FontSize: 10, Align: AlignRight}}})
+	if err := d.DrawGrid(g, 10, 10, 180, 180); err != nil {
+		t.Fatal(err)
+	}
+
+	var box string
+	g = &Grid{}
+	g.Add(&GridItem{Padding: 5, Content: DrawFunc(func(d *Document, x, y, w, h float64) error {
+		box = fmt.Sprint(x, " ", y, " ", w, " ", h)
+		return nil
+	})})
+	if err := d.DrawGrid(g, 10, 10, 180, 180); err != nil {
+		t.Fatal(err)
+	}
+	if box != "15 15 170 170" {
+		t.Errorf("box of DrawFunc: got %s, want 15 15 170 170", box)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"q 0.5 g 10 10 60 180 re f Q",
+		"q 1 0 0 1 110 122.5 cm /Fm",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("%q not found in the output", s)
+		}
+	}
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, _ := r.PageText(1)
+	if len(runs) != 1 || fmt.Sprint(ftoa(runs[0].X), " ", ftoa(runs[0].Y), " ", runs[0].Text) != "178 12.95 hi" {
+		t.Errorf("text: got %v", runs)
+	}
+}
+
+var gridErrorTests = []struct {
+	g     *Grid
+	items []*GridItem
+	err   string
+}{
+	{&Grid{Columns: []float64{100, 100}}, []*GridItem{{}},
+		"columns of grid are larger than the grid"},
+	{&Grid{Rows: []float64{0, 0}}, []*GridItem{{Row: 1, RowSpan: 2}},
+		"grid item at row 2, column 1 is outside the grid"},
+	{&Grid{Rows: []float64{0, 0}}, []*GridItem{{Row: 1}, {RowSpan: 2}},
+		"items of grid overlap at row 2, column 1"},
+	{&Grid{}, []*GridItem{{Content: &Paragraph{Text: strings.Repeat("x\n", 20)}}},
+		"content of grid item at row 1, column 1 doesn't fit"},
+}
+
+func TestGridErrors(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	if err := d.DrawGrid(&Grid{}, 0, 0, 100, 100); err == nil {
+		t.Error("no error for a grid drawn before any page")
+	}
+	d.NewPage(200, 200)
+	for i, tt := range gridErrorTests {
+		for _, it := range tt.items {
+			tt.g.Add(it)
+		}
+		err := d.DrawGrid(tt.g, 0, 0, 150, 150)
+		if fmt.Sprint(err) != "pdf.go: "+tt.err {
+			t.Errorf("%d. got error %v, want %s", i, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the parameters of the graphics state that control how
+// colors are turned into ink on devices: halftones, transfer
+// functions, black generation and undercolor removal. They matter to print
+// shops, and are usually left to the device.
+
+import (
+	"fmt"
+)
+
+// Standard spot functions of halftone screens. Others are named in
+// the same table.
+const (
+	SpotRound     = "Round"
+	SpotEllipse   = "Ellipse"
+	SpotLine      = "Line"
+	SpotSimpleDot = "SimpleDot"
+	SpotSquare    = "Square"
+	SpotDiamond   = "Diamond"
+)
+
+// spotFunctions holds the names of the standard spot functions.
+var spotFunctions = map[string]bool{
+	"SimpleDot": true, "InvertedSimpleDot": true, "DoubleDot": true,
+	"InvertedDoubleDot": true, "CosineDot": true, "Double": true,
+	"InvertedDouble": true, "Line": true, "LineX": true, "LineY": true,
+	"Round": true, "Ellipse": true, "EllipseA": true, "InvertedEllipseA": true,
+	"EllipseB": true, "EllipseC": true, "InvertedEllipseC": true,
+	"Square": true, "Cross": true, "Rhomboid": true, "Diamond": true,
+}
+
+// Halftone is a halftone screen, which makes the shades of colors
+// with dots of ink.
+type Halftone struct {
+	Frequency float64 // number of cells of the screen in an inch
+	Angle     float64 // angle of the screen, in degrees counterclockwise
+
+	// Spot is the name of the standard spot function of the screen, like
+	// SpotRound, which tells the shape of the dots. Function is used
+	// instead if it's set; it maps a position in a cell, from -1 to 1 in
+	// both directions, to the order in which it's painted.
+	Spot     string
+	Function Function
+
+	Accurate bool // whether the device should try harder to get the frequency and angle right
+}
+
+// object returns the type 1 halftone dictionary of h.
+func (h *Halftone) object(d *Document) map[string]interface{} {
+	if h.Frequency <= 0 {
+		panic(fmt.Sprint("bad frequency of halftone: ", h.Frequency))
+	}
+	m := map[string]interface{}{
+		"Type":         name("Halftone"),
+		"HalftoneType": 1,
+		"Frequency":    h.Frequency,
+		"Angle":        h.Angle,
+	}
+	switch {
+	case h.Function != nil:
+		m["SpotFunction"] = d.function(h.Function, 2, 1, "spot function")
+	case h.Spot == "":
+		m["SpotFunction"] = name(SpotRound)
+	case spotFunctions[h.Spot]:
+		m["SpotFunction"] = name(h.Spot)
+	default:
+		panic("unknown spot function: " + h.Spot)
+	}
+	if h.Accurate {
+		m["AccurateScreens"] = true
+	}
+	return m
+}
+
+// InkControl holds the parameters of the graphics state that control inks.
+// The ones that are not set are left as they are.
+type InkControl struct {
+	Halftone        *Halftone
+	DefaultHalftone bool // whether the halftone of the device is used again; Halftone is ignored if true
+
+	// Transfer holds the transfer function that adjusts all the components
+	// of colors, or four of them, which adjust red, green, blue and gray
+	// separately. They can be used to compensate for dot gain.
+	Transfer []Function
+
+	// BlackGeneration gives the black ink for the gray part of colors
+	// turned from RGB to CMYK, and UndercolorRemoval the amount by which
+	// the other inks are reduced for it.
+	BlackGeneration   Function
+	UndercolorRemoval Function
+}
+
+// SetInkControl changes the halftone, transfer functions, black generation
+// or undercolor removal used for what's painted after it.
+func (d *Document) SetInkControl(c *InkControl) (err error) {
+	defer dontPanic(&err)
+
+	d.content()
+	g := map[string]interface{}{
+		"Type": name("ExtGState"),
+	}
+	switch {
+	case c.DefaultHalftone:
+		g["HT"] = name("Default")
+	case c.Halftone != nil:
+		g["HT"] = c.Halftone.object(d)
+	}
+	switch len(c.Transfer) {
+	case 0:
+	case 1:
+		g["TR"] = d.function(c.Transfer[0], 1, 1, "transfer function")
+	case 4:
+		tr := make([]interface{}, 4)
+		for i, f := range c.Transfer {
+			if f == IdentityFunction {
+				panic("identity function as one of four transfer functions")
+			}
+			tr[i] = d.function(f, 1, 1, "transfer function")
+		}
+		g["TR"] = tr
+	default:
+		panic(fmt.Sprint(len(c.Transfer), " transfer functions; expected 1 or 4"))
+	}
+	if c.BlackGeneration != nil {
+		g["BG"] = d.function(c.BlackGeneration, 1, 1, "black generation")
+	}
+	if c.UndercolorRemoval != nil {
+		g["UCR"] = d.function(c.UndercolorRemoval, 1, 1, "undercolor removal")
+	}
+	if len(g) == 1 {
+		panic("ink control with nothing set")
+	}
+	i := d.extGState(g)
+	d.addw(d.ops().Name(d.useResource("ExtGState", resName("GS", i), i)).Op("gs"))
+	return nil
+}
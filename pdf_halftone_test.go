@@ -0,0 +1,112 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSetInkControl(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(100, 100)
+	gain := &SampledFunction{Range: []float64{0, 1}, Size: []int{3}, Samples: []byte{0, 110, 255}}
+	if err := d.SetInkControl(&InkControl{
+		Halftone:          &Halftone{Frequency: 150, Angle: 45, Spot: SpotEllipse, Accurate: true},
+		Transfer:          []Function{gain},
+		BlackGeneration:   &ExponentialFunction{N: 1},
+		UndercolorRemoval: &PostScriptFunction{Domain: []float64{0, 1}, Range: []float64{0, 1}, Code: "0.5 mul"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetInkControl(&InkControl{DefaultHalftone: true,
+		Transfer: []Function{gain, gain, gain, IdentityFunction}}); err == nil ||
+		!strings.Contains(err.Error(), "identity function") {
+		t.Errorf("got error %v for identity among four transfer functions", err)
+	}
+	if err := d.SetInkControl(&InkControl{DefaultHalftone: true,
+		Transfer: []Function{gain, gain, gain, gain}}); err != nil {
+		t.Fatal(err)
+	}
+	spot := &PostScriptFunction{Domain: []float64{-1, 1, -1, 1}, Range: []float64{-1, 1},
+		Code: "dup mul exch dup mul add 1 exch sub"}
+	if err := d.SetInkControl(&InkControl{Halftone: &Halftone{Frequency: 85, Function: spot}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		c   InkControl
+		err string
+	}{
+		{InkControl{}, "pdf.go: ink control with nothing set"},
+		{InkControl{Halftone: &Halftone{}}, "pdf.go: bad frequency of halftone: 0"},
+		{InkControl{Halftone: &Halftone{Frequency: 1, Spot: "Star"}}, "pdf.go: unknown spot function: Star"},
+		{InkControl{Halftone: &Halftone{Frequency: 1, Function: gain}},
+			"pdf.go: spot function with 1 inputs and 1 outputs, expected 2 and 1"},
+		{InkControl{Transfer: []Function{gain, gain}}, "pdf.go: 2 transfer functions; expected 1 or 4"},
+		{InkControl{BlackGeneration: spot},
+			"pdf.go: black generation with 2 inputs and 1 outputs, expected 1 and 1"},
+	} {
+		if err := d.SetInkControl(&tt.c); fmt.Sprint(err) != tt.err {
+			t.Errorf("got %v expected %q", err, tt.err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pg := r.pages()[0].dic
+	c := strings.Fields(string(r.contents(pg["Contents"])))
+	if len(c) != 6 {
+		t.Fatalf("content: got %q", c)
+	}
+	gs := r.resolve(r.resolve(pg["Resources"]).(map[string]interface{})["ExtGState"]).(map[string]interface{})
+	g := r.resolve(gs[c[0][1:]]).(map[string]interface{})
+	ht := r.resolve(g["HT"]).(map[string]interface{})
+	if ht["HalftoneType"] != 1 || ht["Frequency"] != 150 || ht["SpotFunction"] != name("Ellipse") ||
+		ht["AccurateScreens"] != true {
+		t.Errorf("halftone: got %v", ht)
+	}
+	if tr := r.resolve(g["TR"]).(*stream); tr.dic["FunctionType"] != 0 {
+		t.Errorf("transfer function: got %v", tr.dic)
+	}
+	if bg := r.resolve(g["BG"]).(map[string]interface{}); bg["FunctionType"] != 2 {
+		t.Errorf("black generation: got %v", bg)
+	}
+	if ucr := r.resolve(g["UCR"]).(*stream); string(r.decode(ucr)) != "{ 0.5 mul }" {
+		t.Errorf("undercolor removal: got %q", r.decode(ucr))
+	}
+	g = r.resolve(gs[c[2][1:]]).(map[string]interface{})
+	tr := r.resolve(g["TR"]).([]interface{})
+	if g["HT"] != name("Default") || len(tr) != 4 || tr[0] != tr[3] {
+		t.Errorf("second state: got %v", g)
+	}
+	g = r.resolve(gs[c[4][1:]]).(map[string]interface{})
+	ht = r.resolve(g["HT"]).(map[string]interface{})
+	if s := r.resolve(ht["SpotFunction"]).(*stream); s.dic["FunctionType"] != 4 {
+		t.Errorf("spot function: got %v", s.dic)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains hooks, which are called when pages are started and
+// ended and when documents are closed, so that things like headers and
+// stamps can be added to all pages without changing the code that makes them.
+
+// PageHook is called with a page of document d, which is the current page
+// while it's called. It shouldn't start or flush pages.
+type PageHook func(d *Document, p *Page) error
+
+// OnPageStart adds f to the hooks called after each page is started, by
+// NewPage or by the document itself, like for a flow or a table of contents.
+// Hooks are called in the order they're added.
+func (d *Document) OnPageStart(f PageHook) (err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	d.startHooks = append(d.startHooks, f)
+	return nil
+}
+
+// OnPageEnd adds f to the hooks called before each page is written to the
+// output, when it's flushed or when the document is closed. Content drawn by
+// f goes on top of the content of the page.
+func (d *Document) OnPageEnd(f PageHook) (err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	d.endHooks = append(d.endHooks, f)
+	return nil
+}
+
+// OnBeforeClose adds f to the hooks called when d is closed, before anything
+// of it is finished. f can still add pages and content to d.
+func (d *Document) OnBeforeClose(f func(d *Document) error) (err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	d.closeHooks = append(d.closeHooks, f)
+	return nil
+}
+
+// runPageHooks calls the hooks hs with pg, which is the current page.
+func (d *Document) runPageHooks(hs []PageHook, pg *page) {
+	for _, f := range hs {
+		check(f(d, &Page{d, pg}))
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	var calls []string
+	d.OnPageStart(func(d *Document, p *Page) error {
+		calls = append(calls, fmt.Sprint("start ", p.Number()))
+		return d.Rectangle(1, 1, 1, 1)
+	})
+	d.OnPageEnd(func(d *Document, p *Page) error {
+		calls = append(calls, fmt.Sprint("end ", p.Number()))
+		return d.Rectangle(2, 2, 2, 2)
+	})
+	d.OnBeforeClose(func(d *Document) error {
+		calls = append(calls, "close")
+		_, err := d.NewPage(100, 100)
+		return err
+	})
+	p, _ := d.NewPage(100, 100)
+	d.Rectangle(3, 3, 3, 3)
+	p.Flush()
+	d.NewPage(100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(calls, ", "); s != "start 1, end 1, start 2, close, start 3, end 2, end 3" {
+		t.Errorf("calls: got %q", s)
+	}
+	if err := d.OnPageEnd(nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("hook added after Close: got %v", err)
+	}
+
+	r, _ := NewReader(buf.Bytes())
+	if con := string(r.contents(r.pages()[0].dic["Contents"])); con != "1 1 1 1 re\n3 3 3 3 re\n2 2 2 2 re\n\n" {
+		t.Errorf("content of page 1: got %q", con)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.OnPageStart(func(d *Document, p *Page) error {
+		return errors.New("no pages")
+	})
+	if _, err := d.NewPage(100, 100); fmt.Sprint(err) != "pdf.go: no pages" {
+		t.Errorf("error of hook: got %v", err)
+	}
+}
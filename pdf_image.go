@@ -0,0 +1,115 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file writes images to documents as image XObjects (p. 340).
+
+import (
+	"image"
+	"io"
+)
+
+// AddImage writes m to the output as an image XObject, to be drawn with
+// DrawXObject. The size of the XObject is the size of m in pixels, taken as
+// points. Gray images are written in DeviceGray and the others in DeviceRGB,
+// compressed with Flate, and images with transparent pixels get a soft mask.
+func (d *Document) AddImage(m image.Image) (x *XObject, err error) {
+	defer dontPanic(&err)
+
+	b := m.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= 0 || h <= 0 {
+		panic("empty image")
+	}
+	_, gray := m.(*image.Gray)
+	n := 3
+	if gray {
+		n = 1
+	}
+	// Pixels are compressed a row at a time, so that large images don't need
+	// to be in memory when there's a memory limit.
+	pix, pixw := d.flateSpill()
+	alpha, alphaw := d.flateSpill()
+	row := make([]byte, 0, w*n)
+	arow := make([]byte, 0, w)
+	opaque := true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row, arow = row[:0], arow[:0]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, b, a := m.At(x, y).RGBA()
+			// Colors are premultiplied by alpha.
+			if a > 0 && a < 0xffff {
+				r, g, b = r*0xffff/a, g*0xffff/a, b*0xffff/a
+			}
+			if gray {
+				row = append(row, byte(r>>8))
+			} else {
+				row = append(row, byte(r>>8), byte(g>>8), byte(b>>8))
+			}
+			arow = append(arow, byte(a>>8))
+			opaque = opaque && a == 0xffff
+		}
+		_, err = pixw.Write(row)
+		checkWrite(err)
+		_, err = alphaw.Write(arow)
+		checkWrite(err)
+	}
+	closeFlate(pixw)
+	closeFlate(alphaw)
+	dic := imageDict(w, h, "DeviceRGB")
+	if gray {
+		dic["ColorSpace"] = name("DeviceGray")
+	}
+	if !opaque {
+		sm := d.reserveIndirect()
+		d.outputSpill(sm, imageDict(w, h, "DeviceGray"), alpha)
+		dic["SMask"] = sm
+	}
+	if len(d.objAF) > 0 {
+		dic["AF"] = d.objAF
+	}
+	i := d.reserveIndirect()
+	d.outputSpill(i, dic, pix)
+	return &XObject{ref: i, w: float64(w), h: float64(h), image: true}, nil
+}
+
+// imageDict returns the dictionary of an image XObject of w×h pixels with 8
+// bits per component in color space cs, compressed with Flate.
+func imageDict(w, h int, cs string) map[string]interface{} {
+	return map[string]interface{}{
+		"Type":             name("XObject"),
+		"Subtype":          name("Image"),
+		"Width":            w,
+		"Height":           h,
+		"ColorSpace":       name(cs),
+		"BitsPerComponent": 8,
+		"Filter":           name("FlateDecode"),
+	}
+}
+
+// flateSpill returns an empty spill of d, and a writer that compresses data
+// with Flate like the options of d say and writes it to the spill. The writer
+// should be closed by closeFlate.
+func (d *Document) flateSpill() (*spill, io.WriteCloser) {
+	s := d.newSpill()
+	return s, newFlate(s, d.opts.FlateLevel, d.opts.Flater)
+}
+
+// closeFlate closes w, made by flateSpill.
+func closeFlate(w io.WriteCloser) {
+	checkWrite(w.Close())
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestAddImage(t *testing.T) {
+	gray := &image.Gray{Pix: []byte{0, 128, 255, 64}, Stride: 2, Rect: image.Rect(0, 0, 2, 2)}
+	// The second pixel is half transparent red.
+	rgba := &image.RGBA{Pix: []byte{0, 0, 255, 255, 128, 0, 0, 128}, Stride: 8, Rect: image.Rect(0, 0, 2, 1)}
+
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(100, 100)
+	for _, m := range []image.Image{gray, rgba} {
+		x, err := d.AddImage(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w, h := x.Size(); w != float64(m.Bounds().Dx()) || h != float64(m.Bounds().Dy()) {
+			t.Errorf("size of image: got %gx%g", w, h)
+		}
+		d.DrawXObject(x, 10, 10, 20, 30)
+	}
+	if _, err := d.AddImage(&image.Gray{}); err == nil {
+		t.Error("no error for empty image")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(" 20 0 0 30 10 10 cm /Im")) {
+		t.Error("image not drawn in the unit square")
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := r.Images()
+	if err != nil || len(ims) != 2 {
+		t.Fatalf("images: got %v, %v", ims, err)
+	}
+	for i, want := range []string{
+		"0 0 0, 128 128 128|255 255 255, 64 64 64",
+		"0 0 255, 255 0 0",
+	} {
+		m, err := ims[i].Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pixels(m); got != want {
+			t.Errorf("pixels of image %d: got %s, want %s", i, got, want)
+		}
+	}
+	if ims[0].ColorSpace != "DeviceGray" || ims[1].ColorSpace != "DeviceRGB" {
+		t.Errorf("color spaces: got %s and %s", ims[0].ColorSpace, ims[1].ColorSpace)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/SMask ")) {
+		t.Error("no soft mask for transparent image")
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file makes the index of documents from the terms marked in them: an
+// alphabetical list of the terms with the numbers of the pages they are on.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// indexTerm is a place a term of the index is on.
+type indexTerm struct {
+	term string
+	spot
+}
+
+// AddIndexTerm adds term to the index of d, at y on the current page.
+// Paragraphs of flows with IndexTerms add them when they are drawn.
+func (d *Document) AddIndexTerm(term string, y float64) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "index term added before any page was started")
+	}
+	if strings.TrimSpace(term) == "" {
+		panic("empty index term")
+	}
+	d.terms = append(d.terms, &indexTerm{term, d.spot(y)})
+	return nil
+}
+
+// Index is an alphabetical list of the terms of a document with the numbers
+// of the pages they are on, in columns on pages at the end of the document.
+// Page numbers link to the terms.
+type Index struct {
+	Title      string    // drawn above the columns if it's not empty
+	TitleStyle TextStyle // style of the title
+	Style      TextStyle // style of the entries
+	Columns    int       // number of columns; 2 if zero
+	Gap        float64   // space between columns
+
+	// Size of the pages of the index; the size of the last page of the
+	// document if zero.
+	Width, Height int
+	Margins       Margins
+}
+
+// SetIndex makes d have index x, made from its terms when d is closed.
+func (d *Document) SetIndex(x *Index) (err error) {
+	defer dontPanic(&err)
+
+	if x.Columns < 0 {
+		panic(fmt.Sprintf("bad number of columns of index: %d", x.Columns))
+	}
+	d.index = x
+	return nil
+}
+
+// indexLink is a page number in a line of the index.
+type indexLink struct {
+	x, w float64 // offset from the start of the line and width
+	*indexTerm
+}
+
+// indexLine is a line of an entry of the index.
+type indexLine struct {
+	text   string // WinAnsi encoded
+	indent float64
+	links  []indexLink
+}
+
+// Len, Less and Swap sort terms of the index alphabetically, ignoring case.
+// Places of the same term stay in order.
+type indexTerms []*indexTerm
+
+func (ts indexTerms) Len() int {
+	return len(ts)
+}
+
+func (ts indexTerms) Less(i, j int) bool {
+	a, b := strings.ToLower(ts[i].term), strings.ToLower(ts[j].term)
+	if a != b {
+		return a < b
+	}
+	if ts[i].term != ts[j].term {
+		return ts[i].term < ts[j].term
+	}
+	return ts[i].page < ts[j].page
+}
+
+func (ts indexTerms) Swap(i, j int) {
+	ts[i], ts[j] = ts[j], ts[i]
+}
+
+// entryLines returns the lines of the entry of the index for places ts of a
+// term, in a column of width w. Lines after the first are indented.
+func (d *Document) entryLines(ts []*indexTerm, w float64) []indexLine {
+	st := &d.index.Style
+	size := st.size()
+	width := func(s string) float64 {
+		return st.width(s, size)
+	}
+	in := size
+	var lines []indexLine
+	for _, l := range st.wrap(ts[0].term, w) {
+		lines = append(lines, indexLine{text: l, indent: in})
+	}
+	lines[0].indent = 0
+	cur := &lines[len(lines)-1]
+	for i, t := range ts {
+		if i > 0 && t.page == ts[i-1].page {
+			continue
+		}
+		n := winAnsi(d.pageNumber(t.page))
+		if next := cur.text + ", " + n; cur.indent+width(next) <= w {
+			cur.links = append(cur.links, indexLink{width(cur.text + ", "), width(n), t})
+			cur.text = next
+			continue
+		}
+		cur.text += ","
+		lines = append(lines, indexLine{n, in, []indexLink{{0, width(n), t}}})
+		cur = &lines[len(lines)-1]
+	}
+	return lines
+}
+
+// makeIndex draws the index of d on new pages at the end of the document.
+func (d *Document) makeIndex() {
+	x := d.index
+	if len(d.terms) == 0 {
+		return
+	}
+	st, ts := &x.Style, &x.TitleStyle
+	size, lh := st.size(), st.leading()
+	w, h, left, right, top, bottom := d.pageBox(x.Width, x.Height, x.Margins, "index")
+	cols := x.Columns
+	if cols == 0 {
+		cols = 2
+	}
+	colW := (right - left - x.Gap*float64(cols-1)) / float64(cols)
+	if colW <= 0 {
+		panic("columns of index are wider than its pages")
+	}
+
+	terms := make(indexTerms, len(d.terms))
+	copy(terms, d.terms)
+	sort.Sort(terms)
+
+	d.newPage(w, h)
+	y := top
+	if x.Title != "" {
+		d.pageFont(ts.font())
+		for _, l := range ts.wrap(x.Title, right-left) {
+			d.addc(strings.TrimRight(ts.line(l, left, baseline(y-ts.leading(), ts.leading(), ts.size()), right-left, ts.size()), "\n"))
+			y -= ts.leading()
+		}
+		y -= lh
+	}
+	colTop, col := y, 0
+	for i := 0; i < len(terms); {
+		j := i + 1
+		for j < len(terms) && terms[j].term == terms[i].term {
+			j++
+		}
+		// Terms that start with another letter are after an empty line.
+		if i > 0 && y < colTop && strings.ToLower(terms[i].term[:1]) != strings.ToLower(terms[i-1].term[:1]) {
+			y -= lh
+		}
+		for _, l := range d.entryLines(terms[i:j], colW) {
+			if y-lh < bottom && y < colTop {
+				col++
+				if col == cols {
+					d.newPage(w, h)
+					col, colTop = 0, top
+				}
+				y = colTop
+			}
+			lx := left + float64(col)*(colW+x.Gap) + l.indent
+			d.pageFont(st.font())
+			d.addc(strings.TrimRight(st.text(l.text, lx, baseline(y-lh, lh, size), size), "\n"))
+			for _, k := range l.links {
+				d.addAnnot(map[string]interface{}{
+					"Subtype": name("Link"),
+					"Rect":    newRect(lx+k.x, y-lh, lx+k.x+k.w, y),
+					"Border":  []int{0, 0, 0},
+					"F":       4, // Print, which PDF/A needs
+					"Dest":    k.dest(),
+				})
+			}
+			y -= lh
+		}
+		i = j
+	}
+}
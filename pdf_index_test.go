@@ -0,0 +1,92 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	f, _ := d.NewFlow(200, 200, Margins{20, 20, 20, 20})
+	d.SetTableOfContents(&TableOfContents{Style: courier, Page: 1, Margins: Margins{20, 20, 20, 20}})
+	err := d.SetIndex(&Index{Title: "Index", TitleStyle: courier, Style: courier, Gap: 10,
+		Margins: Margins{20, 20, 20, 20}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range []Block{
+		&Paragraph{Text: "a", Heading: 1, IndexTerms: []string{"beta", "alpha"}, Style: courier},
+		&Paragraph{Text: "b", IndexTerms: []string{"Beta"}, Style: courier},
+		lines(20),
+		&Paragraph{Text: "c", IndexTerms: []string{"beta", "gamma"}, Style: courier},
+	} {
+		if err := f.Add(b); err != nil {
+			t.Fatalf("%d. %s", i, err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := r.NumPages()
+	runs, _ := r.PageText(n)
+	var got []string
+	for _, run := range runs {
+		got = append(got, fmt.Sprint(ftoa(run.X), " ", ftoa(run.Y), " ", run.Text))
+	}
+	want := "20 171.45 Index|20 148.45 alpha, 2|20 125.45 Beta, 2|20 113.95 beta, 2, 3|20 90.95 gamma, 3"
+	if g := strings.Join(got, "|"); g != want {
+		t.Errorf("index: got %q, want %q", g, want)
+	}
+	// One link in the table of contents, and one for each page number
+	if n := bytes.Count(buf.Bytes(), []byte("/Subtype /Link")); n != 6 {
+		t.Errorf("number of links: got %d, want 6", n)
+	}
+}
+
+func TestIndexColumns(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(200, 200)
+	for i := 0; i < 20; i++ {
+		d.AddIndexTerm(fmt.Sprintf("t%02d", i), 100)
+	}
+	if err := d.AddIndexTerm(" ", 100); err == nil {
+		t.Error("no error for an empty term")
+	}
+	// Columns are 75 wide, with room for 13 lines.
+	d.SetIndex(&Index{Style: courier, Gap: 10, Margins: Margins{20, 20, 20, 20}})
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, _ := r.PageText(2)
+	if len(runs) != 20 || fmt.Sprint(ftoa(runs[13].X), " ", ftoa(runs[13].Y), " ", runs[13].Text) != "105 171.45 t13, 1" {
+		t.Errorf("index: got %v", runs)
+	}
+}
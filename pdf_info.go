@@ -0,0 +1,146 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file deals with the metadata of documents: the document information
+// dictionary (p. 549) and the XMP metadata stream (p. 556), which say the
+// same things for different readers.
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// producer is the Producer of the documents, unless it's changed by their
+// options.
+const producer = "pdf.go"
+
+// Info holds the metadata of a document. All of the fields are optional.
+type Info struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	Creator  string    // application that made the original of the document
+	Created  time.Time // creation time; time of Close if zero
+}
+
+// SetInfo sets the metadata of the document.
+func (d *Document) SetInfo(i *Info) (err error) {
+	defer dontPanic(&err)
+
+	if i == nil {
+		panic("SetInfo called with nil")
+	}
+	c := *i
+	d.info = &c
+	return nil
+}
+
+// created returns the creation time of the document, setting it the first
+// time.
+func (d *Document) created() time.Time {
+	if d.info == nil {
+		d.info = new(Info)
+	}
+	if d.info.Created.IsZero() {
+		d.info.Created = d.now()
+	}
+	return d.info.Created
+}
+
+// saveInfo writes the information dictionary to the output, if there's any
+// metadata.
+func (d *Document) saveInfo() {
+	if d.info == nil && d.pdfx == "" && len(d.exts[ExtendInfo]) == 0 {
+		return
+	}
+	t := date(d.created())
+	dic := map[string]interface{}{
+		"Producer":     textString(d.opts.Producer),
+		"CreationDate": t,
+		"ModDate":      t,
+	}
+	if d.pdfx != "" {
+		dic["GTS_PDFXVersion"] = d.pdfx
+		dic["Trapped"] = name("False")
+	}
+	for k, v := range map[string]string{"Title": d.info.Title, "Author": d.info.Author,
+		"Subject": d.info.Subject, "Keywords": d.info.Keywords, "Creator": d.info.Creator} {
+		if v != "" {
+			dic[k] = textString(v)
+		}
+	}
+	d.infoRef = d.indirect(d.extend(ExtendInfo, dic, nil))
+}
+
+// xmlEscape returns s escaped to be used as XML text.
+func xmlEscape(s string) string {
+	buf := bytes.NewBuffer(nil)
+	xml.Escape(buf, []byte(s))
+	return buf.String()
+}
+
+// xmp returns the XMP metadata packet of the document. descs are the
+// rdf:Description elements of the document other than the ones of the
+// information dictionary.
+func (d *Document) xmp(descs []string) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n" +
+		"<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n" +
+		"<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+
+	t := d.created().Format("2006-01-02T15:04:05-07:00")
+	fmt.Fprintf(buf, "<rdf:Description rdf:about=\"\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\">\n"+
+		"<xmp:CreateDate>%s</xmp:CreateDate>\n<xmp:ModifyDate>%s</xmp:ModifyDate>\n", t, t)
+	if d.info.Creator != "" {
+		fmt.Fprintf(buf, "<xmp:CreatorTool>%s</xmp:CreatorTool>\n", xmlEscape(d.info.Creator))
+	}
+	buf.WriteString("</rdf:Description>\n")
+
+	fmt.Fprintf(buf, "<rdf:Description rdf:about=\"\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n"+
+		"<pdf:Producer>%s</pdf:Producer>\n", xmlEscape(d.opts.Producer))
+	if d.info.Keywords != "" {
+		fmt.Fprintf(buf, "<pdf:Keywords>%s</pdf:Keywords>\n", xmlEscape(d.info.Keywords))
+	}
+	buf.WriteString("</rdf:Description>\n")
+
+	if d.info.Title != "" || d.info.Author != "" || d.info.Subject != "" {
+		buf.WriteString("<rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+		if d.info.Title != "" {
+			fmt.Fprintf(buf, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n",
+				xmlEscape(d.info.Title))
+		}
+		if d.info.Author != "" {
+			fmt.Fprintf(buf, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n",
+				xmlEscape(d.info.Author))
+		}
+		if d.info.Subject != "" {
+			fmt.Fprintf(buf, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n",
+				xmlEscape(d.info.Subject))
+		}
+		buf.WriteString("</rdf:Description>\n")
+	}
+
+	for _, s := range descs {
+		buf.WriteString(s)
+	}
+	buf.WriteString("</rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>")
+	return buf.Bytes()
+}
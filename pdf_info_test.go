@@ -0,0 +1,72 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetInfo(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.SetInfo(&Info{
+		Title:   "Report",
+		Author:  "سلام",
+		Created: time.Date(2011, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	d.NewPage(100, 100)
+	d.Close()
+
+	b := buf.String()
+	for _, s := range []string{
+		"/Title (Report)", "/Author (\xfe\xff\x06\x33\x06\x44\x06\x27\x06\x45)",
+		"/CreationDate (D:20110102030405Z)", "/Producer (pdf.go)", "/Info 4 0 R",
+	} {
+		if !strings.Contains(b, s) {
+			t.Errorf("document doesn't have %q", s)
+		}
+	}
+	if strings.Contains(b, "/Metadata") {
+		t.Error("XMP metadata written for a document that doesn't need it")
+	}
+}
+
+func TestXMP(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	d.SetInfo(&Info{
+		Title:   "A & B",
+		Subject: "<C>",
+		Created: time.Date(2011, 1, 2, 3, 4, 5, 0, time.FixedZone("IRST", 12600)),
+	})
+	x := string(d.xmp([]string{"<rdf:Description/>\n"}))
+	for _, s := range []string{
+		"<xmp:CreateDate>2011-01-02T03:04:05+03:30</xmp:CreateDate>",
+		"<rdf:li xml:lang=\"x-default\">A &amp; B</rdf:li>",
+		"<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">&lt;C&gt;</rdf:li>",
+		"<pdf:Producer>pdf.go</pdf:Producer>", "<rdf:Description/>\n</rdf:RDF>",
+	} {
+		if !strings.Contains(x, s) {
+			t.Errorf("XMP doesn't have %q", s)
+		}
+	}
+	if strings.Contains(x, "dc:creator") {
+		t.Error("XMP has an empty author")
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains output intents (p. 633), which describe the color
+// characteristics of the devices the document is made for, like a printing
+// press. PDF/A and PDF/X need them to reproduce the colors of documents.
+
+import (
+	"strings"
+)
+
+// Subtypes of output intents
+const (
+	IntentPDFX = "GTS_PDFX"
+	IntentPDFA = "GTS_PDFA1"
+)
+
+// outputIntent is an output intent of the document.
+type outputIntent struct {
+	subtype string
+	cond    string // identifier of the output condition
+	profile []byte // ICC profile of the condition, if any
+}
+
+// AddOutputIntent adds an output intent of type subtype, like IntentPDFX or
+// IntentPDFA, to the document. condition is the name of the output condition,
+// like "FOGRA39", and iccProfile is its ICC profile. The profile can be nil
+// if the condition is in the registry of ICC (http://www.color.org). Intents
+// with the same profile share it in the output.
+//
+// SetPDFX adds the intent of PDF/X itself, and PDF/A documents have an sRGB
+// intent unless another one is added.
+func (d *Document) AddOutputIntent(subtype, condition string, iccProfile []byte) (err error) {
+	defer dontPanic(&err)
+
+	d.addIntent(subtype, condition, iccProfile)
+	return nil
+}
+
+// addIntent adds an output intent to the document.
+func (d *Document) addIntent(subtype, condition string, profile []byte) {
+	if subtype == "" {
+		panic("output intent with no subtype")
+	}
+	if condition == "" {
+		panic("output intent with no output condition")
+	}
+	if d.intent(subtype) != nil {
+		panic("output intent is already added: " + subtype)
+	}
+	if profile != nil {
+		iccSpace(profile) // check it
+	}
+	d.intents = append(d.intents, &outputIntent{subtype, condition, profile})
+}
+
+// intent returns the output intent of the document with the given subtype, or
+// nil if there's none.
+func (d *Document) intent(subtype string) *outputIntent {
+	for _, i := range d.intents {
+		if i.subtype == subtype {
+			return i
+		}
+	}
+	return nil
+}
+
+// iccSpace returns the color space of ICC profile p, which is "RGB", "CMYK"
+// or "GRAY", and panics if p is not a profile.
+func iccSpace(p []byte) string {
+	if len(p) < 128 || string(p[36:40]) != "acsp" {
+		panic("bad ICC profile")
+	}
+	s := strings.TrimSpace(string(p[16:20]))
+	switch s {
+	case "RGB", "CMYK", "GRAY":
+		return s
+	}
+	panic("color space of ICC profile is not RGB, CMYK or gray")
+}
+
+// intentSpace returns the color space of the output intent of the standard
+// the document conforms to. Registered printing conditions of PDF/X with no
+// profile are CMYK, and PDF/A is sRGB by default.
+func (d *Document) intentSpace() string {
+	if i := d.intent(IntentPDFX); i != nil && d.pdfx != "" {
+		if i.profile == nil {
+			return "CMYK"
+		}
+		return iccSpace(i.profile)
+	}
+	if i := d.intent(IntentPDFA); i != nil && i.profile != nil {
+		return iccSpace(i.profile)
+	}
+	return "RGB"
+}
+
+// outputIntents writes the profiles of the output intents of the document
+// to the output, and returns the intents. PDF/A documents without an intent
+// of their own get the one of PDF/X, or sRGB.
+func (d *Document) outputIntents() []interface{} {
+	intents := d.intents
+	if d.pdfa && d.intent(IntentPDFA) == nil {
+		a := &outputIntent{IntentPDFA, srgbName, srgbProfile()}
+		if x := d.intent(IntentPDFX); x != nil {
+			a.cond, a.profile = x.cond, x.profile
+		}
+		intents = append(intents, a)
+	}
+
+	var out []interface{}
+	profiles := make(map[string]*indirect)
+	for _, i := range intents {
+		dic := map[string]interface{}{
+			"Type":                      name("OutputIntent"),
+			"S":                         name(i.subtype),
+			"OutputConditionIdentifier": i.cond,
+			"Info":                      i.cond,
+		}
+		if i.profile != nil {
+			ref, ok := profiles[string(i.profile)]
+			if !ok {
+				n := map[string]int{"RGB": 3, "CMYK": 4, "GRAY": 1}[iccSpace(i.profile)]
+				ref = d.indirect(&stream{map[string]interface{}{"N": n}, i.profile})
+				profiles[string(i.profile)] = ref
+			}
+			dic["DestOutputProfile"] = ref
+		} else {
+			dic["RegistryName"] = "http://www.color.org"
+			if d.pdfa && i.subtype == IntentPDFA {
+				// Registered conditions can go without profiles,
+				// but PDF/A needs one anyway.
+				d.violate("PDF/A-3", "output intents should have ICC profiles")
+			}
+		}
+		out = append(out, dic)
+	}
+	if d.pdfa && len(profiles) > 1 {
+		d.violate("PDF/A-3", "output intents should have the same ICC profile")
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAddOutputIntent(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.AddOutputIntent("", "FOGRA39", nil); err == nil {
+		t.Error("output intent with no subtype")
+	}
+	if err := d.AddOutputIntent(IntentPDFX, "", nil); err == nil {
+		t.Error("output intent with no condition")
+	}
+	if err := d.AddOutputIntent(IntentPDFX, "FOGRA39", []byte("profile")); err == nil {
+		t.Error("output intent with bad profile")
+	}
+	p := testProfile("CMYK")
+	if err := d.AddOutputIntent(IntentPDFX, "FOGRA39", p); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddOutputIntent(IntentPDFX, "FOGRA39", p); err == nil {
+		t.Error("output intent added twice")
+	}
+	if err := d.AddOutputIntent("ISO_PDFE1", "FOGRA39", p); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddOutputIntent("Custom", "CGATS TR 001", nil); err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.String()
+	for _, s := range []string{"/S /GTS_PDFX", "/S /ISO_PDFE1", "/S /Custom",
+		"/RegistryName (http://www.color.org)", "/OutputConditionIdentifier (CGATS TR 001)"} {
+		if !strings.Contains(b, s) {
+			t.Errorf("document doesn't have %q", s)
+		}
+	}
+	if n := strings.Count(b, "/DestOutputProfile 4 0 R"); n != 2 {
+		t.Errorf("intents sharing the profile: got %d expected 2", n)
+	}
+}
+
+func TestPDFAOutputIntent(t *testing.T) {
+	// PDF/A with a CMYK intent allows CMYK colors.
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.SetPDFA3()
+	d.AddOutputIntent(IntentPDFA, "FOGRA39", testProfile("CMYK"))
+	d.NewPage(100, 100)
+	d.PushButton(0, 0, 10, 10, &Button{Name: "b",
+		FieldStyle: FieldStyle{Border: CMYK{0, 0, 0, 1}}})
+	err := d.Close()
+	if strings.Contains(fmt.Sprint(err), "colors need") {
+		t.Errorf("CMYK color with CMYK output intent: %v", err)
+	}
+	if strings.Contains(buf.String(), srgbName) {
+		t.Error("sRGB output intent added to PDF/A document with an intent")
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.SetPDFA3()
+	d.AddOutputIntent("Custom", "FOGRA39", testProfile("CMYK"))
+	d.NewPage(100, 100)
+	err = d.Close()
+	if !strings.Contains(fmt.Sprint(err), "output intents should have the same ICC profile") {
+		t.Errorf("PDF/A with different profiles: got error %v", err)
+	}
+}
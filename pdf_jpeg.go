@@ -0,0 +1,193 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file writes JPEG images to documents as they are, with the DCTDecode
+// filter, instead of decoding and compressing them again.
+
+import (
+	"encoding/binary"
+)
+
+// exifOrientations holds the matrices that map the unit square of images
+// with each EXIF orientation to the way they should be shown. The first row
+// of an image is at the top of the square.
+var exifOrientations = [9]matrix{
+	1: identity,
+	2: {-1, 0, 0, 1, 1, 0},  // flipped left to right
+	3: {-1, 0, 0, -1, 1, 1}, // turned 180°
+	4: {1, 0, 0, -1, 0, 1},  // flipped top to bottom
+	5: {0, -1, -1, 0, 1, 1}, // flipped across the diagonal from the top left
+	6: {0, -1, 1, 0, 0, 1},  // turned 90° clockwise
+	7: {0, 1, 1, 0, 0, 0},   // flipped across the diagonal from the top right
+	8: {0, 1, -1, 0, 1, 0},  // turned 90° counterclockwise
+}
+
+// jpegInfo holds what's needed of the headers of a JPEG image.
+type jpegInfo struct {
+	w, h   int
+	comps  int // number of color components
+	bits   int // bits per component
+	adobe  bool
+	xform  int // color transform of the Adobe marker
+	orient int // EXIF orientation, or 0
+}
+
+// AddJPEG writes the JPEG image in b to the output as an image XObject, to be
+// drawn with DrawXObject. The image is not decoded; its data is written as
+// it is. Gray, YCbCr, RGB, CMYK and YCCK images are supported, including the
+// inverted CMYK images of Adobe applications. Images with an EXIF orientation
+// are drawn turned or flipped like it says, and the size of the XObject is
+// their size after that, in pixels taken as points.
+func (d *Document) AddJPEG(b []byte) (x *XObject, err error) {
+	defer dontPanic(&err)
+
+	j := parseJPEG(b)
+	dic := map[string]interface{}{
+		"Type":             name("XObject"),
+		"Subtype":          name("Image"),
+		"Width":            j.w,
+		"Height":           j.h,
+		"BitsPerComponent": 8,
+		"Filter":           name("DCTDecode"),
+	}
+	switch j.comps {
+	case 1:
+		dic["ColorSpace"] = name("DeviceGray")
+	case 3:
+		dic["ColorSpace"] = name("DeviceRGB")
+	case 4:
+		dic["ColorSpace"] = name("DeviceCMYK")
+		// Adobe applications write the inks of CMYK images inverted, 1
+		// for none and 0 for all.
+		if j.adobe {
+			dic["Decode"] = []int{1, 0, 1, 0, 1, 0, 1, 0}
+		}
+	}
+	// The transform of the Adobe marker is used by viewers anyway, but
+	// some don't read the marker.
+	if j.adobe {
+		t := 0
+		if j.xform != 0 {
+			t = 1
+		}
+		dic["DecodeParms"] = map[string]interface{}{"ColorTransform": t}
+	}
+	if len(d.objAF) > 0 {
+		dic["AF"] = d.objAF
+	}
+	i := d.indirect(&stream{dic, b})
+	x = &XObject{ref: i, w: float64(j.w), h: float64(j.h), image: true, orient: j.orient}
+	if j.orient >= 5 {
+		x.w, x.h = x.h, x.w
+	}
+	return x, nil
+}
+
+// parseJPEG returns the information in the headers of JPEG image b,
+// panicking if it's not supported.
+func parseJPEG(b []byte) *jpegInfo {
+	if len(b) < 4 || b[0] != 0xff || b[1] != 0xd8 {
+		fail(ErrBadFile, "not a JPEG image")
+	}
+	j := &jpegInfo{}
+	for p := 2; ; {
+		// Markers can be preceded by any number of 0xff.
+		for p < len(b) && b[p] == 0xff {
+			p++
+		}
+		if p >= len(b) || b[p-1] != 0xff {
+			fail(ErrBadFile, "bad JPEG image")
+		}
+		m := b[p]
+		p++
+		if m == 0x01 || m >= 0xd0 && m <= 0xd7 {
+			continue // markers with no data
+		}
+		if p+2 > len(b) {
+			fail(ErrBadFile, "JPEG image too short")
+		}
+		n := int(binary.BigEndian.Uint16(b[p:]))
+		if n < 2 || p+n > len(b) {
+			fail(ErrBadFile, "JPEG image too short")
+		}
+		seg := b[p+2 : p+n]
+		p += n
+		switch {
+		case m == 0xda || m == 0xd9: // start of scan or end of image
+			if j.comps == 0 {
+				fail(ErrBadFile, "JPEG image with no frame header")
+			}
+			return j
+		case m >= 0xc0 && m <= 0xcf && m != 0xc4 && m != 0xc8 && m != 0xcc:
+			if len(seg) < 6 {
+				fail(ErrBadFile, "bad frame header in JPEG image")
+			}
+			j.bits, j.comps = int(seg[0]), int(seg[5])
+			j.h, j.w = int(binary.BigEndian.Uint16(seg[1:])), int(binary.BigEndian.Uint16(seg[3:]))
+			if j.bits != 8 {
+				fail(ErrBadFile, "JPEG images must have 8 bits per component")
+			}
+			if j.comps != 1 && j.comps != 3 && j.comps != 4 {
+				fail(ErrBadFile, "JPEG image with 2 or more than 4 components")
+			}
+			if j.w == 0 || j.h == 0 {
+				fail(ErrBadFile, "JPEG image with no size in the frame header")
+			}
+		case m == 0xee && len(seg) >= 12 && string(seg[:5]) == "Adobe":
+			j.adobe, j.xform = true, int(seg[11])
+		case m == 0xe1 && len(seg) >= 6 && string(seg[:6]) == "Exif\x00\x00":
+			j.orient = exifOrientation(seg[6:])
+		}
+	}
+}
+
+// exifOrientation returns the orientation in the first IFD of EXIF data t,
+// which is in TIFF format, or 0 if it has none.
+func exifOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 0
+	}
+	var o binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		o = binary.LittleEndian
+	case "MM":
+		o = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(o.Uint32(t[4:]))
+	if ifd < 8 || ifd+2 > len(t) {
+		return 0
+	}
+	n := int(o.Uint16(t[ifd:]))
+	for i := 0; i < n; i++ {
+		e := ifd + 2 + 12*i
+		if e+12 > len(t) {
+			break
+		}
+		// Orientation is a SHORT, kept in the first bytes of the value.
+		if o.Uint16(t[e:]) == 0x0112 && o.Uint16(t[e+2:]) == 3 {
+			if v := int(o.Uint16(t[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 0
+		}
+	}
+	return 0
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// testSegment returns the JPEG marker segment m with data b.
+func testSegment(m byte, b []byte) []byte {
+	s := []byte{0xff, m, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(b)+2))
+	return append(s, b...)
+}
+
+// testExif returns the APP1 segment of EXIF data with the given orientation,
+// in big-endian byte order if mm is true and little-endian otherwise.
+func testExif(orient int, mm bool) []byte {
+	var o binary.ByteOrder = binary.LittleEndian
+	t := make([]byte, 8+2+2*12+4)
+	copy(t, "II*\x00")
+	if mm {
+		o = binary.BigEndian
+		copy(t, "MM\x00*")
+	}
+	o.PutUint32(t[4:], 8)
+	o.PutUint16(t[8:], 2)
+	// An entry before the orientation, and the orientation.
+	o.PutUint16(t[10:], 0x010f)
+	o.PutUint16(t[12:], 2)
+	o.PutUint16(t[22:], 0x0112)
+	o.PutUint16(t[24:], 3)
+	o.PutUint32(t[26:], 1)
+	o.PutUint16(t[30:], uint16(orient))
+	return testSegment(0xe1, append([]byte("Exif\x00\x00"), t...))
+}
+
+// testJPEGHeaders returns the headers of a JPEG image of w×h pixels with
+// the given components and bits per component, with the segments segs
+// before the frame header.
+func testJPEGHeaders(w, h, comps, bits int, segs ...[]byte) []byte {
+	b := []byte{0xff, 0xd8}
+	for _, s := range segs {
+		b = append(b, s...)
+	}
+	sof := []byte{byte(bits), byte(h >> 8), byte(h), byte(w >> 8), byte(w), byte(comps)}
+	for i := 0; i < comps; i++ {
+		sof = append(sof, byte(i+1), 0x11, 0)
+	}
+	b = append(b, testSegment(0xc0, sof)...)
+	return append(b, testSegment(0xda, []byte{0})...)
+}
+
+type parseJPEGTest struct {
+	b   []byte
+	j   jpegInfo
+	err bool
+}
+
+func TestParseJPEG(t *testing.T) {
+	adobe := testSegment(0xee, []byte("Adobe\x00\x64\x00\x00\x00\x00\x02"))
+	tests := []parseJPEGTest{
+		{testJPEGHeaders(30, 20, 3, 8), jpegInfo{30, 20, 3, 8, false, 0, 0}, false},
+		{testJPEGHeaders(300, 200, 1, 8, testExif(6, true)), jpegInfo{300, 200, 1, 8, false, 0, 6}, false},
+		{testJPEGHeaders(30, 20, 4, 8, testExif(3, false), adobe), jpegInfo{30, 20, 4, 8, true, 2, 3}, false},
+		// Fill bytes before markers, and an orientation out of range.
+		{append([]byte{0xff, 0xd8, 0xff}, testJPEGHeaders(1, 2, 3, 8, testExif(9, true))[2:]...),
+			jpegInfo{1, 2, 3, 8, false, 0, 0}, false},
+		{testJPEGHeaders(30, 20, 3, 12), jpegInfo{}, true},
+		{testJPEGHeaders(30, 20, 2, 8), jpegInfo{}, true},
+		{testJPEGHeaders(30, 0, 3, 8), jpegInfo{}, true},
+		{testJPEGHeaders(30, 20, 3, 8)[:12], jpegInfo{}, true},
+		{[]byte{0xff, 0xd8, 0xff, 0xda, 0, 2}, jpegInfo{}, true},
+		{[]byte("GIF89a"), jpegInfo{}, true},
+	}
+
+	for i, test := range tests {
+		var j *jpegInfo
+		err := func() (err error) {
+			defer dontPanic(&err)
+			j = parseJPEG(test.b)
+			return nil
+		}()
+		if test.err {
+			if !errors.Is(err, ErrBadFile) {
+				t.Errorf("%d: got %v expected ErrBadFile", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+		} else if *j != test.j {
+			t.Errorf("%d: got %+v expected %+v", i, *j, test.j)
+		}
+	}
+}
+
+func TestAddJPEG(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for i := range m.Pix {
+		m.Pix[i] = 255
+	}
+	buf := bytes.NewBuffer(nil)
+	jpeg.Encode(buf, m, nil)
+	// The EXIF segment goes after the start of the image.
+	turned := append(append([]byte{0xff, 0xd8}, testExif(6, false)...), buf.Bytes()[2:]...)
+	cmyk := testJPEGHeaders(30, 20, 4, 8, testSegment(0xee, []byte("Adobe\x00\x64\x00\x00\x00\x00\x00")))
+
+	out := bytes.NewBuffer(nil)
+	d, _ := New(out)
+	d.NewPage(100, 100)
+	for i, test := range []struct {
+		b    []byte
+		w, h float64
+	}{{buf.Bytes(), 40, 20}, {turned, 20, 40}, {cmyk, 30, 20}} {
+		x, err := d.AddJPEG(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w, h := x.Size(); w != test.w || h != test.h {
+			t.Errorf("size of image %d: got %gx%g expected %gx%g", i, w, h, test.w, test.h)
+		}
+		d.DrawXObject(x, 10, 10, 20, 40)
+	}
+	if _, err := d.AddJPEG([]byte("not a JPEG")); !errors.Is(err, ErrBadFile) {
+		t.Errorf("AddJPEG of bad data: got %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		" 20 0 0 40 10 10 cm /Im",
+		" 0 -40 20 0 10 50 cm /Im",
+		"/ColorSpace /DeviceCMYK",
+		"/Decode [ 1 0 1 0 1 0 1 0 ]",
+		"/ColorTransform 0",
+	} {
+		if !bytes.Contains(out.Bytes(), []byte(s)) {
+			t.Errorf("no %q in the output", s)
+		}
+	}
+
+	r, err := NewReader(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ims, err := r.Images()
+	if err != nil || len(ims) != 3 {
+		t.Fatalf("images: got %v, %v", ims, err)
+	}
+	if ims[0].Filter != "DCTDecode" || !bytes.Equal(ims[0].Data, buf.Bytes()) {
+		t.Errorf("JPEG image not written as it is")
+	}
+	dm, err := ims[1].Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := dm.Bounds(); b.Dx() != 40 || b.Dy() != 20 {
+		t.Errorf("size of decoded image: got %v", b)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file lets content be marked with custom tags and property lists
+// (p. 850), apart from the tags of the structure of the document. They are
+// meant for the markup of workflows, like the regions of variable data, and
+// are ignored by viewers.
+
+// markedContent returns the operands of BMC and BDC, or of MP and DP, for tag
+// and property list props, which can be nil.
+func markedContent(tag string, props map[string]interface{}) string {
+	if tag == "" {
+		panic("marked content with no tag")
+	}
+	if _, ok := props["MCID"]; ok {
+		panic("MCID of marked content is set by BeginTag")
+	}
+	s := "/" + escapeName(tag)
+	if props != nil {
+		s += " " + string(output(props))
+	}
+	return s
+}
+
+// BeginMarkedContent marks the content added until EndMarkedContent is
+// called with tag, and the property list props if it's not nil. The values of
+// the properties are like the operands of Raw. Marked content can be nested,
+// and should be ended on the same page.
+func (d *Document) BeginMarkedContent(tag string, props map[string]interface{}) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil && d.xbox == nil {
+		fail(ErrNoPage, "BeginMarkedContent called before any page was started")
+	}
+	if props == nil {
+		d.addm(markedContent(tag, nil) + " BMC")
+	} else {
+		d.addm(markedContent(tag, props) + " BDC")
+	}
+	d.marked++
+	return nil
+}
+
+// EndMarkedContent ends the marked content begun last by BeginMarkedContent.
+func (d *Document) EndMarkedContent() (err error) {
+	defer dontPanic(&err)
+
+	if d.marked == 0 {
+		panic("EndMarkedContent called without BeginMarkedContent")
+	}
+	d.addm("EMC")
+	d.marked--
+	return nil
+}
+
+// MarkPoint marks a point of the content with tag, and the property list
+// props if it's not nil.
+func (d *Document) MarkPoint(tag string, props map[string]interface{}) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil && d.xbox == nil {
+		fail(ErrNoPage, "MarkPoint called before any page was started")
+	}
+	if props == nil {
+		d.addm(markedContent(tag, nil) + " MP")
+	} else {
+		d.addm(markedContent(tag, props) + " DP")
+	}
+	return nil
+}
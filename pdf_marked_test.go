@@ -0,0 +1,78 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMarkedContent(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.BeginMarkedContent("VarData", nil); !errors.Is(err, ErrNoPage) {
+		t.Errorf("marked content with no page: got %v expected ErrNoPage", err)
+	}
+	d.NewPage(100, 100)
+	if err := d.BeginMarkedContent("VarData", map[string]interface{}{"Field": "name", "Row": 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.BeginMarkedContent("Price Tag", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.MarkPoint("Anchor", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.MarkPoint("Anchor", map[string]interface{}{"ID": Name("A1")}); err != nil {
+		t.Fatal(err)
+	}
+	d.EndMarkedContent()
+	if err := d.EndMarkedContent(); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{
+		d.EndMarkedContent(),
+		d.BeginMarkedContent("", nil),
+		d.MarkPoint("X", map[string]interface{}{"MCID": 1}),
+	} {
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("bad marked content: got %v expected ErrInvalid", err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+	r, _ := NewReader(buf.Bytes())
+	con := string(r.contents(r.pages()[0].dic["Contents"]))
+	want := "/VarData <<\n/Field (name)\n/Row 3\n>> BDC\n/Price#20Tag BMC\n/Anchor MP\n" +
+		"/Anchor <<\n/ID /A1\n>> DP\nEMC\nEMC\n"
+	if !strings.HasPrefix(con, want) {
+		t.Errorf("content: got %q expected %q", con, want)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.NewPage(100, 100)
+	d.BeginMarkedContent("VarData", nil)
+	if err := d.Close(); err == nil || !strings.Contains(err.Error(), "marked content not ended") {
+		t.Errorf("got %v for marked content not ended", err)
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains printer's marks (p. 750), which are drawn outside the
+// trim box of pages for the printer, like where the pages are cut, and the
+// color bars and slugs that are put in the margins of sheets for the
+// operators of the press.
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Marks holds which printer's marks DrawMarks draws around the trim box of a
+// page, and how. The sizes are in points.
+type Marks struct {
+	Crop         bool // crop marks at the corners, where the page is cut
+	Registration bool // targets in the middle of the sides, for aligning the plates of the colors
+
+	// Folds are where the page is folded, as the distance of vertical
+	// folds from the left of the trim box, and of horizontal folds from its
+	// bottom. They're drawn as dashed lines.
+	VerticalFolds, HorizontalFolds []float64
+
+	Length float64 // length of the marks; 18 (1/4 inch) if zero
+	Width  float64 // width of the lines; 0.25 if zero
+
+	// Offset is the distance of the marks from the trim box. If it's zero,
+	// the marks start at the bleed box, so that they're not printed on the
+	// bleed, or 9 points (1/8 inch) away if the page has no bleed box.
+	Offset float64
+}
+
+// offsets returns the distances of the marks of m from the left, bottom,
+// right and top of the trim box of p.
+func (m *Marks) offsets(p *page) [4]float64 {
+	switch {
+	case m.Offset > 0:
+		return [4]float64{m.Offset, m.Offset, m.Offset, m.Offset}
+	case p.bleed != nil:
+		t, b := p.trim, p.bleed
+		return [4]float64{t.llx - b.llx, t.lly - b.lly, b.urx - t.urx, b.ury - t.ury}
+	}
+	return [4]float64{9, 9, 9, 9}
+}
+
+// DrawMarks draws the printer's marks m around the trim box of the current
+// page, which should be set by SetTrimBox. The page should be larger than
+// its trim box and bleed box, so that the marks are on it. They're drawn in
+// the registration color, which shows on all the plates of the colors.
+func (d *Document) DrawMarks(m Marks) (err error) {
+	defer dontPanic(&err)
+
+	d.canDraw("printer's marks")
+	t := d.pg.trim
+	if t == nil {
+		panic("printer's marks drawn on a page with no trim box")
+	}
+	if m.Length < 0 || m.Width < 0 || m.Offset < 0 {
+		panic("negative size of printer's marks")
+	}
+	l, lw := m.Length, m.Width
+	if l == 0 {
+		l = 18
+	}
+	if lw == 0 {
+		lw = 0.25
+	}
+	o := m.offsets(d.pg)
+	for _, f := range m.VerticalFolds {
+		if f <= 0 || f >= t.urx-t.llx {
+			panic("vertical fold outside the trim box")
+		}
+	}
+	for _, f := range m.HorizontalFolds {
+		if f <= 0 || f >= t.ury-t.lly {
+			panic("horizontal fold outside the trim box")
+		}
+	}
+
+	cs := d.registration()
+	w := d.ops().SaveState().Name(d.useResource("ColorSpace", resName("CS", cs), cs)).Op("CS").
+		Float(1).Op("SCN").LineWidth(lw)
+	line := func(x0, y0, x1, y1 float64) {
+		w.MoveTo(x0, y0).LineTo(x1, y1)
+	}
+	if m.Crop {
+		for _, y := range []float64{t.lly, t.ury} {
+			line(t.llx-o[0]-l, y, t.llx-o[0], y)
+			line(t.urx+o[2], y, t.urx+o[2]+l, y)
+		}
+		for _, x := range []float64{t.llx, t.urx} {
+			line(x, t.lly-o[1]-l, x, t.lly-o[1])
+			line(x, t.ury+o[3], x, t.ury+o[3]+l)
+		}
+	}
+	if m.Registration {
+		mx, my := (t.llx+t.urx)/2, (t.lly+t.ury)/2
+		for _, c := range [][2]float64{
+			{t.llx - o[0] - l/2, my}, {mx, t.lly - o[1] - l/2},
+			{t.urx + o[2] + l/2, my}, {mx, t.ury + o[3] + l/2},
+		} {
+			w.Circle(c[0], c[1], l/4)
+			line(c[0]-l/2, c[1], c[0]+l/2, c[1])
+			line(c[0], c[1]-l/2, c[0], c[1]+l/2)
+		}
+	}
+	if m.Crop || m.Registration {
+		w.Stroke()
+	}
+	if len(m.VerticalFolds) > 0 || len(m.HorizontalFolds) > 0 {
+		w.Dash([]float64{3, 2}, 0)
+		for _, f := range m.VerticalFolds {
+			line(t.llx+f, t.lly-o[1]-l, t.llx+f, t.lly-o[1])
+			line(t.llx+f, t.ury+o[3], t.llx+f, t.ury+o[3]+l)
+		}
+		for _, f := range m.HorizontalFolds {
+			line(t.llx-o[0]-l, t.lly+f, t.llx-o[0], t.lly+f)
+			line(t.urx+o[2], t.lly+f, t.urx+o[2]+l, t.lly+f)
+		}
+		w.Stroke()
+	}
+	d.addw(w.RestoreState())
+	return nil
+}
+
+// registration returns the Separation color space of the registration color,
+// named All, which is painted on all the plates (p. 266). It's written to
+// the output the first time it's needed.
+func (d *Document) registration() *indirect {
+	d.resMu.Lock()
+	defer d.resMu.Unlock()
+
+	if d.regSpace == nil {
+		d.regSpace = d.indirect([]interface{}{
+			name("Separation"), name("All"), name("DeviceCMYK"),
+			map[string]interface{}{
+				"FunctionType": 2,
+				"Domain":       []int{0, 1},
+				"C0":           []int{0, 0, 0, 0},
+				"C1":           []int{1, 1, 1, 1},
+				"N":            1,
+			},
+		})
+	}
+	return d.regSpace
+}
+
+// colorBar holds the patches of color bars: the solid process colors, their
+// overprints, tints of 25, 50 and 75 percent of each of them, and a patch of
+// gray made of the three colors to be compared with the one of black beside
+// it.
+var colorBar = []CMYK{
+	{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1},
+	{0, 1, 1, 0}, {1, 0, 1, 0}, {1, 1, 0, 0}, {1, 1, 1, 0},
+	{0.25, 0, 0, 0}, {0.5, 0, 0, 0}, {0.75, 0, 0, 0},
+	{0, 0.25, 0, 0}, {0, 0.5, 0, 0}, {0, 0.75, 0, 0},
+	{0, 0, 0.25, 0}, {0, 0, 0.5, 0}, {0, 0, 0.75, 0},
+	{0, 0, 0, 0.25}, {0, 0, 0, 0.5}, {0, 0, 0, 0.75},
+	{0.5, 0.4, 0.4, 0}, {0, 0, 0, 0.5},
+}
+
+// DrawColorBar draws a color control bar on the current page, for checking
+// the density and balance of the colors on the press. It's a row of patches
+// of size×size starting at (x, y), from left to right, or from bottom to top
+// if vertical is true. It should be in the margin of the sheet, outside the
+// bleed box.
+func (d *Document) DrawColorBar(x, y, size float64, vertical bool) (err error) {
+	defer dontPanic(&err)
+
+	d.canDraw("color bar")
+	if size <= 0 {
+		panic("color bar with no size")
+	}
+	w := d.ops().SaveState()
+	for i, c := range colorBar {
+		px, py := x+float64(i)*size, y
+		if vertical {
+			px, py = x, y+float64(i)*size
+		}
+		w.FillColor(c).Rectangle(px, py, size, size).Fill()
+	}
+	d.addw(w.RestoreState())
+	return nil
+}
+
+// Slug holds what's known about a print job, to be put in the margin of its
+// sheets by DrawSlug for the operators of the press.
+type Slug struct {
+	Job         string    // name of the job
+	Date        time.Time // when the job is made; now if zero
+	Separations []string  // names of the separations, like "Cyan" or "PANTONE 185 C"
+	FontSize    float64   // size of the text; 6 if zero
+}
+
+// text returns the line of text of s on page n.
+func (s *Slug) text(n int, now time.Time) string {
+	t := s.Date
+	if t.IsZero() {
+		t = now
+	}
+	parts := []string{}
+	if s.Job != "" {
+		parts = append(parts, "Job: "+s.Job)
+	}
+	parts = append(parts, "Date: "+t.Format("2006-01-02 15:04"), fmt.Sprint("Page: ", n))
+	if len(s.Separations) > 0 {
+		parts = append(parts, "Separations: "+strings.Join(s.Separations, ", "))
+	}
+	return strings.Join(parts, "   ")
+}
+
+// DrawSlug draws s as a line of text on the current page, with the baseline
+// starting at (x, y), followed by the number of the page. It's in the
+// registration color, so that it's on all the plates, and should be in the
+// margin of the sheet, outside the bleed box.
+func (d *Document) DrawSlug(s Slug, x, y float64) (err error) {
+	defer dontPanic(&err)
+
+	d.canDraw("slug")
+	size := s.FontSize
+	if size < 0 {
+		panic(fmt.Sprint("negative font size: ", size))
+	}
+	if size == 0 {
+		size = 6
+	}
+	t := d.encodeText(s.text(d.pg.index+1, d.now()))
+	cs := d.registration()
+	w := d.ops().SaveState().Op("BT").Name(d.pageFont(FontHelvetica)).Float(size).Op("Tf").
+		Name(d.useResource("ColorSpace", resName("CS", cs), cs)).Op("cs").Float(1).Op("scn").
+		Float(x).Float(y).Op("Td").String(t).Op("Tj").Op("ET").RestoreState()
+	w.t.text(t, x, y, size, &TextStyle{})
+	d.addw(w)
+	return nil
+}
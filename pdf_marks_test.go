@@ -0,0 +1,144 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type marksTest struct {
+	marks Marks
+	bleed bool
+	want  []string // parts of the content
+	err   string
+}
+
+func TestMarks(t *testing.T) {
+	tests := []marksTest{
+		{Marks{Crop: true}, true, []string{
+			"0.25 w\n", "23 50 m\n41 50 l\n", "359 350 m\n377 350 l\n",
+			"50 23 m\n50 41 l\n", "350 359 m\n350 377 l\nS\nQ\n",
+		}, ""},
+		{Marks{Crop: true}, false, []string{"23 50 m\n41 50 l\n"}, ""},
+		{Marks{Crop: true, Offset: 4, Length: 10, Width: 1}, true, []string{
+			"1 w\n", "36 50 m\n46 50 l\n",
+		}, ""},
+		{Marks{Registration: true}, true, []string{
+			"36.5 200 m\n", "23 200 m\n41 200 l\n", "200 23 m\n200 41 l\n",
+		}, ""},
+		{Marks{VerticalFolds: []float64{100}, HorizontalFolds: []float64{150}}, true, []string{
+			"[3 2] 0 d\n", "150 23 m\n150 41 l\n", "359 200 m\n377 200 l\nS\nQ\n",
+		}, ""},
+		{Marks{Crop: true, Length: -1}, true, nil, "pdf.go: negative size of printer's marks"},
+		{Marks{VerticalFolds: []float64{300}}, true, nil, "pdf.go: vertical fold outside the trim box"},
+	}
+	for i, tt := range tests {
+		buf := bytes.NewBuffer(nil)
+		d, _ := New(buf)
+		d.NewPage(400, 400)
+		d.SetTrimBox(50, 50, 300, 300)
+		if tt.bleed {
+			d.SetBleedBox(41, 41, 318, 318)
+		}
+		err := d.DrawMarks(tt.marks)
+		if fmt.Sprint(err) != tt.err && (err != nil || tt.err != "") {
+			t.Errorf("%d: got %v expected %q", i, err, tt.err)
+		}
+		d.Close()
+		if tt.err != "" {
+			continue
+		}
+		r, _ := NewReader(buf.Bytes())
+		con := string(r.contents(r.pages()[0].dic["Contents"]))
+		if !strings.HasPrefix(con, "q\n/CS") || !strings.Contains(con, " CS\n1 SCN\n") {
+			t.Errorf("%d: content %q isn't in the registration color", i, con)
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(con, w) {
+				t.Errorf("%d: content %q has no %q", i, con, w)
+			}
+		}
+		if !bytes.Contains(buf.Bytes(), []byte("/Separation /All /DeviceCMYK")) {
+			t.Errorf("%d: no registration color space", i)
+		}
+		if probs := Validate(buf.Bytes()); len(probs) > 0 {
+			t.Errorf("%d: problems: %v", i, probs)
+		}
+	}
+
+	d, _ := New(new(bytes.Buffer))
+	d.NewPage(400, 400)
+	if err := d.DrawMarks(Marks{Crop: true}); fmt.Sprint(err) != "pdf.go: printer's marks drawn on a page with no trim box" {
+		t.Errorf("no trim box: got %v", err)
+	}
+}
+
+func TestColorBar(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(400, 400)
+	if err := d.DrawColorBar(10, 5, 8, false); err != nil {
+		t.Fatal(err)
+	}
+	d.DrawColorBar(5, 20, 8, true)
+	if err := d.DrawColorBar(5, 20, 0, true); fmt.Sprint(err) != "pdf.go: color bar with no size" {
+		t.Errorf("no size: got %v", err)
+	}
+	d.Close()
+
+	r, _ := NewReader(buf.Bytes())
+	con := string(r.contents(r.pages()[0].dic["Contents"]))
+	for _, want := range []string{
+		"q\n1 0 0 0 k\n10 5 8 8 re\nf\n0 1 0 0 k\n18 5 8 8 re\nf\n",
+		"0.5 0.4 0.4 0 k\n170 5 8 8 re\nf\n0 0 0 0.5 k\n178 5 8 8 re\nf\nQ\n",
+		"0 0 0 0.5 k\n5 188 8 8 re\nf\nQ\n",
+	} {
+		if !strings.Contains(con, want) {
+			t.Errorf("content %q has no %q", con, want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf, Options{Deterministic: true})
+	d.NewPage(400, 400)
+	d.NewPage(400, 400)
+	err := d.DrawSlug(Slug{Job: "Book (cover)", Separations: []string{"Cyan", "PANTONE 185 C"}}, 10, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DrawSlug(Slug{FontSize: -1}, 10, 5); fmt.Sprint(err) != "pdf.go: negative font size: -1" {
+		t.Errorf("negative font size: got %v", err)
+	}
+	d.Close()
+
+	r, _ := NewReader(buf.Bytes())
+	con := string(r.contents(r.pages()[1].dic["Contents"]))
+	want := "q\nBT\n/Helv 6 Tf\n/CS"
+	text := " cs\n1 scn\n10 5 Td\n(Job: Book \\(cover\\)   Date: 1970-01-01 00:00   Page: 2   " +
+		"Separations: Cyan, PANTONE 185 C) Tj\nET\nQ\n"
+	if !strings.HasPrefix(con, want) || !strings.Contains(con, text) {
+		t.Errorf("content: got %q", con)
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+}
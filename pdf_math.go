@@ -0,0 +1,353 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file typesets simple formulas, like the ones of scientific reports,
+// with the text and the graphics of content streams: fractions, radicals,
+// subscripts and superscripts, and delimiters that stretch to the height of
+// what they enclose.
+
+import (
+	"math"
+	"strings"
+)
+
+// Math is a part of a formula: MathText, MathRow, *Fraction, *Radical,
+// *Scripts or *Delimited.
+type Math interface {
+	// layout returns the box of the part in the style st, with the
+	// given font size.
+	layout(st *TextStyle, size float64) *mathBox
+}
+
+// mathBox is a part of a formula laid out.
+type mathBox struct {
+	w, asc, desc float64                               // width, and how far it goes above and below the baseline
+	draw         func(cw *ContentWriter, x, y float64) // draws it with the left end of its baseline at (x, y)
+}
+
+// Proportions of formulas, in font sizes.
+const (
+	mathAscent  = 0.72 // about the height of capital letters of Helvetica
+	mathDescent = 0.21 // about the descender of Helvetica
+	mathAxis    = 0.26 // height of the bars of fractions, about the middle of a minus sign
+	mathRule    = 0.05 // thickness of the bars of fractions and radicals
+	mathGap     = 0.12 // space around bars and in delimiters
+	mathScript  = 0.7  // size of subscripts, superscripts and indices of radicals
+)
+
+// layout returns the box of m, which is empty if m is nil.
+func layout(m Math, st *TextStyle, size float64) *mathBox {
+	if m == nil {
+		return &mathBox{draw: func(*ContentWriter, float64, float64) {}}
+	}
+	return m.layout(st, size)
+}
+
+// MathText is text in a formula, like a number, a variable or an operator.
+type MathText string
+
+func (t MathText) layout(st *TextStyle, size float64) *mathBox {
+	s := winAnsi(string(t))
+	return &mathBox{
+		w:    st.width(s, size),
+		asc:  mathAscent * size,
+		desc: mathDescent * size,
+		draw: func(cw *ContentWriter, x, y float64) {
+			cw.Write([]byte(st.text(s, x, y, size)))
+		},
+	}
+}
+
+// MathRow is parts of a formula one after another, like "x = " followed by
+// a fraction.
+type MathRow []Math
+
+func (r MathRow) layout(st *TextStyle, size float64) *mathBox {
+	b := &mathBox{}
+	boxes := make([]*mathBox, len(r))
+	for i, m := range r {
+		boxes[i] = layout(m, st, size)
+		b.w += boxes[i].w
+		b.asc = math.Max(b.asc, boxes[i].asc)
+		b.desc = math.Max(b.desc, boxes[i].desc)
+	}
+	b.draw = func(cw *ContentWriter, x, y float64) {
+		for _, c := range boxes {
+			c.draw(cw, x, y)
+			x += c.w
+		}
+	}
+	return b
+}
+
+// Fraction is a numerator over a denominator, with a bar between them.
+type Fraction struct {
+	Num, Den Math
+}
+
+func (f *Fraction) layout(st *TextStyle, size float64) *mathBox {
+	num, den := layout(f.Num, st, size), layout(f.Den, st, size)
+	pad, rule, gap := mathGap*size, mathRule*size, mathGap*size
+	axis := mathAxis * size
+	up := axis + rule/2 + gap + num.desc
+	down := axis - rule/2 - gap - den.asc
+	b := &mathBox{
+		w:    math.Max(num.w, den.w) + 2*pad,
+		asc:  up + num.asc,
+		desc: -down + den.desc,
+	}
+	b.draw = func(cw *ContentWriter, x, y float64) {
+		num.draw(cw, x+(b.w-num.w)/2, y+up)
+		den.draw(cw, x+(b.w-den.w)/2, y+down)
+		cw.Rectangle(x+pad/2, y+axis-rule/2, b.w-pad, rule).Fill()
+	}
+	return b
+}
+
+// Radical is the square root of Body, or another root if there's an Index,
+// like 3 for the cube root.
+type Radical struct {
+	Body, Index Math
+}
+
+func (r *Radical) layout(st *TextStyle, size float64) *mathBox {
+	body := layout(r.Body, st, size)
+	idx := layout(r.Index, st, size*mathScript)
+	rule, gap := mathRule*size, mathGap*size
+	sign := 0.55 * size // width of the sign
+	// The index sits above the short stroke at the left of the sign, and
+	// moves the sign to the right if it's wider.
+	shift := math.Max(0, idx.w-0.3*size)
+	top := body.asc + gap + rule/2
+	bottom := -body.desc
+	b := &mathBox{
+		w:    shift + sign + body.w + gap,
+		asc:  top + rule/2,
+		desc: body.desc,
+	}
+	mid := bottom + 0.45*(top-bottom)
+	if idx.w > 0 {
+		b.asc = math.Max(b.asc, mid+gap+idx.desc+idx.asc)
+	}
+	b.draw = func(cw *ContentWriter, x, y float64) {
+		if idx.w > 0 {
+			idx.draw(cw, x+shift+0.3*size-idx.w, y+mid+gap+idx.desc)
+		}
+		x += shift
+		cw.LineWidth(rule).LineJoinStyle(0).
+			MoveTo(x, y+mid).
+			LineTo(x+0.12*size, y+mid+0.06*size).
+			LineTo(x+0.28*size, y+bottom).
+			LineTo(x+sign-0.05*size, y+top).
+			LineTo(x+b.w-shift, y+top).Stroke()
+		body.draw(cw, x+sign, y)
+	}
+	return b
+}
+
+// Scripts is Base with a subscript below its right side, a superscript above
+// it, or both. The scripts are smaller than the base.
+type Scripts struct {
+	Base, Sub, Sup Math
+}
+
+func (s *Scripts) layout(st *TextStyle, size float64) *mathBox {
+	base := layout(s.Base, st, size)
+	sub := layout(s.Sub, st, size*mathScript)
+	sup := layout(s.Sup, st, size*mathScript)
+	up := math.Max(0.35*size, base.asc-0.35*size)
+	down := math.Max(0.2*size, base.desc)
+	// Scripts are kept apart if there are both.
+	if s.Sub != nil && s.Sup != nil {
+		if gap := (up - sup.desc) - (sub.asc - down); gap < mathGap*size {
+			down += mathGap*size - gap
+		}
+	}
+	b := &mathBox{w: base.w + math.Max(sub.w, sup.w), asc: base.asc, desc: base.desc}
+	if s.Sup != nil {
+		b.asc = math.Max(b.asc, up+sup.asc)
+	}
+	if s.Sub != nil {
+		b.desc = math.Max(b.desc, down+sub.desc)
+	}
+	b.draw = func(cw *ContentWriter, x, y float64) {
+		base.draw(cw, x, y)
+		if s.Sup != nil {
+			sup.draw(cw, x+base.w, y+up)
+		}
+		if s.Sub != nil {
+			sub.draw(cw, x+base.w, y-down)
+		}
+	}
+	return b
+}
+
+// Delimiters
+const (
+	DelimParen   = "("
+	DelimBracket = "["
+	DelimBrace   = "{"
+	DelimBar     = "|"
+)
+
+// closing holds the closing delimiters of the opening ones.
+var closing = map[string]string{
+	DelimParen:   ")",
+	DelimBracket: "]",
+	DelimBrace:   "}",
+	DelimBar:     "|",
+}
+
+// Delimited is Body between delimiters that are as high as it, like
+// parentheses. Left is one of the opening delimiters, like DelimParen, and
+// Right is closing, like ")"; either can be empty for no delimiter.
+type Delimited struct {
+	Left, Right string
+	Body        Math
+}
+
+func (dl *Delimited) layout(st *TextStyle, size float64) *mathBox {
+	body := layout(dl.Body, st, size)
+	for _, c := range []string{dl.Left, dl.Right} {
+		found := c == ""
+		for o, cl := range closing {
+			found = found || c == o || c == cl
+		}
+		if !found {
+			panic("unknown delimiter: " + c)
+		}
+	}
+	gap, rule := mathGap*size, mathRule*size
+	// Delimiters are symmetric around the axis, like in TeX.
+	axis := mathAxis * size
+	half := math.Max(body.asc-axis, body.desc+axis) + gap
+	dw := 0.3*size + 0.05*half
+	b := &mathBox{asc: axis + half, desc: half - axis, w: body.w + 2*gap}
+	lw, rw := 0.0, 0.0
+	if dl.Left != "" {
+		lw = dw
+	}
+	if dl.Right != "" {
+		rw = dw
+	}
+	b.w += lw + rw
+	b.draw = func(cw *ContentWriter, x, y float64) {
+		cw.LineWidth(rule * 1.5).LineCapStyle(1)
+		if dl.Left != "" {
+			delimiter(cw, dl.Left, x, y+axis-half, dw, 2*half)
+		}
+		body.draw(cw, x+lw+gap, y)
+		if dl.Right != "" {
+			delimiter(cw, dl.Right, x+b.w-rw, y+axis-half, dw, 2*half)
+		}
+	}
+	return b
+}
+
+// delimiter appends a path of delimiter c in the box of size w×h with the
+// lower left corner at (x, y) to cw, and strokes it. Closing delimiters
+// mirror the opening ones.
+func delimiter(cw *ContentWriter, c string, x, y, w, h float64) {
+	// Points are given as fractions of the width from the side the
+	// delimiter opens from, and of the height from the bottom.
+	open := closing[c] != ""
+	pts := func(ps ...float64) []float64 {
+		for i := 0; i < len(ps); i += 2 {
+			if !open {
+				ps[i] = 1 - ps[i]
+			}
+			ps[i], ps[i+1] = x+ps[i]*w, y+ps[i+1]*h
+		}
+		return ps
+	}
+	curve := func(ps ...float64) {
+		p := pts(ps...)
+		cw.Curve(p[0], p[1], p[2], p[3], p[4], p[5])
+	}
+	line := func(u, v float64) {
+		p := pts(u, v)
+		cw.LineTo(p[0], p[1])
+	}
+	start := pts(0.8, 1)
+	if c == DelimBar {
+		start = pts(0.5, 1)
+	}
+	cw.MoveTo(start[0], start[1])
+	switch c {
+	case DelimParen, ")":
+		curve(0.15, 0.75, 0.15, 0.25, 0.8, 0)
+	case DelimBracket, "]":
+		line(0.35, 1)
+		line(0.35, 0)
+		line(0.8, 0)
+	case DelimBrace, "}":
+		curve(0.45, 1, 0.45, 0.9, 0.45, 0.75)
+		line(0.45, 0.62)
+		curve(0.45, 0.52, 0.3, 0.5, 0.15, 0.5)
+		curve(0.3, 0.5, 0.45, 0.48, 0.45, 0.38)
+		line(0.45, 0.25)
+		curve(0.45, 0.1, 0.45, 0, 0.8, 0)
+	case DelimBar:
+		line(0.5, 0)
+	}
+	cw.Stroke()
+}
+
+// Formula is a formula made of Math, which can be drawn in a box, like a
+// cell of a grid, or added to a flow. It's aligned in its box by the
+// alignment of its style, and the color of its style is used for the bars
+// and the delimiters too.
+type Formula struct {
+	Math  Math
+	Style TextStyle
+}
+
+// Size returns the width w and the height of f.
+func (f *Formula) Size(w, h float64) (float64, float64) {
+	st := f.Style
+	b := layout(f.Math, &st, st.size())
+	return w, b.asc + b.desc
+}
+
+// Draw draws f at the top of the box of size w×h with the lower left corner
+// at (x, y).
+func (f *Formula) Draw(d *Document, x, y, w, h float64) (err error) {
+	defer dontPanic(&err)
+
+	d.canDraw("formula")
+	if f.Math == nil {
+		panic("formula with no math")
+	}
+	st := f.Style
+	b := layout(f.Math, &st, st.size())
+	switch st.Align {
+	case AlignCenter:
+		x += (w - b.w) / 2
+	case AlignRight:
+		x += w - b.w
+	}
+	d.pageFont(st.font())
+	cw := NewContentWriter(nil).SaveState().FillColor(st.color()).StrokeColor(st.color())
+	b.draw(cw, x, y+h-b.asc)
+	d.addc(strings.TrimRight(string(cw.RestoreState().Bytes()), "\n"))
+	return nil
+}
+
+func (f *Formula) pieces(fr *frame) []piece {
+	return drawablePieces(f, fr)
+}
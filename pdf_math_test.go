@@ -0,0 +1,109 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFormula(t *testing.T) {
+	// The roots of a quadratic equation
+	roots := &Formula{Style: TextStyle{FontSize: 10, Align: AlignCenter}, Math: MathRow{
+		MathText("x = "),
+		&Fraction{
+			Num: MathRow{
+				MathText("-b ± "),
+				&Radical{Body: MathRow{
+					&Scripts{Base: MathText("b"), Sup: MathText("2")},
+					MathText(" - 4ac"),
+				}},
+			},
+			Den: MathText("2a"),
+		},
+	}}
+	sum := &Formula{Style: TextStyle{FontSize: 10}, Math: &Delimited{
+		Left: DelimParen, Right: ")",
+		Body: &Scripts{Base: MathText("x"), Sub: MathText("i"), Sup: MathText("3")},
+	}}
+	root := &Formula{Math: &Radical{Body: MathText("8"), Index: MathText("3")}}
+
+	if w, h := sum.Size(100, 0); w != 100 || ftoa(h) != "18.56" {
+		t.Errorf("size of formula: got %v×%v", w, h)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	f, _ := d.NewFlow(300, 200, Margins{20, 20, 20, 20})
+	for _, b := range []Block{roots, sum, root} {
+		if err := f.Add(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewReader(buf.Bytes())
+	c := string(r.contents(r.pages()[0].dic["Contents"]))
+	for _, s := range []string{
+		// The bar of the fraction
+		"re\nf\n",
+		// The sign of the radical
+		"l\nS\n",
+		// The superscript is smaller.
+		"BT /Helv 7 Tf 0 g",
+		// Parentheses
+		"c\nS\n",
+	} {
+		if !strings.Contains(c, s) {
+			t.Errorf("%q not found in the content", s)
+		}
+	}
+	runs, _ := r.PageText(1)
+	var text []string
+	for _, run := range runs {
+		text = append(text, run.Text)
+	}
+	if got := strings.Join(text, "|"); got != "x = |-b ± |b|2| - 4ac|2a|x|3|i|3|8" {
+		t.Errorf("text: got %q", got)
+	}
+	if ps := Validate(buf.Bytes()); len(ps) > 0 {
+		t.Errorf("problems: %v", ps)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	err := (&Formula{Math: MathText("x")}).Draw(d, 0, 0, 100, 100)
+	if !errors.Is(err, ErrNoPage) {
+		t.Errorf("formula with no page: got error %v", err)
+	}
+	d.NewPage(100, 100)
+	for _, c := range []struct {
+		f    *Formula
+		want string
+	}{
+		{&Formula{}, "formula with no math"},
+		{&Formula{Math: &Delimited{Left: "<"}}, "unknown delimiter: <"},
+	} {
+		err := c.f.Draw(d, 0, 0, 100, 100)
+		if fmt.Sprint(err) != "pdf.go: "+c.want {
+			t.Errorf("got error %v, want %q", err, c.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains measurement scales, viewports and measurement annotations
+// for type Document. They let viewers show real-world distances and areas for
+// drawings such as floor plans and maps (p. 708).
+
+import (
+	"math"
+	"strconv"
+)
+
+// Measure describes a rectilinear measurement scale. A distance of one unit in
+// the default user space of the page (1/72 inch) is Factor units of Unit in
+// the real world.
+type Measure struct {
+	Ratio     string  // human readable scale, e.g. "1 in = 10 ft"
+	Unit      string  // label of the real-world unit, e.g. "ft"
+	AreaUnit  string  // label of the unit for areas; "sq " + Unit if empty
+	Factor    float64 // real-world units per user space unit
+	Precision int     // 10 for one decimal place, 100 for two, etc.
+}
+
+// precision returns the denominator used for precision of the numbers.
+func (m *Measure) precision() int {
+	if m.Precision <= 0 {
+		return 100
+	}
+	return m.Precision
+}
+
+// areaUnit returns the label of the unit for areas.
+func (m *Measure) areaUnit() string {
+	if m.AreaUnit == "" {
+		return "sq " + m.Unit
+	}
+	return m.AreaUnit
+}
+
+// format returns v as a string with the precision of m.
+func (m *Measure) format(v float64) string {
+	digits := 0
+	for p := m.precision(); p > 1; p /= 10 {
+		digits++
+	}
+	return strconv.FormatFloat(v, 'f', digits, 64)
+}
+
+// numberFormat returns a number format dictionary (p. 712) with label u and
+// conversion factor c.
+func (m *Measure) numberFormat(u string, c float64) map[string]interface{} {
+	return map[string]interface{}{
+		"Type": name("NumberFormat"),
+		"U":    textString(u),
+		"C":    c,
+		"D":    m.precision(),
+		"F":    name("D"),
+	}
+}
+
+func (m *Measure) object() interface{} {
+	d := map[string]interface{}{
+		"Type":    name("Measure"),
+		"Subtype": name("RL"),
+		"R":       textString(m.Ratio),
+		"X":       []interface{}{m.numberFormat(m.Unit, m.Factor)},
+		"D":       []interface{}{m.numberFormat(m.Unit, 1)},
+		"A":       []interface{}{m.numberFormat(m.areaUnit(), 1)},
+	}
+	return d
+}
+
+// viewport is a rectangular region of a page with its own measurement scale.
+type viewport struct {
+	box  *rect
+	name string
+	m    *Measure
+}
+
+func (v *viewport) object() interface{} {
+	d := map[string]interface{}{
+		"Type":    name("Viewport"),
+		"BBox":    v.box,
+		"Measure": v.m,
+	}
+	if v.name != "" {
+		d["Name"] = textString(v.name)
+	}
+	return d
+}
+
+// AddViewport adds a viewport to the current page. Measurements made by viewers
+// inside the rectangle with lower-left corner (x, y), width w, and height h use
+// the scale m. Viewports added later are on top of the earlier ones.
+func (d *Document) AddViewport(x, y, w, h float64, title string, m *Measure) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "viewport added before any page was started")
+	}
+	if m == nil {
+		panic("nil measure passed to AddViewport")
+	}
+	v := &viewport{newRect(x, y, x+w, y+h), title, m}
+	d.pg.vps = append(d.pg.vps, v)
+	return nil
+}
+
+// DistanceAnnotation adds a dimension line from (x1, y1) to (x2, y2) to the
+// current page. The real-world length, according to m, is shown as the caption
+// of the line.
+func (d *Document) DistanceAnnotation(x1, y1, x2, y2 float64, m *Measure) (err error) {
+	defer dontPanic(&err)
+
+	if m == nil {
+		panic("nil measure passed to DistanceAnnotation")
+	}
+	l := math.Sqrt((x2-x1)*(x2-x1)+(y2-y1)*(y2-y1)) * m.Factor
+	pts := []float64{x1, y1, x2, y2}
+	d.addAnnot(map[string]interface{}{
+		"Subtype":  name("Line"),
+		"Rect":     pointsRect(pts, 4),
+		"L":        pts,
+		"LE":       []name{"OpenArrow", "OpenArrow"},
+		"IT":       name("LineDimension"),
+		"Cap":      true,
+		"Measure":  m,
+		"Contents": textString(m.format(l) + " " + m.Unit),
+	})
+	return nil
+}
+
+// AreaAnnotation adds a polygon to the current page with the real-world area,
+// according to m, shown as its contents. pts holds x and y of the vertices one
+// after another.
+func (d *Document) AreaAnnotation(pts []float64, m *Measure) (err error) {
+	defer dontPanic(&err)
+
+	if m == nil {
+		panic("nil measure passed to AreaAnnotation")
+	}
+	if len(pts) < 6 || len(pts)%2 != 0 {
+		panic("area annotation needs at least three vertices")
+	}
+	// Shoelace formula
+	a := 0.0
+	for i := 0; i < len(pts); i += 2 {
+		j := (i + 2) % len(pts)
+		a += pts[i]*pts[j+1] - pts[j]*pts[i+1]
+	}
+	a = math.Abs(a) / 2 * m.Factor * m.Factor
+	d.addAnnot(map[string]interface{}{
+		"Subtype":  name("Polygon"),
+		"Rect":     pointsRect(pts, 2),
+		"Vertices": pts,
+		"IT":       name("PolygonDimension"),
+		"Measure":  m,
+		"Contents": textString(m.format(a) + " " + m.areaUnit()),
+	})
+	return nil
+}
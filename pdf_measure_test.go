@@ -0,0 +1,103 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMeasureOutput(t *testing.T) {
+	m := &Measure{Ratio: "1 in = 1 ft", Unit: "ft", Factor: 1.0 / 72}
+	o := output(m)
+	for _, s := range []string{
+		"/Subtype /RL",
+		"/R (1 in = 1 ft)",
+		"/U (sq ft)",
+		"/D 100",
+	} {
+		if !bytes.Contains(o, []byte(s)) {
+			t.Errorf("measure output: %q not found in\n\t%s", s, o)
+		}
+	}
+}
+
+func TestMeasureAnnotations(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, err := New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Measure{Ratio: "1 pt = 2 m", Unit: "m", Factor: 2, Precision: 10}
+	if err = d.AddViewport(0, 0, 100, 100, "plan", m); err == nil {
+		t.Errorf("AddViewport without a page: expected error")
+	}
+	d.NewPage(200, 200)
+	if err = d.AddViewport(0, 0, 100, 100, "plan", m); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.DistanceAnnotation(0, 0, 3, 4, m); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.AreaAnnotation([]float64{0, 0, 10, 0, 10, 10}, m); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.AreaAnnotation([]float64{0, 0, 10, 0}, m); err == nil {
+		t.Errorf("AreaAnnotation with two vertices: expected error")
+	}
+	if err = d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"/Type /Viewport",
+		"/Contents (10.0 m)",
+		"/Contents (200.0 sq m)",
+		"/IT /PolygonDimension",
+		"/Annots [ ",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("document: %q not found", s)
+		}
+	}
+}
+
+func TestMeasureUnicode(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, err := New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(200, 200)
+	m := &Measure{Ratio: "1 pt = 1 µm", Unit: "µm", AreaUnit: "µm²", Factor: 1, Precision: 10}
+	if err = d.AddViewport(0, 0, 100, 100, "Maßstab", m); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.DistanceAnnotation(0, 0, 3, 4, m); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.AreaAnnotation([]float64{0, 0, 10, 0, 10, 10}, m); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"1 pt = 1 µm", "µm", "Maßstab", "5.0 µm", "50.0 µm²"} {
+		if !bytes.Contains(buf.Bytes(), output(textString(s))) {
+			t.Errorf("document: %q not found as a text string", s)
+		}
+	}
+}
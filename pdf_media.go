@@ -0,0 +1,243 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains screen annotations, which play video and sound embedded
+// in the document, rendition actions, which control them, and sound
+// annotations, like recorded voice notes.
+
+// Operations of rendition actions on the media of screens.
+const (
+	MediaPlay = iota
+	MediaStop
+	MediaPause
+	MediaResume
+)
+
+// Media is a video or sound clip to be played by a screen annotation.
+type Media struct {
+	// File holds the clip, which is embedded in the document. Its
+	// MIMEType, like "video/mp4" or "audio/mpeg", is needed by viewers to
+	// choose a player.
+	File *File
+
+	Title    string   // shown by viewers, like in the list of annotations
+	Controls bool     // whether the player shows its controls
+	Loop     bool     // whether the clip is played again and again
+	AutoPlay bool     // whether the clip is played when its page is opened
+	Poster   *XObject // shown while the clip isn't played, scaled to the screen; blank if nil
+}
+
+// Screen is a screen annotation, added by AddScreen. It's used by rendition
+// actions to control its media.
+type Screen struct {
+	ref  *indirect // the annotation
+	rend *indirect // the rendition of its media
+}
+
+// AddScreen adds a screen annotation playing m to the current page, in the
+// rectangle with lower-left corner at (x, y), width w and height h. The
+// media is played when the screen is clicked.
+func (d *Document) AddScreen(x, y, w, h float64, m *Media) (s *Screen, err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "screen added before any page was started")
+	}
+	if m.File == nil || m.File.Name == "" {
+		panic("media with no file")
+	}
+	if m.File.MIMEType == "" {
+		panic("media with no MIME type: " + m.File.Name)
+	}
+	if d.version < "1.5" {
+		panic("screen annotations need PDF 1.5")
+	}
+
+	params := map[string]interface{}{
+		"Type": name("MediaPlayParams"),
+		"BE": map[string]interface{}{
+			"C": m.Controls,
+		},
+	}
+	if m.Loop {
+		// A repeat count of zero plays the clip forever.
+		params["BE"].(map[string]interface{})["RC"] = 0
+	}
+	s = &Screen{ref: d.reserveIndirect()}
+	s.rend = d.indirect(map[string]interface{}{
+		"Type": name("Rendition"),
+		"S":    name("MR"),
+		"N":    m.File.Name,
+		"C": map[string]interface{}{
+			"Type": name("MediaClip"),
+			"S":    name("MCD"),
+			"N":    m.File.Name,
+			"CT":   m.File.MIMEType,
+			"D":    d.fileSpec(m.File),
+			// Viewers may write the clip to a temporary file to
+			// play it.
+			"P": map[string]interface{}{"TF": "TEMPACCESS"},
+		},
+		"P": params,
+	})
+
+	c := ""
+	var xo map[string]interface{}
+	if m.Poster != nil {
+		c = string(m.Poster.draw(new(ContentWriter), 0, 0, w, h).Bytes())
+		xo = map[string]interface{}{m.Poster.name(): m.Poster.ref}
+	}
+	play := &Rendition{s, MediaPlay}
+	a := map[string]interface{}{
+		"Subtype": name("Screen"),
+		"Rect":    newRect(x, y, x+w, y+h),
+		"A":       play,
+		"AP":      map[string]interface{}{"N": d.appearance(w, h, c, "", xo)},
+		"F":       4, // Print flag of annotations
+	}
+	if m.Title != "" {
+		a["T"] = textString(m.Title)
+	}
+	if m.AutoPlay {
+		a["AA"] = map[string]interface{}{"PO": play}
+	}
+	d.addAnnotAt(s.ref, a)
+	return s, nil
+}
+
+// Rendition is an action that plays, stops, pauses or resumes the media of a
+// screen, like for the buttons of a player.
+type Rendition struct {
+	Screen *Screen
+	Op     int // MediaPlay, MediaStop, MediaPause or MediaResume
+}
+
+func (r *Rendition) object() interface{} {
+	if r.Screen == nil {
+		panic("rendition action with no screen")
+	}
+	if r.Op < MediaPlay || r.Op > MediaResume {
+		panic("unknown operation of rendition action")
+	}
+	e := map[string]interface{}{
+		"OP": r.Op,
+		"AN": r.Screen.ref,
+	}
+	if r.Op == MediaPlay {
+		e["R"] = r.Screen.rend
+	}
+	return actionDict("Rendition", e)
+}
+
+// Encodings of the samples of sounds.
+const (
+	SoundRaw    = "Raw"    // unsigned numbers
+	SoundSigned = "Signed" // two's complement numbers
+	SoundMuLaw  = "muLaw"  // μ-law
+	SoundALaw   = "ALaw"   // A-law
+)
+
+// Sound is a recorded sound, like a voice note, added to pages by AddSound.
+type Sound struct {
+	// Data holds the samples, with the ones of all the channels of a
+	// moment one after another. Samples larger than a byte have their most
+	// significant byte first.
+	Data     []byte
+	Rate     float64 // samples per second
+	Channels int     // 1 if zero
+	Bits     int     // bits per sample; 8 if zero
+	Encoding string  // SoundRaw, SoundSigned, SoundMuLaw or SoundALaw; SoundRaw if empty
+
+	Note string // text of the annotation, like a transcript of the note
+	Mic  bool   // whether the icon is a microphone instead of a speaker
+}
+
+// soundIconSize is the width and height of the icons of sound annotations.
+const soundIconSize = 20
+
+// AddSound adds a sound annotation playing s to the current page, shown as an
+// icon with its lower-left corner at (x, y).
+func (d *Document) AddSound(x, y float64, s *Sound) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "sound added before any page was started")
+	}
+	if len(s.Data) == 0 {
+		panic("sound with no data")
+	}
+	if s.Rate <= 0 {
+		panic("sound with no sampling rate")
+	}
+	dic := map[string]interface{}{
+		"Type": name("Sound"),
+		"R":    s.Rate,
+	}
+	if s.Channels < 0 || s.Bits < 0 {
+		panic("negative channels or bits of sound")
+	}
+	if s.Channels > 1 {
+		dic["C"] = s.Channels
+	}
+	if s.Bits != 0 && s.Bits != 8 {
+		dic["B"] = s.Bits
+	}
+	switch s.Encoding {
+	case "", SoundRaw:
+	case SoundSigned, SoundMuLaw, SoundALaw:
+		dic["E"] = name(s.Encoding)
+	default:
+		panic("unknown encoding of sound: " + s.Encoding)
+	}
+
+	icon := "Speaker"
+	if s.Mic {
+		icon = "Mic"
+	}
+	a := map[string]interface{}{
+		"Subtype": name("Sound"),
+		"Rect":    newRect(x, y, x+soundIconSize, y+soundIconSize),
+		"Sound":   d.indirect(&stream{dic, s.Data}),
+		"Name":    name(icon),
+		"AP": map[string]interface{}{
+			"N": d.appearance(soundIconSize, soundIconSize, soundIcon(s.Mic), "", nil),
+		},
+		"F": 4, // Print flag of annotations
+	}
+	if s.Note != "" {
+		a["Contents"] = textString(s.Note)
+	}
+	d.addAnnot(a)
+	return nil
+}
+
+// soundIcon returns the content of the appearance of sound annotations, which
+// is a speaker, or a microphone if mic is true, on a yellow square.
+func soundIcon(mic bool) string {
+	w := new(ContentWriter).FillColor(RGB{1, 0.9, 0.4}).
+		Rectangle(0, 0, soundIconSize, soundIconSize).Fill().FillColor(Gray(0.2))
+	if mic {
+		w.Rectangle(8, 8, 4, 8).Fill().LineWidth(1.5).StrokeColor(Gray(0.2)).
+			MoveTo(6, 11).CurveV(6, 6, 10, 6).CurveY(14, 6, 14, 11).Stroke().
+			MoveTo(10, 6).LineTo(10, 3).Stroke()
+	} else {
+		w.Rectangle(4, 7, 4, 6).Fill().
+			MoveTo(8, 7).LineTo(13, 3).LineTo(13, 17).LineTo(8, 13).ClosePath().Fill()
+	}
+	return string(w.Bytes())
+}
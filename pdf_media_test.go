@@ -0,0 +1,168 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestScreen(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(300, 300)
+	m := &Media{
+		File:     &File{Name: "intro.mp4", MIMEType: "video/mp4", Data: []byte("video")},
+		Title:    "Introduction",
+		Controls: true,
+		Loop:     true,
+		AutoPlay: true,
+	}
+	s, err := d.AddScreen(10, 100, 160, 90, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PushButton(10, 10, 50, 20, &Button{Name: "stop", Caption: "Stop",
+		Action: &Rendition{s, MediaStop}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		m   Media
+		err string
+	}{
+		{Media{}, "pdf.go: media with no file"},
+		{Media{File: &File{Name: "a.mp3"}}, "pdf.go: media with no MIME type: a.mp3"},
+	} {
+		if _, err := d.AddScreen(0, 0, 10, 10, &tt.m); fmt.Sprint(err) != tt.err {
+			t.Errorf("got %v expected %q", err, tt.err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	annots := r.resolve(r.pages()[0].dic["Annots"]).([]interface{})
+	a := r.resolve(annots[0]).(map[string]interface{})
+	if a["Subtype"] != name("Screen") || a["T"] != "Introduction" {
+		t.Fatalf("screen: got %v", a)
+	}
+	play := r.resolve(a["A"]).(map[string]interface{})
+	if play["S"] != name("Rendition") || play["OP"] != MediaPlay || play["AN"] != annots[0] {
+		t.Errorf("play action: got %v", play)
+	}
+	if aa := r.resolve(a["AA"]).(map[string]interface{}); aa["PO"] == nil {
+		t.Errorf("not played when the page is opened")
+	}
+	rend := r.resolve(play["R"]).(map[string]interface{})
+	clip := r.resolve(rend["C"]).(map[string]interface{})
+	if clip["CT"] != "video/mp4" {
+		t.Errorf("media clip: got %v", clip)
+	}
+	spec := r.resolve(clip["D"]).(map[string]interface{})
+	ef := r.resolve(r.resolve(spec["EF"]).(map[string]interface{})["F"]).(*stream)
+	if string(r.decode(ef)) != "video" {
+		t.Errorf("embedded clip: got %q", r.decode(ef))
+	}
+	be := r.resolve(r.resolve(rend["P"]).(map[string]interface{})["BE"]).(map[string]interface{})
+	if be["C"] != true || be["RC"] != 0 {
+		t.Errorf("play parameters: got %v", be)
+	}
+	stop := r.resolve(r.resolve(annots[1]).(map[string]interface{})["A"]).(map[string]interface{})
+	if stop["OP"] != MediaStop || stop["AN"] != annots[0] || stop["R"] != nil {
+		t.Errorf("stop action: got %v", stop)
+	}
+	// The clip isn't an attached file of the document.
+	if bytes.Contains(buf.Bytes(), []byte("/EmbeddedFiles")) {
+		t.Errorf("clip is attached to the document")
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+}
+
+func TestSound(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(300, 300)
+	if err := d.AddSound(20, 250, &Sound{Data: []byte{1, 2, 3, 4}, Rate: 8000,
+		Bits: 16, Encoding: SoundSigned, Note: "Check the numbers"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddSound(50, 250, &Sound{Data: []byte{1}, Rate: 8000, Mic: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		s   Sound
+		err string
+	}{
+		{Sound{Rate: 8000}, "pdf.go: sound with no data"},
+		{Sound{Data: []byte{1}}, "pdf.go: sound with no sampling rate"},
+		{Sound{Data: []byte{1}, Rate: 1, Encoding: "MP3"}, "pdf.go: unknown encoding of sound: MP3"},
+	} {
+		if err := d.AddSound(0, 0, &tt.s); fmt.Sprint(err) != tt.err {
+			t.Errorf("got %v expected %q", err, tt.err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	annots := r.resolve(r.pages()[0].dic["Annots"]).([]interface{})
+	if len(annots) != 2 {
+		t.Fatalf("got %d annotations, expected 2", len(annots))
+	}
+	for i, tt := range []struct {
+		icon     string
+		contents interface{}
+		dic      map[string]interface{}
+	}{
+		{"Speaker", "Check the numbers", map[string]interface{}{"B": 16, "E": name("Signed")}},
+		{"Mic", nil, map[string]interface{}{"B": nil, "E": nil}},
+	} {
+		a := r.resolve(annots[i]).(map[string]interface{})
+		if a["Subtype"] != name("Sound") || a["Name"] != name(tt.icon) || a["Contents"] != tt.contents {
+			t.Errorf("annotation %d: got %v", i, a)
+		}
+		if ap := r.resolve(a["AP"]).(map[string]interface{}); ap["N"] == nil {
+			t.Errorf("annotation %d has no appearance", i)
+		}
+		s := r.resolve(a["Sound"]).(*stream)
+		if s.dic["Type"] != name("Sound") || s.dic["R"] != 8000 || s.dic["C"] != nil {
+			t.Errorf("sound %d: got %v", i, s.dic)
+		}
+		for k, v := range tt.dic {
+			if s.dic[k] != v {
+				t.Errorf("sound %d: got %v for %s, expected %v", i, s.dic[k], k, v)
+			}
+		}
+	}
+	if s := r.resolve(r.resolve(annots[0]).(map[string]interface{})["Sound"]).(*stream); string(r.decode(s)) != "\x01\x02\x03\x04" {
+		t.Errorf("samples: got %q", r.decode(s))
+	}
+}
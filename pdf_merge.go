@@ -0,0 +1,187 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file merges existing files into one document, and extracts pages of
+// them into new documents.
+
+import (
+	"fmt"
+	"io"
+)
+
+// Merge writes a document to w with the pages of all the inputs, in order.
+// The outlines (bookmarks) of the inputs are put one after another, and their
+// named destinations are kept; if two inputs have a destination with the same
+// name, the one of the first input is kept. Interactive forms of the inputs are
+// not merged.
+func Merge(w io.Writer, inputs ...*Reader) (err error) {
+	defer dontPanic(&err)
+
+	d, err := New(w)
+	check(err)
+	cs := make([]*copier, len(inputs))
+	for i, r := range inputs {
+		c := newCopier(d, r)
+		for _, p := range r.pages() {
+			c.addPage(p)
+		}
+		d.copyDests(c)
+		cs[i] = c
+	}
+	d.mergeOutlines(cs)
+	for _, c := range cs {
+		c.flush()
+	}
+	check(d.Close())
+	return nil
+}
+
+// copyDests adds the named destinations of the file of c to the document
+// (p. 367). Destinations are in the Dests dictionary of the catalog in PDF
+// 1.1, and in the Dests name tree since PDF 1.2.
+func (d *Document) copyDests(c *copier) {
+	cat, _ := c.r.resolve(c.r.trailer["Root"]).(map[string]interface{})
+	dests := make(map[string]interface{})
+	if old, ok := c.r.resolve(cat["Dests"]).(map[string]interface{}); ok {
+		for k, v := range old {
+			dests[k] = v
+		}
+	}
+	if names, ok := c.r.resolve(cat["Names"]).(map[string]interface{}); ok {
+		c.r.nameTree(names["Dests"], dests, make(map[int]bool))
+	}
+	if d.dests == nil && len(dests) > 0 {
+		d.dests = make(map[string]interface{})
+	}
+	for k, v := range dests {
+		if _, ok := d.dests[k]; !ok && c.destCopied(v) {
+			d.dests[k] = c.value(v)
+		}
+	}
+}
+
+// destCopied tells whether the page of destination v (p. 365) is copied.
+func (c *copier) destCopied(v interface{}) bool {
+	v = c.r.resolve(v)
+	if m, ok := v.(map[string]interface{}); ok {
+		v = c.r.resolve(m["D"])
+	}
+	a, _ := v.([]interface{})
+	if len(a) == 0 {
+		return false
+	}
+	p, ok := a[0].(ref)
+	_, copied := c.refs[p.num]
+	return !ok || copied // pages of other files are numbers
+}
+
+// mergeOutlines puts the top-level items of the outlines of the files of cs
+// one after another in the outline of the document (p. 584).
+func (d *Document) mergeOutlines(cs []*copier) {
+	type item struct {
+		c   *copier
+		num int
+	}
+	var items []item
+	for _, c := range cs {
+		cat, _ := c.r.resolve(c.r.trailer["Root"]).(map[string]interface{})
+		o, _ := c.r.resolve(cat["Outlines"]).(map[string]interface{})
+		seen := make(map[int]bool)
+		n, ok := o["First"].(ref)
+		for ok && !seen[n.num] {
+			seen[n.num] = true
+			items = append(items, item{c, n.num})
+			m, _ := c.r.resolve(n).(map[string]interface{})
+			n, ok = m["Next"].(ref)
+		}
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	d.outlines = d.reserveIndirect()
+	refs := make([]*indirect, len(items))
+	for i, it := range items {
+		refs[i], _ = it.c.ref(it.num).(*indirect)
+	}
+	for i, it := range items {
+		m := make(map[string]interface{})
+		old, _ := it.c.r.object(it.num).(map[string]interface{})
+		for k, v := range old {
+			if k != "Prev" && k != "Next" {
+				m[k] = v
+			}
+		}
+		m["Parent"] = d.outlines
+		if i > 0 {
+			m["Prev"] = refs[i-1]
+		}
+		if i < len(items)-1 {
+			m["Next"] = refs[i+1]
+		}
+		it.c.override(it.num, m)
+	}
+	d.outputIndirect(d.outlines, map[string]interface{}{
+		"Type":  name("Outlines"),
+		"First": refs[0],
+		"Last":  refs[len(refs)-1],
+		"Count": len(refs),
+	})
+}
+
+// PageRange is a range of pages of a file, from First to Last. Pages are
+// numbered from 1, and a Last of zero means the last page of the file.
+type PageRange struct {
+	First, Last int
+}
+
+// ExtractPages writes a document to w with the pages of r in ranges, in the
+// order of the ranges, and all the objects they need. Named destinations of
+// the pages are kept. A page can't be in more than one range.
+func ExtractPages(w io.Writer, r *Reader, ranges ...PageRange) (err error) {
+	defer dontPanic(&err)
+
+	pgs := r.pages()
+	d, err := New(w)
+	check(err)
+	c := newCopier(d, r)
+	added := make(map[int]bool)
+	for _, pr := range ranges {
+		last := pr.Last
+		if last == 0 {
+			last = len(pgs)
+		}
+		if pr.First < 1 || last < pr.First || last > len(pgs) {
+			panic(fmt.Sprintf("bad page range %d-%d of %d pages", pr.First, pr.Last, len(pgs)))
+		}
+		for i := pr.First; i <= last; i++ {
+			if added[i] {
+				panic(fmt.Sprintf("page %d is in more than one range", i))
+			}
+			added[i] = true
+			c.addPage(pgs[i-1])
+		}
+	}
+	if len(added) == 0 {
+		panic("no pages to extract")
+	}
+	d.copyDests(c)
+	c.flush()
+	check(d.Close())
+	return nil
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+// testMergeInputs returns two files with outlines and named destinations.
+// The first one has one page, and the second one two.
+func testMergeInputs(t *testing.T) (*Reader, *Reader) {
+	a, err := NewReader(testFile([]string{
+		"<< /Type /Catalog /Pages 2 0 R /Outlines 5 0 R /Names << /Dests << /Names [ (intro) 7 0 R ] >> >> >>",
+		"<< /Type /Pages /Kids [ 3 0 R ] /Count 1 /MediaBox [ 0 0 100 100 ] /Resources << >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		"<< /Length 3 >>\nstream\nq Q\nendstream",
+		"<< /Type /Outlines /First 6 0 R /Last 6 0 R /Count 1 >>",
+		"<< /Title (A) /Parent 5 0 R /Dest [ 3 0 R /Fit ] >>",
+		"[ 3 0 R /Fit ]",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewReader(testFile([]string{
+		"<< /Type /Catalog /Pages 2 0 R /Outlines 6 0 R /Dests << /intro [ 4 0 R /Fit ] /end [ 4 0 R /Fit ] >> >>",
+		"<< /Type /Pages /Kids [ 3 0 R 4 0 R ] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [ 0 0 200 200 ] /Resources << >> >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [ 0 0 300 300 ] /Resources 5 0 R >>",
+		"<< /Font << >> >>",
+		"<< /Type /Outlines /First 7 0 R /Last 8 0 R /Count 2 >>",
+		"<< /Title (B1) /Parent 6 0 R /Next 8 0 R /Dest [ 3 0 R /Fit ] >>",
+		"<< /Title (B2) /Parent 6 0 R /Prev 7 0 R /Dest [ 4 0 R /Fit ] >>",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, b
+}
+
+func TestMerge(t *testing.T) {
+	a, b := testMergeInputs(t)
+	buf := bytes.NewBuffer(nil)
+	if err := Merge(buf, a, b); err != nil {
+		t.Fatal(err)
+	}
+	if p := Validate(buf.Bytes()); len(p) != 0 {
+		t.Errorf("problems of merged document: %v", p)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgs := r.pages()
+	if len(pgs) != 3 {
+		t.Fatalf("number of pages: got %d expected 3", len(pgs))
+	}
+	for i, w := range []int{100, 200, 300} {
+		if box, _ := pgs[i].dic["MediaBox"].([]interface{}); len(box) != 4 || box[2] != w {
+			t.Errorf("MediaBox of page %d: got %v", i, pgs[i].dic["MediaBox"])
+		}
+	}
+
+	// Outline items link one after another, and their destinations point
+	// to the new pages.
+	cat := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	o, _ := r.resolve(cat["Outlines"]).(map[string]interface{})
+	var titles []string
+	n, ok := o["First"].(ref)
+	for i := 0; ok && i < 10; i++ {
+		m := r.resolve(n).(map[string]interface{})
+		titles = append(titles, m["Title"].(string))
+		if dest, _ := m["Dest"].([]interface{}); len(dest) == 0 || dest[0] != (ref{pgs[i].num, 0}) {
+			t.Errorf("destination of outline item %d: got %v", i, m["Dest"])
+		}
+		n, ok = m["Next"].(ref)
+	}
+	if len(titles) != 3 || titles[0] != "A" || titles[1] != "B1" || titles[2] != "B2" {
+		t.Errorf("outline: got %v", titles)
+	}
+
+	dests := make(map[string]interface{})
+	names := r.resolve(cat["Names"]).(map[string]interface{})
+	r.nameTree(names["Dests"], dests, make(map[int]bool))
+	if len(dests) != 2 {
+		t.Fatalf("named destinations: got %v", dests)
+	}
+	if d := r.resolve(dests["intro"]).([]interface{}); d[0] != (ref{pgs[0].num, 0}) {
+		t.Errorf("destination intro: got %v", d)
+	}
+	if d := r.resolve(dests["end"]).([]interface{}); d[0] != (ref{pgs[2].num, 0}) {
+		t.Errorf("destination end: got %v", d)
+	}
+}
+
+func TestExtractPages(t *testing.T) {
+	_, b := testMergeInputs(t)
+	buf := bytes.NewBuffer(nil)
+	if err := ExtractPages(buf, b, PageRange{2, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if p := Validate(buf.Bytes()); len(p) != 0 {
+		t.Errorf("problems of document: %v", p)
+	}
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgs := r.pages()
+	if len(pgs) != 1 {
+		t.Fatalf("number of pages: got %d expected 1", len(pgs))
+	}
+	res, _ := r.resolve(pgs[0].dic["Resources"]).(map[string]interface{})
+	if res == nil || res["Font"] == nil {
+		t.Errorf("resources of page: got %v", pgs[0].dic["Resources"])
+	}
+	if bytes.Contains(buf.Bytes(), []byte("/MediaBox [ 0 0 200 200 ]")) {
+		t.Error("page not in the ranges was copied")
+	}
+	cat := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	dests := make(map[string]interface{})
+	r.nameTree(r.resolve(cat["Names"]).(map[string]interface{})["Dests"], dests, make(map[int]bool))
+	if len(dests) != 2 {
+		t.Errorf("named destinations: got %v", dests)
+	}
+	for _, n := range []string{"intro", "end"} {
+		if d, _ := r.resolve(dests[n]).([]interface{}); len(d) == 0 || d[0] != (ref{pgs[0].num, 0}) {
+			t.Errorf("destination %s: got %v", n, dests[n])
+		}
+	}
+
+	// Destinations on pages that are not extracted are left out.
+	buf.Reset()
+	if err := ExtractPages(buf, b, PageRange{1, 1}); err != nil {
+		t.Fatal(err)
+	}
+	r, err = NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat = r.resolve(r.trailer["Root"]).(map[string]interface{})
+	if names, ok := r.resolve(cat["Names"]).(map[string]interface{}); ok && names["Dests"] != nil {
+		t.Errorf("named destinations of page 1: got %v", names["Dests"])
+	}
+
+	for _, rs := range [][]PageRange{{{0, 1}}, {{2, 1}}, {{1, 3}}, {{1, 2}, {2, 2}}, nil} {
+		if err := ExtractPages(bytes.NewBuffer(nil), b, rs...); err == nil {
+			t.Errorf("%v: expected error", rs)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file has the object model that users can build arbitrary PDF objects
+// with, for the parts of PDF that the rest of pdf.go doesn't cover. Objects
+// are made of Go values: bools, ints and floats for numbers, strings for
+// byte strings, and the types here for the rest.
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Name is a PDF name, like Name("Widget"), to be used in custom entries and
+// objects.
+type Name string
+
+// Str is a PDF text string, which is written in UTF-16 if it has non-ASCII
+// characters, unlike plain strings that are written byte by byte. Strings
+// shown to users, like titles, should be Strs.
+type Str string
+
+// Dict is a PDF dictionary.
+type Dict map[string]interface{}
+
+// NewDict returns a dictionary with the given keys and values, which come in
+// pairs, like NewDict("Type", Name("Example"), "Count", 2). Keys that are not
+// strings, and a key with no value, are kept in the dictionary as bad
+// entries, so that AddObject and the other functions it's used with return
+// errors of kind ErrInvalid.
+func NewDict(kv ...interface{}) Dict {
+	d := make(Dict, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		k, ok := kv[i].(string)
+		if !ok {
+			d[fmt.Sprint(kv[i])] = badEntry(fmt.Sprintf("key of dictionary is not a string: %v", kv[i]))
+			continue
+		}
+		if i+1 == len(kv) {
+			d[k] = badEntry("odd number of keys and values for NewDict")
+			continue
+		}
+		d[k] = kv[i+1]
+	}
+	return d
+}
+
+// badEntry is an entry of a dictionary made by NewDict with bad arguments,
+// which panics with what's wrong when it's checked or written.
+type badEntry string
+
+func (e badEntry) output() []byte {
+	panic(string(e))
+}
+
+// Array is a PDF array.
+type Array []interface{}
+
+// NewArray returns an array of the given values.
+func NewArray(v ...interface{}) Array {
+	return Array(v)
+}
+
+// Ref is a reference to an object added by AddObject, to be used in custom
+// entries and objects.
+type Ref struct {
+	i *indirect
+}
+
+func (r Ref) output() []byte {
+	if r.i == nil {
+		panic("zero Ref used")
+	}
+	return r.i.output()
+}
+
+// Stream is a PDF stream with dictionary Dict, which doesn't need the
+// Length entry, and data Data, to be added by AddObject. Data is encoded with
+// Filters, which set the Filter and DecodeParms entries, if there are any.
+type Stream struct {
+	Dict    map[string]interface{}
+	Data    []byte
+	Filters []StreamFilter
+}
+
+// NewStream returns a stream with dictionary dic and data b.
+func NewStream(dic Dict, b []byte, filters ...StreamFilter) *Stream {
+	return &Stream{dic, b, filters}
+}
+
+// AddObject writes v to the output as a new object, and returns a reference
+// to it. v can be made of bools, numbers, strings, Strs, Names, Refs, slices,
+// Arrays, maps with string keys and Dicts, or it can be a *Stream.
+func (d *Document) AddObject(v interface{}) (r Ref, err error) {
+	defer dontPanic(&err)
+
+	checkObject(v, 0)
+	return Ref{d.indirect(v)}, nil
+}
+
+// Reserve returns a reference to a new object that is written to the output
+// later by Define, so that objects can refer to each other, like parents and
+// their kids. All reserved objects should be defined before the document is
+// closed.
+func (d *Document) Reserve() (r Ref, err error) {
+	defer dontPanic(&err)
+
+	i := d.reserveIndirect()
+	d.resMu.Lock()
+	defer d.resMu.Unlock()
+	if d.reserved == nil {
+		d.reserved = make(map[*indirect]bool)
+	}
+	d.reserved[i] = true
+	return Ref{i}, nil
+}
+
+// Define writes v, which can be of the types that AddObject accepts, to the
+// output as the object that r refers to. r should be returned by Reserve and
+// can be defined only once.
+func (d *Document) Define(r Ref, v interface{}) (err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	checkObject(v, 0)
+	d.resMu.Lock()
+	ok := d.reserved[r.i]
+	delete(d.reserved, r.i)
+	d.resMu.Unlock()
+	if !ok {
+		panic("object is not reserved or is already defined")
+	}
+	d.outputIndirect(r.i, v)
+	return nil
+}
+
+// maxDepth is the deepest that objects passed to AddObject can be nested,
+// which catches the ones that contain themselves.
+const maxDepth = 100
+
+// checkObject panics if v can't be written as a PDF object. depth is how
+// deep v is in the object passed to AddObject.
+func checkObject(v interface{}, depth int) {
+	if depth > maxDepth {
+		panic("object is nested too deep")
+	}
+	switch t := v.(type) {
+	case nil, bool, int, float32, float64, string, Str, Name, []byte:
+		return
+	case Ref:
+		if t.i == nil {
+			panic("zero Ref used")
+		}
+		return
+	case badEntry:
+		panic(string(t))
+	case *Stream:
+		if t == nil {
+			panic("nil *Stream")
+		}
+		checkObject(t.Dict, depth+1)
+		return
+	}
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < r.Len(); i++ {
+			checkObject(r.Index(i).Interface(), depth+1)
+		}
+		return
+	case reflect.Map:
+		if r.Type().Key().Kind() != reflect.String {
+			panic("key of dictionary is not a string: " + r.Type().String())
+		}
+		for _, k := range r.MapKeys() {
+			checkObject(r.MapIndex(k).Interface(), depth+1)
+		}
+		return
+	}
+	panic(fmt.Sprintf("unsupported type in object: %T", v))
+}
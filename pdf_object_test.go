@@ -0,0 +1,152 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestObject(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{Name("A B"), "/A#20B"},
+		{Str("Tea"), "(Tea)"},
+		{Str("é"), "(\xfe\xff\x00\xe9)"},
+		{NewArray(1, 2.5, Name("X")), "[ 1 2.5 /X ]"},
+		{NewDict("Type", Name("T"), "N", NewArray()), "<<\n/N [ ]\n/Type /T\n>>"},
+		{NewStream(NewDict("K", true), []byte("ab")), "<<\n/K true\n/Length 2\n>>\nstream\nab\nendstream"},
+	}
+	for _, tt := range tests {
+		if got := string(output(tt.v)); got != tt.want {
+			t.Errorf("output of %v: got %q, want %q", tt.v, got, tt.want)
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	loop := Array{nil}
+	loop[0] = loop
+	bad := []interface{}{
+		int64(1),
+		struct{}{},
+		Ref{},
+		NewArray(1, Ref{}),
+		map[int]interface{}{1: 2},
+		(*Stream)(nil),
+		loop,
+	}
+	for _, v := range bad {
+		_, err := d.AddObject(v)
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("bad object %T: got %v expected ErrInvalid", v, err)
+		}
+	}
+	for _, kv := range [][]interface{}{{"A"}, {1, 2}} {
+		dic := NewDict(kv...)
+		if _, err := d.AddObject(NewArray(dic)); !errors.Is(err, ErrInvalid) {
+			t.Errorf("NewDict%v: got %v expected ErrInvalid", kv, err)
+		}
+		p, _ := d.NewPage(100, 100)
+		if err := p.Raw("DP", Name("X"), dic); !errors.Is(err, ErrInvalid) {
+			t.Errorf("NewDict%v in content: got %v expected ErrInvalid", kv, err)
+		}
+	}
+
+	data, err := d.AddObject(NewStream(NewDict("Type", Name("Data")), []byte("abc"), FlateFilter{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := d.AddObject(NewDict("Title", Str("Tea ☕"), "Data", data, "Sizes", NewArray(1, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Extend(ExtendCatalog, func(dic map[string]interface{}) error {
+		dic["Custom"] = obj
+		return nil
+	})
+	d.NewPage(100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	custom := r.resolve(cat["Custom"]).(map[string]interface{})
+	if custom["Title"] != textString("Tea ☕") || fmt.Sprint(custom["Sizes"]) != "[1 2]" {
+		t.Errorf("custom object: got %v", custom)
+	}
+	if s := r.resolve(custom["Data"]).(*stream); string(r.decode(s)) != "abc" || s.dic["Filter"] != name("FlateDecode") {
+		t.Errorf("custom stream: got %v", s.dic)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	parent, _ := d.Reserve()
+	kid, err := d.Reserve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Define(kid, NewDict("Parent", parent, "Name", "kid")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Define(parent, NewDict("Kids", NewArray(kid))); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Define(kid, 1); !errors.Is(err, ErrInvalid) {
+		t.Errorf("object defined twice: got %v expected ErrInvalid", err)
+	}
+	added, _ := d.AddObject(1)
+	if err := d.Define(added, 2); !errors.Is(err, ErrInvalid) {
+		t.Errorf("object not reserved: got %v expected ErrInvalid", err)
+	}
+	d.Extend(ExtendCatalog, func(dic map[string]interface{}) error {
+		dic["Tree"] = parent
+		return nil
+	})
+	d.NewPage(100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	tree := r.resolve(cat["Tree"]).(map[string]interface{})
+	k := r.resolve(r.resolve(tree["Kids"]).([]interface{})[0]).(map[string]interface{})
+	if k["Name"] != "kid" || k["Parent"] != cat["Tree"] {
+		t.Errorf("kid: got %v", k)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.Reserve()
+	d.NewPage(100, 100)
+	if err := d.Close(); !errors.Is(err, ErrInvalid) {
+		t.Errorf("object not defined: got %v expected ErrInvalid", err)
+	}
+}
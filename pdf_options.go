@@ -0,0 +1,167 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the options of documents, which are given when they're
+// made.
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Options holds how a document is made. The zero value gives the defaults.
+type Options struct {
+	Version   string  // PDF version in the header, like "1.4"; "1.7" if empty
+	Compress  bool    // whether content streams are compressed with Flate; same as Filters with only FlateFilter
+	Precision int     // digits after the point of numbers of graphics methods and XObjects drawn; 3 if zero or negative, at most 10
+	UserUnit  float64 // size of the unit of pages, in 1/72 inch (p. 145); 1 if zero
+
+	// Size of pages started by NewPage with zero width and height; A4 if
+	// zero.
+	PageWidth, PageHeight int
+
+	// Deterministic makes the same document each time it's made with the
+	// same content, for tests and reproducible builds: times that aren't
+	// set are the start of 1970, and the identifier of the file is made from
+	// the producer instead of random numbers. Encryption still uses random
+	// numbers.
+	Deterministic bool
+
+	Producer string // Producer in the metadata; "pdf.go" if empty
+
+	// TempFile makes the temporary files of documents whose memory is
+	// limited by SetMemoryLimit, for where there's no file system, like
+	// in sandboxes and under js/wasm. Files of the operating system are
+	// used if it's nil.
+	TempFile func() (TempFile, error)
+
+	// FlateLevel is the level of Flate compression of images, and of
+	// content streams if Compress is set: FlateStore, or from FlateSpeed
+	// to FlateBest. It's FlateBest if zero.
+	FlateLevel int
+
+	// Flater, if set, compresses images, and content streams if Compress
+	// is set, instead of compress/zlib.
+	Flater Flater
+
+	// FailOnMissingGlyphs makes drawing text with characters that its
+	// font and the sources of emoji don't have fail with an error of kind
+	// ErrNoGlyph, instead of showing them as question marks, or as the
+	// .notdef glyph of outline fonts. MissingGlyphs returns them either
+	// way.
+	FailOnMissingGlyphs bool
+
+	// Strict makes the document check objects and content streams as
+	// they're written, against the rules of PDF and the limits of
+	// viewers: names of at most 127 bytes, strings of at most 32767
+	// bytes, numbers in range, dictionaries with names for keys and values
+	// that can be written, balanced q and Q, BT and ET, and marked
+	// content, and no object reserved but never written when it's
+	// closed. Mistakes fail at once, with errors of kind ErrInvalid that
+	// say what's wrong and where, instead of making a broken file.
+	Strict bool
+
+	// Filters, if set, encode content streams, in the order viewers
+	// decode them, which is the reverse of the order they're applied.
+	Filters []StreamFilter
+
+	// Trace, if set, makes content streams have comments with the line of
+	// code that drew what comes after them, like
+	// "% source:main.go:12 Rectangle", and logs every object written to
+	// it with its offset, for debugging the output.
+	Trace io.Writer
+}
+
+// Size of A4 pages, in points.
+const (
+	a4Width  = 595
+	a4Height = 842
+)
+
+// versions are the versions of PDF that documents can have.
+var versions = map[string]bool{
+	"1.0": true, "1.1": true, "1.2": true, "1.3": true, "1.4": true,
+	"1.5": true, "1.6": true, "1.7": true, "2.0": true,
+}
+
+// options returns the options in opts with the defaults filled in. opts has
+// at most one element.
+func options(opts []Options) Options {
+	var o Options
+	switch len(opts) {
+	case 0:
+	case 1:
+		o = opts[0]
+	default:
+		panic("more than one Options given")
+	}
+	if o.Version == "" {
+		o.Version = "1.7"
+	}
+	if !versions[o.Version] {
+		panic("unknown PDF version: " + o.Version)
+	}
+	if o.UserUnit < 0 {
+		panic(fmt.Sprint("negative user unit: ", o.UserUnit))
+	}
+	if o.UserUnit != 0 && o.UserUnit != 1 && o.Version < "1.6" {
+		panic("user units need PDF 1.6")
+	}
+	if o.PageWidth == 0 && o.PageHeight == 0 {
+		o.PageWidth, o.PageHeight = a4Width, a4Height
+	}
+	if o.Producer == "" {
+		o.Producer = producer
+	}
+	zlibLevel(o.FlateLevel) // check it
+	if o.Compress && len(o.Filters) == 0 {
+		o.Filters = []StreamFilter{FlateFilter{o.FlateLevel, o.Flater}}
+	}
+	checkFilters(o.Filters)
+	return o
+}
+
+// now returns the current time, or the start of 1970 if d is deterministic.
+func (d *Document) now() time.Time {
+	if d.opts.Deterministic {
+		return time.Unix(0, 0).UTC()
+	}
+	return time.Now()
+}
+
+// outputContent writes a content stream with dictionary dic and the data of
+// s as the indirect object i, encoded with the filters of the content streams
+// of d.
+func (d *Document) outputContent(i *indirect, dic map[string]interface{}, s *spill) {
+	i.part = partContent
+	d.checkContent(i, s)
+	if len(d.opts.Filters) == 0 {
+		d.outputSpill(i, dic, s)
+		return
+	}
+	c := d.newSpill()
+	encodeWith(c, s.reader(), d.opts.Filters)
+	s.release()
+	f := make(map[string]interface{}, len(dic)+2)
+	for k, v := range dic {
+		f[k] = v
+	}
+	filterEntries(f, d.opts.Filters)
+	d.outputSpill(i, f, c)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestOptions(t *testing.T) {
+	makeDoc := func(o Options) []byte {
+		buf := bytes.NewBuffer(nil)
+		d, err := New(buf, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.SetInfo(&Info{Title: "Options"})
+		d.NewPage(0, 0)
+		d.Rectangle(1, 2, 3, 4)
+		x, _ := d.AddImage(image.NewGray(image.Rect(0, 0, 2, 2)))
+		d.DrawXObject(x, 0, 0, 10.123, 10)
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	o := Options{
+		Version:       "1.6",
+		Compress:      true,
+		Precision:     1,
+		UserUnit:      2,
+		PageWidth:     300,
+		PageHeight:    400,
+		Deterministic: true,
+		Producer:      "Tests",
+	}
+	out := makeDoc(o)
+	if !bytes.Equal(out, makeDoc(o)) {
+		t.Error("deterministic documents differ")
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-1.6\n")) {
+		t.Errorf("header: got %q", out[:9])
+	}
+	r, err := NewReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := r.Info()
+	if info["Producer"] != "Tests" || !strings.HasPrefix(info["CreationDate"], "D:19700101") {
+		t.Errorf("info: got %v", info)
+	}
+	pg := r.pages()[0]
+	if box := fmt.Sprint(r.resolve(pg.dic["MediaBox"])); box != "[0 0 300 400]" {
+		t.Errorf("size of page: got %v", box)
+	}
+	if u := pg.dic["UserUnit"]; u != 2 {
+		t.Errorf("user unit: got %v", u)
+	}
+	c := r.resolve(r.resolve(pg.dic["Contents"]).([]interface{})[0]).(*stream)
+	if c.dic["Filter"] != name("FlateDecode") {
+		t.Errorf("content not compressed: %v", c.dic)
+	}
+	if con := string(r.contents(pg.dic["Contents"])); !strings.Contains(con, "1 2 3 4 re\nq 10.1 0 0 10 0 0 cm /Im") {
+		t.Errorf("content: got %q", con)
+	}
+
+	// The defaults
+	r, _ = NewReader(makeDoc(Options{}))
+	pg = r.pages()[0]
+	if box := fmt.Sprint(r.resolve(pg.dic["MediaBox"])); box != "[0 0 595 842]" {
+		t.Errorf("default size of page: got %v", box)
+	}
+	if con := string(r.contents(pg.dic["Contents"])); !strings.Contains(con, "q 10.123 0 0 10 0 0 cm") {
+		t.Errorf("default content: got %q", con)
+	}
+	r, _ = NewReader(makeDoc(Options{Precision: -1}))
+	if con := string(r.contents(r.pages()[0].dic["Contents"])); !strings.Contains(con, "q 10.123 0 0 10 0 0 cm") {
+		t.Errorf("negative precision: got %q", con)
+	}
+
+	tests := []struct {
+		opts []Options
+		err  string
+	}{
+		{[]Options{{Version: "3.0"}}, "pdf.go: unknown PDF version: 3.0"},
+		{[]Options{{UserUnit: 2, Version: "1.4"}}, "pdf.go: user units need PDF 1.6"},
+		{[]Options{{}, {}}, "pdf.go: more than one Options given"},
+	}
+	for _, tt := range tests {
+		if _, err := New(bytes.NewBuffer(nil), tt.opts...); fmt.Sprint(err) != tt.err {
+			t.Errorf("%v: got %v expected %s", tt.opts, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the temporary files of the operating system. It's the
+// only part of the package that uses the file system, so that documents can
+// be made entirely in memory where there's none.
+
+import (
+	"os"
+)
+
+// osFile is a temporary file of the operating system, which is removed when
+// it's closed.
+type osFile struct {
+	*os.File
+}
+
+// osTempFile makes a temporary file of the operating system.
+func osTempFile() (TempFile, error) {
+	f, err := os.CreateTemp("", "pdf.go-")
+	if err != nil {
+		return nil, err
+	}
+	return osFile{f}, nil
+}
+
+func (f osFile) Close() error {
+	err := f.File.Close()
+	if e := os.Remove(f.Name()); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains handles of pages, which let pages be drawn on in any
+// order until they're written to the output.
+
+// Page is a page of a document, returned by NewPage. Pages are kept open
+// after other pages are started, so that they can be drawn on later, e.g. a
+// summary page filled when the totals of the next pages are known. They're
+// written to the output when they're flushed, or when the document is closed.
+type Page struct {
+	d  *Document
+	pg *page
+}
+
+// Number returns the number of p in the document, counted from 1.
+func (p *Page) Number() int {
+	return p.pg.index + 1
+}
+
+// Size returns the width and height of p.
+func (p *Page) Size() (w, h float64) {
+	return p.pg.box.urx, p.pg.box.ury
+}
+
+// check panics if p can't be drawn on.
+func (p *Page) check() {
+	if p.pg.flushed {
+		panic("page is already flushed")
+	}
+	if p.d.xbox != nil {
+		panic("page changed inside an XObject")
+	}
+}
+
+// Use makes p the current page of its document, which the methods of the
+// document draw on.
+func (p *Page) Use() (err error) {
+	defer dontPanic(&err)
+
+	p.check()
+	p.d.setPage(p.pg)
+	return nil
+}
+
+// Draw calls f with p as the current page of the document, and makes the
+// page that was current before it current again.
+func (p *Page) Draw(f func(d *Document) error) (err error) {
+	defer dontPanic(&err)
+
+	p.check()
+	cur := p.d.pg
+	p.d.setPage(p.pg)
+	err = f(p.d)
+	if cur == nil || !cur.flushed {
+		p.d.setPage(cur)
+	}
+	return err
+}
+
+// DrawXObject draws x on p, like the DrawXObject method of the document.
+func (p *Page) DrawXObject(x *XObject, px, py, w, h float64) error {
+	return p.Draw(func(d *Document) error {
+		return d.DrawXObject(x, px, py, w, h)
+	})
+}
+
+// DrawGrid draws the content of g on p, like the DrawGrid method of the
+// document.
+func (p *Page) DrawGrid(g *Grid, x, y, w, h float64) error {
+	return p.Draw(func(d *Document) error {
+		return d.DrawGrid(g, x, y, w, h)
+	})
+}
+
+// Flush writes p to the output. It can't be drawn on after it. There's no
+// current page after p is flushed, if p was the current page.
+func (p *Page) Flush() (err error) {
+	defer dontPanic(&err)
+
+	p.check()
+	p.d.flushPage(p.pg)
+	return nil
+}
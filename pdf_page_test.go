@@ -0,0 +1,74 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	summary, _ := d.NewPage(100, 100)
+	d.Rectangle(1, 1, 1, 1)
+	for i := 0; i < 2; i++ {
+		d.NewPage(200, 200)
+		d.Rectangle(2, 2, 2, 2)
+	}
+	err := summary.Draw(func(d *Document) error {
+		d.Rectangle(3, 3, 3, 3)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Rectangle(4, 4, 4, 4) // still on the last page
+	if n := summary.Number(); n != 1 {
+		t.Errorf("number of summary: got %d expected 1", n)
+	}
+	if w, h := summary.Size(); w != 100 || h != 100 {
+		t.Errorf("size of summary: got %v×%v expected 100×100", w, h)
+	}
+	if err := summary.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := summary.Use(); fmt.Sprint(err) != "pdf.go: page is already flushed" {
+		t.Errorf("Use after Flush: got %v", err)
+	}
+	d.Close()
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgs := r.pages()
+	if len(pgs) != 3 {
+		t.Fatalf("pages: got %d expected 3", len(pgs))
+	}
+	tests := []string{
+		"1 1 1 1 re\n3 3 3 3 re\n",
+		"2 2 2 2 re\n",
+		"2 2 2 2 re\n4 4 4 4 re\n",
+	}
+	for i, s := range tests {
+		if con := string(r.contents(pgs[i].dic["Contents"])); con != s+"\n" {
+			t.Errorf("content of page %d: got %q expected %q", i+1, con, s)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file makes documents conform to PDF/A-3 (ISO 19005-3), the standard for
+// long-term archiving that allows any kind of file to be attached. Only level
+// B, which preserves the visual appearance, is supported.
+
+// pdfaActions are the types of actions that PDF/A documents can't have.
+var pdfaActions = map[string]bool{
+	"Launch": true, "Sound": true, "Movie": true, "ResetForm": true,
+	"ImportData": true, "Hide": true, "SetOCGState": true, "Rendition": true,
+	"Trans": true, "GoTo3DView": true, "JavaScript": true,
+}
+
+// srgbName is the name of the output condition of sRGB in the ICC registry.
+const srgbName = "sRGB IEC61966-2.1"
+
+// SetPDFA3 makes the document conform to PDF/A-3b. It adds the metadata and
+// the sRGB output intent that the standard needs, and checks the rest of the
+// document against its rules; Close returns an error listing the broken
+// rules, if any. PDF/A documents can't be encrypted, can't have JavaScript,
+// and all their fonts and annotations should be embedded and printable.
+//
+// SetPDFA3 should be called right after New, before anything is added to
+// the document.
+func (d *Document) SetPDFA3() (err error) {
+	defer dontPanic(&err)
+
+	if d.sec != nil {
+		panic("PDF/A documents can't be encrypted")
+	}
+	if d.started() {
+		panic("SetPDFA3 must be called before anything is added to the document")
+	}
+	if d.pdfa {
+		return nil
+	}
+	d.pdfa = true
+	d.fileID() // needed by PDF/A
+	d.xmpDescs = append(d.xmpDescs,
+		"<rdf:Description rdf:about=\"\" xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\">\n"+
+			"<pdfaid:part>3</pdfaid:part>\n<pdfaid:conformance>B</pdfaid:conformance>\n"+
+			"</rdf:Description>\n")
+	return nil
+}
+
+// checkPDFA checks the dictionary m against the rules of PDF/A-3.
+func (d *Document) checkPDFA(m map[string]interface{}) {
+	const std = "PDF/A-3"
+	if s, ok := m["S"].(name); ok && pdfaActions[string(s)] {
+		d.violate(std, string(s)+" actions are not allowed")
+	}
+	if _, ok := m["AA"]; ok {
+		d.violate(std, "additional actions are not allowed")
+	}
+	if v, ok := m["NeedAppearances"]; ok && v == true {
+		d.violate(std, "NeedAppearances is not allowed")
+	}
+	// Type 0 fonts have their descriptors in their CIDFonts, which are
+	// checked on their own.
+	if m["Type"] == name("Font") && m["FontDescriptor"] == nil &&
+		m["Subtype"] != name("Type3") && m["Subtype"] != name("Type0") {
+		d.violate(std, "fonts should be embedded")
+	}
+	if _, ok := m["TR"]; ok && m["Type"] == name("ExtGState") {
+		d.violate(std, "transfer functions are not allowed")
+	}
+	if m["Type"] == name("EmbeddedFile") && m["Subtype"] == nil {
+		d.violate(std, "attached files should have a MIME type")
+	}
+	if m["Type"] == name("Annot") {
+		f, _ := m["F"].(int)
+		if f&4 == 0 || f&(1|2|32) != 0 {
+			d.violate(std, "annotations should be printable")
+		}
+		st := m["Subtype"]
+		r, _ := m["Rect"].(*rect)
+		zero := r != nil && r.urx == r.llx && r.ury == r.lly
+		if _, ok := m["AP"]; !ok && !zero && st != name("Popup") && st != name("Link") {
+			d.violate(std, "annotations should have appearances")
+		}
+	}
+
+	d.checkColors(std, m)
+}
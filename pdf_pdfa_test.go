@@ -0,0 +1,130 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSRGBProfile(t *testing.T) {
+	p := srgbProfile()
+	if n := binary.BigEndian.Uint32(p); int(n) != len(p) || n%4 != 0 {
+		t.Errorf("size of profile: got %d expected %d", n, len(p))
+	}
+	if string(p[12:24]) != "mntrRGB XYZ " || string(p[36:40]) != "acsp" {
+		t.Error("bad header of profile")
+	}
+	if n := binary.BigEndian.Uint32(p[128:]); n != 9 {
+		t.Errorf("number of tags: got %d expected 9", n)
+	}
+}
+
+func TestPDFA3(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.SetPDFA3(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Encrypt("", "", 128, PermAll); err == nil {
+		t.Error("PDF/A document encrypted")
+	}
+	d.NewPage(100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.String()
+	for _, s := range []string{"/S /GTS_PDFA1", "/DestOutputProfile 4 0 R",
+		"/Metadata 5 0 R", "<pdfaid:part>3</pdfaid:part>", "/ID [ <"} {
+		if !strings.Contains(b, s) {
+			t.Errorf("PDF/A document doesn't have %q", s)
+		}
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.Encrypt("", "", 128, PermAll)
+	if err := d.SetPDFA3(); err == nil {
+		t.Error("encrypted document made PDF/A")
+	}
+}
+
+type pdfaTest struct {
+	name string
+	add  func(d *Document)
+	rule string // empty if the document conforms
+}
+
+func TestPDFA3Violations(t *testing.T) {
+	tests := []pdfaTest{
+		{"empty page", func(d *Document) {}, ""},
+		{"check box", func(d *Document) {
+			d.CheckBox(0, 0, 10, 10, &CheckField{Name: "c"})
+		}, "fonts should be embedded"},
+		{"embedded font", func(d *Document) {
+			font, _ := d.AddFont(testCompositeFont())
+			d.BeginText()
+			d.SetFont(font, 10)
+			d.ShowText("CA")
+			d.EndText()
+		}, ""},
+		{"scripts", func(d *Document) {
+			d.ComboBox(0, 0, 10, 10, &ChoiceField{Name: "c",
+				FieldScripts: FieldScripts{Validate: "true;"}})
+		}, "JavaScript actions are not allowed"},
+		{"CMYK", func(d *Document) {
+			d.PushButton(0, 0, 10, 10, &Button{Name: "b",
+				FieldStyle: FieldStyle{Background: CMYK{0, 1, 0, 0}}})
+		}, "CMYK colors need a CMYK output intent"},
+		{"CMYK content", func(d *Document) {
+			d.DefineStyle("cyan", Style{Stroke: CMYK{1, 0, 0, 0}})
+			d.UseStyle("cyan")
+			d.MoveTo(0, 0)
+			d.LineTo(10, 10)
+			d.Stroke()
+		}, "CMYK colors need a CMYK output intent"},
+		{"no appearance", func(d *Document) {
+			d.DistanceAnnotation(0, 0, 10, 10, &Measure{Ratio: "1 in = 1 in", Unit: "in", Factor: 1 / 72.0})
+		}, "annotations should have appearances"},
+		{"file with no MIME type", func(d *Document) {
+			d.AttachFile(&File{Name: "a.txt"})
+		}, "attached files should have a MIME type"},
+		{"transfer function", func(d *Document) {
+			d.SetInkControl(&InkControl{Transfer: []Function{IdentityFunction}})
+		}, "transfer functions are not allowed"},
+		{"halftone", func(d *Document) {
+			d.SetInkControl(&InkControl{Halftone: &Halftone{Frequency: 150, Angle: 45}})
+		}, ""},
+	}
+
+	for _, test := range tests {
+		d, _ := New(bytes.NewBuffer(nil))
+		d.SetPDFA3()
+		d.NewPage(100, 100)
+		test.add(d)
+		err := d.Close()
+		if test.rule == "" && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.rule != "" && !strings.Contains(fmt.Sprint(err), test.rule) {
+			t.Errorf("%s: got error %v expected %q", test.name, err, test.rule)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file makes documents conform to PDF/UA-1 (ISO 14289-1), the standard
+// for documents that are accessible to people with disabilities, e.g. through
+// screen readers.
+
+// uaAnnots are the types of annotations that don't need alternative
+// descriptions in PDF/UA documents.
+var uaAnnots = map[string]bool{"Widget": true, "Link": true, "Popup": true}
+
+// SetPDFUA makes the document conform to PDF/UA-1. lang is the natural
+// language of the document, like "en-US" (RFC 3066). The document is checked
+// against the rules of PDF/UA, and Close returns an error listing the broken
+// rules, if any. PDF/UA documents should be tagged and have a title, which
+// viewers show instead of the name of the file. Their fonts should be
+// embedded, figures and annotations should have alternative descriptions,
+// and pages with annotations should use TabStructure as their tab order.
+//
+// SetPDFUA should be called right after New, before anything is added to
+// the document.
+func (d *Document) SetPDFUA(lang string) (err error) {
+	defer dontPanic(&err)
+
+	if d.started() {
+		panic("SetPDFUA must be called before anything is added to the document")
+	}
+	if d.pdfua {
+		panic("PDF/UA is already set")
+	}
+	if lang == "" {
+		panic("PDF/UA needs the language of the document")
+	}
+	d.pdfua = true
+	d.lang = lang
+	d.xmpDescs = append(d.xmpDescs,
+		"<rdf:Description rdf:about=\"\" xmlns:pdfuaid=\"http://www.aiim.org/pdfua/ns/id/\">\n"+
+			"<pdfuaid:part>1</pdfuaid:part>\n"+
+			"</rdf:Description>\n")
+	return nil
+}
+
+// checkPDFUA checks the dictionary m against the rules of PDF/UA.
+func (d *Document) checkPDFUA(m map[string]interface{}) {
+	const std = "PDF/UA-1"
+	switch t, _ := m["Type"].(name); t {
+	case "Catalog":
+		if m["StructTreeRoot"] == nil {
+			d.violate(std, "documents should be tagged")
+		}
+	case "Font":
+		if m["FontDescriptor"] == nil && m["Subtype"] != name("Type0") {
+			d.violate(std, "fonts should be embedded")
+		}
+	case "Page":
+		if m["Annots"] != nil && m["Tabs"] != name("S") {
+			d.violate(std, "pages with annotations should use the structure tab order")
+		}
+	case "StructElem":
+		if m["S"] == name("Figure") && m["Alt"] == nil && m["ActualText"] == nil {
+			d.violate(std, "figures should have alternative descriptions")
+		}
+	}
+	if s, ok := m["Subtype"].(name); ok && m["Rect"] != nil && !uaAnnots[string(s)] && m["Contents"] == nil {
+		d.violate(std, "annotations should have alternative descriptions")
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSetPDFUA(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.SetPDFUA(""); err == nil {
+		t.Error("PDF/UA with no language")
+	}
+	if err := d.SetPDFUA("en-US"); err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(100, 100)
+	d.DistanceAnnotation(10, 10, 50, 10, &Measure{Unit: "m", Factor: 1})
+	err := d.Close()
+	for _, r := range []string{"documents should have a title", "documents should be tagged",
+		"pages with annotations should use the structure tab order"} {
+		if !strings.Contains(fmt.Sprint(err), "PDF/UA-1: "+r) {
+			t.Errorf("got error %v expected %q", err, r)
+		}
+	}
+
+	b := buf.String()
+	for _, s := range []string{"/Lang (en-US)", "/DisplayDocTitle true",
+		"<pdfuaid:part>1</pdfuaid:part>"} {
+		if !strings.Contains(b, s) {
+			t.Errorf("PDF/UA document doesn't have %q", s)
+		}
+	}
+}
+
+type pdfuaTest struct {
+	m    map[string]interface{}
+	rule string // empty if m conforms
+}
+
+func TestPDFUAViolations(t *testing.T) {
+	tests := []pdfuaTest{
+		{map[string]interface{}{"Type": name("Font"), "Subtype": name("Type1")},
+			"fonts should be embedded"},
+		{map[string]interface{}{"Subtype": name("Square"), "Rect": newRect(0, 0, 1, 1)},
+			"annotations should have alternative descriptions"},
+		{map[string]interface{}{"Subtype": name("Square"), "Rect": newRect(0, 0, 1, 1),
+			"Contents": "box"}, ""},
+		{map[string]interface{}{"Subtype": name("Widget"), "Rect": newRect(0, 0, 1, 1)}, ""},
+		{map[string]interface{}{"Type": name("StructElem"), "S": name("Figure")},
+			"figures should have alternative descriptions"},
+		{map[string]interface{}{"Type": name("StructElem"), "S": name("Figure"),
+			"Alt": "A cat"}, ""},
+		{map[string]interface{}{"Type": name("Page"), "Annots": []*indirect{},
+			"Tabs": name("S")}, ""},
+	}
+
+	for _, test := range tests {
+		d := new(Document)
+		walk(test.m, d.checkPDFUA)
+		if test.rule == "" && len(d.violations) != 0 {
+			t.Errorf("%v: got violations %v", test.m, d.violations)
+		}
+		if test.rule != "" && !d.violations["PDF/UA-1: "+test.rule] {
+			t.Errorf("%v: got violations %v expected %q", test.m, d.violations, test.rule)
+		}
+	}
+}
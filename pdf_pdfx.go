@@ -0,0 +1,143 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file makes documents conform to PDF/X (ISO 15930), the standard for
+// exchanging documents with printers. PDF/X-1a:2003 (ISO 15930-4) has only
+// CMYK and spot colors, and PDF/X-4 (ISO 15930-7) allows RGB colors too, which
+// are converted by the printer.
+
+import (
+	"encoding/hex"
+)
+
+// Versions of PDF/X.
+const (
+	PDFX1a = "PDF/X-1a:2003"
+	PDFX4  = "PDF/X-4"
+)
+
+// SetPDFX makes the document conform to PDF/X version v, which is PDFX1a or
+// PDFX4. condition is the name of the printing condition the document is made
+// for, like "FOGRA39", and profile is its ICC profile. The profile can be nil
+// for PDF/X-1a if the condition is in the registry of ICC
+// (http://www.color.org).
+//
+// The document is checked against the rules of PDF/X, and Close returns an
+// error listing the broken rules, if any. Documents should have a title, and
+// every page a trim box; see SetInfo and SetTrimBox. Annotations, including
+// form fields, should be outside the bleed box, or the trim box if the page
+// has no bleed box. Fonts should be embedded, and colors should be in the
+// color space of the profile.
+//
+// SetPDFX should be called right after New, before anything is added to the
+// document.
+func (d *Document) SetPDFX(v, condition string, profile []byte) (err error) {
+	defer dontPanic(&err)
+
+	if d.sec != nil {
+		panic("PDF/X documents can't be encrypted")
+	}
+	if d.started() {
+		panic("SetPDFX must be called before anything is added to the document")
+	}
+	if d.pdfx != "" {
+		panic("PDF/X version is already set")
+	}
+	if condition == "" {
+		panic("PDF/X needs a printing condition")
+	}
+	if d.intent(IntentPDFX) != nil {
+		panic("PDF/X output intent is already added")
+	}
+	switch v {
+	case PDFX1a:
+		if profile != nil && iccSpace(profile) != "CMYK" {
+			panic("PDF/X-1a needs a CMYK profile")
+		}
+		d.version = "1.4"
+	case PDFX4:
+		if profile == nil {
+			panic("PDF/X-4 needs an ICC profile")
+		}
+		iccSpace(profile) // check it
+		d.version = "1.6"
+		d.xmpDescs = append(d.xmpDescs,
+			"<rdf:Description rdf:about=\"\" xmlns:pdfxid=\"http://www.npes.org/pdfx/ns/id/\">\n"+
+				"<pdfxid:GTS_PDFXVersion>PDF/X-4</pdfxid:GTS_PDFXVersion>\n"+
+				"</rdf:Description>\n"+
+				"<rdf:Description rdf:about=\"\" xmlns:xmpMM=\"http://ns.adobe.com/xap/1.0/mm/\">\n"+
+				"<xmpMM:DocumentID>uuid:"+hex.EncodeToString(d.fileID())+"</xmpMM:DocumentID>\n"+
+				"<xmpMM:VersionID>1</xmpMM:VersionID>\n"+
+				"<xmpMM:RenditionClass>default</xmpMM:RenditionClass>\n"+
+				"</rdf:Description>\n"+
+				"<rdf:Description rdf:about=\"\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n"+
+				"<pdf:Trapped>False</pdf:Trapped>\n"+
+				"</rdf:Description>\n")
+	default:
+		panic("unknown version of PDF/X: " + v)
+	}
+	d.addIntent(IntentPDFX, condition, profile)
+	d.pdfx = v
+	d.fileID() // needed by PDF/X
+	return nil
+}
+
+// checkPDFX checks the dictionary m against the rules of PDF/X.
+func (d *Document) checkPDFX(m map[string]interface{}) {
+	// PDF/X doesn't allow the same actions as PDF/A.
+	if s, ok := m["S"].(name); ok && pdfaActions[string(s)] {
+		d.violate(d.pdfx, string(s)+" actions are not allowed")
+	}
+	if _, ok := m["AA"]; ok {
+		d.violate(d.pdfx, "additional actions are not allowed")
+	}
+	// Type 0 fonts have their descriptors in their CIDFonts, which are
+	// checked on their own.
+	if m["Type"] == name("Font") && m["FontDescriptor"] == nil &&
+		m["Subtype"] != name("Type3") && m["Subtype"] != name("Type0") {
+		d.violate(d.pdfx, "fonts should be embedded")
+	}
+	if _, ok := m["TR"]; ok && m["Type"] == name("ExtGState") {
+		d.violate(d.pdfx, "transfer functions are not allowed")
+	}
+	d.checkColors(d.pdfx, m)
+}
+
+// checkPDFXPage checks the boxes and annotations of page p against the rules
+// of PDF/X.
+func (d *Document) checkPDFXPage(p *page) {
+	if p.trim == nil {
+		d.violate(d.pdfx, "pages should have a trim box")
+		return
+	}
+	printed := p.trim
+	if p.bleed != nil {
+		if !p.trim.inside(p.bleed) {
+			d.violate(d.pdfx, "trim boxes should be inside bleed boxes")
+		}
+		printed = p.bleed
+	}
+	if !printed.inside(p.box) {
+		d.violate(d.pdfx, "trim and bleed boxes should be inside pages")
+	}
+	for _, r := range p.arects {
+		if r.overlaps(printed) {
+			d.violate(d.pdfx, "annotations should be outside the printed area")
+		}
+	}
+}
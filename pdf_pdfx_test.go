@@ -0,0 +1,169 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+)
+
+// testProfile returns a fake ICC profile of the given color space.
+func testProfile(space string) []byte {
+	p := make([]byte, 128)
+	copy(p[16:], space)
+	copy(p[36:], "acsp")
+	return p
+}
+
+func TestSetPDFX(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.SetPDFX(PDFX4, "FOGRA39", testProfile("CMYK")); err != nil {
+		t.Fatal(err)
+	}
+	d.SetInfo(&Info{Title: "Poster"})
+	d.NewPage(100, 100)
+	d.SetTrimBox(10, 10, 80, 80)
+	d.SetBleedBox(5, 5, 90, 90)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.String()
+	for _, s := range []string{
+		"%PDF-1.6\n", "/TrimBox [ 10 10 90 90 ]", "/BleedBox [ 5 5 95 95 ]",
+		"/S /GTS_PDFX", "/OutputConditionIdentifier (FOGRA39)", "/N 4\n",
+		"/GTS_PDFXVersion (PDF/X-4)", "/Trapped /False",
+		"<pdfxid:GTS_PDFXVersion>PDF/X-4</pdfxid:GTS_PDFXVersion>",
+	} {
+		if !strings.Contains(b, s) {
+			t.Errorf("PDF/X document doesn't have %q", s)
+		}
+	}
+	if strings.Contains(b, "GTS_PDFA1") {
+		t.Error("PDF/X document has a PDF/A output intent")
+	}
+}
+
+func TestSetPDFXErrors(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	if err := d.SetPDFX(PDFX4, "FOGRA39", nil); err == nil {
+		t.Error("PDF/X-4 with no profile")
+	}
+	if err := d.SetPDFX(PDFX1a, "FOGRA39", testProfile("RGB ")); err == nil {
+		t.Error("PDF/X-1a with an RGB profile")
+	}
+	if err := d.SetPDFX("PDF/X-3", "FOGRA39", nil); err == nil {
+		t.Error("unknown version of PDF/X")
+	}
+	if err := d.SetPDFX(PDFX1a, "", nil); err == nil {
+		t.Error("PDF/X with no printing condition")
+	}
+	if err := d.SetPDFX(PDFX1a, "CGATS TR 001", nil); err != nil {
+		t.Error(err)
+	}
+	if err := d.Encrypt("", "", 40, PermAll); err == nil {
+		t.Error("PDF/X document encrypted")
+	}
+}
+
+type pdfxTest struct {
+	name string
+	v    string
+	add  func(d *Document)
+	rule string // empty if the document conforms
+}
+
+func TestPDFXViolations(t *testing.T) {
+	tests := []pdfxTest{
+		{"trimmed page", PDFX1a, func(d *Document) {
+			d.SetTrimBox(10, 10, 80, 80)
+		}, ""},
+		{"no trim box", PDFX1a, func(d *Document) {}, "pages should have a trim box"},
+		{"trim box outside page", PDFX1a, func(d *Document) {
+			d.SetTrimBox(-10, 10, 80, 80)
+		}, "should be inside pages"},
+		{"trim box outside bleed box", PDFX4, func(d *Document) {
+			d.SetTrimBox(10, 10, 80, 80)
+			d.SetBleedBox(20, 20, 50, 50)
+		}, "trim boxes should be inside bleed boxes"},
+		{"field on the page", PDFX1a, func(d *Document) {
+			d.SetTrimBox(10, 10, 80, 80)
+			d.PushButton(20, 20, 10, 10, &Button{Name: "b"})
+		}, "annotations should be outside the printed area"},
+		{"RGB", PDFX1a, func(d *Document) {
+			d.SetTrimBox(10, 10, 80, 80)
+			d.PushButton(0, 0, 5, 5, &Button{Name: "b",
+				FieldStyle: FieldStyle{Border: RGB{1, 0, 0}}})
+		}, "RGB colors need an RGB output intent"},
+		{"RGB style", PDFX1a, func(d *Document) {
+			d.SetTrimBox(10, 10, 80, 80)
+			d.DefineStyle("red", Style{Fill: RGB{1, 0, 0}})
+			d.UseStyle("red")
+			d.Rectangle(20, 20, 10, 10)
+			d.Fill()
+		}, "RGB colors need an RGB output intent"},
+		{"CMYK style", PDFX1a, func(d *Document) {
+			d.SetTrimBox(10, 10, 80, 80)
+			d.DefineStyle("cyan", Style{Fill: CMYK{1, 0, 0, 0}})
+			d.UseStyle("cyan")
+			d.Rectangle(20, 20, 10, 10)
+			d.Fill()
+		}, ""},
+		{"RGB text", PDFX1a, func(d *Document) {
+			d.SetTrimBox(10, 10, 80, 80)
+			g := &Grid{}
+			g.Add(&GridItem{Content: &Paragraph{Text: "red", Style: TextStyle{Color: RGB{1, 0, 0}}}})
+			d.DrawGrid(g, 20, 20, 50, 50)
+		}, "RGB colors need an RGB output intent"},
+		{"RGB image", PDFX1a, func(d *Document) {
+			d.SetTrimBox(10, 10, 80, 80)
+			x, _ := d.AddImage(image.NewRGBA(image.Rect(0, 0, 2, 2)))
+			d.DrawXObject(x, 20, 20, 10, 10)
+		}, "RGB colors need an RGB output intent"},
+		{"scripts", PDFX4, func(d *Document) {
+			d.SetTrimBox(10, 10, 80, 80)
+			d.PushButton(0, 0, 5, 5, &Button{Name: "b", Action: JavaScript("1")})
+		}, "JavaScript actions are not allowed"},
+	}
+
+	for _, test := range tests {
+		d, _ := New(bytes.NewBuffer(nil))
+		d.SetPDFX(test.v, "FOGRA39", testProfile("CMYK"))
+		d.SetInfo(&Info{Title: "Test"})
+		d.NewPage(100, 100)
+		test.add(d)
+		err := d.Close()
+		if test.rule == "" && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.rule != "" && !strings.Contains(fmt.Sprint(err), test.rule) {
+			t.Errorf("%s: got error %v expected %q", test.name, err, test.rule)
+		}
+	}
+
+	d, _ := New(bytes.NewBuffer(nil))
+	d.SetPDFX(PDFX1a, "CGATS TR 001", nil)
+	d.NewPage(100, 100)
+	d.SetTrimBox(0, 0, 100, 100)
+	if err := d.Close(); !strings.Contains(fmt.Sprint(err), "documents should have a title") {
+		t.Errorf("document with no title: got error %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains page-piece dictionaries (p. 876), which hold the
+// private data of the applications that made a document or its pages, like
+// the template a page is made from, so that they can read it back later.
+
+import (
+	"fmt"
+)
+
+// pieceData returns the data dictionary of private data v of an application
+// in a page-piece dictionary, which was last modified at date modified.
+func pieceData(app string, v interface{}, modified string) map[string]interface{} {
+	if app == "" {
+		panic("private data with no application")
+	}
+	return map[string]interface{}{
+		"LastModified": modified,
+		"Private":      v,
+	}
+}
+
+// SetPieceInfo sets the private data of application app in the document to
+// v, replacing what was set before for app. v can be made of the same values
+// as the ones of AddObject, except for streams, which should be added by
+// AddObject and referred to by their Refs. app should be unique to the
+// application, like the name of its company and its own.
+func (d *Document) SetPieceInfo(app string, v interface{}) (err error) {
+	defer dontPanic(&err)
+
+	data := pieceData(app, v, date(d.now()))
+	if d.pieces == nil {
+		d.pieces = make(map[string]interface{})
+	}
+	d.pieces[app] = data
+	return nil
+}
+
+// SetPieceInfo sets the private data of application app in p to v, like the
+// SetPieceInfo method of the document.
+func (p *Page) SetPieceInfo(app string, v interface{}) (err error) {
+	defer dontPanic(&err)
+
+	if p.pg.flushed {
+		panic("page is already flushed")
+	}
+	p.pg.modified = date(p.d.now())
+	if p.pg.pieces == nil {
+		p.pg.pieces = make(map[string]interface{})
+	}
+	p.pg.pieces[app] = pieceData(app, v, p.pg.modified)
+	return nil
+}
+
+// PieceInfo returns the private data of application app in page n of the
+// file, counted from 1, or in the document if n is 0. It returns nil if
+// there is none. Names are returned as Names, streams as *Streams with their
+// data decoded, and references are replaced by the objects they refer to.
+func (r *Reader) PieceInfo(n int, app string) (v interface{}, err error) {
+	defer dontPanic(&err)
+
+	var dic map[string]interface{}
+	if n == 0 {
+		dic, _ = r.resolve(r.trailer["Root"]).(map[string]interface{})
+	} else {
+		pgs := r.pages()
+		if n < 1 || n > len(pgs) {
+			panic(fmt.Sprint("page ", n, " out of range"))
+		}
+		dic = pgs[n-1].dic
+	}
+	pi, _ := r.resolve(dic["PieceInfo"]).(map[string]interface{})
+	data, _ := r.resolve(pi[app]).(map[string]interface{})
+	return r.public(data["Private"], make(map[int]bool)), nil
+}
+
+// public returns v, an object of r, made of the types that can be passed to
+// AddObject. seen holds the numbers of the objects being converted, whose
+// references become nil, so that loops end.
+func (r *Reader) public(v interface{}, seen map[int]bool) interface{} {
+	switch t := v.(type) {
+	case ref:
+		if seen[t.num] {
+			return nil
+		}
+		seen[t.num] = true
+		defer delete(seen, t.num)
+		return r.public(r.object(t.num), seen)
+	case name:
+		return Name(t)
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = r.public(e, seen)
+		}
+		return a
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = r.public(e, seen)
+		}
+		return m
+	case *stream:
+		s := &Stream{Dict: r.public(t.dic, seen).(map[string]interface{}), Data: r.decode(t)}
+		delete(s.Dict, "Length")
+		delete(s.Dict, "Filter")
+		delete(s.Dict, "DecodeParms")
+		return s
+	}
+	return v
+}
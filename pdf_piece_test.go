@@ -0,0 +1,77 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPieceInfo(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf, Options{Deterministic: true})
+	p, _ := d.NewPage(100, 100)
+	d.NewPage(100, 100)
+	layout, _ := d.AddObject(&Stream{Dict: map[string]interface{}{"Type": Name("Layout")}, Data: []byte("grid 12")})
+	doc := map[string]interface{}{"Template": "invoice-v2", "Version": 3}
+	if err := d.SetPieceInfo("ExampleCorp:Billing", doc); err != nil {
+		t.Fatal(err)
+	}
+	private := []interface{}{Name("Region"), 1.5, layout}
+	if err := p.SetPieceInfo("ExampleCorp:Billing", private); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetPieceInfo("", 1); !errors.Is(err, ErrInvalid) {
+		t.Errorf("no application: got %v expected ErrInvalid", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+
+	r, _ := NewReader(buf.Bytes())
+	pg := r.pages()[0].dic
+	if pg["LastModified"] != date(d.now()) {
+		t.Errorf("page modified: got %v", pg["LastModified"])
+	}
+	for _, tt := range []struct {
+		page int
+		app  string
+		want interface{}
+	}{
+		{0, "ExampleCorp:Billing", doc},
+		{1, "ExampleCorp:Billing", []interface{}{Name("Region"), 1.5,
+			&Stream{Dict: map[string]interface{}{"Type": Name("Layout")}, Data: []byte("grid 12")}}},
+		{1, "Other", nil},
+		{2, "ExampleCorp:Billing", nil},
+	} {
+		v, err := r.PieceInfo(tt.page, tt.app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("page %d, %s: got %#v expected %#v", tt.page, tt.app, v, tt.want)
+		}
+	}
+	if _, err := r.PieceInfo(3, "ExampleCorp:Billing"); err == nil {
+		t.Errorf("no error for a page out of range")
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file is for documents shown as slides: pages that advance by
+// themselves after their durations, full screen mode, and the
+// speaker notes of the slides, which are written to a document of their own.
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Presentation sets how a document of slides is shown.
+type Presentation struct {
+	FullScreen bool // opens in full screen mode
+
+	// Duration is the number of seconds pages are shown before viewers
+	// advance to the next page, for pages with no durations of their
+	// own. Pages advance only by the user if it's zero.
+	Duration float64
+
+	// Notes, if set, is the output of a document with the speaker notes
+	// of the pages, one after another, made when the document is closed.
+	Notes io.Writer
+}
+
+// SetPresentation makes d a presentation shown as p, and marks it as one in
+// its metadata.
+func (d *Document) SetPresentation(p *Presentation) (err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	if p == nil {
+		panic("SetPresentation called with nil")
+	}
+	if p.Duration < 0 {
+		panic("negative duration of pages")
+	}
+	if d.present == nil {
+		d.xmpDescs = append(d.xmpDescs,
+			"<rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n"+
+				"<dc:type><rdf:Bag><rdf:li>Presentation</rdf:li></rdf:Bag></dc:type>\n"+
+				"</rdf:Description>\n")
+	}
+	c := *p
+	d.present = &c
+	return nil
+}
+
+// SetDuration sets the number of seconds the current page is shown before
+// viewers advance to the next page, overriding the duration of the
+// presentation.
+func (d *Document) SetDuration(sec float64) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "SetDuration called before any page was started")
+	}
+	d.pg.setDuration(sec)
+	return nil
+}
+
+// SetDuration sets the duration of p, like the SetDuration method of the
+// document.
+func (p *Page) SetDuration(sec float64) (err error) {
+	defer dontPanic(&err)
+
+	if p.pg.flushed {
+		panic("page is already flushed")
+	}
+	p.pg.setDuration(sec)
+	return nil
+}
+
+func (p *page) setDuration(sec float64) {
+	if sec <= 0 {
+		panic(fmt.Sprint("bad duration of page: ", sec))
+	}
+	p.dur = sec
+}
+
+// SetSpeakerNotes sets the speaker notes of the current page, which go to
+// the document of notes of the presentation.
+func (d *Document) SetSpeakerNotes(notes string) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "SetSpeakerNotes called before any page was started")
+	}
+	d.setNotes(d.pg, notes)
+	return nil
+}
+
+// SetSpeakerNotes sets the speaker notes of p, like the SetSpeakerNotes
+// method of the document.
+func (p *Page) SetSpeakerNotes(notes string) (err error) {
+	defer dontPanic(&err)
+
+	p.d.checkClosed()
+	p.d.setNotes(p.pg, notes)
+	return nil
+}
+
+func (d *Document) setNotes(pg *page, notes string) {
+	if d.notes == nil {
+		d.notes = make(map[*page]string)
+	}
+	if notes == "" {
+		delete(d.notes, pg)
+		return
+	}
+	d.notes[pg] = notes
+}
+
+// writeNotes writes the document of the speaker notes of d to the output of
+// the notes of its presentation. Each page with notes gets a heading with
+// its number, followed by the notes.
+func (d *Document) writeNotes() {
+	var pgs []*page
+	for pg := range d.notes {
+		pgs = append(pgs, pg)
+	}
+	sort.Slice(pgs, func(i, j int) bool { return pgs[i].index < pgs[j].index })
+
+	nd, err := New(d.present.Notes, Options{
+		Deterministic: d.opts.Deterministic,
+		Producer:      d.opts.Producer,
+		Compress:      d.opts.Compress,
+	})
+	check(err)
+	if d.info != nil {
+		info := *d.info
+		if info.Title != "" {
+			info.Title = "Notes: " + info.Title
+		}
+		check(nd.SetInfo(&info))
+	}
+	f, err := nd.NewFlow(a4Width, a4Height, Margins{72, 72, 72, 72})
+	check(err)
+	for _, pg := range pgs {
+		check(f.Add(&Paragraph{
+			Text:         fmt.Sprint("Slide ", pg.index+1),
+			Heading:      1,
+			Style:        TextStyle{FontSize: 16},
+			SpaceBefore:  12,
+			SpaceAfter:   6,
+			KeepWithNext: true,
+		}))
+		check(f.Add(&Paragraph{Text: d.notes[pg]}))
+	}
+	if len(pgs) == 0 {
+		check(f.Add(&Paragraph{Text: "No speaker notes."}))
+	}
+	checkWrite(nd.Close())
+}
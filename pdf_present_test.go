@@ -0,0 +1,83 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPresentation(t *testing.T) {
+	buf, notes := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	d, _ := New(buf, Options{Deterministic: true})
+	if err := d.SetPresentation(&Presentation{FullScreen: true, Duration: 5, Notes: notes}); err != nil {
+		t.Fatal(err)
+	}
+	d.SetInfo(&Info{Title: "Results"})
+	p1, _ := d.NewPage(400, 300)
+	d.SetSpeakerNotes("Thank everyone.")
+	d.NewPage(400, 300)
+	if err := d.SetDuration(20); err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(400, 300)
+	if err := p1.SetSpeakerNotes("Welcome the audience."); err != nil {
+		t.Fatal(err)
+	}
+	d.SetSpeakerNotes("Questions")
+	if err := d.SetDuration(-1); err == nil {
+		t.Error("no error for negative duration")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	if cat["PageMode"] != name("FullScreen") {
+		t.Errorf("page mode: got %v", cat["PageMode"])
+	}
+	for i, want := range []string{"5", "20", "5"} {
+		if dur := fmt.Sprint(r.pages()[i].dic["Dur"]); dur != want {
+			t.Errorf("duration of page %d: got %v, want %v", i+1, dur, want)
+		}
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("<dc:type><rdf:Bag><rdf:li>Presentation</rdf:li></rdf:Bag></dc:type>")) {
+		t.Error("no type in the metadata")
+	}
+
+	nr, err := NewReader(notes.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, _ := nr.PageText(1)
+	var text []string
+	for _, run := range runs {
+		text = append(text, run.Text)
+	}
+	if got := strings.Join(text, "|"); got != "Slide 1|Welcome the audience.|Slide 3|Questions" {
+		t.Errorf("text of notes: got %q", got)
+	}
+	if info, _ := nr.Info(); info["Title"] != "Notes: Results" {
+		t.Errorf("info of notes: got %v", info)
+	}
+}
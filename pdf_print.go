@@ -0,0 +1,119 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file sets the entries of the viewer preferences that fill in the print
+// dialog of viewers.
+
+import (
+	"fmt"
+)
+
+// Duplex modes of printing
+const (
+	Simplex             = iota + 1 // printed on one side
+	DuplexFlipShortEdge            // printed on both sides, flipped on the short edge
+	DuplexFlipLongEdge             // printed on both sides, flipped on the long edge
+)
+
+// PrintPreset holds how the print dialog of viewers is filled in when the
+// document is printed. Users can still change them. Its zero value leaves
+// them all to viewers.
+type PrintPreset struct {
+	// NoScaling prints pages at their size, instead of scaling them to fit
+	// the paper, which labels and forms need.
+	NoScaling bool
+
+	// Duplex is Simplex, DuplexFlipShortEdge or DuplexFlipLongEdge, or
+	// zero to leave it to the printer.
+	Duplex int
+
+	// PickTrayByPDFSize picks the paper tray by the size of pages.
+	PickTrayByPDFSize bool
+
+	// PageRanges are the pages printed, each from the first page to the
+	// last one of the range. Pages are numbered from 1. All pages are
+	// printed if it's empty.
+	PageRanges [][2]int
+
+	// NumCopies is the number of copies printed, or zero to leave it to
+	// viewers, which usually print one.
+	NumCopies int
+}
+
+// SetPrintPreset sets how the print dialog of viewers is filled in when d is
+// printed. SetPrintPreset(&PrintPreset{NoScaling: true, Duplex:
+// DuplexFlipLongEdge}) prints pages double-sided at their size.
+func (d *Document) SetPrintPreset(p *PrintPreset) (err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	if p == nil {
+		panic("SetPrintPreset called with nil")
+	}
+	if p.Duplex < 0 || p.Duplex > DuplexFlipLongEdge {
+		panic(fmt.Sprint("bad duplex mode: ", p.Duplex))
+	}
+	if p.NumCopies < 0 {
+		panic(fmt.Sprint("bad number of copies: ", p.NumCopies))
+	}
+	for _, r := range p.PageRanges {
+		if r[0] < 1 || r[1] < r[0] {
+			panic(fmt.Sprintf("bad range of pages: %d to %d", r[0], r[1]))
+		}
+	}
+	c := *p
+	c.PageRanges = append([][2]int(nil), p.PageRanges...)
+	d.print = &c
+	return nil
+}
+
+// printPreferences returns the entries of the viewer preferences of d for
+// printing, which is empty if there's no print preset.
+func (d *Document) printPreferences() map[string]interface{} {
+	vp := make(map[string]interface{})
+	p := d.print
+	if p == nil {
+		return vp
+	}
+	if p.NoScaling {
+		vp["PrintScaling"] = name("None")
+	}
+	if p.Duplex != 0 {
+		vp["Duplex"] = name([]string{Simplex: "Simplex",
+			DuplexFlipShortEdge: "DuplexFlipShortEdge",
+			DuplexFlipLongEdge:  "DuplexFlipLongEdge"}[p.Duplex])
+	}
+	if p.PickTrayByPDFSize {
+		vp["PickTrayByPDFSize"] = true
+	}
+	if len(p.PageRanges) > 0 {
+		a := make([]int, 0, 2*len(p.PageRanges))
+		for _, r := range p.PageRanges {
+			if r[1] > len(d.pgs) {
+				panic(fmt.Sprintf("range of pages to print ends at page %d of %d",
+					r[1], len(d.pgs)))
+			}
+			a = append(a, r[0], r[1])
+		}
+		vp["PrintPageRange"] = a
+	}
+	if p.NumCopies > 0 {
+		vp["NumCopies"] = p.NumCopies
+	}
+	return vp
+}
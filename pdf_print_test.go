@@ -0,0 +1,92 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type printPresetTest struct {
+	p   *PrintPreset
+	vp  string // viewer preferences, or the error
+	err bool
+}
+
+func TestPrintPreset(t *testing.T) {
+	tests := []printPresetTest{
+		{&PrintPreset{NoScaling: true, Duplex: DuplexFlipLongEdge},
+			"map[Duplex:DuplexFlipLongEdge PrintScaling:None]", false},
+		{&PrintPreset{Duplex: Simplex, PickTrayByPDFSize: true, NumCopies: 3},
+			"map[Duplex:Simplex NumCopies:3 PickTrayByPDFSize:true]", false},
+		{&PrintPreset{PageRanges: [][2]int{{1, 1}, {2, 3}}, Duplex: DuplexFlipShortEdge},
+			"map[Duplex:DuplexFlipShortEdge PrintPageRange:[1 1 2 3]]", false},
+		{&PrintPreset{}, "<nil>", false},
+		{&PrintPreset{PageRanges: [][2]int{{2, 4}}}, "pdf.go: range of pages to print ends at page 4 of 3", false},
+		{&PrintPreset{Duplex: 4}, "pdf.go: bad duplex mode: 4", true},
+		{&PrintPreset{NumCopies: -1}, "pdf.go: bad number of copies: -1", true},
+		{&PrintPreset{PageRanges: [][2]int{{0, 2}}}, "pdf.go: bad range of pages: 0 to 2", true},
+		{&PrintPreset{PageRanges: [][2]int{{3, 2}}}, "pdf.go: bad range of pages: 3 to 2", true},
+		{nil, "pdf.go: SetPrintPreset called with nil", true},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		d, _ := New(buf)
+		for j := 0; j < 3; j++ {
+			d.NewPage(100, 100)
+		}
+		err := d.SetPrintPreset(test.p)
+		if test.err {
+			if !errors.Is(err, ErrInvalid) || err.Error() != test.vp {
+				t.Errorf("%d: got %v expected %s", i, err, test.vp)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if err := d.Close(); err != nil {
+			if err.Error() != test.vp {
+				t.Errorf("%d: closing: %v", i, err)
+			}
+			continue
+		}
+		r, err := NewReader(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cat := r.resolve(r.trailer["Root"]).(map[string]interface{})
+		if vp := fmt.Sprint(r.resolve(cat["ViewerPreferences"])); vp != test.vp {
+			t.Errorf("%d: viewer preferences: got %s expected %s", i, vp, test.vp)
+		}
+	}
+
+	// PDF/UA documents display their titles too. They're not tagged here,
+	// which is reported when they're closed.
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.SetPDFUA("en")
+	d.NewPage(100, 100)
+	d.SetPrintPreset(&PrintPreset{NoScaling: true})
+	d.Close()
+	if !bytes.Contains(buf.Bytes(), []byte("<<\n/DisplayDocTitle true\n/PrintScaling /None\n>>")) {
+		t.Error("viewer preferences of PDF/UA document don't have both entries")
+	}
+}
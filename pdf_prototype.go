@@ -0,0 +1,105 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains prototypes of documents, which hold what many documents
+// have in common, like the size of their pages and their headers.
+
+import (
+	"io"
+)
+
+// Prototype holds what documents made from it have in common, so that it's
+// set up once and documents are made from it cheaply, like a letter with the
+// letterhead of a company for each customer.
+type Prototype struct {
+	Width, Height int     // size of pages made by NewPage and NewFlow
+	Margins       Margins // margins of flows made by NewFlow
+	Info          *Info   // metadata of the documents, if set
+	Options       Options // options of the documents made by New
+
+	Styles map[string]Style // named styles of the documents
+
+	Header PageHook                // called after each page is started, if set
+	Footer PageHook                // called before each page is written, if set
+	Setup  func(d *Document) error // called for each document, if set
+}
+
+// New returns a new document written to w with the options of p, set up
+// according to p.
+func (p *Prototype) New(w io.Writer) (d *Document, err error) {
+	defer dontPanic(&err)
+
+	d, err = New(w, p.Options)
+	check(err)
+	check(p.Apply(d))
+	return d, nil
+}
+
+// Apply sets up d according to p, like for documents made by NewSeekable or
+// NewBuffered. It should be called before any page of d is started.
+func (p *Prototype) Apply(d *Document) (err error) {
+	defer dontPanic(&err)
+
+	if len(d.pgs) > 0 {
+		panic("prototype applied after pages were started")
+	}
+	if p.Info != nil {
+		check(d.SetInfo(p.Info))
+	}
+	for n, s := range p.Styles {
+		check(d.DefineStyle(n, s))
+	}
+	if p.Header != nil {
+		check(d.OnPageStart(p.Header))
+	}
+	if p.Footer != nil {
+		check(d.OnPageEnd(p.Footer))
+	}
+	if p.Setup != nil {
+		check(p.Setup(d))
+	}
+	return nil
+}
+
+// Clone returns a copy of p, which can be changed without changing p, like
+// to make a prototype with another header.
+func (p *Prototype) Clone() *Prototype {
+	c := *p
+	if p.Info != nil {
+		i := *p.Info
+		c.Info = &i
+	}
+	return &c
+}
+
+// NewPage starts a new page of d with the size of the pages of p.
+func (p *Prototype) NewPage(d *Document) (*Page, error) {
+	return d.NewPage(p.Width, p.Height)
+}
+
+// NewFlow returns a new flow of d with the size of the pages and the margins
+// of p.
+func (p *Prototype) NewFlow(d *Document) (*Flow, error) {
+	return d.NewFlow(p.Width, p.Height, p.Margins)
+}
+
+// NewTemplate returns an empty template for flows with the size of the pages
+// and the margins of p.
+func (p *Prototype) NewTemplate() *Template {
+	return NewTemplate(p.Width, p.Height, p.Margins)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrototype(t *testing.T) {
+	p := &Prototype{
+		Width:   200,
+		Height:  300,
+		Margins: Margins{20, 20, 20, 20},
+		Info:    &Info{Title: "Letter"},
+		Header: func(d *Document, p *Page) error {
+			return d.Rectangle(1, 1, 1, 1)
+		},
+	}
+	c := p.Clone()
+	c.Info.Title = "Invoice"
+	c.Footer = func(d *Document, p *Page) error {
+		return d.Rectangle(2, 2, 2, 2)
+	}
+
+	tests := []struct {
+		p     *Prototype
+		title string
+		con   string
+	}{
+		{p, "Letter", "1 1 1 1 re\n"},
+		{c, "Invoice", "1 1 1 1 re\n2 2 2 2 re\n"},
+	}
+	for _, tt := range tests {
+		buf := bytes.NewBuffer(nil)
+		d, err := tt.p.New(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := tt.p.NewPage(d); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		r, _ := NewReader(buf.Bytes())
+		if info, _ := r.Info(); info["Title"] != tt.title {
+			t.Errorf("title: got %q expected %q", info["Title"], tt.title)
+		}
+		for i, pg := range r.pages() {
+			if w := r.resolve(pg.dic["MediaBox"]).([]interface{})[2]; w != 200 {
+				t.Errorf("%s: width of page %d: got %v", tt.title, i+1, w)
+			}
+			if con := string(r.contents(pg.dic["Contents"])); strings.TrimSpace(con) != strings.TrimSpace(tt.con) {
+				t.Errorf("%s: content of page %d: got %q expected %q", tt.title, i+1, con, tt.con)
+			}
+		}
+	}
+
+	d, _ := New(bytes.NewBuffer(nil))
+	p.NewPage(d)
+	if err := p.Apply(d); err == nil {
+		t.Error("prototype applied after pages were started")
+	}
+}
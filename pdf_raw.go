@@ -0,0 +1,224 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file lets operators that have no methods be added to content streams,
+// checking their operands so that the content is still valid. Operators that
+// are not known, like the ones of newer versions of PDF, can be added in
+// compatibility sections, which viewers that don't know them skip.
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// operands holds the operands of the operators that can be added with Raw
+// (p. 985). Each operand is one of:
+//
+//	n  a number
+//	i  an integer
+//	N  a Name
+//	s  a string
+//	a  an array
+//	p  a Name or a dictionary, for property lists
+//	*  one or more numbers, optionally followed by a Name, for colors
+//
+// Inline images (BI, ID and EI) can't be added.
+var operands = map[string]string{
+	"w": "n", "J": "i", "j": "i", "M": "n", "d": "an", "ri": "N", "i": "n", "gs": "N",
+	"q": "", "Q": "", "cm": "nnnnnn",
+	"m": "nn", "l": "nn", "c": "nnnnnn", "v": "nnnn", "y": "nnnn", "h": "", "re": "nnnn",
+	"S": "", "s": "", "f": "", "F": "", "f*": "", "B": "", "B*": "", "b": "", "b*": "", "n": "",
+	"W": "", "W*": "",
+	"BT": "", "ET": "",
+	"Tc": "n", "Tw": "n", "Tz": "n", "TL": "n", "Tf": "Nn", "Tr": "i", "Ts": "n",
+	"Td": "nn", "TD": "nn", "Tm": "nnnnnn", "T*": "",
+	"Tj": "s", "TJ": "a", "'": "s", "\"": "nns",
+	"d0": "nn", "d1": "nnnnnn",
+	"CS": "N", "cs": "N", "SC": "*", "SCN": "*", "sc": "*", "scn": "*",
+	"G": "n", "g": "n", "RG": "nnn", "rg": "nnn", "K": "nnnn", "k": "nnnn",
+	"sh": "N", "Do": "N",
+	"MP": "N", "DP": "Np", "BMC": "N", "BDC": "Np", "EMC": "",
+	"BX": "", "EX": "",
+}
+
+// Raw adds operator op with operands args to the end of the content of p,
+// for what has no method, like "Tc" for the spacing of characters. Numbers
+// are ints or float64s, names are Names, and arrays and dictionaries are
+// slices, Arrays, maps with string keys and Dicts. The operands are checked
+// against op, and the resources they name, like the fonts of "Tf", should be
+// in the resources of p.
+//
+// Unknown operators can be added only in compatibility sections, between
+// "BX" and "EX", and their operands are not checked.
+func (p *Page) Raw(op string, args ...interface{}) (err error) {
+	defer dontPanic(&err)
+
+	p.check()
+	ops, ok := operands[op]
+	switch {
+	case !ok && (p.pg.compat == 0 || op == "BI" || op == "ID" || op == "EI"):
+		panic("unknown operator: " + op)
+	case !ok && !isOperator(op):
+		panic("bad operator: " + op)
+	case op == "EX" && p.pg.compat == 0:
+		panic("EX without BX")
+	}
+	if ok {
+		if err := checkOperands(ops, args); err != "" {
+			panic(fmt.Sprint("bad operands of ", op, ": ", err))
+		}
+	}
+	switch op {
+	case "BX":
+		p.pg.compat++
+	case "EX":
+		p.pg.compat--
+	}
+	if cat, ok := resourceTypes[op]; ok {
+		n, named := args[0].(Name)
+		if op == "BDC" || op == "DP" {
+			// Property lists can be inline.
+			n, named = args[1].(Name)
+		}
+		device := n == "DeviceGray" || n == "DeviceRGB" || n == "DeviceCMYK" || n == "Pattern"
+		if named && p.pg.res[cat][string(n)] == nil && !(cat == "ColorSpace" && device) {
+			panic(fmt.Sprint("resource /", n, " of ", op, " is not in the ", cat, " of the page"))
+		}
+	}
+	buf := bytes.NewBuffer(nil)
+	for _, a := range args {
+		buf.Write(output(a))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(op + "\n")
+	p.write(buf.Bytes())
+	nums := make([]float64, 0, len(args))
+	for _, a := range args {
+		switch n := a.(type) {
+		case int:
+			nums = append(nums, float64(n))
+		case float64:
+			nums = append(nums, n)
+		}
+	}
+	p.tracker().op(op, nums...)
+	return nil
+}
+
+// checkOperands returns what's wrong with args as operands of kinds ops, or
+// an empty string if they're right.
+func checkOperands(ops string, args []interface{}) string {
+	if ops == "*" {
+		if len(args) > 0 {
+			if _, ok := args[len(args)-1].(Name); ok {
+				args = args[:len(args)-1]
+			}
+		}
+		if len(args) == 0 {
+			return "no numbers"
+		}
+		ops = ""
+		for range args {
+			ops += "n"
+		}
+	}
+	if len(args) != len(ops) {
+		return fmt.Sprintf("%d operands instead of %d", len(args), len(ops))
+	}
+	for i, a := range args {
+		var ok bool
+		switch ops[i] {
+		case 'n':
+			switch a.(type) {
+			case int, float64:
+				ok = true
+			}
+		case 'i':
+			_, ok = a.(int)
+		case 'N':
+			_, ok = a.(Name)
+		case 's':
+			_, ok = a.(string)
+		case 'a':
+			_, b := a.([]byte)
+			ok = a != nil && reflect.TypeOf(a).Kind() == reflect.Slice && !b
+		case 'p':
+			switch a.(type) {
+			case Name, map[string]interface{}, Dict:
+				ok = true
+			}
+		}
+		if !ok {
+			return fmt.Sprintf("operand %d is %T", i+1, a)
+		}
+	}
+	return ""
+}
+
+// isOperator returns whether op can be an operator of content streams: a
+// word of letters, digits and the characters *, ' and ", which doesn't
+// start like a number.
+func isOperator(op string) bool {
+	if op == "" || (op[0] >= '0' && op[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(op); i++ {
+		c := op[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '*' || c == '\'' || c == '"') {
+			return false
+		}
+	}
+	return true
+}
+
+// BeginCompatibility begins a compatibility section on the current page,
+// which is ended by EndCompatibility. Viewers don't report operators they
+// don't know in it, so operators of newer versions of PDF, added with the Raw
+// method of the page, leave the rest of the page to look right in older
+// viewers. It should be ended on the same page.
+func (d *Document) BeginCompatibility() (err error) {
+	defer dontPanic(&err)
+
+	if d.xbox != nil {
+		panic("BeginCompatibility called inside an XObject")
+	}
+	if d.pg == nil {
+		fail(ErrNoPage, "BeginCompatibility called before any page was started")
+	}
+	d.addm("BX")
+	d.pg.compat++
+	return nil
+}
+
+// EndCompatibility ends the compatibility section begun last by
+// BeginCompatibility.
+func (d *Document) EndCompatibility() (err error) {
+	defer dontPanic(&err)
+
+	if d.xbox != nil {
+		panic("EndCompatibility called inside an XObject")
+	}
+	if d.pg == nil || d.pg.compat == 0 {
+		panic("EndCompatibility called without BeginCompatibility")
+	}
+	d.addm("EX")
+	d.pg.compat--
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type rawTest struct {
+	op   string
+	args []interface{}
+	err  string
+}
+
+func TestRaw(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	p, _ := d.NewPage(100, 100)
+	d.pageFont(FontHelvetica)
+	tests := []rawTest{
+		{"Tc", []interface{}{0.5}, ""},
+		{"d", []interface{}{[]int{3, 1}, 0}, ""},
+		{"BDC", []interface{}{Name("Span"), map[string]interface{}{"ActualText": "x"}}, ""},
+		{"Tf", []interface{}{Name("Helv"), 12}, ""},
+		{"DP", []interface{}{Name("Pt"), NewDict("N", 1)}, ""},
+		{"TJ", []interface{}{[]interface{}{"a", -20, "b"}}, ""},
+		{"scn", []interface{}{0.1, 0.2, 0.3}, ""},
+		{"cs", []interface{}{Name("DeviceRGB")}, ""},
+		{"BX", nil, ""},
+		{"BI", nil, "pdf.go: unknown operator: BI"},
+		{"sh2", []interface{}{Name("Sh1"), 0.5}, ""},
+		{"a b", nil, "pdf.go: bad operator: a b"},
+		{"EX", nil, ""},
+		{"EX", nil, "pdf.go: EX without BX"},
+		{"sh2", nil, "pdf.go: unknown operator: sh2"},
+		{"re", []interface{}{1, 2, 3}, "pdf.go: bad operands of re: 3 operands instead of 4"},
+		{"Tr", []interface{}{1.5}, "pdf.go: bad operands of Tr: operand 1 is float64"},
+		{"TJ", []interface{}{[]byte("ab")}, "pdf.go: bad operands of TJ: operand 1 is []uint8"},
+		{"sc", []interface{}{Name("P1")}, "pdf.go: bad operands of sc: no numbers"},
+		{"Do", []interface{}{Name("Im9")}, "pdf.go: resource /Im9 of Do is not in the XObject of the page"},
+	}
+	for _, tt := range tests {
+		if err := p.Raw(tt.op, tt.args...); fmt.Sprint(err) != tt.err && (err != nil || tt.err != "") {
+			t.Errorf("%s %v: got %v expected %q", tt.op, tt.args, err, tt.err)
+		}
+	}
+	d.Close()
+
+	r, _ := NewReader(buf.Bytes())
+	con := string(r.contents(r.pages()[0].dic["Contents"]))
+	want := "0.5 Tc\n[ 3 1 ] 0 d\n/Span <<\n/ActualText (x)\n>> BDC\n/Helv 12 Tf\n/Pt <<\n/N 1\n>> DP\n" +
+		"[ (a) -20 (b) ] TJ\n0.1 0.2 0.3 scn\n/DeviceRGB cs\nBX\n/Sh1 0.5 sh2\nEX\n\n"
+	if con != want {
+		t.Errorf("content: got %q expected %q", con, want)
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+}
+
+func TestCompatibility(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.BeginCompatibility(); !errors.Is(err, ErrNoPage) {
+		t.Errorf("section with no page: got %v expected ErrNoPage", err)
+	}
+	p, _ := d.NewPage(100, 100)
+	if err := d.EndCompatibility(); err == nil {
+		t.Errorf("section ended without being begun")
+	}
+	if err := d.BeginCompatibility(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Raw("Xx", 1, Name("N")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.EndCompatibility(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Raw("Xx"); err == nil {
+		t.Errorf("unknown operator added after the section")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewReader(buf.Bytes())
+	if con := string(r.contents(r.pages()[0].dic["Contents"])); con != "BX\n1 /N Xx\nEX\n\n" {
+		t.Errorf("content: got %q", con)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.NewPage(100, 100)
+	d.BeginCompatibility()
+	if err := d.Close(); err == nil || !strings.Contains(err.Error(), "compatibility section not ended") {
+		t.Errorf("got %v for a section not ended", err)
+	}
+}
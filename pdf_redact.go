@@ -0,0 +1,442 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file applies redactions to existing files. Text and images in the
+// redacted regions are removed from the content of the pages, not only
+// covered, so that they can't be copied or extracted from the copy, and the
+// regions are painted over.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Redaction is a region of a page of an existing file whose content is
+// removed by ApplyRedactions.
+type Redaction struct {
+	Page       int     // from 1
+	X, Y, W, H float64 // in the default coordinates of the page
+	Color      Color   // the region is painted with it; black if nil
+}
+
+// AddRedaction adds a redaction annotation to the current page, which marks
+// the rectangle of size w×h with the lower left corner at (x, y) to be
+// removed by ApplyRedactions from the written file. c is the color the
+// region is painted with then, which is also the color of its outline until
+// then; black if nil.
+func (d *Document) AddRedaction(x, y, w, h float64, c Color) (err error) {
+	defer dontPanic(&err)
+
+	if w <= 0 || h <= 0 {
+		panic("empty redaction")
+	}
+	if c == nil {
+		c = Gray(0)
+	}
+	ap := fmt.Sprint(colorOp(c, true), " 1 w 0.5 0.5 ", ftoa(w-1), " ", ftoa(h-1), " re S\n")
+	d.addAnnot(map[string]interface{}{
+		"Subtype": name("Redact"),
+		"Rect":    newRect(x, y, x+w, y+h),
+		"IC":      c.components(),
+		"C":       c.components(),
+		"F":       4, // Print flag of annotations
+		"AP":      map[string]interface{}{"N": d.appearance(w, h, ap, "", nil)},
+	})
+	return nil
+}
+
+// ApplyRedactions writes a copy of the file read by r to w, with the content
+// of the regions of its redaction annotations and of regs removed: text
+// shown and images drawn in them are removed, and they are painted over.
+// Characters and images that are partly in a region are removed whole, and
+// so are the annotations that overlap the regions, including the redaction
+// annotations. Outlines and named destinations are kept, like in Flatten.
+func ApplyRedactions(w io.Writer, r *Reader, regs ...Redaction) (err error) {
+	defer dontPanic(&err)
+
+	// The copy would be written with no encryption.
+	if r.Encrypted() {
+		panic("encrypted files can't be redacted")
+	}
+	pgs := r.pages()
+	marks := make([][]Redaction, len(pgs))
+	for _, g := range regs {
+		if g.Page < 1 || g.Page > len(pgs) {
+			panic(fmt.Sprintf("redaction on page %d of %d pages", g.Page, len(pgs)))
+		}
+		if g.W <= 0 || g.H <= 0 {
+			panic("empty redaction")
+		}
+		marks[g.Page-1] = append(marks[g.Page-1], g)
+	}
+	d, err := New(w)
+	check(err)
+	c := newCopier(d, r)
+	for i, p := range pgs {
+		c.addPage(d.redactPage(c, p, marks[i]))
+	}
+	d.copyDests(c)
+	d.mergeOutlines([]*copier{c})
+	c.flush()
+	check(d.Close())
+	return nil
+}
+
+// redactions returns the regions of redaction annotation a, which are its
+// quadrilaterals, or its rectangle if it has none.
+func (r *Reader) redactions(a map[string]interface{}) []Redaction {
+	c := r.color(a["IC"])
+	var regs []Redaction
+	qp, _ := r.resolve(a["QuadPoints"]).([]interface{})
+	for i := 0; i+8 <= len(qp); i += 8 {
+		var b bounds
+		for j := i; j < i+8; j += 2 {
+			x, ok1 := r.number(qp[j])
+			y, ok2 := r.number(qp[j+1])
+			if ok1 && ok2 {
+				b.add(x, y)
+			}
+		}
+		if x, y, w, h, ok := b.size(); ok && w > 0 && h > 0 {
+			regs = append(regs, Redaction{X: x, Y: y, W: w, H: h, Color: c})
+		}
+	}
+	if b := r.box(a["Rect"]); len(regs) == 0 && b != nil && b.urx > b.llx && b.ury > b.lly {
+		regs = append(regs, Redaction{X: b.llx, Y: b.lly, W: b.urx - b.llx, H: b.ury - b.lly, Color: c})
+	}
+	return regs
+}
+
+// redactPage returns page p of the file of c with the content of regs and of
+// its redaction annotations removed and painted over.
+func (d *Document) redactPage(c *copier, p rpage, regs []Redaction) rpage {
+	r := c.r
+	dic := make(map[string]interface{})
+	for k, v := range p.dic {
+		dic[k] = v
+	}
+	annots, _ := r.resolve(p.dic["Annots"]).([]interface{})
+	for _, a := range annots {
+		if ad, ok := r.resolve(a).(map[string]interface{}); ok && r.resolve(ad["Subtype"]) == name("Redact") {
+			regs = append(regs, r.redactions(ad)...)
+		}
+	}
+	if len(regs) == 0 {
+		return rpage{p.num, dic}
+	}
+	rd := &redactor{extractor: extractor{r: r, fonts: make(map[int]*rfont)}, c: c}
+	rd.gs = textState{ctm: identity, th: 1}
+	for _, g := range regs {
+		rd.regs = append(rd.regs, newRect(g.X, g.Y, g.X+g.W, g.Y+g.H))
+	}
+
+	var kept []interface{}
+	for _, a := range annots {
+		ad, _ := r.resolve(a).(map[string]interface{})
+		if b := r.box(ad["Rect"]); b == nil || !rd.covered(b) {
+			kept = append(kept, a)
+		}
+	}
+	delete(dic, "Annots")
+	if len(kept) > 0 {
+		dic["Annots"] = kept
+	}
+
+	res, _ := r.resolve(p.dic["Resources"]).(map[string]interface{})
+	con, res := rd.redact(r.contents(p.dic["Contents"]), res, false)
+	buf := bytes.NewBufferString("q\n")
+	buf.Write(con)
+	buf.WriteString("Q\n")
+	for _, g := range regs {
+		col := g.Color
+		if col == nil {
+			col = Gray(0)
+		}
+		fmt.Fprintf(buf, "%s %s %s %s %s re f\n", colorOp(col, false), ftoa(g.X), ftoa(g.Y), ftoa(g.W), ftoa(g.H))
+	}
+	if res != nil {
+		dic["Resources"] = res
+	}
+	dic["Contents"] = d.indirect(buf.Bytes())
+	return rpage{p.num, dic}
+}
+
+// redactor removes the text and images in regions of pages from their
+// content streams. It follows the text state like extractor.
+type redactor struct {
+	extractor
+	c    *copier
+	regs []*rect // regions, in the default coordinates of the page
+	n    int     // number of copies of forms made
+}
+
+// covered tells whether b overlaps any of the regions of rd.
+func (rd *redactor) covered(b *rect) bool {
+	for _, g := range rd.regs {
+		if b.overlaps(g) {
+			return true
+		}
+	}
+	return false
+}
+
+// area returns the bounding box of rectangle b transformed by m and the
+// current transformation matrix.
+func (rd *redactor) area(b rect, m matrix) *rect {
+	var bs bounds
+	bs.addRect(b, m.mul(rd.gs.ctm))
+	return &bs.r
+}
+
+// redact returns content stream b, whose resources are res, with the text
+// and images in the regions of rd removed, and the resources of the new
+// content. Forms partly in the regions are replaced by redacted copies,
+// which are added to the resources. XObjects that aren't drawn anymore are
+// removed from the resources, and if inherited is true, which is for forms
+// that use the resources of their page, only the ones drawn are kept.
+func (rd *redactor) redact(b []byte, res map[string]interface{}, inherited bool) ([]byte, map[string]interface{}) {
+	buf := new(bytes.Buffer)
+	xobjs, _ := rd.r.resolve(res["XObject"]).(map[string]interface{})
+	var added map[string]interface{}
+	// XObjects that are removed or replaced, and the ones still drawn; the
+	// first are removed from the resources if they aren't drawn anymore,
+	// so that they aren't copied.
+	gone, used := make(map[name]bool), make(map[name]bool)
+	ops := operations(b)
+	for i := 0; i < len(ops); i++ {
+		op := ops[i]
+		switch op.op {
+		case "Tj", "'", "\"", "TJ":
+			if t := rd.text(op); t != nil {
+				for _, o := range t {
+					writeOperation(buf, o)
+				}
+				continue
+			}
+		case "BI":
+			// Inline images fill the unit square like image XObjects.
+			if i+2 < len(ops) && ops[i+1].op == "ID" && ops[i+2].op == "EI" &&
+				rd.covered(rd.area(rect{0, 0, 1, 1}, identity)) {
+				i += 2
+				continue
+			}
+		case "Do":
+			if len(op.args) != 1 {
+				break
+			}
+			k, _ := op.args[0].(name)
+			s, ok := rd.r.resolve(xobjs[string(k)]).(*stream)
+			if !ok {
+				break
+			}
+			switch rd.r.resolve(s.dic["Subtype"]) {
+			case name("Image"):
+				if rd.covered(rd.area(rect{0, 0, 1, 1}, identity)) {
+					gone[k] = true
+					continue
+				}
+			case name("Form"):
+				f, covered := rd.form(s, res)
+				if covered {
+					gone[k] = true
+				}
+				if f == nil && covered {
+					continue
+				}
+				if f != nil {
+					n := fmt.Sprint("Redacted", rd.n)
+					rd.n++
+					for xobjs[n] != nil {
+						n += "_"
+					}
+					if added == nil {
+						added = make(map[string]interface{})
+					}
+					added[n] = f
+					op = operation{"Do", []interface{}{name(n)}}
+				}
+			}
+			used[k] = !gone[k] || used[k]
+		default:
+			rd.state(op, res)
+		}
+		writeOperation(buf, op)
+	}
+	if len(gone) == 0 && !inherited {
+		return buf.Bytes(), res
+	}
+	nres := make(map[string]interface{}, len(res)+1)
+	for k, v := range res {
+		nres[k] = v
+	}
+	nx := make(map[string]interface{}, len(xobjs)+len(added))
+	for k, v := range xobjs {
+		if used[name(k)] || !gone[name(k)] && !inherited {
+			nx[k] = v
+		}
+	}
+	for k, v := range added {
+		nx[k] = v
+	}
+	nres["XObject"] = nx
+	return buf.Bytes(), nres
+}
+
+// text follows text showing operation op, and returns the operations that
+// replace it if some of its characters are in the regions of rd, or nil if
+// none of them are.
+func (rd *redactor) text(op operation) []operation {
+	a := op.args
+	var pre []operation
+	switch op.op {
+	case "'":
+		rd.move(0, -rd.gs.tl)
+		pre = []operation{{"T*", nil}}
+	case "\"":
+		if len(a) != 3 {
+			return nil
+		}
+		rd.gs.tw, _ = rd.r.number(a[0])
+		rd.gs.tc, _ = rd.r.number(a[1])
+		rd.move(0, -rd.gs.tl)
+		pre = []operation{{"Tw", a[:1]}, {"Tc", a[1:2]}, {"T*", nil}}
+		a = a[2:]
+	case "TJ":
+		if len(a) != 1 {
+			return nil
+		}
+		a, _ = a[0].([]interface{})
+	}
+	kept, changed := rd.show(a)
+	if !changed {
+		return nil
+	}
+	return append(pre, operation{"TJ", []interface{}{kept}})
+}
+
+// show shows the strings in a, and moves by the numbers in it like TJ. It
+// returns a with the characters in the regions of rd replaced by moves of
+// their widths, and whether any of them are.
+func (rd *redactor) show(a []interface{}) ([]interface{}, bool) {
+	gs := &rd.gs
+	if gs.font == nil {
+		return a, false
+	}
+	var kept []interface{}
+	var cur []byte // bytes of the characters kept since the last number
+	changed := false
+	for _, o := range a {
+		s, ok := o.(string)
+		if !ok {
+			if len(cur) > 0 {
+				kept = append(kept, string(cur))
+				cur = nil
+			}
+			if v, ok := rd.r.number(o); ok {
+				kept = append(kept, o)
+				rd.tm = matrix{1, 0, 0, 1, -v / 1000 * gs.size * gs.th, 0}.mul(rd.tm)
+			}
+			continue
+		}
+		for _, c := range gs.font.chars(s) {
+			w := gs.advance(c)
+			// Glyphs are taken to be as high as the font size, with a
+			// quarter of it below the baseline.
+			g := rect{0, gs.rise - gs.size/4, gs.font.width(c.code) * gs.size, gs.rise + gs.size}
+			if gs.size != 0 && rd.covered(rd.area(g, matrix{gs.th, 0, 0, 1, 0, 0}.mul(rd.tm))) {
+				if len(cur) > 0 {
+					kept = append(kept, string(cur))
+					cur = nil
+				}
+				kept = append(kept, math.Round(-w*1e6/gs.size)/1000)
+				changed = true
+			} else {
+				cur = append(cur, c.raw...)
+			}
+			rd.tm = matrix{1, 0, 0, 1, w * gs.th, 0}.mul(rd.tm)
+		}
+	}
+	if len(cur) > 0 {
+		kept = append(kept, string(cur))
+	}
+	return kept, changed
+}
+
+// form returns a redacted copy of form s, which is drawn with resources res,
+// if it's partly in the regions of rd, and whether it's in them. The copy is
+// nil if the form is in the regions but can't be read, and should be
+// removed.
+func (rd *redactor) form(s *stream, res map[string]interface{}) (*indirect, bool) {
+	m := identity
+	if a, ok := rd.r.resolve(s.dic["Matrix"]).([]interface{}); ok {
+		if v, ok := rd.numbers(a, 6); ok {
+			m = matrix{v[0], v[1], v[2], v[3], v[4], v[5]}
+		}
+	}
+	bbox := rd.r.box(s.dic["BBox"])
+	if bbox != nil && !rd.covered(rd.area(*bbox, m)) {
+		return nil, false
+	}
+	b := rd.r.decode(s)
+	if bbox == nil || b == nil || rd.depth >= maxFormDepth {
+		return nil, true
+	}
+	r, own := rd.r.resolve(s.dic["Resources"]).(map[string]interface{})
+	if own {
+		res = r
+	}
+	gs, stack, tm, tlm := rd.gs, rd.stack, rd.tm, rd.tlm
+	rd.gs.ctm = m.mul(rd.gs.ctm)
+	rd.stack = nil
+	rd.depth++
+	con, res := rd.redact(b, res, !own)
+	rd.depth--
+	rd.gs, rd.stack, rd.tm, rd.tlm = gs, stack, tm, tlm
+
+	dic := make(map[string]interface{}, len(s.dic))
+	for k, v := range s.dic {
+		if k != "Length" && k != "Filter" && k != "DecodeParms" {
+			dic[k] = v
+		}
+	}
+	if res != nil {
+		dic["Resources"] = res
+	}
+	return rd.c.d.indirect(rd.c.value(&stream{dic, con})), true
+}
+
+// writeOperation writes op to buf as it's written in content streams, on a
+// line of its own.
+func writeOperation(buf *bytes.Buffer, op operation) {
+	args := op.args
+	if op.op == "ID" && len(args) > 0 {
+		args = args[:len(args)-1]
+	}
+	for _, a := range args {
+		buf.Write(output(a))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(op.op)
+	if op.op == "ID" && len(op.args) > 0 {
+		buf.WriteByte(' ')
+		buf.WriteString(op.args[len(op.args)-1].(string))
+	}
+	buf.WriteByte('\n')
+}
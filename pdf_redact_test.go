@@ -0,0 +1,117 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestApplyRedactions(t *testing.T) {
+	c := "BT /F1 10 Tf 10 50 Td (Name: Secret) Tj 0 -20 Td [(Pub) -100 (lic)] TJ ET\n" +
+		"q 20 0 0 20 60 10 cm /Im1 Do Q q 1 0 0 1 0 70 cm /X1 Do Q"
+	form := "BT /F1 10 Tf 10 10 Td (Hidden) Tj ET"
+	objs := append(testPage(c, "<< /Font << /F1 5 0 R >> /XObject << /Im1 6 0 R /X1 7 0 R >> >>"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Length 1 >>\nstream\n\x00\nendstream",
+		fmt.Sprintf("<< /Type /XObject /Subtype /Form /BBox [ 0 0 100 30 ] /Length %d >>\nstream\n%s\nendstream", len(form), form),
+		"<< /Type /Annot /Subtype /Redact /Rect [ 5 78 20 90 ] /IC [ 1 0 0 ] >>",
+		"<< /Type /Annot /Subtype /Link /Rect [ 0 0 5 5 ] >>")
+	objs[2] = strings.Replace(objs[2], "/Resources", "/Annots [ 8 0 R 9 0 R ] /Resources", 1)
+	r, err := NewReader(testFile(objs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err = ApplyRedactions(buf, r, Redaction{Page: 1, X: 43, Y: 48, W: 40, H: 10},
+		Redaction{Page: 1, X: 65, Y: 15, W: 5, H: 5, Color: Gray(0.5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps := Validate(buf.Bytes()); len(ps) > 0 {
+		t.Errorf("problems of redacted file: %v", ps)
+	}
+	rr, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, _ := rr.PageText(1)
+	var got []string
+	for _, run := range runs {
+		got = append(got, fmt.Sprint(ftoa(run.X), " ", ftoa(run.Y), " ", run.Text))
+	}
+	// Characters after the removed ones stay where they were.
+	want := "10 50 Name: |10 30 Public|10 80 den"
+	if strings.Join(got, "|") != want {
+		t.Errorf("text of redacted page: got %q, want %q", got, want)
+	}
+	p := rr.pages()[0].dic
+	con := string(rr.contents(p["Contents"]))
+	if strings.Contains(con, "/Im1 Do") {
+		t.Error("image in a region is not removed")
+	}
+	for _, s := range []string{"0 g 43 48 40 10 re f", "0.5 g 65 15 5 5 re f", "1 0 0 rg 5 78 15 12 re f"} {
+		if !strings.Contains(con, s) {
+			t.Errorf("no %q in content: %s", s, con)
+		}
+	}
+	annots := rr.resolve(p["Annots"]).([]interface{})
+	if len(annots) != 1 || rr.resolve(annots[0]).(map[string]interface{})["Subtype"] != name("Link") {
+		t.Errorf("annotations: got %v", annots)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("Secret")) || bytes.Contains(buf.Bytes(), []byte("Hidden")) {
+		t.Error("redacted text is in the file")
+	}
+
+	if err := ApplyRedactions(bytes.NewBuffer(nil), r, Redaction{Page: 2, W: 1, H: 1}); err == nil {
+		t.Error("no error for redaction on a missing page")
+	}
+	if err := ApplyRedactions(bytes.NewBuffer(nil), r, Redaction{Page: 1}); err == nil {
+		t.Error("no error for empty redaction")
+	}
+}
+
+func TestAddRedaction(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(100, 100)
+	d.Rectangle(0, 0, 100, 100)
+	d.Fill()
+	if err := d.AddRedaction(10, 10, 50, 20, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddRedaction(10, 10, 0, 20, nil); err == nil {
+		t.Error("no error for empty redaction")
+	}
+	d.Close()
+	r, _ := NewReader(buf.Bytes())
+	out := bytes.NewBuffer(nil)
+	if err := ApplyRedactions(out, r); err != nil {
+		t.Fatal(err)
+	}
+	rr, _ := NewReader(out.Bytes())
+	p := rr.pages()[0].dic
+	if _, ok := p["Annots"]; ok {
+		t.Error("redaction annotation is not removed")
+	}
+	if con := string(rr.contents(p["Contents"])); !strings.Contains(con, "0 g 10 10 50 20 re f") {
+		t.Errorf("content of page: got %s", con)
+	}
+}
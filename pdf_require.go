@@ -0,0 +1,153 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the requirements of documents, which tell viewers what
+// they need to do for the document to work as meant, and the developer
+// extensions they use, which tell what they need beyond the version of PDF
+// of the document.
+
+import (
+	"fmt"
+)
+
+// Requirements of documents. Only RequireJavaScript can be used in documents
+// of versions before PDF 2.0.
+const (
+	RequireJavaScript       = "EnableJavaScripts"
+	RequireAttachment       = "Attachment"
+	RequireAcroFormInteract = "AcroFormInteract"
+	RequireMarkup           = "Markup"
+	RequireMultimedia       = "Multimedia"
+	Require3DMarkup         = "3DMarkup"
+	RequireDigSigValidation = "DigSigValidation"
+	RequireEncryption       = "Encryption"
+	RequireOCInteract       = "OCInteract"
+	RequireNavigation       = "Navigation"
+)
+
+// requirements holds the types of requirements of PDF 2.0.
+var requirements = map[string]bool{
+	"OCInteract": true, "OCAutoStates": true, "AcroFormInteract": true,
+	"Navigation": true, "Markup": true, "3DMarkup": true, "Multimedia": true,
+	"U3D": true, "PRC": true, "Action": true, "EnableJavaScripts": true,
+	"Attachment": true, "AttachmentEditing": true, "Collection": true,
+	"CollectionEditing": true, "DigSigValidation": true, "DigSig": true,
+	"DigSigMDP": true, "RichMedia": true, "Geospatial2D": true,
+	"Geospatial3D": true, "DPartInteract": true, "SeparationSimulation": true,
+	"Transitions": true, "Encryption": true,
+}
+
+// AddRequirement declares that viewers need to support the feature of type
+// typ, like RequireJavaScript, to process the document as meant. It needs PDF
+// 1.7, and all the types other than RequireJavaScript need PDF 2.0.
+func (d *Document) AddRequirement(typ string) (err error) {
+	defer dontPanic(&err)
+
+	switch {
+	case !requirements[typ]:
+		panic("unknown requirement: " + typ)
+	case d.version < "1.7":
+		panic("requirements need PDF 1.7")
+	case typ != RequireJavaScript && d.version < "2.0":
+		panic("requirement " + typ + " needs PDF 2.0")
+	}
+	for _, r := range d.reqs {
+		if r == typ {
+			return nil
+		}
+	}
+	d.reqs = append(d.reqs, typ)
+	return nil
+}
+
+// requirements returns the requirements of the document for the catalog.
+func (d *Document) requirements() []interface{} {
+	a := make([]interface{}, len(d.reqs))
+	for i, r := range d.reqs {
+		a[i] = map[string]interface{}{
+			"Type": name("Requirement"),
+			"S":    name(r),
+		}
+	}
+	return a
+}
+
+// Extension is a developer extension to PDF, which adds features to a version
+// of PDF, like the extensions of Adobe named ADBE.
+type Extension struct {
+	Prefix      string // prefix of the names of the developer, registered with ISO
+	BaseVersion string // version of PDF that is extended; the version of the document if empty
+	Level       int    // level of the extension, which is larger for later ones
+	URL         string // where the extension is documented, if set
+}
+
+// AddExtension declares that the document uses the developer extension e. A
+// document can have one extension of each prefix; the one with the larger
+// level is kept. Extensions need PDF 1.7.
+func (d *Document) AddExtension(e *Extension) (err error) {
+	defer dontPanic(&err)
+
+	if d.version < "1.7" {
+		panic("extensions need PDF 1.7")
+	}
+	if e.Prefix == "" {
+		panic("extension with no prefix")
+	}
+	if e.Level < 1 {
+		panic(fmt.Sprint("bad level of extension ", e.Prefix, ": ", e.Level))
+	}
+	base := e.BaseVersion
+	if base == "" {
+		base = d.version
+	}
+	if !versions[base] || base > d.version {
+		panic(fmt.Sprint("bad base version of extension ", e.Prefix, ": ", base))
+	}
+	d.addExtension(&Extension{e.Prefix, base, e.Level, e.URL})
+	return nil
+}
+
+// addExtension adds e to the extensions of d, unless an extension with the
+// same prefix and a larger level is already there.
+func (d *Document) addExtension(e *Extension) {
+	if old, ok := d.extensions[e.Prefix]; ok && old.Level >= e.Level {
+		return
+	}
+	if d.extensions == nil {
+		d.extensions = make(map[string]*Extension)
+	}
+	d.extensions[e.Prefix] = e
+}
+
+// extensionsDict returns the extensions dictionary of the document for the
+// catalog.
+func (d *Document) extensionsDict() map[string]interface{} {
+	m := make(map[string]interface{}, len(d.extensions))
+	for p, e := range d.extensions {
+		dic := map[string]interface{}{
+			"Type":           name("DeveloperExtensions"),
+			"BaseVersion":    name(e.BaseVersion),
+			"ExtensionLevel": e.Level,
+		}
+		if e.URL != "" {
+			dic["URL"] = e.URL
+		}
+		m[p] = dic
+	}
+	return m
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestRequirements(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf, Options{Version: "2.0"})
+	d.NewPage(100, 100)
+	for _, r := range []string{RequireJavaScript, RequireAttachment, RequireJavaScript} {
+		if err := d.AddRequirement(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewReader(buf.Bytes())
+	cat := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	reqs := r.resolve(cat["Requirements"]).([]interface{})
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requirements, expected 2", len(reqs))
+	}
+	for i, s := range []string{"EnableJavaScripts", "Attachment"} {
+		if m := r.resolve(reqs[i]).(map[string]interface{}); m["Type"] != name("Requirement") || m["S"] != name(s) {
+			t.Errorf("requirement %d: got %v", i, m)
+		}
+	}
+
+	for _, tt := range []struct {
+		version, typ, err string
+	}{
+		{"1.7", RequireJavaScript, ""},
+		{"1.7", RequireAttachment, "pdf.go: requirement Attachment needs PDF 2.0"},
+		{"1.6", RequireJavaScript, "pdf.go: requirements need PDF 1.7"},
+		{"2.0", "Telepathy", "pdf.go: unknown requirement: Telepathy"},
+	} {
+		d, _ := New(bytes.NewBuffer(nil), Options{Version: tt.version})
+		if err := d.AddRequirement(tt.typ); fmt.Sprint(err) != tt.err && (err != nil || tt.err != "") {
+			t.Errorf("%s in PDF %s: got %v expected %q", tt.typ, tt.version, err, tt.err)
+		}
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(100, 100)
+	for _, e := range []*Extension{
+		{Prefix: "ADBE", Level: 3},
+		{Prefix: "ADBE", BaseVersion: "1.7", Level: 2},
+		{Prefix: "XMPL", Level: 1, URL: "https://example.com/ext"},
+	} {
+		if err := d.AddExtension(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range []struct {
+		e   Extension
+		err string
+	}{
+		{Extension{Level: 1}, "pdf.go: extension with no prefix"},
+		{Extension{Prefix: "XMPL"}, "pdf.go: bad level of extension XMPL: 0"},
+		{Extension{Prefix: "XMPL", BaseVersion: "2.0", Level: 1}, "pdf.go: bad base version of extension XMPL: 2.0"},
+	} {
+		if err := d.AddExtension(&tt.e); fmt.Sprint(err) != tt.err {
+			t.Errorf("got %v expected %q", err, tt.err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewReader(buf.Bytes())
+	cat := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	ext := r.resolve(cat["Extensions"]).(map[string]interface{})
+	adbe := r.resolve(ext["ADBE"]).(map[string]interface{})
+	if adbe["BaseVersion"] != name("1.7") || adbe["ExtensionLevel"] != 3 {
+		t.Errorf("ADBE: got %v", adbe)
+	}
+	xmpl := r.resolve(ext["XMPL"]).(map[string]interface{})
+	if xmpl["Type"] != name("DeveloperExtensions") || xmpl["URL"] != "https://example.com/ext" {
+		t.Errorf("XMPL: got %v", xmpl)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil), Options{Version: "1.6"})
+	if err := d.AddExtension(&Extension{Prefix: "XMPL", Level: 1}); fmt.Sprint(err) != "pdf.go: extensions need PDF 1.7" {
+		t.Errorf("got %v for an extension in PDF 1.6", err)
+	}
+}
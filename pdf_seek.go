@@ -0,0 +1,163 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file writes documents to outputs that can seek. What isn't known when
+// a part of the document is written, like the length of a stream read from an
+// io.Reader or the number of pages, is left blank and filled in when the
+// document is closed.
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Widths of the blanks left for lengths of streams and for page counts.
+const (
+	lengthWidth = 10
+	countWidth  = 6
+)
+
+// patch is a blank in the output, filled in when the document is closed.
+type patch struct {
+	off   int                   // offset of the blank
+	width int                   // width of the blank
+	value func(d *Document) int // what goes in the blank
+}
+
+// NewSeekable is like New, but the document is written to an output that can
+// seek, so that some of it can be filled in later: attached files are
+// streamed from their readers, and page counts can be drawn.
+func NewSeekable(w io.WriteSeeker, opts ...Options) (d *Document, err error) {
+	defer dontPanic(&err)
+
+	// Offsets of the document start at the current position of w.
+	base, err := w.Seek(0, io.SeekCurrent)
+	checkWrite(err)
+	d, err = New(w, opts...)
+	check(err)
+	d.ws, d.base = w, base
+	return d, nil
+}
+
+// seekable tells whether blanks left in the output of d can be filled in
+// later. Encrypted and signed documents can't have blanks, since their
+// content is encrypted or hashed when it's written.
+func (d *Document) seekable() bool {
+	return d.ws != nil && d.sec == nil && d.sig == nil
+}
+
+// blank leaves a blank of width w in the output, at offset off, which is
+// filled in with value when the document is closed.
+func (d *Document) blank(off, w int, value func(d *Document) int) {
+	d.patches = append(d.patches, patch{off, w, value})
+}
+
+// fillBlanks writes the values of the blanks in the output of d, and then
+// seeks back to the end of the document.
+func (d *Document) fillBlanks() {
+	if len(d.patches) == 0 {
+		return
+	}
+	for _, p := range d.patches {
+		s := strconv.Itoa(p.value(d))
+		if len(s) > p.width {
+			panic(fmt.Sprintf("%s doesn't fit in a blank of %d digits", s, p.width))
+		}
+		_, err := d.ws.Seek(d.base+int64(p.off), io.SeekStart)
+		checkWrite(err)
+		_, err = d.ws.Write([]byte(s))
+		checkWrite(err)
+	}
+	_, err := d.ws.Seek(d.base+int64(d.off), io.SeekStart)
+	checkWrite(err)
+}
+
+// docWriter writes to the output of a document.
+type docWriter struct {
+	d *Document
+}
+
+func (w docWriter) Write(b []byte) (int, error) {
+	w.d.write(b)
+	return len(b), nil
+}
+
+// outputStreamFrom writes a stream with dictionary dic and the data read
+// from r as the indirect object i, and returns the length of the data. The
+// length in the dictionary is filled in when the document is closed.
+func (d *Document) outputStreamFrom(i *indirect, dic map[string]interface{}, r io.Reader) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	checkContext(d.ctx)
+	if d.off == 0 {
+		d.writeHeader()
+	}
+	d.checkObject(&stream{dic, nil})
+	d.checkStrict(i, &stream{dic, nil})
+	i.off = d.off
+	defer d.countPart(i, &stream{dic, nil})
+	d.traceObject(i, &stream{dic, nil})
+	d.write([]byte(fmt.Sprintf("%d 0 obj\n<< /Length ", i.num)))
+	off := d.off
+	d.write([]byte(strings.Repeat(" ", lengthWidth)))
+	d.write(output(dic)[len("<<"):])
+	d.write([]byte("\nstream\n"))
+	n, err := io.Copy(docWriter{d}, r)
+	check(err)
+	d.write([]byte("\nendstream\nendobj\n"))
+	d.blank(off, lengthWidth, func(*Document) int {
+		return int(n)
+	})
+	return int(n)
+}
+
+// DrawPageCount draws the number of pages of the document with style st on
+// the current page, with the baseline starting at (x, y), like for "page 2
+// of 9". The number is filled in when the document is closed, so it needs a
+// document made by NewSeekable or NewBuffered, with no encryption or
+// signature.
+func (d *Document) DrawPageCount(x, y float64, st TextStyle) (err error) {
+	defer dontPanic(&err)
+
+	d.canDraw("page count")
+	if !d.seekable() {
+		panic("page count drawn on a document that can't be filled in later")
+	}
+	d.pageFont(st.font())
+	s := st.text(strings.Repeat(" ", countWidth), x, y, st.size())
+	d.addc(strings.TrimSuffix(s, "\n"))
+	// The offset is found after s is added, since comments of traced
+	// documents go before it.
+	off := d.con.Len() - len(s) + strings.Index(s, "(") + 1
+	d.pg.counts = append(d.pg.counts, off)
+	return nil
+}
+
+// pageCountBlanks leaves the blanks of the page counts of the current page,
+// whose content stream of length n has just been written to the output.
+func (d *Document) pageCountBlanks(n int) {
+	start := d.off - len("\nendstream\nendobj\n") - n
+	for _, off := range d.pg.counts {
+		d.blank(start+off, countWidth, func(d *Document) int {
+			return len(d.pgs)
+		})
+	}
+}
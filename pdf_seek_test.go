@@ -0,0 +1,79 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSeekable(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "seek.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// Documents start where the output is when they're made.
+	f.Write([]byte("junk"))
+	d, err := NewSeekable(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := "streamed data"
+	if err := d.AttachFile(&File{Name: "a.txt", Reader: strings.NewReader(data)}); err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(100, 100)
+	if err := d.DrawPageCount(10, 10, courier); err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(100, 100)
+	d.NewPage(100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = bytes.TrimPrefix(b, []byte("junk"))
+	if probs := Validate(b); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+	r, _ := NewReader(b)
+	runs, _ := r.PageText(1)
+	if len(runs) != 1 || strings.TrimSpace(runs[0].Text) != "3" {
+		t.Errorf("page count: got %v", runs)
+	}
+	s, _ := r.object(3).(*stream)
+	if s == nil || string(r.decode(s)) != data {
+		t.Fatalf("attached file: got %v", s)
+	}
+	if p, _ := r.resolve(s.dic["Params"]).(map[string]interface{}); p["Size"] != len(data) {
+		t.Errorf("parameters of attached file: got %v", p)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.NewPage(100, 100)
+	if err := d.DrawPageCount(10, 10, TextStyle{}); err == nil {
+		t.Error("page count drawn on a document that can't seek")
+	}
+}
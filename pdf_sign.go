@@ -0,0 +1,254 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file deals with digital signatures (p. 725). The whole file is
+// signed, except the signature itself, which is written into a gap left for it
+// in the signature dictionary. Since the gap and the byte ranges around it are
+// known only at the end, the last part of the file, from the signature
+// dictionary on, is kept in memory until it's signed; the rest is hashed as it
+// is written.
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+)
+
+// Signature holds what is needed to sign a document.
+type Signature struct {
+	Field string        // name of the signature field; "Signature1" if empty
+	Key   crypto.Signer // private key of the signer, RSA or ECDSA
+	// Certificates are put in the signature so that it can be verified.
+	// The first one is the certificate of Key, and the rest are its chain.
+	Certificates []*x509.Certificate
+
+	Name        string    // name of the signer; optional
+	Reason      string    // reason of signing; optional
+	Location    string    // where the document was signed; optional
+	ContactInfo string    // how to reach the signer; optional
+	Time        time.Time // time of signing; now if zero
+
+	// Timestamp, if not nil, adds a trusted timestamp of the signature
+	// to it.
+	Timestamp TimestampClient
+}
+
+// signer signs the document while it's being written.
+type signer struct {
+	s     *Signature
+	w     io.Writer     // the real output of the document
+	h     hash.Hash     // digest of what is written so far
+	field *indirect     // signature field, merged with its widget
+	v     *indirect     // signature dictionary
+	fname string        // name of the signature field
+	doc   bool          // whether it's a document timestamp
+	tail  *bytes.Buffer // the last part of the file, to be signed
+	size  int           // bytes kept for the signature
+	sig   []byte        // Contents of the signature dictionary, once it's made
+}
+
+// tokenSize is the number of bytes kept for timestamp tokens.
+const tokenSize = 8192
+
+// byteRangeWidth is the number of characters kept for the ByteRange of
+// signatures; four numbers of ten digits fit in it.
+const byteRangeWidth = 45
+
+// sigByteRange is the ByteRange of a signature before it's known; it's
+// replaced with the real one when the document is closed.
+type sigByteRange struct{}
+
+func (r sigByteRange) output() []byte {
+	return []byte("[" + strings.Repeat(" ", byteRangeWidth-2) + "]")
+}
+
+// sigContents is the gap left for a signature of n bytes.
+type sigContents int
+
+func (c sigContents) output() []byte {
+	return []byte("<" + strings.Repeat("0", 2*int(c)) + ">")
+}
+
+// Sign makes the document to be signed with s when it's closed. The signature
+// field is invisible and its widget is put on the first page.
+//
+// Sign should be called right after New, or Encrypt, before anything is added
+// to the document.
+func (d *Document) Sign(s *Signature) (err error) {
+	defer dontPanic(&err)
+
+	if s.Key == nil || len(s.Certificates) == 0 {
+		panic("signature with no key or certificate")
+	}
+	signatureAlgorithm(s.Key.Public()) // check the key before it's too late
+
+	// Enough room for the certificates, a signature of a 4096 bits RSA key,
+	// and the rest of the CMS structure.
+	size := 2048
+	for _, c := range s.Certificates {
+		size += len(c.Raw)
+	}
+	if s.Timestamp != nil {
+		size += tokenSize
+	}
+	d.startSigning(&signer{s: s, size: size})
+	return nil
+}
+
+// Timestamp makes the document to have a document timestamp from c when it's
+// closed (ISO 32000-2, section 12.8.5). It's like a signature, but it only proves
+// that the document existed at that time.
+//
+// Timestamp should be called right after New, or Encrypt, before anything is
+// added to the document.
+func (d *Document) Timestamp(c TimestampClient) (err error) {
+	defer dontPanic(&err)
+
+	if c == nil {
+		panic("Timestamp called with a nil client")
+	}
+	d.startSigning(&signer{s: &Signature{Timestamp: c}, doc: true, size: tokenSize})
+	return nil
+}
+
+// startSigning starts hashing the output for g.
+func (d *Document) startSigning(g *signer) {
+	if d.sig != nil {
+		panic("document is already signed")
+	}
+	if d.started() {
+		panic("document must be signed before anything is added to it")
+	}
+	g.fname = g.s.Field
+	if g.fname == "" {
+		g.fname = "Signature1"
+	}
+	if strings.Contains(g.fname, ".") {
+		fail(ErrBadName, "bad field name: "+g.fname)
+	}
+	if d.fnames == nil {
+		d.fnames = make(map[string]bool)
+	}
+	d.fnames[g.fname] = true
+
+	g.w = d.w
+	g.h = sha256.New() // the header is written after this too
+	g.field = d.reserveIndirect()
+	g.v = d.reserveIndirect()
+	d.w = io.MultiWriter(g.w, g.h)
+	d.sig = g
+}
+
+// beginSignature starts keeping the output in memory, and writes the
+// signature field and dictionary.
+func (d *Document) beginSignature() {
+	g := d.sig
+	if len(d.pgs) == 0 {
+		panic("signed document has no pages")
+	}
+	g.tail = bytes.NewBuffer(nil)
+	d.w = g.tail
+
+	v := map[string]interface{}{
+		"Type":      name("Sig"),
+		"Filter":    name("Adobe.PPKLite"),
+		"SubFilter": name("adbe.pkcs7.detached"),
+		"ByteRange": sigByteRange{},
+		"Contents":  sigContents(g.size),
+	}
+	if g.doc {
+		// The time is in the token.
+		v["Type"] = name("DocTimeStamp")
+		v["SubFilter"] = name("ETSI.RFC3161")
+	} else {
+		t := g.s.Time
+		if t.IsZero() {
+			t = d.now()
+		}
+		v["M"] = date(t)
+		for k, s := range map[string]string{"Name": g.s.Name, "Reason": g.s.Reason,
+			"Location": g.s.Location, "ContactInfo": g.s.ContactInfo} {
+			if s != "" {
+				v[k] = textString(s)
+			}
+		}
+	}
+	d.outputIndirect(g.v, v)
+
+	d.outputIndirect(g.field, map[string]interface{}{
+		"Type":    name("Annot"),
+		"Subtype": name("Widget"),
+		"FT":      name("Sig"),
+		"T":       textString(g.fname),
+		"V":       g.v,
+		"Rect":    newRect(0, 0, 0, 0),
+		"F":       132, // Print and Locked
+		"P":       d.pgs[0],
+	})
+	d.fields = append(d.fields, g.field)
+}
+
+// endSignature fills the byte range and the signature in the last part of
+// the file, and writes it to the output.
+func (d *Document) endSignature() {
+	g := d.sig
+	tail := g.tail.Bytes()
+	start := d.off - len(tail) // offset of tail in the file
+
+	// The signature dictionary is the first object of the tail, and
+	// ByteRange is its first entry.
+	gap := sigContents(g.size).output()
+	br := bytes.Index(tail, []byte("/ByteRange "))
+	con := bytes.Index(tail, append([]byte("/Contents "), gap...))
+	if br < 0 || con < 0 {
+		panic("signature dictionary not found")
+	}
+	br += len("/ByteRange ")
+	con += len("/Contents ")
+	n := len(gap)
+
+	// The gap is the hexadecimal string, including < and >.
+	r := fmt.Sprintf("[0 %d %d %d]", start+con, start+con+n, len(tail)-con-n)
+	copy(tail[br:], r+strings.Repeat(" ", byteRangeWidth-len(r)))
+
+	g.h.Write(tail[:con])
+	g.h.Write(tail[con+n:])
+	var sig []byte
+	if g.doc {
+		sig = timestamp(g.s.Timestamp, g.h.Sum(nil))
+	} else {
+		sig = signCMS(g.h.Sum(nil), g.s.Key, g.s.Certificates, g.s.Timestamp)
+	}
+	if len(sig) > g.size {
+		panic("signature is too big")
+	}
+	g.sig = make([]byte, g.size)
+	copy(g.sig, sig)
+	copy(tail[con+1:], hex.EncodeToString(sig))
+
+	_, err := g.w.Write(tail)
+	checkWrite(err)
+	d.w = g.w
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// testSignature returns a signature with a new ECDSA key and a self-signed
+// certificate.
+func testSignature(t *testing.T) *Signature {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(7),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Signature{Key: key, Certificates: []*x509.Certificate{cert}, Reason: "test"}
+}
+
+var byteRangeRe = regexp.MustCompile(`/ByteRange \[0 (\d+) (\d+) (\d+) *\]`)
+
+// signedContent checks the byte range of the signed document b, and returns
+// the signed bytes and the signature.
+func signedContent(t *testing.T, b []byte) (data, sig []byte) {
+	m := byteRangeRe.FindSubmatch(b)
+	if m == nil {
+		t.Fatal("no byte range in the signed document")
+	}
+	var l1, s2, l2 int
+	fmt.Sscan(string(m[1])+" "+string(m[2])+" "+string(m[3]), &l1, &s2, &l2)
+	if s2+l2 != len(b) || b[l1] != '<' || b[s2-1] != '>' {
+		t.Fatalf("byte range doesn't match the document: %s", m[0])
+	}
+	sig, err := hex.DecodeString(string(b[l1+1 : s2-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = make([]byte, 0, l1+l2)
+	data = append(data, b[:l1]...)
+	return append(data, b[s2:]...), sig
+}
+
+func TestSign(t *testing.T) {
+	s := testSignature(t)
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.Sign(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Sign(s); err == nil {
+		t.Error("document signed twice")
+	}
+	d.NewPage(100, 100)
+	d.MoveTo(0, 0)
+	d.LineTo(100, 100)
+	d.Stroke()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	for _, s := range []string{"/FT /Sig", "/SigFlags 3", "/Annots [ 3 0 R ]",
+		"/SubFilter /adbe.pkcs7.detached", "/Reason (test)"} {
+		if !bytes.Contains(b, []byte(s)) {
+			t.Errorf("signed document doesn't have %q", s)
+		}
+	}
+
+	data, der := signedContent(t, b)
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		t.Fatal(err)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		t.Fatal(err)
+	}
+	si := sd.SignerInfos[0]
+	if si.Sid.Serial.Int64() != 7 {
+		t.Errorf("signer serial number: got %v expected 7", si.Sid.Serial)
+	}
+
+	// The signed attributes should have the digest of the document, and
+	// be signed as a SET.
+	var attrs []attribute
+	set := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	if _, err := asn1.UnmarshalWithParams(set, &attrs, "set"); err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.New()
+	h.Write(data)
+	found := false
+	for _, a := range attrs {
+		if a.Type.Equal(oidMessageDigest) {
+			found = bytes.Equal(a.Values[0].Bytes, h.Sum(nil))
+		}
+	}
+	if !found {
+		t.Error("digest of the document is not signed")
+	}
+	h = sha256.New()
+	h.Write(set)
+	key := s.Key.(*ecdsa.PrivateKey)
+	if !ecdsa.VerifyASN1(&key.PublicKey, h.Sum(nil), si.Signature) {
+		t.Error("signature can't be verified")
+	}
+}
+
+func TestSignUnicode(t *testing.T) {
+	s := testSignature(t)
+	s.Field, s.Reason, s.Location = "Unterschrift-ä", "Prüfung", "Köln"
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.Sign(s); err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := signedContent(t, buf.Bytes())
+	for _, s := range []string{"Unterschrift-ä", "Prüfung", "Köln"} {
+		if b := output(textString(s)); !bytes.Contains(data, b) {
+			t.Errorf("signed data: %q not found", b)
+		}
+	}
+}
+
+func TestSignErrors(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	d.NewPage(100, 100)
+	d.Close()
+	if err := d.Sign(testSignature(t)); err == nil {
+		t.Error("document signed after objects were written")
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	if err := d.Sign(&Signature{}); err == nil {
+		t.Error("document signed with no key")
+	}
+	if err := d.Sign(testSignature(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err == nil {
+		t.Error("signed document with no pages closed")
+	}
+}
+
+func TestSignTimestamp(t *testing.T) {
+	s := testSignature(t)
+	ts := &fakeTSA{}
+	s.Timestamp = ts
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.Sign(s)
+	d.NewPage(100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, der := signedContent(t, buf.Bytes())
+	var ci contentInfo
+	asn1.Unmarshal(der, &ci)
+	var sd signedData
+	asn1.Unmarshal(ci.Content.Bytes, &sd)
+	si := sd.SignerInfos[0]
+
+	h := sha256.New()
+	h.Write(si.Signature)
+	if !bytes.Equal(ts.digest, h.Sum(nil)) {
+		t.Error("timestamp is not of the signature")
+	}
+	if !bytes.Contains(si.UnsignedAttrs.Bytes, fakeToken) {
+		t.Error("timestamp token is not in the signature")
+	}
+}
+
+func TestDocumentTimestamp(t *testing.T) {
+	ts := &fakeTSA{}
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.Timestamp(ts); err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(100, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	for _, s := range []string{"/Type /DocTimeStamp", "/SubFilter /ETSI.RFC3161"} {
+		if !bytes.Contains(b, []byte(s)) {
+			t.Errorf("timestamped document doesn't have %q", s)
+		}
+	}
+	data, tok := signedContent(t, b)
+	h := sha256.New()
+	h.Write(data)
+	if !bytes.Equal(ts.digest, h.Sum(nil)) {
+		t.Error("timestamp is not of the document")
+	}
+	if !bytes.HasPrefix(tok, fakeToken) {
+		t.Error("timestamp token is not in the document")
+	}
+}
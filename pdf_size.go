@@ -0,0 +1,133 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file counts the bytes of documents, by the parts of documents they
+// are used for, so that the size of documents can be checked before they're
+// written.
+
+import (
+	"io"
+)
+
+// Parts of documents counted by Sizes. The objects of other parts, like the
+// structure of the document and its annotations, are partOther.
+const (
+	partOther = iota
+	partContent
+	partFonts
+	partImages
+	numParts
+)
+
+// Sizes holds the size of a document and of its parts, in bytes.
+type Sizes struct {
+	Total   int // all of the file
+	Content int // content streams of pages and of form XObjects
+	Fonts   int // font dictionaries, font programs and glyphs
+	Images  int // image XObjects and their soft masks
+	Other   int // the rest, like the structure of the document, annotations and metadata
+}
+
+// Sizes returns the sizes of what's written to the output of d so far.
+// After Close, they are the sizes of the whole file.
+func (d *Document) Sizes() Sizes {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := Sizes{Total: d.off, Content: d.parts[partContent], Fonts: d.parts[partFonts],
+		Images: d.parts[partImages]}
+	s.Other = s.Total - s.Content - s.Fonts - s.Images
+	return s
+}
+
+// DryRun makes a document by calling build with a new document, made with
+// options opts, and closing it, and returns its sizes. Nothing is kept of
+// what's written, so services can check that a document is small enough
+// before they make it. Errors of build are returned as they are.
+func DryRun(build func(d *Document) error, opts ...Options) (s Sizes, err error) {
+	defer dontPanic(&err)
+
+	d, err := New(io.Discard, opts...)
+	check(err)
+	if err := build(d); err != nil {
+		return Sizes{}, err
+	}
+	check(d.Close())
+	return d.Sizes(), nil
+}
+
+// partIndirect is like indirect, but o is counted in part p by Sizes.
+func (d *Document) partIndirect(p int, o interface{}) *indirect {
+	i := d.reserveIndirect()
+	i.part = p
+	d.outputIndirect(i, o)
+	return i
+}
+
+// countPart counts the bytes written since object i with value o started,
+// in the part of the document it belongs to. It should be deferred when i
+// starts to be written, with d.mu locked.
+func (d *Document) countPart(i *indirect, o interface{}) {
+	p := i.part
+	if p == partOther {
+		p = objectPart(o)
+	}
+	d.parts[p] += d.off - i.off
+}
+
+// objectPart returns the part of documents that object o belongs to, found
+// from its type and subtype.
+func objectPart(o interface{}) int {
+	var dic map[string]interface{}
+	switch t := o.(type) {
+	case map[string]interface{}:
+		dic = t
+	case Dict:
+		dic = t
+	case *stream:
+		dic = t.dic
+	case *Stream:
+		dic = t.Dict
+	default:
+		return partOther
+	}
+	is := func(k, v string) bool {
+		switch n := dic[k].(type) {
+		case name:
+			return string(n) == v
+		case Name:
+			return string(n) == v
+		}
+		return false
+	}
+	switch {
+	case is("Type", "Font") || is("Type", "FontDescriptor") || is("Type", "CMap"):
+		return partFonts
+	case is("Subtype", "Type1C") || is("Subtype", "CIDFontType0C") || is("Subtype", "OpenType"):
+		// Font programs in FontFile3 streams
+		return partFonts
+	case dic["Length1"] != nil || dic["Length2"] != nil:
+		// Font programs in FontFile and FontFile2 streams
+		return partFonts
+	case is("Subtype", "Image"):
+		return partImages
+	case is("Subtype", "Form"):
+		return partContent
+	}
+	return partOther
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	build := func(d *Document) error {
+		d.NewPage(300, 300)
+		d.Rectangle(10, 10, 100, 100)
+		d.Fill()
+		x, err := d.AddImage(image.NewGray(image.Rect(0, 0, 50, 50)))
+		if err != nil {
+			return err
+		}
+		d.DrawXObject(x, 0, 0, 50, 50)
+		return d.TextBox(10, 200, 100, 30, &TextField{Name: "name", Value: "Ali"})
+	}
+	s, err := DryRun(build, Options{Deterministic: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf, Options{Deterministic: true})
+	if err := build(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s != d.Sizes() || s.Total != buf.Len() {
+		t.Errorf("sizes of dry run: got %+v, want %+v of %d bytes", s, d.Sizes(), buf.Len())
+	}
+	if s.Content == 0 || s.Fonts == 0 || s.Images == 0 || s.Other == 0 {
+		t.Errorf("parts of the document: got %+v", s)
+	}
+	if s.Content+s.Fonts+s.Images+s.Other != s.Total {
+		t.Errorf("parts don't add up to the total: %+v", s)
+	}
+
+	e := errors.New("failed")
+	if _, err := DryRun(func(*Document) error { return e }); err != e {
+		t.Errorf("error of build: got %v", err)
+	}
+	if _, err := DryRun(build, Options{Version: "3.0"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("bad options: got %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file keeps large content streams and images in temporary files,
+// instead of memory, when the memory of a document is limited. They're
+// copied from the files to the output when they're written.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// spill holds the data of a stream. It's kept in memory until it grows
+// larger than limit, and then moved to a temporary file.
+type spill struct {
+	d     *Document
+	limit int           // no limit if zero
+	mem   *bytes.Buffer // data in memory, if any is written
+	f     TempFile      // temporary file, once the data is moved to it
+	n     int           // length of the data
+}
+
+// spillBufs holds the memory of spills that are written to the output
+// already, to be reused by later spills, like the content of the next page.
+var spillBufs = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// maxPooled is the largest memory of spills kept to be reused.
+const maxPooled = 1 << 20
+
+// newSpill returns an empty spill for the data of a stream of d.
+func (d *Document) newSpill() *spill {
+	return &spill{d: d, limit: d.limit}
+}
+
+func (s *spill) Write(b []byte) (int, error) {
+	s.n += len(b)
+	if s.f != nil {
+		return s.f.Write(b)
+	}
+	if s.mem == nil {
+		s.mem = spillBufs.Get().(*bytes.Buffer)
+	}
+	s.mem.Write(b)
+	if s.limit > 0 && s.mem.Len() > s.limit {
+		f, err := s.d.tempFile()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := f.Write(s.mem.Bytes()); err != nil {
+			return 0, err
+		}
+		s.f = f
+		s.release()
+	}
+	return len(b), nil
+}
+
+// release gives the memory of s back to be reused, once its data is written
+// to the output.
+func (s *spill) release() {
+	if s.mem != nil && s.mem.Cap() <= maxPooled {
+		s.mem.Reset()
+		spillBufs.Put(s.mem)
+	}
+	s.mem = nil
+}
+
+// Len returns the length of the data of s.
+func (s *spill) Len() int {
+	return s.n
+}
+
+// reader returns a reader of the data of s, from its start.
+func (s *spill) reader() io.Reader {
+	if s.f == nil {
+		return bytes.NewReader(s.bytes())
+	}
+	_, err := s.f.Seek(0, io.SeekStart)
+	checkWrite(err)
+	return s.f
+}
+
+// bytes returns the data of s.
+func (s *spill) bytes() []byte {
+	if s.f == nil {
+		if s.mem == nil {
+			return nil
+		}
+		return s.mem.Bytes()
+	}
+	b, err := io.ReadAll(s.reader())
+	checkWrite(err)
+	return b
+}
+
+// SetMemoryLimit limits the memory used by each content stream and image of
+// d to about n bytes. Larger ones are kept in temporary files until they're
+// written to the output, and documents made by NewBuffered are moved to a
+// temporary file when they grow larger than n. The files are removed when
+// the document is closed. There's no limit if n is zero, which is the
+// default.
+func (d *Document) SetMemoryLimit(n int) (err error) {
+	defer dontPanic(&err)
+
+	if n < 0 {
+		panic(fmt.Sprint("negative memory limit: ", n))
+	}
+	d.limit = n
+	if f, ok := d.ws.(*memFile); ok {
+		f.d = d
+	}
+	return nil
+}
+
+// TempFile is a temporary file, which keeps the data larger than the memory
+// limit of a document until it's written to the output. It's closed when the
+// document is closed, and should be removed then.
+type TempFile interface {
+	io.ReadWriteSeeker
+	io.Closer
+}
+
+// tempFile makes a temporary file with the TempFile function of the options
+// of d, or in the file system if it has none. It's closed when d is closed.
+func (d *Document) tempFile() (TempFile, error) {
+	newTemp := d.opts.TempFile
+	if newTemp == nil {
+		newTemp = osTempFile
+	}
+	f, err := newTemp()
+	if err != nil {
+		return nil, err
+	}
+	d.tempMu.Lock()
+	d.temps = append(d.temps, f)
+	d.tempMu.Unlock()
+	return f, nil
+}
+
+// removeTemps closes the temporary files of d, which removes them.
+func (d *Document) removeTemps() {
+	d.tempMu.Lock()
+	defer d.tempMu.Unlock()
+	for _, f := range d.temps {
+		f.Close()
+	}
+	d.temps = nil
+}
+
+// outputSpill writes a stream with dictionary dic and the data of s as the
+// indirect object i. The data is copied to the output, unless the document
+// is encrypted and it has to be in memory to be encrypted. The memory of s
+// is reused after it.
+func (d *Document) outputSpill(i *indirect, dic map[string]interface{}, s *spill) {
+	defer s.release()
+	if d.sec != nil || s.f == nil {
+		d.outputIndirect(i, &stream{dic, s.bytes()})
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	checkContext(d.ctx)
+	if d.off == 0 {
+		d.writeHeader()
+	}
+	d.checkObject(&stream{dic, nil})
+	d.checkStrict(i, &stream{dic, nil})
+	all := map[string]interface{}{"Length": s.Len()}
+	for k, v := range dic {
+		all[k] = v
+	}
+	i.off = d.off
+	defer d.countPart(i, &stream{dic, nil})
+	d.traceObject(i, &stream{dic, nil})
+	d.write([]byte(fmt.Sprintf("%d 0 obj\n", i.num)))
+	d.write(output(all))
+	d.write([]byte("\nstream\n"))
+	_, err := io.Copy(docWriter{d}, s.reader())
+	checkWrite(err)
+	d.write([]byte("\nendstream\nendobj\n"))
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"testing"
+)
+
+// drawLarge adds a large image, page and XObject to d.
+func drawLarge(d *Document) {
+	m := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for i := range m.Pix {
+		m.Pix[i] = byte(i * 7)
+	}
+	m.Set(0, 0, color.NRGBA{1, 2, 3, 4})
+	x, _ := d.AddImage(m)
+	d.NewPage(100, 100)
+	for i := 0; i < 100; i++ {
+		d.Rectangle(i, i, 1, 1)
+	}
+	d.DrawXObject(x, 0, 0, 64, 64)
+	if d.seekable() {
+		d.DrawPageCount(10, 10, courier)
+	}
+	d.BeginXObject(10, 10)
+	for i := 0; i < 100; i++ {
+		d.LineTo(i, i)
+	}
+	d.EndXObject()
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	tests := []struct {
+		name string
+		new  func(w io.Writer, opts ...Options) (*Document, error)
+	}{
+		{"New", New},
+		{"NewBuffered", NewBuffered},
+	}
+	for _, tt := range tests {
+		var outs [2]bytes.Buffer
+		for i, limit := range []int{0, 100} {
+			d, _ := tt.new(&outs[i])
+			if err := d.SetMemoryLimit(limit); err != nil {
+				t.Fatal(err)
+			}
+			drawLarge(d)
+			if limit > 0 && len(d.temps) == 0 {
+				t.Errorf("%s: no temporary files with a memory limit", tt.name)
+			}
+			if err := d.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(outs[0].Bytes(), outs[1].Bytes()) {
+			t.Errorf("%s: output with a memory limit differs", tt.name)
+		}
+		if probs := Validate(outs[1].Bytes()); len(probs) > 0 {
+			t.Errorf("%s: problems: %v", tt.name, probs)
+		}
+		if files, _ := os.ReadDir(tmp); len(files) > 0 {
+			t.Errorf("%s: %d temporary files not removed", tt.name, len(files))
+		}
+	}
+
+	d, _ := New(io.Discard)
+	if err := d.SetMemoryLimit(-1); fmt.Sprint(err) != "pdf.go: negative memory limit: -1" {
+		t.Errorf("negative limit: got %v", err)
+	}
+}
+
+func TestSpill(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	d, _ := New(io.Discard)
+	s := &spill{d: d, limit: 4}
+	for _, p := range []string{"abc", "def", "gh"} {
+		s.Write([]byte(p))
+	}
+	if s.f == nil {
+		t.Error("data over the limit not moved to a file")
+	}
+	if n := s.Len(); n != 8 {
+		t.Errorf("length: got %d expected 8", n)
+	}
+	for i := 0; i < 2; i++ {
+		if b := string(s.bytes()); b != "abcdefgh" {
+			t.Errorf("data: got %q", b)
+		}
+	}
+	d.removeTemps()
+}
+
+// memTemp is a temporary file in memory.
+type memTemp struct {
+	memFile
+	closed bool
+}
+
+func (f *memTemp) Read(b []byte) (int, error) {
+	if f.pos >= len(f.b) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.b[f.pos:])
+	f.pos += n
+	return n, nil
+}
+
+func (f *memTemp) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestTempFile(t *testing.T) {
+	var temps []*memTemp
+	opts := Options{TempFile: func() (TempFile, error) {
+		f := new(memTemp)
+		temps = append(temps, f)
+		return f, nil
+	}}
+	for _, buffered := range []bool{false, true} {
+		var outs [2]bytes.Buffer
+		for i, limit := range []int{0, 100} {
+			d, _ := New(&outs[i], opts)
+			if buffered {
+				d, _ = NewBuffered(&outs[i], opts)
+			}
+			d.SetMemoryLimit(limit)
+			drawLarge(d)
+			if err := d.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(outs[0].Bytes(), outs[1].Bytes()) {
+			t.Errorf("buffered %v: output with temporary files in memory differs", buffered)
+		}
+	}
+	if len(temps) == 0 {
+		t.Error("temporary files not made by the option")
+	}
+	for i, f := range temps {
+		if !f.closed {
+			t.Errorf("temporary file %d not closed", i)
+		}
+	}
+
+	// Documents fail when no temporary file can be made.
+	opts.TempFile = func() (TempFile, error) {
+		return nil, fmt.Errorf("no file system")
+	}
+	d, _ := New(io.Discard, opts)
+	d.SetMemoryLimit(10)
+	d.NewPage(100, 100)
+	if err := d.Rectangle(0, 0, 100, 100); !errors.Is(err, ErrWriterFailed) {
+		t.Errorf("no temporary file: got %v expected ErrWriterFailed", err)
+	}
+}
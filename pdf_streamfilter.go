@@ -0,0 +1,296 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the filters that encode the data of streams written to
+// documents (p. 65). Content streams are encoded with the filters of the
+// options of their document, and streams added by AddObject with their own.
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// StreamFilter encodes the data of streams with a filter of PDF, so that
+// viewers can decode it.
+type StreamFilter interface {
+	// Name returns the name of the filter, like "FlateDecode".
+	Name() string
+	// Params returns the decode parameters of the filter, or nil if it
+	// has none.
+	Params() map[string]interface{}
+	// Encode returns a writer that encodes what's written to it and
+	// writes it to w. Closing it writes the rest of the data to w; w
+	// itself is not closed.
+	Encode(w io.Writer) (io.WriteCloser, error)
+}
+
+// Levels of Flate compression, for FlateFilter and Options.FlateLevel.
+// Levels between FlateSpeed and FlateBest trade the time of compression for
+// the size of the data.
+const (
+	FlateStore = -1 // data is stored without compressing it, for data that doesn't get smaller
+	FlateSpeed = 1
+	FlateBest  = 9
+)
+
+// Flater makes writers that compress data with Flate and write it to w in
+// the zlib format (RFC 1950), for implementations of Flate other than the
+// one of the standard library. level is from 0, which stores the data, to
+// 9, like the levels of compress/zlib.
+type Flater func(w io.Writer, level int) (io.WriteCloser, error)
+
+// FlateFilter compresses data with Flate. The zero value compresses it the
+// most, with compress/zlib.
+type FlateFilter struct {
+	Level  int    // FlateStore, or from FlateSpeed to FlateBest; FlateBest if zero
+	Flater Flater // compress/zlib if nil
+}
+
+func (FlateFilter) Name() string                   { return "FlateDecode" }
+func (FlateFilter) Params() map[string]interface{} { return nil }
+
+func (f FlateFilter) Encode(w io.Writer) (z io.WriteCloser, err error) {
+	defer dontPanic(&err)
+
+	return newFlate(w, f.Level, f.Flater), nil
+}
+
+// zlibLevel returns the level of compress/zlib for Flate level l.
+func zlibLevel(l int) int {
+	switch {
+	case l == 0:
+		return zlib.BestCompression
+	case l == FlateStore:
+		return zlib.NoCompression
+	case l < FlateSpeed || l > FlateBest:
+		panic(fmt.Sprint("bad Flate level: ", l))
+	}
+	return l
+}
+
+// flaters holds the writers of compress/zlib made by newFlate, which are
+// large, to be reused once they're closed. They are kept by their levels.
+var flaters [zlib.BestCompression + 1]sync.Pool
+
+// newFlate returns a writer that compresses data with Flate level l and
+// writes it to w, made by f, or by compress/zlib if f is nil. Writers of
+// compress/zlib are reused when they're closed.
+func newFlate(w io.Writer, l int, f Flater) io.WriteCloser {
+	zl := zlibLevel(l)
+	if f != nil {
+		z, err := f(w, zl)
+		check(err)
+		return z
+	}
+	p := &flaters[zl]
+	if z, ok := p.Get().(*zlib.Writer); ok {
+		z.Reset(w)
+		return &flateWriter{z, p}
+	}
+	z, err := zlib.NewWriterLevel(w, zl)
+	check(err)
+	return &flateWriter{z, p}
+}
+
+// flateWriter returns its writer to the pool it came from when it's closed.
+type flateWriter struct {
+	*zlib.Writer
+	pool *sync.Pool
+}
+
+func (w *flateWriter) Close() error {
+	err := w.Writer.Close()
+	if err == nil {
+		w.pool.Put(w.Writer)
+	}
+	return err
+}
+
+// ASCII85Filter encodes data with ASCII base-85, which is made of printable
+// characters only, for channels that don't keep binary data.
+type ASCII85Filter struct{}
+
+func (ASCII85Filter) Name() string                   { return "ASCII85Decode" }
+func (ASCII85Filter) Params() map[string]interface{} { return nil }
+
+func (ASCII85Filter) Encode(w io.Writer) (io.WriteCloser, error) {
+	return &ascii85Writer{ascii85.NewEncoder(w), w}, nil
+}
+
+// ascii85Writer writes the end of data marker of ASCII85Decode after the
+// data.
+type ascii85Writer struct {
+	io.WriteCloser
+	w io.Writer
+}
+
+func (w *ascii85Writer) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.w, "~>")
+	return err
+}
+
+// IdentityCrypt is the Crypt filter that leaves a stream of an encrypted
+// document unencrypted, like the metadata that should be read by search
+// engines, or data that is already encrypted. It works in documents
+// encrypted with AES, and should be the first of the filters of a stream.
+var IdentityCrypt StreamFilter = cryptFilter{}
+
+type cryptFilter struct{}
+
+func (cryptFilter) Name() string { return "Crypt" }
+
+func (cryptFilter) Params() map[string]interface{} {
+	return map[string]interface{}{
+		"Type": name("CryptFilterDecodeParms"),
+		"Name": name("Identity"),
+	}
+}
+
+func (cryptFilter) Encode(w io.Writer) (io.WriteCloser, error) {
+	return nopCloser{w}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// checkFilters panics if fs can't be the filters of a stream.
+func checkFilters(fs []StreamFilter) {
+	for i, f := range fs {
+		if f == nil {
+			panic("nil stream filter")
+		}
+		if f == IdentityCrypt && i > 0 {
+			panic("Crypt filter after other filters")
+		}
+	}
+}
+
+// filterEntries adds the Filter and DecodeParms entries of streams encoded
+// with fs to dic. The filters are in the order they decode the data, which is
+// the reverse of the order they encode it.
+func filterEntries(dic map[string]interface{}, fs []StreamFilter) {
+	if len(fs) == 0 {
+		return
+	}
+	names := make([]interface{}, len(fs))
+	parms := make([]interface{}, len(fs))
+	some := false
+	for i, f := range fs {
+		names[i] = name(f.Name())
+		if p := f.Params(); p != nil {
+			parms[i], some = p, true
+		} else {
+			parms[i] = nil
+		}
+	}
+	if len(fs) == 1 {
+		dic["Filter"] = names[0]
+		if some {
+			dic["DecodeParms"] = parms[0]
+		}
+		return
+	}
+	dic["Filter"] = names
+	if some {
+		dic["DecodeParms"] = parms
+	}
+}
+
+// encodeWith copies r to w encoded with filters fs.
+func encodeWith(w io.Writer, r io.Reader, fs []StreamFilter) {
+	ws := make([]io.WriteCloser, len(fs))
+	for i, f := range fs {
+		e, err := f.Encode(w)
+		if err != nil {
+			panic(fmt.Sprint("stream filter ", f.Name(), ": ", err))
+		}
+		ws[i], w = e, e
+	}
+	_, err := io.Copy(w, r)
+	checkWrite(err)
+	for i := len(ws) - 1; i >= 0; i-- {
+		checkWrite(ws[i].Close())
+	}
+}
+
+// encodeStream returns the dictionary and the data of a stream with
+// dictionary dic and data b, encoded with filters fs.
+func encodeStream(dic map[string]interface{}, b []byte, fs []StreamFilter) (map[string]interface{}, []byte) {
+	checkFilters(fs)
+	all := make(map[string]interface{}, len(dic)+2)
+	for k, v := range dic {
+		all[k] = v
+	}
+	filterEntries(all, fs)
+	buf := bytes.NewBuffer(make([]byte, 0, len(b)/2))
+	encodeWith(buf, bytes.NewReader(b), fs)
+	return all, buf.Bytes()
+}
+
+// unencrypted returns whether the stream with dictionary dic is left
+// unencrypted by the Identity Crypt filter, which is the one used when the
+// filter has no name.
+func unencrypted(dic map[string]interface{}) bool {
+	var p interface{}
+	switch f := dic["Filter"].(type) {
+	case name:
+		if f != "Crypt" {
+			return false
+		}
+		p = dic["DecodeParms"]
+	case []interface{}:
+		if len(f) == 0 || f[0] != name("Crypt") {
+			return false
+		}
+		if a, ok := dic["DecodeParms"].([]interface{}); ok && len(a) > 0 {
+			p = a[0]
+		}
+	default:
+		return false
+	}
+	m, _ := p.(map[string]interface{})
+	n, _ := m["Name"].(name)
+	return n == "" || n == "Identity"
+}
+
+// unencryptedStream returns whether o is a stream left unencrypted by the
+// Identity Crypt filter.
+func unencryptedStream(o interface{}) bool {
+	switch s := o.(type) {
+	case *stream:
+		return unencrypted(s.dic)
+	case *Stream:
+		if len(s.Filters) > 0 {
+			return s.Filters[0] == IdentityCrypt
+		}
+		return unencrypted(s.Dict)
+	}
+	return false
+}
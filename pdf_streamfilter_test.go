@@ -0,0 +1,173 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"testing"
+)
+
+// hexFilter is a custom filter, which encodes with ASCIIHexDecode.
+type hexFilter struct{}
+
+func (hexFilter) Name() string                   { return "ASCIIHexDecode" }
+func (hexFilter) Params() map[string]interface{} { return nil }
+
+func (hexFilter) Encode(w io.Writer) (io.WriteCloser, error) {
+	return nopCloser{hex.NewEncoder(w)}, nil
+}
+
+func TestContentFilters(t *testing.T) {
+	for _, fs := range [][]StreamFilter{
+		{FlateFilter{}},
+		{ASCII85Filter{}, FlateFilter{}},
+		{hexFilter{}},
+	} {
+		buf := bytes.NewBuffer(nil)
+		d, _ := New(buf, Options{Filters: fs})
+		d.NewPage(100, 100)
+		d.Rectangle(10, 10, 20, 20)
+		d.Fill()
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, _ := NewReader(buf.Bytes())
+		pg := r.pages()[0].dic
+		s := r.resolve(r.resolve(pg["Contents"]).([]interface{})[0]).(*stream)
+		names, _ := r.filters(s)
+		if fmt.Sprint(names) != fmt.Sprint(encoderNames(fs)) {
+			t.Errorf("%v: got filters %v", fs, names)
+		}
+		if c := string(r.contents(pg["Contents"])); c != "10 10 20 20 re\nf\n\n" {
+			t.Errorf("%v: got content %q", fs, c)
+		}
+	}
+}
+
+// encoderNames returns the names of fs.
+func encoderNames(fs []StreamFilter) []name {
+	n := make([]name, len(fs))
+	for i, f := range fs {
+		n[i] = name(f.Name())
+	}
+	return n
+}
+
+func TestIdentityCrypt(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.EncryptAES("", "owner", 128, PermPrint); err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(100, 100)
+	data := []byte("readable by anyone, readable by anyone")
+	plain, err := d.AddObject(&Stream{Dict: map[string]interface{}{"Type": Name("Data")},
+		Data: data, Filters: []StreamFilter{IdentityCrypt, FlateFilter{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := d.AddObject(&Stream{Data: data, Filters: []StreamFilter{FlateFilter{}}})
+	if _, err := d.AddObject(&Stream{Data: data, Filters: []StreamFilter{FlateFilter{}, IdentityCrypt}}); err == nil {
+		t.Errorf("no error for Crypt after Flate")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(buf.Bytes(), flateEncode(data)); n != 1 {
+		t.Errorf("got the unencrypted data %d times, expected once", n)
+	}
+	r, _ := NewReader(buf.Bytes())
+	if err := r.Decrypt(""); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []Ref{plain, secret} {
+		if got := r.decode(r.object(ref.i.num).(*stream)); !bytes.Equal(got, data) {
+			t.Errorf("object %d: got %q", ref.i.num, got)
+		}
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.Encrypt("", "owner", 128, PermPrint)
+	if _, err := d.AddObject(&Stream{Data: data, Filters: []StreamFilter{IdentityCrypt}}); fmt.Sprint(err) !=
+		"pdf.go: Crypt filters need AES encryption" {
+		t.Errorf("got %v for a Crypt filter with RC4", err)
+	}
+}
+
+func TestFlateLevel(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(i * i / 7)
+	}
+	var sizes []int
+	for _, l := range []int{FlateStore, FlateSpeed, 0} {
+		buf := bytes.NewBuffer(nil)
+		d, _ := New(buf, Options{FlateLevel: l})
+		d.NewPage(100, 100)
+		x, err := d.AddImage(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.DrawXObject(x, 0, 0, 64, 64)
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, _ := NewReader(buf.Bytes())
+		s := r.object(x.ref.num).(*stream)
+		if !bytes.Equal(r.decode(s), m.Pix) {
+			t.Errorf("level %d: pixels of image are changed", l)
+		}
+		sizes = append(sizes, len(s.buf))
+	}
+	if sizes[0] <= len(m.Pix) || sizes[1] >= sizes[0] || sizes[2] > sizes[1] {
+		t.Errorf("sizes of images stored, compressed fast and the most: %v", sizes)
+	}
+
+	var levels []int
+	flater := func(w io.Writer, level int) (io.WriteCloser, error) {
+		levels = append(levels, level)
+		return zlib.NewWriterLevel(w, level)
+	}
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf, Options{Compress: true, FlateLevel: FlateSpeed, Flater: flater})
+	d.NewPage(100, 100)
+	d.Rectangle(10, 10, 20, 20)
+	d.Fill()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(levels) == 0 || levels[0] != 1 {
+		t.Errorf("levels given to Flater: got %v", levels)
+	}
+	r, _ := NewReader(buf.Bytes())
+	if c := string(r.contents(r.pages()[0].dic["Contents"])); c != "10 10 20 20 re\nf\n\n" {
+		t.Errorf("content compressed by Flater: got %q", c)
+	}
+
+	if _, err := New(io.Discard, Options{FlateLevel: 10}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Flate level 10: got %v", err)
+	}
+	if _, err := (FlateFilter{Level: -2}).Encode(io.Discard); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Flate level -2: got %v", err)
+	}
+}
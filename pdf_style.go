@@ -0,0 +1,128 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains named styles of documents, which keep how lines, shapes
+// and text look the same all over a document.
+
+// Style holds how lines, shapes and text look. Fields that aren't set don't
+// change what's set before the style is used.
+type Style struct {
+	LineWidth float64   // width of lines; unchanged if zero
+	Stroke    Color     // color of lines; unchanged if nil
+	Fill      Color     // color of filled shapes and text; unchanged if nil
+	Dash      []float64 // lengths of dashes and gaps of lines; unchanged if nil, solid if empty
+
+	Font     string  // font of text, like FontCourier
+	FontSize float64 // size of text
+}
+
+// TextStyle returns the style of text with the font, size and fill color of
+// s, aligned to the left.
+func (s Style) TextStyle() TextStyle {
+	return TextStyle{Font: s.Font, FontSize: s.FontSize, Color: s.Fill}
+}
+
+// DefineStyle adds style s with name n to d, or replaces the style with that
+// name.
+func (d *Document) DefineStyle(n string, s Style) (err error) {
+	defer dontPanic(&err)
+
+	if n == "" {
+		fail(ErrBadName, "style with no name")
+	}
+	if s.Font != "" {
+		(&TextStyle{Font: s.Font}).font() // check it
+	}
+	if d.styles == nil {
+		d.styles = make(map[string]Style)
+	}
+	if s.Dash != nil {
+		s.Dash = append([]float64{}, s.Dash...)
+	}
+	d.styles[n] = s
+	return nil
+}
+
+// Style returns the style of d with name n.
+func (d *Document) Style(n string) (s Style, err error) {
+	defer dontPanic(&err)
+
+	return d.style(n), nil
+}
+
+// style returns the style of d with name n.
+func (d *Document) style(n string) Style {
+	s, ok := d.styles[n]
+	if !ok {
+		fail(ErrBadName, "no style named "+n)
+	}
+	return s
+}
+
+// UseStyle changes the line width, colors and dashes of what's drawn after
+// it to the ones of the style with name n. Only what's different from the
+// styles used before it on the same content is added to the content.
+func (d *Document) UseStyle(n string) (err error) {
+	defer dontPanic(&err)
+
+	s := d.style(n)
+	d.content()
+	cur := d.gstate
+	if cur == nil {
+		cur = new(Style)
+	}
+	w := d.ops()
+	if s.LineWidth != 0 && s.LineWidth != cur.LineWidth {
+		w.LineWidth(s.LineWidth)
+		cur.LineWidth = s.LineWidth
+	}
+	if s.Stroke != nil && s.Stroke != cur.Stroke {
+		w.StrokeColor(s.Stroke)
+		cur.Stroke = s.Stroke
+	}
+	if s.Fill != nil && s.Fill != cur.Fill {
+		w.FillColor(s.Fill)
+		cur.Fill = s.Fill
+	}
+	if s.Dash != nil && (cur.Dash == nil || !sameFloats(s.Dash, cur.Dash)) {
+		w.Dash(s.Dash, 0)
+		cur.Dash = s.Dash
+	}
+	d.addw(w)
+	d.gstate = cur
+	return nil
+}
+
+// stateChanged tells d that the graphics state of the current content might
+// be changed by something other than UseStyle.
+func (d *Document) stateChanged() {
+	d.gstate = nil
+}
+
+// sameFloats tells whether a and b have the same numbers.
+func sameFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
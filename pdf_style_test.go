@@ -0,0 +1,66 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestStyles(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	p := &Prototype{Styles: map[string]Style{
+		"border": {LineWidth: 2, Stroke: Gray(0.5), Dash: []float64{3, 1}},
+		"solid":  {Dash: []float64{}},
+		"note":   {Fill: RGB{1, 0, 0}, Font: FontCourier, FontSize: 8},
+	}}
+	d, _ := p.New(buf)
+	d.NewPage(100, 100)
+	d.UseStyle("border")
+	d.Rectangle(1, 1, 10, 10)
+	d.Stroke()
+	d.UseStyle("border") // nothing changed since the last one
+	d.UseStyle("solid")
+	d.UseStyle("note")
+	d.LineWidth(1)
+	d.UseStyle("border") // the line width is changed
+	d.NewPage(100, 100)
+	d.UseStyle("note") // a new page
+	if err := d.UseStyle("heading"); fmt.Sprint(err) != "pdf.go: no style named heading" {
+		t.Errorf("unknown style: got %v", err)
+	}
+	if err := d.DefineStyle("bad", Style{Font: "Times"}); err == nil {
+		t.Error("style with unknown font defined")
+	}
+	s, _ := d.Style("note")
+	if ts := s.TextStyle(); ts.Font != FontCourier || ts.FontSize != 8 || ts.Color != (RGB{1, 0, 0}) {
+		t.Errorf("text style: got %v", ts)
+	}
+	d.Close()
+
+	r, _ := NewReader(buf.Bytes())
+	tests := []string{
+		"2 w\n0.5 G\n[3 1] 0 d\n1 1 10 10 re\nS\n[] 0 d\n1 0 0 rg\n1 w\n2 w\n[3 1] 0 d\n\n",
+		"1 0 0 rg\n\n",
+	}
+	for i, pg := range r.pages() {
+		if con := string(r.contents(pg.dic["Contents"])); con != tests[i] {
+			t.Errorf("content of page %d: got %q expected %q", i+1, con, tests[i])
+		}
+	}
+}
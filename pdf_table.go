@@ -0,0 +1,355 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file lays out tables of text on pages. Rows that don't fit on a page
+// go to new pages, after the header rows of the table.
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ColumnWidth is the width of a column of a table. The zero value is an
+// automatic width: automatic columns share the width left by the others, in
+// proportion to the widths of their texts.
+type ColumnWidth struct {
+	Points  float64 // fixed width
+	Percent float64 // percentage of the width of the table
+}
+
+// Cell is a cell of a table.
+type Cell struct {
+	Text       string
+	ColSpan    int        // number of columns the cell spans; 1 if zero
+	RowSpan    int        // number of rows the cell spans; 1 if zero
+	Background Color      // no background if nil
+	Style      *TextStyle // style of the table if nil
+}
+
+// colSpan returns the number of columns c spans.
+func (c *Cell) colSpan() int {
+	if c.ColSpan < 1 {
+		return 1
+	}
+	return c.ColSpan
+}
+
+// rowSpan returns the number of rows c spans.
+func (c *Cell) rowSpan() int {
+	if c.RowSpan < 1 {
+		return 1
+	}
+	return c.RowSpan
+}
+
+// Table is a table of text. Cells of rows are put in the first columns that
+// are not taken by cells of the rows above them that span more than one
+// row, like tables of HTML.
+type Table struct {
+	Columns     []ColumnWidth // widths of columns; all automatic if empty
+	Padding     float64       // space between the borders and the text of cells
+	Border      Color         // color of the borders of cells; no borders if nil
+	BorderWidth float64       // 1 if zero
+	HeaderRows  int           // number of rows at the top repeated on each page
+	Style       TextStyle     // style of the text of cells
+
+	// KeepTogether keeps the rows of the table on the same page, and
+	// KeepWithNext keeps its last row on the same page as the next block.
+	// They are only used by flows.
+	KeepTogether, KeepWithNext bool
+
+	rows [][]*Cell
+}
+
+// AddRow adds a row with the given cells to the bottom of t.
+func (t *Table) AddRow(cells ...*Cell) (err error) {
+	defer dontPanic(&err)
+
+	for _, c := range cells {
+		if c == nil {
+			panic("AddRow called with a nil cell")
+		}
+	}
+	t.rows = append(t.rows, cells)
+	return nil
+}
+
+// tableCell is a cell placed in the grid of a table.
+type tableCell struct {
+	*Cell
+	row, col int
+	lines    []string // WinAnsi encoded lines of the text
+	style    *TextStyle
+}
+
+// tableLayout holds the position and size of the cells of a table.
+type tableLayout struct {
+	t      *Table
+	cells  []*tableCell
+	x      []float64 // left edges of the columns, and the right edge of the last one
+	height []float64 // heights of the rows
+	blocks []int     // rows that start groups of rows joined by cells, and len(height)
+}
+
+// layout returns the layout of t with width w.
+func (t *Table) layout(w float64) *tableLayout {
+	l := &tableLayout{t: t, height: make([]float64, len(t.rows))}
+	ncols := l.place()
+	l.columns(ncols, w)
+
+	// Rows are as high as their highest cell. Cells that span more than
+	// one row make their last row higher if they need to.
+	for _, c := range l.cells {
+		cw := l.x[c.col+c.colSpan()] - l.x[c.col] - 2*t.Padding
+		c.lines = c.style.wrap(c.Text, cw)
+	}
+	for pass := 0; pass < 2; pass++ {
+		for _, c := range l.cells {
+			last := c.row + c.rowSpan() - 1
+			if (c.rowSpan() > 1) != (pass == 1) {
+				continue
+			}
+			h := float64(len(c.lines))*c.style.leading() + 2*t.Padding
+			for i := c.row; i < last; i++ {
+				h -= l.height[i]
+			}
+			if h > l.height[last] {
+				l.height[last] = h
+			}
+		}
+	}
+
+	// Rows joined by cells that span them stay on the same page.
+	end := -1
+	for i := range t.rows {
+		if i > end {
+			l.blocks = append(l.blocks, i)
+		}
+		for _, c := range l.cells {
+			if c.row == i && c.row+c.rowSpan()-1 > end {
+				end = c.row + c.rowSpan() - 1
+			}
+		}
+	}
+	l.blocks = append(l.blocks, len(t.rows))
+	if t.HeaderRows < 0 || t.HeaderRows > len(t.rows) {
+		panic(fmt.Sprintf("table with %d header rows and %d rows", t.HeaderRows, len(t.rows)))
+	}
+	if t.HeaderRows > 0 && !l.blockStart(t.HeaderRows) {
+		panic("cells of header rows of table span other rows")
+	}
+	return l
+}
+
+// place puts the cells of the table of l in its grid, and returns the number
+// of columns.
+func (l *tableLayout) place() int {
+	t := l.t
+	ncols := len(t.Columns)
+	taken := make(map[[2]int]bool)
+	for i, row := range t.rows {
+		col := 0
+		for _, c := range row {
+			for taken[[2]int{i, col}] {
+				col++
+			}
+			if c.rowSpan() > len(t.rows)-i {
+				panic(fmt.Sprintf("cell in row %d of table spans rows it doesn't have", i+1))
+			}
+			for r := i; r < i+c.rowSpan(); r++ {
+				for k := col; k < col+c.colSpan(); k++ {
+					taken[[2]int{r, k}] = true
+				}
+			}
+			style := c.Style
+			if style == nil {
+				style = &t.Style
+			}
+			l.cells = append(l.cells, &tableCell{c, i, col, nil, style})
+			col += c.colSpan()
+			if len(t.Columns) > 0 && col > ncols {
+				panic(fmt.Sprintf("row %d of table has more than %d columns", i+1, ncols))
+			}
+			if col > ncols {
+				ncols = col
+			}
+		}
+	}
+	if ncols == 0 {
+		panic("table with no columns")
+	}
+	return ncols
+}
+
+// columns sets the edges of the columns of l, which has ncols columns and
+// width w.
+func (l *tableLayout) columns(ncols int, w float64) {
+	t := l.t
+	widths := make([]float64, ncols)
+	natural := make([]float64, ncols)
+	for _, c := range l.cells {
+		if c.colSpan() > 1 {
+			continue
+		}
+		for _, s := range strings.Split(winAnsi(c.Text), "\n") {
+			if n := c.style.width(s, c.style.size()) + 2*t.Padding; n > natural[c.col] {
+				natural[c.col] = n
+			}
+		}
+	}
+	left, auto, sum := w, 0, 0.0
+	for i := range widths {
+		var cw ColumnWidth
+		if i < len(t.Columns) {
+			cw = t.Columns[i]
+		}
+		switch {
+		case cw.Points > 0:
+			widths[i] = cw.Points
+		case cw.Percent > 0:
+			widths[i] = w * cw.Percent / 100
+		default:
+			auto++
+			sum += natural[i]
+			continue
+		}
+		left -= widths[i]
+	}
+	if auto > 0 && left < 0 {
+		panic("columns of table are wider than the table")
+	}
+	l.x = make([]float64, ncols+1)
+	for i := range widths {
+		if i >= len(t.Columns) || t.Columns[i].Points <= 0 && t.Columns[i].Percent <= 0 {
+			if sum > 0 {
+				widths[i] = left * natural[i] / sum
+			} else {
+				widths[i] = left / float64(auto)
+			}
+		}
+		l.x[i+1] = l.x[i] + widths[i]
+	}
+}
+
+// blockStart tells whether row i starts a group of rows joined by cells.
+func (l *tableLayout) blockStart(i int) bool {
+	for _, b := range l.blocks {
+		if b == i {
+			return true
+		}
+	}
+	return false
+}
+
+// rowsHeight returns the height of rows from to to, not including to.
+func (l *tableLayout) rowsHeight(from, to int) float64 {
+	h := 0.0
+	for i := from; i < to; i++ {
+		h += l.height[i]
+	}
+	return h
+}
+
+// draw returns content stream operators drawing rows from to to of l, not
+// including to, with the top left corner of row from at (x, y). font is
+// called with the names of the fonts used by the text.
+func (l *tableLayout) draw(from, to int, x, y float64, font func(string) string) string {
+	t := l.t
+	buf := bytes.NewBufferString("q\n")
+	var cells []*tableCell
+	for _, c := range l.cells {
+		if c.row >= from && c.row < to {
+			cells = append(cells, c)
+		}
+	}
+	// box returns the lower-left corner and the size of c.
+	box := func(c *tableCell) (float64, float64, float64, float64) {
+		top := y - l.rowsHeight(from, c.row)
+		h := l.rowsHeight(c.row, c.row+c.rowSpan())
+		return x + l.x[c.col], top - h, l.x[c.col+c.colSpan()] - l.x[c.col], h
+	}
+	// Backgrounds go first, so that they don't cover the borders of the
+	// cells next to them.
+	for _, c := range cells {
+		if c.Background != nil {
+			cx, cy, cw, ch := box(c)
+			fmt.Fprint(buf, colorOp(c.Background, false), " ", ftoa(cx), " ", ftoa(cy), " ",
+				ftoa(cw), " ", ftoa(ch), " re f\n")
+		}
+	}
+	for _, c := range cells {
+		cx, cy, cw, ch := box(c)
+		size, lh := c.style.size(), c.style.leading()
+		font(c.style.font())
+		for i, s := range c.lines {
+			by := baseline(cy+ch-t.Padding-float64(i+1)*lh, lh, size)
+			buf.WriteString(c.style.line(s, cx+t.Padding, by, cw-2*t.Padding, size))
+		}
+	}
+	if t.Border != nil {
+		bw := t.BorderWidth
+		if bw <= 0 {
+			bw = 1
+		}
+		fmt.Fprint(buf, colorOp(t.Border, true), " ", ftoa(bw), " w\n")
+		for _, c := range cells {
+			cx, cy, cw, ch := box(c)
+			fmt.Fprint(buf, ftoa(cx), " ", ftoa(cy), " ", ftoa(cw), " ", ftoa(ch), " re S\n")
+		}
+	}
+	buf.WriteString("Q")
+	return buf.String()
+}
+
+// DrawTable draws t on the current page, with width w and the top left
+// corner at (x, y), and returns the y of its bottom edge. Rows that would go
+// below bottom are drawn on new pages of the same size as the current one,
+// from y down, after the header rows of t. Rows joined by cells that span
+// them are kept on the same page.
+func (d *Document) DrawTable(t *Table, x, y, w, bottom float64) (end float64, err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "table drawn before any page was started")
+	}
+	if d.xbox != nil {
+		panic("DrawTable called inside an XObject")
+	}
+	l := t.layout(w)
+	head := l.rowsHeight(0, t.HeaderRows)
+	top := y // top of the rows not drawn yet
+	for k := 0; k+1 < len(l.blocks); k++ {
+		from, to := l.blocks[k], l.blocks[k+1]
+		h := l.rowsHeight(from, to)
+		if top-h < bottom {
+			if from < t.HeaderRows || y-head-h < bottom {
+				panic(fmt.Sprintf("row %d of table is too high for the page", from+1))
+			}
+			d.newPage(int(d.pg.box.urx), int(d.pg.box.ury))
+			top = y
+			if t.HeaderRows > 0 {
+				d.addc(l.draw(0, t.HeaderRows, x, top, d.pageFont))
+				top -= head
+			}
+		}
+		d.addc(l.draw(from, to, x, top, d.pageFont))
+		top -= h
+	}
+	return top, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// courier is a style whose text widths are easy to compute: each character
+// is 6 wide.
+var courier = TextStyle{Font: FontCourier, FontSize: 10}
+
+func TestTableLayout(t *testing.T) {
+	tb := &Table{
+		Columns: []ColumnWidth{{Points: 100}, {Percent: 25}, {}, {}},
+		Padding: 1,
+		Style:   courier,
+	}
+	tb.AddRow(&Cell{Text: "a"}, &Cell{Text: "b"}, &Cell{Text: "ccc"}, &Cell{Text: strings.Repeat("d", 13)})
+	tb.AddRow(&Cell{Text: "tall\ncell\nhere", RowSpan: 2}, &Cell{Text: "wide", ColSpan: 3})
+	tb.AddRow(&Cell{Text: "e"}, &Cell{Text: "f"}, &Cell{Text: "g"})
+	l := tb.layout(400)
+	if got := fmt.Sprint(l.x); got != "[0 100 200 240 400]" {
+		t.Errorf("edges of columns: got %s", got)
+	}
+	// Rows are 13.5 high, and the tall cell makes the last row it spans
+	// higher.
+	var heights []string
+	for _, h := range l.height {
+		heights = append(heights, ftoa(h))
+	}
+	if got := strings.Join(heights, " "); got != "13.5 13.5 23" {
+		t.Errorf("heights of rows: got %s", got)
+	}
+	if got := fmt.Sprint(l.blocks); got != "[0 1 3]" {
+		t.Errorf("groups of rows: got %s", got)
+	}
+	var cells []string
+	for _, c := range l.cells {
+		cells = append(cells, fmt.Sprint(c.row, ",", c.col))
+	}
+	if got := strings.Join(cells, " "); got != "0,0 0,1 0,2 0,3 1,0 1,1 2,1 2,2 2,3" {
+		t.Errorf("positions of cells: got %s", got)
+	}
+	if err := tb.AddRow(&Cell{Text: "h"}, nil); !errors.Is(err, ErrInvalid) {
+		t.Errorf("row with a nil cell: got %v", err)
+	}
+	if len(tb.rows) != 3 {
+		t.Errorf("row with a nil cell added")
+	}
+}
+
+func TestDrawTable(t *testing.T) {
+	tb := &Table{
+		Padding:    1,
+		Border:     Gray(0),
+		HeaderRows: 1,
+		Style:      courier,
+	}
+	tb.AddRow(&Cell{Text: "Name", Background: Gray(0.8)}, &Cell{Text: "Price"})
+	for i := 0; i < 30; i++ {
+		tb.AddRow(&Cell{Text: fmt.Sprint("item ", i)}, &Cell{Text: "(1.00)", Style: &TextStyle{Font: FontCourier, FontSize: 10, Align: AlignRight}})
+	}
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(200, 200)
+	end, err := d.DrawTable(tb, 10, 190, 180, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pages have the header and 12 rows, and the last one 6 rows.
+	if e := ftoa(end); e != "95.5" {
+		t.Errorf("end of table: got %s, want 95.5", e)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"/Font <<\n/Cour ",
+		"0.8 g 10 176.5 ",
+		"BT /Cour 10 Tf 0 g 11 180.45 Td (Name) Tj ET",
+		"(\\(1.00\\)) Tj",
+		"0 G 1 w\n10 176.5 ",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("%q not found in the output", s)
+		}
+	}
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := r.NumPages(); n != 3 {
+		t.Fatalf("number of pages: got %d, want 3", n)
+	}
+	for i, want := range []string{"item 0", "item 12", "item 24"} {
+		runs, err := r.PageText(i + 1)
+		if err != nil || len(runs) < 5 || runs[0].Text != "Name" || runs[2].Text != want {
+			t.Errorf("text of page %d: got %v, %v", i+1, runs, err)
+		}
+	}
+}
+
+func TestDrawTableErrors(t *testing.T) {
+	for _, tt := range []struct {
+		rows [][]*Cell
+		cols int
+		err  string
+	}{
+		{[][]*Cell{{{}, {}, {}}}, 2, "row 1 of table has more than 2 columns"},
+		{[][]*Cell{{{RowSpan: 2}}}, 0, "cell in row 1 of table spans rows it doesn't have"},
+		{[][]*Cell{{{RowSpan: 2}}, {}, {}}, 0, "cells of header rows of table span other rows"},
+		{[][]*Cell{{{}}, {{Text: strings.Repeat("x\n", 20)}}}, 0, "row 2 of table is too high for the page"},
+		{nil, 0, "table with no columns"},
+	} {
+		tb := &Table{HeaderRows: 1, Columns: make([]ColumnWidth, tt.cols)}
+		tb.rows = tt.rows
+		d, _ := New(bytes.NewBuffer(nil))
+		d.NewPage(200, 200)
+		if _, err := d.DrawTable(tb, 0, 200, 200, 0); fmt.Sprint(err) != "pdf.go: "+tt.err {
+			t.Errorf("got error %v, want %q", err, tt.err)
+		}
+	}
+}
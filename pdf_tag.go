@@ -0,0 +1,270 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the logical structure of tagged documents (p. 856). The
+// content of pages is marked with the structure elements it belongs to, like
+// headings and paragraphs, so that it can be read in the right order and
+// understood by assistive technologies.
+
+// Standard structure types (p. 899)
+const (
+	TagDocument = "Document"
+	TagPart     = "Part"
+	TagSect     = "Sect"
+	TagDiv      = "Div"
+	TagH1       = "H1"
+	TagH2       = "H2"
+	TagH3       = "H3"
+	TagH4       = "H4"
+	TagH5       = "H5"
+	TagH6       = "H6"
+	TagP        = "P"
+	TagSpan     = "Span"
+	TagFigure   = "Figure"
+	TagCaption  = "Caption"
+	TagTable    = "Table"
+	TagTR       = "TR"
+	TagTH       = "TH"
+	TagTD       = "TD"
+	TagL        = "L"
+	TagLI       = "LI"
+	TagLbl      = "Lbl"
+	TagLBody    = "LBody"
+)
+
+// tagContent maps the supported structure types to whether elements of the
+// type hold content. Other elements only group their kids.
+var tagContent = map[string]bool{
+	TagDocument: false, TagPart: false, TagSect: false, TagDiv: false,
+	TagH1: true, TagH2: true, TagH3: true, TagH4: true, TagH5: true,
+	TagH6: true, TagP: true, TagSpan: true, TagFigure: true,
+	TagCaption: true, TagTable: false, TagTR: false, TagTH: true,
+	TagTD: true, TagL: false, TagLI: false, TagLbl: true, TagLBody: true,
+}
+
+// structElem is a structure element (p. 858).
+type structElem struct {
+	ref    *indirect
+	typ    string
+	parent *structElem // nil for top-level elements
+	kids   []*structElem
+	page   int    // index of the page of the content; -1 if there's none
+	mcid   int    // marked-content ID of the content
+	alt    string // alternate description, if any
+	actual string // replacement text, if any
+}
+
+// BeginTag starts a structure element of type t, one of the Tag constants,
+// inside the element started last. Headings, paragraphs, figures, table cells
+// and other elements that hold content mark the content added to the current
+// page until EndTag is called, and should be ended on the same page. Grouping
+// elements, like TagDocument, TagTable and TagTR, can span pages.
+func (d *Document) BeginTag(t string) (err error) {
+	defer dontPanic(&err)
+
+	content, ok := tagContent[t]
+	if !ok {
+		panic("unknown structure type: " + t)
+	}
+	if d.xbox != nil {
+		panic("BeginTag called inside an XObject")
+	}
+	e := &structElem{ref: d.reserveIndirect(), typ: t, parent: d.tag, page: -1}
+	if content {
+		if d.pg == nil {
+			fail(ErrNoPage, "content tag begun before any page was started")
+		}
+		e.page = d.pg.index
+		e.mcid = len(d.pg.mcids)
+		d.pg.mcids = append(d.pg.mcids, e)
+		d.addm("/" + escapeName(t) + " " + string(output(map[string]interface{}{
+			"MCID": e.mcid,
+		})) + " BDC")
+	}
+	if d.tag != nil {
+		d.tag.kids = append(d.tag.kids, e)
+	} else {
+		d.tags = append(d.tags, e)
+	}
+	d.tag = e
+	return nil
+}
+
+// EndTag ends the structure element started last by BeginTag.
+func (d *Document) EndTag() (err error) {
+	defer dontPanic(&err)
+
+	if d.tag == nil {
+		panic("EndTag called without BeginTag")
+	}
+	if d.xbox != nil {
+		panic("EndTag called inside an XObject")
+	}
+	if d.tag.page >= 0 {
+		d.addm("EMC")
+	}
+	d.tag = d.tag.parent
+	return nil
+}
+
+// SetAlt sets the alternate description of the structure element started
+// last, which is read instead of its content by assistive technologies. It's
+// needed for figures and formulas, and for elements that are made of
+// graphics, like lines of a table.
+func (d *Document) SetAlt(alt string) (err error) {
+	defer dontPanic(&err)
+
+	if d.tag == nil {
+		panic("SetAlt called without BeginTag")
+	}
+	d.tag.alt = alt
+	return nil
+}
+
+// SetActualText sets the replacement text of the structure element started
+// last, which is exactly what its content says when the content is not text,
+// e.g. "®" for a drawn symbol. See BeginActualText for parts of the content.
+func (d *Document) SetActualText(text string) (err error) {
+	defer dontPanic(&err)
+
+	if d.tag == nil {
+		panic("SetActualText called without BeginTag")
+	}
+	d.tag.actual = text
+	return nil
+}
+
+// BeginActualText marks the content added to the current page until
+// EndActualText is called with replacement text, which is used for searching
+// and copying the content and by assistive technologies. It's meant for a
+// span of glyphs that don't map to their text one by one, like the ligature
+// "ﬁ", or decorative glyphs; empty text means the content is not text. It
+// works in documents with no tags too, and should be ended on the same page.
+func (d *Document) BeginActualText(text string) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil && d.xbox == nil {
+		fail(ErrNoPage, "BeginActualText called before any page was started")
+	}
+	d.addm("/Span " + string(output(map[string]interface{}{
+		"ActualText": textString(text),
+	})) + " BDC")
+	d.actual++
+	return nil
+}
+
+// EndActualText ends the content started by BeginActualText.
+func (d *Document) EndActualText() (err error) {
+	defer dontPanic(&err)
+
+	if d.actual == 0 {
+		panic("EndActualText called without BeginActualText")
+	}
+	d.addm("EMC")
+	d.actual--
+	return nil
+}
+
+// checkTags panics if a structure element that holds content, a span of
+// replacement text, custom marked content, a compatibility section or a text
+// object is still open on the current page.
+func (d *Document) checkTags() {
+	if d.text.open {
+		panic("text object not ended on its page")
+	}
+	if d.actual > 0 {
+		panic("ActualText not ended on its page")
+	}
+	if d.marked > 0 {
+		panic("marked content not ended on its page")
+	}
+	if d.pg.compat > 0 {
+		panic("compatibility section not ended on its page")
+	}
+	for e := d.tag; e != nil; e = e.parent {
+		if e.page >= 0 {
+			panic(e.typ + " tag not ended on its page")
+		}
+	}
+}
+
+// saveStructTree writes the structure elements and the structure tree root
+// (p. 857) to the output. The pages should be already saved.
+func (d *Document) saveStructTree() {
+	if d.tag != nil {
+		panic("document closed before EndTag was called")
+	}
+	d.stree = d.reserveIndirect()
+	for _, e := range d.tags {
+		d.saveStructElem(e)
+	}
+
+	// The parent tree maps the marked content of each page to its
+	// structure elements (p. 868).
+	nums := make([]interface{}, 0, 2*len(d.parents))
+	for i, es := range d.parents {
+		refs := make([]*indirect, len(es))
+		for j, e := range es {
+			refs[j] = e.ref
+		}
+		nums = append(nums, i, refs)
+	}
+	kids := make([]*indirect, len(d.tags))
+	for i, e := range d.tags {
+		kids[i] = e.ref
+	}
+	d.outputIndirect(d.stree, map[string]interface{}{
+		"Type":              name("StructTreeRoot"),
+		"K":                 kids,
+		"ParentTree":        map[string]interface{}{"Nums": nums},
+		"ParentTreeNextKey": len(d.parents),
+	})
+}
+
+// saveStructElem writes e and its kids to the output.
+func (d *Document) saveStructElem(e *structElem) {
+	k := make([]interface{}, 0, len(e.kids)+1)
+	if e.page >= 0 {
+		k = append(k, e.mcid)
+	}
+	for _, c := range e.kids {
+		k = append(k, c.ref)
+	}
+	dic := map[string]interface{}{
+		"Type": name("StructElem"),
+		"S":    name(e.typ),
+		"P":    d.stree,
+		"K":    k,
+	}
+	if e.parent != nil {
+		dic["P"] = e.parent.ref
+	}
+	if e.page >= 0 {
+		dic["Pg"] = d.pgs[e.page]
+	}
+	if e.alt != "" {
+		dic["Alt"] = textString(e.alt)
+	}
+	if e.actual != "" {
+		dic["ActualText"] = textString(e.actual)
+	}
+	d.outputIndirect(e.ref, dic)
+	for _, c := range e.kids {
+		d.saveStructElem(c)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.BeginTag(TagDocument)
+	d.NewPage(100, 100)
+	d.BeginTag(TagH1)
+	d.Rectangle(10, 80, 80, 10)
+	d.Fill()
+	d.EndTag()
+	d.BeginTag(TagTable)
+	d.BeginTag(TagTR)
+	for i := 0; i < 2; i++ {
+		d.BeginTag(TagTD)
+		d.Rectangle(10+40*i, 50, 30, 10)
+		d.Stroke()
+		d.EndTag()
+	}
+	d.EndTag()
+	d.EndTag()
+	d.NewPage(100, 100)
+	d.NewPage(100, 100)
+	d.BeginTag(TagP)
+	d.Rectangle(10, 10, 80, 80)
+	d.Stroke()
+	if err := d.EndTag(); err != nil {
+		t.Fatal(err)
+	}
+	d.EndTag()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.String()
+	for _, s := range []string{
+		"/H1 <<\n/MCID 0\n>> BDC\n10 80 80 10 re\n", "/TD <<\n/MCID 2\n>> BDC\n",
+		"/P <<\n/MCID 0\n>> BDC\n", "EMC\n",
+		"/StructParents 0\n", "/StructParents 1\n",
+		"/MarkInfo <<\n/Marked true\n>>", "/Type /StructTreeRoot",
+		"/ParentTreeNextKey 2", "/S /Document", "/S /TR",
+	} {
+		if !strings.Contains(b, s) {
+			t.Errorf("tagged document doesn't have %q", s)
+		}
+	}
+	if strings.Contains(b, "/Table <<") {
+		t.Error("grouping element has marked content")
+	}
+	if n := strings.Count(b, "/Type /StructElem"); n != 7 {
+		t.Errorf("number of structure elements: got %d expected 7", n)
+	}
+}
+
+func TestTagErrors(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	if err := d.BeginTag("Heading"); err == nil {
+		t.Error("unknown structure type")
+	}
+	if err := d.EndTag(); err == nil {
+		t.Error("EndTag without BeginTag")
+	}
+	if err := d.BeginTag(TagP); err == nil {
+		t.Error("content tag begun before any page")
+	}
+	p, _ := d.NewPage(100, 100)
+	d.BeginTag(TagP)
+	if err := p.Flush(); err == nil {
+		t.Error("page ended inside a paragraph")
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.BeginTag(TagDocument)
+	if err := d.Close(); err == nil {
+		t.Error("document closed with open tag")
+	}
+}
+
+func TestTaggedPDFUA(t *testing.T) {
+	d, _ := New(bytes.NewBuffer(nil))
+	d.SetPDFUA("en")
+	d.SetInfo(&Info{Title: "Accessible"})
+	d.NewPage(100, 100)
+	d.BeginTag(TagDocument)
+	d.BeginTag(TagP)
+	d.Rectangle(10, 10, 80, 80)
+	d.Stroke()
+	d.EndTag()
+	d.EndTag()
+	if err := d.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAltText(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.SetAlt("nothing"); err == nil {
+		t.Error("SetAlt without BeginTag")
+	}
+	if err := d.BeginActualText("fi"); err == nil {
+		t.Error("BeginActualText before any page")
+	}
+	d.NewPage(100, 100)
+	d.BeginTag(TagFigure)
+	d.SetAlt("Logo of Café")
+	d.Rectangle(10, 10, 20, 20)
+	d.Fill()
+	d.EndTag()
+	d.BeginTag(TagP)
+	d.SetActualText("®")
+	d.EndTag()
+	d.BeginActualText("fi")
+	d.Rectangle(50, 50, 10, 10)
+	d.Fill()
+	d.EndActualText()
+	if err := d.EndActualText(); err == nil {
+		t.Error("EndActualText without BeginActualText")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.String()
+	for _, s := range []string{
+		"/Alt (" + textString("Logo of Café") + ")", "/ActualText (\xfe\xff\x00\xae)",
+		"/Span <<\n/ActualText (fi)\n>> BDC\n50 50 10 10 re\n", "EMC\n",
+	} {
+		if !strings.Contains(b, s) {
+			t.Errorf("tagged document doesn't have %q", s)
+		}
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.NewPage(100, 100)
+	d.BeginActualText("")
+	if err := d.Close(); err == nil {
+		t.Error("ActualText not ended on its page")
+	}
+}
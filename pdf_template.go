@@ -0,0 +1,151 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file makes documents from layouts with texts that are templates of
+// package template, like the letters of a mail merge.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"text/template"
+)
+
+// Template is a layout of blocks of a flow, which is rendered with
+// different data to make similar documents. The texts of its paragraphs and
+// of the cells of its tables are templates of package template, like
+// "Dear {{.Name}},".
+type Template struct {
+	w, h   int
+	m      Margins
+	blocks []func(data interface{}) Block // blocks with their templates executed with data
+}
+
+// NewTemplate returns an empty template for flows with pages of width w
+// and height h, and content inside margins m.
+func NewTemplate(w, h int, m Margins) *Template {
+	return &Template{w: w, h: h, m: m}
+}
+
+// Add adds b below the blocks already added to t. Paragraphs and tables are
+// copied, so changing them after they are added doesn't change t.
+func (t *Template) Add(b Block) (err error) {
+	defer dontPanic(&err)
+
+	var f func(data interface{}) Block
+	switch b := b.(type) {
+	case *Paragraph:
+		p, tx := *b, parseTemplate(b.Text)
+		f = func(data interface{}) Block {
+			p := p
+			p.Text = executeTemplate(tx, data)
+			return &p
+		}
+	case *Table:
+		tb := *b
+		txs := make([][]*template.Template, len(b.rows))
+		for i, row := range b.rows {
+			for _, c := range row {
+				txs[i] = append(txs[i], parseTemplate(c.Text))
+			}
+		}
+		f = func(data interface{}) Block {
+			tb := tb
+			tb.rows = make([][]*Cell, len(txs))
+			for i, row := range b.rows {
+				for j, c := range row {
+					c := *c
+					c.Text = executeTemplate(txs[i][j], data)
+					tb.rows[i] = append(tb.rows[i], &c)
+				}
+			}
+			return &tb
+		}
+	default:
+		f = func(interface{}) Block { return b }
+	}
+	t.blocks = append(t.blocks, f)
+	return nil
+}
+
+// Render adds the blocks of t to d in a new flow, with the templates
+// executed with data, starting from a new page. It can be called many times,
+// with the same document or others, like once for each letter of a mail
+// merge.
+func (t *Template) Render(d *Document, data interface{}) (err error) {
+	defer dontPanic(&err)
+
+	t.render(d, data)
+	return nil
+}
+
+// RenderAll renders t to d once for each element of the slice data, like a
+// batch of letters. It stops when ctx is done, with an error of kind
+// ErrCanceled.
+func (t *Template) RenderAll(ctx context.Context, d *Document, data interface{}) (err error) {
+	defer dontPanic(&err)
+
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("template rendered with %T which is not a slice", data))
+	}
+	// Pages are written to the output while rendering, and they're
+	// stopped by ctx too.
+	prev := d.ctx
+	d.ctx = ctx
+	defer func() {
+		d.ctx = prev
+	}()
+	for i := 0; i < v.Len(); i++ {
+		checkContext(ctx)
+		t.render(d, v.Index(i).Interface())
+	}
+	return nil
+}
+
+// render adds the blocks of t to d in a new flow, with the templates
+// executed with data.
+func (t *Template) render(d *Document, data interface{}) {
+	f, err := d.NewFlow(t.w, t.h, t.m)
+	check(err)
+	for _, b := range t.blocks {
+		check(f.Add(b(data)))
+	}
+	// Blocks kept with the next one and the rest of footnotes are drawn
+	// now, before the content of other flows.
+	f.finish()
+}
+
+// parseTemplate returns the template with text s.
+func parseTemplate(s string) *template.Template {
+	tx, err := template.New("text").Parse(s)
+	if err != nil {
+		panic("bad template: " + fmt.Sprint(err))
+	}
+	return tx
+}
+
+// executeTemplate returns the text made by tx with data.
+func executeTemplate(tx *template.Template, data interface{}) string {
+	buf := bytes.NewBuffer(nil)
+	if err := tx.Execute(buf, data); err != nil {
+		panic("can't execute template: " + fmt.Sprint(err))
+	}
+	return buf.String()
+}
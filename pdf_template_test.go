@@ -0,0 +1,107 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type letter struct {
+	Name  string
+	Total int
+}
+
+func TestTemplate(t *testing.T) {
+	tm := NewTemplate(200, 200, Margins{20, 20, 20, 20})
+	p := &Paragraph{Text: "Dear {{.Name}},", Style: courier, KeepWithNext: true}
+	tb := &Table{Style: courier}
+	tb.AddRow(&Cell{Text: "Total"}, &Cell{Text: "{{.Total}}"})
+	for _, b := range []Block{p, Spacer(10), tb} {
+		if err := tm.Add(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Changing the blocks after they are added doesn't change the
+	// template.
+	p.Text = "changed"
+
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	for _, l := range []letter{{"Ann", 10}, {"Bob", 20}} {
+		if err := tm.Render(d, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tm.Render(d, 5); err == nil {
+		t.Error("no error for data with no fields")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"Dear Ann,|Total|10", "Dear Bob,|Total|20"} {
+		runs, _ := r.PageText(i + 1)
+		var got []string
+		for _, run := range runs {
+			got = append(got, run.Text)
+		}
+		if g := strings.Join(got, "|"); g != want {
+			t.Errorf("text of page %d: got %q, want %q", i+1, g, want)
+		}
+	}
+
+	err = tm.Add(&Paragraph{Text: "{{.Name"})
+	if err == nil || !strings.HasPrefix(fmt.Sprint(err), "pdf.go: bad template: ") {
+		t.Errorf("bad template: got error %v", err)
+	}
+}
+
+func TestTemplateRenderAll(t *testing.T) {
+	tm := NewTemplate(200, 200, Margins{20, 20, 20, 20})
+	tm.Add(&Paragraph{Text: "Dear {{.Name}},", Style: courier})
+	letters := []letter{{"Ann", 10}, {"Bob", 20}, {"Cy", 30}}
+
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := tm.RenderAll(context.Background(), d, letters); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.RenderAll(context.Background(), d, letters[0]); err == nil {
+		t.Error("no error for data which is not a slice")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := tm.RenderAll(ctx, d, letters)
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled rendering: got error %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewReader(buf.Bytes())
+	if n, _ := r.NumPages(); n != 3 {
+		t.Errorf("pages: got %d, want 3", n)
+	}
+}
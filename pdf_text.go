@@ -0,0 +1,155 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file lets text be put on pages operator by operator, in the standard
+// fonts, for labels of drawings and simple reports. See TextStyle and Flow
+// for text that's laid out.
+
+import (
+	"fmt"
+)
+
+// textObject holds the text object and the font of a page, as set by the
+// text methods of Document.
+type textObject struct {
+	open   bool    // whether a text object is open
+	font   string  // name of the font, or empty if it's not set
+	size   float64 // font size
+	lx, ly float64 // start of the current line
+	x, y   float64 // where the next text is shown
+}
+
+// outsideText panics if d has an open text object, in which c can't be
+// drawn. Only text, and the colors and graphics state it's shown with, can
+// be inside text objects (p. 405).
+func (d *Document) outsideText(c string) {
+	if d.text.open {
+		panic(c + " drawn inside a text object")
+	}
+}
+
+// BeginText begins a text object on the current page, which is ended by
+// EndText. SetTextPosition and ShowText are called inside text objects, and
+// the position of the text is at the origin when they begin. Paths,
+// components and XObjects can't be drawn inside them, but colors, the
+// graphics state and marked content can be changed.
+func (d *Document) BeginText() (err error) {
+	defer dontPanic(&err)
+
+	if d.text.open {
+		panic("BeginText called inside a text object")
+	}
+	d.canDraw("text")
+	d.addw(d.ops().BeginText())
+	d.text.open = true
+	d.text.lx, d.text.ly, d.text.x, d.text.y = 0, 0, 0, 0
+	return nil
+}
+
+// EndText ends the text object begun by BeginText.
+func (d *Document) EndText() (err error) {
+	defer dontPanic(&err)
+
+	if !d.text.open {
+		panic("EndText called without BeginText")
+	}
+	d.addw(d.ops().EndText())
+	d.text.open = false
+	return nil
+}
+
+// SetFont changes the font of the text shown after it to one of the standard
+// fonts, like FontTimes or FontSymbol, or to a font added by AddFont, in the
+// given size. The font is added to the resources of the page; standard fonts
+// are not embedded. It stays the same on the page until it's changed again,
+// even between text objects.
+func (d *Document) SetFont(font string, size float64) (err error) {
+	defer dontPanic(&err)
+
+	if !d.text.open {
+		d.canDraw("text")
+	}
+	tt, embedded := d.sfnts[font]
+	if _, ok := stdFonts[font]; !ok && !embedded {
+		panic("unknown font: " + font)
+	}
+	if size <= 0 {
+		panic(fmt.Sprint("font size is not positive: ", size))
+	}
+	if embedded {
+		d.useResource("Font", font, tt.ref)
+	} else {
+		d.pageFont(font)
+	}
+	d.addw(d.ops().Font(font, size))
+	d.text.font, d.text.size = font, size
+	return nil
+}
+
+// SetTextPosition moves the start of the next line of text by (x, y) from the
+// start of the current line, or from the origin if it's the first line of the
+// text object, like the Td operator.
+func (d *Document) SetTextPosition(x, y float64) (err error) {
+	defer dontPanic(&err)
+
+	if !d.text.open {
+		panic("SetTextPosition called without BeginText")
+	}
+	d.addw(d.ops().TextPosition(x, y))
+	d.text.lx += x
+	d.text.ly += y
+	d.text.x, d.text.y = d.text.lx, d.text.ly
+	return nil
+}
+
+// ShowText shows the UTF-8 string s at the current position of the text, in
+// the font set by SetFont, and moves the position to the end of s.
+// Characters that the standard fonts don't have are shown as question marks,
+// and the ones that fonts added by AddFont don't have by their glyph 0.
+// In FontSymbol and FontZapfDingbats, the runes of s are the codes of their
+// own encodings, like 'a' for α in Symbol.
+func (d *Document) ShowText(s string) (err error) {
+	defer dontPanic(&err)
+
+	if !d.text.open {
+		panic("ShowText called without BeginText")
+	}
+	if d.text.font == "" {
+		panic("ShowText called without SetFont")
+	}
+	size := d.text.size
+	var t string
+	var tw float64
+	if tt, ok := d.sfnts[d.text.font]; ok {
+		t, tw = d.glyphCodes(tt, s, size)
+	} else {
+		f := stdFonts[d.text.font]
+		if f.symbolic {
+			t = d.encodeCodes(s, f)
+		} else {
+			t = d.encodeText(s)
+		}
+		tw = f.width(t, size)
+	}
+	w := d.ops().ShowText(t)
+	// Like tracker.text, which is for TextStyle.
+	w.t.paint(d.text.x, d.text.y-0.22*size, tw, size)
+	d.addw(w)
+	d.text.x += tw
+	return nil
+}
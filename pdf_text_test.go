@@ -0,0 +1,177 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	if err := d.BeginText(); !errors.Is(err, ErrNoPage) {
+		t.Errorf("text with no page: got %v expected ErrNoPage", err)
+	}
+	d.NewPage(200, 100)
+	steps := []func() error{
+		d.BeginText,
+		func() error { return d.SetFont(FontHelvetica, 12) },
+		func() error { return d.SetTextPosition(10, 80) },
+		func() error { return d.ShowText("Total: ") },
+		func() error { return d.ShowText("5 €") },
+		func() error { return d.SetTextPosition(0, -14) },
+		func() error { return d.SetFont(FontCourier, 10) },
+		func() error { return d.ShowText("(note)") },
+		d.EndText,
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+	}
+	for i, err := range []error{
+		d.EndText(),
+		d.ShowText("x"),
+		d.SetTextPosition(1, 1),
+		d.SetFont("Times", 12),
+		d.SetFont(FontCourier, 0),
+	} {
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%d: got %v expected ErrInvalid", i, err)
+		}
+	}
+	g := &Grid{}
+	g.Add(&GridItem{Background: Gray(0.5)})
+	d.BeginText()
+	for _, c := range []struct {
+		err  error
+		want string
+	}{
+		{d.BeginText(), "BeginText called inside a text object"},
+		{d.DrawSlug(Slug{}, 0, 0), "slug drawn inside a text object"},
+		{d.Rectangle(0, 0, 10, 10), "path drawn inside a text object"},
+		{d.MoveTo(0, 0), "path drawn inside a text object"},
+		{d.Stroke(), "path drawn inside a text object"},
+		{d.DrawGrid(g, 0, 0, 10, 10), "graphics drawn inside a text object"},
+	} {
+		if !errors.Is(c.err, ErrInvalid) || !strings.Contains(c.err.Error(), c.want) {
+			t.Errorf("got %v expected %q", c.err, c.want)
+		}
+	}
+	// The graphics state and marked content can change inside text objects.
+	if err := d.LineWidth(2); err != nil {
+		t.Error(err)
+	}
+	if err := d.BeginMarkedContent("Em", nil); err != nil {
+		t.Error(err)
+	}
+	d.EndMarkedContent()
+	d.EndText()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+
+	r, _ := NewReader(buf.Bytes())
+	con := string(r.contents(r.pages()[0].dic["Contents"]))
+	want := "BT\n/Helv 12 Tf\n10 80 Td\n(Total: ) Tj\n(5 \x80) Tj\n0 -14 Td\n/Cour 10 Tf\n(\\(note\\)) Tj\nET\n"
+	if !strings.HasPrefix(con, want) {
+		t.Errorf("content: got %q expected %q", con, want)
+	}
+	runs, err := r.PageText(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text []string
+	for _, run := range runs {
+		text = append(text, run.Text)
+	}
+	if s := strings.Join(text, "|"); !strings.Contains(s, "Total:") || !strings.Contains(s, "(note)") {
+		t.Errorf("text: got %q", s)
+	}
+
+	// Text objects end on their pages, and fonts are set on each page.
+	d, _ = New(bytes.NewBuffer(nil))
+	d.NewPage(100, 100)
+	d.BeginText()
+	if err := d.Close(); err == nil || !strings.Contains(err.Error(), "text object not ended") {
+		t.Errorf("got %v for text object not ended", err)
+	}
+	d, _ = New(bytes.NewBuffer(nil))
+	d.NewPage(100, 100)
+	d.SetFont(FontHelvetica, 12)
+	d.NewPage(100, 100)
+	d.BeginText()
+	if err := d.ShowText("x"); err == nil || !strings.Contains(err.Error(), "without SetFont") {
+		t.Errorf("got %v for text with the font of another page", err)
+	}
+}
+
+func TestStandardFonts(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(300, 300)
+	d.BeginText()
+	for n := range stdFonts {
+		if err := d.SetFont(n, 10); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.ShowText("a4"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.SetFont(FontSymbol, 10)
+	d.ShowText("α")
+	d.EndText()
+	if r := recovered(func() { (&TextStyle{Font: FontTimesItalic}).font() }); r != nil {
+		t.Errorf("styled Times-Italic: %v", r)
+	}
+	if r := recovered(func() { (&TextStyle{Font: FontSymbol}).font() }); r == nil {
+		t.Error("styled Symbol: no error")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+
+	r, _ := NewReader(buf.Bytes())
+	res := r.resolve(r.pages()[0].dic["Resources"]).(map[string]interface{})
+	fonts := r.resolve(res["Font"]).(map[string]interface{})
+	if len(fonts) != len(stdFonts) {
+		t.Errorf("got %d fonts expected %d", len(fonts), len(stdFonts))
+	}
+	for n, f := range stdFonts {
+		dic, _ := r.resolve(fonts[n]).(map[string]interface{})
+		if dic["BaseFont"] != name(f.base) || dic["Subtype"] != name("Type1") {
+			t.Errorf("%s: got %v", n, dic)
+		}
+		if _, ok := dic["Encoding"]; ok == f.symbolic {
+			t.Errorf("%s: encoding %v", n, dic["Encoding"])
+		}
+	}
+	con := string(r.contents(r.pages()[0].dic["Contents"]))
+	if !strings.Contains(con, "/Symb 10 Tf\n( ) Tj\n") {
+		t.Errorf("α in Symbol: got %q", con)
+	}
+}
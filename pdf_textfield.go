@@ -0,0 +1,209 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains text fields (p. 686) and check boxes (p. 682).
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Flags of text fields
+const (
+	textMultiline = 1 << 12
+	textPassword  = 1 << 13
+	textComb      = 1 << 24
+)
+
+// TextField describes a field in which the user types text.
+type TextField struct {
+	Name   string // name of the field, which should be unique
+	Value  string // text in the field
+	MaxLen int    // maximum number of characters; no limit if zero
+
+	// Multiline lets the text to have more than one line. Lines are broken
+	// to fit the width of the field.
+	Multiline bool
+	// Password hides the characters of the text.
+	Password bool
+	// Comb divides the field into MaxLen equal boxes, one for each
+	// character.
+	Comb bool
+
+	FieldStyle
+	FieldScripts
+}
+
+// TextBox adds a text field with lower-left corner at (x, y), width w, and
+// height h to the current page.
+func (d *Document) TextBox(x, y, w, h float64, f *TextField) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "field added before any page was started")
+	}
+	if f.Name == "" {
+		fail(ErrBadName, "text field with no name")
+	}
+	n := utf8.RuneCountInString(f.Value)
+	if f.MaxLen > 0 && n > f.MaxLen {
+		panic("value of text field is longer than its MaxLen")
+	}
+	flags := 0
+	if f.Multiline {
+		flags |= textMultiline
+	}
+	if f.Password {
+		flags |= textPassword
+	}
+	if f.Comb {
+		if f.MaxLen <= 0 || f.Multiline || f.Password {
+			panic("comb text field needs MaxLen and can't be multiline or password")
+		}
+		flags |= textComb
+	}
+	dict := map[string]interface{}{
+		"FT":   name("Tx"),
+		"T":    f.Name,
+		"Ff":   flags,
+		"Rect": newRect(x, y, x+w, y+h),
+	}
+	if f.Value != "" {
+		dict["V"] = textString(f.Value)
+	}
+	if f.MaxLen > 0 {
+		dict["MaxLen"] = f.MaxLen
+	}
+	f.set(dict, Gray(1))
+
+	v := d.encodeText(f.Value)
+	var c string
+	switch {
+	case f.Password:
+		c = f.variableText(w, h, []string{strings.Repeat("*", n)}, false, Gray(1))
+	case f.Comb:
+		c = f.comb(w, h, v)
+	case f.Multiline:
+		width := func(s string) float64 { return f.width(s, f.size()) }
+		c = f.variableText(w, h, wrapText(v, w-4, width), true, Gray(1))
+	default:
+		c = f.variableText(w, h, []string{v}, false, Gray(1))
+	}
+	dict["AP"] = map[string]interface{}{
+		"N": d.appearance(w, h, c, f.font(), nil),
+	}
+	d.addScripted(dict, &f.FieldScripts)
+	return nil
+}
+
+// comb returns the appearance of comb field f of size w×h that shows the
+// WinAnsi encoded string v.
+func (f *TextField) comb(w, h float64, v string) string {
+	size := f.size()
+	if size > h-4 {
+		size = h - 4
+	}
+	cw := w / float64(f.MaxLen)
+	buf := bytes.NewBufferString(f.box(w, h, Gray(1)))
+	// Lines between the boxes
+	buf.WriteString(colorOp(f.border(), true) + "\n")
+	for i := 1; i < f.MaxLen; i++ {
+		buf.WriteString(fmt.Sprint(ftoa(cw*float64(i)), " 0 m ",
+			ftoa(cw*float64(i)), " ", ftoa(h), " l S\n"))
+	}
+	buf.WriteString(fieldClip(w, h))
+	for i := 0; i < len(v); i++ {
+		c := v[i : i+1]
+		x := cw*float64(i) + (cw-f.width(c, size))/2
+		buf.WriteString(f.text(c, x, baseline(0, h, size), size))
+	}
+	buf.WriteString("Q EMC\n")
+	return buf.String()
+}
+
+// CheckField describes a check box. The check mark is drawn with the color
+// and size of the style, and the font and alignment of the style are ignored.
+type CheckField struct {
+	Name    string // name of the field, which should be unique
+	Value   string // value of the field when checked; "Yes" if empty
+	Checked bool
+
+	FieldStyle
+}
+
+// CheckBox adds a check box with lower-left corner at (x, y), width w, and
+// height h to the current page.
+func (d *Document) CheckBox(x, y, w, h float64, f *CheckField) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "field added before any page was started")
+	}
+	if f.Name == "" {
+		panic("check box with no name")
+	}
+	on := f.Value
+	if on == "" {
+		on = "Yes"
+	}
+	if on == "Off" {
+		panic("value of check box can't be Off")
+	}
+	state := name("Off")
+	if f.Checked {
+		state = name(on)
+	}
+	size := f.FontSize
+	if size <= 0 {
+		size = h * 0.8
+		if w < h {
+			size = w * 0.8
+		}
+	}
+
+	dict := map[string]interface{}{
+		"FT":   name("Btn"),
+		"T":    f.Name,
+		"Rect": newRect(x, y, x+w, y+h),
+		"V":    state,
+		"AS":   state,
+	}
+	st := f.FieldStyle
+	st.Align = AlignLeft
+	st.set(dict, Gray(1))
+	dict["DA"] = "/" + fieldZapfDingbats + " " + ftoa(size) + " Tf " +
+		colorOp(f.color(), false)
+	dict["MK"].(map[string]interface{})["CA"] = "4"
+
+	// The check mark is character 4 of ZapfDingbats, which is 0.76 of the
+	// font size wide.
+	off := f.box(w, h, Gray(1))
+	check := fmt.Sprint("q BT /", fieldZapfDingbats, " ", ftoa(size), " Tf ",
+		colorOp(f.color(), false), " ", ftoa((w-0.76*size)/2), " ",
+		ftoa((h-0.7*size)/2), " Td (4) Tj ET Q\n")
+	dict["AP"] = map[string]interface{}{
+		"N": map[string]interface{}{
+			on:    d.appearance(w, h, off+check, fieldZapfDingbats, nil),
+			"Off": d.appearance(w, h, off, "", nil),
+		},
+	}
+	d.addField(dict)
+	return nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTextFields(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, err := New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(300, 300)
+	fields := []*TextField{
+		{Name: "name", Value: "Ali (Jr.)",
+			FieldStyle: FieldStyle{Align: AlignRight, Color: RGB{0, 0, 1}}},
+		{Name: "pin", Value: "1234", Password: true},
+		{Name: "zip", Value: "12", MaxLen: 5, Comb: true,
+			FieldStyle: FieldStyle{Font: FieldCourier}},
+		{Name: "notes", Value: "first line\nsecond", Multiline: true},
+	}
+	for i, f := range fields {
+		if err = d.TextBox(10, float64(10+40*i), 100, 30, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = d.TextBox(10, 10, 100, 30, &TextField{Name: "x", Comb: true}); err == nil {
+		t.Errorf("comb field with no MaxLen: expected error")
+	}
+	if err = d.CheckBox(200, 10, 20, 20, &CheckField{Name: "agree", Checked: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.CheckBox(200, 40, 20, 20, &CheckField{Name: "opt", Value: "Sure"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"/DA (/Helv 12 Tf 0 0 1 rg)",
+		"/Q 2",
+		"(Ali \\(Jr.\\)) Tj",
+		"(****) Tj",
+		"/DA (/Cour 12 Tf 0 g)",
+		"/MaxLen 5",
+		"(second) Tj",
+		"/V /Yes",
+		"/AS /Off",
+		"/Sure ",
+		"/ZaDb ",
+		"/BaseFont /ZapfDingbats",
+		"/BaseFont /Courier",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("document: %q not found", s)
+		}
+	}
+}
+
+func TestTextFieldUnicode(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, _ := New(buf)
+	d.NewPage(300, 300)
+	if err := d.TextBox(10, 10, 100, 30, &TextField{Name: "adresse.straße", Value: "Müller"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewReader(buf.Bytes())
+	values, err := r.FieldValues()
+	if err != nil || values["adresse.straße"] != "Müller" {
+		t.Errorf("values: got %v, %v", values, err)
+	}
+	x := new(bytes.Buffer)
+	if err := r.WriteXFDF(x, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(x.String(), "Müller") {
+		t.Errorf("XFDF: got %s", x)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file draws text as the outlines of its glyphs, which are filled like
+// other paths, so that the font isn't needed in the document. It's for logos,
+// and for fonts whose licenses don't allow them to be embedded.
+
+import (
+	"fmt"
+)
+
+// OutlineFont is a TrueType font whose glyphs are drawn as paths by
+// DrawTextOutlines.
+type OutlineFont struct {
+	f       *sfnt
+	lookups []int // lookups of GSUB of the features used
+}
+
+// NewOutlineFont returns the TrueType or OpenType font in the font file b.
+// Fonts with outlines in CFF instead of TrueType are not supported.
+func NewOutlineFont(b []byte) (f *OutlineFont, err error) {
+	defer dontPanic(&err)
+
+	sf := parseSfnt(b)
+	for _, t := range []string{"cmap", "glyf", "loca", "hhea", "hmtx"} {
+		sf.table(t, 0)
+	}
+	return &OutlineFont{f: sf}, nil
+}
+
+// Features returns the tags of the OpenType features of f that can be used
+// by WithFeatures, like "liga" and "smcp".
+func (f *OutlineFont) Features() (tags []string, err error) {
+	defer dontPanic(&err)
+
+	return f.f.gsubFeatures(), nil
+}
+
+// WithFeatures returns f with the OpenType features with the given tags
+// used, for runs of text that need them, like "liga" for ligatures, "smcp"
+// for small capitals, "onum" for oldstyle numerals, "tnum" for tabular
+// numerals and "frac" for fractions. Features that f doesn't have are left
+// out; Features returns the ones it has. Only features that substitute
+// glyphs are supported.
+func (f *OutlineFont) WithFeatures(tags ...string) (g *OutlineFont, err error) {
+	defer dontPanic(&err)
+
+	return &OutlineFont{f.f, f.f.gsubLookups(tags)}, nil
+}
+
+// glyphs returns the glyphs of the UTF-8 string s in f, and the characters
+// of s that f doesn't have, whose glyphs are .notdef.
+func (f *OutlineFont) glyphs(s string) (gs []int, missing []rune) {
+	for _, r := range s {
+		g := f.f.glyphIndex(r)
+		if g == 0 {
+			missing = append(missing, r)
+		}
+		gs = append(gs, g)
+	}
+	if len(f.lookups) > 0 {
+		gs = f.f.substitute(gs, f.lookups)
+	}
+	return gs, missing
+}
+
+// Width returns the width of the UTF-8 string s drawn with f in the given
+// size.
+func (f *OutlineFont) Width(s string, size float64) (w float64, err error) {
+	defer dontPanic(&err)
+
+	u := 0
+	gs, _ := f.glyphs(s)
+	for _, g := range gs {
+		u += f.f.advance(g)
+	}
+	return float64(u) * size / float64(f.f.unitsPerEm), nil
+}
+
+// DrawTextOutlines draws the UTF-8 string s with font f in the given size and
+// color, with the baseline starting at (x, y). The glyphs are filled paths
+// instead of text, so viewers can't select or search them, and s is their
+// replacement text, like BeginActualText sets, for copying them and for
+// assistive technologies. Characters f doesn't have are drawn with its
+// .notdef glyph, unless d fails on missing glyphs.
+func (d *Document) DrawTextOutlines(f *OutlineFont, s string, x, y, size float64, c Color) (err error) {
+	defer dontPanic(&err)
+
+	if f == nil {
+		panic("DrawTextOutlines called with nil font")
+	}
+	d.outsideText("text outlines")
+	if size <= 0 {
+		panic(fmt.Sprint("bad font size: ", size))
+	}
+	if c == nil {
+		c = Gray(0)
+	}
+	gs, missing := f.glyphs(s)
+	d.noGlyphs(missing)
+	k := size / float64(f.f.unitsPerEm)
+	w := d.ops()
+	w.Name("Span").Write(append(output(map[string]interface{}{"ActualText": textString(s)}), ' '))
+	w.Op("BDC")
+	w.SaveState().FillColor(c)
+	for _, g := range gs {
+		f.f.glyphPath(w, g, matrix{k, 0, 0, k, x, y}, 0)
+		x += float64(f.f.advance(g)) * k
+	}
+	w.Fill().RestoreState().Op("EMC")
+	d.addw(w)
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testOutlineFont returns a TrueType font with 1000 units per em, where "A"
+// is glyph 1, a square 600 units wide, and "B" is glyph 2, a curve 400 units
+// wide. Glyph 0 is empty and 500 units wide.
+func testOutlineFont() []byte {
+	square := be(int16(1), int16(0), int16(0), int16(100), int16(100), uint16(3), uint16(0),
+		[]byte{1, 1, 1, 1}, int16(0), int16(100), int16(0), int16(-100),
+		int16(0), int16(0), int16(100), int16(0))
+	curve := be(int16(1), int16(0), int16(0), int16(100), int16(100), uint16(2), uint16(0),
+		[]byte{1, 0, 1}, int16(0), int16(50), int16(50), int16(0), int16(0), int16(100))
+	glyf := append(append([]byte{}, square...), curve...)
+	return writeSfnt(map[string][]byte{
+		"head": be(make([]byte, 18), uint16(1000), make([]byte, 30), uint16(1), uint16(0)),
+		"maxp": be(uint32(0x5000), uint16(3)),
+		"hhea": be(make([]byte, 34), uint16(3)),
+		"hmtx": be(uint16(500), int16(0), uint16(600), int16(0), uint16(400), int16(0)),
+		"cmap": be(uint16(0), uint16(1), uint16(3), uint16(10), uint32(12),
+			uint16(12), uint16(0), uint32(28), uint32(0), uint32(1), uint32('A'), uint32('B'), uint32(1)),
+		"loca": be(uint32(0), uint32(0), uint32(len(square)), uint32(len(glyf))),
+		"glyf": glyf,
+	})
+}
+
+func TestDrawTextOutlines(t *testing.T) {
+	f, err := NewOutlineFont(testOutlineFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, err := f.Width("ABC", 10); w != 15 || err != nil {
+		t.Errorf("width: got %v, %v, want 15", w, err)
+	}
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(100, 100)
+	if err := d.DrawTextOutlines(f, "ABC", 10, 20, 10, RGB{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewReader(buf.Bytes())
+	c := string(r.contents(r.pages()[0].dic["Contents"]))
+	want := "/Span <<\n/ActualText (ABC)\n>> BDC\nq\n1 0 0 rg\n" +
+		"10 20 m\n11 20 l\n11 21 l\n10 21 l\nh\n" +
+		"16 20 m\n16.333 20 16.667 20.333 17 21 c\nh\nf\nQ\nEMC\n"
+	if !strings.Contains(c, want) {
+		t.Errorf("content: got %q, want %q", c, want)
+	}
+	if runs, _ := r.PageText(1); len(runs) > 0 {
+		t.Errorf("text of outlines: got %v", runs)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	d.NewPage(100, 100)
+	for _, c := range []struct {
+		f    *OutlineFont
+		size float64
+	}{
+		{nil, 10},
+		{f, 0},
+	} {
+		if err := d.DrawTextOutlines(c.f, "A", 0, 0, c.size, nil); !errors.Is(err, ErrInvalid) {
+			t.Errorf("font %v of size %v: got %v", c.f, c.size, err)
+		}
+	}
+	if _, err := NewOutlineFont(testVariableFont()); !errors.Is(err, ErrBadFile) {
+		t.Errorf("font with no cmap: got %v", err)
+	}
+}
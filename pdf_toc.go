@@ -0,0 +1,332 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file keeps the headings of documents, and makes the table of contents
+// and the bookmarks (outline, p. 584) of documents from them.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// spot is a place on a page of the document, which can be linked to.
+type spot struct {
+	page int       // index of the page in pgs of the document
+	ref  *indirect // the page
+	y    float64
+}
+
+// spot returns the spot at y on the current page of d.
+func (d *Document) spot(y float64) spot {
+	return spot{d.pg.index, d.pg.ref, y}
+}
+
+// dest returns a destination (p. 582) that shows the page of s with s at the
+// top of the window.
+func (s *spot) dest() []interface{} {
+	return []interface{}{s.ref, name("FitH"), s.y}
+}
+
+// pageNumber returns the number of the page with index i in pgs of d, as
+// it's shown in the document: pages of the table of contents before it are
+// counted too.
+func (d *Document) pageNumber(i int) string {
+	n := i + 1
+	if p := d.tocPlan; p != nil && i >= p.pos {
+		n += p.pages
+	}
+	return fmt.Sprint(n)
+}
+
+// heading is a heading of the document.
+type heading struct {
+	text  string
+	level int           // from 1
+	spot                // top of the heading
+	to    []interface{} // destination of a bookmark, which is only in the outline
+}
+
+// AddHeading adds a heading of the given level, from 1 for the top level,
+// with its top at y on the current page. Headings are listed in the table of
+// contents and the bookmarks of the document. Paragraphs of flows with
+// Heading set are added as headings when they are drawn.
+func (d *Document) AddHeading(text string, level int, y float64) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil {
+		fail(ErrNoPage, "heading added before any page was started")
+	}
+	if level < 1 {
+		panic(fmt.Sprintf("bad level of heading: %d", level))
+	}
+	d.headings = append(d.headings, &heading{text: text, level: level, spot: d.spot(y)})
+	return nil
+}
+
+// TableOfContents is a list of the headings of a document and their page
+// numbers, with dot leaders between them. Its entries link to the headings.
+type TableOfContents struct {
+	Title      string    // drawn above the entries if it's not empty
+	TitleStyle TextStyle // style of the title
+	Style      TextStyle // style of the entries
+	Indent     float64   // indentation of each level of headings below the top
+	MaxLevel   int       // lowest level of headings listed; all of them if zero
+
+	// Pages of the table of contents go before page Page, counted from 1,
+	// or after the last page if it's zero. Page numbers of the entries
+	// count them.
+	Page int
+	// Size of the pages of the table; the size of the last page of the
+	// document if zero.
+	Width, Height int
+	Margins       Margins
+}
+
+// SetTableOfContents makes d have table of contents t, made from its
+// headings when d is closed.
+func (d *Document) SetTableOfContents(t *TableOfContents) (err error) {
+	defer dontPanic(&err)
+
+	if t.Page < 0 {
+		panic(fmt.Sprintf("bad page of table of contents: %d", t.Page))
+	}
+	d.toc = t
+	return nil
+}
+
+// tocEntry is a heading laid out in the table of contents.
+type tocEntry struct {
+	*heading
+	indent float64
+	lines  []string // WinAnsi encoded lines of the text
+}
+
+// tocPlan is the layout of the table of contents of a document, which is
+// made before the table is drawn, so that page numbers can count its pages.
+type tocPlan struct {
+	w, h    int // size of pages
+	entries []*tocEntry
+	pages   int // number of pages of the table
+	pos     int // index of the page the table goes before
+}
+
+// pageBox returns the size of pages of width w and height h, or the size of
+// the current page of d if they are zero, and the room inside margins m.
+func (d *Document) pageBox(w, h int, m Margins, what string) (pw, ph int, left, right, top, bottom float64) {
+	if w <= 0 || h <= 0 {
+		if d.pg == nil {
+			panic(what + " of a document with no pages")
+		}
+		w, h = int(d.pg.box.urx), int(d.pg.box.ury)
+	}
+	left, right = m.Left, float64(w)-m.Right
+	top, bottom = float64(h)-m.Top, m.Bottom
+	if right <= left || top <= bottom {
+		panic("margins of " + what + " are larger than its pages")
+	}
+	return w, h, left, right, top, bottom
+}
+
+// planTOC lays out the table of contents of d, which is drawn by makeTOC.
+func (d *Document) planTOC() {
+	t := d.toc
+	st := &t.Style
+	size, lh := st.size(), st.leading()
+	w, h, left, right, top, bottom := d.pageBox(t.Width, t.Height, t.Margins, "table of contents")
+	// Page numbers go in a column as wide as four digits.
+	gap, numW := st.width(" ", size), st.width("0000", size)
+	if right-left-numW-gap <= 0 {
+		panic("margins of table of contents are larger than its pages")
+	}
+
+	var entries []*tocEntry
+	for _, hd := range d.headings {
+		if hd.to != nil || t.MaxLevel > 0 && hd.level > t.MaxLevel {
+			continue
+		}
+		in := t.Indent * float64(hd.level-1)
+		entries = append(entries, &tocEntry{hd, in, st.wrap(hd.text, right-left-in-numW-gap)})
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	// Entries are not broken across pages, and the number of pages is
+	// known before they are drawn, so that the page numbers count them.
+	ts := &t.TitleStyle
+	titleH := 0.0
+	if t.Title != "" {
+		titleH = float64(len(ts.wrap(t.Title, right-left)))*ts.leading() + lh
+	}
+	pages, y := 1, top-titleH
+	for _, e := range entries {
+		eh := float64(len(e.lines)) * lh
+		if y-eh < bottom && y < top {
+			pages++
+			y = top
+		}
+		y -= eh
+	}
+	pos := len(d.pgs)
+	if t.Page > 0 && t.Page-1 < pos {
+		pos = t.Page - 1
+	}
+	d.tocPlan = &tocPlan{w, h, entries, pages, pos}
+}
+
+// makeTOC draws the table of contents of d on new pages at the end of the
+// document. They are moved to their place by pageOrder.
+func (d *Document) makeTOC() {
+	p := d.tocPlan
+	if p == nil {
+		return
+	}
+	t := d.toc
+	st, ts := &t.Style, &t.TitleStyle
+	size, lh := st.size(), st.leading()
+	w, h, left, right, top, bottom := d.pageBox(p.w, p.h, t.Margins, "table of contents")
+	gap := st.width(" ", size)
+
+	d.tocStart = d.newPage(w, h).index
+	y := top
+	if t.Title != "" {
+		d.pageFont(ts.font())
+		for _, l := range ts.wrap(t.Title, right-left) {
+			d.addc(strings.TrimRight(ts.line(l, left, baseline(y-ts.leading(), ts.leading(), ts.size()), right-left, ts.size()), "\n"))
+			y -= ts.leading()
+		}
+		y -= lh
+	}
+	for _, e := range p.entries {
+		eh := float64(len(e.lines)) * lh
+		if y-eh < bottom && y < top {
+			d.newPage(w, h)
+			y = top
+		}
+		d.pageFont(st.font())
+		x := left + e.indent
+		by := 0.0
+		for i, l := range e.lines {
+			by = baseline(y-float64(i+1)*lh, lh, size)
+			d.addc(strings.TrimRight(st.text(l, x, by, size), "\n"))
+		}
+		n := d.pageNumber(e.page)
+		nx := right - st.width(n, size)
+		end := x + st.width(e.lines[len(e.lines)-1], size)
+		dw := st.width(" .", size)
+		if dots := int((nx - gap - end) / dw); dots > 0 {
+			d.addc(strings.TrimRight(st.text(strings.Repeat(" .", dots), nx-gap-float64(dots)*dw, by, size), "\n"))
+		}
+		d.addc(strings.TrimRight(st.text(n, nx, by, size), "\n"))
+		d.addAnnot(map[string]interface{}{
+			"Subtype": name("Link"),
+			"Rect":    newRect(x, y-eh, right, y),
+			"Border":  []int{0, 0, 0},
+			"F":       4, // Print, which PDF/A needs
+			"Dest":    e.dest(),
+		})
+		y -= eh
+	}
+}
+
+// pageOrder returns the pages of d in the order they are in the document,
+// with the pages of the table of contents moved to their place.
+func (d *Document) pageOrder() []*indirect {
+	p := d.tocPlan
+	if p == nil || d.tocStart == 0 || p.pos >= d.tocStart {
+		return d.pgs
+	}
+	pos := p.pos
+	pgs := make([]*indirect, 0, len(d.pgs))
+	pgs = append(pgs, d.pgs[:pos]...)
+	pgs = append(pgs, d.pgs[d.tocStart:]...)
+	return append(pgs, d.pgs[pos:d.tocStart]...)
+}
+
+// outlineItem is an item of the outline of the document.
+type outlineItem struct {
+	ref  *indirect
+	hd   *heading // nil for the outline dictionary
+	kids []*outlineItem
+}
+
+// count returns the number of items under it, which are all open.
+func (it *outlineItem) count() int {
+	n := len(it.kids)
+	for _, k := range it.kids {
+		n += k.count()
+	}
+	return n
+}
+
+// headingOutlines writes the outline of d made from its headings to the
+// output. Headings are under the nearest heading above them with a higher
+// level.
+func (d *Document) headingOutlines() {
+	root := &outlineItem{ref: d.reserveIndirect()}
+	stack := []*outlineItem{root}
+	for _, hd := range d.headings {
+		for len(stack) > 1 && stack[len(stack)-1].hd.level >= hd.level {
+			stack = stack[:len(stack)-1]
+		}
+		it := &outlineItem{ref: d.reserveIndirect(), hd: hd}
+		par := stack[len(stack)-1]
+		par.kids = append(par.kids, it)
+		stack = append(stack, it)
+	}
+	d.outlines = root.ref
+	d.outputIndirect(root.ref, map[string]interface{}{
+		"Type":  name("Outlines"),
+		"First": root.kids[0].ref,
+		"Last":  root.kids[len(root.kids)-1].ref,
+		"Count": root.count(),
+	})
+	d.outputOutlineItems(root)
+}
+
+// outlineDest returns the destination of the outline item of hd.
+func (hd *heading) outlineDest() []interface{} {
+	if hd.to != nil {
+		return hd.to
+	}
+	return hd.dest()
+}
+
+// outputOutlineItems writes the items under it to the output.
+func (d *Document) outputOutlineItems(it *outlineItem) {
+	for i, k := range it.kids {
+		m := map[string]interface{}{
+			"Title":  textString(k.hd.text),
+			"Parent": it.ref,
+			"Dest":   k.hd.outlineDest(),
+		}
+		if i > 0 {
+			m["Prev"] = it.kids[i-1].ref
+		}
+		if i < len(it.kids)-1 {
+			m["Next"] = it.kids[i+1].ref
+		}
+		if len(k.kids) > 0 {
+			m["First"] = k.kids[0].ref
+			m["Last"] = k.kids[len(k.kids)-1].ref
+			m["Count"] = k.count()
+		}
+		d.outputIndirect(k.ref, m)
+		d.outputOutlineItems(k)
+	}
+}
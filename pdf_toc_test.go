@@ -0,0 +1,87 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTableOfContents(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	f, _ := d.NewFlow(200, 200, Margins{20, 20, 20, 20})
+	err := d.SetTableOfContents(&TableOfContents{Title: "Contents", Style: courier,
+		TitleStyle: courier, Indent: 10, Page: 1, Margins: Margins{20, 20, 20, 20}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range []Block{
+		&Paragraph{Text: "One", Heading: 1, Style: courier},
+		&Paragraph{Text: "Two", Heading: 2, Style: courier},
+		lines(20),
+		&Paragraph{Text: "Three", Heading: 1, Style: courier},
+	} {
+		if err := f.Add(b); err != nil {
+			t.Fatalf("%d. %s", i, err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"/Title (One)",
+		"/Count 3",
+		"/FitH 180 ]",
+		"/Subtype /Link",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("%q not found in the output", s)
+		}
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := r.NumPages(); n != 3 {
+		t.Fatalf("number of pages: got %d, want 3", n)
+	}
+	runs, _ := r.PageText(1)
+	var got []string
+	for _, run := range runs {
+		got = append(got, fmt.Sprint(ftoa(run.X), " ", ftoa(run.Y), " ", strings.TrimSpace(run.Text)))
+	}
+	want := "20 171.45 Contents|" +
+		"20 148.45 One|48 148.45 . . . . . . . . . .|174 148.45 2|" +
+		"30 136.95 Two|48 136.95 . . . . . . . . . .|174 136.95 2|" +
+		"20 125.45 Three|60 125.45 . . . . . . . . .|174 125.45 3"
+	if g := strings.Join(got, "|"); g != want {
+		t.Errorf("table of contents: got %q, want %q", g, want)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	if err := d.AddHeading("x", 1, 0); err == nil {
+		t.Error("no error for a heading added before any page")
+	}
+	d.NewPage(100, 100)
+	if err := d.AddHeading("x", 0, 0); fmt.Sprint(err) != "pdf.go: bad level of heading: 0" {
+		t.Errorf("heading of level 0: got error %v", err)
+	}
+}
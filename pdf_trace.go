@@ -0,0 +1,109 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains tracing of documents, for debugging what they write:
+// content streams get comments with the code that drew their content, and
+// the objects written are logged with their offsets.
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// pkgPath is what the names of the functions of the package start with in
+// stack traces. It's the import path of the package, with the dots of its
+// last element escaped, like "pdf%2ego".
+var pkgPath = strings.TrimSuffix(runtime.FuncForPC(reflect.ValueOf(describe).Pointer()).Name(), ".describe")
+
+// traceContent writes a comment to the current content stream of d, or to
+// s, with the line of code that draws the content after it, like
+// "% source:main.go:12 Rectangle", if d is traced.
+func (d *Document) traceContent(s *spill) {
+	if d.opts.Trace == nil {
+		return
+	}
+	file, line, fn := caller()
+	_, err := fmt.Fprintf(s, "%% source:%s:%d %s\n", file, line, fn)
+	checkWrite(err)
+}
+
+// traceObject logs that object i with value o is written at the current
+// offset of the output, if d is traced.
+func (d *Document) traceObject(i *indirect, o interface{}) {
+	if d.opts.Trace == nil {
+		return
+	}
+	_, err := fmt.Fprintf(d.opts.Trace, "%d 0 obj at %d: %s\n", i.num, i.off, describe(o))
+	checkWrite(err)
+}
+
+// caller returns the file name and line of the first caller of the package
+// outside it, and the name of the function of the package it called. Tests
+// of the package count as outside.
+func caller() (file string, line int, fn string) {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPath+".") || strings.HasSuffix(f.File, "_test.go") {
+			return filepath.Base(f.File), f.Line, fn
+		}
+		// Methods are named like "(*Document).Rectangle", and closures
+		// like "(*Page).Draw.func1".
+		parts := strings.Split(f.Function[len(pkgPath)+1:], ".")
+		fn = parts[0]
+		if strings.HasPrefix(fn, "(") && len(parts) > 1 {
+			fn = parts[1]
+		}
+		if !more {
+			return "?", 0, fn
+		}
+	}
+}
+
+// describe returns the kind of object o, with its type and subtype if it's
+// a dictionary or stream that has them, like "dictionary /Page".
+func describe(o interface{}) string {
+	if ob, ok := o.(objecter); ok {
+		o = ob.object()
+	}
+	var dic map[string]interface{}
+	kind := ""
+	switch v := o.(type) {
+	case *stream:
+		dic, kind = v.dic, "stream"
+	case *Stream:
+		dic, kind = v.Dict, "stream"
+	case map[string]interface{}:
+		dic, kind = v, "dictionary"
+	default:
+		if k := reflect.ValueOf(o).Kind(); k == reflect.Slice || k == reflect.Array {
+			return "array"
+		}
+		return fmt.Sprintf("%T", o)
+	}
+	for _, k := range []string{"Type", "Subtype"} {
+		if n, ok := dic[k]; ok {
+			kind += " " + string(output(n))
+		}
+	}
+	return kind
+}
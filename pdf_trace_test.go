@@ -0,0 +1,84 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	out, log := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	d, _ := NewBuffered(out, Options{Trace: log})
+	p, _ := d.NewPage(100, 100)
+	d.Rectangle(10, 10, 20, 20)
+	_, _, line, _ := callerLine()
+	d.DrawPageCount(10, 50, courier)
+	p.Raw("f")
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := NewReader(out.Bytes())
+	con := string(r.contents(r.pages()[0].dic["Contents"]))
+	for _, want := range []string{
+		fmt.Sprintf("%% source:pdf_trace_test.go:%d Rectangle\n10 10 20 20 re\n", line-1),
+		fmt.Sprintf("%% source:pdf_trace_test.go:%d DrawPageCount\n", line+1),
+		fmt.Sprintf("%% source:pdf_trace_test.go:%d Raw\nf\n", line+2),
+	} {
+		if !strings.Contains(con, want) {
+			t.Errorf("content %q has no %q", con, want)
+		}
+	}
+	// Page counts are still filled in.
+	runs, _ := r.PageText(1)
+	if len(runs) != 1 || strings.TrimSpace(runs[0].Text) != "1" {
+		t.Errorf("page count: got %v", runs)
+	}
+
+	// Each object logged is at its offset.
+	lines := regexp.MustCompile(`(?m)^(\d+) 0 obj at (\d+): (.*)$`).FindAllStringSubmatch(log.String(), -1)
+	if len(lines) != len(d.objs) {
+		t.Errorf("%d objects logged instead of %d:\n%s", len(lines), len(d.objs), log)
+	}
+	kinds := map[string]bool{}
+	for _, l := range lines {
+		off, _ := strconv.Atoi(l[2])
+		if !bytes.HasPrefix(out.Bytes()[off:], []byte(l[1]+" 0 obj\n")) {
+			t.Errorf("object %s is not at %d", l[1], off)
+		}
+		kinds[l[3]] = true
+	}
+	for _, k := range []string{"dictionary /Page", "dictionary /Catalog", "stream"} {
+		if !kinds[k] {
+			t.Errorf("no %q in log:\n%s", k, log)
+		}
+	}
+	if probs := Validate(out.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+}
+
+// callerLine returns the file and line of its caller.
+func callerLine() (pc uintptr, file string, line int, ok bool) {
+	return runtime.Caller(1)
+}
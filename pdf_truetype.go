@@ -0,0 +1,277 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file embeds TrueType and OpenType fonts in documents, as the CIDFonts
+// of Type 0 fonts (p. 433) whose codes are the indices of glyphs, or their
+// CIDs in CID-keyed CFF fonts, so that text can be shown in any language.
+// Fonts are written when documents are closed, and only the glyphs shown are
+// in the programs of TrueType fonts.
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// sfntFont is a TrueType or OpenType font embedded in a document.
+type sfntFont struct {
+	f    *sfnt
+	cff  *cff         // outlines in CFF, or nil if they're in TrueType
+	file []byte       // font file, embedded whole if the outlines are in CFF
+	ref  *indirect    // Type 0 font dictionary, written when the document is closed
+	base string       // PostScript name
+	used map[int]rune // glyphs shown, and the characters they're shown for
+}
+
+// AddFont embeds the TrueType or OpenType font in the font file b in d, and
+// returns its name, to be used by SetFont like the standard fonts.
+// Characters are mapped to glyphs by the Unicode cmap of the font.
+//
+// Fonts with TrueType outlines are subset: only the glyphs of the text shown
+// with them are embedded, when the document is closed, so that fonts with
+// many glyphs, like the ones of CJK, don't make documents large. Their names
+// in the document have a tag of the subset before them, like
+// "KQWXZB+NotoSansJP-Regular".
+//
+// OpenType fonts with outlines in CFF, which are usually .otf files, are
+// embedded whole, and need PDF 1.6. The widths of their glyphs are read from
+// their charstrings.
+func (d *Document) AddFont(b []byte) (font string, err error) {
+	defer dontPanic(&err)
+
+	d.checkClosed()
+	f := parseSfnt(b)
+	t := &sfntFont{f: f, file: b, base: f.postScriptName(), used: make(map[int]rune)}
+	if c, ok := f.tables["CFF "]; ok {
+		if d.version < "1.6" {
+			panic("OpenType fonts need PDF 1.6")
+		}
+		t.cff = parseCFF(c)
+		if len(t.cff.glyphs) != f.numGlyphs {
+			fail(ErrBadFile, "CFF font with a different number of glyphs")
+		}
+	} else if _, ok := f.tables["glyf"]; !ok {
+		fail(ErrBadFile, "font with no TrueType or CFF outlines")
+	}
+	f.table("cmap", 4)
+	f.table("hhea", 36)
+	t.ref = d.reserveIndirect()
+	t.ref.part = partFonts
+	if d.sfnts == nil {
+		d.sfnts = make(map[string]*sfntFont)
+	}
+	font = fmt.Sprint("TT", len(d.sfnts)+1)
+	d.sfnts[font] = t
+	return font, nil
+}
+
+// advance returns the advance width of glyph g of t, in units of the font.
+func (t *sfntFont) advance(g int) float64 {
+	if t.cff != nil {
+		return t.cff.width(g)
+	}
+	return float64(t.f.advance(g))
+}
+
+// code returns the code of glyph g of t in the text shown with it, which is
+// its CID. It's the index of the glyph, unless t is a CID-keyed CFF font.
+func (t *sfntFont) code(g int) int {
+	if t.cff != nil {
+		return t.cff.cid(g)
+	}
+	return g
+}
+
+// glyphCodes returns the codes of the glyphs of the UTF-8 string s in t, in
+// two bytes, and the width of s in the given size. Characters t doesn't have
+// are noted like by encodeText, and shown by glyph 0.
+func (d *Document) glyphCodes(t *sfntFont, s string, size float64) (string, float64) {
+	b := make([]byte, 0, 2*len(s))
+	var missing []rune
+	w := 0.0
+	d.resMu.Lock()
+	for _, r := range s {
+		g := t.f.glyphIndex(r)
+		if g == 0 {
+			missing = append(missing, r)
+		} else if _, ok := t.used[g]; !ok {
+			t.used[g] = r
+		}
+		c := t.code(g)
+		b = append(b, byte(c>>8), byte(c))
+		w += t.advance(g)
+	}
+	d.resMu.Unlock()
+	d.noGlyphs(missing)
+	return string(b), w * size / float64(t.f.unitsPerEm)
+}
+
+// saveFonts writes the TrueType and OpenType fonts of d to the output.
+func (d *Document) saveFonts() {
+	names := make([]string, 0, len(d.sfnts))
+	for n := range d.sfnts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		d.sfnts[n].save(d)
+	}
+}
+
+// save writes t to the output of d, with only the glyphs used if it has
+// TrueType outlines.
+func (t *sfntFont) save(d *Document) {
+	gs := []int{0}
+	for g := range t.used {
+		gs = append(gs, g)
+	}
+	sort.Ints(gs)
+
+	var base, font0 string
+	var file map[string]interface{}
+	cid := map[string]interface{}{
+		"Type": name("Font"),
+		"CIDSystemInfo": map[string]interface{}{
+			"Registry":   "Adobe",
+			"Ordering":   "Identity",
+			"Supplement": 0,
+		},
+	}
+	if t.cff != nil {
+		// The name of Type 0 fonts of CFF CIDFonts has the name of
+		// their CMap after it (p. 434).
+		base = t.base
+		font0 = base + "-Identity-H"
+		file = map[string]interface{}{"Subtype": name("OpenType")}
+		cid["Subtype"] = name("CIDFontType0")
+	} else {
+		base = subsetTag(t.base, gs) + "+" + t.base
+		font0 = base
+		file = map[string]interface{}{"Length1": 0}
+		cid["Subtype"] = name("CIDFontType2")
+		cid["CIDToGIDMap"] = name("Identity")
+	}
+	prog := t.file
+	if t.cff == nil {
+		prog = t.f.subset(gs)
+		file["Length1"] = len(prog)
+	}
+	file["Filter"] = name("FlateDecode")
+	ff := d.partIndirect(partFonts, &stream{file, flateEncode(prog)})
+	cid["BaseFont"] = name(base)
+	cid["FontDescriptor"] = d.partIndirect(partFonts, t.descriptor(base, ff))
+	cid["W"] = t.widths(gs)
+
+	codes, chars := make([]int, 0, len(gs)), make([]rune, 0, len(gs))
+	for _, g := range gs[1:] {
+		codes = append(codes, t.code(g))
+		chars = append(chars, t.used[g])
+	}
+	d.outputIndirect(t.ref, map[string]interface{}{
+		"Type":            name("Font"),
+		"Subtype":         name("Type0"),
+		"BaseFont":        name(font0),
+		"Encoding":        name("Identity-H"),
+		"DescendantFonts": []*indirect{d.partIndirect(partFonts, cid)},
+		"ToUnicode":       d.partIndirect(partFonts, &stream{nil, []byte(toUnicodeCMap(2, codes, chars))}),
+	})
+}
+
+// scale returns v, in units of the font of t, in thousandths of the font
+// size.
+func (t *sfntFont) scale(v float64) int {
+	return int(math.Round(v * 1000 / float64(t.f.unitsPerEm)))
+}
+
+// widths returns the W array of the CIDFont of t (p. 441), with the widths of
+// the sorted glyphs gs. Glyphs with consecutive codes share an array of
+// widths.
+func (t *sfntFont) widths(gs []int) []interface{} {
+	codes := make([]int, len(gs))
+	for i, g := range gs {
+		codes[i] = t.code(g)
+	}
+	sort.Sort(byCode{gs, codes})
+	var w []interface{}
+	for i := 0; i < len(gs); {
+		j := i + 1
+		for j < len(gs) && codes[j] == codes[j-1]+1 {
+			j++
+		}
+		ws := make([]int, 0, j-i)
+		for _, g := range gs[i:j] {
+			ws = append(ws, t.scale(t.advance(g)))
+		}
+		w = append(w, codes[i], ws)
+		i = j
+	}
+	return w
+}
+
+// byCode sorts glyphs by their codes.
+type byCode struct {
+	gs, codes []int
+}
+
+func (b byCode) Len() int           { return len(b.gs) }
+func (b byCode) Less(i, j int) bool { return b.codes[i] < b.codes[j] }
+func (b byCode) Swap(i, j int) {
+	b.gs[i], b.gs[j] = b.gs[j], b.gs[i]
+	b.codes[i], b.codes[j] = b.codes[j], b.codes[i]
+}
+
+// descriptor returns the font descriptor (p. 455) of t, with name base and
+// font program file.
+func (t *sfntFont) descriptor(base string, file *indirect) map[string]interface{} {
+	head, hhea := t.f.table("head", 54), t.f.table("hhea", 36)
+	s := func(b []byte, off int) int {
+		return t.scale(float64(int16(u16(b, off))))
+	}
+	ascent := s(hhea, 4)
+	capHeight := ascent
+	if os2 := t.f.tables["OS/2"]; len(os2) >= 90 && u16(os2, 0) >= 2 {
+		capHeight = s(os2, 88)
+	}
+	flags := 4 // symbolic, since the glyphs are not in a standard encoding
+	angle := 0.0
+	if post := t.f.tables["post"]; len(post) >= 16 {
+		angle = fixed(post, 4)
+		if u32(post, 12) != 0 {
+			flags |= 1 // fixed pitch
+		}
+	}
+	if angle != 0 {
+		flags |= 64 // italic
+	}
+	prog := "FontFile2"
+	if t.cff != nil {
+		prog = "FontFile3"
+	}
+	return map[string]interface{}{
+		"Type":        name("FontDescriptor"),
+		"FontName":    name(base),
+		"Flags":       flags,
+		"FontBBox":    []int{s(head, 36), s(head, 38), s(head, 40), s(head, 42)},
+		"ItalicAngle": angle,
+		"Ascent":      ascent,
+		"Descent":     s(hhea, 6),
+		"CapHeight":   capHeight,
+		"StemV":       80,
+		prog:          file,
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAddFont(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	font, err := d.AddFont(testCompositeFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(200, 100)
+	d.BeginText()
+	if err := d.SetFont(font, 10); err != nil {
+		t.Fatal(err)
+	}
+	d.SetTextPosition(10, 50)
+	if err := d.ShowText("CAX"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ShowText("A"); err != nil {
+		t.Fatal(err)
+	}
+	d.EndText()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+
+	r, _ := NewReader(buf.Bytes())
+	con := string(r.contents(r.pages()[0].dic["Contents"]))
+	if want := "/TT1 10 Tf\n10 50 Td\n(\x00\x03\x00\x01\x00\x00) Tj\n(\x00\x01) Tj\n"; !strings.Contains(con, want) {
+		t.Errorf("content: got %q expected %q", con, want)
+	}
+	runs, _ := r.PageText(1)
+	var text []string
+	for _, run := range runs {
+		text = append(text, run.Text)
+	}
+	if s := strings.Join(text, ""); !strings.HasPrefix(s, "CA") {
+		t.Errorf("text: got %q", s)
+	}
+
+	res := r.resolve(r.pages()[0].dic["Resources"]).(map[string]interface{})
+	f := r.resolve(r.resolve(res["Font"]).(map[string]interface{})[font]).(map[string]interface{})
+	base := string(f["BaseFont"].(name))
+	if !regexp.MustCompile(`^[A-Z]{6}\+Test1$`).MatchString(base) || f["Encoding"] != name("Identity-H") {
+		t.Errorf("font: got %v", f)
+	}
+	cid := r.resolve(r.resolve(f["DescendantFonts"]).([]interface{})[0]).(map[string]interface{})
+	if w := string(output(r.resolve(cid["W"]))); w != "[ 0 [ 500 600 ] 3 [ 700 ] ]" {
+		t.Errorf("widths: got %s", w)
+	}
+	desc := r.resolve(cid["FontDescriptor"]).(map[string]interface{})
+	if desc["FontName"] != name(base) {
+		t.Errorf("font descriptor: got %v", desc)
+	}
+	s := r.resolve(desc["FontFile2"]).(*stream)
+	prog := parseSfnt(r.decode(s))
+	if n := len(prog.glyphData(2)); n != 0 {
+		t.Errorf("glyph 2 not shown, but it has %d bytes", n)
+	}
+	if len(prog.glyphData(1)) == 0 || len(prog.glyphData(3)) == 0 {
+		t.Error("glyphs shown are not in the subset")
+	}
+	if s.dic["Length1"] != len(r.decode(s)) {
+		t.Errorf("Length1: got %v", s.dic["Length1"])
+	}
+	if d.Sizes().Fonts == 0 {
+		t.Error("fonts not counted in sizes")
+	}
+
+	d, _ = New(bytes.NewBuffer(nil))
+	for _, b := range [][]byte{
+		[]byte("not a font"),
+		writeSfnt(map[string][]byte{
+			"head": be(make([]byte, 18), uint16(1000), make([]byte, 34)),
+			"maxp": be(uint32(0x5000), uint16(1)),
+		}),
+	} {
+		if _, err := d.AddFont(b); !errors.Is(err, ErrBadFile) {
+			t.Errorf("bad font: got %v", err)
+		}
+	}
+}
+
+// testOpenTypeFont returns an OpenType font with the glyphs of testCFF, in a
+// CID-keyed font whose glyphs 1 and 2 have CIDs 10 and 20, and are "A" and
+// "B".
+func testOpenTypeFont() []byte {
+	tables := map[string][]byte{}
+	for t, b := range parseSfnt(testCompositeFont()).tables {
+		tables[t] = b
+	}
+	delete(tables, "glyf")
+	delete(tables, "loca")
+	tables["CFF "] = testCFF(be(uint8(0), uint16(10), uint16(20)))
+	tables["maxp"] = be(uint32(0x5000), uint16(3))
+	tables["hhea"] = be(make([]byte, 34), uint16(3))
+	tables["hmtx"] = tables["hmtx"][:12]
+	tables["cmap"] = be(uint16(0), uint16(1), uint16(3), uint16(10), uint32(12),
+		uint16(12), uint16(0), uint32(28), uint32(0), uint32(1), uint32('A'), uint32('B'), uint32(1))
+	b := writeSfnt(tables)
+	copy(b, "OTTO")
+	return b
+}
+
+func TestAddOpenTypeFont(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	otf := testOpenTypeFont()
+	font, err := d.AddFont(otf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.NewPage(200, 100)
+	d.BeginText()
+	d.SetFont(font, 10)
+	d.SetTextPosition(10, 50)
+	if err := d.ShowText("BA"); err != nil {
+		t.Fatal(err)
+	}
+	if d.text.x != 20 {
+		t.Errorf("text position after the text: got %v, expected 20", d.text.x)
+	}
+	d.EndText()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+
+	r, _ := NewReader(buf.Bytes())
+	con := string(r.contents(r.pages()[0].dic["Contents"]))
+	if want := "(\x00\x14\x00\x0a) Tj\n"; !strings.Contains(con, want) {
+		t.Errorf("content: got %q expected %q", con, want)
+	}
+	runs, _ := r.PageText(1)
+	var text []string
+	for _, run := range runs {
+		text = append(text, run.Text)
+	}
+	if s := strings.Join(text, ""); s != "BA" {
+		t.Errorf("text: got %q", s)
+	}
+
+	res := r.resolve(r.pages()[0].dic["Resources"]).(map[string]interface{})
+	f := r.resolve(r.resolve(res["Font"]).(map[string]interface{})[font]).(map[string]interface{})
+	if f["BaseFont"] != name("Test1-Identity-H") {
+		t.Errorf("font: got %v", f)
+	}
+	cid := r.resolve(r.resolve(f["DescendantFonts"]).([]interface{})[0]).(map[string]interface{})
+	if cid["Subtype"] != name("CIDFontType0") || cid["BaseFont"] != name("Test1") || cid["CIDToGIDMap"] != nil {
+		t.Errorf("CIDFont: got %v", cid)
+	}
+	if w := string(output(r.resolve(cid["W"]))); w != "[ 0 [ 450 ] 10 [ 600 ] 20 [ 400 ] ]" {
+		t.Errorf("widths: got %s", w)
+	}
+	desc := r.resolve(cid["FontDescriptor"]).(map[string]interface{})
+	s := r.resolve(desc["FontFile3"]).(*stream)
+	if s.dic["Subtype"] != name("OpenType") || !bytes.Equal(r.decode(s), otf) {
+		t.Errorf("font program: got %v", s.dic)
+	}
+
+	d, _ = New(bytes.NewBuffer(nil), Options{Version: "1.5"})
+	if _, err := d.AddFont(otf); !errors.Is(err, ErrInvalid) {
+		t.Errorf("PDF 1.5: got %v", err)
+	}
+}
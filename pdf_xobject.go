@@ -0,0 +1,189 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains form XObjects for type Document. A form XObject is a
+// piece of content that can be drawn many times, or used as the appearance of
+// form fields (p. 355).
+
+import (
+	"fmt"
+)
+
+// XObject is a form or image XObject which is already written to the
+// output.
+type XObject struct {
+	ref    *indirect
+	w, h   float64
+	image  bool // images are drawn in the unit square (p. 341)
+	orient int  // EXIF orientation of JPEG images, or 0
+}
+
+// Size returns the width and height of x.
+func (x *XObject) Size() (w, h float64) {
+	return x.w, x.h
+}
+
+// BeginXObject starts a new form XObject of width w and height h. Content
+// added after it, with functions like LineTo and Stroke, goes to the XObject
+// instead of the current page until EndXObject is called.
+func (d *Document) BeginXObject(w, h float64) (err error) {
+	defer dontPanic(&err)
+
+	if d.xbox != nil {
+		panic("BeginXObject called inside another XObject")
+	}
+	d.outsideText("XObject")
+	d.xbox = newRect(0, 0, w, h)
+	d.xres, d.xaf = nil, nil
+	d.pcon, d.ptrack = d.con, d.cw.t
+	d.stateChanged()
+	d.con, d.cw.t = d.newSpill(), tracker{}
+	return nil
+}
+
+// EndXObject finishes the XObject started by BeginXObject, writes it to the
+// output, and returns it. Content added after it goes to the current page
+// again.
+func (d *Document) EndXObject() (x *XObject, err error) {
+	defer dontPanic(&err)
+
+	if d.xbox == nil {
+		panic("EndXObject called without BeginXObject")
+	}
+	i := d.reserveIndirect()
+	dic := map[string]interface{}{
+		"Type":      name("XObject"),
+		"Subtype":   name("Form"),
+		"BBox":      d.xbox,
+		"Resources": d.xres.dict(),
+	}
+	if len(d.xaf) > 0 {
+		dic["AF"] = d.xaf
+	}
+	d.outputContent(i, dic, d.con)
+	x = &XObject{ref: i, w: d.xbox.urx, h: d.xbox.ury}
+	d.con, d.cw.t = d.pcon, d.ptrack
+	d.stateChanged()
+	d.pcon, d.ptrack = nil, tracker{}
+	d.xbox, d.xres, d.xaf = nil, nil, nil
+	return x, nil
+}
+
+// DrawXObject draws x on the current page, scaled to width w and height h,
+// with its lower-left corner at (px, py).
+func (d *Document) DrawXObject(x *XObject, px, py, w, h float64) (err error) {
+	defer dontPanic(&err)
+
+	if d.pg == nil && d.xbox == nil {
+		fail(ErrNoPage, "XObject drawn before any page was started")
+	}
+	d.outsideText("XObject")
+	d.useResource("XObject", x.name(), x.ref)
+	d.stateChanged()
+	d.addw(x.draw(d.ops(), px, py, w, h))
+	d.cw.t.paint(px, py, w, h)
+	return nil
+}
+
+// name returns the name of x in the resources of pages.
+func (x *XObject) name() string {
+	if x.image {
+		return resName("Im", x.ref)
+	}
+	return resName("Fm", x.ref)
+}
+
+// draw writes the operators drawing x scaled to width w and height h, with
+// its lower-left corner at (px, py), to cw, all in one line.
+func (x *XObject) draw(cw *ContentWriter, px, py, w, h float64) *ContentWriter {
+	sx, sy := w/x.w, h/x.h
+	if x.image {
+		sx, sy = w, h
+	}
+	m := matrix{sx, 0, 0, sy, px, py}
+	if x.orient > 1 {
+		m = exifOrientations[x.orient].mul(m)
+	}
+	cw.b = append(cw.b, "q "...)
+	cw.points(m[:]...)
+	cw.b = append(cw.b, "cm "...)
+	return cw.Name(x.name()).Op("Do Q")
+}
+
+// ImportPage makes a form XObject of page n of r, so that it can be drawn on
+// the pages of the document, e.g. to put new content on a preprinted form.
+// Pages are numbered from 1. The size of the XObject is the size of the
+// page as viewers show it, after it's cropped and rotated. Annotations of the
+// page are not imported.
+func (d *Document) ImportPage(r *Reader, n int) (x *XObject, err error) {
+	defer dontPanic(&err)
+
+	pgs := r.pages()
+	if n < 1 || n > len(pgs) {
+		panic(fmt.Sprintf("page %d of %d pages imported", n, len(pgs)))
+	}
+	p := pgs[n-1].dic
+	if d.copiers == nil {
+		d.copiers = make(map[*Reader]*copier)
+	}
+	c, ok := d.copiers[r]
+	if !ok {
+		c = newCopier(d, r)
+		d.copiers[r] = c
+	}
+
+	box := r.pageBox(p)
+
+	// The content of the page is kept as it is if it's one stream.
+	dic := map[string]interface{}{
+		"Type":      name("XObject"),
+		"Subtype":   name("Form"),
+		"BBox":      box,
+		"Resources": c.value(p["Resources"]),
+	}
+	var con []byte
+	switch t := r.resolve(p["Contents"]).(type) {
+	case *stream:
+		con = t.buf
+		for _, k := range []string{"Filter", "DecodeParms"} {
+			if v, ok := t.dic[k]; ok {
+				dic[k] = c.value(v)
+			}
+		}
+	case []interface{}:
+		con = r.contents(t)
+	}
+
+	// The matrix moves the page to the origin and rotates it clockwise.
+	w, h := box.urx-box.llx, box.ury-box.lly
+	switch r.rotation(p) {
+	case 0:
+		dic["Matrix"] = []float64{1, 0, 0, 1, -box.llx, -box.lly}
+	case 90:
+		dic["Matrix"] = []float64{0, -1, 1, 0, -box.lly, box.urx}
+		w, h = h, w
+	case 180:
+		dic["Matrix"] = []float64{-1, 0, 0, -1, box.urx, box.ury}
+	case 270:
+		dic["Matrix"] = []float64{0, 1, -1, 0, box.ury, -box.llx}
+		w, h = h, w
+	}
+	i := d.indirect(&stream{dic, con})
+	c.flush()
+	return &XObject{ref: i, w: w, h: h}, nil
+}
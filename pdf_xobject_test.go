@@ -0,0 +1,83 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestImportPage(t *testing.T) {
+	r, err := NewReader(testFile([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [ 3 0 R 5 0 R ] /Count 2 /Resources << /Font << /F1 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [ 10 20 110 220 ] /Contents 4 0 R >>",
+		"<< /Length 28 >>\nstream\nBT /F1 12 Tf (Hello) Tj ET\nendstream",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [ 0 0 100 200 ] /Rotate 90 /Contents [ 4 0 R 4 0 R ] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	x1, err := d.ImportPage(r, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := x1.Size(); w != 100 || h != 200 {
+		t.Errorf("size of page 1: got %v %v", w, h)
+	}
+	x2, err := d.ImportPage(r, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := x2.Size(); w != 200 || h != 100 {
+		t.Errorf("size of rotated page: got %v %v", w, h)
+	}
+	if _, err := d.ImportPage(r, 3); err == nil {
+		t.Error("page that doesn't exist imported")
+	}
+	if err := d.DrawXObject(x1, 0, 0, 50, 100); err == nil {
+		t.Error("XObject drawn before any page")
+	}
+	d.NewPage(400, 400)
+	d.DrawXObject(x1, 0, 0, 50, 100)
+	d.DrawXObject(x2, 100, 100, 200, 100)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if p := Validate(buf.Bytes()); len(p) != 0 {
+		t.Errorf("problems of document: %v", p)
+	}
+
+	b := buf.String()
+	for _, s := range []string{
+		"/BBox [ 10 20 110 220 ]", "/Matrix [ 1 0 0 1 -10 -20 ]", "/Matrix [ 0 -1 1 0 0 100 ]",
+		"q 0.5 0 0 0.5 0 0 cm /Fm", "q 1 0 0 1 100 100 cm /Fm",
+		"BT /F1 12 Tf (Hello) Tj ET\nBT /F1 12 Tf (Hello) Tj ET\n",
+	} {
+		if !strings.Contains(b, s) {
+			t.Errorf("document doesn't have %q", s)
+		}
+	}
+	if n := strings.Count(b, "/BaseFont /Helvetica"); n != 1 {
+		t.Errorf("font of the pages written %d times", n)
+	}
+}
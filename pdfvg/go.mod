@@ -0,0 +1,21 @@
+module github.com/mostafah/pdf.go/pdfvg
+
+go 1.21
+
+require (
+	github.com/mostafah/pdf.go v0.0.0
+	gonum.org/v1/plot v0.14.0
+)
+
+replace github.com/mostafah/pdf.go => ../
+
+require (
+	git.sr.ht/~sbinet/gg v0.5.0 // indirect
+	github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b // indirect
+	github.com/go-fonts/liberation v0.3.1 // indirect
+	github.com/go-latex/latex v0.0.0-20230307184459-12ec69307ad9 // indirect
+	github.com/go-pdf/fpdf v0.8.0 // indirect
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	golang.org/x/image v0.11.0 // indirect
+	golang.org/x/text v0.12.0 // indirect
+)
@@ -0,0 +1,293 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pdfvg implements the vg.Canvas interface of gonum.org/v1/plot on
+// pages of pdf.go documents, so that plots are drawn as vector graphics in
+// them instead of images.
+//
+// Text is drawn as the outlines of its glyphs, by DrawTextOutlines of the
+// document, so the fonts of plots should have TrueType outlines, like the
+// Liberation fonts plot uses by default.
+//
+// It's a module of its own, so that programs using pdf.go without plots
+// don't depend on plot.
+package pdfvg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/mostafah/pdf.go"
+	"gonum.org/v1/plot/font"
+	"gonum.org/v1/plot/vg"
+)
+
+// Canvas is a vg.Canvas that draws on a page of a document. Methods of
+// vg.Canvas have no errors, so the first error of the drawing is kept, to be
+// returned by Err.
+type Canvas struct {
+	p     *pdf.Page
+	w, h  vg.Length
+	stack []state
+	fonts map[font.Font]*pdf.OutlineFont
+	err   error
+}
+
+// state is what's saved by Push.
+type state struct {
+	color pdf.RGB
+	alpha float64
+	width vg.Length
+}
+
+// New returns a canvas that draws on page p, whose size is the size of the
+// page.
+func New(p *pdf.Page) *Canvas {
+	w, h := p.Size()
+	c := &Canvas{
+		p:     p,
+		w:     vg.Points(w),
+		h:     vg.Points(h),
+		stack: []state{{alpha: 1}},
+		fonts: make(map[font.Font]*pdf.OutlineFont),
+	}
+	vg.Initialize(c)
+	return c
+}
+
+// Err returns the first error of the drawing, or nil if there's none.
+func (c *Canvas) Err() error {
+	return c.err
+}
+
+// Size returns the width and height of c.
+func (c *Canvas) Size() (w, h vg.Length) {
+	return c.w, c.h
+}
+
+// check keeps err if it's the first error.
+func (c *Canvas) check(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// state returns the current state of c.
+func (c *Canvas) state() *state {
+	return &c.stack[len(c.stack)-1]
+}
+
+// add adds the operators written by f to the page of c.
+func (c *Canvas) add(f func(w *pdf.ContentWriter)) {
+	var con pdf.Content
+	f(con.Writer())
+	c.check(c.p.AddContent(&con))
+}
+
+// SetLineWidth sets the width of the lines stroked after it.
+func (c *Canvas) SetLineWidth(w vg.Length) {
+	c.state().width = w
+	if w > 0 {
+		c.add(func(cw *pdf.ContentWriter) { cw.LineWidth(w.Points()) })
+	}
+}
+
+// SetLineDash sets the lengths of the dashes and gaps of the lines stroked
+// after it, starting offset into the pattern.
+func (c *Canvas) SetLineDash(pattern []vg.Length, offset vg.Length) {
+	a := make([]float64, len(pattern))
+	for i, l := range pattern {
+		a[i] = l.Points()
+	}
+	c.add(func(cw *pdf.ContentWriter) { cw.Dash(a, offset.Points()) })
+}
+
+// SetColor sets the color of both strokes and fills. Colors that are not
+// opaque set the opacity too.
+func (c *Canvas) SetColor(col color.Color) {
+	if col == nil {
+		col = color.Black
+	}
+	r, g, b, a := col.RGBA()
+	// Colors are premultiplied by alpha.
+	if a > 0 && a < 0xffff {
+		r, g, b = r*0xffff/a, g*0xffff/a, b*0xffff/a
+	}
+	s := c.state()
+	s.color = pdf.RGB{R: float64(r) / 0xffff, G: float64(g) / 0xffff, B: float64(b) / 0xffff}
+	c.add(func(cw *pdf.ContentWriter) { cw.FillColor(s.color).StrokeColor(s.color) })
+	if alpha := float64(a) / 0xffff; alpha != s.alpha {
+		s.alpha = alpha
+		c.check(c.p.Draw(func(d *pdf.Document) error {
+			return d.SetOpacity(alpha, alpha)
+		}))
+	}
+}
+
+// Rotate rotates the coordinates of what's drawn after it by rad radians
+// counterclockwise.
+func (c *Canvas) Rotate(rad float64) {
+	sin, cos := math.Sincos(rad)
+	c.add(func(cw *pdf.ContentWriter) { cw.Transform(cos, sin, -sin, cos, 0, 0) })
+}
+
+// Translate moves the origin of what's drawn after it to pt.
+func (c *Canvas) Translate(pt vg.Point) {
+	c.add(func(cw *pdf.ContentWriter) { cw.Transform(1, 0, 0, 1, pt.X.Points(), pt.Y.Points()) })
+}
+
+// Scale scales the coordinates of what's drawn after it.
+func (c *Canvas) Scale(x, y float64) {
+	c.add(func(cw *pdf.ContentWriter) { cw.Transform(x, 0, 0, y, 0, 0) })
+}
+
+// Push saves the line width, dash pattern, color and transformations, to be
+// restored by Pop.
+func (c *Canvas) Push() {
+	c.stack = append(c.stack, *c.state())
+	c.add(func(cw *pdf.ContentWriter) { cw.SaveState() })
+}
+
+// Pop restores what the last Push saved.
+func (c *Canvas) Pop() {
+	if len(c.stack) == 1 {
+		c.check(&pdf.Error{Kind: pdf.ErrInvalid, Msg: "Pop with no Push"})
+		return
+	}
+	c.stack = c.stack[:len(c.stack)-1]
+	c.add(func(cw *pdf.ContentWriter) { cw.RestoreState() })
+}
+
+// Stroke strokes p, if the line width is positive.
+func (c *Canvas) Stroke(p vg.Path) {
+	if c.state().width <= 0 {
+		return
+	}
+	c.add(func(cw *pdf.ContentWriter) { path(cw, p).Stroke() })
+}
+
+// Fill fills p, with the nonzero winding rule.
+func (c *Canvas) Fill(p vg.Path) {
+	c.add(func(cw *pdf.ContentWriter) { path(cw, p).Fill() })
+}
+
+// FillString draws text with its baseline starting at pt, as the outlines of
+// the glyphs of f.
+func (c *Canvas) FillString(f font.Face, pt vg.Point, text string) {
+	if f.Font.Size == 0 {
+		return
+	}
+	of, ok := c.fonts[f.Font]
+	if !ok {
+		b := bytes.NewBuffer(nil)
+		_, err := f.Face.WriteSourceTo(nil, b)
+		if err == nil {
+			of, err = pdf.NewOutlineFont(b.Bytes())
+		}
+		if err != nil {
+			c.check(err)
+			return
+		}
+		c.fonts[f.Font] = of
+	}
+	col := c.state().color
+	c.check(c.p.Draw(func(d *pdf.Document) error {
+		return d.DrawTextOutlines(of, text, pt.X.Points(), pt.Y.Points(), f.Font.Size.Points(), col)
+	}))
+}
+
+// DrawImage draws m in rectangle r, as an image XObject of the document.
+func (c *Canvas) DrawImage(r vg.Rectangle, m image.Image) {
+	c.check(c.p.Draw(func(d *pdf.Document) error {
+		x, err := d.AddImage(m)
+		if err != nil {
+			return err
+		}
+		s := r.Size()
+		return d.DrawXObject(x, r.Min.X.Points(), r.Min.Y.Points(), s.X.Points(), s.Y.Points())
+	}))
+}
+
+// path writes the operators that make path p to cw, and returns cw.
+func path(cw *pdf.ContentWriter, p vg.Path) *pdf.ContentWriter {
+	var cur vg.Point // current point
+	for i, pc := range p {
+		switch pc.Type {
+		case vg.MoveComp:
+			cw.MoveTo(pc.Pos.X.Points(), pc.Pos.Y.Points())
+			cur = pc.Pos
+		case vg.LineComp:
+			cw.LineTo(pc.Pos.X.Points(), pc.Pos.Y.Points())
+			cur = pc.Pos
+		case vg.ArcComp:
+			cur = arc(cw, pc, i == 0)
+		case vg.CurveComp:
+			switch len(pc.Control) {
+			case 1:
+				// Quadratic curves are cubic curves with control
+				// points 2/3 of the way to the one control point.
+				q := pc.Control[0]
+				cw.Curve((cur.X+2*q.X).Points()/3, (cur.Y+2*q.Y).Points()/3,
+					(pc.Pos.X+2*q.X).Points()/3, (pc.Pos.Y+2*q.Y).Points()/3,
+					pc.Pos.X.Points(), pc.Pos.Y.Points())
+			case 2:
+				a, b := pc.Control[0], pc.Control[1]
+				cw.Curve(a.X.Points(), a.Y.Points(), b.X.Points(), b.Y.Points(),
+					pc.Pos.X.Points(), pc.Pos.Y.Points())
+			default:
+				cw.LineTo(pc.Pos.X.Points(), pc.Pos.Y.Points())
+			}
+			cur = pc.Pos
+		case vg.CloseComp:
+			cw.ClosePath()
+		}
+	}
+	return cw
+}
+
+// arc writes the operators of the arc of pc to cw, as Bézier curves of at
+// most 90° each, and returns its end. The arc is joined to the current point
+// with a line, or starts a new path if first is true.
+func arc(cw *pdf.ContentWriter, pc vg.PathComp, first bool) vg.Point {
+	r := pc.Radius.Points()
+	cx, cy := pc.Pos.X.Points(), pc.Pos.Y.Points()
+	at := func(a float64) (x, y float64) {
+		sin, cos := math.Sincos(a)
+		return cx + r*cos, cy + r*sin
+	}
+	x, y := at(pc.Start)
+	if first {
+		cw.MoveTo(x, y)
+	} else {
+		cw.LineTo(x, y)
+	}
+	n := math.Ceil(math.Abs(pc.Angle) / (math.Pi / 2))
+	step := pc.Angle / n
+	// Control points are k times the radius away from the ends, along
+	// their tangents.
+	k := 4.0 / 3 * math.Tan(step/4)
+	a := pc.Start
+	for i := 0; i < int(n); i++ {
+		sin0, cos0 := math.Sincos(a)
+		sin1, cos1 := math.Sincos(a + step)
+		x1, y1 := at(a + step)
+		cw.Curve(x-k*r*sin0, y+k*r*cos0, x1+k*r*sin1, y1-k*r*cos1, x1, y1)
+		x, y, a = x1, y1, a+step
+	}
+	return vg.Point{X: vg.Points(x), Y: vg.Points(y)}
+}
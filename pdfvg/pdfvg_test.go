@@ -0,0 +1,118 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdfvg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mostafah/pdf.go"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+func TestPlot(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := pdf.New(buf)
+	pg, _ := d.NewPage(300, 200)
+	c := New(pg)
+	if w, h := c.Size(); w != 300 || h != 200 {
+		t.Errorf("size: got %vx%v", w, h)
+	}
+
+	p := plot.New()
+	p.Title.Text = "Sales"
+	l, err := plotter.NewLine(plotter.XYs{{X: 0, Y: 1}, {X: 1, Y: 3}, {X: 2, Y: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Color = color.RGBA{R: 255, A: 255}
+	s, _ := plotter.NewScatter(plotter.XYs{{X: 1, Y: 1}})
+	s.Shape = draw.CircleGlyph{}
+	p.Add(l, s)
+	p.Draw(draw.New(c))
+	c.DrawImage(vg.Rectangle{Max: vg.Point{X: 10, Y: 10}}, image.NewGray(image.Rect(0, 0, 2, 2)))
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{
+		"/ActualText (Sales)",  // the title, as outlines
+		"1 0 0 RG",             // the line
+		" c\n",                 // the circle of the scatter
+		"10 0 0 10 0 0 cm /Im", // the image
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("no %q in the output", s)
+		}
+	}
+	if _, err := pdf.NewReader(buf.Bytes()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCanvas(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := pdf.New(buf)
+	pg, _ := d.NewPage(100, 100)
+	c := New(pg)
+	c.Push()
+	c.SetColor(color.NRGBA{G: 255, A: 128})
+	c.SetLineDash([]vg.Length{2, 1}, 1)
+	c.Translate(vg.Point{X: 10, Y: 20})
+	c.Scale(2, 2)
+	c.Rotate(math.Pi / 2)
+	c.SetLineWidth(0)
+	c.Stroke(vg.Path{{Type: vg.MoveComp}, {Type: vg.LineComp, Pos: vg.Point{X: 5}}})
+	var p vg.Path
+	p.Arc(vg.Point{X: 50, Y: 50}, 10, 0, 2*math.Pi)
+	p.Close()
+	c.Fill(p)
+	c.Pop()
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+	c.Pop()
+	if err := c.Err(); err == nil || !strings.Contains(err.Error(), "Pop with no Push") {
+		t.Errorf("Pop with no Push: got %v", err)
+	}
+	d.Close()
+
+	out := buf.String()
+	for _, s := range []string{
+		"0 1 0 rg\n0 1 0 RG", "/GS", "[2 1] 1 d", "1 0 0 1 10 20 cm", "2 0 0 2 0 0 cm",
+		"0 1 -1 0 0 0 cm", "60 50 m",
+		// A circle is four curves.
+		"60 55.523 55.523 60 50 60 c", "50 40 c", "60 50 c\nh\nf",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("no %q in the output", s)
+		}
+	}
+	if strings.Contains(out, "5 0 l") {
+		t.Error("line stroked with width 0")
+	}
+}
@@ -0,0 +1,387 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file turns paths into the pixels they cover, for rendering pages.
+// Paths are flattened into polygons, which are scanned with a few samples
+// per row of pixels and exact coverage along the rows. Strokes are made into
+// polygons too.
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// subSamples is the number of samples of coverage in each row of pixels.
+const subSamples = 4
+
+// point is a point of a polygon.
+type point struct {
+	x, y float64
+}
+
+// polygon is a closed polygon.
+type polygon []point
+
+// area returns the signed area of p, which is positive if it's
+// counterclockwise when y goes up.
+func (p polygon) area() float64 {
+	a := 0.0
+	for i := range p {
+		q, r := p[i], p[(i+1)%len(p)]
+		a += q.x*r.y - r.x*q.y
+	}
+	return a / 2
+}
+
+// ccw returns p counterclockwise, so that polygons of strokes don't cancel
+// each other out when they're filled with the nonzero winding rule.
+func (p polygon) ccw() polygon {
+	if p.area() >= 0 {
+		return p
+	}
+	q := make(polygon, len(p))
+	for i, pt := range p {
+		q[len(p)-1-i] = pt
+	}
+	return q
+}
+
+// transform returns p transformed by m.
+func (p polygon) transform(m matrix) polygon {
+	q := make(polygon, len(p))
+	for i, pt := range p {
+		q[i].x, q[i].y = m.apply(pt.x, pt.y)
+	}
+	return q
+}
+
+// cover holds how much of each pixel of r is covered, from 0 to 1.
+type cover struct {
+	r image.Rectangle
+	a []float32
+}
+
+// at returns the coverage of pixel (x, y), which should be in c.r.
+func (c *cover) at(x, y int) float32 {
+	return c.a[(y-c.r.Min.Y)*c.r.Dx()+x-c.r.Min.X]
+}
+
+// edge is an edge of a polygon, going down from (x0, y0) to (x1, y1) if dir
+// is 1, and up if it's -1.
+type edge struct {
+	x0, y0, x1, y1 float64
+	dir            int
+}
+
+// coverage returns the coverage of the pixels in bounds by polygons ps,
+// which are in device space, filled with the even-odd rule if evenOdd is
+// true and with the nonzero winding rule otherwise. It returns nil if they
+// cover nothing.
+func coverage(ps []polygon, bounds image.Rectangle, evenOdd bool) *cover {
+	var es []edge
+	minx, miny, maxx, maxy := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, p := range ps {
+		for i := range p {
+			a, b := p[i], p[(i+1)%len(p)]
+			if math.IsNaN(a.x+a.y+b.x+b.y) || math.IsInf(a.x+a.y+b.x+b.y, 0) {
+				return nil
+			}
+			minx, maxx = math.Min(minx, a.x), math.Max(maxx, a.x)
+			miny, maxy = math.Min(miny, a.y), math.Max(maxy, a.y)
+			switch {
+			case a.y < b.y:
+				es = append(es, edge{a.x, a.y, b.x, b.y, 1})
+			case a.y > b.y:
+				es = append(es, edge{b.x, b.y, a.x, a.y, -1})
+			}
+		}
+	}
+	r := image.Rect(int(math.Floor(minx)), int(math.Floor(miny)),
+		int(math.Ceil(maxx))+1, int(math.Ceil(maxy))+1).Intersect(bounds)
+	if len(es) == 0 || r.Empty() {
+		return nil
+	}
+	sort.Slice(es, func(i, j int) bool { return es[i].y0 < es[j].y0 })
+
+	c := &cover{r, make([]float32, r.Dx()*r.Dy())}
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	var active []*edge
+	var xs []crossing
+	next := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := c.a[(y-r.Min.Y)*r.Dx() : (y-r.Min.Y+1)*r.Dx()]
+		for s := 0; s < subSamples; s++ {
+			sy := float64(y) + (float64(s)+0.5)/subSamples
+			for next < len(es) && es[next].y0 <= sy {
+				active = append(active, &es[next])
+				next++
+			}
+			xs = xs[:0]
+			k := 0
+			for _, e := range active {
+				if e.y1 <= sy {
+					continue
+				}
+				active[k] = e
+				k++
+				if e.y0 <= sy {
+					xs = append(xs, crossing{e.x0 + (sy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0), e.dir})
+				}
+			}
+			active = active[:k]
+			sort.Slice(xs, func(i, j int) bool { return xs[i].x < xs[j].x })
+			w := 0
+			for i, x := range xs {
+				w += x.dir
+				in := w != 0
+				if evenOdd {
+					in = w%2 != 0
+				}
+				if in && i+1 < len(xs) {
+					span(row, x.x-float64(r.Min.X), xs[i+1].x-float64(r.Min.X), 1.0/subSamples)
+				}
+			}
+		}
+	}
+	for i, a := range c.a {
+		if a > 1 {
+			c.a[i] = 1
+		}
+	}
+	return c
+}
+
+// span adds a to the coverage of row from x0 to x1, adding parts of a to the
+// pixels that are partly in the span.
+func span(row []float32, x0, x1 float64, a float32) {
+	x0, x1 = math.Max(x0, 0), math.Min(x1, float64(len(row)))
+	if x0 >= x1 {
+		return
+	}
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		row[i0] += a * float32(x1-x0)
+		return
+	}
+	row[i0] += a * float32(float64(i0+1)-x0)
+	for i := i0 + 1; i < i1; i++ {
+		row[i] += a
+	}
+	if i1 < len(row) {
+		row[i1] += a * float32(x1-float64(i1))
+	}
+}
+
+// subpath is a part of a path, flattened into lines.
+type subpath struct {
+	pts    []point
+	closed bool
+}
+
+// flatten appends the points of the cubic Bézier curve from p0 to p3 with
+// control points p1 and p2, except p0, to pts. scale is about how many
+// pixels a unit is, which sets how many lines the curve is made of.
+func flatten(pts []point, p0, p1, p2, p3 point, scale float64) []point {
+	l := math.Hypot(p1.x-p0.x, p1.y-p0.y) + math.Hypot(p2.x-p1.x, p2.y-p1.y) +
+		math.Hypot(p3.x-p2.x, p3.y-p2.y)
+	n := int(math.Sqrt(l*scale)) + 1
+	if n > 100 {
+		n = 100
+	}
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		u := 1 - t
+		a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+		pts = append(pts, point{a*p0.x + b*p1.x + c*p2.x + d*p3.x, a*p0.y + b*p1.y + c*p2.y + d*p3.y})
+	}
+	return pts
+}
+
+// circle returns a circle around c with radius r as a polygon. scale is
+// about how many pixels a unit is.
+func circle(c point, r, scale float64) polygon {
+	n := int(r*scale) + 8
+	if n > 64 {
+		n = 64
+	}
+	p := make(polygon, n)
+	for i := range p {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		p[i] = point{c.x + r*math.Cos(a), c.y + r*math.Sin(a)}
+	}
+	return p
+}
+
+// strokeStyle is how paths are stroked.
+type strokeStyle struct {
+	width      float64
+	cap, join  int // like the arguments of the J and j operators
+	miterLimit float64
+	dash       []float64
+	phase      float64
+}
+
+// dashes returns the dashes of sp made by the dash pattern of st, or sp if
+// there's no dash pattern.
+func (st *strokeStyle) dashes(sp subpath) []subpath {
+	total := 0.0
+	for _, d := range st.dash {
+		if d < 0 {
+			return []subpath{sp}
+		}
+		total += d
+	}
+	if total <= 0 {
+		return []subpath{sp}
+	}
+	pts := sp.pts
+	if sp.closed && len(pts) > 0 {
+		pts = append(pts[:len(pts):len(pts)], pts[0])
+	}
+	// The dash that the path starts in, and how much of it is left.
+	i, on := 0, true
+	phase := math.Mod(st.phase, total)
+	for phase >= st.dash[i] {
+		phase -= st.dash[i]
+		i = (i + 1) % len(st.dash)
+		on = !on
+	}
+	left := st.dash[i] - phase
+
+	var ds []subpath
+	var cur []point
+	if on && len(pts) > 0 {
+		cur = []point{pts[0]}
+	}
+	for k := 1; k < len(pts); k++ {
+		a, b := pts[k-1], pts[k]
+		l := math.Hypot(b.x-a.x, b.y-a.y)
+		t := 0.0
+		for l-t > left {
+			t += left
+			p := point{a.x + (b.x-a.x)*t/l, a.y + (b.y-a.y)*t/l}
+			if on {
+				ds = append(ds, subpath{pts: append(cur, p)})
+				cur = nil
+			} else {
+				cur = []point{p}
+			}
+			on = !on
+			i = (i + 1) % len(st.dash)
+			left = st.dash[i]
+		}
+		left -= l - t
+		if on {
+			cur = append(cur, b)
+		}
+	}
+	if on && len(cur) > 1 {
+		ds = append(ds, subpath{pts: cur})
+	}
+	return ds
+}
+
+// outline returns polygons that cover subpath sp stroked with st, in the
+// same space as sp. scale is about how many pixels a unit is.
+func (st *strokeStyle) outline(sp subpath, scale float64) []polygon {
+	// Points that are the same as the ones before them are dropped.
+	var pts []point
+	for _, p := range sp.pts {
+		if len(pts) == 0 || p != pts[len(pts)-1] {
+			pts = append(pts, p)
+		}
+	}
+	if sp.closed && len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+	hw := st.width / 2
+	var ps []polygon
+	if len(pts) == 1 {
+		// Lone points are drawn only with round caps.
+		if st.cap == 1 {
+			ps = append(ps, circle(pts[0], hw, scale))
+		}
+		return ps
+	}
+	n := len(pts) - 1
+	if sp.closed {
+		n = len(pts)
+	}
+	dir := func(i int) (point, point) {
+		a, b := pts[i%len(pts)], pts[(i+1)%len(pts)]
+		l := math.Hypot(b.x-a.x, b.y-a.y)
+		d := point{(b.x - a.x) / l, (b.y - a.y) / l}
+		return d, point{-d.y * hw, d.x * hw}
+	}
+	for i := 0; i < n; i++ {
+		a, b := pts[i], pts[(i+1)%len(pts)]
+		d, o := dir(i)
+		// Square caps extend the ends of open paths.
+		if !sp.closed && st.cap == 2 {
+			if i == 0 {
+				a = point{a.x - d.x*hw, a.y - d.y*hw}
+			}
+			if i == n-1 {
+				b = point{b.x + d.x*hw, b.y + d.y*hw}
+			}
+		}
+		ps = append(ps, polygon{{a.x + o.x, a.y + o.y}, {a.x - o.x, a.y - o.y},
+			{b.x - o.x, b.y - o.y}, {b.x + o.x, b.y + o.y}}.ccw())
+	}
+
+	// Joins are between segments i-1 and i; closed paths have one at their
+	// start too.
+	joins := n - 1
+	if sp.closed {
+		joins = n
+	}
+	for i := 1; i <= joins; i++ {
+		p := pts[i%len(pts)]
+		d1, o1 := dir(i - 1)
+		d2, o2 := dir(i)
+		cos := d1.x*d2.x + d1.y*d2.y
+		switch {
+		case st.join == 1:
+			ps = append(ps, circle(p, hw, scale))
+		case st.join == 0 && cos > -1 && math.Sqrt(2/(1+cos)) <= st.miterLimit:
+			// The tip of the miter is on the bisector of the
+			// offsets, on both sides.
+			for _, s := range []float64{1, -1} {
+				k := s / (1 + cos)
+				tip := point{p.x + (o1.x+o2.x)*k, p.y + (o1.y+o2.y)*k}
+				ps = append(ps, polygon{p, {p.x + s*o1.x, p.y + s*o1.y}, tip,
+					{p.x + s*o2.x, p.y + s*o2.y}}.ccw())
+			}
+		default:
+			for _, s := range []float64{1, -1} {
+				ps = append(ps, polygon{p, {p.x + s*o1.x, p.y + s*o1.y},
+					{p.x + s*o2.x, p.y + s*o2.y}}.ccw())
+			}
+		}
+	}
+
+	// Round caps
+	if !sp.closed && st.cap == 1 {
+		ps = append(ps, circle(pts[0], hw, scale), circle(pts[len(pts)-1], hw, scale))
+	}
+	return ps
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"fmt"
+	"image"
+	"testing"
+)
+
+// coverAt returns the coverage of pixel (x, y) by c, which is zero outside
+// it.
+func coverAt(c *cover, x, y int) float32 {
+	if c == nil || !image.Pt(x, y).In(c.r) {
+		return 0
+	}
+	return c.at(x, y)
+}
+
+func TestCoverage(t *testing.T) {
+	square := func(x, y, s float64) polygon {
+		return polygon{{x, y}, {x + s, y}, {x + s, y + s}, {x, y + s}}
+	}
+	bounds := image.Rect(0, 0, 10, 10)
+	tests := []struct {
+		ps      []polygon
+		evenOdd bool
+		want    string // coverage of pixels (1, 1), (2, 2) and (5, 5)
+	}{
+		{[]polygon{square(1, 1, 8)}, false, "1 1 1"},
+		{[]polygon{square(1.5, 1.5, 7)}, false, "0.25 1 1"},
+		{[]polygon{square(1, 1, 8), square(4, 4, 3)}, false, "1 1 1"},
+		{[]polygon{square(1, 1, 8), square(4, 4, 3)}, true, "1 1 0"},
+		// The inner square goes the other way.
+		{[]polygon{square(1, 1, 8), {{4, 4}, {4, 7}, {7, 7}, {7, 4}}}, false, "1 1 0"},
+	}
+	for _, tt := range tests {
+		c := coverage(tt.ps, bounds, tt.evenOdd)
+		if got := fmt.Sprint(coverAt(c, 1, 1), coverAt(c, 2, 2), coverAt(c, 5, 5)); got != tt.want {
+			t.Errorf("coverage of %v: got %s, want %s", tt.ps, got, tt.want)
+		}
+	}
+	if c := coverage([]polygon{square(20, 20, 5)}, bounds, false); c != nil {
+		t.Errorf("coverage outside the bounds: got %v", c)
+	}
+}
+
+func TestOutline(t *testing.T) {
+	line := subpath{pts: []point{{2, 5}, {8, 5}}}
+	bounds := image.Rect(0, 0, 10, 10)
+	tests := []struct {
+		st   strokeStyle
+		want string // coverage of pixels (1, 4), (3, 4) and (5, 6)
+	}{
+		{strokeStyle{width: 2}, "0 1 0"},
+		{strokeStyle{width: 2, cap: 2}, "1 1 0"},
+		{strokeStyle{width: 4}, "0 1 1"},
+		{strokeStyle{width: 2, dash: []float64{2, 2}, phase: 1}, "0 0 0"},
+	}
+	for _, tt := range tests {
+		var ps []polygon
+		for _, d := range tt.st.dashes(line) {
+			ps = append(ps, tt.st.outline(d, 1)...)
+		}
+		c := coverage(ps, bounds, false)
+		if got := fmt.Sprint(coverAt(c, 1, 4), coverAt(c, 3, 4), coverAt(c, 5, 6)); got != tt.want {
+			t.Errorf("outline with %+v: got %s, want %s", tt.st, got, tt.want)
+		}
+	}
+}
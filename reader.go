@@ -0,0 +1,652 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file reads existing PDF files: their cross-reference tables and
+// streams (p. 93), object streams (p. 100), and the objects themselves, which
+// are parsed into the same values that are used for making documents.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Reader reads an existing PDF file. Objects are read when they are needed.
+type Reader struct {
+	b       []byte
+	version string
+	xref    map[int]xrefEntry
+	objs    map[int]interface{}
+	trailer map[string]interface{}
+	xoff    int             // offset of the last cross-reference section
+	rebuilt bool            // whether the cross-reference table was rebuilt
+	probs   []Problem       // problems tolerated while reading
+	sec     *security       // security handler of encrypted files, once unlocked
+	enc     int             // number of the encryption dictionary, if it's indirect
+	locked  bool            // whether the file is encrypted and not unlocked yet
+	ctx     context.Context // reading stops when it's done
+}
+
+// xrefEntry is the entry of an object in the cross-reference table.
+type xrefEntry struct {
+	off int // offset of the object, or its index in its object stream
+	stm int // number of the object stream of the object; 0 if it's not in one
+}
+
+// NewReader reads the PDF file b. Later incremental updates of the file
+// replace the objects of the older ones. Encrypted files are decrypted if
+// their user password is empty; others need a call to Decrypt.
+func NewReader(b []byte) (r *Reader, err error) {
+	return NewReaderContext(context.Background(), b)
+}
+
+// NewReaderContext is like NewReader, but stops reading the file when ctx is
+// done, with an error of kind ErrCanceled. Objects of the file are read when
+// they're needed, so ctx is kept for the later uses of r too, like Merge.
+func NewReaderContext(ctx context.Context, b []byte) (r *Reader, err error) {
+	defer dontPanic(&err)
+
+	r = &Reader{b: b, xref: make(map[int]xrefEntry), objs: make(map[int]interface{}), ctx: ctx}
+	if bytes.HasPrefix(b, []byte("%PDF-")) {
+		p := &parser{b: b, pos: 5}
+		r.version = p.word()
+	}
+	if e := recovered(r.readXrefs); e != nil {
+		r.rebuild(0, panicText(e))
+	} else if r.trailer["Root"] == nil {
+		r.rebuild(0, "trailer with no Root")
+	}
+	if r.Encrypted() {
+		if ref, ok := r.trailer["Encrypt"].(ref); ok {
+			r.enc = ref.num
+		}
+		r.locked = true
+		recovered(func() {
+			r.unlock("")
+		})
+	}
+	return r, nil
+}
+
+// Version returns the version of PDF in the header of the file, like "1.7".
+func (r *Reader) Version() string {
+	return r.version
+}
+
+// Encrypted tells whether the file is encrypted.
+func (r *Reader) Encrypted() bool {
+	return r.trailer["Encrypt"] != nil
+}
+
+// Info returns the entries of the document information dictionary of the
+// file (p. 550), like "Title" and "Producer". Dates are returned as they are
+// in the file, like "D:20110830120000+04'30'". Entries with values that are
+// not strings or names are left out.
+func (r *Reader) Info() (info map[string]string, err error) {
+	defer dontPanic(&err)
+
+	info = make(map[string]string)
+	d, _ := r.resolve(r.trailer["Info"]).(map[string]interface{})
+	for k, v := range d {
+		switch t := r.resolve(v).(type) {
+		case string:
+			info[k] = decodeText(t)
+		case name:
+			info[k] = string(t)
+		}
+	}
+	return info, nil
+}
+
+// Metadata returns the XMP metadata stream of the document, or nil if it has
+// none.
+func (r *Reader) Metadata() (xmp []byte, err error) {
+	defer dontPanic(&err)
+
+	cat, _ := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	s, ok := r.resolve(cat["Metadata"]).(*stream)
+	if !ok {
+		return nil, nil
+	}
+	if xmp = r.decode(s); xmp == nil {
+		fail(ErrBadFile, "unsupported filter in metadata")
+	}
+	return xmp, nil
+}
+
+// NumPages returns the number of pages of the file.
+func (r *Reader) NumPages() (n int, err error) {
+	defer dontPanic(&err)
+
+	return len(r.pages()), nil
+}
+
+// PageSize returns the size of page n of the file as it's shown, which is
+// the size of its crop box, or its media box if it has none, turned by its
+// rotation. Pages are numbered from 1.
+func (r *Reader) PageSize(n int) (w, h float64, err error) {
+	defer dontPanic(&err)
+
+	pgs := r.pages()
+	if n < 1 || n > len(pgs) {
+		fail(ErrBadFile, fmt.Sprintf("size of page %d of %d pages", n, len(pgs)))
+	}
+	p := pgs[n-1].dic
+	box := r.pageBox(p)
+	w, h = box.urx-box.llx, box.ury-box.lly
+	if rot := r.rotation(p); rot == 90 || rot == 270 {
+		w, h = h, w
+	}
+	return w, h, nil
+}
+
+// problem records a problem of object num that was tolerated.
+func (r *Reader) problem(num int, format string, a ...interface{}) {
+	r.probs = append(r.probs, Problem{num, fmt.Sprintf(format, a...)})
+}
+
+// readXrefs reads all the cross-reference sections of the file, starting
+// from the last one.
+func (r *Reader) readXrefs() {
+	i := bytes.LastIndex(r.b, []byte("startxref"))
+	if i < 0 {
+		fail(ErrBadFile, "no startxref")
+	}
+	p := &parser{b: r.b, pos: i + len("startxref")}
+	off, ok := p.token().(int)
+	if !ok {
+		fail(ErrBadFile, "bad startxref")
+	}
+	r.xoff = off
+
+	seen := make(map[int]bool)
+	for off != 0 && !seen[off] {
+		seen[off] = true
+		t := r.readXref(off)
+		if r.trailer == nil {
+			r.trailer = t
+		}
+		// Hybrid files have a stream for the objects that old readers
+		// should not see (p. 109).
+		if s, ok := t["XRefStm"].(int); ok && !seen[s] {
+			seen[s] = true
+			r.readXref(s)
+		}
+		off, _ = t["Prev"].(int)
+	}
+}
+
+// readXref reads the cross-reference table or stream at offset off, and
+// returns its trailer. Entries of objects already in r.xref are ignored.
+func (r *Reader) readXref(off int) map[string]interface{} {
+	checkContext(r.ctx)
+	if off < 0 || off >= len(r.b) {
+		fail(ErrBadFile, "cross-reference table out of the file")
+	}
+	p := &parser{b: r.b, pos: off}
+	switch t := p.token(); t {
+	case keyword("xref"):
+		return r.readXrefTable(p)
+	default:
+		if _, ok := t.(int); !ok {
+			fail(ErrBadFile, "bad offset of cross-reference table")
+		}
+	}
+	p.pos = off
+	_, o := r.parse(p)
+	s, ok := o.(*stream)
+	if !ok || s.dic["Type"] != name("XRef") {
+		fail(ErrBadFile, "bad offset of cross-reference table")
+	}
+	r.readXrefStream(s)
+	return s.dic
+}
+
+// readXrefTable reads a cross-reference table (p. 93) after its xref keyword.
+func (r *Reader) readXrefTable(p *parser) map[string]interface{} {
+	for {
+		t := p.token()
+		if t == keyword("trailer") {
+			break
+		}
+		start, ok1 := t.(int)
+		count, ok2 := p.token().(int)
+		if !ok1 || !ok2 {
+			fail(ErrBadFile, "bad cross-reference table")
+		}
+		p.skipSpace()
+		for i := 0; i < count; i++ {
+			// Entries are exactly 20 bytes long (p. 94).
+			if p.pos+20 > len(r.b) {
+				fail(ErrBadFile, "cross-reference table out of the file")
+			}
+			e := r.b[p.pos : p.pos+20]
+			p.pos += 20
+			o, err1 := atoi(e[0:10])
+			_, err2 := atoi(e[11:16])
+			if err1 != nil || err2 != nil || e[10] != ' ' || e[16] != ' ' ||
+				!(e[18] == ' ' || e[18] == '\r') || !(e[19] == '\r' || e[19] == '\n') {
+				fail(ErrBadFile, fmt.Sprintf("bad entry for object %d in cross-reference table", start+i))
+			}
+			if _, ok := r.xref[start+i]; !ok {
+				if e[17] == 'n' {
+					r.xref[start+i] = xrefEntry{o, 0}
+				} else {
+					r.xref[start+i] = xrefEntry{-1, 0}
+				}
+			}
+		}
+	}
+	t, ok := p.object().(map[string]interface{})
+	if !ok {
+		fail(ErrBadFile, "bad trailer")
+	}
+	return t
+}
+
+// readXrefStream reads the entries of cross-reference stream s (p. 106).
+func (r *Reader) readXrefStream(s *stream) {
+	b := r.decode(s)
+	if b == nil {
+		fail(ErrBadFile, "unsupported filter of cross-reference stream")
+	}
+	var w [3]int
+	wa, _ := s.dic["W"].([]interface{})
+	if len(wa) != 3 {
+		fail(ErrBadFile, "bad W in cross-reference stream")
+	}
+	for i, v := range wa {
+		w[i], _ = v.(int)
+	}
+	index, _ := s.dic["Index"].([]interface{})
+	if index == nil {
+		index = []interface{}{0, s.dic["Size"]}
+	}
+
+	field := func(n int, def int) int {
+		if n == 0 {
+			return def
+		}
+		if len(b) < n {
+			fail(ErrBadFile, "cross-reference stream is too short")
+		}
+		v := 0
+		for _, c := range b[:n] {
+			v = v<<8 | int(c)
+		}
+		b = b[n:]
+		return v
+	}
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int)
+		count, _ := index[i+1].(int)
+		for j := start; j < start+count; j++ {
+			t, f2, f3 := field(w[0], 1), field(w[1], 0), field(w[2], 0)
+			if _, ok := r.xref[j]; ok {
+				continue
+			}
+			switch t {
+			case 0:
+				r.xref[j] = xrefEntry{-1, 0}
+			case 1:
+				r.xref[j] = xrefEntry{f2, 0}
+			case 2:
+				r.xref[j] = xrefEntry{f3, f2}
+			}
+		}
+	}
+}
+
+// atoi parses the decimal number b.
+func atoi(b []byte) (int, error) {
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, errors.New("bad number")
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, nil
+}
+
+// object returns object num, reading it the first time. Objects that don't
+// exist are null.
+func (r *Reader) object(num int) interface{} {
+	if o, ok := r.objs[num]; ok {
+		return o
+	}
+	checkContext(r.ctx)
+	if r.locked && num != r.enc {
+		fail(ErrPassword, "encrypted file needs a password")
+	}
+	r.objs[num] = nil // for loops, like streams that are their own Length
+	e, ok := r.xref[num]
+	if !ok || e.off < 0 {
+		return nil
+	}
+	if e.stm != 0 {
+		r.readObjectStream(e.stm)
+		return r.objs[num]
+	}
+	if !r.rebuilt && r.objectAt(e.off) != num {
+		r.rebuild(num, "bad offset in cross-reference table")
+		return r.object(num)
+	}
+	if e.off >= len(r.b) {
+		fail(ErrBadFile, "offset in cross-reference table out of the file")
+	}
+	n, o := r.parse(&parser{b: r.b, pos: e.off})
+	if n != num {
+		fail(ErrBadFile, "bad offset in cross-reference table")
+	}
+	r.objs[num] = o
+	return o
+}
+
+// parse reads an indirect object at the position of p, and returns its
+// number and value.
+func (r *Reader) parse(p *parser) (int, interface{}) {
+	num, ok := p.token().(int)
+	gen, ok2 := p.token().(int)
+	if !ok || !ok2 || p.token() != keyword("obj") {
+		fail(ErrBadFile, "bad offset in cross-reference table")
+	}
+	o := p.object()
+	pos := p.pos
+	if d, ok := o.(map[string]interface{}); ok && !p.eof() && p.token() == keyword("stream") {
+		o = r.stream(num, p, d)
+		pos = p.pos
+	}
+	p.pos = pos
+	if p.eof() || p.token() != keyword("endobj") {
+		r.problem(num, "object with no endobj")
+	}
+	if r.sec != nil && num != r.enc {
+		o = r.decrypt(o, r.sec.decrypter(num, gen))
+	}
+	return num, o
+}
+
+// stream reads the data of a stream with dictionary d, right after the
+// stream keyword. Length is not kept in the dictionary of the stream, since
+// it's written again with the data.
+func (r *Reader) stream(num int, p *parser, d map[string]interface{}) *stream {
+	// The data starts after CRLF or LF (p. 60).
+	if p.pos < len(r.b) && r.b[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(r.b) && r.b[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+	n, ok := r.resolve(d["Length"]).(int)
+	if !ok {
+		r.problem(num, "stream with no Length")
+	}
+	e := start + n
+	if !ok || e < start || e > len(r.b) ||
+		!bytes.HasPrefix(bytes.TrimLeft(r.b[e:], "\r\n"), []byte("endstream")) {
+		if ok {
+			r.problem(num, "wrong Length of stream")
+		}
+		i := bytes.Index(r.b[start:], []byte("endstream"))
+		if i < 0 {
+			fail(ErrBadFile, "stream with no endstream")
+		}
+		e = start + i
+		// The end of line before endstream is not part of the data.
+		if e > start && r.b[e-1] == '\n' {
+			e--
+		}
+		if e > start && r.b[e-1] == '\r' {
+			e--
+		}
+	}
+	p.pos = e
+	p.expect("endstream")
+
+	dic := make(map[string]interface{}, len(d))
+	for k, v := range d {
+		if k != "Length" {
+			dic[k] = v
+		}
+	}
+	return &stream{dic, r.b[start:e]}
+}
+
+// readObjectStream reads the objects in object stream num (p. 100).
+func (r *Reader) readObjectStream(num int) {
+	s, ok := r.object(num).(*stream)
+	if !ok || s.dic["Type"] != name("ObjStm") {
+		fail(ErrBadFile, fmt.Sprintf("object %d is not an object stream", num))
+	}
+	b := r.decode(s)
+	if b == nil {
+		fail(ErrBadFile, "unsupported filter of object stream")
+	}
+	n, _ := s.dic["N"].(int)
+	first, _ := s.dic["First"].(int)
+	p := &parser{b: b}
+	for i := 0; i < n; i++ {
+		o, ok1 := p.token().(int)
+		off, ok2 := p.token().(int)
+		if !ok1 || !ok2 {
+			fail(ErrBadFile, "bad object stream")
+		}
+		if e := r.xref[o]; e.stm != num || e.off != i {
+			continue
+		}
+		q := &parser{b: b, pos: first + off}
+		r.objs[o] = q.object()
+	}
+}
+
+// resolve returns the object referred to by o if it's a reference, or o
+// itself otherwise.
+func (r *Reader) resolve(o interface{}) interface{} {
+	if ref, ok := o.(ref); ok {
+		return r.object(ref.num)
+	}
+	return o
+}
+
+// decode returns the decoded data of stream s, or nil if any of its filters
+// is not supported.
+func (r *Reader) decode(s *stream) []byte {
+	fs, parms := r.filters(s)
+	return decodeFilters(s.buf, fs, parms)
+}
+
+// filters returns the filters of stream s, with their parameters.
+func (r *Reader) filters(s *stream) (fs []name, parms []map[string]interface{}) {
+	var a, pa []interface{}
+	switch f := r.resolve(s.dic["Filter"]).(type) {
+	case name:
+		a = []interface{}{f}
+		pa = []interface{}{r.resolve(s.dic["DecodeParms"])}
+	case []interface{}:
+		a = f
+		pa, _ = r.resolve(s.dic["DecodeParms"]).([]interface{})
+	}
+	for i, f := range a {
+		var p map[string]interface{}
+		if i < len(pa) {
+			p, _ = r.resolve(pa[i]).(map[string]interface{})
+		}
+		f, _ := r.resolve(f).(name)
+		fs = append(fs, f)
+		parms = append(parms, p)
+	}
+	return fs, parms
+}
+
+// rpage is a page of a parsed file.
+type rpage struct {
+	num int                    // number of the page object
+	dic map[string]interface{} // page dictionary, with inherited attributes
+}
+
+// inheritable are the attributes of pages that can be inherited from the
+// nodes of the page tree (p. 149).
+var inheritable = []string{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// pages returns the pages of the file in order.
+func (r *Reader) pages() []rpage {
+	cat, ok := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	if !ok {
+		fail(ErrBadFile, "no catalog")
+	}
+	var pgs []rpage
+	r.pageTree(cat["Pages"], nil, make(map[int]bool), &pgs)
+	return pgs
+}
+
+// pageTree appends the pages in page tree node n to pgs. inh holds the
+// attributes inherited from the parents of n.
+func (r *Reader) pageTree(n interface{}, inh map[string]interface{}, seen map[int]bool, pgs *[]rpage) {
+	ref, ok := n.(ref)
+	if !ok || seen[ref.num] {
+		fail(ErrBadFile, "bad page tree")
+	}
+	seen[ref.num] = true
+	d, ok := r.object(ref.num).(map[string]interface{})
+	if !ok {
+		fail(ErrBadFile, "bad page tree")
+	}
+	m := make(map[string]interface{})
+	for k, v := range inh {
+		m[k] = v
+	}
+	for _, k := range inheritable {
+		if v, ok := d[k]; ok {
+			m[k] = v
+		}
+	}
+	if d["Type"] == name("Pages") {
+		kids, _ := r.resolve(d["Kids"]).([]interface{})
+		for _, k := range kids {
+			r.pageTree(k, m, seen, pgs)
+		}
+		return
+	}
+	for k, v := range d {
+		m[k] = v
+	}
+	*pgs = append(*pgs, rpage{ref.num, m})
+}
+
+// nameTree adds the entries of name tree n (p. 161) to m.
+func (r *Reader) nameTree(n interface{}, m map[string]interface{}, seen map[int]bool) {
+	if ref, ok := n.(ref); ok {
+		if seen[ref.num] {
+			return
+		}
+		seen[ref.num] = true
+	}
+	t, ok := r.resolve(n).(map[string]interface{})
+	if !ok {
+		return
+	}
+	names, _ := r.resolve(t["Names"]).([]interface{})
+	for i := 0; i+1 < len(names); i += 2 {
+		if k, ok := names[i].(string); ok {
+			m[k] = names[i+1]
+		}
+	}
+	kids, _ := r.resolve(t["Kids"]).([]interface{})
+	for _, k := range kids {
+		r.nameTree(k, m, seen)
+	}
+}
+
+// pageBox returns the crop box of page p, or its media box if it has none.
+func (r *Reader) pageBox(p map[string]interface{}) *rect {
+	box := r.box(p["CropBox"])
+	if box == nil {
+		box = r.box(p["MediaBox"])
+	}
+	if box == nil {
+		fail(ErrBadFile, "page with no MediaBox")
+	}
+	return box
+}
+
+// rotation returns the rotation of page p, which is 0, 90, 180 or 270.
+func (r *Reader) rotation(p map[string]interface{}) int {
+	rot, _ := r.resolve(p["Rotate"]).(int)
+	if rot%90 != 0 {
+		fail(ErrBadFile, "rotation of page is not a multiple of 90")
+	}
+	return (rot%360 + 360) % 360
+}
+
+// box returns the rectangle o, or nil if it's not a rectangle.
+func (r *Reader) box(o interface{}) *rect {
+	a, _ := r.resolve(o).([]interface{})
+	if len(a) != 4 {
+		return nil
+	}
+	var v [4]float64
+	for i, e := range a {
+		var ok bool
+		if v[i], ok = r.number(e); !ok {
+			return nil
+		}
+	}
+	// Any two opposite corners can be used (p. 161).
+	return newRect(math.Min(v[0], v[2]), math.Min(v[1], v[3]),
+		math.Max(v[0], v[2]), math.Max(v[1], v[3]))
+}
+
+// number returns the value of o if it's a number.
+func (r *Reader) number(o interface{}) (float64, bool) {
+	switch t := r.resolve(o).(type) {
+	case int:
+		return float64(t), true
+	case float64:
+		return t, true
+	}
+	return 0, false
+}
+
+// contents returns the decoded content of the page or form whose Contents
+// entry is o, which is a stream or an array of streams.
+func (r *Reader) contents(o interface{}) []byte {
+	var ss []interface{}
+	switch t := r.resolve(o).(type) {
+	case *stream:
+		ss = []interface{}{t}
+	case []interface{}:
+		ss = t
+	}
+	var con []byte
+	for _, s := range ss {
+		s, ok := r.resolve(s).(*stream)
+		if !ok {
+			fail(ErrBadFile, "contents of page is not a stream")
+		}
+		b := r.decode(s)
+		if b == nil {
+			fail(ErrBadFile, "unsupported filter in contents of page")
+		}
+		con = append(append(con, b...), '\n')
+	}
+	return con
+}
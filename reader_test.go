@@ -0,0 +1,210 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// testCompressedFile makes a PDF file whose catalog and page tree are in an
+// object stream, with a cross-reference stream.
+func testCompressedFile() []byte {
+	objs := "<< /Type /Catalog /Pages 2 0 R >> << /Type /Pages /Kids [ ] /Count 0 >>"
+	header := fmt.Sprintf("1 0 2 %d ", len("<< /Type /Catalog /Pages 2 0 R >> "))
+	data := flate([]byte(header + objs))
+
+	buf := bytes.NewBufferString("%PDF-1.5\n")
+	off3 := buf.Len()
+	fmt.Fprintf(buf, "3 0 obj\n<< /Type /ObjStm /N 2 /First %d /Filter /FlateDecode /Length %d >>\n"+
+		"stream\n%s\nendstream\nendobj\n", len(header), len(data), data)
+
+	// Entries of objects 0 to 4 with W [ 1 2 1 ]
+	off4 := buf.Len()
+	xref := []byte{
+		0, 0, 0, 255,
+		2, 0, 3, 0,
+		2, 0, 3, 1,
+		1, byte(off3 >> 8), byte(off3), 0,
+		1, byte(off4 >> 8), byte(off4), 0,
+	}
+	fmt.Fprintf(buf, "4 0 obj\n<< /Type /XRef /Size 5 /W [ 1 2 1 ] /Root 1 0 R /Length %d >>\n"+
+		"stream\n%s\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", len(xref), xref, off4)
+	return buf.Bytes()
+}
+
+func TestReader(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(100, 200)
+	d.Rectangle(10, 10, 20, 20)
+	d.Stroke()
+	d.Close()
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := r.Version(); v != "1.7" {
+		t.Errorf("version: got %q expected 1.7", v)
+	}
+	cat, _ := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	if cat["Type"] != name("Catalog") {
+		t.Fatalf("catalog: got %v", cat)
+	}
+	pages, _ := r.resolve(cat["Pages"]).(map[string]interface{})
+	kids, _ := pages["Kids"].([]interface{})
+	if len(kids) != 1 {
+		t.Fatalf("kids of page tree: got %v", pages["Kids"])
+	}
+	pg, _ := r.resolve(kids[0]).(map[string]interface{})
+	if box := pg["MediaBox"]; !reflect.DeepEqual(box, []interface{}{0, 0, 100, 200}) {
+		t.Errorf("MediaBox: got %v", box)
+	}
+	s, _ := r.resolve(pg["Contents"].([]interface{})[0]).(*stream)
+	if s == nil || string(r.decode(s)) != "10 10 20 20 re\nS\n" {
+		t.Errorf("contents: got %v", s)
+	}
+	if _, ok := s.dic["Length"]; ok {
+		t.Error("Length kept in the dictionary of the stream")
+	}
+	if len(r.probs) != 0 {
+		t.Errorf("problems: %v", r.probs)
+	}
+}
+
+func TestReaderCompressed(t *testing.T) {
+	r, err := NewReader(testCompressedFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, _ := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	if cat["Type"] != name("Catalog") {
+		t.Fatalf("catalog: got %v", cat)
+	}
+	pages, _ := r.resolve(cat["Pages"]).(map[string]interface{})
+	if pages["Count"] != 0 {
+		t.Errorf("page tree: got %v", pages)
+	}
+	if o := r.object(9); o != nil {
+		t.Errorf("object that doesn't exist: got %v", o)
+	}
+}
+
+func TestReaderUpdate(t *testing.T) {
+	s := testSignature(t)
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.Sign(s)
+	d.NewPage(100, 100)
+	d.AddValidation(&Validation{Certificates: s.Certificates})
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, _ := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	if cat["DSS"] == nil {
+		t.Error("catalog of the incremental update not read")
+	}
+	if _, err := NewReader([]byte("%PDF-1.7\n")); err == nil {
+		t.Error("file with no startxref")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewReaderContext(ctx, buf.Bytes())
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("canceled reading: got error %v", err)
+	}
+}
+
+func TestReaderInfo(t *testing.T) {
+	xmp := "<x:xmpmeta xmlns:x=\"adobe:ns:meta/\"/>"
+	objs := testPage("", "<< >>")
+	objs[0] = "<< /Type /Catalog /Pages 2 0 R /Metadata 5 0 R >>"
+	objs[1] = "<< /Type /Pages /Kids [ 3 0 R 7 0 R ] /Count 2 /MediaBox [ 0 0 100 200 ] >>"
+	objs = append(objs,
+		fmt.Sprintf("<< /Type /Metadata /Subtype /XML /Length %d >>\nstream\n%s\nendstream", len(xmp), xmp),
+		"<< /Title <feff0041006c0069> /Producer (pdf.go) /CreationDate (D:20110830) /Trapped /False /Custom 5 >>",
+		"<< /Type /Page /Parent 2 0 R /CropBox [ 300 10 10 20 ] /Rotate -90 /Contents 4 0 R >>")
+	b := bytes.Replace(testFile(objs), []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Info 6 0 R"), 1)
+	r, err := NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := r.Info()
+	want := map[string]string{
+		"Title":        "Ali",
+		"Producer":     "pdf.go",
+		"CreationDate": "D:20110830",
+		"Trapped":      "False",
+	}
+	if err != nil || !reflect.DeepEqual(info, want) {
+		t.Errorf("info: got %v, %v", info, err)
+	}
+	if m, err := r.Metadata(); err != nil || string(m) != xmp {
+		t.Errorf("metadata: got %q, %v", m, err)
+	}
+	if n, err := r.NumPages(); err != nil || n != 2 {
+		t.Errorf("number of pages: got %d, %v", n, err)
+	}
+	for _, tt := range []struct {
+		n    int
+		size string
+	}{
+		{1, "100 200 <nil>"},
+		{2, "10 290 <nil>"},
+		{3, "0 0 pdf.go: size of page 3 of 2 pages"},
+	} {
+		if w, h, err := r.PageSize(tt.n); fmt.Sprint(w, " ", h, " ", err) != tt.size {
+			t.Errorf("size of page %d: got %v %v %v, want %s", tt.n, w, h, err, tt.size)
+		}
+	}
+	if r.Encrypted() {
+		t.Error("file is encrypted")
+	}
+}
+
+func TestReaderInfoEncrypted(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.Encrypt("", "", 128, PermAll)
+	d.SetInfo(&Info{Title: "secret"})
+	d.NewPage(100, 100)
+	d.Close()
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Encrypted() {
+		t.Error("file is not encrypted")
+	}
+	if info, err := r.Info(); err != nil || info["Title"] != "secret" {
+		t.Errorf("info of encrypted file: got %v, %v", info, err)
+	}
+	if n, err := r.NumPages(); err != nil || n != 1 {
+		t.Errorf("number of pages: got %d, %v", n, err)
+	}
+}
@@ -35,3 +35,13 @@ func (r *rect) output() []byte {
 	a := []float64{r.llx, r.lly, r.urx, r.ury}
 	return output(a)
 }
+
+// inside tells whether r is inside s.
+func (r *rect) inside(s *rect) bool {
+	return r.llx >= s.llx && r.lly >= s.lly && r.urx <= s.urx && r.ury <= s.ury
+}
+
+// overlaps tells whether r and s have any area in common.
+func (r *rect) overlaps(s *rect) bool {
+	return r.llx < s.urx && s.llx < r.urx && r.lly < s.ury && s.lly < r.ury
+}
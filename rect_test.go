@@ -18,7 +18,7 @@ package pdf
 
 import (
 	"bytes"
-	"rand"
+	"math/rand"
 	"testing"
 )
 
@@ -43,7 +43,7 @@ func TestRect(t *testing.T) {
 			if rand.Intn(2) == 0 {
 				sign = -1
 			}
-			return sign * rand.Int()
+			return sign * int(rand.Int31())
 		}
 
 		llx, lly, urx, ury := rnd(), rnd(), rnd(), rnd()
@@ -55,3 +55,28 @@ func TestRect(t *testing.T) {
 		}
 	}
 }
+
+type rectPairTest struct {
+	r, s            *rect
+	inside, overlap bool
+}
+
+func TestRectPairs(t *testing.T) {
+	tests := []rectPairTest{
+		{newRect(1, 1, 2, 2), newRect(0, 0, 3, 3), true, true},
+		{newRect(0, 0, 3, 3), newRect(0, 0, 3, 3), true, true},
+		{newRect(0, 0, 3, 3), newRect(1, 1, 2, 2), false, true},
+		{newRect(0, 0, 2, 2), newRect(1, 1, 3, 3), false, true},
+		{newRect(0, 0, 1, 1), newRect(1, 0, 2, 1), false, false},
+		{newRect(0, 0, 0, 0), newRect(0, 0, 3, 3), true, false},
+	}
+
+	for _, test := range tests {
+		if i := test.r.inside(test.s); i != test.inside {
+			t.Errorf("%s inside %s: got %v", test.r.output(), test.s.output(), i)
+		}
+		if o := test.r.overlaps(test.s); o != test.overlap {
+			t.Errorf("%s overlaps %s: got %v", test.r.output(), test.s.output(), o)
+		}
+	}
+}
@@ -0,0 +1,936 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file renders pages of existing files to images, for previews like
+// thumbnails and for comparing pages in tests. It's a basic renderer: paths,
+// images and text are drawn, with the glyphs of embedded TrueType fonts and
+// of Type 3 fonts, but not shadings, patterns, blend modes or soft masks of
+// the graphics state.
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// maxPixels limits the size of rendered pages.
+const maxPixels = 1 << 26
+
+// RenderPage returns an image of page n of the file, counting from 1, with
+// dpi pixels per inch, on white. Annotations are drawn with their
+// appearances, like viewers show them. Glyphs of fonts that aren't embedded,
+// or whose embedded programs aren't TrueType, are drawn as gray bars, and
+// the painting of patterns is drawn in gray. Pages of documents made by the
+// package can be rendered by reading what they write.
+func (r *Reader) RenderPage(n int, dpi float64) (m *image.RGBA, err error) {
+	defer dontPanic(&err)
+
+	pgs := r.pages()
+	if n < 1 || n > len(pgs) {
+		panic(fmt.Sprintf("rendering page %d of %d pages", n, len(pgs)))
+	}
+	if dpi <= 0 {
+		panic(fmt.Sprint("bad resolution: ", dpi))
+	}
+	p := pgs[n-1].dic
+	box := r.pageBox(p)
+	s := dpi / 72
+	w, h := (box.urx-box.llx)*s, (box.ury-box.lly)*s
+	var rot matrix
+	switch r.rotation(p) {
+	case 0:
+		rot = matrix{1, 0, 0, -1, 0, h}
+	case 90:
+		rot = matrix{0, 1, 1, 0, 0, 0}
+	case 180:
+		rot = matrix{-1, 0, 0, 1, w, 0}
+	case 270:
+		rot = matrix{0, -1, -1, 0, h, w}
+	}
+	base := matrix{s, 0, 0, s, -box.llx * s, -box.lly * s}.mul(rot)
+	var b bounds
+	b.addRect(*box, base)
+	iw, ih := int(math.Ceil(b.r.urx-0.001)), int(math.Ceil(b.r.ury-0.001))
+	if iw < 1 || ih < 1 || float64(iw)*float64(ih) > maxPixels {
+		panic(fmt.Sprintf("bad size of image of page: %dx%d", iw, ih))
+	}
+	m = image.NewRGBA(image.Rect(0, 0, iw, ih))
+	draw.Draw(m, m.Rect, image.White, image.Point{}, draw.Src)
+
+	rn := &renderer{extractor: extractor{r: r, fonts: make(map[int]*rfont)}, img: m,
+		glyphs: make(map[*rfont]*glyphSet), images: make(map[*stream]*rimage)}
+	rn.reset(base)
+	rn.run(r.contents(p["Contents"]), p["Resources"])
+	annots, _ := r.resolve(p["Annots"]).([]interface{})
+	for _, a := range annots {
+		ap, am := r.flatAppearance(a)
+		if s, ok := r.resolve(ap).(*stream); ok {
+			rn.reset(am.mul(base))
+			rn.form(s, p["Resources"])
+		}
+	}
+	return m, nil
+}
+
+// invert returns the inverse of m, and false if m can't be inverted.
+func (m matrix) invert() (matrix, bool) {
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 || math.IsNaN(det) {
+		return matrix{}, false
+	}
+	return matrix{m[3] / det, -m[1] / det, -m[2] / det, m[0] / det,
+		(m[2]*m[5] - m[3]*m[4]) / det, (m[1]*m[4] - m[0]*m[5]) / det}, true
+}
+
+// scale returns about how long a unit transformed by m is.
+func (m matrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// rspace is a color space of a parsed file, as much of it as is needed to
+// turn its colors to RGB.
+type rspace struct {
+	n       int       // number of components
+	initial []float64 // color that's set with the color space
+	rgb     func(c []float64) [3]float64
+}
+
+var (
+	spaceGray = &rspace{1, []float64{0}, func(c []float64) [3]float64 { return [3]float64{c[0], c[0], c[0]} }}
+	spaceRGB  = &rspace{3, []float64{0, 0, 0}, func(c []float64) [3]float64 { return [3]float64{c[0], c[1], c[2]} }}
+	spaceCMYK = &rspace{4, []float64{0, 0, 0, 1}, func(c []float64) [3]float64 {
+		k := 1 - c[3]
+		return [3]float64{(1 - c[0]) * k, (1 - c[1]) * k, (1 - c[2]) * k}
+	}}
+	// Patterns are painted in gray.
+	spacePattern = &rspace{0, nil, func(c []float64) [3]float64 { return [3]float64{0.5, 0.5, 0.5} }}
+)
+
+// space returns the color space cs, which is a name or an array, with
+// resources res.
+func (rn *renderer) space(cs interface{}, res map[string]interface{}) *rspace {
+	r := rn.r
+	cs = r.resolve(cs)
+	if n, ok := cs.(name); ok {
+		switch n {
+		case "DeviceGray", "G", "CalGray":
+			return spaceGray
+		case "DeviceRGB", "RGB", "CalRGB":
+			return spaceRGB
+		case "DeviceCMYK", "CMYK":
+			return spaceCMYK
+		case "Pattern":
+			return spacePattern
+		}
+		spaces, _ := r.resolve(res["ColorSpace"]).(map[string]interface{})
+		if s, ok := spaces[string(n)]; ok && rn.depth < maxFormDepth {
+			rn.depth++
+			defer func() { rn.depth-- }()
+			return rn.space(s, nil)
+		}
+		return spaceGray
+	}
+	a, _ := cs.([]interface{})
+	if len(a) == 0 {
+		return spaceGray
+	}
+	switch r.resolve(a[0]) {
+	case name("CalGray"):
+		return spaceGray
+	case name("CalRGB"):
+		return spaceRGB
+	case name("Lab"):
+		return &rspace{3, []float64{0, 0, 0}, func(c []float64) [3]float64 {
+			return [3]float64{c[0] / 100, c[0] / 100, c[0] / 100}
+		}}
+	case name("ICCBased"):
+		if len(a) > 1 {
+			if s, ok := r.resolve(a[1]).(*stream); ok {
+				switch n, _ := r.number(s.dic["N"]); n {
+				case 3:
+					return spaceRGB
+				case 4:
+					return spaceCMYK
+				}
+			}
+		}
+		return spaceGray
+	case name("Indexed"):
+		if len(a) < 4 || rn.depth >= maxFormDepth {
+			return spaceGray
+		}
+		rn.depth++
+		base := rn.space(a[1], res)
+		rn.depth--
+		var lut []byte
+		switch l := r.resolve(a[3]).(type) {
+		case string:
+			lut = []byte(l)
+		case *stream:
+			lut = r.decode(l)
+		}
+		return &rspace{1, []float64{0}, func(c []float64) [3]float64 {
+			i := int(c[0])
+			v := make([]float64, base.n)
+			if i < 0 || (i+1)*base.n > len(lut) {
+				return base.rgb(base.initial)
+			}
+			for j := range v {
+				v[j] = float64(lut[i*base.n+j]) / 255
+			}
+			return base.rgb(v)
+		}}
+	case name("Separation"), name("DeviceN"):
+		// Tints are shown as shades of gray, which is what they are
+		// in black.
+		n := 1
+		if names, ok := r.resolve(a[1]).([]interface{}); ok && len(a) > 1 && r.resolve(a[0]) == name("DeviceN") {
+			n = len(names)
+		}
+		initial := make([]float64, n)
+		for i := range initial {
+			initial[i] = 1
+		}
+		return &rspace{n, initial, func(c []float64) [3]float64 {
+			t := 0.0
+			for _, v := range c {
+				t = math.Max(t, v)
+			}
+			return [3]float64{1 - t, 1 - t, 1 - t}
+		}}
+	case name("Pattern"):
+		return spacePattern
+	}
+	return spaceGray
+}
+
+// paintState is the part of the graphics state that's used for painting.
+type paintState struct {
+	fillSpace, strokeSpace *rspace
+	fill, stroke           [3]float64 // RGB colors
+	fillAlpha, strokeAlpha float64
+	line                   strokeStyle
+	clip                   *cover // of the whole image; nil if nothing is clipped
+	render                 int    // text rendering mode
+}
+
+// glyphSet has the glyphs of a font of a parsed file.
+type glyphSet struct {
+	sf     *sfnt               // embedded TrueType font, if any
+	cid    bool                // whether codes are CIDs of a Type0 font
+	cidGID []byte              // CIDToGIDMap of the CID font; nil for Identity
+	ops    map[int][]operation // operators of the outlines of glyphs, by glyph
+	procs  map[int]*stream     // glyph descriptions of a Type 3 font, by code
+	fm     matrix              // font matrix of a Type 3 font
+	res    interface{}         // resources of a Type 3 font
+}
+
+// rimage is an image decoded for rendering.
+type rimage struct {
+	m, mask image.Image // mask is the soft mask, if any
+	stencil bool        // whether m is a stencil mask, painted where it's black
+}
+
+// renderer draws content streams on an image. It follows the text state
+// like extractor.
+type renderer struct {
+	extractor
+	img    *image.RGBA
+	ps     paintState
+	pstack []paintState // states saved by q
+	path   []subpath
+	cur    point // current point of the path
+	clip   int   // 1 if the path clips with the nonzero rule, 2 with the even-odd rule
+	glyphs map[*rfont]*glyphSet
+	images map[*stream]*rimage
+}
+
+// reset starts drawing with the default graphics state and transformation
+// matrix ctm.
+func (rn *renderer) reset(ctm matrix) {
+	rn.gs = textState{ctm: ctm, th: 1}
+	rn.stack, rn.pstack, rn.path, rn.clip = nil, nil, nil, 0
+	rn.ps = paintState{fillSpace: spaceGray, strokeSpace: spaceGray, fillAlpha: 1, strokeAlpha: 1,
+		line: strokeStyle{width: 1, miterLimit: 10}}
+}
+
+// run draws content stream b with resources res.
+func (rn *renderer) run(b []byte, res interface{}) {
+	resd, _ := rn.r.resolve(res).(map[string]interface{})
+	ops := operations(b)
+	for i := 0; i < len(ops); i++ {
+		op := ops[i]
+		switch op.op {
+		case "q":
+			rn.pstack = append(rn.pstack, rn.ps)
+		case "Q":
+			if len(rn.pstack) > 0 && len(rn.stack) > 0 {
+				rn.ps = rn.pstack[len(rn.pstack)-1]
+				rn.pstack = rn.pstack[:len(rn.pstack)-1]
+			}
+		}
+		if rn.state(op, resd) {
+			continue
+		}
+		v, _ := rn.numbers(op.args, len(op.args))
+		switch op.op {
+		case "m", "l", "c", "v", "y", "h", "re":
+			rn.pathOp(op.op, v)
+		case "W":
+			rn.clip = 1
+		case "W*":
+			rn.clip = 2
+		case "f", "F", "f*", "S", "s", "B", "B*", "b", "b*", "n":
+			rn.paintPath(op.op)
+		case "w", "J", "j", "M":
+			if len(v) == 1 {
+				switch op.op {
+				case "w":
+					rn.ps.line.width = v[0]
+				case "J":
+					rn.ps.line.cap = int(v[0])
+				case "j":
+					rn.ps.line.join = int(v[0])
+				case "M":
+					rn.ps.line.miterLimit = v[0]
+				}
+			}
+		case "d":
+			if len(op.args) == 2 {
+				rn.dash(op.args[0], op.args[1])
+			}
+		case "gs":
+			if len(op.args) == 1 {
+				rn.extGState(resd, op.args[0])
+			}
+		case "g", "rg", "k":
+			rn.setColor(op.args, false, map[string]*rspace{"g": spaceGray, "rg": spaceRGB, "k": spaceCMYK}[op.op])
+		case "G", "RG", "K":
+			rn.setColor(op.args, true, map[string]*rspace{"G": spaceGray, "RG": spaceRGB, "K": spaceCMYK}[op.op])
+		case "cs", "CS":
+			if len(op.args) == 1 {
+				rn.setColor(nil, op.op == "CS", rn.space(op.args[0], resd))
+			}
+		case "sc", "scn":
+			rn.setColor(op.args, false, nil)
+		case "SC", "SCN":
+			rn.setColor(op.args, true, nil)
+		case "Tr":
+			if len(v) == 1 {
+				rn.ps.render = int(v[0])
+			}
+		case "Tj":
+			rn.show(op.args)
+		case "'":
+			rn.move(0, -rn.gs.tl)
+			rn.show(op.args)
+		case "\"":
+			if len(op.args) == 3 {
+				rn.gs.tw, _ = rn.r.number(op.args[0])
+				rn.gs.tc, _ = rn.r.number(op.args[1])
+				rn.move(0, -rn.gs.tl)
+				rn.show(op.args[2:])
+			}
+		case "TJ":
+			if len(op.args) == 1 {
+				a, _ := op.args[0].([]interface{})
+				rn.show(a)
+			}
+		case "Do":
+			if len(op.args) == 1 {
+				rn.xobject(resd, op.args[0], res)
+			}
+		case "BI":
+			if i+1 < len(ops) && ops[i+1].op == "ID" {
+				rn.inlineImage(ops[i+1].args)
+				i++
+			}
+		}
+	}
+}
+
+// pathOp adds the path construction operator op with operands v to the
+// path, flattening curves.
+func (rn *renderer) pathOp(op string, v []float64) {
+	scale := rn.gs.ctm.scale()
+	last := func() *subpath {
+		if len(rn.path) == 0 {
+			rn.path = append(rn.path, subpath{pts: []point{rn.cur}})
+		}
+		return &rn.path[len(rn.path)-1]
+	}
+	n := map[string]int{"m": 2, "l": 2, "c": 6, "v": 4, "y": 4, "h": 0, "re": 4}[op]
+	if len(v) != n {
+		return
+	}
+	switch op {
+	case "m":
+		rn.cur = point{v[0], v[1]}
+		rn.path = append(rn.path, subpath{pts: []point{rn.cur}})
+	case "l":
+		rn.cur = point{v[0], v[1]}
+		sp := last()
+		sp.pts = append(sp.pts, rn.cur)
+	case "c", "v", "y":
+		p1, p2, p3 := point{v[0], v[1]}, point{v[2], v[3]}, point{}
+		switch op {
+		case "c":
+			p3 = point{v[4], v[5]}
+		case "v":
+			p1, p2, p3 = rn.cur, p1, p2
+		case "y":
+			p3 = p2
+			p2 = p3
+		}
+		sp := last()
+		sp.pts = flatten(sp.pts, rn.cur, p1, p2, p3, scale)
+		rn.cur = p3
+	case "h":
+		if len(rn.path) > 0 {
+			sp := last()
+			sp.closed = true
+			rn.cur = sp.pts[0]
+			rn.path = append(rn.path, subpath{pts: []point{rn.cur}})
+		}
+	case "re":
+		x, y, w, h := v[0], v[1], v[2], v[3]
+		rn.path = append(rn.path, subpath{pts: []point{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}}, closed: true})
+		rn.cur = point{x, y}
+		rn.path = append(rn.path, subpath{pts: []point{rn.cur}})
+	}
+}
+
+// fillPolygons returns the polygons of the path filled, in device space.
+func (rn *renderer) fillPolygons() []polygon {
+	var ps []polygon
+	for _, sp := range rn.path {
+		if len(sp.pts) > 2 {
+			ps = append(ps, polygon(sp.pts).transform(rn.gs.ctm))
+		}
+	}
+	return ps
+}
+
+// paintPath paints the path with painting operator op, and ends it.
+func (rn *renderer) paintPath(op string) {
+	evenOdd := op == "f*" || op == "B*" || op == "b*"
+	if op == "s" || op == "b" || op == "b*" {
+		rn.pathOp("h", nil)
+	}
+	bounds := rn.img.Rect
+	switch op {
+	case "f", "F", "f*", "B", "B*", "b", "b*":
+		rn.paint(coverage(rn.fillPolygons(), bounds, evenOdd), rn.ps.fill, rn.ps.fillAlpha)
+	}
+	switch op {
+	case "S", "s", "B", "B*", "b", "b*":
+		st := rn.ps.line
+		scale := rn.gs.ctm.scale()
+		if st.width <= 0 && scale > 0 {
+			// The thinnest line is a pixel wide.
+			st.width = 1 / scale
+		}
+		var ps []polygon
+		for _, sp := range rn.path {
+			for _, d := range st.dashes(sp) {
+				for _, p := range st.outline(d, scale) {
+					ps = append(ps, p.transform(rn.gs.ctm))
+				}
+			}
+		}
+		rn.paint(coverage(ps, bounds, false), rn.ps.stroke, rn.ps.strokeAlpha)
+	}
+	if rn.clip != 0 {
+		rn.clipTo(coverage(rn.fillPolygons(), bounds, rn.clip == 2))
+	}
+	rn.path, rn.clip = nil, 0
+}
+
+// clipTo intersects the clipping path with c.
+func (rn *renderer) clipTo(c *cover) {
+	r := rn.img.Rect
+	clip := &cover{r, make([]float32, r.Dx()*r.Dy())}
+	if c != nil {
+		for y := c.r.Min.Y; y < c.r.Max.Y; y++ {
+			for x := c.r.Min.X; x < c.r.Max.X; x++ {
+				a := c.at(x, y)
+				if rn.ps.clip != nil {
+					a *= rn.ps.clip.at(x, y)
+				}
+				clip.a[y*r.Dx()+x] = a
+			}
+		}
+	}
+	rn.ps.clip = clip
+}
+
+// paint paints the pixels covered by c with color col and opacity alpha.
+func (rn *renderer) paint(c *cover, col [3]float64, alpha float64) {
+	if c == nil {
+		return
+	}
+	for y := c.r.Min.Y; y < c.r.Max.Y; y++ {
+		for x := c.r.Min.X; x < c.r.Max.X; x++ {
+			rn.blend(x, y, col, float64(c.at(x, y))*alpha)
+		}
+	}
+}
+
+// blend paints pixel (x, y) with color col and opacity a, inside the
+// clipping path.
+func (rn *renderer) blend(x, y int, col [3]float64, a float64) {
+	if rn.ps.clip != nil {
+		a *= float64(rn.ps.clip.at(x, y))
+	}
+	if a <= 0 {
+		return
+	}
+	if a > 1 {
+		a = 1
+	}
+	i := rn.img.PixOffset(x, y)
+	px := rn.img.Pix[i : i+3]
+	for j, c := range col {
+		c = math.Max(0, math.Min(1, c)) * 255
+		px[j] = uint8(float64(px[j]) + (c-float64(px[j]))*a + 0.5)
+	}
+}
+
+// setColor sets the fill or stroke color to the numbers in a. cs is the new
+// color space, which sets the color to its initial one if a is empty; it's
+// nil to keep the current one.
+func (rn *renderer) setColor(a []interface{}, stroke bool, cs *rspace) {
+	space, col := &rn.ps.fillSpace, &rn.ps.fill
+	if stroke {
+		space, col = &rn.ps.strokeSpace, &rn.ps.stroke
+	}
+	if cs != nil {
+		*space = cs
+	}
+	v := (*space).initial
+	if len(a) > 0 {
+		// Operands after the components, like the names of patterns,
+		// are left out.
+		nv, ok := rn.numbers(a[:min(len(a), (*space).n)], (*space).n)
+		if !ok {
+			if *space == spacePattern {
+				*col = spacePattern.rgb(nil)
+			}
+			return
+		}
+		v = nv
+	}
+	*col = (*space).rgb(v)
+}
+
+// dash sets the dash pattern to array a and phase p.
+func (rn *renderer) dash(a, p interface{}) {
+	arr, _ := rn.r.resolve(a).([]interface{})
+	v, ok := rn.numbers(arr, len(arr))
+	if !ok {
+		return
+	}
+	rn.ps.line.dash = v
+	rn.ps.line.phase, _ = rn.r.number(p)
+}
+
+// extGState sets the parameters of the graphics state dictionary named n in
+// the resources res.
+func (rn *renderer) extGState(res map[string]interface{}, n interface{}) {
+	k, _ := n.(name)
+	gss, _ := rn.r.resolve(res["ExtGState"]).(map[string]interface{})
+	gs, _ := rn.r.resolve(gss[string(k)]).(map[string]interface{})
+	num := func(k string) (float64, bool) { return rn.r.number(gs[k]) }
+	if v, ok := num("LW"); ok {
+		rn.ps.line.width = v
+	}
+	if v, ok := num("LC"); ok {
+		rn.ps.line.cap = int(v)
+	}
+	if v, ok := num("LJ"); ok {
+		rn.ps.line.join = int(v)
+	}
+	if v, ok := num("ML"); ok {
+		rn.ps.line.miterLimit = v
+	}
+	if v, ok := num("ca"); ok {
+		rn.ps.fillAlpha = v
+	}
+	if v, ok := num("CA"); ok {
+		rn.ps.strokeAlpha = v
+	}
+	if d, ok := rn.r.resolve(gs["D"]).([]interface{}); ok && len(d) == 2 {
+		rn.dash(d[0], d[1])
+	}
+}
+
+// show draws the strings in a, and moves by the numbers in it like TJ.
+func (rn *renderer) show(a []interface{}) {
+	gs := &rn.gs
+	if gs.font == nil {
+		return
+	}
+	g := rn.glyphs[gs.font]
+	for _, o := range a {
+		s, ok := o.(string)
+		if !ok {
+			if v, ok := rn.r.number(o); ok {
+				rn.tm = matrix{1, 0, 0, 1, -v / 1000 * gs.size * gs.th, 0}.mul(rn.tm)
+			}
+			continue
+		}
+		for _, c := range gs.font.chars(s) {
+			// Modes 3 and 7 are invisible; clipping by text is left out.
+			if rn.ps.render%4 != 3 {
+				trm := matrix{gs.size * gs.th, 0, 0, gs.size, 0, gs.rise}.mul(rn.tm).mul(gs.ctm)
+				rn.glyph(g, c, trm)
+			}
+			rn.tm = matrix{1, 0, 0, 1, gs.advance(c) * gs.th, 0}.mul(rn.tm)
+		}
+	}
+}
+
+// glyph draws character c of font g with text rendering matrix trm.
+func (rn *renderer) glyph(g *glyphSet, c fchar, trm matrix) {
+	col, alpha := rn.ps.fill, rn.ps.fillAlpha
+	if rn.ps.render%4 == 1 {
+		col, alpha = rn.ps.stroke, rn.ps.strokeAlpha
+	}
+	if g != nil && g.procs != nil {
+		if s, ok := g.procs[c.code]; ok && rn.depth < maxFormDepth {
+			rn.type3(g, s, trm)
+		}
+		return
+	}
+	if g != nil && g.sf != nil {
+		if ops, ok := rn.outline(g, c); ok {
+			upem := 1 / float64(g.sf.unitsPerEm)
+			m := matrix{upem, 0, 0, upem, 0, 0}.mul(trm)
+			saved, cur := rn.path, rn.cur
+			rn.path = nil
+			for _, op := range ops {
+				v, _ := rn.numbers(op.args, len(op.args))
+				rn.pathOpScaled(op.op, v, m.scale())
+			}
+			var ps []polygon
+			for _, sp := range rn.path {
+				if len(sp.pts) > 2 {
+					ps = append(ps, polygon(sp.pts).transform(m))
+				}
+			}
+			rn.path, rn.cur = saved, cur
+			rn.paint(coverage(ps, rn.img.Rect, false), col, alpha)
+			return
+		}
+	}
+	// Glyphs that can't be drawn are bars as high as lowercase letters,
+	// in a lighter color.
+	if c.text == " " || c.text == "" {
+		return
+	}
+	w := rn.gs.font.width(c.code)
+	bar := polygon{{0.05 * w, 0}, {0.95 * w, 0}, {0.95 * w, 0.5}, {0.05 * w, 0.5}}
+	rn.paint(coverage([]polygon{bar.transform(trm)}, rn.img.Rect, false), col, alpha*0.4)
+}
+
+// pathOpScaled is like pathOp, but flattens curves for a transformation
+// that scales by scale, instead of the current one.
+func (rn *renderer) pathOpScaled(op string, v []float64, scale float64) {
+	ctm := rn.gs.ctm
+	rn.gs.ctm = matrix{scale, 0, 0, scale, 0, 0}
+	rn.pathOp(op, v)
+	rn.gs.ctm = ctm
+}
+
+// outline returns the operators of the outline of character c of the
+// TrueType font of g, in the units of the font, and whether it has one.
+func (rn *renderer) outline(g *glyphSet, c fchar) ([]operation, bool) {
+	gid := 0
+	switch {
+	case g.cid && g.cidGID == nil:
+		gid = c.code
+	case g.cid:
+		if 2*c.code+1 < len(g.cidGID) {
+			gid = int(g.cidGID[2*c.code])<<8 | int(g.cidGID[2*c.code+1])
+		}
+	default:
+		for _, r := range c.text {
+			recovered(func() { gid = g.sf.glyphIndex(r) })
+			break
+		}
+	}
+	if gid <= 0 || gid >= g.sf.numGlyphs {
+		return nil, false
+	}
+	if ops, ok := g.ops[gid]; ok {
+		return ops, ops != nil
+	}
+	var ops []operation
+	if e := recovered(func() {
+		w := NewContentWriter(nil)
+		g.sf.glyphPath(w, gid, identity, 0)
+		ops = operations(w.Bytes())
+	}); e != nil {
+		ops = nil
+	}
+	g.ops[gid] = ops
+	return ops, ops != nil
+}
+
+// type3 draws the glyph of a Type 3 font g described by s, with text
+// rendering matrix trm.
+func (rn *renderer) type3(g *glyphSet, s *stream, trm matrix) {
+	b := rn.r.decode(s)
+	if b == nil {
+		return
+	}
+	gs, stack, tm, tlm := rn.gs, rn.stack, rn.tm, rn.tlm
+	ps, pstack, path, cur := rn.ps, rn.pstack, rn.path, rn.cur
+	rn.gs.ctm = g.fm.mul(trm)
+	rn.stack, rn.pstack, rn.path = nil, nil, nil
+	rn.depth++
+	recovered(func() { rn.run(b, g.res) })
+	rn.depth--
+	rn.gs, rn.stack, rn.tm, rn.tlm = gs, stack, tm, tlm
+	rn.ps, rn.pstack, rn.path, rn.cur = ps, pstack, path, cur
+}
+
+// state is like the one of extractor, but also reads the glyphs of fonts
+// set by Tf.
+func (rn *renderer) state(op operation, res map[string]interface{}) bool {
+	if !rn.extractor.state(op, res) {
+		return false
+	}
+	if op.op == "Tf" && rn.gs.font != nil {
+		if _, ok := rn.glyphs[rn.gs.font]; !ok {
+			fonts, _ := rn.r.resolve(res["Font"]).(map[string]interface{})
+			k, _ := op.args[0].(name)
+			d, _ := rn.r.resolve(fonts[string(k)]).(map[string]interface{})
+			rn.glyphs[rn.gs.font] = rn.glyphSet(d, res)
+		}
+	}
+	return true
+}
+
+// glyphSet reads the glyphs of font d, which is in resources res.
+func (rn *renderer) glyphSet(d, res map[string]interface{}) *glyphSet {
+	r := rn.r
+	g := &glyphSet{ops: make(map[int][]operation)}
+	fd := d
+	switch r.resolve(d["Subtype"]) {
+	case name("Type3"):
+		g.fm = matrix{0.001, 0, 0, 0.001, 0, 0}
+		if a, ok := r.resolve(d["FontMatrix"]).([]interface{}); ok {
+			if v, ok := rn.numbers(a, 6); ok {
+				g.fm = matrix{v[0], v[1], v[2], v[3], v[4], v[5]}
+			}
+		}
+		g.res = res
+		if _, ok := d["Resources"]; ok {
+			g.res = d["Resources"]
+		}
+		procs, _ := r.resolve(d["CharProcs"]).(map[string]interface{})
+		enc, _ := r.resolve(d["Encoding"]).(map[string]interface{})
+		diffs, _ := r.resolve(enc["Differences"]).([]interface{})
+		g.procs = make(map[int]*stream)
+		code := 0
+		for _, o := range diffs {
+			switch t := r.resolve(o).(type) {
+			case int:
+				code = t
+			case name:
+				if s, ok := r.resolve(procs[string(t)]).(*stream); ok {
+					g.procs[code] = s
+				}
+				code++
+			}
+		}
+		return g
+	case name("Type0"):
+		g.cid = true
+		descs, _ := r.resolve(d["DescendantFonts"]).([]interface{})
+		if len(descs) == 0 {
+			return g
+		}
+		fd, _ = r.resolve(descs[0]).(map[string]interface{})
+		if s, ok := r.resolve(fd["CIDToGIDMap"]).(*stream); ok {
+			g.cidGID = r.decode(s)
+		}
+	}
+	desc, _ := r.resolve(fd["FontDescriptor"]).(map[string]interface{})
+	s, ok := r.resolve(desc["FontFile2"]).(*stream)
+	if !ok {
+		if s, ok = r.resolve(desc["FontFile3"]).(*stream); !ok || r.resolve(s.dic["Subtype"]) != name("OpenType") {
+			return g
+		}
+	}
+	if b := r.decode(s); b != nil {
+		recovered(func() {
+			sf := parseSfnt(b)
+			if _, ok := sf.tables["glyf"]; ok {
+				g.sf = sf
+			}
+		})
+	}
+	return g
+}
+
+// xobject draws the XObject named n in the resources res. Forms with no
+// resources use pres, the ones of the content drawing them.
+func (rn *renderer) xobject(res map[string]interface{}, n, pres interface{}) {
+	k, _ := n.(name)
+	xobjs, _ := rn.r.resolve(res["XObject"]).(map[string]interface{})
+	s, ok := rn.r.resolve(xobjs[string(k)]).(*stream)
+	if !ok {
+		return
+	}
+	switch rn.r.resolve(s.dic["Subtype"]) {
+	case name("Image"):
+		rn.image(s)
+	case name("Form"):
+		rn.form(s, pres)
+	}
+}
+
+// form draws form s, clipped by its bounding box. pres are the resources
+// used if it has none.
+func (rn *renderer) form(s *stream, pres interface{}) {
+	bbox := rn.r.box(s.dic["BBox"])
+	if bbox == nil || rn.depth >= maxFormDepth {
+		return
+	}
+	b := rn.r.decode(s)
+	if b == nil {
+		return
+	}
+	if r, ok := s.dic["Resources"]; ok {
+		pres = r
+	}
+	gs, stack, tm, tlm := rn.gs, rn.stack, rn.tm, rn.tlm
+	ps, pstack, path, cur := rn.ps, rn.pstack, rn.path, rn.cur
+	if a, ok := rn.r.resolve(s.dic["Matrix"]).([]interface{}); ok {
+		if v, ok := rn.numbers(a, 6); ok {
+			rn.gs.ctm = matrix{v[0], v[1], v[2], v[3], v[4], v[5]}.mul(rn.gs.ctm)
+		}
+	}
+	rn.path = nil
+	rn.pathOp("re", []float64{bbox.llx, bbox.lly, bbox.urx - bbox.llx, bbox.ury - bbox.lly})
+	rn.clip = 1
+	rn.paintPath("n")
+	rn.stack, rn.pstack = nil, nil
+	rn.depth++
+	rn.run(b, pres)
+	rn.depth--
+	rn.gs, rn.stack, rn.tm, rn.tlm = gs, stack, tm, tlm
+	rn.ps, rn.pstack, rn.path, rn.cur = ps, pstack, path, cur
+}
+
+// inlineKeys holds the full names of the abbreviated entries of inline
+// images, and inlineNames the ones of their values.
+var (
+	inlineKeys = map[name]string{
+		"BPC": "BitsPerComponent", "CS": "ColorSpace", "D": "Decode", "DP": "DecodeParms",
+		"F": "Filter", "H": "Height", "IM": "ImageMask", "I": "Interpolate", "W": "Width",
+	}
+	inlineNames = map[name]name{"G": "DeviceGray", "RGB": "DeviceRGB", "CMYK": "DeviceCMYK", "I": "Indexed"}
+)
+
+// inlineImage draws the inline image with the operands a of its ID
+// operator.
+func (rn *renderer) inlineImage(a []interface{}) {
+	if len(a)%2 != 1 {
+		return
+	}
+	dic := make(map[string]interface{})
+	for i := 0; i+1 < len(a); i += 2 {
+		k, _ := a[i].(name)
+		v := a[i+1]
+		if n, ok := v.(name); ok && inlineNames[n] != "" {
+			v = inlineNames[n]
+		}
+		if full, ok := inlineKeys[k]; ok {
+			k = name(full)
+		}
+		dic[string(k)] = v
+	}
+	data, _ := a[len(a)-1].(string)
+	rn.image(&stream{dic, []byte(data)})
+}
+
+// image draws image s in the unit square of user space.
+func (rn *renderer) image(s *stream) {
+	im, ok := rn.images[s]
+	if !ok {
+		im = rn.decodeImage(s)
+		rn.images[s] = im
+	}
+	inv, ok := rn.gs.ctm.invert()
+	if im == nil || !ok {
+		return
+	}
+	var b bounds
+	b.addRect(rect{0, 0, 1, 1}, rn.gs.ctm)
+	r := image.Rect(int(math.Floor(b.r.llx)), int(math.Floor(b.r.lly)),
+		int(math.Ceil(b.r.urx)), int(math.Ceil(b.r.ury))).Intersect(rn.img.Rect)
+	ib := im.m.Bounds()
+	// sample returns the pixel of m at (u, v) of the unit square.
+	sample := func(m image.Image, u, v float64) color.Color {
+		mb := m.Bounds()
+		x := mb.Min.X + min(int(u*float64(mb.Dx())), mb.Dx()-1)
+		y := mb.Min.Y + min(int((1-v)*float64(mb.Dy())), mb.Dy()-1)
+		return m.At(x, y)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			u, v := inv.apply(float64(x)+0.5, float64(y)+0.5)
+			if u < 0 || u >= 1 || v < 0 || v >= 1 || ib.Empty() {
+				continue
+			}
+			cr, cg, cb, _ := sample(im.m, u, v).RGBA()
+			col := [3]float64{float64(cr) / 0xffff, float64(cg) / 0xffff, float64(cb) / 0xffff}
+			a := rn.ps.fillAlpha
+			if im.stencil {
+				if cr >= 0x8000 {
+					continue
+				}
+				col = rn.ps.fill
+			}
+			if im.mask != nil {
+				ma, _, _, _ := sample(im.mask, u, v).RGBA()
+				a *= float64(ma) / 0xffff
+			}
+			rn.blend(x, y, col, a)
+		}
+	}
+}
+
+// decodeImage returns image s decoded, or nil if it can't be.
+func (rn *renderer) decodeImage(s *stream) *rimage {
+	im := &rimage{}
+	var err error
+	if im.m, err = rn.r.image(s, 0, "").Decode(); err != nil {
+		return nil
+	}
+	im.stencil, _ = rn.r.resolve(s.dic["ImageMask"]).(bool)
+	if sm, ok := rn.r.resolve(s.dic["SMask"]).(*stream); ok {
+		im.mask, _ = rn.r.image(sm, 0, "").Decode()
+	}
+	return im
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testRender returns a parsed file of one page with content c and resources
+// res. The page is rotated by rot degrees and has the given media box.
+func testRender(t *testing.T, c, res, box string, rot int, extra ...string) *Reader {
+	objs := append(testPage(c, res), extra...)
+	objs[2] = strings.Replace(objs[2], "/Resources", "/MediaBox "+box+" /Rotate "+strconv.Itoa(rot)+" /Resources", 1)
+	r, err := NewReader(testFile(objs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRenderPage(t *testing.T) {
+	c := "1 0 0 rg 10 10 30 30 re f 0 0 1 RG 4 w 60 50 m 90 50 l S\n" +
+		"q 50 0 0 20 50 70 cm /Im1 Do Q\n" +
+		"q 0 0 50 10 re W n 0 g 0 0 100 10 re f Q\n" +
+		"q /G1 gs 0 g 50 10 50 20 re f Q"
+	r := testRender(t, c, "<< /XObject << /Im1 5 0 R >> /ExtGState << /G1 6 0 R >> >>", "[ 0 0 100 100 ]", 0,
+		"<< /Type /XObject /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Length 6 >>\nstream\n\x00\xff\x00\x00\x00\xff\nendstream",
+		"<< /Type /ExtGState /ca 0.5 >>")
+	m, err := r.RenderPage(1, 72)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := m.Bounds(); b.Dx() != 100 || b.Dy() != 100 {
+		t.Fatalf("size of image: got %v", b)
+	}
+	tests := []struct {
+		x, y int
+		c    color.RGBA
+	}{
+		{5, 5, color.RGBA{255, 255, 255, 255}},   // background
+		{25, 75, color.RGBA{255, 0, 0, 255}},     // filled rectangle
+		{75, 49, color.RGBA{0, 0, 255, 255}},     // stroked line
+		{75, 45, color.RGBA{255, 255, 255, 255}}, // above the line
+		{60, 20, color.RGBA{0, 255, 0, 255}},     // left pixel of the image
+		{90, 20, color.RGBA{0, 0, 255, 255}},     // right pixel of the image
+		{25, 95, color.RGBA{0, 0, 0, 255}},       // inside the clipping path
+		{75, 95, color.RGBA{255, 255, 255, 255}}, // outside the clipping path
+		{75, 80, color.RGBA{128, 128, 128, 255}}, // half transparent
+	}
+	for _, tt := range tests {
+		if got := m.RGBAAt(tt.x, tt.y); got != tt.c {
+			t.Errorf("pixel (%d, %d): got %v, want %v", tt.x, tt.y, got, tt.c)
+		}
+	}
+
+	m, err = r.RenderPage(1, 144)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.RGBAAt(50, 150); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("pixel at 144 dpi: got %v", got)
+	}
+}
+
+func TestRenderPageRotated(t *testing.T) {
+	c := "1 0 0 rg 0 0 10 10 re f"
+	tests := []struct {
+		rot  int
+		w, h int
+		x, y int // pixel of the lower left corner of the page
+	}{
+		{0, 100, 50, 5, 45},
+		{90, 50, 100, 5, 5},
+		{180, 100, 50, 95, 5},
+		{270, 50, 100, 45, 95},
+	}
+	for _, tt := range tests {
+		m, err := testRender(t, c, "<< >>", "[ 0 0 100 50 ]", tt.rot).RenderPage(1, 72)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := m.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("size of page rotated by %d: got %v", tt.rot, b)
+			continue
+		}
+		if got := m.RGBAAt(tt.x, tt.y); got != (color.RGBA{255, 0, 0, 255}) {
+			t.Errorf("corner of page rotated by %d: got %v", tt.rot, got)
+		}
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	ff := testVariableFont()
+	proc := "1000 0 0 0 1000 1000 d1 0 0 1000 1000 re f"
+	c := "BT /F1 100 Tf 10 10 Td <0001> Tj ET BT 1 0 0 rg /F2 10 Tf 50 50 Td (A) Tj ET\n" +
+		"BT /F3 20 Tf 10 40 Td (I) Tj ET BT 3 Tr /F2 10 Tf 70 50 Td (A) Tj ET"
+	r := testRender(t, c, "<< /Font << /F1 5 0 R /F2 8 0 R /F3 10 0 R >> >>", "[ 0 0 100 100 ]", 0,
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Test /Encoding /Identity-H /DescendantFonts [ 6 0 R ] >>",
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /Test /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor 7 0 R /CIDToGIDMap /Identity >>",
+		"<< /Type /FontDescriptor /FontName /Test /Flags 4 /FontBBox [ 0 0 1000 1000 ] /ItalicAngle 0 /Ascent 1000 /Descent 0 /CapHeight 1000 /StemV 80 /FontFile2 11 0 R >>",
+		"<< /Type /Font /Subtype /Type3 /FontBBox [ 0 0 1000 1000 ] /FontMatrix [ 0.001 0 0 0.001 0 0 ] /CharProcs << /square 9 0 R >> /Encoding << /Differences [ 65 /square ] >> /FirstChar 65 /LastChar 65 /Widths [ 1000 ] >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(proc), proc),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(ff), ff))
+	m, err := r.RenderPage(1, 72)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		x, y int
+		c    color.RGBA
+	}{
+		{15, 85, color.RGBA{0, 0, 0, 255}},       // glyph of the TrueType font
+		{25, 85, color.RGBA{255, 255, 255, 255}}, // after it
+		{55, 45, color.RGBA{255, 0, 0, 255}},     // glyph of the Type 3 font
+		{75, 45, color.RGBA{255, 255, 255, 255}}, // invisible glyph
+		{12, 57, color.RGBA{255, 153, 153, 255}}, // bar for a font that isn't embedded
+	}
+	for _, tt := range tests {
+		if got := m.RGBAAt(tt.x, tt.y); got != tt.c {
+			t.Errorf("pixel (%d, %d): got %v, want %v", tt.x, tt.y, got, tt.c)
+		}
+	}
+}
+
+func TestRenderDocument(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(200, 100)
+	d.Rectangle(20, 20, 40, 40)
+	d.Fill()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := r.RenderPage(1, 36)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := m.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Fatalf("size of image: got %v", b)
+	}
+	if got := m.RGBAAt(20, 30); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("filled rectangle: got %v", got)
+	}
+}
+
+func TestRenderPageErrors(t *testing.T) {
+	r := testRender(t, "", "<< >>", "[ 0 0 100 100 ]", 0)
+	tests := []struct {
+		n   int
+		dpi float64
+	}{
+		{0, 72},
+		{2, 72},
+		{1, 0},
+		{1, -1},
+		{1, 1e6},
+	}
+	for _, tt := range tests {
+		_, err := r.RenderPage(tt.n, tt.dpi)
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("rendering page %d at %v dpi: got %v", tt.n, tt.dpi, err)
+		}
+	}
+}
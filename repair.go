@@ -0,0 +1,198 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file rebuilds the cross-reference tables of damaged files, by scanning
+// them for objects, so that they can still be read.
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// recovered calls f, and returns the value it panics with, or nil if it
+// returns normally. Cancellations are not recovered.
+func recovered(f func()) (e interface{}) {
+	defer func() {
+		e = recover()
+		if canceled(e) {
+			panic(e)
+		}
+	}()
+	f()
+	return nil
+}
+
+// Repaired tells whether the cross-reference table of the file was broken
+// and rebuilt when it was read. Objects of repaired files may not be the
+// ones their authors meant.
+func (r *Reader) Repaired() bool {
+	return r.rebuilt
+}
+
+// objectAt returns the number of the object that starts at offset off of the
+// file, or -1 if no object starts there.
+func (r *Reader) objectAt(off int) int {
+	num := -1
+	recovered(func() {
+		p := &parser{b: r.b, pos: off}
+		n, ok := p.token().(int)
+		if _, ok2 := p.token().(int); ok && ok2 && p.token() == keyword("obj") {
+			num = n
+		}
+	})
+	return num
+}
+
+// numberBefore returns the number that ends right before white-space which
+// ends at offset i of b, and the offset of the number. ok is false if there's
+// no such number.
+func numberBefore(b []byte, i int) (n, off int, ok bool) {
+	j := i
+	for j > 0 && isSpace(b[j-1]) {
+		j--
+	}
+	k := j
+	for k > 0 && b[k-1] >= '0' && b[k-1] <= '9' {
+		k--
+	}
+	if j == i || k == j {
+		return 0, 0, false
+	}
+	n, err := atoi(b[k:j])
+	return n, k, err == nil
+}
+
+// rebuild makes the cross-reference table of the file again, from the objects
+// found in it, after it's found broken for reason while reading object num.
+// Later objects with the same number replace the earlier ones, like in
+// incremental updates. The trailer is made of the trailers and the
+// cross-reference streams in the file.
+func (r *Reader) rebuild(num int, reason string) {
+	r.problem(num, "%s; cross-reference table rebuilt", reason)
+	r.rebuilt = true
+	r.xref = make(map[int]xrefEntry)
+	r.objs = make(map[int]interface{})
+
+	for i := 0; ; {
+		checkContext(r.ctx)
+		j := bytes.Index(r.b[i:], []byte("obj"))
+		if j < 0 {
+			break
+		}
+		j += i
+		i = j + 3
+		if i < len(r.b) && !isSpace(r.b[i]) && !isDelim(r.b[i]) {
+			continue
+		}
+		_, k, ok := numberBefore(r.b, j)
+		if !ok {
+			continue
+		}
+		n, k, ok := numberBefore(r.b, k)
+		if ok && (k == 0 || isSpace(r.b[k-1]) || isDelim(r.b[k-1])) {
+			r.xref[n] = xrefEntry{off: k}
+		}
+	}
+	nums := make([]int, 0, len(r.xref))
+	for n := range r.xref {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	// Objects in object streams are added if they are not found on their
+	// own. Trailers and cross-reference streams are kept by their offsets.
+	trailers := make(map[int]map[string]interface{})
+	for _, n := range nums {
+		recovered(func() {
+			s, ok := r.object(n).(*stream)
+			if !ok {
+				return
+			}
+			switch s.dic["Type"] {
+			case name("ObjStm"):
+				r.objectStreamRefs(n, s)
+			case name("XRef"):
+				trailers[r.xref[n].off] = s.dic
+			}
+		})
+	}
+	for i := len(r.b); ; {
+		i = bytes.LastIndex(r.b[:i], []byte("trailer"))
+		if i < 0 {
+			break
+		}
+		recovered(func() {
+			p := &parser{b: r.b, pos: i + len("trailer")}
+			if d, ok := p.object().(map[string]interface{}); ok {
+				trailers[i] = d
+			}
+		})
+	}
+	offs := make([]int, 0, len(trailers))
+	for off := range trailers {
+		offs = append(offs, off)
+	}
+	sort.Ints(offs)
+
+	// Entries of later trailers are newer.
+	r.trailer = map[string]interface{}{"Size": 0}
+	for i := len(offs) - 1; i >= 0; i-- {
+		t := trailers[offs[i]]
+		for _, k := range []string{"Root", "Info", "ID", "Encrypt"} {
+			if _, ok := r.trailer[k]; !ok && t[k] != nil {
+				r.trailer[k] = t[k]
+			}
+		}
+	}
+	if root, ok := r.trailer["Root"].(ref); !ok || r.object(root.num) == nil {
+		r.trailer["Root"] = nil
+		for _, n := range nums {
+			if d, ok := r.object(n).(map[string]interface{}); ok && d["Type"] == name("Catalog") {
+				r.trailer["Root"] = ref{n, 0}
+			}
+		}
+	}
+	if r.trailer["Root"] == nil {
+		fail(ErrBadFile, reason)
+	}
+	if len(nums) > 0 {
+		r.trailer["Size"] = nums[len(nums)-1] + 1
+	}
+}
+
+// objectStreamRefs adds the objects in object stream s, which is object num,
+// to the cross-reference table, if they are not in it.
+func (r *Reader) objectStreamRefs(num int, s *stream) {
+	b := r.decode(s)
+	if b == nil {
+		return
+	}
+	n, _ := s.dic["N"].(int)
+	p := &parser{b: b}
+	for i := 0; i < n; i++ {
+		o, ok1 := p.token().(int)
+		_, ok2 := p.token().(int)
+		if !ok1 || !ok2 {
+			panic(fmt.Sprintf("bad object stream %d", num))
+		}
+		if _, ok := r.xref[o]; !ok {
+			r.xref[o] = xrefEntry{off: i, stm: num}
+		}
+	}
+}
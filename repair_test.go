@@ -0,0 +1,93 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type repairTest struct {
+	name string
+	file []byte
+}
+
+func TestRepair(t *testing.T) {
+	good := testFile(testPage("BT /F1 10 Tf (Hello) Tj ET",
+		"<< /Font << /F1 << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> >> >>"))
+	x := bytes.Index(good, []byte("xref"))
+	update := "4 0 obj\n<< /Length 26 >>\nstream\nBT /F1 10 Tf (Hello) Tj ET\nendstream\nendobj\n" +
+		"trailer\n<< /Root 1 0 R >>\n"
+	old := bytes.Replace(good, []byte("(Hello)"), []byte("(Bye!!)"), 1)
+	// end returns b up to i followed by s.
+	end := func(b []byte, i int, s string) []byte {
+		return append(append([]byte{}, b[:i]...), s...)
+	}
+	tests := []repairTest{
+		{"shifted offsets", bytes.Replace(good, []byte("%PDF-1.7\n"), []byte("%PDF-1.7\n%junk\n"), 1)},
+		{"no cross-reference table", good[:x]},
+		{"bad startxref", end(good, bytes.LastIndex(good, []byte("startxref")), "startxref\n12\n%%EOF\n")},
+		{"no trailer", end(good, x, "startxref\n0\n%%EOF\n")},
+		{"broken update", end(old, x, update)},
+	}
+	for _, tt := range tests {
+		r, err := NewReader(tt.file)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		runs, err := r.PageText(1)
+		if err != nil || len(runs) != 1 || runs[0].Text != "Hello" {
+			t.Errorf("%s: text got %v, %v", tt.name, runs, err)
+		}
+		if !r.Repaired() {
+			t.Errorf("%s: not repaired", tt.name)
+		}
+		if !strings.Contains(fmt.Sprint(r.probs), "cross-reference table rebuilt") {
+			t.Errorf("%s: problems got %v", tt.name, r.probs)
+		}
+	}
+
+	r, err := NewReader(good)
+	if err != nil || r.Repaired() {
+		t.Errorf("good file: got %v, %v", r.Repaired(), err)
+	}
+	if _, err := NewReader([]byte("%PDF-1.7\n1 0 obj\n<< >>\nendobj\n")); err == nil {
+		t.Error("file with no catalog")
+	}
+}
+
+func TestRepairCompressed(t *testing.T) {
+	b := testCompressedFile()
+	b = append(b[:bytes.LastIndex(b, []byte("startxref"))], "startxref\n1\n%%EOF\n"...)
+	r, err := NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Repaired() {
+		t.Error("not repaired")
+	}
+	cat, _ := r.resolve(r.trailer["Root"]).(map[string]interface{})
+	if cat["Type"] != name("Catalog") {
+		t.Errorf("catalog: got %v", cat)
+	}
+	if pgs := r.pages(); len(pgs) != 0 {
+		t.Errorf("pages: got %v", pgs)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file keeps track of the resources used by content streams, like fonts
+// and XObjects, so that pages and form XObjects get resource dictionaries
+// with all of them (p. 153).
+
+import (
+	"strconv"
+)
+
+// resources holds the resources used by a content stream, by category, like
+// "Font", "XObject", "ExtGState", "Pattern" or "ColorSpace", and then by name.
+type resources map[string]map[string]*indirect
+
+// add adds resource i of category cat with name n to r.
+func (r *resources) add(cat, n string, i *indirect) {
+	if *r == nil {
+		*r = make(resources)
+	}
+	if (*r)[cat] == nil {
+		(*r)[cat] = make(map[string]*indirect)
+	}
+	(*r)[cat][n] = i
+}
+
+// merge adds the resources of o to r.
+func (r *resources) merge(o resources) {
+	for cat, m := range o {
+		for n, i := range m {
+			r.add(cat, n, i)
+		}
+	}
+}
+
+// dict returns the resource dictionary of r.
+func (r resources) dict() map[string]interface{} {
+	res := map[string]interface{}{}
+	for cat, m := range r {
+		if len(m) > 0 {
+			res[cat] = m
+		}
+	}
+	return res
+}
+
+// resName returns the name of resource i, which is prefix followed by the
+// object number of i. Names are the same on all pages, so content can be
+// made before it's known where it goes.
+func resName(prefix string, i *indirect) string {
+	return prefix + strconv.Itoa(i.num)
+}
+
+// useResource adds resource i of category cat with name n to the resources of
+// the XObject being made, if there's one, or of the current page, and returns
+// n.
+func (d *Document) useResource(cat, n string, i *indirect) string {
+	if d.xbox != nil {
+		d.xres.add(cat, n, i)
+		return n
+	}
+	if d.pg == nil {
+		fail(ErrNoPage, "resource used before any page was started")
+	}
+	d.pg.res.add(cat, n, i)
+	return n
+}
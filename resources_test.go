@@ -0,0 +1,88 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"testing"
+)
+
+func TestResources(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(100, 100)
+	inner, _ := d.AddImage(image.NewGray(image.Rect(0, 0, 2, 2)))
+	d.BeginXObject(10, 10)
+	d.DrawXObject(inner, 0, 0, 5, 5)
+	st := &TextStyle{Font: FontCourier}
+	d.pageFont(st.font())
+	d.addc(st.text("form", 0, 0, 10))
+	x, err := d.EndXObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			d.NewPage(100, 100)
+		}
+		d.DrawXObject(x, 0, 0, 10, 10)
+		if err := d.SetOpacity(0.5, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.SetOpacity(2, 1); fmt.Sprint(err) != "pdf.go: opacity out of range: 2, 1" {
+		t.Errorf("opacity out of range: got %v", err)
+	}
+	d.Close()
+
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+	r, _ := NewReader(buf.Bytes())
+	var gs interface{}
+	for i, pg := range r.pages() {
+		res := r.resolve(pg.dic["Resources"]).(map[string]interface{})
+		gstates := r.resolve(res["ExtGState"]).(map[string]interface{})
+		if len(gstates) != 1 {
+			t.Fatalf("graphics states of page %d: got %v", i+1, gstates)
+		}
+		for n, g := range gstates {
+			if i > 0 && g != gs {
+				t.Errorf("graphics state not shared between pages")
+			}
+			gs = g
+			if con := string(r.contents(pg.dic["Contents"])); !bytes.Contains([]byte(con), []byte("/"+n+" gs")) {
+				t.Errorf("content of page %d: %q doesn't use %s", i+1, con, n)
+			}
+		}
+		xobjs := r.resolve(res["XObject"]).(map[string]interface{})
+		if _, ok := xobjs[x.name()]; !ok || len(xobjs) != 1 {
+			t.Errorf("XObjects of page %d: got %v", i+1, xobjs)
+		}
+	}
+
+	form := r.resolve(ref{x.ref.num, 0}).(*stream)
+	res := r.resolve(form.dic["Resources"]).(map[string]interface{})
+	if xobjs := r.resolve(res["XObject"]).(map[string]interface{}); xobjs[inner.name()] == nil {
+		t.Errorf("XObjects of the form: got %v", xobjs)
+	}
+	if fonts := r.resolve(res["Font"]).(map[string]interface{}); fonts[FontCourier] == nil {
+		t.Errorf("fonts of the form: got %v", fonts)
+	}
+}
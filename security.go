@@ -0,0 +1,595 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains the standard security handler, which encrypts the
+// strings and streams of documents (p. 115).
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"io"
+)
+
+// padding is used to pad passwords to 32 bytes (p. 125).
+var padding = []byte{
+	0x28, 0xbf, 0x4e, 0x5e, 0x4e, 0x75, 0x8a, 0x41,
+	0x64, 0x00, 0x4e, 0x56, 0xff, 0xfa, 0x01, 0x08,
+	0x2e, 0x2e, 0x00, 0xb6, 0xd0, 0x68, 0x3e, 0x80,
+	0x2f, 0x0c, 0xa9, 0xfe, 0x64, 0x53, 0x69, 0x7a,
+}
+
+// security holds what's needed to encrypt a document with the standard
+// security handler.
+type security struct {
+	ref  *indirect // encryption dictionary
+	v    int       // version of the algorithm
+	r    int       // revision of the security handler
+	n    int       // length of the key in bytes
+	p    int32     // permissions
+	o, u []byte    // owner and user password values
+	key  []byte    // encryption key of the file
+	aes  bool      // AES is used instead of RC4
+
+	// These are only for revision 6.
+	oe, ue []byte // encrypted file key
+	perms  []byte // encrypted permissions
+
+	noMeta bool // metadata is not encrypted; only in files being read
+}
+
+// padPassword converts password pw to WinAnsiEncoding and pads or truncates
+// it to 32 bytes (algorithm 3.2, step 1).
+func padPassword(pw string) []byte {
+	b := append([]byte(winAnsi(pw)), padding...)
+	return b[:32]
+}
+
+// md5Sum returns the MD5 hash of the concatenation of the given byte slices.
+func md5Sum(bs ...[]byte) []byte {
+	h := md5.New()
+	for _, b := range bs {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// rc4Crypt returns b encrypted, or decrypted, with RC4 using key.
+func rc4Crypt(key, b []byte) []byte {
+	c, err := rc4.NewCipher(key)
+	check(err)
+	out := make([]byte, len(b))
+	c.XORKeyStream(out, b)
+	return out
+}
+
+// rc4Rounds encrypts b with key, and then 19 more times with key XORed with
+// the number of the round (algorithm 3.3, step 7, and algorithm 3.5, step 5).
+func rc4Rounds(key, b []byte) []byte {
+	b = rc4Crypt(key, b)
+	k := make([]byte, len(key))
+	for i := 1; i <= 19; i++ {
+		for j := range key {
+			k[j] = key[j] ^ byte(i)
+		}
+		b = rc4Crypt(k, b)
+	}
+	return b
+}
+
+// ownerKey computes the key used to encrypt the O entry of the encryption
+// dictionary from the owner password (algorithm 3.3, steps 1 to 4).
+func (s *security) ownerKey(owner string) []byte {
+	h := md5Sum(padPassword(owner))
+	if s.r >= 3 {
+		for i := 0; i < 50; i++ {
+			h = md5Sum(h)
+		}
+	}
+	return h[:s.n]
+}
+
+// ownerValue computes the O entry of the encryption dictionary (algorithm
+// 3.3).
+func (s *security) ownerValue(owner, user string) []byte {
+	if owner == "" {
+		owner = user
+	}
+	key := s.ownerKey(owner)
+	if s.r >= 3 {
+		return rc4Rounds(key, padPassword(user))
+	}
+	return rc4Crypt(key, padPassword(user))
+}
+
+// fileKey computes the encryption key of the file from the padded user
+// password pw (algorithm 3.2).
+func (s *security) fileKey(pw, id []byte) []byte {
+	p := uint32(s.p)
+	bs := [][]byte{pw, s.o, {byte(p), byte(p >> 8), byte(p >> 16), byte(p >> 24)}, id}
+	if s.r >= 4 && s.noMeta {
+		bs = append(bs, []byte{0xff, 0xff, 0xff, 0xff})
+	}
+	h := md5Sum(bs...)
+	if s.r >= 3 {
+		for i := 0; i < 50; i++ {
+			h = md5Sum(h[:s.n])
+		}
+	}
+	return h[:s.n]
+}
+
+// userValue computes the U entry of the encryption dictionary (algorithms
+// 3.4 and 3.5).
+func (s *security) userValue(id []byte) []byte {
+	if s.r == 2 {
+		return rc4Crypt(s.key, padding)
+	}
+	u := rc4Rounds(s.key, md5Sum(padding, id))
+	// The other 16 bytes are arbitrary.
+	return append(u, make([]byte, 16)...)
+}
+
+// newRC4Security returns the security handler of a document with the given
+// ID, which is encrypted with RC4 and a key of the given number of bits.
+func newRC4Security(user, owner string, bits int, p int32, id []byte) *security {
+	s := &security{n: bits / 8, p: p}
+	switch {
+	case bits == 40:
+		s.v, s.r = 1, 2
+	case bits >= 40 && bits <= 128 && bits%8 == 0:
+		s.v, s.r = 2, 3
+	default:
+		panic("RC4 keys should be between 40 and 128 bits")
+	}
+	s.o = s.ownerValue(owner, user)
+	s.key = s.fileKey(padPassword(user), id)
+	s.u = s.userValue(id)
+	return s
+}
+
+// newAESSecurity returns the security handler of a document with the given
+// ID, which is encrypted with AES and a key of 128 bits.
+func newAESSecurity(user, owner string, p int32, id []byte) *security {
+	s := &security{v: 4, r: 4, n: 16, p: p, aes: true}
+	s.o = s.ownerValue(owner, user)
+	s.key = s.fileKey(padPassword(user), id)
+	s.u = s.userValue(id)
+	return s
+}
+
+// hash2B computes the hash of password pw, with the given salt and user
+// key, which is used in revision 6 of the security handler (algorithm 2.B of
+// ISO 32000-2).
+func hash2B(pw, salt, u []byte) []byte {
+	h := sha256.New()
+	h.Write(pw)
+	h.Write(salt)
+	h.Write(u)
+	k := h.Sum(nil)
+	for i := 0; ; i++ {
+		k1 := make([]byte, 0, 64*(len(pw)+len(k)+len(u)))
+		for j := 0; j < 64; j++ {
+			k1 = append(k1, pw...)
+			k1 = append(k1, k...)
+			k1 = append(k1, u...)
+		}
+		c, err := aes.NewCipher(k[:16])
+		check(err)
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(c, k[16:32]).CryptBlocks(e, k1)
+
+		// The sum of the first 16 bytes modulo 3 selects the hash
+		// function of the next round.
+		sum := 0
+		for _, b := range e[:16] {
+			sum += int(b)
+		}
+		var h hash.Hash
+		switch sum % 3 {
+		case 0:
+			h = sha256.New()
+		case 1:
+			h = sha512.New384()
+		case 2:
+			h = sha512.New()
+		}
+		h.Write(e)
+		k = h.Sum(nil)
+		if i >= 63 && int(e[len(e)-1]) <= i+1-32 {
+			break
+		}
+	}
+	return k[:32]
+}
+
+// aesNoPad returns b, which should be a multiple of the block size long,
+// encrypted with AES in CBC mode using key and an initialization vector of
+// zeros.
+func aesNoPad(key, b []byte) []byte {
+	c, err := aes.NewCipher(key)
+	check(err)
+	out := make([]byte, len(b))
+	cipher.NewCBCEncrypter(c, make([]byte, aes.BlockSize)).CryptBlocks(out, b)
+	return out
+}
+
+// saslPassword converts password pw for revision 6, which uses UTF-8 and
+// at most 127 bytes.
+// TODO SASLprep profile of stringprep (RFC 4013)
+func saslPassword(pw string) []byte {
+	b := []byte(pw)
+	if len(b) > 127 {
+		b = b[:127]
+	}
+	return b
+}
+
+// newAES256Security returns the security handler of a document which is
+// encrypted with AES and a key of 256 bits, as described in ISO 32000-2.
+// Random keys and salts are read from rnd.
+func newAES256Security(user, owner string, p int32, rnd io.Reader) *security {
+	s := &security{v: 5, r: 6, n: 32, p: p, aes: true}
+	if owner == "" {
+		owner = user
+	}
+	up, op := saslPassword(user), saslPassword(owner)
+
+	// The file key and the salts are random.
+	r := make([]byte, 32+4*8+4)
+	_, err := io.ReadFull(rnd, r)
+	check(err)
+	s.key = r[:32]
+	uvs, uks, ovs, oks := r[32:40], r[40:48], r[48:56], r[56:64]
+
+	s.u = append(append(hash2B(up, uvs, nil), uvs...), uks...)
+	s.ue = aesNoPad(hash2B(up, uks, nil), s.key)
+	s.o = append(append(hash2B(op, ovs, s.u), ovs...), oks...)
+	s.oe = aesNoPad(hash2B(op, oks, s.u), s.key)
+
+	// Permissions are encrypted too, so that they can be checked.
+	pu := uint32(p)
+	perms := []byte{byte(pu), byte(pu >> 8), byte(pu >> 16), byte(pu >> 24),
+		0xff, 0xff, 0xff, 0xff, 'T', 'a', 'd', 'b'}
+	perms = append(perms, r[64:68]...)
+	c, err := aes.NewCipher(s.key)
+	check(err)
+	s.perms = make([]byte, 16)
+	c.Encrypt(s.perms, perms)
+	return s
+}
+
+// objectKey returns the key used for the strings and streams of the object
+// with number num and generation gen (algorithm 3.1).
+func (s *security) objectKey(num, gen int) []byte {
+	if s.r >= 5 {
+		// The same key is used for all objects.
+		return s.key
+	}
+	n := s.n + 5
+	if n > 16 {
+		n = 16
+	}
+	b := []byte{byte(num), byte(num >> 8), byte(num >> 16), byte(gen), byte(gen >> 8)}
+	if s.aes {
+		b = append(b, "sAlT"...)
+	}
+	return md5Sum(s.key, b)[:n]
+}
+
+// aesCrypt returns b encrypted with AES in CBC mode using key. A random
+// initialization vector is put before the result, and the end of b is padded
+// as described in RFC 2898 (p. 120).
+func aesCrypt(key, b []byte) []byte {
+	c, err := aes.NewCipher(key)
+	check(err)
+	n := aes.BlockSize - len(b)%aes.BlockSize
+	out := make([]byte, aes.BlockSize+len(b)+n)
+	_, err = io.ReadFull(rand.Reader, out[:aes.BlockSize])
+	check(err)
+	copy(out[aes.BlockSize:], b)
+	for i := len(out) - n; i < len(out); i++ {
+		out[i] = byte(n)
+	}
+	cipher.NewCBCEncrypter(c, out[:aes.BlockSize]).CryptBlocks(out[aes.BlockSize:], out[aes.BlockSize:])
+	return out
+}
+
+// crypt returns the function which encrypts the strings and streams of the
+// object with number num.
+func (s *security) crypt(num int) func(b []byte) []byte {
+	key := s.objectKey(num, 0)
+	if s.aes {
+		return func(b []byte) []byte {
+			return aesCrypt(key, b)
+		}
+	}
+	return func(b []byte) []byte {
+		return rc4Crypt(key, b)
+	}
+}
+
+// aesDecrypt returns b decrypted with AES in CBC mode using key. It's the
+// reverse of aesCrypt. Any partial block at the end of b is dropped.
+func aesDecrypt(key, b []byte) []byte {
+	c, err := aes.NewCipher(key)
+	check(err)
+	n := len(b) - len(b)%aes.BlockSize
+	if n < 2*aes.BlockSize {
+		return nil
+	}
+	out := make([]byte, n-aes.BlockSize)
+	cipher.NewCBCDecrypter(c, b[:aes.BlockSize]).CryptBlocks(out, b[aes.BlockSize:n])
+	if p := int(out[len(out)-1]); p >= 1 && p <= aes.BlockSize {
+		out = out[:len(out)-p]
+	}
+	return out
+}
+
+// decrypter returns the function which decrypts the strings and streams of
+// the object with number num and generation gen, in a file being read.
+func (s *security) decrypter(num, gen int) func(b []byte) []byte {
+	key := s.objectKey(num, gen)
+	if s.aes {
+		return func(b []byte) []byte {
+			return aesDecrypt(key, b)
+		}
+	}
+	return func(b []byte) []byte {
+		return rc4Crypt(key, b)
+	}
+}
+
+// authenticate sets the key of s, which is read from a file whose
+// identifier starts with id, if pw is the user or the owner password of the
+// file (algorithms 3.6 and 3.7).
+func (s *security) authenticate(pw string, id []byte) bool {
+	if s.r >= 5 {
+		return s.authenticate256(saslPassword(pw))
+	}
+	if s.checkUser(padPassword(pw), id) {
+		return true
+	}
+	// The user password is encrypted with the owner password in O.
+	key := s.ownerKey(pw)
+	u := s.o
+	if s.r == 2 {
+		u = rc4Crypt(key, u)
+	} else {
+		k := make([]byte, len(key))
+		for i := 19; i >= 0; i-- {
+			for j := range key {
+				k[j] = key[j] ^ byte(i)
+			}
+			u = rc4Crypt(k, u)
+		}
+	}
+	return s.checkUser(u, id)
+}
+
+// checkUser sets the key of s if the padded password pw is the user password
+// of the file.
+func (s *security) checkUser(pw, id []byte) bool {
+	s.key = s.fileKey(pw, id)
+	// Only 16 bytes of U are defined after revision 2.
+	n := 32
+	if s.r >= 3 {
+		n = 16
+	}
+	if len(s.u) < n || !bytes.Equal(s.userValue(id)[:n], s.u[:n]) {
+		s.key = nil
+		return false
+	}
+	return true
+}
+
+// authenticate256 sets the key of s if pw is the user or the owner password
+// of the file, in revisions 5 and 6 (algorithm 2.A of ISO 32000-2).
+func (s *security) authenticate256(pw []byte) bool {
+	if len(s.u) < 48 || len(s.o) < 48 || len(s.ue) != 32 || len(s.oe) != 32 {
+		return false
+	}
+	u := s.u[:48]
+	switch {
+	case bytes.Equal(s.hash(pw, s.o[32:40], u), s.o[:32]):
+		s.key = aesNoPadDecrypt(s.hash(pw, s.o[40:48], u), s.oe)
+	case bytes.Equal(s.hash(pw, s.u[32:40], nil), s.u[:32]):
+		s.key = aesNoPadDecrypt(s.hash(pw, s.u[40:48], nil), s.ue)
+	default:
+		return false
+	}
+	return true
+}
+
+// hash computes the hash of password pw with the given salt and user key in
+// revisions 5 and 6. Revision 5 is an extension of Adobe that uses SHA-256
+// alone.
+func (s *security) hash(pw, salt, u []byte) []byte {
+	if s.r == 6 {
+		return hash2B(pw, salt, u)
+	}
+	h := sha256.New()
+	h.Write(pw)
+	h.Write(salt)
+	h.Write(u)
+	return h.Sum(nil)
+}
+
+// aesNoPadDecrypt returns b decrypted with AES in CBC mode using key and an
+// initialization vector of zeros. It's the reverse of aesNoPad.
+func aesNoPadDecrypt(key, b []byte) []byte {
+	c, err := aes.NewCipher(key)
+	check(err)
+	out := make([]byte, len(b))
+	cipher.NewCBCDecrypter(c, make([]byte, aes.BlockSize)).CryptBlocks(out, b)
+	return out
+}
+
+func (s *security) object() interface{} {
+	d := map[string]interface{}{
+		"Filter": name("Standard"),
+		"V":      s.v,
+		"R":      s.r,
+		"O":      hexString(s.o),
+		"U":      hexString(s.u),
+		"P":      int(s.p),
+	}
+	if s.v >= 2 {
+		d["Length"] = s.n * 8
+	}
+	if s.v >= 4 {
+		// A single crypt filter is used for both strings and streams
+		// (p. 121).
+		cfm := name("AESV2")
+		if s.v == 5 {
+			cfm = name("AESV3")
+		}
+		d["CF"] = map[string]interface{}{
+			"StdCF": map[string]interface{}{
+				"Type":      name("CryptFilter"),
+				"CFM":       cfm,
+				"AuthEvent": name("DocOpen"),
+				"Length":    s.n,
+			},
+		}
+		d["StmF"] = name("StdCF")
+		d["StrF"] = name("StdCF")
+	}
+	if s.r == 6 {
+		d["OE"] = hexString(s.oe)
+		d["UE"] = hexString(s.ue)
+		d["Perms"] = hexString(s.perms)
+	}
+	return d
+}
+
+// Permissions tells what users who open an encrypted document with the user
+// password are allowed to do (p. 124). Those who use the owner password can
+// do anything. Combine the constants with | to allow more than one thing.
+type Permissions int
+
+const (
+	// PermPrint allows printing, in low quality if PermPrintHighRes isn't
+	// allowed too.
+	PermPrint Permissions = 1 << 2
+	// PermModify allows changing the document in ways not covered by the
+	// other permissions.
+	PermModify Permissions = 1 << 3
+	// PermCopy allows copying text and graphics.
+	PermCopy Permissions = 1 << 4
+	// PermAnnotate allows adding and changing annotations, and filling
+	// forms.
+	PermAnnotate Permissions = 1 << 5
+	// PermFillForms allows filling forms even if PermAnnotate isn't
+	// allowed. It needs 128 bits keys.
+	PermFillForms Permissions = 1 << 8
+	// PermExtract allows copying text and graphics for accessibility, even
+	// if PermCopy isn't allowed. It needs 128 bits keys.
+	PermExtract Permissions = 1 << 9
+	// PermAssemble allows inserting, rotating and deleting pages, and
+	// making bookmarks, even if PermModify isn't allowed. It needs 128 bits
+	// keys.
+	PermAssemble Permissions = 1 << 10
+	// PermPrintHighRes allows printing in the highest quality. It needs
+	// 128 bits keys.
+	PermPrintHighRes Permissions = 1 << 11
+
+	// PermAll allows everything.
+	PermAll = PermPrint | PermModify | PermCopy | PermAnnotate |
+		PermFillForms | PermExtract | PermAssemble | PermPrintHighRes
+)
+
+// value returns the P entry of the encryption dictionary for p. The bits
+// that are not used for permissions should be 1, except the first two.
+func (p Permissions) value() int32 {
+	return int32(uint32(0xfffff0c0) | uint32(p&PermAll))
+}
+
+// Encrypt makes the document to be encrypted with RC4 and a key of the given
+// number of bits, which should be 40 or a multiple of 8 up to 128. Users need
+// the user password to open the document; it can be empty. The owner password
+// is the user password if it's empty. perms tells what users with the user
+// password are allowed to do.
+//
+// Encrypt should be called right after New, before anything is added to the
+// document.
+func (d *Document) Encrypt(user, owner string, bits int, perms Permissions) (err error) {
+	defer dontPanic(&err)
+
+	d.checkEncrypt()
+	d.sec = newRC4Security(user, owner, bits, perms.value(), d.fileID())
+	d.sec.ref = d.reserveIndirect()
+	return nil
+}
+
+// EncryptAES makes the document to be encrypted with AES and a key of 128 or
+// 256 bits. It works like Encrypt, which uses RC4 instead. AES-256 is defined
+// in PDF 2.0, and passwords of it can have any Unicode characters.
+func (d *Document) EncryptAES(user, owner string, bits int, perms Permissions) (err error) {
+	defer dontPanic(&err)
+
+	d.checkEncrypt()
+	switch bits {
+	case 128:
+		d.sec = newAESSecurity(user, owner, perms.value(), d.fileID())
+	case 256:
+		d.fileID() // not used by the keys, but still needed in the trailer
+		d.sec = newAES256Security(user, owner, perms.value(), rand.Reader)
+	default:
+		panic("AES keys should be 128 or 256 bits")
+	}
+	d.sec.ref = d.reserveIndirect()
+	return nil
+}
+
+// checkEncrypt panics if the document can't be encrypted anymore, because
+// objects are already written to the output.
+func (d *Document) checkEncrypt() {
+	if d.sec != nil {
+		panic("document is already encrypted")
+	}
+	if d.pdfa {
+		panic("PDF/A documents can't be encrypted")
+	}
+	if d.pdfx != "" {
+		panic("PDF/X documents can't be encrypted")
+	}
+	if d.started() {
+		panic("Encrypt must be called before anything is added to the document")
+	}
+}
+
+// fileID returns the first part of the identifier of the file, making it the
+// first time.
+func (d *Document) fileID() []byte {
+	if d.id == nil && d.opts.Deterministic {
+		d.id = md5Sum([]byte(d.opts.Producer))
+	}
+	if d.id == nil {
+		d.id = make([]byte, 16)
+		_, err := io.ReadFull(rand.Reader, d.id)
+		check(err)
+	}
+	return d.id
+}
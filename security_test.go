@@ -0,0 +1,226 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+type securityTest struct {
+	bits      int
+	o, u, str string // hexadecimal
+}
+
+func TestRC4Security(t *testing.T) {
+	id := make([]byte, 16)
+	for i := range id {
+		id[i] = byte(i)
+	}
+	tests := []securityTest{
+		{40,
+			"0462807dd3b6d6aecc90f2491a9e85be7bf3674fe091ab1a0e8d8cbfdfd61704",
+			"294c8674d46c4640223d917f546471ea9b02888804a70e55e083d9401defda76",
+			"8c14081c0013"},
+		{128,
+			"3837d2c19f7d45b8cdc1a4d746a59970cd40314831b28750d53a11e31ce9300a",
+			"c5fd127c4fca534ee1ecb56c4890b16b00000000000000000000000000000000",
+			"2de4a23e4396"},
+	}
+
+	for _, test := range tests {
+		s := newRC4Security("usr", "own", test.bits, PermAll.value(), id)
+		if o := hex.EncodeToString(s.o); o != test.o {
+			t.Errorf("%d bits O: got %s expected %s", test.bits, o, test.o)
+		}
+		if u := hex.EncodeToString(s.u); u != test.u {
+			t.Errorf("%d bits U: got %s expected %s", test.bits, u, test.u)
+		}
+		str := hex.EncodeToString(s.crypt(7)([]byte("secret")))
+		if str != test.str {
+			t.Errorf("%d bits string: got %s expected %s", test.bits, str, test.str)
+		}
+	}
+}
+
+func TestAESSecurity(t *testing.T) {
+	id := make([]byte, 16)
+	for i := range id {
+		id[i] = byte(i)
+	}
+	// Keys of AES-128 are made the same way as RC4 with 128 bits.
+	r := newRC4Security("usr", "own", 128, PermAll.value(), id)
+	s := newAESSecurity("usr", "own", PermAll.value(), id)
+	if bytes.Compare(r.o, s.o) != 0 || bytes.Compare(r.u, s.u) != 0 {
+		t.Errorf("AES O and U are different from RC4 with 128 bits")
+	}
+
+	for _, in := range []string{"", "secret", "exactly 16 bytes"} {
+		out := s.crypt(7)([]byte(in))
+		if len(out)%16 != 0 || len(out) <= len(in)+16 {
+			t.Errorf("AES of %q: bad length %d", in, len(out))
+			continue
+		}
+		c, _ := aes.NewCipher(s.objectKey(7, 0))
+		plain := make([]byte, len(out)-16)
+		cipher.NewCBCDecrypter(c, out[:16]).CryptBlocks(plain, out[16:])
+		plain = plain[:len(plain)-int(plain[len(plain)-1])]
+		if string(plain) != in {
+			t.Errorf("AES of %q: decrypted to %q", in, plain)
+		}
+	}
+}
+
+func TestHash2B(t *testing.T) {
+	salt := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+	u := make([]byte, 48)
+	for i := range u {
+		u[i] = byte(i)
+	}
+	h := hex.EncodeToString(hash2B([]byte("user"), salt, nil))
+	if h != "731758c09c8b0160a34721d18bdd24220abada0070aa3f05b8103fd5b8d05f17" {
+		t.Errorf("hash of user password: got %s", h)
+	}
+	h = hex.EncodeToString(hash2B([]byte("owner"), u[8:16], u))
+	if h != "400c13628b144fe2fbb850b65729e9ecb63c00fbb817c685725f25de85af0521" {
+		t.Errorf("hash of owner password: got %s", h)
+	}
+}
+
+func TestAES256Security(t *testing.T) {
+	s := newAES256Security("usr", "own", PermAll.value(), rand.Reader)
+	if len(s.u) != 48 || len(s.o) != 48 || len(s.ue) != 32 || len(s.oe) != 32 {
+		t.Fatalf("bad lengths of O, U, OE and UE")
+	}
+	// The user and owner passwords should give back the file key.
+	decrypt := func(key, b []byte) []byte {
+		c, _ := aes.NewCipher(key)
+		out := make([]byte, len(b))
+		cipher.NewCBCDecrypter(c, make([]byte, 16)).CryptBlocks(out, b)
+		return out
+	}
+	if k := decrypt(hash2B([]byte("usr"), s.u[40:48], nil), s.ue); bytes.Compare(k, s.key) != 0 {
+		t.Errorf("user password doesn't give the file key")
+	}
+	if k := decrypt(hash2B([]byte("own"), s.o[40:48], s.u), s.oe); bytes.Compare(k, s.key) != 0 {
+		t.Errorf("owner password doesn't give the file key")
+	}
+	if h := hash2B([]byte("usr"), s.u[32:40], nil); bytes.Compare(h, s.u[:32]) != 0 {
+		t.Errorf("user password isn't validated")
+	}
+	c, _ := aes.NewCipher(s.key)
+	perms := make([]byte, 16)
+	c.Decrypt(perms, s.perms)
+	if string(perms[:12]) != "\xfc\xff\xff\xff\xff\xff\xff\xffTadb" {
+		t.Errorf("bad permissions: %x", perms)
+	}
+}
+
+type permissionsTest struct {
+	p   Permissions
+	out int32
+}
+
+func TestPermissions(t *testing.T) {
+	tests := []permissionsTest{
+		{PermAll, -4},
+		{0, -3904},
+		{PermPrint, -3900},
+		{PermPrint | PermPrintHighRes, -1852},
+		{PermCopy | PermExtract | 1<<20, -3376},
+	}
+
+	for _, test := range tests {
+		if v := test.p.value(); v != test.out {
+			t.Errorf("permissions %b: got %d expected %d", test.p, v, test.out)
+		}
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	buf := new(bytes.Buffer)
+	d, err := New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Encrypt("", "owner", 56, PermAll); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Encrypt("", "owner", 128, PermAll); err == nil {
+		t.Errorf("second Encrypt: expected error")
+	}
+	d.NewPage(100, 100)
+	d.TextBox(0, 0, 50, 20, &TextField{Name: "plain", Value: "visible"})
+	if err = d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"/Filter /Standard",
+		"/Length 56",
+		"/Encrypt ",
+		"/ID [ <",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("document: %q not found", s)
+		}
+	}
+	for _, s := range []string{"plain", "visible", "Helv"} {
+		if bytes.Contains(buf.Bytes(), []byte("("+s)) {
+			t.Errorf("document: %q is not encrypted", s)
+		}
+	}
+
+	d, _ = New(new(bytes.Buffer))
+	d.NewPage(100, 100)
+	d.LineTo(10, 10)
+	d.NewPage(100, 100)
+	if err = d.Encrypt("", "", 40, PermAll); err == nil {
+		t.Errorf("Encrypt after adding pages: expected error")
+	}
+	buf.Reset()
+	d, _ = New(buf)
+	if err = d.EncryptAES("", "", 128, PermAll); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	for _, s := range []string{"/CFM /AESV2", "/StmF /StdCF", "/V 4", "/R 4"} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("AES document: %q not found", s)
+		}
+	}
+
+	buf.Reset()
+	d, _ = New(buf)
+	if err = d.EncryptAES("", "", 256, PermPrint); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	for _, s := range []string{"/CFM /AESV3", "/V 5", "/R 6", "/Perms <", "/ExtensionLevel 8", "/P -3900"} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("AES-256 document: %q not found", s)
+		}
+	}
+
+	d, _ = New(new(bytes.Buffer))
+	if err = d.Encrypt("", "", 33, PermAll); err == nil {
+		t.Errorf("Encrypt with 33 bits: expected error")
+	}
+}
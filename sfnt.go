@@ -0,0 +1,493 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file reads the tables of TrueType and OpenType fonts, which are called
+// sfnt, like the outlines of their glyphs and the map of their characters to
+// glyphs.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sfnt is a parsed TrueType or OpenType font.
+type sfnt struct {
+	tables     map[string][]byte // tables of the font, by tag
+	unitsPerEm int
+	numGlyphs  int
+	longLoca   bool // whether the offsets of loca are 32 bits
+}
+
+// parseSfnt parses the font file b. It panics with an error of kind
+// ErrBadFile if b is not a font.
+func parseSfnt(b []byte) *sfnt {
+	if len(b) < 12 {
+		fail(ErrBadFile, "font file too short")
+	}
+	switch string(b[:4]) {
+	case "\x00\x01\x00\x00", "OTTO", "true":
+	default:
+		fail(ErrBadFile, "not a TrueType or OpenType font")
+	}
+	f := &sfnt{tables: make(map[string][]byte)}
+	n := int(u16(b, 4))
+	for i := 0; i < n; i++ {
+		r := 12 + 16*i
+		if r+16 > len(b) {
+			fail(ErrBadFile, "font table directory too short")
+		}
+		off, l := int(u32(b, r+8)), int(u32(b, r+12))
+		if off < 0 || l < 0 || off+l > len(b) || off+l < off {
+			fail(ErrBadFile, "font table "+string(b[r:r+4])+" out of the file")
+		}
+		f.tables[string(b[r:r+4])] = b[off : off+l]
+	}
+	head := f.table("head", 54)
+	f.unitsPerEm = int(u16(head, 18))
+	if f.unitsPerEm == 0 {
+		fail(ErrBadFile, "font with zero units per em")
+	}
+	f.longLoca = u16(head, 50) != 0
+	f.numGlyphs = int(u16(f.table("maxp", 6), 4))
+	return f
+}
+
+// table returns the table of f with the given tag, which should be at least
+// n bytes long.
+func (f *sfnt) table(tag string, n int) []byte {
+	t, ok := f.tables[tag]
+	if !ok {
+		fail(ErrBadFile, "font has no "+tag+" table")
+	}
+	if len(t) < n {
+		fail(ErrBadFile, "font table "+tag+" too short")
+	}
+	return t
+}
+
+// u16 and u32 read big-endian numbers from b at offset off, panicking if b
+// is too short.
+func u16(b []byte, off int) uint16 {
+	if off < 0 || off+2 > len(b) {
+		fail(ErrBadFile, "font data too short")
+	}
+	return binary.BigEndian.Uint16(b[off:])
+}
+
+func u32(b []byte, off int) uint32 {
+	if off < 0 || off+4 > len(b) {
+		fail(ErrBadFile, "font data too short")
+	}
+	return binary.BigEndian.Uint32(b[off:])
+}
+
+// glyphIndex returns the glyph of character r in f, or 0 if f doesn't have
+// it. Subtables of the Unicode cmap of formats 4 and 12 are used.
+func (f *sfnt) glyphIndex(r rune) int {
+	cmap := f.table("cmap", 4)
+	var sub4, sub12 []byte
+	for i := 0; i < int(u16(cmap, 2)); i++ {
+		rec := 4 + 8*i
+		pid, eid, off := u16(cmap, rec), u16(cmap, rec+2), int(u32(cmap, rec+4))
+		if pid != 0 && !(pid == 3 && (eid == 1 || eid == 10)) {
+			continue
+		}
+		if off >= len(cmap) {
+			fail(ErrBadFile, "font cmap subtable out of the table")
+		}
+		switch u16(cmap, off) {
+		case 4:
+			sub4 = cmap[off:]
+		case 12:
+			sub12 = cmap[off:]
+		}
+	}
+	switch {
+	case sub12 != nil:
+		n := int(u32(sub12, 12))
+		for i := 0; i < n; i++ {
+			g := 16 + 12*i
+			start, end := rune(u32(sub12, g)), rune(u32(sub12, g+4))
+			if r >= start && r <= end {
+				return int(u32(sub12, g+8)) + int(r-start)
+			}
+		}
+	case sub4 != nil && r <= 0xffff:
+		segs := int(u16(sub4, 6)) / 2
+		for i := 0; i < segs; i++ {
+			end := rune(u16(sub4, 14+2*i))
+			if r > end {
+				continue
+			}
+			start := rune(u16(sub4, 16+2*segs+2*i))
+			if r < start {
+				return 0
+			}
+			delta := int(u16(sub4, 16+4*segs+2*i))
+			ro := 16 + 6*segs + 2*i
+			rangeOff := int(u16(sub4, ro))
+			if rangeOff == 0 {
+				return (int(r) + delta) & 0xffff
+			}
+			g := int(u16(sub4, ro+rangeOff+2*int(r-start)))
+			if g == 0 {
+				return 0
+			}
+			return (g + delta) & 0xffff
+		}
+	}
+	return 0
+}
+
+// postScriptName returns the PostScript name of f from its name table, with
+// only the characters that can be in the names of fonts, or "Font" if it has
+// none.
+func (f *sfnt) postScriptName() string {
+	t := f.tables["name"]
+	if len(t) < 6 {
+		return "Font"
+	}
+	n, strs := int(u16(t, 2)), int(u16(t, 4))
+	for i := 0; i < n; i++ {
+		r := 6 + 12*i
+		pid, id := u16(t, r), u16(t, r+6)
+		l, off := int(u16(t, r+8)), strs+int(u16(t, r+10))
+		if id != 6 || off+l > len(t) {
+			continue
+		}
+		s := t[off : off+l]
+		var ps []byte
+		for j := 0; j < len(s); j++ {
+			c := s[j]
+			// Names of the Unicode and Windows platforms are in
+			// UTF-16BE; the others are in ASCII.
+			if pid == 0 || pid == 3 {
+				if j++; j >= len(s) || c != 0 {
+					continue
+				}
+				c = s[j]
+			}
+			if c > ' ' && c < 127 && !strings.ContainsRune("[](){}<>/%#", rune(c)) {
+				ps = append(ps, c)
+			}
+		}
+		if len(ps) > 0 {
+			return string(ps)
+		}
+	}
+	return "Font"
+}
+
+// glyphData returns the data of glyph g in the glyf table of f, which is
+// empty for glyphs with no outline.
+func (f *sfnt) glyphData(g int) []byte {
+	if g < 0 || g >= f.numGlyphs {
+		fail(ErrBadFile, fmt.Sprint("glyph ", g, " out of the font"))
+	}
+	loca, glyf := f.table("loca", 0), f.table("glyf", 0)
+	var from, to int
+	if f.longLoca {
+		from, to = int(u32(loca, 4*g)), int(u32(loca, 4*g+4))
+	} else {
+		from, to = 2*int(u16(loca, 2*g)), 2*int(u16(loca, 2*g+2))
+	}
+	if from > to || to > len(glyf) {
+		fail(ErrBadFile, fmt.Sprint("glyph ", g, " out of the glyf table"))
+	}
+	return glyf[from:to]
+}
+
+// simpleGlyph is the outline of a glyph that isn't made of other glyphs.
+type simpleGlyph struct {
+	ends   []int     // indices of the last points of contours
+	xs, ys []float64 // points
+	flags  []byte    // flags of the points; bit 1 is set for points on the curve
+	instr  []byte    // instructions for hinting
+}
+
+// parseGlyph parses the data b of glyph g, which should be a simple glyph.
+func parseGlyph(b []byte, g int) *simpleGlyph {
+	n := int(int16(u16(b, 0)))
+	sg := &simpleGlyph{ends: make([]int, n)}
+	for i := range sg.ends {
+		sg.ends[i] = int(u16(b, 10+2*i))
+	}
+	if n == 0 {
+		return sg
+	}
+	np := sg.ends[n-1] + 1
+	p := 10 + 2*n
+	ni := int(u16(b, p))
+	if p+2+ni > len(b) {
+		fail(ErrBadFile, fmt.Sprint("glyph ", g, " too short"))
+	}
+	sg.instr = b[p+2 : p+2+ni]
+	p += 2 + ni
+	flags := make([]byte, 0, np)
+	for len(flags) < np {
+		if p >= len(b) {
+			fail(ErrBadFile, fmt.Sprint("glyph ", g, " too short"))
+		}
+		fl := b[p]
+		p++
+		flags = append(flags, fl)
+		if fl&8 != 0 { // repeated
+			if p >= len(b) {
+				fail(ErrBadFile, fmt.Sprint("glyph ", g, " too short"))
+			}
+			for k := 0; k < int(b[p]) && len(flags) < np; k++ {
+				flags = append(flags, fl)
+			}
+			p++
+		}
+	}
+	coords := func(short, same byte) []float64 {
+		v := make([]float64, np)
+		c := 0
+		for i, fl := range flags {
+			switch {
+			case fl&short != 0:
+				if p >= len(b) {
+					fail(ErrBadFile, fmt.Sprint("glyph ", g, " too short"))
+				}
+				d := int(b[p])
+				p++
+				if fl&same == 0 {
+					d = -d
+				}
+				c += d
+			case fl&same == 0:
+				c += int(int16(u16(b, p)))
+				p += 2
+			}
+			v[i] = float64(c)
+		}
+		return v
+	}
+	sg.xs = coords(2, 16)
+	sg.ys = coords(4, 32)
+	sg.flags = flags
+	start := 0
+	for _, end := range sg.ends {
+		if end < start || end >= np {
+			fail(ErrBadFile, fmt.Sprint("bad contour of glyph ", g))
+		}
+		start = end + 1
+	}
+	return sg
+}
+
+// advance returns the advance width of glyph g of f, in units of the font.
+// Glyphs after the last long horizontal metric of hmtx have its width.
+func (f *sfnt) advance(g int) int {
+	n := int(u16(f.table("hhea", 36), 34))
+	if n == 0 {
+		fail(ErrBadFile, "font with no horizontal metrics")
+	}
+	if g >= n {
+		g = n - 1
+	}
+	return int(u16(f.table("hmtx", 0), 4*g))
+}
+
+// glyphPath appends the operators of a path of the outline of glyph g of f
+// to w, transformed by m. Units of the font are used, unless m scales them.
+// Quadratic curves of TrueType are written as cubic ones.
+func (f *sfnt) glyphPath(w *ContentWriter, g int, m matrix, depth int) {
+	b := f.glyphData(g)
+	if len(b) == 0 {
+		return
+	}
+	if int16(u16(b, 0)) < 0 {
+		f.compositePath(w, b, m, depth)
+		return
+	}
+	sg := parseGlyph(b, g)
+	start := 0
+	for _, end := range sg.ends {
+		contour(w, sg.xs[start:end+1], sg.ys[start:end+1], sg.flags[start:end+1], m)
+		start = end + 1
+	}
+}
+
+// contour appends a closed contour of TrueType points to w, transformed by
+// m. Points with bit 1 of their flags set are on the curve; a point between
+// two points off the curve is implied.
+func contour(w *ContentWriter, xs, ys []float64, flags []byte, m matrix) {
+	n := len(xs)
+	on := func(i int) bool { return flags[i%n]&1 != 0 }
+	pt := func(i int) (float64, float64) { return xs[i%n], ys[i%n] }
+	mid := func(i, j int) (float64, float64) {
+		x0, y0 := pt(i)
+		x1, y1 := pt(j)
+		return (x0 + x1) / 2, (y0 + y1) / 2
+	}
+	// The contour starts at a point on the curve, or at the point implied
+	// between the first two if there's none.
+	first := -1
+	for i := 0; i < n; i++ {
+		if on(i) {
+			first = i
+			break
+		}
+	}
+	var sx, sy float64
+	if first < 0 {
+		first = 0
+		sx, sy = mid(0, 1)
+	} else {
+		sx, sy = pt(first)
+	}
+	w.MoveTo(m.apply(sx, sy))
+	cx, cy := sx, sy
+	for k := 1; k <= n; k++ {
+		i := first + k
+		if on(i) {
+			if k == n {
+				break
+			}
+			x, y := pt(i)
+			w.LineTo(m.apply(x, y))
+			cx, cy = x, y
+			continue
+		}
+		qx, qy := pt(i)
+		var x, y float64
+		if on(i + 1) {
+			x, y = pt(i + 1)
+			k++
+		} else if k == n {
+			x, y = sx, sy
+		} else {
+			x, y = mid(i, i+1)
+		}
+		// A quadratic curve is a cubic one with its control points
+		// two thirds of the way to the quadratic control point.
+		x1, y1 := cx+2*(qx-cx)/3, cy+2*(qy-cy)/3
+		x2, y2 := x+2*(qx-x)/3, y+2*(qy-y)/3
+		ax, ay := m.apply(x1, y1)
+		bx, by := m.apply(x2, y2)
+		ex, ey := m.apply(x, y)
+		w.Curve(ax, ay, bx, by, ex, ey)
+		cx, cy = x, y
+	}
+	w.ClosePath()
+}
+
+// compositePath appends the paths of the components of the composite glyph
+// b to w, transformed by m.
+func (f *sfnt) compositePath(w *ContentWriter, b []byte, m matrix, depth int) {
+	if depth > 8 {
+		fail(ErrBadFile, "composite glyphs nested too deep")
+	}
+	p := 10
+	for {
+		flags, g := u16(b, p), int(u16(b, p+2))
+		p += 4
+		var dx, dy float64
+		if flags&1 != 0 { // words
+			dx, dy = float64(int16(u16(b, p))), float64(int16(u16(b, p+2)))
+			p += 4
+		} else {
+			if p+2 > len(b) {
+				fail(ErrBadFile, "composite glyph too short")
+			}
+			dx, dy = float64(int8(b[p])), float64(int8(b[p+1]))
+			p += 2
+		}
+		// Only offsets are supported; arguments that are points to
+		// be matched are taken as zero offsets.
+		if flags&2 == 0 {
+			dx, dy = 0, 0
+		}
+		f2 := func(off int) float64 { return float64(int16(u16(b, off))) / 16384 }
+		c := matrix{1, 0, 0, 1, dx, dy}
+		switch {
+		case flags&8 != 0: // scale
+			c[0], c[3] = f2(p), f2(p)
+			p += 2
+		case flags&0x40 != 0: // x and y scale
+			c[0], c[3] = f2(p), f2(p+2)
+			p += 4
+		case flags&0x80 != 0: // 2×2 transformation
+			c[0], c[1], c[2], c[3] = f2(p), f2(p+2), f2(p+4), f2(p+6)
+			p += 8
+		}
+		f.glyphPath(w, g, c.mul(m), depth+1)
+		if flags&0x20 == 0 { // more components
+			return
+		}
+	}
+}
+
+// writeSfnt returns a font file with the given tables, by their tags. The
+// checksums of the tables and of the file are set.
+func writeSfnt(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for t := range tables {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	n := len(tags)
+	sel := 0
+	for 2<<sel <= n {
+		sel++
+	}
+	b := make([]byte, 12+16*n, 12+16*n+len(tables)*4)
+	binary.BigEndian.PutUint32(b, 0x10000)
+	binary.BigEndian.PutUint16(b[4:], uint16(n))
+	binary.BigEndian.PutUint16(b[6:], uint16(16<<sel))
+	binary.BigEndian.PutUint16(b[8:], uint16(sel))
+	binary.BigEndian.PutUint16(b[10:], uint16(16*n-16<<sel))
+	head := -1
+	for i, t := range tags {
+		data := tables[t]
+		if t == "head" && len(data) >= 12 {
+			// The adjustment of the checksum is set at the end.
+			data = append([]byte(nil), data...)
+			binary.BigEndian.PutUint32(data[8:], 0)
+			head = len(b)
+		}
+		r := b[12+16*i:]
+		copy(r, t)
+		binary.BigEndian.PutUint32(r[4:], checksum(data))
+		binary.BigEndian.PutUint32(r[8:], uint32(len(b)))
+		binary.BigEndian.PutUint32(r[12:], uint32(len(data)))
+		b = append(b, data...)
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+	}
+	if head >= 0 {
+		binary.BigEndian.PutUint32(b[head+8:], 0xb1b0afba-checksum(b))
+	}
+	return b
+}
+
+// checksum returns the checksum of a table of a font, or of a whole font.
+func checksum(b []byte) uint32 {
+	var s uint32
+	for i := 0; i < len(b); i += 4 {
+		var w [4]byte
+		copy(w[:], b[i:])
+		s += binary.BigEndian.Uint32(w[:])
+	}
+	return s
+}
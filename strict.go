@@ -0,0 +1,283 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file checks documents made with the Strict option as they are
+// written, against the rules of PDF and the limits of viewers, so
+// that mistakes fail at once, saying what's wrong and where, instead of
+// making files that are subtly broken.
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Limits of viewers that strict documents are held to.
+const (
+	maxNameLen   = 127   // bytes of names
+	maxStringLen = 32767 // bytes of strings
+	maxReal      = 3.403e38
+	maxInt       = 1<<31 - 1
+	maxSaves     = 28 // nesting of q operators
+)
+
+// pathOps are the operators that construct, clip and paint paths, which
+// can't be inside text objects.
+var pathOps = map[string]bool{
+	"m": true, "l": true, "c": true, "v": true, "y": true, "h": true, "re": true,
+	"S": true, "s": true, "f": true, "F": true, "f*": true, "B": true, "B*": true,
+	"b": true, "b*": true, "n": true, "W": true, "W*": true,
+}
+
+// strictError panics with the error of strict documents about what, which
+// is an object or a content stream, breaking a rule.
+func strictError(what, rule string) {
+	panic("strict: " + what + ": " + rule)
+}
+
+// checkStrict checks object o of indirect object i, if d is strict.
+func (d *Document) checkStrict(i *indirect, o interface{}) {
+	if !d.opts.Strict {
+		return
+	}
+	what := fmt.Sprintf("object %d", i.num)
+	if r := strictValue(o, ""); r != "" {
+		strictError(what, r)
+	}
+	// Forms written at once, like the appearances of fields, are checked
+	// here; content written bit by bit is checked by checkContent.
+	if s, ok := o.(*stream); ok && s.buf != nil && s.dic["Subtype"] == name("Form") &&
+		s.dic["Filter"] == nil {
+		if r := strictContent(s.buf); r != "" {
+			strictError("content of "+what, r)
+		}
+	}
+}
+
+// checkContent checks the colors of content stream s of indirect object i
+// against the standards of d, and the limits of viewers if d is strict.
+func (d *Document) checkContent(i *indirect, s *spill) {
+	if d.pdfa || d.pdfx != "" {
+		d.checkContentColors(s.bytes())
+	}
+	if !d.opts.Strict {
+		return
+	}
+	if r := strictContent(s.bytes()); r != "" {
+		strictError(fmt.Sprintf("content stream %d", i.num), r)
+	}
+}
+
+// checkWritten panics if d is strict and any of its objects is not written
+// to the output, which would leave its entry of the cross-reference table
+// broken.
+func (d *Document) checkWritten() {
+	if !d.opts.Strict {
+		return
+	}
+	var nums []string
+	for _, i := range d.objs {
+		if i.off == 0 {
+			nums = append(nums, fmt.Sprint(i.num))
+		}
+	}
+	if len(nums) > 0 {
+		strictError("objects "+strings.Join(nums, ", "), "reserved but never written")
+	}
+}
+
+// strictValue returns the rule of strict documents that v breaks, starting
+// with the path to the part of v that breaks it, like "/Resources/Font: ...",
+// or an empty string if it breaks none. Indirect objects in v are not
+// followed; they're checked when they're written.
+func strictValue(v interface{}, path string) string {
+	at := func(r string) string { return strictAt(path, r) }
+	switch t := v.(type) {
+	case nil, bool, *indirect, Ref, ref, *rect, sigByteRange, sigContents:
+		return ""
+	case int:
+		if t > maxInt || t < -maxInt-1 {
+			return at(fmt.Sprint("integer out of range: ", t))
+		}
+		return ""
+	case float32:
+		return strictValue(float64(t), path)
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) || math.Abs(t) > maxReal {
+			return at(fmt.Sprint("real number out of range: ", t))
+		}
+		return ""
+	case string:
+		return strictString(len(t), path)
+	case hexString:
+		return strictString(len(t), path)
+	case Str:
+		return strictString(len(textString(string(t))), path)
+	case name:
+		return strictName(string(t), path)
+	case Name:
+		return strictName(string(t), path)
+	case []byte:
+		return ""
+	case *stream:
+		return strictValue(t.dic, path)
+	case *Stream:
+		return strictValue(t.Dict, path)
+	case outputter:
+		return at(fmt.Sprintf("value of unknown type %T", v))
+	case objecter:
+		return strictValue(t.object(), path)
+	case reflect.Value:
+		return strictValue(t.Interface(), path)
+	}
+
+	switch r := reflect.ValueOf(v); r.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < r.Len(); i++ {
+			if s := strictValue(r.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i)); s != "" {
+				return s
+			}
+		}
+		return ""
+	case reflect.Map:
+		if r.Type().Key().Kind() != reflect.String {
+			return at("key of dictionary is not a name: " + r.Type().Key().String())
+		}
+		if r.IsNil() {
+			return ""
+		}
+		keys := make([]string, 0, r.Len())
+		for _, k := range r.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := path + "/" + k
+			if s := strictName(k, p); s != "" {
+				return s
+			}
+			if s := strictValue(r.MapIndex(reflect.ValueOf(k).Convert(r.Type().Key())).Interface(), p); s != "" {
+				return s
+			}
+		}
+		return ""
+	case reflect.Ptr:
+		if r.IsNil() {
+			return ""
+		}
+	}
+	// Other values are written as null.
+	return at(fmt.Sprintf("value of type %T can't be written", v))
+}
+
+// strictAt returns rule r prefixed with path, if there's one.
+func strictAt(path, r string) string {
+	if path == "" {
+		return r
+	}
+	return path + ": " + r
+}
+
+// strictName returns the rule of strict documents that name n at path
+// breaks, or an empty string.
+func strictName(n, path string) string {
+	switch {
+	case len(n) > maxNameLen:
+		return strictAt(path, "name longer than 127 bytes: /"+n[:20]+"...")
+	case strings.IndexByte(n, 0) >= 0:
+		return strictAt(path, "name with a null character")
+	}
+	return ""
+}
+
+// strictString returns the rule of strict documents that a string of n bytes
+// at path breaks, or an empty string.
+func strictString(n int, path string) string {
+	if n > maxStringLen {
+		return strictAt(path, fmt.Sprintf("string of %d bytes, longer than %d", n, maxStringLen))
+	}
+	return ""
+}
+
+// strictContent returns the rule of strict documents that content stream b
+// breaks, or an empty string. Operators are counted from 1.
+func strictContent(b []byte) string {
+	var ops []operation
+	if r := recovered(func() { ops = operations(b) }); r != nil {
+		if e, ok := r.(*Error); ok {
+			return e.Msg
+		}
+		return fmt.Sprint(r)
+	}
+	saves, marks := 0, 0
+	text := false
+	for n, op := range ops {
+		at := fmt.Sprintf("operator %d, %s", n+1, op.op)
+		for i, a := range op.args {
+			if s := strictValue(a, fmt.Sprintf("%s, operand %d", at, i+1)); s != "" {
+				return s
+			}
+		}
+		if text && pathOps[op.op] {
+			return at + ": " + op.op + " inside a text object"
+		}
+		switch op.op {
+		case "q":
+			if text {
+				return at + ": q inside a text object"
+			}
+			if saves++; saves > maxSaves {
+				return fmt.Sprintf("%s: q nested more than %d deep", at, maxSaves)
+			}
+		case "Q":
+			if text {
+				return at + ": Q inside a text object"
+			}
+			if saves--; saves < 0 {
+				return at + ": Q with no q"
+			}
+		case "BT":
+			if text {
+				return at + ": BT inside a text object"
+			}
+			text = true
+		case "ET":
+			if !text {
+				return at + ": ET with no BT"
+			}
+			text = false
+		case "BMC", "BDC":
+			marks++
+		case "EMC":
+			if marks--; marks < 0 {
+				return at + ": EMC with no BMC or BDC"
+			}
+		}
+	}
+	switch {
+	case text:
+		return "BT with no ET"
+	case saves > 0:
+		return fmt.Sprintf("%d q with no Q", saves)
+	case marks > 0:
+		return fmt.Sprintf("%d BMC or BDC with no EMC", marks)
+	}
+	return ""
+}
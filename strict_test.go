@@ -0,0 +1,118 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+type strictTest struct {
+	build func(d *Document) error
+	err   string // part of the error, or empty if there's none
+}
+
+func TestStrict(t *testing.T) {
+	long := strings.Repeat("n", 128)
+	raw := func(ops ...string) func(d *Document) error {
+		return func(d *Document) error {
+			p, _ := d.NewPage(100, 100)
+			for _, op := range ops {
+				if err := p.Raw(op); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	object := func(v interface{}) func(d *Document) error {
+		return func(d *Document) error {
+			_, err := d.AddObject(v)
+			return err
+		}
+	}
+	tests := []strictTest{
+		{raw("q", "BT", "ET", "Q"), ""},
+		{object(map[string]interface{}{"A": []interface{}{1, 2.5, "s", Name("N")}}), ""},
+		{raw("Q"), "content stream 4: operator 1, Q: Q with no q"},
+		{raw("q", "q"), "content stream 4: 2 q with no Q"},
+		{raw("BT", "q", "Q", "ET"), "operator 2, q: q inside a text object"},
+		{raw("BT", "BT"), "operator 2, BT: BT inside a text object"},
+		{raw("BT", "h", "ET"), "operator 2, h: h inside a text object"},
+		{raw("BT", "f*", "ET"), "operator 2, f*: f* inside a text object"},
+		{raw("ET"), "ET with no BT"},
+		{raw("BT"), "BT with no ET"},
+		{raw("EMC"), "EMC with no BMC or BDC"},
+		{raw(strings.Split(strings.Repeat("q ", 29), " ")[:29]...), "operator 29, q: q nested more than 28 deep"},
+		{func(d *Document) error {
+			p, _ := d.NewPage(100, 100)
+			return p.Raw("BMC", Name(long))
+		}, "operator 1, BMC, operand 1: name longer than 127 bytes"},
+		{object(map[string]interface{}{"K": map[string]interface{}{long: 1}}), "object 3: /K/" + long + ": name longer than 127 bytes"},
+		{object([]interface{}{Name("a\x00b")}), "object 3: [0]: name with a null character"},
+		{func(d *Document) error {
+			d.outputIndirect(d.reserveIndirect(), []interface{}{map[int]int{1: 2}})
+			return nil
+		}, "object 3: [0]: key of dictionary is not a name: int"},
+		{object(map[string]interface{}{"N": []interface{}{1, Name(long)}}), "object 3: /N[1]: name longer than 127 bytes"},
+		{object(strings.Repeat("s", 32768)), "object 3: string of 32768 bytes"},
+		{object([]interface{}{1e39}), "object 3: [0]: real number out of range"},
+		{object([]interface{}{math.NaN()}), "real number out of range: NaN"},
+		{object(map[string]interface{}{"I": 1 << 32}), "object 3: /I: integer out of range: 4294967296"},
+		{func(d *Document) error {
+			d.outputIndirect(d.reserveIndirect(), map[string]interface{}{"T": int64(1)})
+			return nil
+		}, "object 3: /T: value of type int64 can't be written"},
+		{func(d *Document) error {
+			d.reserveIndirect()
+			d.NewPage(100, 100)
+			return nil
+		}, "objects 3: reserved but never written"},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		d, _ := New(buf, Options{Strict: true})
+		err := func() (err error) {
+			defer dontPanic(&err)
+			if err := test.build(d); err != nil {
+				return err
+			}
+			return d.Close()
+		}()
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%d: %v", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%d: got %v expected %q", i, err, test.err)
+		}
+	}
+
+	// Documents that aren't strict are written as they are.
+	d, _ := New(bytes.NewBuffer(nil))
+	p, _ := d.NewPage(100, 100)
+	p.Raw("Q")
+	if err := d.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file makes subsets of TrueType fonts, with only the glyphs used by a
+// document, so that fonts with thousands of glyphs, like the ones of CJK,
+// add only what's shown to the size of documents.
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// subsetTables are the tables of TrueType fonts that PDF uses; the others
+// are dropped from subsets.
+var subsetTables = []string{"head", "hhea", "hmtx", "loca", "glyf", "maxp", "cvt ", "fpgm", "prep"}
+
+// subset returns a font file with glyph 0 and the glyphs gs of f, and the
+// glyphs they are made of. The other glyphs are empty but kept, so that the
+// glyphs of the subset have the same indices as in f.
+func (f *sfnt) subset(gs []int) []byte {
+	keep := map[int]bool{}
+	f.keepGlyph(keep, 0, 0)
+	for _, g := range gs {
+		f.keepGlyph(keep, g, 0)
+	}
+	// Offsets of loca are 32 bits in subsets.
+	var glyf []byte
+	loca := make([]byte, 0, 4*f.numGlyphs+4)
+	for g := 0; g < f.numGlyphs; g++ {
+		loca = binary.BigEndian.AppendUint32(loca, uint32(len(glyf)))
+		if keep[g] {
+			glyf = append(glyf, f.glyphData(g)...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	loca = binary.BigEndian.AppendUint32(loca, uint32(len(glyf)))
+
+	head := append([]byte(nil), f.table("head", 54)...)
+	binary.BigEndian.PutUint16(head[50:], 1)
+	tables := map[string][]byte{"head": head, "loca": loca, "glyf": glyf}
+	for _, t := range subsetTables {
+		if b, ok := f.tables[t]; ok && tables[t] == nil {
+			tables[t] = b
+		}
+	}
+	return writeSfnt(tables)
+}
+
+// keepGlyph adds glyph g of f to keep, with the glyphs it's made of if it's
+// a composite glyph.
+func (f *sfnt) keepGlyph(keep map[int]bool, g, depth int) {
+	if depth > 8 {
+		fail(ErrBadFile, "composite glyphs nested too deep")
+	}
+	b := f.glyphData(g)
+	keep[g] = true
+	if len(b) < 10 || int16(u16(b, 0)) >= 0 {
+		return
+	}
+	p := 10
+	for {
+		flags := u16(b, p)
+		f.keepGlyph(keep, int(u16(b, p+2)), depth+1)
+		p += 6
+		if flags&1 != 0 { // words
+			p += 2
+		}
+		switch {
+		case flags&8 != 0: // scale
+			p += 2
+		case flags&0x40 != 0: // x and y scale
+			p += 4
+		case flags&0x80 != 0: // 2×2 transformation
+			p += 8
+		}
+		if flags&0x20 == 0 { // more components
+			return
+		}
+	}
+}
+
+// subsetTag returns the tag of the subset of the font with PostScript name
+// base that has glyphs gs: six capital letters, which are put before the
+// name of the font followed by a plus sign. It's made from the glyphs, so
+// that different subsets of a font have different names.
+func subsetTag(base string, gs []int) string {
+	h := fnv.New64a()
+	h.Write([]byte(base))
+	for _, g := range gs {
+		h.Write([]byte{byte(g >> 8), byte(g)})
+	}
+	v := h.Sum64()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(v%26)
+		v /= 26
+	}
+	return string(tag)
+}
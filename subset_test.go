@@ -0,0 +1,92 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"testing"
+)
+
+// testCompositeFont returns testOutlineFont with glyph 3 for "C", made of
+// glyph 1 moved by (10, 20).
+func testCompositeFont() []byte {
+	f := parseSfnt(testOutlineFont())
+	comp := be(int16(-1), int16(0), int16(0), int16(0), int16(0), uint16(2), uint16(1), []byte{10, 20})
+	tables := map[string][]byte{}
+	for t, b := range f.tables {
+		tables[t] = b
+	}
+	n := len(tables["glyf"])
+	tables["glyf"] = be(tables["glyf"], comp)
+	tables["loca"] = be(tables["loca"], uint32(n+len(comp)))
+	tables["maxp"] = be(uint32(0x5000), uint16(4))
+	tables["hhea"] = be(make([]byte, 34), uint16(4))
+	tables["hmtx"] = be(tables["hmtx"], uint16(700), int16(0))
+	tables["cmap"] = be(uint16(0), uint16(1), uint16(3), uint16(10), uint32(12),
+		uint16(12), uint16(0), uint32(28), uint32(0), uint32(1), uint32('A'), uint32('C'), uint32(1))
+	tables["name"] = be(uint16(0), uint16(1), uint16(18), uint16(3), uint16(1), uint16(0x409),
+		uint16(6), uint16(12), uint16(0), []byte("\x00T\x00e\x00s\x00t\x00 \x001"))
+	return writeSfnt(tables)
+}
+
+type subsetTest struct {
+	gs   []int
+	kept []int // glyphs with outlines in the subset
+}
+
+func TestSubset(t *testing.T) {
+	f := parseSfnt(testCompositeFont())
+	if n := f.postScriptName(); n != "Test1" {
+		t.Errorf("PostScript name: got %q", n)
+	}
+	if n := parseSfnt(testOutlineFont()).postScriptName(); n != "Font" {
+		t.Errorf("PostScript name of a font with no names: got %q", n)
+	}
+	tests := []subsetTest{
+		{nil, nil},
+		{[]int{2}, []int{2}},
+		{[]int{3}, []int{1, 3}},
+		{[]int{1, 2, 3}, []int{1, 2, 3}},
+	}
+
+	for _, test := range tests {
+		s := parseSfnt(f.subset(test.gs))
+		if s.numGlyphs != 4 || !s.longLoca {
+			t.Errorf("%v: %d glyphs, long loca %v", test.gs, s.numGlyphs, s.longLoca)
+		}
+		if _, ok := s.tables["cmap"]; ok {
+			t.Errorf("%v: cmap is kept", test.gs)
+		}
+		for g := 0; g < 4; g++ {
+			kept := false
+			for _, k := range test.kept {
+				kept = kept || k == g
+			}
+			if b := s.glyphData(g); (len(b) > 0) != kept {
+				t.Errorf("%v: glyph %d has %d bytes", test.gs, g, len(b))
+			} else if kept && string(b[:len(f.glyphData(g))]) != string(f.glyphData(g)) {
+				t.Errorf("%v: glyph %d is changed", test.gs, g)
+			}
+		}
+	}
+
+	if a, b := subsetTag("Test1", []int{0, 1}), subsetTag("Test1", []int{0, 1}); a != b || len(a) != 6 {
+		t.Errorf("tags %q and %q", a, b)
+	}
+	if a, b := subsetTag("Test1", []int{0, 1}), subsetTag("Test1", []int{0, 2}); a == b {
+		t.Errorf("same tag %q for different subsets", a)
+	}
+}
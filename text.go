@@ -0,0 +1,321 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file contains what is needed to put text in content streams with the
+// standard fonts, which are used for the appearances of form fields and the
+// text of pages.
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextStyle holds how text looks. The zero value is black Helvetica of size
+// 12, aligned to the left.
+type TextStyle struct {
+	Font     string  // standard font, like FontHelvetica or FontTimesBold
+	FontSize float64 // 12 if zero
+	Color    Color   // black if nil
+	Align    int     // AlignLeft, AlignCenter or AlignRight
+}
+
+// font returns the name of the font of s.
+func (s *TextStyle) font() string {
+	if s.Font == "" {
+		return FontHelvetica
+	}
+	if f, ok := stdFonts[s.Font]; !ok || f.symbolic {
+		panic("unknown font: " + s.Font)
+	}
+	return s.Font
+}
+
+// size returns the font size of s.
+func (s *TextStyle) size() float64 {
+	if s.FontSize <= 0 {
+		return defaultFontSize
+	}
+	return s.FontSize
+}
+
+// color returns the color of the text of s.
+func (s *TextStyle) color() Color {
+	if s.Color == nil {
+		return Gray(0)
+	}
+	return s.Color
+}
+
+// leading returns the distance between the baselines of lines of text with
+// style s.
+func (s *TextStyle) leading() float64 {
+	return s.size() * 1.15
+}
+
+// width returns the width of the WinAnsi encoded string t in the font of s
+// with the given size.
+func (s *TextStyle) width(t string, size float64) float64 {
+	if before, sup, after, ok := superscript(t); ok {
+		return s.width(before, size) + s.width(sup, size*supSize) + s.width(after, size)
+	}
+	// Emoji are as wide as the font size.
+	if i := strings.Index(t, emojiMark); i >= 0 && i+1 < len(t) {
+		return s.width(t[:i]+t[i+2:], size) + size
+	}
+	return stdFonts[s.font()].width(t, size)
+}
+
+// wrap converts the UTF-8 string t to WinAnsiEncoding and breaks it into
+// lines which are not wider than w with style s.
+func (s *TextStyle) wrap(t string, w float64) []string {
+	return wrapText(winAnsi(t), w, func(l string) float64 {
+		return s.width(l, s.size())
+	})
+}
+
+// text returns content stream operators showing the WinAnsi encoded string t
+// with the font and color of s in the given size, starting at (x, y).
+func (s *TextStyle) text(t string, x, y, size float64) string {
+	return string(s.appendText(make([]byte, 0, len(t)+64), t, x, y, size, 3))
+}
+
+// appendText appends the operators of text to b, with prec digits after the
+// point of numbers at most.
+func (s *TextStyle) appendText(b []byte, t string, x, y, size float64, prec int) []byte {
+	b = append(append(append(b, "BT /"...), s.font()...), ' ')
+	b = append(appendFloat(b, size, prec), " Tf "...)
+	b = append(appendColor(b, s.color(), false, prec), ' ')
+	b = append(appendFloat(b, x, prec), ' ')
+	b = append(appendFloat(b, y, prec), " Td "...)
+	if _, _, _, ok := superscript(t); ok || strings.Contains(t, emojiMark) {
+		b = append(b, s.show(t, size)...)
+	} else {
+		b = append(appendEscaped(append(b, '('), t), ") Tj"...)
+	}
+	return append(b, " ET\n"...)
+}
+
+// show returns text operators showing the WinAnsi encoded string t with the
+// font of s in the given size. Superscripts in t are smaller and raised.
+func (s *TextStyle) show(t string, size float64) string {
+	before, sup, after, ok := superscript(t)
+	if !ok {
+		return s.showEmoji(t, size)
+	}
+	op := ""
+	if before != "" {
+		op = s.showEmoji(before, size) + " "
+	}
+	op += fmt.Sprint("/", s.font(), " ", ftoa(size*supSize), " Tf ", ftoa(size*supRise),
+		" Ts (", escapeString(sup), ") Tj /", s.font(), " ", ftoa(size), " Tf 0 Ts")
+	if after != "" {
+		op += " " + s.show(after, size)
+	}
+	return op
+}
+
+// showEmoji returns text operators showing the WinAnsi encoded string t with
+// the font of s in the given size, switching to the font of emoji for the
+// emoji in t.
+func (s *TextStyle) showEmoji(t string, size float64) string {
+	i := strings.Index(t, emojiMark)
+	if i < 0 || i+1 >= len(t) {
+		return "(" + escapeString(t) + ") Tj"
+	}
+	op := ""
+	if i > 0 {
+		op = "(" + escapeString(t[:i]) + ") Tj "
+	}
+	// Emoji next to each other are shown at once.
+	codes := ""
+	for ; strings.HasPrefix(t[i:], emojiMark) && i+1 < len(t); i += 2 {
+		codes += t[i+1 : i+2]
+	}
+	op += fmt.Sprint("/", emojiFont, " ", ftoa(size), " Tf (", escapeString(codes), ") Tj /",
+		s.font(), " ", ftoa(size), " Tf")
+	if i < len(t) {
+		op += " " + s.showEmoji(t[i:], size)
+	}
+	return op
+}
+
+// Superscripts in text, like the markers of footnotes, are put between
+// supStart and supEnd. They are supSize times the size of the text, and
+// raised by supRise times it.
+const (
+	supStart = "\x01"
+	supEnd   = "\x02"
+	supSize  = 0.6
+	supRise  = 0.35
+)
+
+// superscript splits t at its first superscript, if it has any.
+func superscript(t string) (before, sup, after string, ok bool) {
+	i := strings.Index(t, supStart)
+	if i < 0 {
+		return t, "", "", false
+	}
+	before, sup = t[:i], t[i+1:]
+	if j := strings.Index(sup, supEnd); j >= 0 {
+		sup, after = sup[:j], sup[j+1:]
+	}
+	return before, sup, after, true
+}
+
+// line returns content stream operators showing the WinAnsi encoded string t
+// aligned according to s in a box that starts at x and is w wide, with the
+// baseline at y.
+func (s *TextStyle) line(t string, x, y, w, size float64) string {
+	switch s.Align {
+	case AlignCenter:
+		x += (w - s.width(t, size)) / 2
+	case AlignRight:
+		x += w - s.width(t, size)
+	}
+	return s.text(t, x, y, size)
+}
+
+// pageFont adds the standard font with the given name to the resources of
+// the current page, or of the XObject being made, and returns the name. The
+// standard fonts have the same names as in the resources of the form.
+func (d *Document) pageFont(n string) string {
+	return d.useResource("Font", n, d.fieldFont(n))
+}
+
+// winAnsiHigh maps Unicode characters to codes 128 to 159 of WinAnsiEncoding
+// (p. 997). Codes 160 to 255 are the same as Latin-1.
+var winAnsiHigh = map[int]byte{
+	0x20ac: 0x80, 0x201a: 0x82, 0x0192: 0x83, 0x201e: 0x84,
+	0x2026: 0x85, 0x2020: 0x86, 0x2021: 0x87, 0x02c6: 0x88,
+	0x2030: 0x89, 0x0160: 0x8a, 0x2039: 0x8b, 0x0152: 0x8c,
+	0x017d: 0x8e, 0x2018: 0x91, 0x2019: 0x92, 0x201c: 0x93,
+	0x201d: 0x94, 0x2022: 0x95, 0x2013: 0x96, 0x2014: 0x97,
+	0x02dc: 0x98, 0x2122: 0x99, 0x0161: 0x9a, 0x203a: 0x9b,
+	0x0153: 0x9c, 0x017e: 0x9e, 0x0178: 0x9f,
+}
+
+// winAnsi converts the UTF-8 string s to WinAnsiEncoding. Characters that
+// don't exist in the encoding are replaced by question marks.
+func winAnsi(s string) string {
+	buf := bytes.NewBuffer(make([]byte, 0, len(s)))
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		i += n
+		c := int(r)
+		switch {
+		case c == int(emojiMark[0]):
+			// It only marks emoji.
+			buf.WriteByte('?')
+		case c < 128 || (c >= 160 && c < 256):
+			buf.WriteByte(byte(c))
+		case winAnsiHigh[c] != 0:
+			buf.WriteByte(winAnsiHigh[c])
+		default:
+			buf.WriteByte('?')
+		}
+	}
+	return buf.String()
+}
+
+// unencodable returns the characters of the UTF-8 string s that winAnsi
+// turns into question marks.
+func unencodable(s string) []rune {
+	var rs []rune
+	for _, r := range s {
+		if r != '?' && winAnsi(string(r)) == "?" {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// helveticaWidths holds the widths of the characters of Helvetica in
+// WinAnsiEncoding, in thousandths of the font size.
+var helveticaWidths = [256]int{
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, 0,
+	556, 0, 222, 556, 333, 1000, 556, 556, 333, 1000, 667, 333, 1000, 0, 611, 0,
+	0, 222, 222, 333, 333, 350, 556, 1000, 333, 1000, 500, 333, 944, 0, 500, 667,
+	278, 333, 556, 556, 556, 556, 260, 556, 333, 737, 370, 556, 584, 333, 737, 333,
+	400, 584, 333, 333, 333, 556, 537, 278, 333, 333, 365, 556, 834, 834, 834, 611,
+	667, 667, 667, 667, 667, 667, 1000, 722, 667, 667, 667, 667, 278, 278, 278, 278,
+	722, 722, 778, 778, 778, 778, 778, 584, 778, 722, 722, 722, 722, 667, 667, 611,
+	556, 556, 556, 556, 556, 556, 889, 500, 556, 556, 556, 556, 278, 278, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 584, 611, 556, 556, 556, 556, 500, 556, 500,
+}
+
+// courierWidth is the width of all the characters of Courier, in thousandths
+// of the font size.
+const courierWidth = 600
+
+// wrapText breaks the WinAnsi encoded string s into lines which are not wider
+// than w. width returns the width of a string. Lines are broken at spaces
+// when possible, and always at new line characters. A word wider than w is
+// broken between its characters.
+func wrapText(s string, w float64, width func(string) float64) []string {
+	return wrapLines(s, func(int) float64 { return w }, width)
+}
+
+// wrapLines is like wrapText, but line i of the lines is not wider than
+// w(i), like when some lines are beside a picture.
+func wrapLines(s string, w func(int) float64, width func(string) float64) []string {
+	lines := make([]string, 0, 1)
+	for _, para := range strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n") {
+		line := ""
+		for _, word := range strings.Split(para, " ") {
+			next := word
+			if line != "" {
+				next = line + " " + word
+			}
+			if width(next) <= w(len(lines)) {
+				line = next
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// Break the word itself if it doesn't fit in a line.
+			for len(word) > 1 && width(word) > w(len(lines)) {
+				n := len(word) - 1
+				for n > 1 && (width(word[:n]) > w(len(lines)) || word[n-1:n] == emojiMark) {
+					n--
+				}
+				// Emoji are not broken.
+				if word[n-1:n] == emojiMark {
+					n++
+				}
+				if n >= len(word) {
+					break
+				}
+				lines = append(lines, word[:n])
+				word = word[n:]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"strings"
+	"testing"
+)
+
+type winAnsiTest struct {
+	in  string
+	out string
+}
+
+func TestWinAnsi(t *testing.T) {
+	tests := []winAnsiTest{
+		{"", ""},
+		{"hello", "hello"},
+		{"café", "caf\xe9"},
+		{"€5 – “ok”", "\x805 \x96 \x93ok\x94"},
+		{"سلام", "????"},
+	}
+
+	for _, test := range tests {
+		if o := winAnsi(test.in); o != test.out {
+			t.Errorf("winAnsi(%q): got %q expected %q", test.in, o, test.out)
+		}
+	}
+}
+
+type textWidthTest struct {
+	font string
+	in   string
+	w    float64
+}
+
+func TestTextWidth(t *testing.T) {
+	tests := []textWidthTest{
+		{FontHelvetica, "Hi", 722 + 222},
+		{FontHelveticaBoldOblique, "Hi", 722 + 278},
+		{FontTimes, "Hi", 722 + 278},
+		{FontTimesBoldItalic, "W\x80", 889 + 500},
+		{FontCourierBold, "Hi!", 3 * 600},
+		{FontSymbol, "ap", 631 + 549},
+		{FontZapfDingbats, "4", 846},
+	}
+
+	for _, test := range tests {
+		if w := stdFonts[test.font].width(test.in, 10); w != test.w/100 {
+			t.Errorf("%s %q: got %v expected %v", test.font, test.in, w, test.w/100)
+		}
+	}
+	for n, f := range stdFonts {
+		if f.widths != nil && f.widths[' '] == 0 {
+			t.Errorf("%s has no width for space", n)
+		}
+		if stdFontNamed(f.base) != f {
+			t.Errorf("%s isn't found by its name", f.base)
+		}
+	}
+}
+
+type wrapTextTest struct {
+	in  string
+	w   float64
+	out []string
+}
+
+func TestWrapText(t *testing.T) {
+	// Every character is 10 wide.
+	width := func(s string) float64 { return float64(10 * len(s)) }
+	tests := []wrapTextTest{
+		{"", 100, []string{""}},
+		{"one two three", 100, []string{"one two", "three"}},
+		{"one two three", 130, []string{"one two three"}},
+		{"a\nb c", 100, []string{"a", "b c"}},
+		{"abcdefgh ij", 30, []string{"abc", "def", "gh", "ij"}},
+	}
+
+	for _, test := range tests {
+		o := wrapText(test.in, test.w, width)
+		if strings.Join(o, "|") != strings.Join(test.out, "|") {
+			t.Errorf("wrapText(%q, %v): got %q expected %q",
+				test.in, test.w, o, test.out)
+		}
+	}
+}
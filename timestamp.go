@@ -0,0 +1,121 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file gets trusted timestamps from time stamping authorities (RFC 3161),
+// for timestamps of signatures and of whole documents.
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+)
+
+var oidTimestampToken = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+
+// TimestampClient gets timestamp tokens from a time stamping authority.
+type TimestampClient interface {
+	// Timestamp returns the DER encoded timestamp token of data with the
+	// given SHA-256 digest.
+	Timestamp(digest []byte) ([]byte, error)
+}
+
+// TSA is a TimestampClient that sends requests to a time stamping authority
+// over HTTP.
+type TSA struct {
+	URL string
+	// Client sends the requests; http.DefaultClient is used if it's nil.
+	Client *http.Client
+}
+
+type messageImprint struct {
+	Algorithm algorithm
+	Digest    []byte
+}
+
+type tsRequest struct {
+	Version int
+	Imprint messageImprint
+	Nonce   *big.Int
+	CertReq bool
+}
+
+type tsStatus struct {
+	Status   int
+	Text     []asn1.RawValue `asn1:"optional"` // UTF8Strings
+	FailInfo asn1.BitString  `asn1:"optional"`
+}
+
+type tsResponse struct {
+	Status tsStatus
+	Token  asn1.RawValue `asn1:"optional"`
+}
+
+// Timestamp sends a request for a timestamp of digest to t.URL and returns
+// the token in the response.
+func (t *TSA) Timestamp(digest []byte) (tok []byte, err error) {
+	defer dontPanic(&err)
+
+	nonce, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	check(err)
+	req := marshal(tsRequest{
+		Version: 1,
+		Imprint: messageImprint{algorithm{oidSHA256, asn1Null}, digest},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+
+	c := t.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	r, err := c.Post(t.URL, "application/timestamp-query", bytes.NewBuffer(req))
+	check(err)
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		panic("time stamping authority answered " + r.Status)
+	}
+	b, err := io.ReadAll(r.Body)
+	check(err)
+
+	var resp tsResponse
+	_, err = asn1.Unmarshal(b, &resp)
+	check(err)
+	// 0 is granted, and 1 is granted with modifications.
+	if resp.Status.Status > 1 || len(resp.Token.FullBytes) == 0 {
+		msg := "timestamp was not granted"
+		for _, s := range resp.Status.Text {
+			msg += ": " + string(s.Bytes)
+		}
+		panic(msg)
+	}
+	return resp.Token.FullBytes, nil
+}
+
+// timestamp returns the token of data with the given digest from c, and
+// panics on errors.
+func timestamp(c TimestampClient, digest []byte) []byte {
+	tok, err := c.Timestamp(digest)
+	check(err)
+	if len(tok) == 0 {
+		panic("empty timestamp token")
+	}
+	return tok
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeTSA is a TimestampClient that gives the same token for everything.
+type fakeTSA struct {
+	digest []byte // digest of the last request
+}
+
+var fakeToken = marshal(asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true,
+	Bytes: marshal(oidData)})
+
+func (t *fakeTSA) Timestamp(digest []byte) ([]byte, error) {
+	t.digest = digest
+	return fakeToken, nil
+}
+
+// tsaServer answers timestamp requests with status and the fake token.
+func tsaServer(t *testing.T, digest []byte, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/timestamp-query" {
+			t.Errorf("content type of request: got %q", ct)
+		}
+		b, _ := io.ReadAll(r.Body)
+		var req tsRequest
+		if _, err := asn1.Unmarshal(b, &req); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(req.Imprint.Digest, digest) || !req.Imprint.Algorithm.Algorithm.Equal(oidSHA256) {
+			t.Errorf("bad message imprint in request")
+		}
+		resp := tsResponse{Status: tsStatus{Status: status}}
+		if status == 0 {
+			resp.Token = asn1.RawValue{FullBytes: fakeToken}
+		} else {
+			resp.Status.Text = []asn1.RawValue{{Tag: asn1.TagUTF8String,
+				Bytes: []byte("bad request")}}
+		}
+		w.Write(marshal(resp))
+	}))
+}
+
+func TestTSA(t *testing.T) {
+	digest := bytes.Repeat([]byte{1}, 32)
+
+	s := tsaServer(t, digest, 0)
+	tok, err := (&TSA{URL: s.URL}).Timestamp(digest)
+	s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tok, fakeToken) {
+		t.Errorf("token: got %x expected %x", tok, fakeToken)
+	}
+
+	s = tsaServer(t, digest, 2)
+	_, err = (&TSA{URL: s.URL}).Timestamp(digest)
+	s.Close()
+	if err == nil || !strings.Contains(fmt.Sprint(err), "bad request") {
+		t.Errorf("rejected request: got error %v", err)
+	}
+}
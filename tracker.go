@@ -0,0 +1,259 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file follows the current point of content streams and the bounding
+// box of what they paint, so that new content can be put after what's drawn
+// and pages can be cropped to their content.
+
+// bounds is a rectangle that grows to hold the points added to it. The zero
+// value holds nothing.
+type bounds struct {
+	r  rect
+	ok bool // whether any point is added
+}
+
+// add grows b to hold point (x, y).
+func (b *bounds) add(x, y float64) {
+	if !b.ok {
+		b.r, b.ok = rect{x, y, x, y}, true
+		return
+	}
+	if x < b.r.llx {
+		b.r.llx = x
+	}
+	if y < b.r.lly {
+		b.r.lly = y
+	}
+	if x > b.r.urx {
+		b.r.urx = x
+	}
+	if y > b.r.ury {
+		b.r.ury = y
+	}
+}
+
+// addRect grows b to hold rectangle r transformed by m.
+func (b *bounds) addRect(r rect, m matrix) {
+	for _, p := range [][2]float64{{r.llx, r.lly}, {r.urx, r.lly}, {r.urx, r.ury}, {r.llx, r.ury}} {
+		b.add(m.apply(p[0], p[1]))
+	}
+}
+
+// apply returns point (x, y) transformed by m.
+func (m matrix) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// tracker follows the operators of a content stream, to know its current
+// point and the bounding box of what it paints. Only the path and
+// transformation operators, and what's drawn with XObjects and text by the
+// methods of the package, are followed. The width of lines is not counted.
+// The zero value is the start of a content stream.
+type tracker struct {
+	ctm    matrix   // current transformation matrix, if transformed
+	moved  bool     // whether ctm is set; it's the identity if not
+	saved  []matrix // matrices saved by "q"
+	x, y   float64  // current point, in user space
+	sx, sy float64  // start of the current subpath
+	point  bool     // whether there's a current point
+	path   bounds   // the path being made, in the space of the page
+	box    bounds   // what's painted, in the space of the page
+}
+
+// matrix returns the current transformation matrix of t.
+func (t *tracker) matrix() matrix {
+	if !t.moved {
+		return identity
+	}
+	return t.ctm
+}
+
+// op follows operator op with numeric operands a, which are checked
+// already.
+func (t *tracker) op(op string, a ...float64) {
+	switch op {
+	case "q":
+		t.saved = append(t.saved, t.matrix())
+	case "Q":
+		if n := len(t.saved); n > 0 {
+			t.ctm, t.moved = t.saved[n-1], true
+			t.saved = t.saved[:n-1]
+		}
+	case "cm":
+		t.ctm, t.moved = matrix{a[0], a[1], a[2], a[3], a[4], a[5]}.mul(t.matrix()), true
+	case "m":
+		t.sx, t.sy = a[0], a[1]
+		t.to(a...)
+	case "l", "c", "v", "y":
+		// Curves are inside the polygon of their control points.
+		t.to(a...)
+	case "re":
+		x, y, w, h := a[0], a[1], a[2], a[3]
+		t.to(x, y, x+w, y, x+w, y+h, x, y+h)
+		t.x, t.y, t.sx, t.sy = x, y, x, y
+	case "h":
+		t.x, t.y = t.sx, t.sy
+	case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*":
+		if t.path.ok {
+			t.box.addRect(t.path.r, identity)
+		}
+		fallthrough
+	case "n":
+		t.path, t.point = bounds{}, false
+	}
+}
+
+// to adds the points in a, which are pairs of coordinates, to the path being
+// made. The last one becomes the current point.
+func (t *tracker) to(a ...float64) {
+	m := t.matrix()
+	for i := 0; i+1 < len(a); i += 2 {
+		t.path.add(m.apply(a[i], a[i+1]))
+	}
+	t.x, t.y, t.point = a[len(a)-2], a[len(a)-1], true
+}
+
+// paint adds the rectangle with lower-left corner at (x, y), width w and
+// height h to what's painted, like an XObject drawn there.
+func (t *tracker) paint(x, y, w, h float64) {
+	t.box.addRect(rect{x, y, x + w, y + h}, t.matrix())
+}
+
+// text adds the WinAnsi encoded string s, shown with style st in the given
+// size with the baseline starting at (x, y), to what's painted.
+func (t *tracker) text(s string, x, y, size float64, st *TextStyle) {
+	// Descender of Helvetica is about 0.21 of the font size, like in
+	// baseline.
+	t.paint(x, y-0.22*size, st.width(s, size), size)
+}
+
+// append follows the operators of u, which are added after the ones of t.
+func (t *tracker) append(u *tracker) {
+	m := t.matrix()
+	if u.box.ok {
+		t.box.addRect(u.box.r, m)
+	}
+	if u.path.ok {
+		t.path.addRect(u.path.r, m)
+	}
+	if u.moved {
+		t.ctm, t.moved = u.ctm.mul(m), true
+	}
+	if u.point {
+		t.x, t.y, t.sx, t.sy, t.point = u.x, u.y, u.sx, u.sy, true
+	}
+}
+
+// size returns the lower-left corner, width and height of b, and whether
+// anything is in it.
+func (b *bounds) size() (x, y, w, h float64, ok bool) {
+	if !b.ok {
+		return 0, 0, 0, 0, false
+	}
+	return b.r.llx, b.r.lly, b.r.urx - b.r.llx, b.r.ury - b.r.lly, true
+}
+
+// CurrentPoint returns the current point of the path being made by w, in the
+// coordinates it was given in. ok is false if there's no path.
+func (w *ContentWriter) CurrentPoint() (x, y float64, ok bool) {
+	if !w.t.point {
+		return 0, 0, false
+	}
+	return w.t.x, w.t.y, true
+}
+
+// Bounds returns the lower-left corner, width and height of the bounding box
+// of what's painted by w so far, after the transformations of w. ok is false
+// if nothing is painted. Only paths and the transformations of Transform,
+// SaveState and RestoreState are followed, not operators written by Op;
+// the width of lines is not counted.
+func (w *ContentWriter) Bounds() (x, y, bw, bh float64, ok bool) {
+	return w.t.box.size()
+}
+
+// CurrentPoint returns the current point of the path being made on the
+// current page, or in the XObject being made. ok is false if there's no path.
+func (d *Document) CurrentPoint() (x, y float64, ok bool) {
+	return d.cw.CurrentPoint()
+}
+
+// Bounds returns the lower-left corner, width and height of the bounding box
+// of what's painted on the current page so far, or in the XObject being made,
+// e.g. to put more content below it. Paths, XObjects and text of contents are
+// counted, but not text put by flows and tables, which know where it ends. ok
+// is false if nothing is painted.
+func (d *Document) Bounds() (x, y, w, h float64, ok bool) {
+	return d.cw.Bounds()
+}
+
+// tracker returns what follows the content of p. The one of the current page
+// is kept by the writer of the document, like its content.
+func (p *Page) tracker() *tracker {
+	if p.d.pg == p.pg {
+		return &p.d.cw.t
+	}
+	return &p.pg.track
+}
+
+// Bounds returns the lower-left corner, width and height of the bounding box
+// of what's painted on p so far, like the Bounds method of the document.
+func (p *Page) Bounds() (x, y, w, h float64, ok bool) {
+	return p.tracker().box.size()
+}
+
+// CropToContent sets the crop box of p (p. 962), which is the region viewers
+// show, to the bounding box of its content grown by margin on each side. It's
+// found when p is written to the output, so content added after it counts
+// too. Pages with no content painted aren't cropped.
+func (p *Page) CropToContent(margin float64) (err error) {
+	defer dontPanic(&err)
+
+	p.check()
+	if margin < 0 {
+		panic("negative margin of crop box")
+	}
+	p.pg.autocrop, p.pg.margin = true, margin
+	return nil
+}
+
+// cropToContent sets the crop box of pg, which is the current page, if it's
+// to be cropped to its content.
+func (d *Document) cropToContent(pg *page) {
+	b := d.cw.t.box
+	if !pg.autocrop || !b.ok {
+		return
+	}
+	m := pg.margin
+	r := newRect(b.r.llx-m, b.r.lly-m, b.r.urx+m, b.r.ury+m)
+	// The crop box is in the media box.
+	if r.llx < pg.box.llx {
+		r.llx = pg.box.llx
+	}
+	if r.lly < pg.box.lly {
+		r.lly = pg.box.lly
+	}
+	if r.urx > pg.box.urx {
+		r.urx = pg.box.urx
+	}
+	if r.ury > pg.box.ury {
+		r.ury = pg.box.ury
+	}
+	if r.llx < r.urx && r.lly < r.ury {
+		pg.crop = r
+	}
+}
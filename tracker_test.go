@@ -0,0 +1,113 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+type trackerTest struct {
+	write     func(w *ContentWriter)
+	x, y      float64 // current point
+	point     bool
+	bounds    [4]float64
+	hasBounds bool
+}
+
+func TestTracker(t *testing.T) {
+	tests := []trackerTest{
+		{func(w *ContentWriter) {}, 0, 0, false, [4]float64{}, false},
+		{func(w *ContentWriter) { w.MoveTo(10, 20).LineTo(30, 5) },
+			30, 5, true, [4]float64{}, false},
+		{func(w *ContentWriter) { w.MoveTo(10, 20).LineTo(30, 5).Stroke() },
+			0, 0, false, [4]float64{10, 5, 20, 15}, true},
+		{func(w *ContentWriter) { w.MoveTo(0, 0).Curve(-5, 10, 15, 10, 10, 0).ClosePath() },
+			0, 0, true, [4]float64{}, false},
+		{func(w *ContentWriter) { w.MoveTo(0, 0).CurveV(-5, 10, 10, 0).Fill() },
+			0, 0, false, [4]float64{-5, 0, 15, 10}, true},
+		{func(w *ContentWriter) { w.Rectangle(1, 2, 3, 4).Fill().Rectangle(50, 50, 1, 1).Op("n") },
+			50, 50, true, [4]float64{1, 2, 3, 4}, true},
+		{func(w *ContentWriter) {
+			w.SaveState().Transform(2, 0, 0, 2, 10, 10).Rectangle(0, 0, 5, 5).Fill().
+				RestoreState().Rectangle(0, 0, 1, 1).Stroke()
+		}, 0, 0, false, [4]float64{0, 0, 20, 20}, true},
+		{func(w *ContentWriter) { w.Transform(0, 1, -1, 0, 0, 0).MoveTo(0, 0).LineTo(10, 0).Stroke() },
+			0, 0, false, [4]float64{0, 0, 0, 10}, true},
+	}
+	for i, tt := range tests {
+		w := new(ContentWriter)
+		tt.write(w)
+		if x, y, ok := w.CurrentPoint(); x != tt.x || y != tt.y || ok != tt.point {
+			t.Errorf("%d: current point: got %v, %v, %v expected %v, %v, %v", i, x, y, ok, tt.x, tt.y, tt.point)
+		}
+		x, y, bw, bh, ok := w.Bounds()
+		if b := [4]float64{x, y, bw, bh}; b != tt.bounds || ok != tt.hasBounds {
+			t.Errorf("%d: bounds: got %v, %v expected %v, %v", i, b, ok, tt.bounds, tt.hasBounds)
+		}
+	}
+}
+
+func TestDocumentBounds(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	p1, _ := d.NewPage(200, 200)
+	p1.CropToContent(5)
+	d.Rectangle(10, 10, 20, 20)
+	d.MoveTo(40, 50)
+	if x, y, ok := d.CurrentPoint(); x != 40 || y != 50 || !ok {
+		t.Errorf("current point: got %v, %v, %v", x, y, ok)
+	}
+	d.Stroke()
+
+	// XObjects are followed apart from the page.
+	d.BeginXObject(10, 10)
+	if _, _, _, _, ok := d.Bounds(); ok {
+		t.Errorf("XObject has bounds before it's drawn on")
+	}
+	d.Rectangle(0, 0, 10, 10)
+	d.Fill()
+	x, _ := d.EndXObject()
+	d.DrawXObject(x, 100, 100, 50, 20)
+	if x, y, w, h, _ := d.Bounds(); x != 10 || y != 10 || w != 140 || h != 110 {
+		t.Errorf("bounds of page 1: got %v %v %v %v", x, y, w, h)
+	}
+
+	p2, _ := d.NewPage(200, 200)
+	c := new(Content)
+	c.Text("Hi", 0, 100, TextStyle{FontSize: 10})
+	p1.AddContent(c)
+	p2.Raw("re", 1, 2, 3, 4)
+	p2.Raw("f")
+	if x, y, w, h, _ := p1.Bounds(); x != 0 || y != 10 || w != 150 || h != 110 {
+		t.Errorf("bounds of page 1 after content: got %v %v %v %v", x, y, w, h)
+	}
+	if x, y, w, h, _ := d.Bounds(); x != 1 || y != 2 || w != 3 || h != 4 {
+		t.Errorf("bounds of page 2: got %v %v %v %v", x, y, w, h)
+	}
+	d.Close()
+
+	if !bytes.Contains(buf.Bytes(), []byte("/CropBox [ 0 5 155 125 ]")) {
+		t.Errorf("no crop box to the content")
+	}
+	if bytes.Count(buf.Bytes(), []byte("/CropBox")) != 1 {
+		t.Errorf("page 2 is cropped")
+	}
+	if probs := Validate(buf.Bytes()); len(probs) > 0 {
+		t.Errorf("problems: %v", probs)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file writes incremental updates of existing files (p. 73), which
+// append the objects they change to the file and leave the rest of it as it
+// is. Signatures of the file stay valid after them.
+
+import (
+	"bytes"
+	"io"
+)
+
+// newUpdate writes the file read by r to w, and returns a document that
+// writes an incremental update of it. Objects of the file are changed by
+// writing them again with outputIndirect, and new ones are numbered after
+// them. Objects of encrypted files are encrypted like the rest of the file.
+// The update is finished by finishUpdate.
+func newUpdate(w io.Writer, r *Reader) *Document {
+	size, _ := r.trailer["Size"].(int)
+	root, ok := r.trailer["Root"].(ref)
+	if !ok || root.num <= 0 || root.num >= size {
+		fail(ErrBadFile, "no catalog")
+	}
+	d := &Document{w: w, version: r.version}
+	for i := 1; i < size; i++ {
+		d.objs = append(d.objs, &indirect{num: i})
+	}
+	d.cat = d.objs[root.num-1]
+	if info, ok := r.trailer["Info"].(ref); ok && info.num > 0 && info.num < size {
+		d.infoRef = d.objs[info.num-1]
+	}
+	if id, ok := r.resolve(r.trailer["ID"]).([]interface{}); ok && len(id) > 0 {
+		if s, ok := id[0].(string); ok {
+			d.id = []byte(s)
+		}
+	}
+	if r.Encrypted() {
+		if r.sec == nil {
+			fail(ErrPassword, "encrypted file needs a password")
+		}
+		if d.old(r.enc) == nil {
+			fail(ErrBadFile, "encrypted files with a direct encryption dictionary can't be updated")
+		}
+		sec := *r.sec
+		sec.ref = d.old(r.enc)
+		d.sec = &sec
+	}
+
+	d.write(r.b)
+	if !bytes.HasSuffix(r.b, []byte("\n")) {
+		d.write([]byte("\n"))
+	}
+	d.xOff = r.xoff
+	return d
+}
+
+// old returns object num of the file being updated by d, or nil if there's
+// no such object.
+func (d *Document) old(num int) *indirect {
+	if num <= 0 || num > len(d.objs) {
+		return nil
+	}
+	return d.objs[num-1]
+}
+
+// finishUpdate writes the cross-reference section and the trailer of the
+// update d, for the objects written by it.
+func (d *Document) finishUpdate() {
+	prev := d.xOff
+	var objs []*indirect
+	for _, o := range d.objs {
+		if o.off != 0 {
+			objs = append(objs, o)
+		}
+	}
+	d.writeRefs(objs)
+	d.writeTrailer(prev)
+}
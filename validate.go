@@ -0,0 +1,276 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+// This file checks the structure of PDF files, whether they are made by this
+// package or not, so that broken documents are caught before they reach
+// their readers.
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// Problem is a structural problem of a PDF file found by Validate.
+type Problem struct {
+	Object int    // number of the object with the problem; 0 if there's none
+	Text   string // description of the problem
+}
+
+func (p Problem) String() string {
+	if p.Object == 0 {
+		return p.Text
+	}
+	return fmt.Sprintf("object %d: %s", p.Object, p.Text)
+}
+
+// validator holds the state of Validate.
+type validator struct {
+	*Reader
+	forms map[int]bool // form XObjects already checked
+}
+
+// Validate parses the PDF file b and returns the structural problems it
+// finds: cross-reference tables with wrong offsets, streams with missing or
+// wrong lengths, content streams with unbalanced q and Q or BT and ET
+// operators, and resources used by content streams but not defined in their
+// resource dictionaries. Content of encrypted files is not checked.
+func Validate(b []byte) []Problem {
+	var probs []Problem
+	if !bytes.HasPrefix(b, []byte("%PDF-")) {
+		probs = append(probs, Problem{0, "no PDF header"})
+	}
+	if i := bytes.LastIndex(b, []byte("startxref")); i >= 0 && !bytes.Contains(b[i:], []byte("%%EOF")) {
+		probs = append(probs, Problem{0, "no %%EOF at the end of the file"})
+	}
+	r, err := NewReader(b)
+	if err != nil {
+		return append(probs, Problem{0, panicText(err)})
+	}
+	v := &validator{r, make(map[int]bool)}
+	v.try(0, v.validate)
+	return append(probs, v.probs...)
+}
+
+// try calls f, and records the error it panics with as a problem of object
+// num.
+func (v *validator) try(num int, f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch e := r.(type) {
+			case *Error:
+				v.problem(num, "%s", e.Msg)
+			case string, error:
+				v.problem(num, "%s", e)
+			default:
+				panic(r)
+			}
+		}
+	}()
+	f()
+}
+
+func (v *validator) validate() {
+	nums := make([]int, 0, len(v.xref))
+	for n := range v.xref {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	for _, n := range nums {
+		n := n
+		v.try(n, func() { v.object(n) })
+	}
+	if v.trailer["Size"] == nil {
+		v.problem(0, "trailer with no Size")
+	}
+	if v.trailer["Encrypt"] != nil {
+		return
+	}
+
+	cat, ok := v.resolve(v.trailer["Root"]).(map[string]interface{})
+	if !ok {
+		panic("no catalog")
+	}
+	v.pages(cat["Pages"], nil, nil, make(map[int]bool))
+}
+
+// pages checks the pages in the page tree node n. Resources and MediaBox of
+// pages can be inherited from their parents, so the ones of the parents of n
+// are passed in res and box.
+func (v *validator) pages(n, res, box interface{}, seen map[int]bool) {
+	r, ok := n.(ref)
+	if !ok {
+		v.problem(0, "page tree node is not an indirect object")
+		return
+	}
+	if seen[r.num] {
+		v.problem(r.num, "loop in page tree")
+		return
+	}
+	seen[r.num] = true
+	d, ok := v.resolve(n).(map[string]interface{})
+	if !ok {
+		v.problem(r.num, "page tree node is not a dictionary")
+		return
+	}
+	if d["Resources"] != nil {
+		res = d["Resources"]
+	}
+	if d["MediaBox"] != nil {
+		box = d["MediaBox"]
+	}
+	switch d["Type"] {
+	case name("Pages"):
+		kids, _ := v.resolve(d["Kids"]).([]interface{})
+		for _, k := range kids {
+			v.pages(k, res, box, seen)
+		}
+	case name("Page"):
+		if res == nil {
+			v.problem(r.num, "page with no Resources")
+		}
+		if box == nil {
+			v.problem(r.num, "page with no MediaBox")
+		}
+		v.try(r.num, func() { v.page(r.num, d, res) })
+	default:
+		v.problem(r.num, "page tree node with bad Type")
+	}
+}
+
+// page checks the content and annotations of page d, whose number is num,
+// with resources res.
+func (v *validator) page(num int, d map[string]interface{}, res interface{}) {
+	var con []byte
+	switch c := v.resolve(d["Contents"]).(type) {
+	case *stream:
+		con = v.decode(c)
+	case []interface{}:
+		for _, s := range c {
+			s, ok := v.resolve(s).(*stream)
+			if !ok {
+				panic("contents of page is not a stream")
+			}
+			b := v.decode(s)
+			if b == nil {
+				return
+			}
+			con = append(append(con, b...), '\n')
+		}
+	}
+	if con != nil {
+		v.content(num, con, res)
+	}
+
+	annots, _ := v.resolve(d["Annots"]).([]interface{})
+	for _, a := range annots {
+		a, _ := v.resolve(a).(map[string]interface{})
+		ap, _ := v.resolve(a["AP"]).(map[string]interface{})
+		switch n := ap["N"].(type) {
+		case ref:
+			v.form(n, nil)
+		case map[string]interface{}:
+			for _, s := range n {
+				v.form(s, nil)
+			}
+		}
+	}
+}
+
+// form checks the form XObject referred to by o, which uses resources res if
+// it has none of its own.
+func (v *validator) form(o interface{}, res interface{}) {
+	r, ok := o.(ref)
+	if !ok || v.forms[r.num] {
+		return
+	}
+	v.forms[r.num] = true
+	s, ok := v.resolve(r).(*stream)
+	if !ok || v.resolve(s.dic["Subtype"]) != name("Form") {
+		return
+	}
+	if s.dic["Resources"] != nil {
+		res = s.dic["Resources"]
+	}
+	v.try(r.num, func() {
+		if b := v.decode(s); b != nil {
+			v.content(r.num, b, res)
+		}
+	})
+}
+
+// resourceTypes maps operators to the resource categories of their first
+// operand (p. 153).
+var resourceTypes = map[string]string{
+	"Tf": "Font", "Do": "XObject", "gs": "ExtGState", "cs": "ColorSpace",
+	"CS": "ColorSpace", "sh": "Shading", "BDC": "Properties", "DP": "Properties",
+}
+
+// content checks content stream b of object num with resources res.
+func (v *validator) content(num int, b []byte, res interface{}) {
+	resd, _ := v.resolve(res).(map[string]interface{})
+	q, bt := 0, false
+	for _, op := range operations(b) {
+		switch op.op {
+		case "q":
+			q++
+		case "Q":
+			if q == 0 {
+				v.problem(num, "Q without q")
+			} else {
+				q--
+			}
+		case "BT":
+			if bt {
+				v.problem(num, "BT inside another BT")
+			}
+			bt = true
+		case "ET":
+			if !bt {
+				v.problem(num, "ET without BT")
+			}
+			bt = false
+		}
+
+		cat, ok := resourceTypes[op.op]
+		if !ok || len(op.args) == 0 {
+			continue
+		}
+		n, ok := op.args[0].(name)
+		if op.op == "BDC" || op.op == "DP" {
+			// Property lists can be inline.
+			n, ok = op.args[len(op.args)-1].(name)
+		}
+		if !ok || cat == "ColorSpace" && (n == "DeviceGray" || n == "DeviceRGB" ||
+			n == "DeviceCMYK" || n == "Pattern") {
+			continue
+		}
+		sub, _ := v.resolve(resd[cat]).(map[string]interface{})
+		if sub[string(n)] == nil {
+			v.problem(num, "undefined resource /%s in %s", n, cat)
+		} else if op.op == "Do" {
+			v.form(sub[string(n)], res)
+		}
+	}
+	if q > 0 {
+		v.problem(num, "q without Q")
+	}
+	if bt {
+		v.problem(num, "BT without ET")
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2011 Mostafa Hajizdeh
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testFile makes a PDF file with the given objects, numbered from 1, and a
+// correct cross-reference table. The first object is the catalog.
+func testFile(objs []string) []byte {
+	buf := bytes.NewBufferString("%PDF-1.7\n")
+	offs := make([]int, len(objs))
+	for i, o := range objs {
+		offs[i] = buf.Len()
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	x := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f\r\n", len(objs)+1)
+	for _, o := range offs {
+		fmt.Fprintf(buf, "%010d 00000 n\r\n", o)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, x)
+	return buf.Bytes()
+}
+
+// testPage returns the objects of a file with one page with content c and
+// resources res.
+func testPage(c, res string) []string {
+	return []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [ 3 0 R ] /Count 1 /MediaBox [ 0 0 100 100 ] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources " + res + " >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(c), c),
+	}
+}
+
+func TestValidateDocument(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	d, _ := New(buf)
+	d.NewPage(300, 300)
+	d.Rectangle(10, 10, 100, 100)
+	d.Stroke()
+	d.TextBox(10, 10, 100, 30, &TextField{Name: "name", Value: "Ali"})
+	d.CheckBox(200, 10, 20, 20, &CheckField{Name: "agree", Checked: true})
+	d.ComboBox(10, 50, 100, 20, &ChoiceField{Name: "c", Options: []Option{{Text: "a"}}})
+	d.PushButton(10, 80, 100, 20, &Button{Name: "b", Caption: "OK"})
+	d.NewPage(300, 300)
+	d.BeginTag(TagP)
+	d.Rectangle(10, 10, 100, 100)
+	d.Fill()
+	d.EndTag()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if p := Validate(buf.Bytes()); len(p) != 0 {
+		t.Errorf("problems of document: %v", p)
+	}
+}
+
+type validateTest struct {
+	file    []byte
+	problem string // empty if there's none
+}
+
+func TestValidate(t *testing.T) {
+	page := testPage("q BT /F1 12 Tf (Hi) Tj ET Q /X1 Do",
+		"<< /Font << /F1 5 0 R >> /XObject << /X1 6 0 R >> >>")
+	good := testFile(append(page,
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /XObject /Subtype /Form /BBox [ 0 0 1 1 ] /Length 12 >>\n"+
+			"stream\nq /F1 1 Tf Q\nendstream"))
+	empty := testPage("", "<< >>")
+	tests := []validateTest{
+		{good, ""},
+		{good[1:], "no PDF header"},
+		{bytes.Replace(good, []byte("\n3 0 obj"), []byte("\n7 0 obj"), 1),
+			"object 3: bad offset in cross-reference table"},
+		{good[:bytes.Index(good, []byte("xref"))], "no startxref"},
+		{testFile(testPage("q 1 0 0 1 0 0 cm", "<< >>")), "object 3: q without Q"},
+		{testFile(testPage("Q", "<< >>")), "object 3: Q without q"},
+		{testFile(testPage("BT BT ET", "<< >>")), "object 3: BT inside another BT"},
+		{testFile(testPage("BT (Hi) Tj", "<< >>")), "object 3: BT without ET"},
+		{testFile(testPage("ET", "<< >>")), "object 3: ET without BT"},
+		{testFile(testPage("BT /F1 12 Tf ET", "<< >>")),
+			"object 3: undefined resource /F1 in Font"},
+		{testFile(testPage("/GS0 gs /CS0 cs /DeviceRGB CS",
+			"<< /ExtGState << /GS0 << >> >> >>")),
+			"object 3: undefined resource /CS0 in ColorSpace"},
+		{testFile(append(testPage("/X1 Do", "<< /XObject << /X1 5 0 R >> >>"),
+			"<< /Type /XObject /Subtype /Form /BBox [ 0 0 1 1 ] /Length 8 >>\n"+
+				"stream\n/F2 1 Tf\nendstream")),
+			"object 5: undefined resource /F2 in Font"},
+		{testFile([]string{empty[0], empty[1],
+			"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>", empty[3]}),
+			"object 3: page with no Resources"},
+		{testFile([]string{empty[0], "<< /Type /Pages /Kids [ 3 0 R ] /Count 1 >>",
+			empty[2], empty[3]}),
+			"object 3: page with no MediaBox"},
+		{testFile([]string{empty[0], empty[1], empty[2],
+			"<< /Length 5 >>\nstream\nq Q\nendstream"}),
+			"object 4: wrong Length of stream"},
+		{testFile([]string{empty[0], empty[1], empty[2],
+			"<< >>\nstream\nq Q\nendstream"}),
+			"object 4: stream with no Length"},
+	}
+
+	for i, test := range tests {
+		p := Validate(test.file)
+		if test.problem == "" && len(p) != 0 {
+			t.Errorf("%d: got problems %v", i, p)
+		}
+		if test.problem != "" && !strings.Contains(fmt.Sprint(p), test.problem) {
+			t.Errorf("%d: got problems %v expected %q", i, p, test.problem)
+		}
+	}
+}